@@ -75,6 +75,10 @@ func ChannelType2APIType(channelType int) (int, bool) {
 		apiType = constant.APITypeReplicate
 	case constant.ChannelTypeReplicate2:
 		apiType = constant.APITypeReplicate2
+	case constant.ChannelTypeFal:
+		apiType = constant.APITypeFal
+	case constant.ChannelTypeStability:
+		apiType = constant.APITypeStability
 	}
 	if apiType == -1 {
 		return constant.APITypeOpenAI, false
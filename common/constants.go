@@ -131,6 +131,8 @@ var CohereSafetySetting string
 
 const (
 	RequestIdKey = "X-Oneapi-Request-Id"
+	// TraceIdKey 请求级追踪 ID 的请求头名，会透传给上游请求；context 中通过 WithTraceId/GetTraceId 读写
+	TraceIdKey = "X-Trace-Id"
 )
 
 const (
@@ -171,6 +173,9 @@ var (
 
 	DownloadRateLimitNum            = 10
 	DownloadRateLimitDuration int64 = 60
+
+	VideoUploadRateLimitNum            = 20
+	VideoUploadRateLimitDuration int64 = 60 * 60
 )
 
 var RateLimitKeyExpirationDuration = 20 * time.Minute
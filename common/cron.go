@@ -0,0 +1,90 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule 标准 5 段 cron 表达式（分 时 日 月 周），支持 *、数字、范围、列表与步长
+type CronSchedule struct {
+	minute  map[int]bool
+	hour    map[int]bool
+	day     map[int]bool
+	month   map[int]bool
+	weekday map[int]bool
+}
+
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+	return &CronSchedule{
+		minute:  sets[0],
+		hour:    sets[1],
+		day:     sets[2],
+		month:   sets[3],
+		weekday: sets[4],
+	}, nil
+}
+
+// Match 判断 t 所在的分钟是否命中表达式
+func (s *CronSchedule) Match(t time.Time) bool {
+	return s.minute[t.Minute()] &&
+		s.hour[t.Hour()] &&
+		s.day[t.Day()] &&
+		s.month[int(t.Month())] &&
+		s.weekday[int(t.Weekday())]
+}
+
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		hasStep := false
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			hasStep = true
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:idx]
+		}
+		lo, hi := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if hasStep {
+				// "5/10" 表示从 5 开始到最大值，每隔 10 取一次
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value %q out of range [%d, %d]", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronField(t *testing.T) {
+	cases := []struct {
+		field string
+		min   int
+		max   int
+		want  []int
+	}{
+		{"*", 0, 4, []int{0, 1, 2, 3, 4}},
+		{"5", 0, 59, []int{5}},
+		{"1,3", 0, 59, []int{1, 3}},
+		{"2-4", 0, 59, []int{2, 3, 4}},
+		{"*/15", 0, 59, []int{0, 15, 30, 45}},
+		{"5/10", 0, 59, []int{5, 15, 25, 35, 45, 55}},
+		{"10-30/10", 0, 59, []int{10, 20, 30}},
+	}
+	for _, tc := range cases {
+		set, err := parseCronField(tc.field, tc.min, tc.max)
+		if err != nil {
+			t.Errorf("parseCronField(%q) failed: %v", tc.field, err)
+			continue
+		}
+		if len(set) != len(tc.want) {
+			t.Errorf("parseCronField(%q) = %v, want %v", tc.field, set, tc.want)
+			continue
+		}
+		for _, v := range tc.want {
+			if !set[v] {
+				t.Errorf("parseCronField(%q) missing %d", tc.field, v)
+			}
+		}
+	}
+
+	for _, field := range []string{"", "a", "60", "5-1", "*/0", "1-2-3"} {
+		if _, err := parseCronField(field, 0, 59); err == nil {
+			t.Errorf("parseCronField(%q) expected error", field)
+		}
+	}
+}
+
+func TestCronScheduleMatch(t *testing.T) {
+	schedule, err := ParseCron("30 8 * * 1-5")
+	if err != nil {
+		t.Fatalf("ParseCron failed: %v", err)
+	}
+	// 2026-10-12 为周一
+	if !schedule.Match(time.Date(2026, 10, 12, 8, 30, 0, 0, time.Local)) {
+		t.Error("expected match on Monday 08:30")
+	}
+	if schedule.Match(time.Date(2026, 10, 11, 8, 30, 0, 0, time.Local)) {
+		t.Error("unexpected match on Sunday")
+	}
+	if schedule.Match(time.Date(2026, 10, 12, 8, 31, 0, 0, time.Local)) {
+		t.Error("unexpected match at 08:31")
+	}
+	if _, err := ParseCron("* * * *"); err == nil {
+		t.Error("expected error for 4 fields")
+	}
+}
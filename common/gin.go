@@ -32,6 +32,26 @@ func IsRequestBodyTooLargeError(err error) bool {
 	return errors.As(err, &mbe)
 }
 
+// RequestBodyErrorStatusCode 读取或解析请求体失败时返回的状态码：超出大小限制为 413，否则为 400
+func RequestBodyErrorStatusCode(err error) int {
+	if IsRequestBodyTooLargeError(err) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// requestBodyTooLargeError 优先使用 http.MaxBytesReader（如路由级 MaxBodySize）的实际限制生成错误信息
+func requestBodyTooLargeError(err error, maxMB int) error {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		if mbe.Limit%(1<<20) == 0 {
+			return errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d MB", mbe.Limit>>20))
+		}
+		return errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d bytes", mbe.Limit))
+	}
+	return errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d MB", maxMB))
+}
+
 func GetRequestBody(c *gin.Context) ([]byte, error) {
 	cached, exists := c.Get(KeyRequestBody)
 	if exists && cached != nil {
@@ -45,6 +65,9 @@ func GetRequestBody(c *gin.Context) ([]byte, error) {
 		body, err := io.ReadAll(c.Request.Body)
 		_ = c.Request.Body.Close()
 		if err != nil {
+			if IsRequestBodyTooLargeError(err) {
+				return nil, requestBodyTooLargeError(err, maxMB)
+			}
 			return nil, err
 		}
 		c.Set(KeyRequestBody, body)
@@ -57,7 +80,7 @@ func GetRequestBody(c *gin.Context) ([]byte, error) {
 	if err != nil {
 		_ = c.Request.Body.Close()
 		if IsRequestBodyTooLargeError(err) {
-			return nil, errors.Wrap(ErrRequestBodyTooLarge, fmt.Sprintf("request body exceeds %d MB", maxMB))
+			return nil, requestBodyTooLargeError(err, maxMB)
 		}
 		return nil, err
 	}
@@ -109,6 +109,9 @@ func InitEnv() {
 	CriticalRateLimitEnable = GetEnvOrDefaultBool("CRITICAL_RATE_LIMIT_ENABLE", true)
 	CriticalRateLimitNum = GetEnvOrDefault("CRITICAL_RATE_LIMIT", 20)
 	CriticalRateLimitDuration = int64(GetEnvOrDefault("CRITICAL_RATE_LIMIT_DURATION", 20*60))
+	// /v1/files/upload 按令牌限制上传次数
+	VideoUploadRateLimitNum = GetEnvOrDefault("VIDEO_UPLOAD_RATE_LIMIT", 20)
+	VideoUploadRateLimitDuration = int64(GetEnvOrDefault("VIDEO_UPLOAD_RATE_LIMIT_DURATION", 60*60))
 	initConstantEnv()
 }
 
@@ -119,6 +122,11 @@ func initConstantEnv() {
 	constant.StreamScannerMaxBufferMB = GetEnvOrDefault("STREAM_SCANNER_MAX_BUFFER_MB", 64)
 	// MaxRequestBodyMB 请求体最大大小（解压后），用于防止超大请求/zip bomb导致内存暴涨
 	constant.MaxRequestBodyMB = GetEnvOrDefault("MAX_REQUEST_BODY_MB", 128)
+	// 按路由限制请求体大小（MB），0 表示只受 MAX_REQUEST_BODY_MB 限制；
+	// 文本类请求可能内联 base64 图片、音频或 PDF，默认上限需足够宽松
+	constant.MaxTextRequestBodyMB = GetEnvOrDefault("MAX_TEXT_REQUEST_BODY_MB", 32)
+	constant.MaxImageRequestBodyMB = GetEnvOrDefault("MAX_IMAGE_REQUEST_BODY_MB", 10)
+	constant.MaxMultipartRequestBodyMB = GetEnvOrDefault("MAX_MULTIPART_REQUEST_BODY_MB", 50)
 	// ForceStreamOption 覆盖请求参数，强制返回usage信息
 	constant.ForceStreamOption = GetEnvOrDefaultBool("FORCE_STREAM_OPTION", true)
 	constant.CountToken = GetEnvOrDefaultBool("CountToken", true)
@@ -137,6 +145,57 @@ func initConstantEnv() {
 	constant.TaskQueryLimit = GetEnvOrDefault("TASK_QUERY_LIMIT", 1000)
 	// 视频任务超时时间（分钟），超时后自动标记为失败并退款，0 表示不超时
 	constant.VideoTaskTimeoutMinutes = GetEnvOrDefault("VIDEO_TASK_TIMEOUT_MINUTES", 180)
+	// 失败任务保存的重放请求体的保留天数，超过后清除
+	constant.TaskReplayRetentionDays = GetEnvOrDefault("TASK_REPLAY_RETENTION_DAYS", 7)
+	// 任务提交时预留的额度超过该时间仍未确认则视为提交中断，自动释放并退回
+	constant.QuotaReservationTimeoutMinutes = GetEnvOrDefault("QUOTA_RESERVATION_TIMEOUT_MINUTES", 30)
+	// 按平台覆盖任务超时时间（分钟），格式如 suno=60,kling=240
+	for _, item := range strings.Split(GetEnvOrDefaultString("TASK_PLATFORM_TIMEOUT_MINUTES", ""), ",") {
+		platform, minutes, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(minutes))
+		if err != nil {
+			SysError(fmt.Sprintf("invalid TASK_PLATFORM_TIMEOUT_MINUTES item %q: %s", item, err.Error()))
+			continue
+		}
+		constant.TaskPlatformTimeoutMinutes[strings.TrimSpace(platform)] = n
+	}
+	// 通过 /v1/files/upload 上传视频的最大大小（MB）
+	constant.MaxVideoUploadMB = GetEnvOrDefault("MAX_VIDEO_UPLOAD_MB", 500)
+	// 上传的视频对象保留时长（小时），超时后从 GCS 删除
+	constant.VideoUploadTTLHours = GetEnvOrDefault("VIDEO_UPLOAD_TTL_HOURS", 24)
+	// 异步图片生成渠道轮询上游任务结果的最长等待时间（秒）
+	constant.AsyncImageTimeoutSeconds = GetEnvOrDefault("ASYNC_IMAGE_TIMEOUT_SECONDS", 120)
+	// 渠道未配置 request_timeout_seconds 时等待上游响应头的超时时间（秒），0 表示不限制
+	constant.ChannelRequestTimeoutSeconds = GetEnvOrDefault("CHANNEL_REQUEST_TIMEOUT_SECONDS", 0)
+	// /metrics 访问控制：携带 Bearer METRICS_TOKEN 或来源 IP 在 METRICS_ALLOWED_IPS（逗号分隔，支持 CIDR）中，均未配置时不开放
+	constant.MetricsToken = GetEnvOrDefaultString("METRICS_TOKEN", "")
+	// 自定义任务错误信息模板（YAML），覆盖内置的同名错误码与语言
+	constant.TaskErrorI18nFile = GetEnvOrDefaultString("TASK_ERROR_I18N_FILE", "")
+	// 任务产出视频转存的 S3 兼容对象存储，endpoint 与 bucket 均配置时启用，返回签名链接
+	constant.TaskStorageEndpoint = GetEnvOrDefaultString("TASK_STORAGE_ENDPOINT", "")
+	constant.TaskStorageBucket = GetEnvOrDefaultString("TASK_STORAGE_BUCKET", "")
+	constant.TaskStorageRegion = GetEnvOrDefaultString("TASK_STORAGE_REGION", "us-east-1")
+	constant.TaskStorageAccessKeyId = GetEnvOrDefaultString("TASK_STORAGE_ACCESS_KEY_ID", "")
+	constant.TaskStorageSecretAccessKey = GetEnvOrDefaultString("TASK_STORAGE_SECRET_ACCESS_KEY", "")
+	// 签名链接有效期（秒），默认 7 天（S3 签名链接的上限）
+	constant.TaskStorageURLTTLSeconds = GetEnvOrDefault("TASK_STORAGE_URL_TTL_SECONDS", 7*24*3600)
+	// 从视频任务产出中提取音轨使用的 ffmpeg 可执行文件
+	constant.FfmpegPath = GetEnvOrDefaultString("FFMPEG_PATH", "ffmpeg")
+	// 视频任务成功后截取缩略图的服务地址，需同时配置任务产出对象存储；截取位置为 start（0 秒）或 middle（中间帧）
+	constant.VideoThumbnailServiceURL = GetEnvOrDefaultString("VIDEO_THUMBNAIL_SERVICE_URL", "")
+	constant.VideoThumbnailPosition = GetEnvOrDefaultString("VIDEO_THUMBNAIL_POSITION", "start")
+	// 流式文本请求在输出过程中分段扣费，每累计该数量的输出 token 扣一次，0 表示只在流结束后结算
+	constant.StreamQuotaChunkTokens = GetEnvOrDefault("STREAM_QUOTA_CHUNK_TOKENS", 1000)
+	// 计费对账：任务实际扣费与按提交时价格计算的额度偏差超过该百分比时记录差异
+	constant.BillingDiscrepancyThresholdPercent = GetEnvOrDefault("BILLING_DISCREPANCY_THRESHOLD_PERCENT", 5)
+	for _, ip := range strings.Split(GetEnvOrDefaultString("METRICS_ALLOWED_IPS", ""), ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			constant.MetricsAllowedIps = append(constant.MetricsAllowedIps, ip)
+		}
+	}
 
 	soraPatchStr := GetEnvOrDefaultString("TASK_PRICE_PATCH", "")
 	if soraPatchStr != "" {
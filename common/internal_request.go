@@ -0,0 +1,21 @@
+package common
+
+import (
+	"context"
+
+	"github.com/QuantumNous/new-api/constant"
+)
+
+type internalRequestValuesKey struct{}
+
+// WithInternalRequestValues 为服务内部发起的请求附加需要写入 gin 上下文的键值。
+// 键值只能通过请求 context 传递，外部请求无法伪造
+func WithInternalRequestValues(ctx context.Context, values map[constant.ContextKey]any) context.Context {
+	return context.WithValue(ctx, internalRequestValuesKey{}, values)
+}
+
+// InternalRequestValues 返回服务内部请求附加的键值，外部请求返回 nil
+func InternalRequestValues(ctx context.Context) map[constant.ContextKey]any {
+	values, _ := ctx.Value(internalRequestValuesKey{}).(map[constant.ContextKey]any)
+	return values
+}
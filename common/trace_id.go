@@ -0,0 +1,33 @@
+package common
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// traceIdContextKey 追踪 ID 在 context.Context 中的键，使用私有类型避免与其他包冲突
+type traceIdContextKey struct{}
+
+// WithTraceId 返回携带追踪 ID 的 context
+func WithTraceId(ctx context.Context, traceId string) context.Context {
+	return context.WithValue(ctx, traceIdContextKey{}, traceId)
+}
+
+// GetTraceId 读取请求的追踪 ID；gin.Context 优先读取 TraceId 中间件写入的值
+func GetTraceId(ctx context.Context) string {
+	if c, ok := ctx.(*gin.Context); ok {
+		if traceId := c.GetString(TraceIdKey); traceId != "" {
+			return traceId
+		}
+		if c.Request == nil {
+			return ""
+		}
+		ctx = c.Request.Context()
+	}
+	if ctx == nil {
+		return ""
+	}
+	traceId, _ := ctx.Value(traceIdContextKey{}).(string)
+	return traceId
+}
@@ -35,6 +35,8 @@ const (
 	APITypeSubmodel
 	APITypeMiniMax
 	APITypeReplicate
-	APITypeReplicate2 // Replicate img2img
-	APITypeDummy      // this one is only for count, do not add any channel after this
+	APITypeReplicate2 // Replicate img2img / text-to-image
+	APITypeFal
+	APITypeStability
+	APITypeDummy // this one is only for count, do not add any channel after this
 )
@@ -0,0 +1,9 @@
+package constant
+
+// 渠道能力标签，用于按请求所需能力筛选渠道，详见 docs/channel/capability_tags.md
+const (
+	CapabilityVision    = "vision"    // 支持图片输入
+	CapabilityStreaming = "streaming" // 支持流式输出
+	CapabilityAudio     = "audio"     // 支持音频输入
+	CapabilityTools     = "tools"     // 支持函数调用
+)
@@ -55,7 +55,9 @@ const (
 	ChannelTypeSora           = 55
 	ChannelTypeReplicate  = 56
 	ChannelTypeVolcVideo  = 101 // 火山视频专用渠道（自定义，避免与上游冲突）
-	ChannelTypeReplicate2 = 102 // Replicate img2img 专用渠道（自定义，避免与上游冲突）
+	ChannelTypeReplicate2 = 102 // Replicate 图生图/文生图专用渠道（自定义，避免与上游冲突）
+	ChannelTypeFal        = 103 // fal.ai 图片/视频渠道（自定义，避免与上游冲突）
+	ChannelTypeStability  = 104 // Stability AI SD3 图片渠道（自定义，避免与上游冲突）
 	ChannelTypeDummy          // this one is only for count, do not add any channel after this
 
 )
@@ -164,6 +166,8 @@ var ChannelBaseURLs = []string{
 	"",                                          //100
 	"https://ark.cn-beijing.volces.com",         //101 VolcVideo（自定义渠道）
 	"https://api.replicate.com",                 //102 Replicate2 img2img（自定义渠道）
+	"https://queue.fal.run",                     //103 fal.ai（自定义渠道）
+	"https://api.stability.ai",                  //104 Stability AI（自定义渠道）
 }
 
 var ChannelTypeNames = map[int]string{
@@ -222,6 +226,8 @@ var ChannelTypeNames = map[int]string{
 	ChannelTypeReplicate:      "Replicate",
 	ChannelTypeVolcVideo:      "VolcVideo",
 	ChannelTypeReplicate2:     "Replicate2",
+	ChannelTypeFal:            "Fal",
+	ChannelTypeStability:      "Stability",
 }
 
 func GetChannelTypeName(channelType int) string {
@@ -6,6 +6,8 @@ const (
 	ContextKeyTokenCountMeta  ContextKey = "token_count_meta"
 	ContextKeyPromptTokens    ContextKey = "prompt_tokens"
 	ContextKeyEstimatedTokens ContextKey = "estimated_tokens"
+	// 请求结束后实际消耗的输入与输出 token 总数，分组 TPM 限流按此计数
+	ContextKeyUsageTotalTokens ContextKey = "usage_total_tokens"
 
 	ContextKeyOriginalModel    ContextKey = "original_model"
 	ContextKeyRequestStartTime ContextKey = "request_start_time"
@@ -19,6 +21,7 @@ const (
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
+	ContextKeyTokenScopes            ContextKey = "token_scopes"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -37,6 +40,7 @@ const (
 	ContextKeyChannelIsMultiKey        ContextKey = "channel_is_multi_key"
 	ContextKeyChannelMultiKeyIndex     ContextKey = "channel_multi_key_index"
 	ContextKeyChannelKey               ContextKey = "channel_key"
+	ContextKeyChannelConcurrencyLimit  ContextKey = "channel_concurrency_limit"
 
 	ContextKeyAutoGroup           ContextKey = "auto_group"
 	ContextKeyAutoGroupIndex      ContextKey = "auto_group_index"
@@ -55,4 +59,26 @@ const (
 	ContextKeyLocalCountTokens ContextKey = "local_count_tokens"
 
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
+
+	// 请求头 X-Cost-Center 指定的成本中心，写入消费日志
+	ContextKeyCostCenter ContextKey = "cost_center"
+
+	// 用户自定义模型（UserModel）对应的上游模型 ID 与单次价格
+	ContextKeyUserModelUpstream ContextKey = "user_model_upstream"
+	ContextKeyUserModelPrice    ContextKey = "user_model_price"
+
+	// 请求所需的渠道能力标签（如 vision），选择渠道时只考虑具备这些能力的渠道
+	ContextKeyRequiredCapabilities ContextKey = "required_capabilities"
+
+	// 流式输出过程中额度耗尽，已发送 [QUOTA_EXCEEDED] 事件，不再向下游写入数据
+	ContextKeyStreamQuotaExceeded ContextKey = "stream_quota_exceeded"
+
+	// 重放失败任务时原始任务的 task_id，写入新任务的 parent_task_id
+	ContextKeyTaskReplayParentId ContextKey = "task_replay_parent_id"
+
+	// 批量提交预留的额度池（*service.TaskQuotaPool），批次内的任务从中取用额度
+	ContextKeyTaskQuotaPool ContextKey = "task_quota_pool"
+
+	// 影子模式镜像提交的对比结果（*model.ShadowResult），存在时本次提交为影子提交，不扣费也不写入任务表
+	ContextKeyTaskShadowResult ContextKey = "task_shadow_result"
 )
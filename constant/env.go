@@ -10,6 +10,9 @@ var GetMediaToken bool
 var GetMediaTokenNotStream bool
 var UpdateTask bool
 var MaxRequestBodyMB int
+var MaxTextRequestBodyMB int
+var MaxImageRequestBodyMB int
+var MaxMultipartRequestBodyMB int
 var AzureDefaultAPIVersion string
 var GeminiVisionMaxImageNum int
 var NotifyLimitCount int
@@ -18,6 +21,27 @@ var GenerateDefaultToken bool
 var ErrorLogEnabled bool
 var TaskQueryLimit int
 var VideoTaskTimeoutMinutes int
+var TaskPlatformTimeoutMinutes = map[string]int{}
+var TaskReplayRetentionDays int
+var QuotaReservationTimeoutMinutes int
+var MaxVideoUploadMB int
+var VideoUploadTTLHours int
+var AsyncImageTimeoutSeconds int
+var ChannelRequestTimeoutSeconds int
+var MetricsToken string
+var MetricsAllowedIps []string
+var TaskErrorI18nFile string
+var TaskStorageEndpoint string
+var TaskStorageBucket string
+var TaskStorageRegion string
+var TaskStorageAccessKeyId string
+var TaskStorageSecretAccessKey string
+var TaskStorageURLTTLSeconds int
+var FfmpegPath string
+var VideoThumbnailServiceURL string
+var VideoThumbnailPosition string
+var StreamQuotaChunkTokens int
+var BillingDiscrepancyThresholdPercent int
 
 // temporary variable for sora patch, will be removed in future
 var TaskPricePatches []string
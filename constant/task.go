@@ -5,6 +5,10 @@ type TaskPlatform string
 const (
 	TaskPlatformSuno       TaskPlatform = "suno"
 	TaskPlatformMidjourney              = "mj"
+	TaskPlatformVolcAudio  TaskPlatform = "volcaudio"
+	TaskPlatformRunway     TaskPlatform = "runway"
+	TaskPlatformLuma       TaskPlatform = "luma"
+	TaskPlatformUdio       TaskPlatform = "udio"
 )
 
 const (
@@ -0,0 +1,18 @@
+package constant
+
+// 令牌权限范围，令牌未配置 scopes 时拥有全部权限
+const (
+	TokenScopeVideoGenerate = "video:generate" // 提交视频生成任务
+	TokenScopeVideoFetch    = "video:fetch"    // 查询任务与获取视频产出
+	TokenScopeImageGenerate = "image:generate" // 图片生成与编辑
+	TokenScopeAudioGenerate = "audio:generate" // 语音合成与音乐生成
+	TokenScopeTaskCancel    = "task:cancel"    // 取消任务
+)
+
+var AllTokenScopes = []string{
+	TokenScopeVideoGenerate,
+	TokenScopeVideoFetch,
+	TokenScopeImageGenerate,
+	TokenScopeAudioGenerate,
+	TokenScopeTaskCancel,
+}
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBillingDiscrepancies 分页查询计费对账发现的差异，可按渠道、模型与任务完成时间筛选
+func GetBillingDiscrepancies(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	filter := model.BillingDiscrepancyFilter{ModelName: c.Query("model_name")}
+	filter.ChannelId, _ = strconv.Atoi(c.Query("channel_id"))
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	discrepancies, total, err := model.GetBillingDiscrepancies(filter, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(discrepancies)
+	common.ApiSuccess(c, pageInfo)
+}
@@ -38,19 +38,31 @@ type testResult struct {
 	context     *gin.Context
 	localErr    error
 	newAPIError *types.NewAPIError
+	latency     time.Duration // 上游请求耗时，不含本地转换
+}
+
+// channelTestOptions 渠道测试的可选参数
+type channelTestOptions struct {
+	Prompt         string // 覆盖默认的测试提示词
+	SkipConsumeLog bool   // 不记录消费日志，用于基准测试等批量请求
+}
+
+var unsupportedTestChannelTypes = []int{
+	constant.ChannelTypeMidjourney,
+	constant.ChannelTypeMidjourneyPlus,
+	constant.ChannelTypeSunoAPI,
+	constant.ChannelTypeKling,
+	constant.ChannelTypeJimeng,
+	constant.ChannelTypeDoubaoVideo,
+	constant.ChannelTypeVidu,
 }
 
 func testChannel(channel *model.Channel, testModel string, endpointType string) testResult {
+	return testChannelWithOptions(channel, testModel, endpointType, channelTestOptions{})
+}
+
+func testChannelWithOptions(channel *model.Channel, testModel string, endpointType string, opts channelTestOptions) testResult {
 	tik := time.Now()
-	var unsupportedTestChannelTypes = []int{
-		constant.ChannelTypeMidjourney,
-		constant.ChannelTypeMidjourneyPlus,
-		constant.ChannelTypeSunoAPI,
-		constant.ChannelTypeKling,
-		constant.ChannelTypeJimeng,
-		constant.ChannelTypeDoubaoVideo,
-		constant.ChannelTypeVidu,
-	}
 	if lo.Contains(unsupportedTestChannelTypes, channel.Type) {
 		channelTypeName := constant.GetChannelTypeName(channel.Type)
 		return testResult{
@@ -182,6 +194,9 @@ func testChannel(channel *model.Channel, testModel string, endpointType string)
 	}
 
 	request := buildTestRequest(testModel, endpointType, channel)
+	if opts.Prompt != "" {
+		applyTestPrompt(request, opts.Prompt)
+	}
 
 	info, err := relaycommon.GenRelayInfo(c, relayFormat, request, nil)
 
@@ -311,7 +326,9 @@ func testChannel(channel *model.Channel, testModel string, endpointType string)
 	}
 	requestBody := bytes.NewBuffer(jsonData)
 	c.Request.Body = io.NopCloser(requestBody)
+	requestStart := time.Now()
 	resp, err := adaptor.DoRequest(c, info, requestBody)
+	latency := time.Since(requestStart)
 	if err != nil {
 		return testResult{
 			context:     c,
@@ -358,7 +375,7 @@ func testChannel(channel *model.Channel, testModel string, endpointType string)
 	}
 	usage := usageA.(*dto.Usage)
 	result := w.Result()
-	respBody, err := io.ReadAll(result.Body)
+	respBody, err := service.ReadCompressedBody(result)
 	if err != nil {
 		return testResult{
 			context:     c,
@@ -383,24 +400,41 @@ func testChannel(channel *model.Channel, testModel string, endpointType string)
 	consumedTime := float64(milliseconds) / 1000.0
 	other := service.GenerateTextOtherInfo(c, info, priceData.ModelRatio, priceData.GroupRatioInfo.GroupRatio, priceData.CompletionRatio,
 		usage.PromptTokensDetails.CachedTokens, priceData.CacheRatio, priceData.ModelPrice, priceData.GroupRatioInfo.GroupSpecialRatio)
-	model.RecordConsumeLog(c, 1, model.RecordConsumeLogParams{
-		ChannelId:        channel.Id,
-		PromptTokens:     usage.PromptTokens,
-		CompletionTokens: usage.CompletionTokens,
-		ModelName:        info.OriginModelName,
-		TokenName:        "模型测试",
-		Quota:            quota,
-		Content:          "模型测试",
-		UseTimeSeconds:   int(consumedTime),
-		IsStream:         info.IsStream,
-		Group:            info.UsingGroup,
-		Other:            other,
-	})
+	if !opts.SkipConsumeLog {
+		model.RecordConsumeLog(c, 1, model.RecordConsumeLogParams{
+			ChannelId:        channel.Id,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			ModelName:        info.OriginModelName,
+			TokenName:        "模型测试",
+			Quota:            quota,
+			Content:          "模型测试",
+			UseTimeSeconds:   int(consumedTime),
+			IsStream:         info.IsStream,
+			Group:            info.UsingGroup,
+			Other:            other,
+		})
+	}
 	common.SysLog(fmt.Sprintf("testing channel #%d, response: \n%s", channel.Id, string(respBody)))
 	return testResult{
 		context:     c,
 		localErr:    nil,
 		newAPIError: nil,
+		latency:     latency,
+	}
+}
+
+// applyTestPrompt 将测试请求中的默认提示词替换为 prompt
+func applyTestPrompt(request dto.Request, prompt string) {
+	switch r := request.(type) {
+	case *dto.GeneralOpenAIRequest:
+		if len(r.Messages) > 0 {
+			r.Messages[0].Content = prompt
+		}
+	case *dto.OpenAIResponsesRequest:
+		if input, err := common.Marshal(prompt); err == nil {
+			r.Input = input
+		}
 	}
 }
 
@@ -637,6 +671,58 @@ func TestAllChannels(c *gin.Context) {
 	})
 }
 
+func init() {
+	service.ChannelProbeRunner = probeChannel
+}
+
+// channelProbeTaskID 任务渠道探活时查询的任务 ID，上游应返回任务不存在
+const channelProbeTaskID = "new-api-probe"
+
+// probeChannel 复用渠道测试逻辑，向渠道发送一次低成本的合成请求；
+// 不支持渠道测试的视频等任务渠道改为查询一个不存在的任务
+func probeChannel(channel *model.Channel) error {
+	if lo.Contains(unsupportedTestChannelTypes, channel.Type) {
+		return probeTaskChannel(channel)
+	}
+	result := testChannel(channel, "", "")
+	if result.localErr != nil {
+		return result.localErr
+	}
+	if result.newAPIError != nil {
+		return result.newAPIError
+	}
+	return nil
+}
+
+// probeTaskChannel 通过任务查询接口确认任务渠道的地址与密钥可用：
+// 请求失败、鉴权失败或上游返回 5xx 视为探活失败，任务不存在等其他响应视为正常
+func probeTaskChannel(channel *model.Channel) error {
+	adaptor := relay.GetTaskAdaptor(constant.TaskPlatform(strconv.Itoa(channel.Type)))
+	if adaptor == nil {
+		return service.ErrChannelProbeUnsupported
+	}
+	baseURL := constant.ChannelBaseURLs[channel.Type]
+	if channel.GetBaseURL() != "" {
+		baseURL = channel.GetBaseURL()
+	}
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return apiErr
+	}
+	resp, err := adaptor.FetchTask(baseURL, key, map[string]any{
+		"task_id": channelProbeTaskID,
+		"action":  constant.TaskActionTextGenerate,
+	}, channel.GetSetting().Proxy)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden || resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("upstream returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
 var autoTestChannelsOnce sync.Once
 
 func AutomaticallyTestChannels() {
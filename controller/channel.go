@@ -2,10 +2,12 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -13,6 +15,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay/channel/gemini"
 	"github.com/QuantumNous/new-api/relay/channel/ollama"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/service"
 
 	"github.com/gin-gonic/gin"
@@ -515,6 +518,52 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// GetChannelHealth 获取渠道最近 24 小时按窗口聚合的健康统计
+func GetChannelHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	since := time.Now().Add(-24 * time.Hour).Unix()
+	stats, err := service.GetChannelHealthStats(id, since)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"channel_id": id,
+		"window":     int64(service.ChannelHealthWindow / time.Second),
+		"windows":    stats,
+	})
+}
+
+// GetChannelShadowResults 获取渠道最近 24 小时影子模式的对比汇总与明细
+func GetChannelShadowResults(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	since := time.Now().Add(-24 * time.Hour).Unix()
+	stats, err := model.GetShadowResultStats(id, since)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	results, err := model.GetShadowResults(id, since, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"channel_id": id,
+		"stats":      stats,
+		"items":      results,
+	})
+}
+
 // GetChannelKey 获取渠道密钥（需要通过安全验证中间件）
 // 此函数依赖 SecureVerificationRequired 中间件，确保用户已通过安全验证
 func GetChannelKey(c *gin.Context) {
@@ -588,6 +637,12 @@ func validateChannel(channel *model.Channel, isAdd bool) error {
 		}
 	}
 
+	if channel.ModelMapping != nil {
+		if err := relaycommon.ValidateModelMapping(*channel.ModelMapping); err != nil {
+			return fmt.Errorf("模型重定向[model mapping] 配置错误：%s", err.Error())
+		}
+	}
+
 	// VertexAI 特殊校验
 	if channel.Type == constant.ChannelTypeVertexAi {
 		if channel.Other == "" {
@@ -852,6 +907,15 @@ func EditTagChannels(c *gin.Context) {
 		})
 		return
 	}
+	if channelTag.ModelMapping != nil {
+		if err := relaycommon.ValidateModelMapping(*channelTag.ModelMapping); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "模型重定向配置错误：" + err.Error(),
+			})
+			return
+		}
+	}
 	if channelTag.ParamOverride != nil {
 		trimmed := strings.TrimSpace(*channelTag.ParamOverride)
 		if trimmed != "" && !json.Valid([]byte(trimmed)) {
@@ -922,6 +986,17 @@ type PatchChannel struct {
 	KeyMode      *string `json:"key_mode"` // 多key模式下密钥覆盖或者追加
 }
 
+// enableChannelAfterProbe 对已保存的渠道配置探活，成功或渠道类型不支持探活时启用渠道
+func enableChannelAfterProbe(channel *model.Channel) error {
+	probeChannel := *channel
+	probeChannel.Status = common.ChannelStatusEnabled
+	if err := service.ChannelHealthProbe(&probeChannel); err != nil && !errors.Is(err, service.ErrChannelProbeUnsupported) {
+		return err
+	}
+	channel.Status = common.ChannelStatusEnabled
+	return channel.Update()
+}
+
 func UpdateChannel(c *gin.Context) {
 	channel := PatchChannel{}
 	err := c.ShouldBindJSON(&channel)
@@ -948,6 +1023,12 @@ func UpdateChannel(c *gin.Context) {
 		return
 	}
 
+	// 启用渠道时可通过 ?probe=true 先探活：先以原状态保存新配置，再用保存后的配置探活，通过后才启用
+	probe := c.Query("probe") == "true" && channel.Status == common.ChannelStatusEnabled && originChannel.Status != common.ChannelStatusEnabled
+	if probe {
+		channel.Status = originChannel.Status
+	}
+
 	// Always copy the original ChannelInfo so that fields like IsMultiKey and MultiKeySize are retained.
 	channel.ChannelInfo = originChannel.ChannelInfo
 
@@ -1041,6 +1122,17 @@ func UpdateChannel(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	if probe {
+		if err := enableChannelAfterProbe(&channel.Channel); err != nil {
+			model.InitChannelCache()
+			service.ResetProxyClientCache()
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "渠道配置已保存，但探活失败，未启用: " + err.Error(),
+			})
+			return
+		}
+	}
 	model.InitChannelCache()
 	service.ResetProxyClientCache()
 	channel.Key = ""
@@ -1288,6 +1380,52 @@ func CopyChannel(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "", "data": gin.H{"id": clone.Id}})
 }
 
+type cloneChannelRequest struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+// CloneChannel 以已有渠道为模板创建新渠道，可替换 key 与名称，新渠道为禁用状态
+func CloneChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req cloneChannelRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	origin, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	clone := origin.Clone()
+	if key := strings.TrimSpace(req.Key); key != "" {
+		clone.SetClonedKey(key)
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		clone.Name = name
+	} else {
+		clone.Name = origin.Name + "_复制"
+	}
+	if err := clone.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.InitChannelCache()
+	service.ResetProxyClientCache()
+
+	clone.Key = ""
+	clearChannelInfo(clone)
+	common.ApiSuccess(c, clone)
+}
+
 // MultiKeyManageRequest represents the request for multi-key management operations
 type MultiKeyManageRequest struct {
 	ChannelId int    `json:"channel_id"`
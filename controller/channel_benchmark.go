@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+const (
+	channelBenchmarkMaxChannels = 10
+	channelBenchmarkMaxN        = 20
+	channelBenchmarkDefaultN    = 5
+	// 每个渠道最多返回的错误信息条数
+	channelBenchmarkMaxErrors = 3
+)
+
+type benchmarkChannelsRequest struct {
+	ChannelIds []int  `json:"channel_ids"`
+	Model      string `json:"model"`
+	Prompt     string `json:"prompt"`
+	N          int    `json:"n"`
+}
+
+// channelBenchmarkResult 单个渠道的基准测试结果，延迟单位为毫秒，只统计成功的请求
+type channelBenchmarkResult struct {
+	ChannelId   int      `json:"channel_id"`
+	ChannelName string   `json:"channel_name"`
+	Success     int      `json:"success"`
+	Failed      int      `json:"failed"`
+	P50         int64    `json:"p50_ms"`
+	P95         int64    `json:"p95_ms"`
+	P99         int64    `json:"p99_ms"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// BenchmarkChannels 向每个渠道并发发送 n 个测试请求，返回按 P50 升序排列的延迟分位数，排在首位的渠道即为推荐渠道。
+// 对话渠道的测试请求不扣除额度也不记录消费日志；任务渠道提交最小化任务后立即取消，不支持取消的任务渠道会返回错误
+func BenchmarkChannels(c *gin.Context) {
+	var req benchmarkChannelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	req.ChannelIds = lo.Uniq(req.ChannelIds)
+	if len(req.ChannelIds) == 0 || len(req.ChannelIds) > channelBenchmarkMaxChannels {
+		common.ApiErrorMsg(c, "channel_ids 数量必须在 1 到 10 之间")
+		return
+	}
+	if req.N <= 0 {
+		req.N = channelBenchmarkDefaultN
+	}
+	if req.N > channelBenchmarkMaxN {
+		common.ApiErrorMsg(c, "n 不能超过 20")
+		return
+	}
+	channels := make([]*model.Channel, 0, len(req.ChannelIds))
+	for _, id := range req.ChannelIds {
+		ch, err := model.GetChannelById(id, true)
+		if err != nil {
+			common.ApiErrorMsg(c, "渠道不存在")
+			return
+		}
+		channels = append(channels, ch)
+	}
+
+	results := make([]*channelBenchmarkResult, len(channels))
+	var wg sync.WaitGroup
+	for i, ch := range channels {
+		wg.Add(1)
+		go func(i int, ch *model.Channel) {
+			defer wg.Done()
+			results[i] = benchmarkChannel(ch, req.Model, req.Prompt, req.N)
+		}(i, ch)
+	}
+	wg.Wait()
+
+	sortChannelBenchmarkResults(results)
+	common.ApiSuccess(c, results)
+}
+
+func benchmarkChannel(ch *model.Channel, modelName string, prompt string, n int) *channelBenchmarkResult {
+	result := &channelBenchmarkResult{ChannelId: ch.Id, ChannelName: ch.Name}
+	latencies := make([]int64, 0, n)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			latency, err := benchmarkChannelOnce(ch, modelName, prompt)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Failed++
+				if len(result.Errors) < channelBenchmarkMaxErrors && !lo.Contains(result.Errors, err.Error()) {
+					result.Errors = append(result.Errors, err.Error())
+				}
+				return
+			}
+			result.Success++
+			latencies = append(latencies, latency.Milliseconds())
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = latencyPercentile(latencies, 0.50)
+	result.P95 = latencyPercentile(latencies, 0.95)
+	result.P99 = latencyPercentile(latencies, 0.99)
+	return result
+}
+
+// benchmarkChannelOnce 发送一次测试请求并返回上游提交耗时
+func benchmarkChannelOnce(ch *model.Channel, modelName string, prompt string) (time.Duration, error) {
+	if lo.Contains(unsupportedTestChannelTypes, ch.Type) {
+		if modelName == "" {
+			if models := warmupModelsForChannel(ch); len(models) > 0 {
+				modelName = models[0]
+			}
+		}
+		if prompt == "" {
+			prompt = operation_setting.GetWarmupPrompt(modelName)
+		}
+		return runWarmupTask(context.Background(), ch, modelName, prompt)
+	}
+	result := testChannelWithOptions(ch, modelName, "", channelTestOptions{Prompt: prompt, SkipConsumeLog: true})
+	if result.localErr != nil {
+		return result.latency, result.localErr
+	}
+	if result.newAPIError != nil {
+		return result.latency, errors.New(result.newAPIError.Error())
+	}
+	return result.latency, nil
+}
+
+// latencyPercentile 对已排序的延迟取最近秩分位数
+func latencyPercentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// sortChannelBenchmarkResults 按 P50 升序排列，全部失败的渠道排在最后
+func sortChannelBenchmarkResults(results []*channelBenchmarkResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if (results[i].Success == 0) != (results[j].Success == 0) {
+			return results[i].Success > 0
+		}
+		return results[i].P50 < results[j].P50
+	})
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelDashboard 管理员获取所有渠道的实时状态概览
+func GetChannelDashboard(c *gin.Context) {
+	dashboard, err := service.GetChannelDashboard()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, dashboard)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelGroups 获取渠道回退链列表
+func GetChannelGroups(c *gin.Context) {
+	groups, err := model.GetAllChannelGroups()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, groups)
+}
+
+// validateChannelGroup 校验名称及渠道 ID 列表
+func validateChannelGroup(g *model.ChannelGroup) string {
+	if g.Name == "" {
+		return "回退链名称不能为空"
+	}
+	ids, err := g.GetChannelIdList()
+	if err != nil {
+		return "channel_ids 必须为渠道 ID 数组"
+	}
+	if len(ids) == 0 {
+		return "回退链至少需要包含一个渠道"
+	}
+	if dup, err := model.IsChannelGroupNameDuplicated(g.Id, g.Name); err != nil {
+		return err.Error()
+	} else if dup {
+		return "回退链名称已存在"
+	}
+	return ""
+}
+
+// CreateChannelGroup 创建渠道回退链
+func CreateChannelGroup(c *gin.Context) {
+	var g model.ChannelGroup
+	if err := c.ShouldBindJSON(&g); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	g.Id = 0
+	if msg := validateChannelGroup(&g); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	if err := g.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &g)
+}
+
+// UpdateChannelGroup 更新渠道回退链
+func UpdateChannelGroup(c *gin.Context) {
+	var g model.ChannelGroup
+	if err := c.ShouldBindJSON(&g); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if g.Id == 0 {
+		common.ApiErrorMsg(c, "缺少回退链 ID")
+		return
+	}
+	if msg := validateChannelGroup(&g); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	if err := g.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &g)
+}
+
+// DeleteChannelGroup 删除渠道回退链
+func DeleteChannelGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteChannelGroupByID(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+)
+
+func TestProbeTaskChannel(t *testing.T) {
+	service.InitHttpClient()
+	status := http.StatusNotFound
+	var gotAuth, gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotPath = r.Header.Get("Authorization"), r.URL.Path
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	baseURL := server.URL
+	channel := &model.Channel{Type: constant.ChannelTypeDoubaoVideo, Key: "sk-video", BaseURL: &baseURL}
+	// 任务不存在说明地址与密钥可用
+	if err := probeChannel(channel); err != nil {
+		t.Fatalf("probe with 404 response failed: %v", err)
+	}
+	if gotAuth != "Bearer sk-video" || gotPath != "/api/v3/contents/generations/tasks/"+channelProbeTaskID {
+		t.Fatalf("unexpected probe request: auth=%q path=%q", gotAuth, gotPath)
+	}
+	for _, status = range []int{http.StatusUnauthorized, http.StatusBadGateway} {
+		if err := probeChannel(channel); err == nil {
+			t.Errorf("probe with status %d should fail", status)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+	"github.com/QuantumNous/new-api/relay/channel"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const channelWarmupHistoryLimit = 10
+
+type warmUpChannelRequest struct {
+	Models []string `json:"models"`
+}
+
+// WarmUpChannel 向渠道提交最小化测试任务并立即取消，用于测量冷启动延迟
+func WarmUpChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req warmUpChannelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(req.Models) == 0 {
+		common.ApiErrorMsg(c, "models is required")
+		return
+	}
+	ch, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	results := make([]*model.ChannelWarmupResult, 0, len(req.Models))
+	for _, modelName := range req.Models {
+		results = append(results, warmUpChannelModel(c.Request.Context(), ch, modelName))
+	}
+	common.ApiSuccess(c, results)
+}
+
+// GetChannelWarmupHistory 获取渠道最近的预热记录
+func GetChannelWarmupHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	results, err := model.GetChannelWarmupHistory(id, channelWarmupHistoryLimit)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, results)
+}
+
+func warmUpChannelModel(ctx context.Context, ch *model.Channel, modelName string) *model.ChannelWarmupResult {
+	result := &model.ChannelWarmupResult{
+		ChannelId: ch.Id,
+		ModelName: modelName,
+		CreatedAt: time.Now().Unix(),
+	}
+	latency, err := runWarmupTask(ctx, ch, modelName, operation_setting.GetWarmupPrompt(modelName))
+	result.LatencyMs = latency.Milliseconds()
+	if err != nil {
+		result.Message = err.Error()
+	} else {
+		result.Success = true
+	}
+	if err := result.Insert(); err != nil {
+		common.SysError(fmt.Sprintf("failed to save warm-up result for channel #%d: %s", ch.Id, err.Error()))
+	}
+	return result
+}
+
+// runWarmupTask 使用预热令牌经由内部请求向指定渠道提交一个最小化任务，随后在上游取消并退回额度，返回提交耗时。
+// 仅支持可以取消任务的渠道，避免预热产生真实的生成费用。
+func runWarmupTask(ctx context.Context, ch *model.Channel, modelName string, prompt string) (time.Duration, error) {
+	adaptor := relay.GetTaskAdaptor(constant.TaskPlatform(strconv.Itoa(ch.Type)))
+	if adaptor == nil {
+		return 0, fmt.Errorf("%s channel does not support task warm-up", constant.GetChannelTypeName(ch.Type))
+	}
+	canceller, ok := adaptor.(channel.TaskCanceller)
+	if !ok {
+		return 0, fmt.Errorf("%s channel does not support task cancellation", constant.GetChannelTypeName(ch.Type))
+	}
+	tokenId := operation_setting.GetWarmupSetting().TokenId
+	if tokenId <= 0 {
+		return 0, errors.New("warm-up token is not configured")
+	}
+	token, err := model.GetTokenById(tokenId)
+	if err != nil {
+		return 0, fmt.Errorf("warm-up token #%d not found: %w", tokenId, err)
+	}
+
+	body, err := common.Marshal(map[string]any{
+		"model":  modelName,
+		"prompt": prompt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := serveInternalRelay(ctx, internalRelayRequest{
+		Path:       "/v1/video/generations",
+		Body:       body,
+		Header:     http.Header{"Authorization": []string{"Bearer sk-" + token.Key}},
+		RemoteAddr: "127.0.0.1:0",
+		Values: map[constant.ContextKey]any{
+			constant.ContextKeyTokenSpecificChannelId: strconv.Itoa(ch.Id),
+		},
+	})
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+	if !resp.Success() {
+		var taskErr dto.TaskError
+		if common.Unmarshal(resp.Body(), &taskErr) == nil && taskErr.Message != "" {
+			return latency, fmt.Errorf("submit returned status %d: %s", resp.StatusCode(), taskErr.Message)
+		}
+		return latency, fmt.Errorf("submit returned status %d", resp.StatusCode())
+	}
+	var submitted struct {
+		TaskID string `json:"task_id"`
+		ID     string `json:"id"`
+	}
+	if err := common.Unmarshal(resp.Body(), &submitted); err != nil {
+		return latency, fmt.Errorf("decode submit response failed: %w", err)
+	}
+	taskID := submitted.TaskID
+	if taskID == "" {
+		taskID = submitted.ID
+	}
+	task, exist, err := model.GetByTaskId(token.UserId, taskID)
+	if err != nil {
+		return latency, err
+	}
+	if !exist {
+		return latency, fmt.Errorf("warm-up task %q not found", taskID)
+	}
+
+	baseURL := constant.ChannelBaseURLs[ch.Type]
+	if ch.GetBaseURL() != "" {
+		baseURL = ch.GetBaseURL()
+	}
+	// 任务只为部分渠道记录提交所用的 key，未记录时使用渠道当前可用的 key
+	key := task.PrivateData.Key
+	if key == "" {
+		var newAPIError *types.NewAPIError
+		if key, _, newAPIError = ch.GetNextEnabledKey(); newAPIError != nil {
+			return latency, newAPIError
+		}
+	}
+	if err := canceller.CancelTask(baseURL, key, task.TaskID, ch.GetSetting().Proxy); err != nil {
+		common.SysError(fmt.Sprintf("channel #%d warm-up task %s submitted but not cancelled: %s", ch.Id, task.TaskID, err.Error()))
+		return latency, fmt.Errorf("warm-up task %s was submitted but could not be cancelled: %w", task.TaskID, err)
+	}
+	if _, err := cancelTask(ctx, task, model.TaskCancelReasonWarmup, fmt.Sprintf("Warm-up task on channel #%d cancelled", ch.Id)); err != nil {
+		return latency, err
+	}
+	return latency, nil
+}
+
+var autoWarmupChannelsOnce sync.Once
+
+// AutomaticallyWarmUpChannels 按 warmup_setting.cron 定时预热配置的渠道
+func AutomaticallyWarmUpChannels() {
+	// 只在Master节点定时预热渠道
+	if !common.IsMasterNode {
+		return
+	}
+	autoWarmupChannelsOnce.Do(func() {
+		for {
+			now := time.Now()
+			// 对齐到下一分钟
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+
+			setting := operation_setting.GetWarmupSetting()
+			if !setting.Enabled || len(setting.ChannelIds) == 0 {
+				continue
+			}
+			schedule, err := common.ParseCron(setting.Cron)
+			if err != nil {
+				common.SysError("invalid warm-up cron expression: " + err.Error())
+				continue
+			}
+			if !schedule.Match(time.Now()) {
+				continue
+			}
+			common.SysLog("automatically warming up channels")
+			for _, channelId := range setting.ChannelIds {
+				ch, err := model.GetChannelById(channelId, true)
+				if err != nil {
+					common.SysError(fmt.Sprintf("warm-up channel #%d not found: %s", channelId, err.Error()))
+					continue
+				}
+				if ch.Status != common.ChannelStatusEnabled {
+					continue
+				}
+				for _, modelName := range warmupModelsForChannel(ch) {
+					result := warmUpChannelModel(context.Background(), ch, modelName)
+					common.SysLog(fmt.Sprintf("channel #%d warm-up %s: success=%t latency=%dms", ch.Id, modelName, result.Success, result.LatencyMs))
+				}
+			}
+			common.SysLog("automatically channel warm-up finished")
+		}
+	})
+}
+
+// warmupModelsForChannel 优先使用配置了预热提示词的模型，否则使用渠道测试模型
+func warmupModelsForChannel(ch *model.Channel) []string {
+	prompts := operation_setting.GetWarmupSetting().TestPrompts
+	models := make([]string, 0)
+	for _, m := range ch.GetModels() {
+		if _, ok := prompts[m]; ok {
+			models = append(models, m)
+		}
+	}
+	if len(models) > 0 {
+		return models
+	}
+	if ch.TestModel != nil && *ch.TestModel != "" {
+		return []string{*ch.TestModel}
+	}
+	if all := ch.GetModels(); len(all) > 0 {
+		return all[:1]
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunWarmupTask(t *testing.T) {
+	setupTestDB(t, &model.User{}, &model.Token{}, &model.Task{}, &model.Log{})
+	gin.SetMode(gin.TestMode)
+	service.InitHttpClient()
+	user := &model.User{Id: 1, Username: "warmup", Password: "password", Quota: 0}
+	token := &model.Token{Id: 1, UserId: 1, Key: "warmupkey", Name: "warmup", Status: common.TokenStatusEnabled}
+	for _, row := range []any{user, token} {
+		if err := model.DB.Create(row).Error; err != nil {
+			t.Fatalf("create row failed: %v", err)
+		}
+	}
+	warmupSetting := operation_setting.GetWarmupSetting()
+	originTokenId := warmupSetting.TokenId
+	warmupSetting.TokenId = token.Id
+	t.Cleanup(func() { warmupSetting.TokenId = originTokenId })
+
+	var cancelledPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			cancelledPath = r.URL.Path
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	ch := &model.Channel{Id: 3, Type: constant.ChannelTypeVolcVideo, Key: "ark-key", BaseURL: &upstream.URL, Status: common.ChannelStatusEnabled}
+
+	// 内部请求按普通任务提交流程处理：指定渠道、使用预热令牌鉴权并写入任务
+	var gotChannel, gotAuth string
+	engine := gin.New()
+	engine.Use(middleware.InternalRequestValues())
+	engine.POST("/v1/video/generations", func(c *gin.Context) {
+		gotChannel = common.GetContextKeyString(c, constant.ContextKeyTokenSpecificChannelId)
+		gotAuth = c.GetHeader("Authorization")
+		createTestTask(t, &model.Task{TaskID: "cgt-1", UserId: 1, ChannelId: ch.Id, Quota: 100, Status: model.TaskStatusSubmitted})
+		c.JSON(http.StatusOK, gin.H{"task_id": "cgt-1"})
+	})
+	SetInternalRelayHandler(engine)
+	t.Cleanup(func() { SetInternalRelayHandler(nil) })
+
+	if _, err := runWarmupTask(context.Background(), ch, "doubao-seedance-1-0-pro-250528", "a cat"); err != nil {
+		t.Fatalf("runWarmupTask failed: %v", err)
+	}
+	if gotChannel != "3" || gotAuth != "Bearer sk-warmupkey" {
+		t.Fatalf("internal request channel=%q auth=%q", gotChannel, gotAuth)
+	}
+	if cancelledPath != "/api/v3/contents/generations/tasks/cgt-1" {
+		t.Fatalf("upstream cancel path = %q", cancelledPath)
+	}
+	var task model.Task
+	if err := model.DB.Where("task_id = ?", "cgt-1").First(&task).Error; err != nil {
+		t.Fatalf("load task failed: %v", err)
+	}
+	if task.Status != model.TaskStatusFailure || task.FailReason != model.TaskCancelReasonWarmup {
+		t.Fatalf("task status = %s, reason = %q", task.Status, task.FailReason)
+	}
+	if refunded, _ := model.GetUserQuota(1, true); refunded != 100 {
+		t.Fatalf("refunded quota = %d, want 100", refunded)
+	}
+
+	// 未配置预热令牌时不提交
+	warmupSetting.TokenId = 0
+	if _, err := runWarmupTask(context.Background(), ch, "doubao-seedance-1-0-pro-250528", "a cat"); err == nil {
+		t.Fatal("expected error without warm-up token")
+	}
+}
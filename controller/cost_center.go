@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCostCenterReportWindow = 30 * 24 * time.Hour
+
+// parseCostCenterReportTime 解析 from / to 参数，支持 Unix 时间戳或 YYYY-MM-DD 日期（to 取当天结束）
+func parseCostCenterReportTime(value string, endOfDay bool) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ts, nil
+	}
+	day, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间: %s", value)
+	}
+	if endOfDay {
+		return day.Add(24*time.Hour).Unix() - 1, nil
+	}
+	return day.Unix(), nil
+}
+
+func getCostCenterReportRange(c *gin.Context) (int64, int64, error) {
+	from, err := parseCostCenterReportTime(c.Query("from"), false)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := parseCostCenterReportTime(c.Query("to"), true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to <= 0 {
+		to = time.Now().Unix()
+	}
+	if from <= 0 {
+		from = to - int64(defaultCostCenterReportWindow/time.Second)
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("from 不能大于 to")
+	}
+	return from, to, nil
+}
+
+func respondCostCenterReport(c *gin.Context, userId int) {
+	from, to, err := getCostCenterReportRange(c)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	usages, err := model.GetCostCenterUsage(from, to, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"from":         from,
+		"to":           to,
+		"cost_centers": usages,
+	})
+}
+
+// GetCostCenterReport 管理员按成本中心汇总消费额度，可通过 user_id 筛选用户
+func GetCostCenterReport(c *gin.Context) {
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	respondCostCenterReport(c, userId)
+}
+
+// GetSelfCostCenterReport 用户按成本中心汇总自己的消费额度
+func GetSelfCostCenterReport(c *gin.Context) {
+	respondCostCenterReport(c, c.GetInt("id"))
+}
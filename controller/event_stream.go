@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const eventStreamBufferSize = 256
+
+// GetEventStream streams events published on events.DefaultBus to an admin client via SSE.
+func GetEventStream(c *gin.Context) {
+	sub := events.NewChanSubscriber(eventStreamBufferSize)
+	unsubscribe := events.DefaultBus.Subscribe(sub)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case event := <-sub.C:
+			data, err := events.Marshal(event)
+			if err != nil {
+				common.SysError("failed to marshal event: " + err.Error())
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type(), string(data))
+			c.Writer.Flush()
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	vertexcore "github.com/QuantumNous/new-api/relay/channel/vertex"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var errVideoUploadTooLarge = errors.New("file exceeds the maximum upload size")
+
+// limitedUploadReader 读取超过 limit 字节时返回错误，使上游上传中断而不是截断文件
+type limitedUploadReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedUploadReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, errVideoUploadTooLarge
+	}
+	return n, err
+}
+
+func fileUploadError(c *gin.Context, status int, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "invalid_request_error",
+		},
+	})
+}
+
+// selectVideoUploadChannel 为上传选择支持该模型且配置了 GCS 存储桶的 Vertex AI 渠道
+func selectVideoUploadChannel(c *gin.Context, modelName string) (*model.Channel, error) {
+	if common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		limits, _ := common.GetContextKey(c, constant.ContextKeyTokenModelLimit)
+		tokenModelLimit, _ := limits.(map[string]bool)
+		if _, ok := tokenModelLimit[ratio_setting.FormatMatchingModelName(modelName)]; !ok {
+			return nil, fmt.Errorf("该令牌无权访问模型 %s", modelName)
+		}
+	}
+	channel, _, err := service.CacheGetRandomSatisfiedChannel(&service.RetryParam{
+		Ctx:        c,
+		ModelName:  modelName,
+		TokenGroup: common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Retry:      common.GetPointer(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, fmt.Errorf("no available channel for model %s", modelName)
+	}
+	if channel.Type != constant.ChannelTypeVertexAi {
+		return nil, errors.New("file upload is only supported for Vertex AI channels")
+	}
+	otherSettings := channel.GetOtherSettings()
+	if otherSettings.VertexKeyType == dto.VertexKeyTypeAPIKey {
+		return nil, errors.New("file upload requires a service account key")
+	}
+	if otherSettings.VertexGcsBucket == "" {
+		return nil, errors.New("the channel has no GCS bucket configured")
+	}
+	return channel, nil
+}
+
+// UploadVideoFile 将 multipart 上传的视频流式转存到渠道配置的 GCS 存储桶，返回可用于任务提交 video 字段的 gs:// URI。
+// 模型名通过 ?model= 或位于文件之前的 model 表单字段指定
+func UploadVideoFile(c *gin.Context) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		fileUploadError(c, http.StatusBadRequest, "request must be multipart/form-data")
+		return
+	}
+	modelName := c.Query("model")
+	var filePart *multipart.Part
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fileUploadError(c, http.StatusBadRequest, "invalid multipart body: "+err.Error())
+			return
+		}
+		if part.FormName() == "model" && part.FileName() == "" {
+			value, _ := io.ReadAll(io.LimitReader(part, 256))
+			modelName = strings.TrimSpace(string(value))
+			continue
+		}
+		if part.FormName() == "file" {
+			filePart = part
+			break
+		}
+	}
+	if filePart == nil {
+		fileUploadError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if modelName == "" {
+		fileUploadError(c, http.StatusBadRequest, "model is required and must be sent before the file")
+		return
+	}
+
+	contentType := filePart.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "video/") {
+		fileUploadError(c, http.StatusBadRequest, "only video files are supported")
+		return
+	}
+	// 按文件内容再次校验，防止伪造 Content-Type
+	buffered := bufio.NewReaderSize(filePart, 512)
+	head, _ := buffered.Peek(512)
+	if sniffed := http.DetectContentType(head); !strings.HasPrefix(sniffed, "video/") && sniffed != "application/octet-stream" {
+		fileUploadError(c, http.StatusBadRequest, "file content is not a video")
+		return
+	}
+
+	channel, err := selectVideoUploadChannel(c, modelName)
+	if err != nil {
+		fileUploadError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		fileUploadError(c, http.StatusServiceUnavailable, apiErr.Error())
+		return
+	}
+	creds := vertexcore.Credentials{}
+	if err := common.Unmarshal([]byte(key), &creds); err != nil {
+		fileUploadError(c, http.StatusInternalServerError, "invalid channel credentials")
+		return
+	}
+
+	// 路径中包含渠道 ID，提交任务时据此回到持有该存储桶的渠道
+	object := fmt.Sprintf("uploads/%d/%d/%s%s", c.GetInt("id"), channel.Id, common.GetUUID(), strings.ToLower(path.Ext(filePart.FileName())))
+	maxBytes := int64(constant.MaxVideoUploadMB) << 20
+	body := &limitedUploadReader{r: buffered, limit: maxBytes}
+	uri, err := vertexcore.UploadToGCS(creds, channel.GetSetting().Proxy, channel.GetOtherSettings().VertexGcsBucket, object, contentType, body)
+	if err != nil {
+		if errors.Is(err, errVideoUploadTooLarge) || body.read > maxBytes {
+			fileUploadError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds the maximum upload size of %d MB", constant.MaxVideoUploadMB))
+			return
+		}
+		logger.LogError(c, fmt.Sprintf("upload video to channel #%d failed: %s", channel.Id, err.Error()))
+		fileUploadError(c, http.StatusBadGateway, "failed to upload file")
+		return
+	}
+	upload := &model.VideoUpload{
+		UserId:    c.GetInt("id"),
+		TokenId:   c.GetInt("token_id"),
+		ChannelId: channel.Id,
+		Bucket:    channel.GetOtherSettings().VertexGcsBucket,
+		Object:    object,
+		Bytes:     body.read,
+	}
+	if err := upload.Insert(); err != nil {
+		// 没有记录的对象无法被清理任务删除，直接回滚本次上传
+		logger.LogError(c, fmt.Sprintf("record video upload %s failed: %s", uri, err.Error()))
+		if err := vertexcore.DeleteFromGCS(creds, channel.GetSetting().Proxy, upload.Bucket, object); err != nil {
+			logger.LogError(c, fmt.Sprintf("delete unrecorded video upload %s failed: %s", uri, err.Error()))
+		}
+		fileUploadError(c, http.StatusInternalServerError, "failed to record uploaded file")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"uri":          uri,
+		"bytes":        body.read,
+		"content_type": contentType,
+		"expires_at":   upload.CreatedAt + int64(constant.VideoUploadTTLHours)*3600,
+	})
+}
+
+const videoUploadCleanupBatchSize = 100
+
+var autoCleanupVideoUploadsOnce sync.Once
+
+// AutomaticallyCleanupVideoUploads 每小时删除超过 VIDEO_UPLOAD_TTL_HOURS 的上传对象，仅在 Master 节点运行
+func AutomaticallyCleanupVideoUploads() {
+	if !common.IsMasterNode || constant.VideoUploadTTLHours <= 0 {
+		return
+	}
+	autoCleanupVideoUploadsOnce.Do(func() {
+		for {
+			before := time.Now().Add(-time.Duration(constant.VideoUploadTTLHours) * time.Hour).Unix()
+			cleanupExpiredVideoUploads(before, deleteVideoUploadObject)
+			time.Sleep(time.Hour)
+		}
+	})
+}
+
+// cleanupExpiredVideoUploads 删除创建时间早于 before 的上传对象及其记录，删除对象失败的记录保留到下一轮重试
+func cleanupExpiredVideoUploads(before int64, deleteObject func(upload *model.VideoUpload) error) (deleted int) {
+	for {
+		uploads, err := model.GetExpiredVideoUploads(before, videoUploadCleanupBatchSize)
+		if err != nil {
+			common.SysError("failed to load expired video uploads: " + err.Error())
+			return deleted
+		}
+		batchDeleted := 0
+		for _, upload := range uploads {
+			if err := deleteObject(upload); err != nil {
+				common.SysError(fmt.Sprintf("failed to delete video upload gs://%s/%s: %s", upload.Bucket, upload.Object, err.Error()))
+				continue
+			}
+			if err := model.DeleteVideoUploadById(upload.Id); err != nil {
+				common.SysError(fmt.Sprintf("failed to delete video upload record #%d: %s", upload.Id, err.Error()))
+				continue
+			}
+			batchDeleted++
+		}
+		deleted += batchDeleted
+		// 本批没有可删除的记录时停止，避免反复读取同一批失败记录
+		if len(uploads) < videoUploadCleanupBatchSize || batchDeleted == 0 {
+			return deleted
+		}
+	}
+}
+
+// deleteVideoUploadObject 使用上传时所在渠道的凭据删除 GCS 对象，渠道已删除时只清理记录
+func deleteVideoUploadObject(upload *model.VideoUpload) error {
+	channel, err := model.GetChannelById(upload.ChannelId, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.SysLog(fmt.Sprintf("channel #%d of video upload gs://%s/%s no longer exists, dropping the record", upload.ChannelId, upload.Bucket, upload.Object))
+			return nil
+		}
+		return err
+	}
+	key, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return apiErr
+	}
+	creds := vertexcore.Credentials{}
+	if err := common.Unmarshal([]byte(key), &creds); err != nil {
+		return fmt.Errorf("invalid channel credentials: %w", err)
+	}
+	return vertexcore.DeleteFromGCS(creds, channel.GetSetting().Proxy, upload.Bucket, upload.Object)
+}
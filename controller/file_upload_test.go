@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestCleanupExpiredVideoUploadsKeepsFailedAndFreshUploads(t *testing.T) {
+	setupTestDB(t, &model.VideoUpload{})
+	expired := &model.VideoUpload{UserId: 1, TokenId: 1, ChannelId: 1, Bucket: "b", Object: "uploads/1/expired.mp4", CreatedAt: 100}
+	failing := &model.VideoUpload{UserId: 1, TokenId: 1, ChannelId: 1, Bucket: "b", Object: "uploads/1/failing.mp4", CreatedAt: 200}
+	fresh := &model.VideoUpload{UserId: 1, TokenId: 1, ChannelId: 1, Bucket: "b", Object: "uploads/1/fresh.mp4", CreatedAt: 2000}
+	for _, upload := range []*model.VideoUpload{expired, failing, fresh} {
+		if err := upload.Insert(); err != nil {
+			t.Fatalf("insert upload failed: %v", err)
+		}
+	}
+
+	var deletedObjects []string
+	deleted := cleanupExpiredVideoUploads(1000, func(upload *model.VideoUpload) error {
+		if upload.Id == failing.Id {
+			return errors.New("gcs unavailable")
+		}
+		deletedObjects = append(deletedObjects, upload.Object)
+		return nil
+	})
+	if deleted != 1 || len(deletedObjects) != 1 || deletedObjects[0] != expired.Object {
+		t.Fatalf("expected only the expired object to be deleted, got %d %v", deleted, deletedObjects)
+	}
+
+	var remaining []model.VideoUpload
+	if err := model.DB.Order("id").Find(&remaining).Error; err != nil {
+		t.Fatalf("load uploads failed: %v", err)
+	}
+	if len(remaining) != 2 || remaining[0].Id != failing.Id || remaining[1].Id != fresh.Id {
+		t.Fatalf("expected the failed and fresh uploads to remain, got %+v", remaining)
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+)
+
+// internalRelayHandler 处理服务内部发起的请求（批量提交、任务重放、依赖任务等），
+// 由路由初始化时设置为服务自身的 gin 引擎，使内部请求与外部请求经过相同的鉴权、分发与计费流程
+var internalRelayHandler http.Handler
+
+// SetInternalRelayHandler 设置处理内部请求的路由
+func SetInternalRelayHandler(handler http.Handler) {
+	internalRelayHandler = handler
+}
+
+// internalRelayRequest 服务内部发起、经由自身路由处理的请求
+type internalRelayRequest struct {
+	Path string
+	Body []byte
+	// Header 鉴权等请求头，通常复制自原始请求
+	Header     http.Header
+	RemoteAddr string
+	// Values 写入内部请求 gin 上下文的键值
+	Values map[constant.ContextKey]any
+}
+
+// internalRelayResponse 在内存中保存内部请求的响应
+type internalRelayResponse struct {
+	header http.Header
+	body   bytes.Buffer
+	code   int
+}
+
+func (w *internalRelayResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *internalRelayResponse) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+func (w *internalRelayResponse) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+// Flush 流式响应会调用 Flush，内部请求只需缓存完整响应
+func (w *internalRelayResponse) Flush() {}
+
+// StatusCode 返回响应状态码，未写入时为 200
+func (w *internalRelayResponse) StatusCode() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// Success 判断响应是否为 2xx
+func (w *internalRelayResponse) Success() bool {
+	code := w.StatusCode()
+	return code >= 200 && code < 300
+}
+
+// Body 返回响应体
+func (w *internalRelayResponse) Body() []byte {
+	return w.body.Bytes()
+}
+
+// serveInternalRelay 以 POST JSON 请求的形式经由服务自身的路由处理内部请求
+func serveInternalRelay(ctx context.Context, r internalRelayRequest) (*internalRelayResponse, error) {
+	if internalRelayHandler == nil {
+		return nil, errors.New("internal relay handler is not configured")
+	}
+	if len(r.Values) > 0 {
+		ctx = common.WithInternalRequestValues(ctx, r.Values)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Path, bytes.NewReader(r.Body))
+	if err != nil {
+		return nil, err
+	}
+	if r.Header != nil {
+		req.Header = r.Header.Clone()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Del("Content-Length")
+	req.Header.Del("Accept-Encoding")
+	req.ContentLength = int64(len(r.Body))
+	req.RemoteAddr = r.RemoteAddr
+	resp := &internalRelayResponse{header: make(http.Header)}
+	internalRelayHandler.ServeHTTP(resp, req)
+	return resp, nil
+}
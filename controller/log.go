@@ -167,3 +167,27 @@ func DeleteHistoryLogs(c *gin.Context) {
 	})
 	return
 }
+
+func getLogBillingDetail(c *gin.Context, userId int) {
+	logId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	snapshot, err := model.GetBillingSnapshotByLogId(logId, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, snapshot)
+}
+
+// GetLogBillingDetail 获取任意消费日志的计费快照
+func GetLogBillingDetail(c *gin.Context) {
+	getLogBillingDetail(c, 0)
+}
+
+// GetUserLogBillingDetail 获取当前用户消费日志的计费快照
+func GetUserLogBillingDetail(c *gin.Context) {
+	getLogBillingDetail(c, c.GetInt("id"))
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"time"
 
@@ -103,7 +102,7 @@ func UpdateMidjourneyTaskBulk() {
 				logger.LogError(ctx, fmt.Sprintf("Get Task status code: %d", resp.StatusCode))
 				continue
 			}
-			responseBody, err := io.ReadAll(resp.Body)
+			responseBody, err := service.ReadCompressedBody(resp)
 			if err != nil {
 				logger.LogError(ctx, fmt.Sprintf("Get Task parse body error: %v", err))
 				continue
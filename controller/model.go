@@ -8,6 +8,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/relay/channel/ai360"
@@ -202,6 +203,8 @@ func ListModels(c *gin.Context, modelType int) {
 		}
 	}
 
+	userOpenAiModels = annotateModelAliases(c, userOpenAiModels)
+
 	switch modelType {
 	case constant.ChannelTypeAnthropic:
 		useranthropicModels := make([]dto.AnthropicModel, len(userOpenAiModels))
@@ -240,6 +243,29 @@ func ListModels(c *gin.Context, modelType int) {
 	}
 }
 
+// annotateModelAliases 为可路由的全局模型别名标注目标模型。别名须有渠道直接提供，
+// 且目标模型在当前分组有启用的渠道，否则不标注
+func annotateModelAliases(c *gin.Context, models []dto.OpenAIModels) []dto.OpenAIModels {
+	aliases, err := model.GetCachedModelAliases()
+	if err != nil {
+		logger.LogError(c, "get model aliases failed: "+err.Error())
+		return models
+	}
+	index := make(map[string]int, len(models))
+	for i, m := range models {
+		index[m.Id] = i
+	}
+	for _, alias := range aliases {
+		// 别名按自身名称选择渠道，目标模型也须在当前分组有启用的渠道，否则请求无法路由
+		i, routable := index[alias.Alias]
+		if _, enabled := index[alias.TargetModel]; !routable || !enabled {
+			continue
+		}
+		models[i].Root = alias.TargetModel
+	}
+	return models
+}
+
 func ChannelListModels(c *gin.Context) {
 	c.JSON(200, gin.H{
 		"success": true,
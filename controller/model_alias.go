@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetModelAliases 获取全局模型别名列表
+func GetModelAliases(c *gin.Context) {
+	aliases, err := model.GetAllModelAliases()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, aliases)
+}
+
+// validateModelAlias 校验别名、目标模型及唯一性
+func validateModelAlias(a *model.ModelAlias) string {
+	a.Alias = strings.TrimSpace(a.Alias)
+	a.TargetModel = strings.TrimSpace(a.TargetModel)
+	if a.Alias == "" || a.TargetModel == "" {
+		return "alias 和 target_model 不能为空"
+	}
+	if a.Alias == a.TargetModel {
+		return "别名不能指向自身"
+	}
+	if a.ChannelType < 0 {
+		return "无效的渠道类型"
+	}
+	if a.PinnedUntil < 0 {
+		return "pinned_until 不能为负数"
+	}
+	if dup, err := model.IsModelAliasDuplicated(a.Id, a.Alias, a.ChannelType); err != nil {
+		return err.Error()
+	} else if dup {
+		return "该渠道类型下别名已存在"
+	}
+	return ""
+}
+
+// CreateModelAlias 创建全局模型别名
+func CreateModelAlias(c *gin.Context) {
+	var a model.ModelAlias
+	if err := c.ShouldBindJSON(&a); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	a.Id = 0
+	if msg := validateModelAlias(&a); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	if err := a.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &a)
+}
+
+// UpdateModelAlias 更新全局模型别名，锁定期内不允许修改别名或目标模型
+func UpdateModelAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var a model.ModelAlias
+	if err := c.ShouldBindJSON(&a); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	a.Id = id
+	if msg := validateModelAlias(&a); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	if err := a.Update(); err != nil {
+		if errors.Is(err, model.ErrModelAliasPinned) {
+			common.ApiErrorMsg(c, "别名处于锁定期，无法修改目标模型")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &a)
+}
+
+// DeleteModelAlias 删除全局模型别名，锁定期内不允许删除
+func DeleteModelAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteModelAliasByID(id); err != nil {
+		if errors.Is(err, model.ErrModelAliasPinned) {
+			common.ApiErrorMsg(c, "别名处于锁定期，无法删除")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAnnotateModelAliases(t *testing.T) {
+	setupTestDB(t, &model.ModelAlias{})
+	for _, a := range []*model.ModelAlias{
+		{Alias: "kling-latest", TargetModel: "kling-v2-1"},
+		{Alias: "veo-latest", TargetModel: "veo-3.0"},
+		{Alias: "sora-latest", TargetModel: "sora-2"},
+	} {
+		if err := a.Insert(); err != nil {
+			t.Fatalf("insert alias failed: %v", err)
+		}
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	models := annotateModelAliases(c, []dto.OpenAIModels{
+		{Id: "kling-v2-1"},
+		{Id: "kling-latest"},
+		{Id: "veo-latest"},
+		{Id: "sora-2"},
+	})
+
+	// 别名不在列表中时无法路由，不追加；目标模型无启用渠道时不标注
+	if len(models) != 4 {
+		t.Fatalf("len(models) = %d, want 4", len(models))
+	}
+	roots := make(map[string]string, len(models))
+	for _, m := range models {
+		roots[m.Id] = m.Root
+	}
+	if roots["kling-latest"] != "kling-v2-1" {
+		t.Errorf("kling-latest root = %q, want kling-v2-1", roots["kling-latest"])
+	}
+	if roots["veo-latest"] != "" {
+		t.Errorf("veo-latest root = %q, want empty", roots["veo-latest"])
+	}
+}
@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v81"
+	"github.com/stripe/stripe-go/v81/checkout/session"
+)
+
+// StripeQuotaPackage 可通过 Stripe Checkout 购买的额度套餐，Price 为货币主单位金额
+type StripeQuotaPackage struct {
+	Id       string  `json:"id"`
+	Name     string  `json:"name"`
+	Quota    int     `json:"quota"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+}
+
+type CreateCheckoutSessionRequest struct {
+	PackageId string `json:"package_id"`
+}
+
+func getStripeQuotaPackages() ([]StripeQuotaPackage, error) {
+	var packages []StripeQuotaPackage
+	if err := json.Unmarshal([]byte(setting.StripeQuotaPackages), &packages); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+func GetStripeQuotaPackages(c *gin.Context) {
+	packages, err := getStripeQuotaPackages()
+	if err != nil {
+		common.ApiErrorMsg(c, "套餐配置错误")
+		return
+	}
+	common.ApiSuccess(c, packages)
+}
+
+func CreateStripeCheckoutSession(c *gin.Context) {
+	var req CreateCheckoutSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.PackageId == "" {
+		common.ApiErrorMsg(c, "请选择套餐")
+		return
+	}
+	packages, err := getStripeQuotaPackages()
+	if err != nil {
+		log.Println("解析Stripe额度套餐失败", err)
+		common.ApiErrorMsg(c, "套餐配置错误")
+		return
+	}
+	var selected *StripeQuotaPackage
+	for i := range packages {
+		if packages[i].Id == req.PackageId {
+			selected = &packages[i]
+			break
+		}
+	}
+	if selected == nil {
+		common.ApiErrorMsg(c, "套餐不存在")
+		return
+	}
+	if selected.Quota <= 0 || selected.Price <= 0 {
+		common.ApiErrorMsg(c, "套餐配置错误")
+		return
+	}
+	user, err := model.GetUserById(c.GetInt("id"), false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	result, err := genStripePackageSession(user, selected)
+	if err != nil {
+		log.Println("创建Stripe Checkout会话失败", err)
+		common.ApiErrorMsg(c, "拉起支付失败")
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"session_id": result.ID,
+		"url":        result.URL,
+	})
+}
+
+// genStripePackageSession 按套餐价格创建一次性支付的 Checkout Session，用户与套餐信息写入 metadata 供回调入账
+func genStripePackageSession(user *model.User, pkg *StripeQuotaPackage) (*stripe.CheckoutSession, error) {
+	if !strings.HasPrefix(setting.StripeApiSecret, "sk_") && !strings.HasPrefix(setting.StripeApiSecret, "rk_") {
+		return nil, fmt.Errorf("无效的Stripe API密钥")
+	}
+	stripe.Key = setting.StripeApiSecret
+
+	currency := strings.ToLower(pkg.Currency)
+	if currency == "" {
+		currency = string(stripe.CurrencyUSD)
+	}
+	params := &stripe.CheckoutSessionParams{
+		ClientReferenceID: stripe.String(strconv.Itoa(user.Id)),
+		SuccessURL:        stripe.String(system_setting.ServerAddress + "/console/log"),
+		CancelURL:         stripe.String(system_setting.ServerAddress + "/console/topup"),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{
+			{
+				PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+					Currency:   stripe.String(currency),
+					UnitAmount: stripe.Int64(int64(math.Round(pkg.Price * 100))),
+					ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+						Name: stripe.String(pkg.Name),
+					},
+				},
+				Quantity: stripe.Int64(1),
+			},
+		},
+		Mode: stripe.String(string(stripe.CheckoutSessionModePayment)),
+	}
+	params.AddMetadata("user_id", strconv.Itoa(user.Id))
+	params.AddMetadata("package_id", pkg.Id)
+	params.AddMetadata("quota", strconv.Itoa(pkg.Quota))
+
+	if user.StripeCustomer == "" {
+		if user.Email != "" {
+			params.CustomerEmail = stripe.String(user.Email)
+		}
+		params.CustomerCreation = stripe.String(string(stripe.CheckoutSessionCustomerCreationAlways))
+	} else {
+		params.Customer = stripe.String(user.StripeCustomer)
+	}
+	return session.New(params)
+}
+
+// stripePackageSessionCompleted 处理 metadata 中带有 package_id 的套餐 Checkout 会话，
+// 入账失败时返回错误，由 StripeWebhook 返回 5xx 让 Stripe 重试
+func stripePackageSessionCompleted(event stripe.Event) error {
+	var checkoutSession stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &checkoutSession); err != nil {
+		log.Printf("解析Stripe Checkout会话失败: %v\n", err)
+		return nil
+	}
+	return creditStripePackageSession(&checkoutSession)
+}
+
+// creditStripePackageSession 为已支付的套餐会话入账并保存 Stripe 客户，重复回调直接忽略。
+// 未支付的会话（异步支付方式）等待 async_payment_succeeded 回调再入账
+func creditStripePackageSession(checkoutSession *stripe.CheckoutSession) error {
+	packageId := checkoutSession.Metadata["package_id"]
+	if checkoutSession.PaymentStatus != stripe.CheckoutSessionPaymentStatusPaid {
+		log.Println("Stripe Checkout会话等待支付完成:", checkoutSession.PaymentStatus, ",", checkoutSession.ID)
+		return nil
+	}
+	userId, err := strconv.Atoi(checkoutSession.Metadata["user_id"])
+	if err != nil {
+		log.Println("Stripe Checkout会话缺少用户信息:", checkoutSession.ID)
+		return nil
+	}
+	quota, err := strconv.Atoi(checkoutSession.Metadata["quota"])
+	if err != nil {
+		log.Println("Stripe Checkout会话缺少额度信息:", checkoutSession.ID)
+		return nil
+	}
+
+	customerId := ""
+	if checkoutSession.Customer != nil {
+		customerId = checkoutSession.Customer.ID
+	}
+	err = model.CreditStripePayment(&model.Payment{
+		StripeSessionId: checkoutSession.ID,
+		UserId:          userId,
+		PackageId:       packageId,
+		Amount:          checkoutSession.AmountTotal,
+		Currency:        string(checkoutSession.Currency),
+		QuotaGranted:    quota,
+	}, customerId)
+	if errors.Is(err, model.ErrPaymentAlreadyProcessed) {
+		log.Println("Stripe Checkout会话已入账:", checkoutSession.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("收到套餐款项：%s, 用户: %d, %.2f(%s)", checkoutSession.ID, userId, float64(checkoutSession.AmountTotal)/100, strings.ToUpper(string(checkoutSession.Currency)))
+	return nil
+}
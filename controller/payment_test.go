@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stripe/stripe-go/v81/webhook"
+)
+
+func postStripeWebhook(t *testing.T, payload string) int {
+	t.Helper()
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: []byte(payload), Secret: setting.StripeWebhookSecret})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/stripe/webhook", bytes.NewReader(signed.Payload))
+	c.Request.Header.Set("Stripe-Signature", signed.Header)
+	StripeWebhook(c)
+	return w.Code
+}
+
+func stripePackageEvent(eventType, paymentStatus string) string {
+	return `{"id":"evt_` + eventType + `","object":"event","type":"` + eventType + `","data":{"object":{` +
+		`"id":"cs_async","object":"checkout.session","status":"complete","payment_status":"` + paymentStatus + `",` +
+		`"amount_total":999,"currency":"usd","customer":"cus_1",` +
+		`"metadata":{"package_id":"pro","user_id":"1","quota":"5000"}}}}`
+}
+
+func TestStripeWebhookCreditsAsyncPackagePaymentOnce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t, &model.User{}, &model.Payment{}, &model.Log{})
+	prevSecret := setting.StripeWebhookSecret
+	setting.StripeWebhookSecret = "whsec_test"
+	t.Cleanup(func() { setting.StripeWebhookSecret = prevSecret })
+	if err := model.DB.Create(&model.User{Id: 1, Username: "payer", Quota: 100}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	quota := func() int {
+		var user model.User
+		if err := model.DB.First(&user, 1).Error; err != nil {
+			t.Fatalf("load user failed: %v", err)
+		}
+		return user.Quota
+	}
+
+	// 异步支付方式在 completed 时尚未付款，不应入账
+	if code := postStripeWebhook(t, stripePackageEvent("checkout.session.completed", "unpaid")); code != http.StatusOK {
+		t.Fatalf("completed webhook returned %d", code)
+	}
+	if got := quota(); got != 100 {
+		t.Fatalf("expected no credit before payment succeeds, quota = %d", got)
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := postStripeWebhook(t, stripePackageEvent("checkout.session.async_payment_succeeded", "paid")); code != http.StatusOK {
+			t.Fatalf("async payment webhook returned %d", code)
+		}
+	}
+	if got := quota(); got != 5100 {
+		t.Fatalf("expected the package to be credited once, quota = %d", got)
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type quotaTransferRequest struct {
+	FromUserId int    `json:"from_user_id"`
+	ToUserId   int    `json:"to_user_id"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason"`
+}
+
+// TransferQuota 管理员将额度从一个用户划转给另一个用户
+func TransferQuota(c *gin.Context) {
+	var req quotaTransferRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	if req.FromUserId <= 0 || req.ToUserId <= 0 {
+		common.ApiErrorMsg(c, "from_user_id 和 to_user_id 不能为空")
+		return
+	}
+	if len([]rune(req.Reason)) > 255 {
+		common.ApiErrorMsg(c, "划转原因不能超过 255 个字符")
+		return
+	}
+	transfer, err := model.TransferUserQuota(req.FromUserId, req.ToUserId, req.Amount, c.GetInt("id"), req.Reason)
+	if err != nil {
+		if errors.Is(err, model.ErrQuotaTransferInsufficient) || errors.Is(err, model.ErrQuotaTransferUserNotExists) {
+			common.ApiErrorMsg(c, err.Error())
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+
+	reason := ""
+	if req.Reason != "" {
+		reason = "，原因：" + req.Reason
+	}
+	model.RecordLog(req.FromUserId, model.LogTypeSystem,
+		fmt.Sprintf("管理员将 %s 额度划转给用户 %d%s", logger.LogQuota(req.Amount), req.ToUserId, reason))
+	model.RecordLog(req.ToUserId, model.LogTypeSystem,
+		fmt.Sprintf("管理员从用户 %d 划转 %s 额度%s", req.FromUserId, logger.LogQuota(req.Amount), reason))
+	common.ApiSuccess(c, transfer)
+}
+
+// GetQuotaTransferHistory 分页获取额度划转记录，可通过 ?user_id= 过滤
+func GetQuotaTransferHistory(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId, _ := strconv.Atoi(c.Query("user_id"))
+	transfers, total, err := model.GetQuotaTransfers(userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(transfers)
+	common.ApiSuccess(c, pageInfo)
+}
@@ -8,11 +8,13 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay"
@@ -21,6 +23,7 @@ import (
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -29,10 +32,14 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+func init() {
+	service.ChannelCapabilityResolver = relay.GetChannelCapabilities
+}
+
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
 	var err *types.NewAPIError
 	switch info.RelayMode {
-	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits, relayconstant.RelayModeImagesVariations:
 		err = relay.ImageHelper(c, info)
 	case relayconstant.RelayModeAudioSpeech:
 		fallthrough
@@ -139,6 +146,19 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	cacheKey, cacheTTL, served := prepareResponseCache(c, relayFormat, relayInfo)
+	if served {
+		return
+	}
+	var cacheRecorder *responseCacheRecorder
+	if cacheKey != "" {
+		cacheRecorder = &responseCacheRecorder{ResponseWriter: c.Writer, limit: ratio_setting.GetResponseCacheSetting().MaxBodyBytes}
+		c.Writer = cacheRecorder
+		defer func() {
+			c.Writer = cacheRecorder.ResponseWriter
+		}()
+	}
+
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
@@ -198,6 +218,9 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 			break
 		}
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		if cacheRecorder != nil {
+			cacheRecorder.reset()
+		}
 
 		switch relayFormat {
 		case types.RelayFormatOpenAIRealtime:
@@ -211,6 +234,11 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 
 		if newAPIError == nil {
+			if cacheRecorder != nil {
+				storeResponseCache(cacheKey, cacheTTL, cacheRecorder)
+			}
+			recordChannelKeyUsage(c, channel, relayInfo.GetEstimatePromptTokens())
+			service.DefaultChannelHealthMonitor.RecordSuccess(channel.Id)
 			return
 		}
 
@@ -406,11 +434,34 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 	logger.LogError(c, fmt.Sprintf("channel error (channel #%d, status code: %d): %s", channelError.ChannelId, err.StatusCode, err.Error()))
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
 	// do not use context to get channel info, there may be inconsistent channel info when processing asynchronously
+	service.DefaultChannelHealthMonitor.RecordError(channelError.ChannelId, err)
+	errorCode := string(err.GetErrorCode())
+	if errorCode == "" {
+		errorCode = fmt.Sprintf("status_%d", err.StatusCode)
+	}
+	metrics.IncChannelError(channelError.ChannelId, errorCode)
 	if service.ShouldDisableChannel(channelError.ChannelType, err) && channelError.AutoBan {
 		gopool.Go(func() {
 			service.DisableChannel(channelError, err.Error())
 		})
 	}
+	// 上游 429 时临时禁用多 key 渠道中的该 key
+	if channelError.IsMultiKey && channelError.UsingKey != "" && err.StatusCode == http.StatusTooManyRequests &&
+		err.GetErrorCode() != types.ErrorCodeChannelConcurrencyLimit && err.GetErrorCode() != types.ErrorCodeChannelKeysRateLimited {
+		gopool.Go(func() {
+			if err := model.DisableChannelKeyUntil(channelError.ChannelId, channelError.UsingKey, time.Now().Add(model.ChannelKeyCooldownTime)); err != nil {
+				common.SysError(fmt.Sprintf("failed to disable key of channel #%d: %s", channelError.ChannelId, err.Error()))
+			}
+		})
+	}
+	// 上游额度耗尽时禁用该 key 到计费周期结束，重试时会选到下一个 key
+	if channelError.IsMultiKey && channelError.UsingKey != "" && !types.IsChannelError(err) && service.IsQuotaExhaustedError(err.StatusCode, fmt.Sprint(err.ToOpenAIError().Code), err.Error()) {
+		gopool.Go(func() {
+			if _, err := model.RotateChannelKey(channelError.ChannelId, channelError.UsingKey); err != nil {
+				common.SysError(fmt.Sprintf("failed to rotate exhausted key of channel #%d: %s", channelError.ChannelId, err.Error()))
+			}
+		})
+	}
 
 	if constant.ErrorLogEnabled && types.IsRecordErrorLog(err) {
 		// 保存错误日志到mysql中
@@ -525,6 +576,7 @@ func RelayTask(c *gin.Context) {
 	if taskErr != nil && !taskErr.LocalError {
 		processTaskChannelError(c, taskErr)
 	}
+	channelId, taskErr = relayTaskWithFallback(c, relayInfo, channelId, taskErr)
 	retryParam := &service.RetryParam{
 		Ctx:        c,
 		TokenGroup: relayInfo.TokenGroup,
@@ -546,11 +598,7 @@ func RelayTask(c *gin.Context) {
 
 		requestBody, err := common.GetRequestBody(c)
 		if err != nil {
-			if common.IsRequestBodyTooLargeError(err) || errors.Is(err, common.ErrRequestBodyTooLarge) {
-				taskErr = service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusRequestEntityTooLarge)
-			} else {
-				taskErr = service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
-			}
+			taskErr = service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
 			break
 		}
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
@@ -564,14 +612,39 @@ func RelayTask(c *gin.Context) {
 		retryLogStr := fmt.Sprintf("重试：%s", strings.Trim(strings.Join(strings.Fields(fmt.Sprint(useChannel)), "->"), "[]"))
 		logger.LogInfo(c, retryLogStr)
 	}
+	// 影子模式：提交结束后将请求镜像到最终使用渠道的影子渠道对比
+	if taskRelayIsSubmit(relayInfo) {
+		mirrorTaskToShadow(c, relayInfo, channelId, taskErr)
+	}
 	if taskErr != nil {
+		service.ApplyErrorCatalogue(taskErr)
 		if taskErr.StatusCode == http.StatusTooManyRequests {
-			taskErr.Message = "当前分组上游负载已饱和，请稍后再试"
+			taskErr.Message = service.DefaultI18n.Message("upstream_saturated", relayInfo.Language, "当前分组上游负载已饱和，请稍后再试")
+		} else {
+			service.LocalizeTaskError(taskErr, relayInfo.Language)
 		}
 		c.JSON(taskErr.StatusCode, taskErr)
 	}
 }
 
+// EstimateTask 预估任务提交所需额度，请求体与任务提交相同
+func EstimateTask(c *gin.Context) {
+	relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatTask, nil, nil)
+	if err != nil {
+		taskErr := service.TaskErrorWrapperLocal(err, "gen_relay_info_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	estimate, taskErr := relay.EstimateTaskQuota(c, relayInfo)
+	if taskErr != nil {
+		service.ApplyErrorCatalogue(taskErr)
+		service.LocalizeTaskError(taskErr, service.MatchLanguage(c.GetHeader("Accept-Language")))
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	c.JSON(http.StatusOK, estimate)
+}
+
 func processTaskChannelError(c *gin.Context, taskErr *dto.TaskError) {
 	channelId := c.GetInt("channel_id")
 	channelType := c.GetInt("channel_type")
@@ -588,14 +661,81 @@ func processTaskChannelError(c *gin.Context, taskErr *dto.TaskError) {
 }
 
 func taskRelayHandler(c *gin.Context, relayInfo *relaycommon.RelayInfo) *dto.TaskError {
-	var err *dto.TaskError
+	if !taskRelayIsSubmit(relayInfo) {
+		return relay.RelayTaskFetch(c, relayInfo.RelayMode)
+	}
+	return relay.RelayTaskSubmit(c, relayInfo)
+}
+
+// taskRelayIsSubmit 判断任务请求是否为提交，查询类请求返回 false
+func taskRelayIsSubmit(relayInfo *relaycommon.RelayInfo) bool {
 	switch relayInfo.RelayMode {
 	case relayconstant.RelayModeSunoFetch, relayconstant.RelayModeSunoFetchByID, relayconstant.RelayModeVideoFetchByID:
-		err = relay.RelayTaskFetch(c, relayInfo.RelayMode)
-	default:
-		err = relay.RelayTaskSubmit(c, relayInfo)
+		return false
 	}
-	return err
+	return true
+}
+
+// shouldFallbackTaskRelay 仅在上游返回 5xx 时按回退链切换渠道，4xx 视为用户错误不回退
+func shouldFallbackTaskRelay(c *gin.Context, relayInfo *relaycommon.RelayInfo, taskErr *dto.TaskError) bool {
+	if taskErr == nil || taskErr.LocalError || relayInfo.ChannelMeta == nil {
+		return false
+	}
+	if _, ok := c.Get("specific_channel_id"); ok {
+		return false
+	}
+	return taskErr.StatusCode/100 == 5
+}
+
+// relayTaskWithFallback 按失败渠道设置的回退链依次切换到后续渠道重新提交任务，
+// 渠道无可用 key 或不支持当前模型时跳过该渠道。返回最后使用的渠道 ID 及提交结果
+func relayTaskWithFallback(c *gin.Context, relayInfo *relaycommon.RelayInfo, channelId int, taskErr *dto.TaskError) (int, *dto.TaskError) {
+	if !shouldFallbackTaskRelay(c, relayInfo, taskErr) {
+		return channelId, taskErr
+	}
+	current, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		return channelId, taskErr
+	}
+	groupName := current.GetSetting().FallbackGroup
+	if groupName == "" {
+		return channelId, taskErr
+	}
+	tried := map[int]bool{channelId: true}
+	cursor := channelId
+	for shouldFallbackTaskRelay(c, relayInfo, taskErr) {
+		channel, err := model.GetFallbackChannel(groupName, cursor)
+		if err != nil {
+			logger.LogInfo(c, fmt.Sprintf("channel group %s: %s", groupName, err.Error()))
+			break
+		}
+		if tried[channel.Id] {
+			break
+		}
+		tried[channel.Id] = true
+		cursor = channel.Id
+		if !common.StringsContains(channel.GetModels(), relayInfo.OriginModelName) {
+			continue
+		}
+		if newAPIError := middleware.SetupContextForSelectedChannel(c, channel, relayInfo.OriginModelName); newAPIError != nil {
+			logger.LogWarn(c, fmt.Sprintf("skip fallback channel #%d: %s", channel.Id, newAPIError.Error()))
+			continue
+		}
+		channelId = channel.Id
+		c.Set("use_channel", append(c.GetStringSlice("use_channel"), fmt.Sprintf("%d", channelId)))
+		logger.LogInfo(c, fmt.Sprintf("using fallback channel #%d from channel group %s", channelId, groupName))
+
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			return channelId, service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		taskErr = taskRelayHandler(c, relayInfo)
+		if taskErr != nil && !taskErr.LocalError {
+			processTaskChannelError(c, taskErr)
+		}
+	}
+	return channelId, taskErr
 }
 
 func shouldRetryTaskRelay(c *gin.Context, channelId int, taskErr *dto.TaskError, retryTimes int) bool {
@@ -608,6 +748,10 @@ func shouldRetryTaskRelay(c *gin.Context, channelId int, taskErr *dto.TaskError,
 	if _, ok := c.Get("specific_channel_id"); ok {
 		return false
 	}
+	// 上游响应格式异常通常是渠道配置错误，重试无意义
+	if taskErr.Code == "invalid_upstream_response" {
+		return false
+	}
 	if taskErr.StatusCode == http.StatusTooManyRequests {
 		return true
 	}
@@ -636,3 +780,19 @@ func shouldRetryTaskRelay(c *gin.Context, channelId int, taskErr *dto.TaskError,
 	}
 	return true
 }
+
+// recordChannelKeyUsage 记录多 key 渠道中本次使用的 key 的请求数与 token 数
+func recordChannelKeyUsage(c *gin.Context, channel *model.Channel, tokens int) {
+	if !channel.ChannelInfo.IsMultiKey {
+		return
+	}
+	key := common.GetContextKeyString(c, constant.ContextKeyChannelKey)
+	if key == "" {
+		return
+	}
+	gopool.Go(func() {
+		if err := model.RecordChannelKeyUsage(channel.Id, key, tokens); err != nil {
+			common.SysError(fmt.Sprintf("failed to record key usage of channel #%d: %s", channel.Id, err.Error()))
+		}
+	})
+}
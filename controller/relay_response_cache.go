@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseCacheRecorder 在写出响应的同时记录响应体，超过 limit 后放弃记录
+type responseCacheRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+// reset 丢弃之前失败尝试写出的内容，每次重试前调用
+func (r *responseCacheRecorder) reset() {
+	r.body.Reset()
+	r.overflow = false
+}
+
+func (r *responseCacheRecorder) record(n int, write func()) {
+	if r.overflow {
+		return
+	}
+	if r.body.Len()+n > r.limit {
+		r.overflow = true
+		r.body.Reset()
+		return
+	}
+	write()
+}
+
+func (r *responseCacheRecorder) Write(data []byte) (int, error) {
+	r.record(len(data), func() { r.body.Write(data) })
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseCacheRecorder) WriteString(s string) (int, error) {
+	r.record(len(s), func() { r.body.WriteString(s) })
+	return r.ResponseWriter.WriteString(s)
+}
+
+// prepareResponseCache 查询模型的响应缓存。命中时直接返回缓存内容且不扣费，served 为 true；
+// 未命中时返回缓存键与有效期，由调用方在请求成功后写入。流式与实时请求不缓存，
+// 图片生成请求在模型未单独配置时使用 ImageGenerationTTL
+func prepareResponseCache(c *gin.Context, relayFormat types.RelayFormat, info *relaycommon.RelayInfo) (key string, ttl time.Duration, served bool) {
+	if !common.RedisEnabled || info.IsStream || relayFormat == types.RelayFormatOpenAIRealtime {
+		return "", 0, false
+	}
+	ttl, ok := ratio_setting.GetModelResponseCacheTTL(info.OriginModelName)
+	if info.RelayMode == relayconstant.RelayModeImagesGenerations {
+		ttl, ok = ratio_setting.GetImageGenerationResponseCacheTTL(info.OriginModelName)
+	}
+	if !ok {
+		return "", 0, false
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil || len(body) == 0 {
+		return "", 0, false
+	}
+	key = service.DefaultResponseCache.Key(info.UserId, c.Request.URL.Path, info.OriginModelName, body)
+	cached, hit := service.DefaultResponseCache.Get(key)
+	metrics.ObserveResponseCache(info.OriginModelName, hit)
+	setResponseCacheHeaders(c, info, hit)
+	if !hit {
+		return key, ttl, false
+	}
+	logger.LogInfo(c, fmt.Sprintf("response cache hit, model: %s", info.OriginModelName))
+	service.DefaultResponseCache.RecordHit(info.UserId, info.OriginModelName)
+	c.Data(cached.StatusCode, cached.ContentType, cached.Body)
+	return "", 0, true
+}
+
+// setResponseCacheHeaders 写出缓存命中状态，图片生成请求额外保留原图片缓存的 X-Cache 头
+func setResponseCacheHeaders(c *gin.Context, info *relaycommon.RelayInfo, hit bool) {
+	status := "MISS"
+	if hit {
+		status = "HIT"
+	}
+	c.Header("X-Cache-Status", status)
+	if info.RelayMode == relayconstant.RelayModeImagesGenerations {
+		c.Header("X-Cache", status)
+	}
+}
+
+// storeResponseCache 将成功的响应写入缓存
+func storeResponseCache(key string, ttl time.Duration, recorder *responseCacheRecorder) {
+	if recorder.overflow || recorder.Status() != http.StatusOK {
+		return
+	}
+	service.DefaultResponseCache.Set(key, &service.CachedResponse{
+		StatusCode:  http.StatusOK,
+		ContentType: recorder.Header().Get("Content-Type"),
+		Body:        bytes.Clone(recorder.body.Bytes()),
+	}, ttl)
+}
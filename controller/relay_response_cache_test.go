@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSetResponseCacheHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		relayMode  int
+		hit        bool
+		wantStatus string
+		wantXCache string
+	}{
+		{relayconstant.RelayModeImagesGenerations, true, "HIT", "HIT"},
+		{relayconstant.RelayModeImagesGenerations, false, "MISS", "MISS"},
+		{relayconstant.RelayModeChatCompletions, true, "HIT", ""},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		setResponseCacheHeaders(c, &relaycommon.RelayInfo{RelayMode: tc.relayMode}, tc.hit)
+		if got := w.Header().Get("X-Cache-Status"); got != tc.wantStatus {
+			t.Errorf("mode %d hit %v: X-Cache-Status = %q, want %q", tc.relayMode, tc.hit, got, tc.wantStatus)
+		}
+		if got := w.Header().Get("X-Cache"); got != tc.wantXCache {
+			t.Errorf("mode %d hit %v: X-Cache = %q, want %q", tc.relayMode, tc.hit, got, tc.wantXCache)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type spendingCapRequest struct {
+	ModelName string `json:"model_name"`
+	Period    string `json:"period"`
+	CapQuota  int    `json:"cap_quota"`
+}
+
+// GetUserSpendingCaps 获取用户的模型消费上限
+func GetUserSpendingCaps(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的用户 ID")
+		return
+	}
+	caps, err := model.GetModelSpendingCaps(userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, caps)
+}
+
+// SetUserSpendingCap 设置用户在某个模型上的日/周/月消费上限，cap_quota 为 0 时删除该上限
+func SetUserSpendingCap(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的用户 ID")
+		return
+	}
+	var req spendingCapRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "无效的参数")
+		return
+	}
+	req.ModelName = strings.TrimSpace(req.ModelName)
+	if req.ModelName == "" {
+		common.ApiErrorMsg(c, "model_name 不能为空")
+		return
+	}
+	if !model.IsValidModelSpendingCapPeriod(req.Period) {
+		common.ApiErrorMsg(c, "period 必须为 day、week 或 month")
+		return
+	}
+	if req.CapQuota < 0 {
+		common.ApiErrorMsg(c, "cap_quota 不能为负数")
+		return
+	}
+	if _, err := model.GetUserById(userId, false); err != nil {
+		common.ApiErrorMsg(c, "用户不存在")
+		return
+	}
+
+	spendingCap, err := model.SetModelSpendingCap(userId, req.ModelName, req.Period, req.CapQuota)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if spendingCap == nil {
+		model.RecordLog(userId, model.LogTypeManage, fmt.Sprintf("管理员移除了模型 %s 的 %s 消费上限", req.ModelName, req.Period))
+	} else {
+		model.RecordLog(userId, model.LogTypeManage, fmt.Sprintf("管理员将模型 %s 的 %s 消费上限设置为 %s", req.ModelName, req.Period, logger.LogQuota(req.CapQuota)))
+	}
+	common.ApiSuccess(c, spendingCap)
+}
@@ -5,35 +5,45 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
+	"gorm.io/gorm"
 )
 
 func UpdateTaskBulk() {
 	//revocer
 	//imageModel := "midjourney"
 	for {
-		time.Sleep(time.Duration(15) * time.Second)
+		time.Sleep(service.DefaultAdaptivePoller.MinInterval())
 		common.SysLog("任务进度轮询开始")
 		ctx := context.TODO()
 		allTasks := model.GetAllUnFinishSyncTasks(constant.TaskQueryLimit)
+		scheduleNextTaskPoll(ctx, allTasks)
 		platformTask := make(map[constant.TaskPlatform][]*model.Task)
 		for _, t := range allTasks {
 			platformTask[t.Platform] = append(platformTask[t.Platform], t)
 		}
+		queueDepth := make(map[string]int, len(platformTask))
+		for platform, tasks := range platformTask {
+			queueDepth[string(platform)] = len(tasks)
+		}
+		metrics.SetTaskQueueDepth(queueDepth)
 		for platform, tasks := range platformTask {
 			if len(tasks) == 0 {
 				continue
@@ -71,6 +81,25 @@ func UpdateTaskBulk() {
 	}
 }
 
+// scheduleNextTaskPoll 按任务已运行时长计算下次轮询时间，间隔相同的任务合并为一次批量更新
+func scheduleNextTaskPoll(ctx context.Context, tasks []*model.Task) {
+	now := time.Now().Unix()
+	nextPollIds := make(map[int64][]int64)
+	for _, task := range tasks {
+		submitTime := task.SubmitTime
+		if submitTime == 0 {
+			submitTime = task.CreatedAt
+		}
+		task.NextPollAt = service.DefaultAdaptivePoller.NextPollAt(submitTime, now)
+		nextPollIds[task.NextPollAt] = append(nextPollIds[task.NextPollAt], task.ID)
+	}
+	for nextPollAt, ids := range nextPollIds {
+		if err := model.TaskBulkUpdateByID(ids, map[string]any{"next_poll_at": nextPollAt}); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("Schedule next poll for %d tasks failed: %v", len(ids), err))
+		}
+	}
+}
+
 func UpdateTaskByPlatform(platform constant.TaskPlatform, taskChannelM map[int][]string, taskM map[string]*model.Task) {
 	switch platform {
 	case constant.TaskPlatformMidjourney:
@@ -102,11 +131,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 	channel, err := model.CacheGetChannel(channelId)
 	if err != nil {
 		common.SysLog(fmt.Sprintf("CacheGetChannel: %v", err))
-		err = model.TaskBulkUpdate(taskIds, map[string]any{
-			"fail_reason": fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId),
-			"status":      "FAILURE",
-			"progress":    "100%",
-		})
+		err = failTasksInBulk(ctx, taskIds, taskM, fmt.Sprintf("获取渠道信息失败，请联系管理员，渠道ID：%d", channelId))
 		if err != nil {
 			common.SysLog(fmt.Sprintf("UpdateMidjourneyTask error2: %v", err))
 		}
@@ -129,7 +154,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		return errors.New(fmt.Sprintf("Get Task status code: %d", resp.StatusCode))
 	}
 	defer resp.Body.Close()
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		common.SysLog(fmt.Sprintf("Get Task parse body error: %v", err))
 		return err
@@ -150,6 +175,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		if !checkTaskNeedUpdate(task, responseItem) {
 			continue
 		}
+		preStatus, preProgress := task.Status, task.Progress
 
 		task.Status = lo.If(model.TaskStatus(responseItem.Status) != "", model.TaskStatus(responseItem.Status)).Else(task.Status)
 		task.FailReason = lo.If(responseItem.FailReason != "", responseItem.FailReason).Else(task.FailReason)
@@ -165,7 +191,7 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 			} else {
 				quota := task.Quota
 				if quota != 0 {
-					err = model.IncreaseUserQuota(task.UserId, quota, false)
+					err = service.RefundTaskQuota(ctx, task, quota)
 					if err != nil {
 						logger.LogError(ctx, "fail to increase user quota: "+err.Error())
 					}
@@ -182,6 +208,8 @@ func updateSunoTaskAll(ctx context.Context, channelId int, taskIds []string, tas
 		err = task.Update()
 		if err != nil {
 			common.SysLog("UpdateMidjourneyTask task error: " + err.Error())
+		} else {
+			notifyTaskStatusChanged(ctx, task, preStatus, preProgress)
 		}
 	}
 	return nil
@@ -274,3 +302,237 @@ func GetUserTask(c *gin.Context) {
 	pageInfo.SetItems(items)
 	common.ApiSuccess(c, pageInfo)
 }
+
+const (
+	defaultTaskListLimit = 20
+	maxTaskListLimit     = 100
+)
+
+// ListUserTasks 令牌访问的用户任务列表，按提交时间倒序，使用游标分页
+func ListUserTasks(c *gin.Context) {
+	limit := defaultTaskListLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			taskErr := service.TaskErrorWrapperLocal(errors.New("limit must be a positive integer"), "invalid_request", http.StatusBadRequest)
+			c.JSON(taskErr.StatusCode, taskErr)
+			return
+		}
+		limit = min(n, maxTaskListLimit)
+	}
+
+	var cursor *model.TaskCursor
+	if v := c.Query("cursor"); v != "" {
+		var err error
+		if cursor, err = model.ParseTaskCursor(v); err != nil {
+			taskErr := service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+			c.JSON(taskErr.StatusCode, taskErr)
+			return
+		}
+	}
+
+	from, _ := strconv.ParseInt(c.Query("from"), 10, 64)
+	to, _ := strconv.ParseInt(c.Query("to"), 10, 64)
+	queryParams := model.SyncTaskQueryParams{
+		Platform:       constant.TaskPlatform(c.Query("platform")),
+		Status:         c.Query("status"),
+		Action:         c.Query("action"),
+		StartTimestamp: from,
+		EndTimestamp:   to,
+	}
+	if v := c.Query("tags"); v != "" {
+		tags, err := model.NormalizeTaskTags(strings.Split(v, ","))
+		if err != nil {
+			taskErr := service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+			c.JSON(taskErr.StatusCode, taskErr)
+			return
+		}
+		queryParams.Tags = tags
+	}
+
+	// 多取一条用于判断是否还有下一页
+	tasks, err := model.GetTasksByUser(c.GetInt("id"), queryParams, cursor, limit+1)
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "get_tasks_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+
+	resp := dto.TaskResponse[[]dto.TaskDto]{
+		Code: dto.TaskSuccessCode,
+		Data: make([]dto.TaskDto, 0, len(tasks)),
+	}
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		last := tasks[len(tasks)-1]
+		resp.NextCursor = model.TaskCursor{SubmitTime: last.SubmitTime, ID: last.ID}.Encode()
+	}
+	for _, task := range tasks {
+		resp.Data = append(resp.Data, *relay.TaskModel2Dto(task))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateUserTaskTags 替换当前用户任务的标签，请求体为 {"tags": [...]}，空数组表示清除全部标签
+func UpdateUserTaskTags(c *gin.Context) {
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		taskErr := service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	tags, err := model.NormalizeTaskTags(req.Tags)
+	if err != nil {
+		taskErr := service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	task, err := model.UpdateUserTaskTags(c.GetInt("id"), c.Param("id"), tags)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("task not found"), "task_not_exist", http.StatusNotFound)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "update_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TaskResponse[dto.TaskDto]{
+		Code: dto.TaskSuccessCode,
+		Data: *relay.TaskModel2Dto(task),
+	})
+}
+
+// CancelUserTask 取消当前用户未完成的任务并退还额度，需要令牌拥有 task:cancel 权限
+func CancelUserTask(c *gin.Context) {
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), c.Param("id"))
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "get_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !exist {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("task not found"), "task_not_exist", http.StatusNotFound)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	cancelled, err := cancelTask(c.Request.Context(), task, model.TaskCancelReasonUser, "Task cancelled by user")
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "update_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !cancelled {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("task already finished"), "task_already_finished", http.StatusConflict)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TaskResponse[dto.TaskDto]{
+		Code: dto.TaskSuccessCode,
+		Data: *relay.TaskModel2Dto(task),
+	})
+}
+
+// UpdateTaskPriority 管理员调整未完成任务的轮询优先级
+func UpdateTaskPriority(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ApiErrorMsg(c, "invalid task id")
+		return
+	}
+	var req struct {
+		Priority *int `json:"priority"`
+	}
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.Priority == nil {
+		common.ApiErrorMsg(c, "priority is required")
+		return
+	}
+	if !ratio_setting.IsValidTaskPriority(*req.Priority) {
+		common.ApiErrorMsg(c, fmt.Sprintf("priority must be between %d and %d", ratio_setting.TaskPriorityNormal, ratio_setting.TaskPriorityCritical))
+		return
+	}
+	updated, err := model.UpdateTaskPriority(id, *req.Priority)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !updated {
+		common.ApiErrorMsg(c, "task not found or already finished")
+		return
+	}
+	common.ApiSuccess(c, gin.H{"id": id, "priority": *req.Priority})
+}
+
+// cancelTasksBatchSize 批量取消时每次从数据库读取的任务数
+const cancelTasksBatchSize = 500
+
+// CancelTasksBulk 管理员批量取消渠道下指定状态的任务并退还额度，用于渠道故障后清理卡住的任务。
+// 每个任务都经由 cancelTask 取消，与单个任务取消一样退还用户、令牌额度及消费上限并发送事件与回调
+func CancelTasksBulk(c *gin.Context) {
+	var req struct {
+		ChannelId int      `json:"channel_id"`
+		Status    []string `json:"status"`
+	}
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil {
+		common.ApiErrorMsg(c, "invalid request body")
+		return
+	}
+	if req.ChannelId <= 0 {
+		common.ApiErrorMsg(c, "channel_id is required")
+		return
+	}
+	statuses := make([]model.TaskStatus, 0, len(req.Status))
+	for _, status := range req.Status {
+		statuses = append(statuses, model.TaskStatus(strings.ToUpper(strings.TrimSpace(status))))
+	}
+	if err := model.ValidateCancelTaskStatuses(statuses); err != nil {
+		common.ApiErrorMsg(c, err.Error())
+		return
+	}
+	summary, err := cancelTasksByChannel(c.Request.Context(), req.ChannelId, statuses)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, summary)
+}
+
+// cancelTasksByChannel 分批取消渠道下处于 statuses 状态的任务，返回按用户汇总的退款明细
+func cancelTasksByChannel(ctx context.Context, channelId int, statuses []model.TaskStatus) (*model.TaskCancelSummary, error) {
+	summary := &model.TaskCancelSummary{}
+	userIndex := make(map[int]int)
+	var afterId int64
+	for {
+		tasks, err := model.GetTasksByChannelAndStatus(channelId, statuses, afterId, cancelTasksBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			quota := task.Quota
+			cancelled, err := cancelTask(ctx, task, model.TaskCancelReasonAdmin, fmt.Sprintf("Task on channel #%d cancelled by admin", channelId), statuses...)
+			if err != nil {
+				return nil, err
+			}
+			if !cancelled {
+				continue
+			}
+			summary.Count++
+			summary.RefundedQuota += quota
+			i, ok := userIndex[task.UserId]
+			if !ok {
+				i = len(summary.Users)
+				userIndex[task.UserId] = i
+				summary.Users = append(summary.Users, model.TaskCancelUserRefund{UserId: task.UserId})
+			}
+			summary.Users[i].Count++
+			summary.Users[i].Quota += quota
+		}
+		if len(tasks) < cancelTasksBatchSize {
+			return summary, nil
+		}
+		afterId = tasks[len(tasks)-1].ID
+	}
+}
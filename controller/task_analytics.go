@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultTaskAnalyticsWindow = 7 * 24 * time.Hour
+
+// getTaskAnalyticsRange 解析 start_timestamp / end_timestamp，缺省时统计最近 7 天。
+// 默认结束时间按缓存时间向上取整，使缓存在有效期内可以命中
+func getTaskAnalyticsRange(c *gin.Context) (int64, int64) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if endTimestamp <= 0 {
+		endTimestamp = time.Now().Truncate(service.TaskAnalyticsCacheTTL).Add(service.TaskAnalyticsCacheTTL).Unix()
+	}
+	if startTimestamp <= 0 {
+		startTimestamp = endTimestamp - int64(defaultTaskAnalyticsWindow/time.Second)
+	}
+	return startTimestamp, endTimestamp
+}
+
+// GetTaskAnalytics 管理员获取任务统计面板数据
+func GetTaskAnalytics(c *gin.Context) {
+	startTimestamp, endTimestamp := getTaskAnalyticsRange(c)
+	if startTimestamp > endTimestamp {
+		common.ApiErrorMsg(c, "start_timestamp 不能大于 end_timestamp")
+		return
+	}
+	analytics, err := service.GetTaskAnalytics(startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, analytics)
+}
+
+// ExportTaskAnalytics 以 CSV 导出任务统计数据，每行为 metric,dimension,value
+func ExportTaskAnalytics(c *gin.Context) {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		common.ApiErrorMsg(c, "不支持的导出格式: "+format)
+		return
+	}
+	startTimestamp, endTimestamp := getTaskAnalyticsRange(c)
+	if startTimestamp > endTimestamp {
+		common.ApiErrorMsg(c, "start_timestamp 不能大于 end_timestamp")
+		return
+	}
+	analytics, err := service.GetTaskAnalytics(startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=task_analytics_%d_%d.csv", startTimestamp, endTimestamp))
+	c.Status(http.StatusOK)
+	writer := csv.NewWriter(c.Writer)
+	_ = writer.WriteAll(taskAnalyticsCSVRecords(analytics))
+}
+
+func taskAnalyticsCSVRecords(analytics *model.TaskAnalytics) [][]string {
+	records := [][]string{{"metric", "dimension", "value"}}
+	add := func(metric string, dimension string, value string) {
+		records = append(records, []string{metric, dimension, value})
+	}
+	formatFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	}
+	for _, stat := range analytics.CountByStatus {
+		add("count_by_status", stat.Key, strconv.FormatInt(stat.Count, 10))
+	}
+	for _, stat := range analytics.CountByPlatform {
+		add("count_by_platform", stat.Key, strconv.FormatInt(stat.Count, 10))
+	}
+	for _, stat := range analytics.CountByModel {
+		add("count_by_model", stat.Key, strconv.FormatInt(stat.Count, 10))
+	}
+	for _, stat := range analytics.CountByDay {
+		add("count_by_day", stat.Key, strconv.FormatInt(stat.Count, 10))
+	}
+	for _, stat := range analytics.AvgDurationByPlatform {
+		add("avg_duration_seconds_by_platform", stat.Platform, formatFloat(stat.AvgSeconds))
+	}
+	for _, stat := range analytics.FailureRateByChannel {
+		add("failure_rate_by_channel", strconv.Itoa(stat.ChannelId), formatFloat(stat.FailureRate))
+	}
+	latency := analytics.CompletionLatency
+	add("completion_latency_seconds", "p50", strconv.FormatInt(latency.P50, 10))
+	add("completion_latency_seconds", "p95", strconv.FormatInt(latency.P95, 10))
+	add("completion_latency_seconds", "p99", strconv.FormatInt(latency.P99, 10))
+	for _, stat := range analytics.TopModelsByQuota {
+		add("quota_by_model", stat.Model, strconv.FormatInt(stat.Quota, 10))
+	}
+	return records
+}
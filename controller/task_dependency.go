@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SubmitTaskDependency 使用依赖任务保存的令牌与请求体重新走一遍鉴权、渠道分发与任务提交，返回下游任务 ID
+func SubmitTaskDependency(ctx context.Context, dep *model.TaskDependency) (string, error) {
+	token, err := model.GetTokenById(dep.TokenId)
+	if err != nil {
+		return "", err
+	}
+	resp, err := serveInternalRelay(ctx, internalRelayRequest{
+		Path:   dep.RequestPath,
+		Body:   []byte(dep.RequestBody),
+		Header: http.Header{"Authorization": []string{"Bearer sk-" + token.Key}},
+		// 沿用提交时的客户端 IP，以通过令牌的 IP 限制
+		RemoteAddr: net.JoinHostPort(dep.ClientIp, "0"),
+	})
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success() {
+		return "", errors.New(extractVideoBatchError(resp.Body()))
+	}
+	var submitted struct {
+		ID     string `json:"id"`
+		TaskID string `json:"task_id"`
+	}
+	if err := common.Unmarshal(resp.Body(), &submitted); err != nil {
+		return "", err
+	}
+	if submitted.TaskID != "" {
+		return submitted.TaskID, nil
+	}
+	return submitted.ID, nil
+}
+
+// GetTaskDependency 查询依赖任务的状态，提交成功后返回下游任务 ID
+func GetTaskDependency(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	dep, exist, err := model.GetUserTaskDependency(c.GetInt("id"), id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !exist {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "task dependency not found",
+		})
+		return
+	}
+	common.ApiSuccess(c, dep)
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSubmitTaskDependency(t *testing.T) {
+	setupTestDB(t, &model.Token{})
+	gin.SetMode(gin.TestMode)
+	token := &model.Token{Id: 1, UserId: 1, Key: "depkey", Name: "dep", Status: common.TokenStatusEnabled}
+	if err := model.DB.Create(token).Error; err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+
+	// 依赖任务经由内部路由提交，沿用保存的令牌、请求体与客户端 IP
+	var gotAuth, gotIP, gotBody string
+	engine := gin.New()
+	engine.POST("/v1/videos", func(c *gin.Context) {
+		gotAuth = c.GetHeader("Authorization")
+		gotIP = c.ClientIP()
+		body, _ := io.ReadAll(c.Request.Body)
+		gotBody = string(body)
+		c.JSON(http.StatusOK, gin.H{"id": "video_1"})
+	})
+	engine.POST("/v1/video/generations", func(c *gin.Context) {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invalid prompt"})
+	})
+	SetInternalRelayHandler(engine)
+	t.Cleanup(func() { SetInternalRelayHandler(nil) })
+
+	dep := &model.TaskDependency{TokenId: token.Id, RequestPath: "/v1/videos", RequestBody: `{"model":"sora-2","input_reference":"https://cdn.example.com/a.mp4"}`, ClientIp: "203.0.113.7"}
+	taskId, err := SubmitTaskDependency(context.Background(), dep)
+	if err != nil || taskId != "video_1" {
+		t.Fatalf("SubmitTaskDependency = %q, %v", taskId, err)
+	}
+	if gotAuth != "Bearer sk-depkey" || gotIP != "203.0.113.7" || gotBody != dep.RequestBody {
+		t.Fatalf("internal request auth=%q ip=%q body=%q", gotAuth, gotIP, gotBody)
+	}
+
+	dep.RequestPath = "/v1/video/generations"
+	if _, err := SubmitTaskDependency(context.Background(), dep); err == nil {
+		t.Fatal("expected error for a rejected submit")
+	}
+}
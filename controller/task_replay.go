@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplayUserTask 使用失败任务保存的请求经由服务自身的路由重新提交一个新任务，鉴权、渠道选择与扣费和正常提交一致，
+// 新任务的 parent_task_id 指向最初的任务。每个原始任务最多重放 model.MaxTaskReplays 次，名额在提交前以条件更新占用
+func ReplayUserTask(c *gin.Context) {
+	userId := c.GetInt("id")
+	task, exist, err := model.GetByTaskId(userId, c.Param("id"))
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "get_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !exist {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("task not found"), "task_not_exist", http.StatusNotFound)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if task.Status != model.TaskStatusFailure {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("only failed tasks can be replayed"), "task_not_failed", http.StatusBadRequest)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !task.CanReplay() {
+		taskErr := service.TaskErrorWrapperLocal(errors.New("the original request of this task was not saved"), "task_not_replayable", http.StatusBadRequest)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+
+	rootTaskId := task.ReplayRootTaskID()
+	reserved, err := model.ReserveTaskReplay(userId, rootTaskId)
+	if err != nil {
+		taskErr := service.TaskErrorWrapper(err, "reserve_task_replay_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	if !reserved {
+		taskErr := service.TaskErrorWrapperLocal(fmt.Errorf("task %s has already been replayed %d times", rootTaskId, model.MaxTaskReplays), "task_replay_limit_exceeded", http.StatusTooManyRequests)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+
+	resp, err := serveInternalRelay(c.Request.Context(), internalRelayRequest{
+		Path:       task.RequestPath,
+		Body:       task.RequestBody,
+		Header:     c.Request.Header,
+		RemoteAddr: c.Request.RemoteAddr,
+		Values:     map[constant.ContextKey]any{constant.ContextKeyTaskReplayParentId: rootTaskId},
+	})
+	if err != nil || !resp.Success() {
+		// 提交未成功时归还名额
+		if releaseErr := model.ReleaseTaskReplay(userId, rootTaskId); releaseErr != nil {
+			logger.LogError(c, fmt.Sprintf("release replay slot of task %s failed: %s", rootTaskId, releaseErr.Error()))
+		}
+	}
+	if err != nil {
+		taskErr := service.TaskErrorWrapperLocal(err, "replay_task_failed", http.StatusInternalServerError)
+		c.JSON(taskErr.StatusCode, taskErr)
+		return
+	}
+	c.Data(resp.StatusCode(), resp.Header().Get("Content-Type"), resp.Body())
+}
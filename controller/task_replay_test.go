@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReplayUserTask(t *testing.T) {
+	setupTestDB(t, &model.Task{})
+	gin.SetMode(gin.TestMode)
+	createTestTask(t, &model.Task{
+		TaskID:      "origin",
+		UserId:      1,
+		Status:      model.TaskStatusFailure,
+		RequestPath: "/v1/video/generations",
+		RequestBody: json.RawMessage(`{"model":"m","prompt":"p"}`),
+	})
+
+	// 内部请求经由路由处理，记录收到的鉴权头与重放来源
+	upstreamStatus := http.StatusOK
+	var gotParent, gotAuth string
+	engine := gin.New()
+	engine.Use(middleware.InternalRequestValues())
+	engine.POST("/v1/video/generations", func(c *gin.Context) {
+		gotParent = common.GetContextKeyString(c, constant.ContextKeyTaskReplayParentId)
+		gotAuth = c.GetHeader("Authorization")
+		c.JSON(upstreamStatus, gin.H{"task_id": "replayed"})
+	})
+	SetInternalRelayHandler(engine)
+	t.Cleanup(func() { SetInternalRelayHandler(nil) })
+
+	replay := func() int {
+		router := gin.New()
+		router.POST("/v1/tasks/:id/replay", func(c *gin.Context) {
+			c.Set("id", 1)
+			ReplayUserTask(c)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/tasks/origin/replay", nil)
+		req.Header.Set("Authorization", "Bearer sk-test")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := replay(); code != http.StatusOK {
+		t.Fatalf("replay status = %d, want 200", code)
+	}
+	if gotParent != "origin" || gotAuth != "Bearer sk-test" {
+		t.Fatalf("internal request parent=%q auth=%q", gotParent, gotAuth)
+	}
+
+	// 提交失败时归还名额
+	upstreamStatus = http.StatusInternalServerError
+	for i := 0; i < model.MaxTaskReplays; i++ {
+		if code := replay(); code != http.StatusInternalServerError {
+			t.Fatalf("failed replay status = %d, want 500", code)
+		}
+	}
+	upstreamStatus = http.StatusOK
+	for i := 1; i < model.MaxTaskReplays; i++ {
+		if code := replay(); code != http.StatusOK {
+			t.Fatalf("replay #%d status = %d, want 200", i+1, code)
+		}
+	}
+	if code := replay(); code != http.StatusTooManyRequests {
+		t.Fatalf("replay over limit status = %d, want 429", code)
+	}
+}
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// mirrorTaskToShadow 主渠道开启影子模式时按采样比例将本次任务提交异步镜像到影子渠道，并记录双方的提交结果。
+// 影子提交经由内部请求指定影子渠道处理，不向用户扣费、不写入任务表，消耗的额度计入对比结果，
+// 当日累计额度达到上限后停止镜像。主渠道因本地校验未提交到上游或请求体不是 JSON 时不镜像
+func mirrorTaskToShadow(c *gin.Context, info *relaycommon.RelayInfo, channelId int, primaryErr *dto.TaskError) {
+	if primaryErr != nil && primaryErr.LocalError {
+		return
+	}
+	// 影子提交本身不再镜像
+	if _, ok := common.GetContextKeyType[*model.ShadowResult](c, constant.ContextKeyTaskShadowResult); ok {
+		return
+	}
+	if c.Request.Method != http.MethodPost || c.ContentType() != "application/json" {
+		return
+	}
+	primary, err := model.CacheGetChannel(channelId)
+	if err != nil {
+		return
+	}
+	shadowId := primary.GetShadowChannelId()
+	setting := primary.GetSetting()
+	if shadowId <= 0 || shadowId == channelId || setting.ShadowSampleRate <= 0 || rand.Float64() >= setting.ShadowSampleRate {
+		return
+	}
+	shadow, err := model.CacheGetChannel(shadowId)
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("shadow channel #%d not found: %s", shadowId, err.Error()))
+		return
+	}
+	if shadow.Status != common.ChannelStatusEnabled {
+		return
+	}
+	if setting.ShadowQuotaLimit > 0 {
+		now := time.Now()
+		dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
+		used, err := model.SumShadowQuota(channelId, dayStart)
+		if err != nil {
+			logger.LogError(c, "sum shadow quota failed: "+err.Error())
+			return
+		}
+		if used >= int64(setting.ShadowQuotaLimit) {
+			return
+		}
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return
+	}
+
+	result := &model.ShadowResult{
+		ChannelId:       channelId,
+		ShadowChannelId: shadowId,
+		UserId:          info.UserId,
+		ModelName:       info.OriginModelName,
+		Action:          info.Action,
+		PrimarySuccess:  primaryErr == nil,
+	}
+	if primaryErr != nil {
+		result.PrimaryErrorCode = primaryErr.Code
+	}
+	// 请求结束后 gin.Context 会被复用，需在当前请求内复制影子请求所需的数据
+	request := internalRelayRequest{
+		Path:       c.Request.URL.RequestURI(),
+		Body:       body,
+		Header:     c.Request.Header.Clone(),
+		RemoteAddr: c.Request.RemoteAddr,
+		Values: map[constant.ContextKey]any{
+			constant.ContextKeyTokenSpecificChannelId: strconv.Itoa(shadowId),
+			constant.ContextKeyTaskShadowResult:       result,
+		},
+	}
+
+	gopool.Go(func() {
+		startTime := time.Now()
+		resp, err := serveInternalRelay(context.Background(), request)
+		result.ShadowLatencyMs = time.Since(startTime).Milliseconds()
+		if err != nil {
+			result.ShadowStatusCode = http.StatusInternalServerError
+			result.ShadowError = err.Error()
+		} else {
+			result.ShadowStatusCode = resp.StatusCode()
+			result.ShadowSuccess = resp.Success()
+			if !result.ShadowSuccess {
+				var taskErr dto.TaskError
+				if common.Unmarshal(resp.Body(), &taskErr) == nil {
+					result.ShadowErrorCode = taskErr.Code
+					result.ShadowError = taskErr.Message
+				} else {
+					result.ShadowError = string(resp.Body())
+				}
+			}
+		}
+		if err := result.Insert(); err != nil {
+			common.SysError("insert shadow result failed: " + err.Error())
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMirrorTaskToShadow(t *testing.T) {
+	setupTestDB(t, &model.Channel{}, &model.ShadowResult{})
+	gin.SetMode(gin.TestMode)
+	primary := &model.Channel{Id: 1, Name: "primary", Key: "sk-primary", Status: common.ChannelStatusEnabled, ShadowChannelId: common.GetPointer(2)}
+	primary.SetSetting(dto.ChannelSettings{ShadowSampleRate: 1, ShadowQuotaLimit: 100})
+	shadow := &model.Channel{Id: 2, Name: "shadow", Key: "sk-shadow", Status: common.ChannelStatusEnabled}
+	for _, ch := range []*model.Channel{primary, shadow} {
+		if err := model.DB.Create(ch).Error; err != nil {
+			t.Fatalf("create channel failed: %v", err)
+		}
+	}
+
+	// 内部请求指定影子渠道处理，由任务提交流程回填消耗的额度与上游任务 ID
+	var gotChannel, gotAuth string
+	engine := gin.New()
+	engine.Use(middleware.InternalRequestValues())
+	engine.POST("/v1/video/generations", func(c *gin.Context) {
+		gotChannel = common.GetContextKeyString(c, constant.ContextKeyTokenSpecificChannelId)
+		gotAuth = c.GetHeader("Authorization")
+		result, ok := common.GetContextKeyType[*model.ShadowResult](c, constant.ContextKeyTaskShadowResult)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"code": "missing_shadow_result"})
+			return
+		}
+		result.ShadowQuota = 100
+		result.ShadowTaskId = "shadow-task"
+		c.JSON(http.StatusOK, gin.H{"task_id": "shadow-task"})
+	})
+	SetInternalRelayHandler(engine)
+	t.Cleanup(func() { SetInternalRelayHandler(nil) })
+
+	mirror := func() {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/video/generations", strings.NewReader(`{"model":"m","prompt":"p"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request.Header.Set("Authorization", "Bearer sk-test")
+		info := &relaycommon.RelayInfo{UserId: 1, OriginModelName: "m", TaskRelayInfo: &relaycommon.TaskRelayInfo{}}
+		mirrorTaskToShadow(c, info, primary.Id, nil)
+	}
+	waitResults := func(want int64) {
+		t.Helper()
+		var count int64
+		for i := 0; i < 100; i++ {
+			model.DB.Model(&model.ShadowResult{}).Count(&count)
+			if count == want {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("shadow results = %d, want %d", count, want)
+	}
+
+	mirror()
+	waitResults(1)
+	if gotChannel != "2" || gotAuth != "Bearer sk-test" {
+		t.Fatalf("internal request channel=%q auth=%q", gotChannel, gotAuth)
+	}
+	var result model.ShadowResult
+	if err := model.DB.First(&result).Error; err != nil {
+		t.Fatalf("load shadow result failed: %v", err)
+	}
+	if !result.ShadowSuccess || result.ShadowQuota != 100 || result.ShadowTaskId != "shadow-task" || result.ShadowChannelId != 2 {
+		t.Fatalf("unexpected shadow result: %+v", result)
+	}
+
+	// 当日镜像额度已达上限，不再镜像
+	mirror()
+	time.Sleep(50 * time.Millisecond)
+	waitResults(1)
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCancelUserTask(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	gin.SetMode(gin.TestMode)
+	if err := model.DB.Create(&model.User{Id: 1, Username: "cancel", Quota: 0}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	task := createTestTask(t, &model.Task{TaskID: "task_cancel", UserId: 1, Status: model.TaskStatusInProgress, Quota: 100})
+
+	cancel := func(userId int) int {
+		router := gin.New()
+		router.POST("/v1/tasks/:id/cancel", func(c *gin.Context) {
+			c.Set("id", userId)
+			CancelUserTask(c)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/tasks/task_cancel/cancel", nil))
+		return w.Code
+	}
+
+	if code := cancel(2); code != http.StatusNotFound {
+		t.Fatalf("other user's cancel status = %d, want 404", code)
+	}
+	if code := cancel(1); code != http.StatusOK {
+		t.Fatalf("cancel status = %d, want 200", code)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if stored.Status != model.TaskStatusFailure || stored.Quota != 0 || stored.FailReason != model.TaskCancelReasonUser {
+		t.Fatalf("unexpected task after cancel: status=%s quota=%d reason=%q", stored.Status, stored.Quota, stored.FailReason)
+	}
+	user, _ := model.GetUserById(1, false)
+	if user.Quota != 100 {
+		t.Fatalf("user quota = %d, want refund of 100", user.Quota)
+	}
+
+	// 已结束的任务不会重复退款
+	if code := cancel(1); code != http.StatusConflict {
+		t.Fatalf("second cancel status = %d, want 409", code)
+	}
+	user, _ = model.GetUserById(1, false)
+	if user.Quota != 100 {
+		t.Fatalf("user quota = %d after second cancel, want 100", user.Quota)
+	}
+}
+
+func TestCancelTasksBulk(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Token{}, &model.Log{})
+	gin.SetMode(gin.TestMode)
+	for _, user := range []*model.User{{Id: 1, Username: "u1", AffCode: "a1"}, {Id: 2, Username: "u2", AffCode: "a2", Quota: 10}} {
+		if err := model.DB.Create(user).Error; err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+	}
+	if err := model.DB.Create(&model.Token{Id: 1, UserId: 1, Key: "bulk", Name: "bulk"}).Error; err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	withToken := &model.Task{TaskID: "a", ChannelId: 7, UserId: 1, Status: model.TaskStatusQueued, Quota: 100}
+	withToken.PrivateData.TokenId = 1
+	withToken.PrivateData.TokenKey = "bulk"
+	for _, task := range []*model.Task{
+		withToken,
+		{TaskID: "b", ChannelId: 7, UserId: 1, Status: model.TaskStatusSubmitted, Quota: 50},
+		{TaskID: "c", ChannelId: 7, UserId: 2, Status: model.TaskStatusSubmitted, Quota: 30},
+		{TaskID: "d", ChannelId: 7, UserId: 2, Status: model.TaskStatusInProgress, Quota: 999},
+		{TaskID: "e", ChannelId: 8, UserId: 2, Status: model.TaskStatusQueued, Quota: 999},
+	} {
+		createTestTask(t, task)
+	}
+
+	cancel := func(body string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/api/task/cancel", CancelTasksBulk)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/task/cancel", strings.NewReader(body)))
+		return w
+	}
+
+	w := cancel(`{"channel_id":7,"status":["queued","SUBMITTED"]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"count":3`) || !strings.Contains(w.Body.String(), `"refunded_quota":180`) {
+		t.Fatalf("bulk cancel status = %d, body %s", w.Code, w.Body.String())
+	}
+	user1, _ := model.GetUserById(1, false)
+	user2, _ := model.GetUserById(2, false)
+	if user1.Quota != 150 || user2.Quota != 40 {
+		t.Fatalf("user quotas = %d, %d; want 150, 40", user1.Quota, user2.Quota)
+	}
+	var token model.Token
+	model.DB.First(&token, 1)
+	if token.RemainQuota != 100 {
+		t.Fatalf("token quota = %d, want refund of 100", token.RemainQuota)
+	}
+	var remaining int64
+	model.DB.Model(&model.Task{}).Where("status <> ?", model.TaskStatusFailure).Count(&remaining)
+	if remaining != 2 {
+		t.Fatalf("unfinished tasks = %d, want 2", remaining)
+	}
+
+	// 再次取消不会重复退款
+	w = cancel(`{"channel_id":7,"status":["QUEUED","SUBMITTED"]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"count":0`) {
+		t.Fatalf("second bulk cancel status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w = cancel(`{"channel_id":7,"status":["SUCCESS"]}`); strings.Contains(w.Body.String(), `"success":true`) {
+		t.Fatalf("cancelling finished tasks should fail, body %s", w.Body.String())
+	}
+	if w = cancel(`{"status":["QUEUED"]}`); strings.Contains(w.Body.String(), `"success":true`) {
+		t.Fatalf("missing channel_id should fail, body %s", w.Body.String())
+	}
+}
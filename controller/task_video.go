@@ -2,9 +2,11 @@ package controller
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -12,13 +14,20 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/service/events"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
+// videoTaskBatchSize is the number of tasks queried per request for adaptors
+// implementing channel.BatchTaskAdaptor.
+const videoTaskBatchSize = 20
+
 func UpdateVideoTaskAll(ctx context.Context, platform constant.TaskPlatform, taskChannelM map[int][]string, taskM map[string]*model.Task) error {
 	for channelId, taskIds := range taskChannelM {
 		if err := updateVideoTaskAll(ctx, platform, channelId, taskIds, taskM); err != nil {
@@ -35,11 +44,7 @@ func updateVideoTaskAll(ctx context.Context, platform constant.TaskPlatform, cha
 	}
 	cacheGetChannel, err := model.CacheGetChannel(channelId)
 	if err != nil {
-		errUpdate := model.TaskBulkUpdate(taskIds, map[string]any{
-			"fail_reason": fmt.Sprintf("Failed to get channel info, channel ID: %d", channelId),
-			"status":      "FAILURE",
-			"progress":    "100%",
-		})
+		errUpdate := failTasksInBulk(ctx, taskIds, taskM, fmt.Sprintf("Failed to get channel info, channel ID: %d", channelId))
 		if errUpdate != nil {
 			common.SysLog(fmt.Sprintf("UpdateVideoTask error: %v", errUpdate))
 		}
@@ -55,6 +60,11 @@ func updateVideoTaskAll(ctx context.Context, platform constant.TaskPlatform, cha
 	}
 	info.ApiKey = cacheGetChannel.Key
 	adaptor.Init(info)
+	taskIds = filterVideoTasksDueForPoll(taskIds, taskM)
+	taskIds = sortVideoTasksByPriority(ctx, channelId, taskIds)
+	if batchAdaptor, ok := adaptor.(channel.BatchTaskAdaptor); ok {
+		taskIds = updateVideoTaskBatch(ctx, batchAdaptor, cacheGetChannel, taskIds, taskM)
+	}
 	for _, taskId := range taskIds {
 		if err := updateVideoSingleTask(ctx, adaptor, cacheGetChannel, taskId, taskM); err != nil {
 			logger.LogError(ctx, fmt.Sprintf("Failed to update video task %s: %s", taskId, err.Error()))
@@ -63,6 +73,86 @@ func updateVideoTaskAll(ctx context.Context, platform constant.TaskPlatform, cha
 	return nil
 }
 
+// updateVideoTaskBatch polls tasks in groups of videoTaskBatchSize through the
+// adaptor's batch endpoint. Tasks that cannot be batched (private keys, missing
+// from the upstream response, failed batches) are returned for sequential polling.
+func updateVideoTaskBatch(ctx context.Context, adaptor channel.BatchTaskAdaptor, channel *model.Channel, taskIds []string, taskM map[string]*model.Task) []string {
+	baseURL := constant.ChannelBaseURLs[channel.Type]
+	if channel.GetBaseURL() != "" {
+		baseURL = channel.GetBaseURL()
+	}
+	proxy := channel.GetSetting().Proxy
+
+	remaining := make([]string, 0)
+	batchIds := make([]string, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		if task == nil || task.PrivateData.Key != "" {
+			remaining = append(remaining, taskId)
+			continue
+		}
+		if timedOut, err := failVideoTaskIfTimedOut(ctx, task); timedOut || err != nil {
+			if err != nil {
+				logger.LogError(ctx, fmt.Sprintf("Failed to update video task %s: %s", taskId, err.Error()))
+			}
+			continue
+		}
+		batchIds = append(batchIds, taskId)
+	}
+
+	for start := 0; start < len(batchIds); start += videoTaskBatchSize {
+		batch := batchIds[start:min(start+videoTaskBatchSize, len(batchIds))]
+		release, err := relaycommon.DefaultChannelConcurrencyGuard.Acquire(ctx, channel.Id, channel.GetConcurrencyLimit())
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("Channel #%d skip batch polling: %s", channel.Id, err.Error()))
+			continue
+		}
+		startTime := time.Now()
+		results, err := adaptor.FetchTaskBatch(baseURL, channel.Key, batch, proxy)
+		release()
+		logger.LogInfo(ctx, fmt.Sprintf("Channel #%d batch fetched %d video tasks in %dms", channel.Id, len(batch), time.Since(startTime).Milliseconds()))
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("Channel #%d batch fetch failed, falling back to sequential: %s", channel.Id, err.Error()))
+			remaining = append(remaining, batch...)
+			continue
+		}
+		handled := make(map[string]bool, len(results))
+		for _, taskResult := range results {
+			task := taskM[taskResult.TaskID]
+			if task == nil || handled[taskResult.TaskID] {
+				continue
+			}
+			handled[taskResult.TaskID] = true
+			if len(taskResult.Data) > 0 {
+				task.Data = redactVideoResponseBody(taskResult.Data)
+			}
+			if err := applyVideoTaskResult(ctx, task, taskResult); err != nil {
+				logger.LogError(ctx, fmt.Sprintf("Failed to update video task %s: %s", taskResult.TaskID, err.Error()))
+			}
+		}
+		for _, taskId := range batch {
+			if !handled[taskId] {
+				remaining = append(remaining, taskId)
+			}
+		}
+	}
+	return remaining
+}
+
+// observeTaskPoll 记录轮询上游任务状态的 Prometheus 指标，请求失败或上游 5xx 计为渠道错误
+func observeTaskPoll(channelId int, modelName string, startTime time.Time, resp *http.Response, err error) {
+	status := metrics.StatusError
+	if err == nil {
+		status = metrics.StatusLabel(resp.StatusCode)
+	}
+	metrics.ObserveRequest(channelId, modelName, status, time.Since(startTime))
+	if err != nil {
+		metrics.IncChannelError(channelId, "fetch_task_failed")
+	} else if resp.StatusCode >= http.StatusInternalServerError {
+		metrics.IncChannelError(channelId, fmt.Sprintf("status_%d", resp.StatusCode))
+	}
+}
+
 func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, channel *model.Channel, taskId string, taskM map[string]*model.Task) error {
 	baseURL := constant.ChannelBaseURLs[channel.Type]
 	if channel.GetBaseURL() != "" {
@@ -76,52 +166,52 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 		return fmt.Errorf("task %s not found", taskId)
 	}
 
-	if constant.VideoTaskTimeoutMinutes > 0 && task.SubmitTime > 0 {
-		elapsed := time.Now().Unix() - task.SubmitTime
-		if elapsed > int64(constant.VideoTaskTimeoutMinutes*60) {
-			logger.LogWarn(ctx, fmt.Sprintf("Task %s timed out after %d seconds, marking as failure", taskId, elapsed))
-			preStatus := task.Status
-			task.Status = model.TaskStatusFailure
-			task.Progress = "100%"
-			task.FinishTime = time.Now().Unix()
-			task.FailReason = fmt.Sprintf("task timed out after %d minutes", constant.VideoTaskTimeoutMinutes)
-			quota := task.Quota
-			if quota != 0 && preStatus != model.TaskStatusFailure {
-				task.Quota = 0
-			}
-			if err := task.Update(); err != nil {
-				return fmt.Errorf("update timed out task failed: %w", err)
-			}
-			if quota != 0 && preStatus != model.TaskStatusFailure {
-				model.IncreaseUserQuota(task.UserId, quota, false)
-				if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
-					model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, quota)
-				}
-				logContent := fmt.Sprintf("Video task timed out %s, refund %s", task.TaskID, logger.LogQuota(quota))
-				model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
-			}
-			return nil
-		}
+	if timedOut, err := failVideoTaskIfTimedOut(ctx, task); timedOut || err != nil {
+		return err
 	}
 
+	// 渠道熔断期间跳过本轮轮询，不计入重试次数
+	releaseProbe, err := service.DefaultCircuitBreaker.Allow(ctx, channel.Id)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("skip polling task %s: %s", taskId, err.Error()))
+		return nil
+	}
+	defer releaseProbe()
+
+	// 轮询与转发请求共用渠道的并发上限，名额已满时跳过本轮轮询
+	release, err := relaycommon.DefaultChannelConcurrencyGuard.Acquire(ctx, channel.Id, channel.GetConcurrencyLimit())
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("skip polling task %s: %s", taskId, err.Error()))
+		return nil
+	}
+	defer release()
+
 	key := channel.Key
 
 	privateData := task.PrivateData
 	if privateData.Key != "" {
 		key = privateData.Key
 	}
+	startTime := time.Now()
 	resp, err := adaptor.FetchTask(baseURL, key, map[string]any{
 		"task_id": taskId,
 		"action":  task.Action,
 	}, proxy)
+	service.DefaultCircuitBreaker.Record(ctx, channel.Id, err == nil && resp.StatusCode < http.StatusInternalServerError)
+	observeTaskPoll(channel.Id, task.Properties.OriginModelName, startTime, resp, err)
 	if err != nil {
-		return fmt.Errorf("fetchTask failed for task %s: %w", taskId, err)
+		return scheduleVideoTaskRetry(ctx, task, channel, fmt.Errorf("fetchTask failed for task %s: %w", taskId, err))
 	}
 	//if resp.StatusCode != http.StatusOK {
 	//return fmt.Errorf("get Video Task status code: %d", resp.StatusCode)
 	//}
 	defer resp.Body.Close()
-	responseBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return scheduleVideoTaskRetry(ctx, task, channel, fmt.Errorf("fetchTask failed for task %s: upstream status code %d", taskId, resp.StatusCode))
+	}
+	task.RetryCount = 0
+	task.NextRetryAt = 0
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return fmt.Errorf("readAll failed for task %s: %w", taskId, err)
 	}
@@ -148,16 +238,45 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 
 	logger.LogDebug(ctx, fmt.Sprintf("UpdateVideoSingleTask taskResult: %+v", taskResult))
 
+	return applyVideoTaskResult(ctx, task, taskResult)
+}
+
+// applyVideoTaskResult moves a task to the state reported by upstream,
+// settling quota differences and refunds along the way.
+func applyVideoTaskResult(ctx context.Context, task *model.Task, taskResult *relaycommon.TaskInfo) error {
+	taskId := task.TaskID
+
 	now := time.Now().Unix()
 	if taskResult.Status == "" {
-		//return fmt.Errorf("task %s status is empty", taskId)
-		taskResult = relaycommon.FailTaskInfo("upstream returned empty status")
+		// 部分上游在任务初始化期间会短暂返回空状态，连续超过上限后才判定失败
+		task.EmptyStatusCount++
+		if limit := taskResult.EmptyStatusRetryLimit(); task.EmptyStatusCount <= limit {
+			logger.LogWarn(ctx, fmt.Sprintf("Task %s upstream returned empty status (%d/%d), will retry", taskId, task.EmptyStatusCount, limit))
+			if _, err := task.UpdatePollResult(); err != nil {
+				return fmt.Errorf("update task %s empty status count failed: %w", taskId, err)
+			}
+			return nil
+		}
+		taskResult = relaycommon.FailTaskInfo(fmt.Sprintf("upstream returned empty status %d times", task.EmptyStatusCount))
+	} else {
+		task.EmptyStatusCount = 0
+	}
+
+	// 上游直接返回视频内容时先转存到任务存储，转存失败时保持原状态等待下次轮询
+	if taskResult.Status == model.TaskStatusSuccess && strings.HasPrefix(taskResult.RemoteUrl, "data:") {
+		if err := storeInlineVideoResult(ctx, task, taskResult); err != nil {
+			if !errors.Is(err, errTaskStorageNotConfigured) {
+				return fmt.Errorf("store inline video of task %s failed: %w", taskId, err)
+			}
+			taskResult = relaycommon.FailTaskInfo("upstream returned the video inline but task storage is not configured")
+		}
 	}
 
 	// 记录原本的状态，防止重复退款
 	shouldRefund := false
 	quota := task.Quota
 	preStatus := task.Status
+	preProgress := task.Progress
 
 	task.Status = model.TaskStatus(taskResult.Status)
 	switch taskResult.Status {
@@ -273,7 +392,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 							logger.LogQuota(preConsumedQuota),
 							taskResult.TotalTokens,
 						))
-						if err := model.IncreaseUserQuota(task.UserId, refundQuota, false); err != nil {
+						if err := service.RefundTaskQuota(ctx, task, refundQuota); err != nil {
 							logger.LogError(ctx, fmt.Sprintf("退还预扣费失败: %s", err.Error()))
 						} else {
 							task.Quota = actualQuota // 更新任务记录的实际扣费额度
@@ -327,10 +446,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 				}
 				refundQuota := preChargedQuota - actualQuota
 				if refundQuota > 0 {
-					model.IncreaseUserQuota(task.UserId, refundQuota, false)
-					if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
-						model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, refundQuota)
-					}
+					service.RefundTaskQuota(ctx, task, refundQuota)
 				} else if refundQuota < 0 {
 					// 补扣
 					model.DecreaseUserQuota(task.UserId, -refundQuota)
@@ -412,10 +528,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 
 				refundQuota := preChargedQuota - actualQuota
 				if refundQuota > 0 {
-					model.IncreaseUserQuota(task.UserId, refundQuota, false)
-					if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
-						model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, refundQuota)
-					}
+					service.RefundTaskQuota(ctx, task, refundQuota)
 				} else if refundQuota < 0 {
 					model.DecreaseUserQuota(task.UserId, -refundQuota)
 					if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
@@ -523,10 +636,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 				}
 				refundDiff := task.Quota - moderationQuota
 				if refundDiff > 0 {
-					model.IncreaseUserQuota(task.UserId, refundDiff, false)
-					if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
-						model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, refundDiff)
-					}
+					service.RefundTaskQuota(ctx, task, refundDiff)
 				}
 				task.Quota = moderationQuota
 
@@ -599,18 +709,26 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 	if taskResult.Progress != "" {
 		task.Progress = taskResult.Progress
 	}
-	if err := task.Update(); err != nil {
+	if updated, err := task.UpdatePollResult(); err != nil {
 		common.SysLog("UpdateVideoTask task error: " + err.Error())
 		shouldRefund = false
+	} else if !updated {
+		// 轮询期间任务已被取消或过期，保留其终态与退款结果
+		logger.LogInfo(ctx, fmt.Sprintf("Task %s finished elsewhere during polling, skip poll result", taskId))
+		shouldRefund = false
+	} else {
+		notifyTaskStatusChanged(ctx, task, preStatus, preProgress)
+		if preStatus != task.Status && task.Status == model.TaskStatusSuccess {
+			service.DefaultVideoQualityScorer.ScoreTask(ctx, task)
+			service.DefaultVideoThumbnailExtractor.ExtractTask(ctx, task)
+			service.GetSignedURLProxy().MirrorTaskOutput(task)
+		}
 	}
 
 	if shouldRefund {
-		if err := model.IncreaseUserQuota(task.UserId, quota, false); err != nil {
+		if err := service.RefundTaskQuota(ctx, task, quota); err != nil {
 			logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
 		}
-		if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
-			model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, quota)
-		}
 		logContent := fmt.Sprintf("Video async task failed %s, refund %s", task.TaskID, logger.LogQuota(quota))
 		model.RecordLog(task.UserId, model.LogTypeSystem, logContent)
 	}
@@ -618,6 +736,170 @@ func updateVideoSingleTask(ctx context.Context, adaptor channel.TaskAdaptor, cha
 	return nil
 }
 
+var errTaskStorageNotConfigured = errors.New("task storage is not configured")
+
+// storeInlineVideoResult uploads a video returned inline as a data: URL to
+// task storage, records the object key on the task and points the result's
+// RemoteUrl at the stored object.
+func storeInlineVideoResult(ctx context.Context, task *model.Task, taskResult *relaycommon.TaskInfo) error {
+	proxy := service.GetSignedURLProxy()
+	if proxy == nil {
+		return errTaskStorageNotConfigured
+	}
+	mimeType, data, err := service.DecodeBase64FileData(taskResult.RemoteUrl)
+	if err != nil {
+		return err
+	}
+	content, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("decode inline video failed: %w", err)
+	}
+	key, uri, err := proxy.StoreTaskOutput(ctx, task, mimeType, content)
+	if err != nil {
+		return err
+	}
+	if err := task.UpdateStorageKey(key); err != nil {
+		return err
+	}
+	taskResult.RemoteUrl = uri
+	return nil
+}
+
+// failVideoTaskIfTimedOut marks a task as failed and refunds its quota once it
+// passes its own execution_expires_after deadline or, when none was requested,
+// has been pending longer than its platform timeout (VideoTaskTimeoutMinutes
+// unless overridden per platform).
+func failVideoTaskIfTimedOut(ctx context.Context, task *model.Task) (bool, error) {
+	if task.SubmitTime <= 0 {
+		return false, nil
+	}
+	now := time.Now().Unix()
+	elapsed := now - task.SubmitTime
+	var failReason string
+	if task.Properties.ExecutionExpiresAfterSec > 0 {
+		if !task.IsExpired(now) {
+			return false, nil
+		}
+		failReason = fmt.Sprintf("task expired after %d seconds", task.Properties.ExecutionExpiresAfterSec)
+	} else {
+		timeoutMinutes := service.TaskTimeoutMinutes(task.Platform)
+		if timeoutMinutes <= 0 || elapsed <= int64(timeoutMinutes*60) {
+			return false, nil
+		}
+		failReason = fmt.Sprintf("task timed out after %d minutes", timeoutMinutes)
+	}
+	logger.LogWarn(ctx, fmt.Sprintf("Task %s timed out after %d seconds, marking as failure", task.TaskID, elapsed))
+	if err := failVideoTask(ctx, task, failReason, "Video task timed out"); err != nil {
+		return true, fmt.Errorf("update timed out task failed: %w", err)
+	}
+	return true, nil
+}
+
+// failVideoTask marks a task as failed, refunds its quota and notifies
+// subscribers. refundLogPrefix is used for the user's refund log entry. The
+// update is conditional, so a task already failed by TaskExpiryJob or a cancel
+// is not refunded twice.
+func failVideoTask(ctx context.Context, task *model.Task, failReason string, refundLogPrefix string) error {
+	_, err := cancelTask(ctx, task, failReason, refundLogPrefix)
+	return err
+}
+
+// cancelTask fails an unfinished task with a conditional update, then refunds
+// its quota and notifies subscribers. When statuses is given the task is only
+// cancelled while it is still in one of them. It returns false without side
+// effects when the task has already moved on.
+func cancelTask(ctx context.Context, task *model.Task, reason string, refundLogPrefix string, statuses ...model.TaskStatus) (bool, error) {
+	quota := task.Quota
+	now := time.Now().Unix()
+	var updated bool
+	var err error
+	if len(statuses) > 0 {
+		updated, err = task.FailIfStatusIn(statuses, reason, now)
+	} else {
+		updated, err = task.FailIfUnfinished(reason, now)
+	}
+	if err != nil || !updated {
+		return false, err
+	}
+	service.NotifyTaskUpdated(task.TaskID)
+	events.Publish(&events.TaskCancelledEvent{
+		TaskID:      task.TaskID,
+		UserId:      task.UserId,
+		ChannelId:   task.ChannelId,
+		Reason:      reason,
+		RefundQuota: quota,
+		Timestamp:   now,
+	})
+	DispatchTaskFinishedWebhooks(ctx, task)
+	if quota != 0 {
+		if err := service.RefundTaskQuota(ctx, task, quota); err != nil {
+			logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
+		}
+		model.RecordLog(task.UserId, model.LogTypeSystem, fmt.Sprintf("%s %s, refund %s", refundLogPrefix, task.TaskID, logger.LogQuota(quota)))
+	}
+	return true, nil
+}
+
+// filterVideoTasksDueForPoll drops tasks that are backing off after a failed poll.
+func filterVideoTasksDueForPoll(taskIds []string, taskM map[string]*model.Task) []string {
+	now := time.Now().Unix()
+	due := make([]string, 0, len(taskIds))
+	for _, taskId := range taskIds {
+		if task := taskM[taskId]; task != nil && task.NextRetryAt > now {
+			continue
+		}
+		due = append(due, taskId)
+	}
+	return due
+}
+
+// sortVideoTasksByPriority orders tasks so that higher-priority tasks are polled
+// first. The original order is kept if the lookup fails.
+func sortVideoTasksByPriority(ctx context.Context, channelId int, taskIds []string) []string {
+	tasks, err := model.GetPendingTasksByPriorityAndChannel(channelId, taskIds)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Channel #%d failed to order tasks by priority: %s", channelId, err.Error()))
+		return taskIds
+	}
+	sorted := make([]string, 0, len(taskIds))
+	seen := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		if seen[task.TaskID] {
+			continue
+		}
+		seen[task.TaskID] = true
+		sorted = append(sorted, task.TaskID)
+	}
+	// 查询结果中缺失的任务保持原顺序排在最后
+	for _, taskId := range taskIds {
+		if !seen[taskId] {
+			sorted = append(sorted, taskId)
+		}
+	}
+	return sorted
+}
+
+// scheduleVideoTaskRetry records a failed poll and schedules the next attempt
+// according to the channel's retry policy. Once the policy is exhausted the
+// task is failed and refunded.
+func scheduleVideoTaskRetry(ctx context.Context, task *model.Task, channel *model.Channel, pollErr error) error {
+	policy := service.GetTaskRetryPolicy(channel.GetSetting())
+	task.RetryCount++
+	if policy.Exhausted(task.RetryCount) {
+		logger.LogWarn(ctx, fmt.Sprintf("Task %s polling failed %d times, marking as failure: %s", task.TaskID, task.RetryCount, pollErr.Error()))
+		failReason := fmt.Sprintf("task polling failed after %d retries", policy.MaxRetries)
+		if err := failVideoTask(ctx, task, failReason, "Video task polling failed"); err != nil {
+			return fmt.Errorf("update task after polling retries exhausted failed: %w", err)
+		}
+		return pollErr
+	}
+	task.NextRetryAt = time.Now().Add(policy.NextDelay(task.RetryCount)).Unix()
+	if _, err := task.UpdatePollResult(); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Failed to record retry for task %s: %s", task.TaskID, err.Error()))
+	}
+	return pollErr
+}
+
 func redactVideoResponseBody(body []byte) []byte {
 	var m map[string]any
 	if err := json.Unmarshal(body, &m); err != nil {
@@ -651,3 +933,83 @@ func truncateBase64(s string) string {
 	}
 	return s[:maxKeep] + "..."
 }
+
+// DispatchTaskWebhook 在任务进入终态后推送回调
+func DispatchTaskWebhook(ctx context.Context, task *model.Task) {
+	if task.CallbackURL == "" {
+		return
+	}
+	payload, err := relay.BuildTaskWebhookPayload(task)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Build webhook payload for task %s failed: %s", task.TaskID, err.Error()))
+		return
+	}
+	service.DefaultWebhookDispatcher.Dispatch(task, payload)
+}
+
+// DispatchTaskProgressWebhook 在任务状态或进度变化后推送进度回调
+func DispatchTaskProgressWebhook(ctx context.Context, task *model.Task) {
+	if task.ProgressCallbackURL == "" {
+		return
+	}
+	payload, err := relay.BuildTaskWebhookPayload(task)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("Build progress webhook payload for task %s failed: %s", task.TaskID, err.Error()))
+		return
+	}
+	service.DefaultProgressWebhookDispatcher.Dispatch(task, payload)
+}
+
+// notifyTaskStatusChanged 在任务的状态或进度更新并保存后调用，所有平台的轮询共用：
+// 通知订阅者，进度变化时推送进度回调，状态变化时发布事件，进入终态时推送结果回调
+func notifyTaskStatusChanged(ctx context.Context, task *model.Task, preStatus model.TaskStatus, preProgress string) {
+	service.NotifyTaskUpdated(task.TaskID)
+	if preStatus == task.Status && preProgress == task.Progress {
+		return
+	}
+	DispatchTaskProgressWebhook(ctx, task)
+	if preStatus == task.Status {
+		return
+	}
+	events.Publish(&events.TaskStatusChangedEvent{
+		TaskID:     task.TaskID,
+		UserId:     task.UserId,
+		ChannelId:  task.ChannelId,
+		FromStatus: string(preStatus),
+		ToStatus:   string(task.Status),
+		Progress:   task.Progress,
+		Timestamp:  time.Now().Unix(),
+	})
+	if task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure {
+		DispatchTaskWebhook(ctx, task)
+	}
+}
+
+// failTasksInBulk 将一组任务批量标记为失败并推送状态变化，用于渠道信息不可用等无法逐个轮询的情况
+func failTasksInBulk(ctx context.Context, taskIds []string, taskM map[string]*model.Task, failReason string) error {
+	if err := model.TaskBulkUpdate(taskIds, map[string]any{
+		"fail_reason": failReason,
+		"status":      "FAILURE",
+		"progress":    "100%",
+	}); err != nil {
+		return err
+	}
+	for _, taskId := range taskIds {
+		task := taskM[taskId]
+		if task == nil {
+			continue
+		}
+		preStatus, preProgress := task.Status, task.Progress
+		task.Status = model.TaskStatusFailure
+		task.Progress = "100%"
+		task.FailReason = failReason
+		notifyTaskStatusChanged(ctx, task, preStatus, preProgress)
+	}
+	return nil
+}
+
+// DispatchTaskFinishedWebhooks 任务在轮询之外被标记为终态（超时、过期、取消）后推送结果回调与进度回调
+func DispatchTaskFinishedWebhooks(ctx context.Context, task *model.Task) {
+	DispatchTaskProgressWebhook(ctx, task)
+	DispatchTaskWebhook(ctx, task)
+}
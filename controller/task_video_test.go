@@ -0,0 +1,332 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/service/events"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originLogDB, originSQLite, originRedis := model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled
+	model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled = db, db, true, false
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled = originDB, originLogDB, originSQLite, originRedis
+	})
+}
+
+func createTestTask(t *testing.T, task *model.Task) *model.Task {
+	t.Helper()
+	if err := task.Insert(); err != nil {
+		t.Fatalf("insert task failed: %v", err)
+	}
+	return task
+}
+
+func reloadTestTask(t *testing.T, id int64) *model.Task {
+	t.Helper()
+	var task model.Task
+	if err := model.DB.First(&task, id).Error; err != nil {
+		t.Fatalf("reload task failed: %v", err)
+	}
+	return &task
+}
+
+func TestApplyVideoTaskResultStoresInlineVideo(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	var uploadedPath string
+	var uploaded []byte
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploadedPath = r.URL.Path
+		uploaded, _ = io.ReadAll(r.Body)
+	}))
+	defer storage.Close()
+	service.InitHttpClient()
+	service.SetSignedURLProxy(service.NewSignedURLProxy(storage.URL, "bucket", "", "ak", "sk", 0))
+	t.Cleanup(func() { service.SetSignedURLProxy(nil) })
+
+	task := createTestTask(t, &model.Task{TaskID: "task_inline", UserId: 1, Status: model.TaskStatusInProgress, Quota: 100})
+	err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{
+		Status:    model.TaskStatusSuccess,
+		Url:       "https://api.example.com/v1/videos/task_inline/content",
+		RemoteUrl: "data:video/mp4;base64,AAECAw==",
+		Progress:  "100%",
+	})
+	if err != nil {
+		t.Fatalf("applyVideoTaskResult: %v", err)
+	}
+
+	if uploadedPath != "/bucket/tasks/1/task_inline.mp4" || string(uploaded) != "\x00\x01\x02\x03" {
+		t.Fatalf("unexpected upload %s %v", uploadedPath, uploaded)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if stored.Status != model.TaskStatusSuccess || stored.PrivateData.StorageKey != "tasks/1/task_inline.mp4" {
+		t.Fatalf("unexpected task after success: status=%s storage_key=%q", stored.Status, stored.PrivateData.StorageKey)
+	}
+	if stored.FailReason != "https://api.example.com/v1/videos/task_inline/content" {
+		t.Fatalf("result url = %q", stored.FailReason)
+	}
+}
+
+func TestApplyVideoTaskResultFailsInlineVideoWithoutStorage(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	service.SetSignedURLProxy(nil)
+	if err := model.DB.Create(&model.User{Id: 1, Username: "inline", Quota: 0}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+
+	task := createTestTask(t, &model.Task{TaskID: "task_inline", UserId: 1, Status: model.TaskStatusInProgress, Quota: 100})
+	err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{
+		Status:    model.TaskStatusSuccess,
+		RemoteUrl: "data:video/mp4;base64,AAECAw==",
+	})
+	if err != nil {
+		t.Fatalf("applyVideoTaskResult: %v", err)
+	}
+
+	stored := reloadTestTask(t, task.ID)
+	if stored.Status != model.TaskStatusFailure || stored.Quota != 0 {
+		t.Fatalf("expected refunded failure, got status=%s quota=%d", stored.Status, stored.Quota)
+	}
+	user, _ := model.GetUserById(1, false)
+	if user.Quota != 100 {
+		t.Fatalf("user quota = %d, want refund of 100", user.Quota)
+	}
+}
+
+type fakeBatchTaskAdaptor struct {
+	results []*relaycommon.TaskInfo
+}
+
+func (a *fakeBatchTaskAdaptor) FetchTaskBatch(baseUrl, key string, taskIds []string, proxy string) ([]*relaycommon.TaskInfo, error) {
+	return a.results, nil
+}
+
+func TestUpdateVideoTaskBatchStoresTaskData(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	task := createTestTask(t, &model.Task{TaskID: "cgt-1", UserId: 1, Status: model.TaskStatusQueued})
+	adaptor := &fakeBatchTaskAdaptor{results: []*relaycommon.TaskInfo{{
+		TaskID: "cgt-1",
+		Status: model.TaskStatusInProgress,
+		Data:   []byte(`{"id":"cgt-1","status":"running"}`),
+	}}}
+
+	remaining := updateVideoTaskBatch(context.Background(), adaptor, &model.Channel{Id: 1}, []string{"cgt-1"}, map[string]*model.Task{"cgt-1": task})
+	if len(remaining) != 0 {
+		t.Fatalf("remaining = %v", remaining)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if stored.Status != model.TaskStatusInProgress || string(stored.Data) != `{"id":"cgt-1","status":"running"}` {
+		t.Fatalf("unexpected task after batch poll: status=%s data=%s", stored.Status, stored.Data)
+	}
+}
+
+func TestTaskLifecycleEventOrdering(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	if err := model.DB.Create(&model.User{Id: 1, Username: "events", Quota: 0}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	mem := events.NewMemorySubscriber()
+	unsubscribe := events.DefaultBus.Subscribe(mem)
+	defer unsubscribe()
+
+	task := createTestTask(t, &model.Task{TaskID: "task_events", UserId: 1, Status: model.TaskStatusSubmitted, Quota: 100})
+	// 重复的 IN_PROGRESS 不应产生新的事件
+	for _, status := range []model.TaskStatus{model.TaskStatusQueued, model.TaskStatusInProgress, model.TaskStatusInProgress, model.TaskStatusFailure} {
+		if err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{Status: string(status), Reason: "upstream error"}); err != nil {
+			t.Fatalf("applyVideoTaskResult(%s): %v", status, err)
+		}
+	}
+
+	want := [][2]string{
+		{string(model.TaskStatusSubmitted), string(model.TaskStatusQueued)},
+		{string(model.TaskStatusQueued), string(model.TaskStatusInProgress)},
+		{string(model.TaskStatusInProgress), string(model.TaskStatusFailure)},
+	}
+	var got [][2]string
+	for _, event := range mem.Events() {
+		changed, ok := event.Payload().(*events.TaskStatusChangedEvent)
+		if !ok || changed.TaskID != "task_events" {
+			continue
+		}
+		got = append(got, [2]string{changed.FromStatus, changed.ToStatus})
+	}
+	if len(got) != len(want) {
+		t.Fatalf("status events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if stored := reloadTestTask(t, task.ID); stored.Status != model.TaskStatusFailure {
+		t.Fatalf("final status = %s", stored.Status)
+	}
+}
+
+func TestFailVideoTaskIfTimedOutSkipsExpiredTask(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	if err := model.DB.Create(&model.User{Id: 1, Username: "timeout", Quota: 0}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	submitTime := time.Now().Add(-time.Hour).Unix()
+	task := createTestTask(t, &model.Task{TaskID: "task_timeout", UserId: 1, Status: model.TaskStatusInProgress, Quota: 100, SubmitTime: submitTime,
+		Properties: model.Properties{ExecutionExpiresAfterSec: 60}})
+
+	// 轮询读取任务后，TaskExpiryJob 已将其标记为失败并退款
+	expired := reloadTestTask(t, task.ID)
+	if updated, err := expired.FailIfUnfinished("task expired", time.Now().Unix()); err != nil || !updated {
+		t.Fatalf("FailIfUnfinished: updated=%v err=%v", updated, err)
+	}
+	if err := model.IncreaseUserQuota(1, 100, true); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+
+	timedOut, err := failVideoTaskIfTimedOut(context.Background(), task)
+	if err != nil || !timedOut {
+		t.Fatalf("failVideoTaskIfTimedOut: timedOut=%v err=%v", timedOut, err)
+	}
+	user, _ := model.GetUserById(1, false)
+	if user.Quota != 100 {
+		t.Fatalf("user quota = %d, want a single refund of 100", user.Quota)
+	}
+	if stored := reloadTestTask(t, task.ID); stored.FailReason != "task expired" {
+		t.Fatalf("fail reason overwritten: %q", stored.FailReason)
+	}
+}
+
+func TestApplyVideoTaskResultKeepsCancelledTask(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	mem := events.NewMemorySubscriber()
+	unsubscribe := events.DefaultBus.Subscribe(mem)
+	defer unsubscribe()
+
+	task := createTestTask(t, &model.Task{TaskID: "task_cancelled", UserId: 1, Status: model.TaskStatusInProgress, Quota: 100})
+	// 轮询读取任务后，用户取消了任务
+	cancelled := reloadTestTask(t, task.ID)
+	if updated, err := cancelled.FailIfUnfinished("cancelled by user", time.Now().Unix()); err != nil || !updated {
+		t.Fatalf("FailIfUnfinished: updated=%v err=%v", updated, err)
+	}
+
+	err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{
+		Status: model.TaskStatusSuccess,
+		Url:    "https://example.com/video.mp4",
+	})
+	if err != nil {
+		t.Fatalf("applyVideoTaskResult: %v", err)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if stored.Status != model.TaskStatusFailure || stored.Quota != 0 || stored.FailReason != "cancelled by user" {
+		t.Fatalf("cancelled task overwritten: status=%s quota=%d reason=%q", stored.Status, stored.Quota, stored.FailReason)
+	}
+	for _, event := range mem.Events() {
+		if changed, ok := event.Payload().(*events.TaskStatusChangedEvent); ok && changed.TaskID == "task_cancelled" {
+			t.Fatalf("unexpected status event for cancelled task: %+v", changed)
+		}
+	}
+}
+
+func TestVideoPollKeepsPriorityChangedDuringPoll(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	task := createTestTask(t, &model.Task{TaskID: "task_priority", UserId: 1, Status: model.TaskStatusQueued})
+	// 轮询读取任务后，用户调整了优先级
+	if updated, err := model.UpdateTaskPriority(task.ID, 2); err != nil || !updated {
+		t.Fatalf("UpdateTaskPriority: updated=%v err=%v", updated, err)
+	}
+
+	pollErr := errors.New("upstream unavailable")
+	if err := scheduleVideoTaskRetry(context.Background(), task, &model.Channel{Id: 1}, pollErr); !errors.Is(err, pollErr) {
+		t.Fatalf("scheduleVideoTaskRetry: %v", err)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if stored.Priority != 2 || stored.RetryCount != 1 || stored.NextRetryAt == 0 {
+		t.Fatalf("after retry: priority=%d retry_count=%d next_retry_at=%d", stored.Priority, stored.RetryCount, stored.NextRetryAt)
+	}
+
+	if err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{Status: model.TaskStatusInProgress}); err != nil {
+		t.Fatalf("applyVideoTaskResult: %v", err)
+	}
+	if stored = reloadTestTask(t, task.ID); stored.Priority != 2 || stored.Status != model.TaskStatusInProgress {
+		t.Fatalf("after poll: priority=%d status=%s", stored.Priority, stored.Status)
+	}
+}
+
+func TestVideoPollKeepsTagsChangedDuringPoll(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	task := createTestTask(t, &model.Task{TaskID: "task_tags", UserId: 1, Status: model.TaskStatusQueued, Tags: model.TaskTags{"draft"}})
+	// 轮询读取任务后，用户修改了标签
+	if _, err := model.UpdateUserTaskTags(1, "task_tags", model.TaskTags{"final", "campaign-a"}); err != nil {
+		t.Fatalf("UpdateUserTaskTags: %v", err)
+	}
+
+	if err := applyVideoTaskResult(context.Background(), task, &relaycommon.TaskInfo{Status: model.TaskStatusInProgress}); err != nil {
+		t.Fatalf("applyVideoTaskResult: %v", err)
+	}
+	stored := reloadTestTask(t, task.ID)
+	if len(stored.Tags) != 2 || stored.Tags[0] != "final" || stored.Status != model.TaskStatusInProgress {
+		t.Fatalf("after poll: tags=%v status=%s", stored.Tags, stored.Status)
+	}
+}
+
+type countingTaskAdaptor struct {
+	channel.TaskAdaptor
+	fetches int
+}
+
+func (a *countingTaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	a.fetches++
+	return nil, errors.New("unexpected fetch")
+}
+
+func TestUpdateVideoSingleTaskRespectsChannelConcurrency(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	guard := relaycommon.DefaultChannelConcurrencyGuard
+	originTimeout := guard.WaitTimeout
+	guard.WaitTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { guard.WaitTimeout = originTimeout })
+
+	limit := 1
+	ch := &model.Channel{Id: 9696, ConcurrencyLimit: &limit}
+	// 渠道的并发名额已被转发请求占满
+	release, err := guard.Acquire(context.Background(), ch.Id, limit)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer release()
+
+	task := createTestTask(t, &model.Task{TaskID: "task_busy", UserId: 1, ChannelId: ch.Id, Status: model.TaskStatusQueued})
+	adaptor := &countingTaskAdaptor{}
+	if err := updateVideoSingleTask(context.Background(), adaptor, ch, task.TaskID, map[string]*model.Task{task.TaskID: task}); err != nil {
+		t.Fatalf("updateVideoSingleTask: %v", err)
+	}
+	if adaptor.fetches != 0 {
+		t.Fatalf("poll bypassed the channel concurrency limit, fetches = %d", adaptor.fetches)
+	}
+	if stored := reloadTestTask(t, task.ID); stored.RetryCount != 0 || stored.Status != model.TaskStatusQueued {
+		t.Fatalf("skipped poll changed the task: retry_count=%d status=%s", stored.RetryCount, stored.Status)
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+type receivedWebhook struct {
+	path      string
+	signature string
+	body      []byte
+}
+
+func TestFailTasksInBulkDispatchesWebhooks(t *testing.T) {
+	setupTestDB(t, &model.Task{})
+	service.InitHttpClient()
+	fetchSetting := system_setting.GetFetchSetting()
+	originSSRF := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = originSSRF })
+
+	received := make(chan receivedWebhook, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- receivedWebhook{path: r.URL.Path, signature: r.Header.Get(service.TaskWebhookSignatureHeader), body: body}
+	}))
+	defer server.Close()
+
+	task := createTestTask(t, &model.Task{
+		TaskID:              "suno-1",
+		Platform:            "suno",
+		Status:              model.TaskStatusInProgress,
+		Progress:            "30%",
+		CallbackURL:         server.URL + "/result",
+		ProgressCallbackURL: server.URL + "/progress",
+		PrivateData:         model.TaskPrivateData{CallbackSecret: "per-task-secret"},
+	})
+	if err := failTasksInBulk(context.Background(), []string{task.TaskID}, map[string]*model.Task{task.TaskID: task}, "channel missing"); err != nil {
+		t.Fatalf("failTasksInBulk failed: %v", err)
+	}
+	if got := reloadTestTask(t, task.ID); got.Status != model.TaskStatusFailure || got.FailReason != "channel missing" {
+		t.Fatalf("task status = %s, reason = %q", got.Status, got.FailReason)
+	}
+
+	// 进入终态时推送进度回调与结果回调，均使用任务自身的密钥签名
+	paths := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case hook := <-received:
+			mac := hmac.New(sha256.New, []byte("per-task-secret"))
+			mac.Write(hook.body)
+			if hook.signature != hex.EncodeToString(mac.Sum(nil)) {
+				t.Errorf("%s signature mismatch", hook.path)
+			}
+			paths[hook.path] = true
+		case <-time.After(2 * time.Second):
+			t.Fatal("webhook not delivered")
+		}
+	}
+	if !paths["/result"] || !paths["/progress"] {
+		t.Fatalf("delivered webhooks = %v", paths)
+	}
+}
+
+func TestNotifyTaskStatusChangedSkipsUnchanged(t *testing.T) {
+	service.InitHttpClient()
+	fetchSetting := system_setting.GetFetchSetting()
+	originSSRF := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = originSSRF })
+
+	received := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	task := &model.Task{TaskID: "suno-2", Status: model.TaskStatusInProgress, Progress: "30%", CallbackURL: server.URL, ProgressCallbackURL: server.URL}
+	notifyTaskStatusChanged(context.Background(), task, model.TaskStatusInProgress, "30%")
+	select {
+	case <-received:
+		t.Fatal("unexpected webhook for unchanged task")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -2,14 +2,19 @@ package controller
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 func GetAllTokens(c *gin.Context) {
@@ -114,28 +119,46 @@ func GetTokenUsage(c *gin.Context) {
 		return
 	}
 
-	expiredAt := token.ExpiredTime
-	if expiredAt == -1 {
-		expiredAt = 0
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"code":    true,
 		"message": "ok",
-		"data": gin.H{
-			"object":               "token_usage",
-			"name":                 token.Name,
-			"total_granted":        token.RemainQuota + token.UsedQuota,
-			"total_used":           token.UsedQuota,
-			"total_available":      token.RemainQuota,
-			"unlimited_quota":      token.UnlimitedQuota,
-			"model_limits":         token.GetModelLimitsMap(),
-			"model_limits_enabled": token.ModelLimitsEnabled,
-			"expires_at":           expiredAt,
-		},
+		"data":    buildTokenUsage(token),
 	})
 }
 
+// GetTokenUsageById 供控制台用户按令牌 id 查看该令牌的额度消耗
+func GetTokenUsageById(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token, err := model.GetTokenByIds(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, buildTokenUsage(token))
+}
+
+func buildTokenUsage(token *model.Token) gin.H {
+	expiredAt := token.ExpiredTime
+	if expiredAt == -1 {
+		expiredAt = 0
+	}
+	return gin.H{
+		"object":               "token_usage",
+		"name":                 token.Name,
+		"total_granted":        token.RemainQuota + token.UsedQuota,
+		"total_used":           token.UsedQuota,
+		"total_available":      token.RemainQuota,
+		"unlimited_quota":      token.UnlimitedQuota,
+		"model_limits":         token.GetModelLimitsMap(),
+		"model_limits_enabled": token.ModelLimitsEnabled,
+		"expires_at":           expiredAt,
+	}
+}
+
 func AddToken(c *gin.Context) {
 	token := model.Token{}
 	err := c.ShouldBindJSON(&token)
@@ -324,3 +347,173 @@ func DeleteTokenBatch(c *gin.Context) {
 		"data":    count,
 	})
 }
+
+// InvalidateSelfToken 作废当前请求所用的令牌，供令牌泄露时由持有者自行紧急停用
+func InvalidateSelfToken(c *gin.Context) {
+	invalidateToken(c, c.GetInt("token_id"), c.GetInt("id"), "self")
+}
+
+// AdminInvalidateToken 管理员作废任意用户的令牌
+func AdminInvalidateToken(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	invalidateToken(c, id, 0, "admin")
+}
+
+func invalidateToken(c *gin.Context, tokenId int, userId int, operator string) {
+	token, changed, err := model.InvalidateToken(tokenId, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if changed {
+		model.RecordLog(token.UserId, model.LogTypeManage, fmt.Sprintf("令牌 %s（#%d）已被作废，操作方：%s", token.Name, token.Id, operator))
+		service.DispatchTokenInvalidated(token, operator)
+	}
+	common.ApiSuccess(c, gin.H{
+		"id":     token.Id,
+		"status": token.Status,
+	})
+}
+
+type tokenCorsRequest struct {
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// validateAllowedOrigin 校验跨域来源，只允许 "*" 或不带路径的 http(s)://host[:port]
+func validateAllowedOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return (u.Path == "" || u.Path == "/") && u.RawQuery == "" && u.Fragment == ""
+}
+
+// UpdateTokenCors 管理员设置令牌允许的浏览器跨域来源，allowed_origins 为空时恢复全局 CORS 策略
+func UpdateTokenCors(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req tokenCorsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	origins := make([]string, 0, len(req.AllowedOrigins))
+	for _, origin := range req.AllowedOrigins {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		if !validateAllowedOrigin(origin) {
+			common.ApiErrorMsg(c, fmt.Sprintf("无效的跨域来源：%s", origin))
+			return
+		}
+		origins = append(origins, origin)
+	}
+	token, err := model.UpdateTokenAllowedOrigins(id, origins)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"id":              token.Id,
+		"allowed_origins": token.GetAllowedOrigins(),
+	})
+}
+
+type tokenScopesRequest struct {
+	Scopes []string `json:"scopes"`
+}
+
+// UpdateTokenScopes 更新令牌的权限范围，用户只能修改自己的令牌，管理员可修改任意令牌。scopes 为空时恢复为全部权限
+func UpdateTokenScopes(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req tokenScopesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	scopes := lo.Uniq(req.Scopes)
+	for _, scope := range scopes {
+		if !lo.Contains(constant.AllTokenScopes, scope) {
+			common.ApiErrorMsg(c, fmt.Sprintf("无效的权限范围：%s", scope))
+			return
+		}
+	}
+	var token *model.Token
+	if c.GetInt("role") >= common.RoleAdminUser {
+		token, err = model.GetTokenById(id)
+	} else {
+		token, err = model.GetTokenByIds(id, c.GetInt("id"))
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.UpdateTokenScopes(token, scopes); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"id":     token.Id,
+		"scopes": token.GetScopes(),
+	})
+}
+
+type tokenAllowIpsRequest struct {
+	AllowIps []string `json:"allow_ips"`
+}
+
+// UpdateTokenAllowIps 更新令牌允许访问的 IP 列表，支持单个 IP 与 CIDR。用户只能修改自己的令牌，管理员可修改任意令牌。allow_ips 为空时不限制
+func UpdateTokenAllowIps(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	var req tokenAllowIpsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	ips := make([]string, 0, len(req.AllowIps))
+	for _, ip := range req.AllowIps {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			common.ApiErrorMsg(c, fmt.Sprintf("无效的 IP 或 CIDR：%s", ip))
+			return
+		}
+		ips = append(ips, ip)
+	}
+	var token *model.Token
+	if c.GetInt("role") >= common.RoleAdminUser {
+		token, err = model.GetTokenById(id)
+	} else {
+		token, err = model.GetTokenByIds(id, c.GetInt("id"))
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.UpdateTokenAllowIps(token, lo.Uniq(ips)); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"id":        token.Id,
+		"allow_ips": token.GetIpLimits(),
+	})
+}
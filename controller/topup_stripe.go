@@ -146,7 +146,18 @@ func StripeWebhook(c *gin.Context) {
 	}
 
 	switch event.Type {
-	case stripe.EventTypeCheckoutSessionCompleted:
+	// 异步支付方式在 completed 时仍为 unpaid，款项到账后另行发送 async_payment_succeeded，两者均按会话幂等入账
+	case stripe.EventTypeCheckoutSessionCompleted, stripe.EventTypeCheckoutSessionAsyncPaymentSucceeded:
+		// 额度套餐的会话在 metadata 中带有 package_id，其余为在线充值订单
+		if event.GetObjectValue("metadata", "package_id") != "" {
+			if err := stripePackageSessionCompleted(event); err != nil {
+				// 返回 5xx 让 Stripe 稍后重试
+				log.Printf("Stripe套餐入账失败: %v\n", err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			break
+		}
 		sessionCompleted(event)
 	case stripe.EventTypeCheckoutSessionExpired:
 		sessionExpired(event)
@@ -165,6 +176,10 @@ func sessionCompleted(event stripe.Event) {
 		log.Println("错误的Stripe Checkout完成状态:", status, ",", referenceId)
 		return
 	}
+	if paymentStatus := event.GetObjectValue("payment_status"); paymentStatus == string(stripe.CheckoutSessionPaymentStatusUnpaid) {
+		log.Println("Stripe Checkout会话等待异步支付:", referenceId)
+		return
+	}
 
 	err := model.Recharge(referenceId, customerId)
 	if err != nil {
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type registerUserModelRequest struct {
+	Alias           string  `json:"alias"`
+	ChannelId       int     `json:"channel_id"`
+	UpstreamModelId string  `json:"upstream_model_id"`
+	PriceOverride   float64 `json:"price_override"`
+}
+
+// validateUserModel 校验自定义模型的别名、绑定渠道与价格
+func validateUserModel(userId int, m *model.UserModel) string {
+	if m.Alias == "" || m.UpstreamModelId == "" {
+		return "alias 和 upstream_model_id 不能为空"
+	}
+	if len(m.Alias) > 128 || len(m.UpstreamModelId) > 255 {
+		return "alias 或 upstream_model_id 过长"
+	}
+	setting := operation_setting.GetUserModelSetting()
+	if m.PriceOverride <= 0 || m.PriceOverride < setting.MinPrice {
+		return fmt.Sprintf("price_override 不能低于 %v", setting.MinPrice)
+	}
+	// 不允许通过别名以低于运营方定价的价格调用上游模型
+	if price, ok := ratio_setting.GetModelPrice(m.UpstreamModelId, false); ok && m.PriceOverride < price {
+		return fmt.Sprintf("price_override 不能低于该模型的定价 %v", price)
+	}
+	channel, err := model.GetChannelById(m.ChannelId, false)
+	if err != nil {
+		return "渠道不存在"
+	}
+	if channel.Status != common.ChannelStatusEnabled {
+		return "渠道未启用"
+	}
+	if !operation_setting.IsUserModelChannelTypeAllowed(channel.Type) {
+		return "该渠道类型不支持注册自定义模型"
+	}
+	group, err := model.GetUserGroup(userId, false)
+	if err != nil {
+		return err.Error()
+	}
+	if !slices.Contains(channel.GetGroups(), group) {
+		return "无权使用该渠道"
+	}
+	if taken, err := model.IsUserModelAliasTaken(userId, m.Alias); err != nil {
+		return err.Error()
+	} else if taken {
+		return "别名已存在"
+	}
+	return ""
+}
+
+// RegisterUserModel 注册用户自定义模型（如微调模型），需要审核时状态为待审核
+func RegisterUserModel(c *gin.Context) {
+	var req registerUserModelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	userId := c.GetInt("id")
+	m := model.UserModel{
+		UserId:          userId,
+		Alias:           strings.TrimSpace(req.Alias),
+		ChannelId:       req.ChannelId,
+		UpstreamModelId: strings.TrimSpace(req.UpstreamModelId),
+		PriceOverride:   req.PriceOverride,
+		Status:          model.UserModelStatusApproved,
+	}
+	if msg := validateUserModel(userId, &m); msg != "" {
+		common.ApiErrorMsg(c, msg)
+		return
+	}
+	if operation_setting.GetUserModelSetting().RequireApproval {
+		m.Status = model.UserModelStatusPending
+	}
+	if err := m.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &m)
+}
+
+// GetSelfUserModels 获取当前用户注册的自定义模型
+func GetSelfUserModels(c *gin.Context) {
+	models, err := model.GetUserModelsByUserId(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, models)
+}
+
+// DeleteSelfUserModel 删除当前用户注册的自定义模型
+func DeleteSelfUserModel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	deleted, err := model.DeleteUserModel(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !deleted {
+		common.ApiErrorMsg(c, "模型不存在")
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// GetAllUserModels 管理员分页查询用户自定义模型，可按 status 过滤
+func GetAllUserModels(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	status, _ := strconv.Atoi(c.Query("status"))
+	models, total, err := model.GetUserModels(status, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(models)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// ApproveUserModel 审核通过用户自定义模型
+func ApproveUserModel(c *gin.Context) {
+	reviewUserModel(c, model.UserModelStatusApproved)
+}
+
+// RejectUserModel 拒绝用户自定义模型
+func RejectUserModel(c *gin.Context) {
+	reviewUserModel(c, model.UserModelStatusRejected)
+}
+
+func reviewUserModel(c *gin.Context, status int) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	m, err := model.GetUserModelById(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorMsg(c, "模型不存在")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.UpdateUserModelStatus(id, status); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	m.Status = status
+	common.ApiSuccess(c, m)
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func videoAudioError(c *gin.Context, status int, errType string, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+		},
+	})
+}
+
+// videoAudioLocks 按任务 ID 串行化音轨提取，避免并发请求重复调用 ffmpeg
+var videoAudioLocks sync.Map
+
+// lockVideoAudio 获取任务的提取锁，返回的函数释放锁并移除该任务的锁记录
+func lockVideoAudio(taskID string) func() {
+	for {
+		mu := &sync.Mutex{}
+		mu.Lock()
+		actual, loaded := videoAudioLocks.LoadOrStore(taskID, mu)
+		if !loaded {
+			return func() {
+				videoAudioLocks.Delete(taskID)
+				mu.Unlock()
+			}
+		}
+		// 等待当前持有者释放后重新竞争
+		holder := actual.(*sync.Mutex)
+		holder.Lock()
+		holder.Unlock()
+	}
+}
+
+// VideoAudio GET /v1/videos/:task_id/audio 从已完成的视频任务中提取音轨。
+// 配置了对象存储时音轨转存后返回签名链接，否则直接返回音频文件；
+// 每个任务仅首次提取按固定额度扣费，之后的提取不再扣费
+func VideoAudio(c *gin.Context) {
+	taskID := c.Param("task_id")
+	userId := c.GetInt("id")
+	unlock := lockVideoAudio(taskID)
+	defer unlock()
+	// 加锁后读取任务，获取并发请求已写入的音轨存储位置与扣费标记
+	task, exists, err := model.GetByTaskId(userId, taskID)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("Failed to query task %s: %s", taskID, err.Error()))
+		videoAudioError(c, http.StatusInternalServerError, "server_error", "Failed to query task")
+		return
+	}
+	if !exists {
+		videoAudioError(c, http.StatusNotFound, "invalid_request_error", "Task not found")
+		return
+	}
+	if task.Status != model.TaskStatusSuccess {
+		videoAudioError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Task is not completed yet, current status: %s", task.Status))
+		return
+	}
+	modelName := task.Properties.OriginModelName
+	if !operation_setting.IsAudioExtractionModel(modelName) && !operation_setting.IsAudioExtractionModel(task.Properties.UpstreamModelName) {
+		videoAudioError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("Model %s does not generate audio", modelName))
+		return
+	}
+	if !task.Properties.GenerateAudio {
+		videoAudioError(c, http.StatusNotFound, "invalid_request_error", "The video was generated without audio, submit the task with generate_audio: true")
+		return
+	}
+
+	proxy := service.GetSignedURLProxy()
+	if proxy != nil && task.PrivateData.AudioStorageKey != "" {
+		writeVideoAudioURL(c, proxy, task, task.PrivateData.AudioStorageKey)
+		return
+	}
+	if !strings.HasPrefix(task.FailReason, "https://") && !strings.HasPrefix(task.FailReason, "http://") {
+		videoAudioError(c, http.StatusNotFound, "invalid_request_error", "The video output is not available")
+		return
+	}
+
+	quota := operation_setting.GetTaskSetting().AudioExtractionQuota
+	if task.PrivateData.AudioExtractionCharged {
+		quota = 0
+	}
+	if quota > 0 {
+		userQuota, err := model.GetUserQuota(userId, false)
+		if err != nil {
+			videoAudioError(c, http.StatusInternalServerError, "server_error", "Failed to get user quota")
+			return
+		}
+		if userQuota < quota {
+			videoAudioError(c, http.StatusForbidden, "insufficient_quota", "user quota is not enough")
+			return
+		}
+	}
+
+	audio, err := service.DefaultVideoAudioExtractor.Extract(c.Request.Context(), task.FailReason)
+	if err != nil {
+		if errors.Is(err, service.ErrVideoHasNoAudio) {
+			videoAudioError(c, http.StatusNotFound, "invalid_request_error", err.Error())
+			return
+		}
+		logger.LogError(c, fmt.Sprintf("task %s extract audio failed: %s", taskID, err.Error()))
+		videoAudioError(c, http.StatusBadGateway, "server_error", "Failed to extract audio from the video")
+		return
+	}
+	if err := service.ChargeVideoAudioExtraction(c, task, quota); err != nil {
+		if errors.Is(err, model.ErrQuotaNotEnough) {
+			videoAudioError(c, http.StatusForbidden, "insufficient_quota", "user quota is not enough")
+			return
+		}
+		logger.LogError(c, fmt.Sprintf("task %s charge audio extraction failed: %s", taskID, err.Error()))
+		videoAudioError(c, http.StatusInternalServerError, "server_error", "Failed to charge audio extraction")
+		return
+	}
+
+	if proxy == nil {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", taskID+".aac"))
+		c.Data(http.StatusOK, service.VideoAudioContentType, audio)
+		return
+	}
+	key := service.TaskAudioStorageKey(task)
+	if err := proxy.Upload(context.Background(), key, bytes.NewReader(audio), int64(len(audio)), service.VideoAudioContentType); err != nil {
+		logger.LogError(c, fmt.Sprintf("task %s upload audio failed: %s", taskID, err.Error()))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", taskID+".aac"))
+		c.Data(http.StatusOK, service.VideoAudioContentType, audio)
+		return
+	}
+	if err := task.UpdateAudioStorageKey(key); err != nil {
+		logger.LogError(c, fmt.Sprintf("task %s save audio storage key failed: %s", taskID, err.Error()))
+	}
+	writeVideoAudioURL(c, proxy, task, key)
+}
+
+func writeVideoAudioURL(c *gin.Context, proxy *service.SignedURLProxy, task *model.Task, key string) {
+	signedURL, err := proxy.SignURL(c.Request.Context(), key)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("task %s sign audio url failed: %s", task.TaskID, err.Error()))
+		videoAudioError(c, http.StatusInternalServerError, "server_error", "Failed to sign audio url")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":    task.TaskID,
+		"url":        signedURL,
+		"expires_in": int64(proxy.TTL.Seconds()),
+	})
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type countingAudioExtractor struct {
+	calls   atomic.Int32
+	running atomic.Int32
+	overlap atomic.Bool
+}
+
+func (e *countingAudioExtractor) Extract(ctx context.Context, videoURL string) ([]byte, error) {
+	e.calls.Add(1)
+	if e.running.Add(1) > 1 {
+		e.overlap.Store(true)
+	}
+	defer e.running.Add(-1)
+	time.Sleep(10 * time.Millisecond)
+	return []byte("aac"), nil
+}
+
+func TestVideoAudioChargesOnce(t *testing.T) {
+	setupTestDB(t, &model.Task{}, &model.User{}, &model.Log{})
+	gin.SetMode(gin.TestMode)
+	extractor := &countingAudioExtractor{}
+	originExtractor := service.DefaultVideoAudioExtractor
+	service.DefaultVideoAudioExtractor = extractor
+	t.Cleanup(func() { service.DefaultVideoAudioExtractor = originExtractor })
+
+	if err := model.DB.Create(&model.User{Id: 1, Username: "audio", Quota: 10000}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	task := createTestTask(t, &model.Task{
+		TaskID:     "task_audio",
+		UserId:     1,
+		Status:     model.TaskStatusSuccess,
+		FailReason: "https://example.com/video.mp4",
+		Properties: model.Properties{OriginModelName: "doubao-seedance-1-5-pro", GenerateAudio: true},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/v1/videos/task_audio/audio", nil)
+			c.Params = gin.Params{{Key: "task_id", Value: "task_audio"}}
+			c.Set("id", 1)
+			VideoAudio(c)
+			if w.Code != http.StatusOK {
+				t.Errorf("status = %d, body %s", w.Code, w.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if extractor.overlap.Load() {
+		t.Error("concurrent requests for the same task should not extract in parallel")
+	}
+	var user model.User
+	model.DB.First(&user, 1)
+	if user.Quota != 9000 {
+		t.Errorf("user quota = %d, want 9000 (charged once)", user.Quota)
+	}
+	if !reloadTestTask(t, task.ID).PrivateData.AudioExtractionCharged {
+		t.Error("task should be marked as charged")
+	}
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	VideoBatchMaxItems = 20
+	videoBatchWorkers  = 4
+	videoBatchItemPath = "/v1/videos"
+)
+
+// VideoBatchItemResult 批量提交中单个任务的结果
+type VideoBatchItemResult struct {
+	Index      int             `json:"index"`
+	Success    bool            `json:"success"`
+	StatusCode int             `json:"status_code"`
+	TaskID     string          `json:"task_id,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// validateVideoBatchItem 校验单个任务的基本字段，返回模型名
+func validateVideoBatchItem(raw json.RawMessage) (string, error) {
+	var item map[string]any
+	if err := common.Unmarshal(raw, &item); err != nil {
+		return "", errors.New("item must be a JSON object")
+	}
+	modelName, _ := item["model"].(string)
+	if strings.TrimSpace(modelName) == "" {
+		return "", errors.New("model is required")
+	}
+	prompt, _ := item["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return "", errors.New("prompt is required")
+	}
+	return modelName, nil
+}
+
+// reserveVideoBatchQuota 按各任务的基础价格一次性预留整个批次所需的用户与令牌额度，
+// 批次内的任务提交时从额度池中取用，避免并发提交时额度被重复使用
+func reserveVideoBatchQuota(c *gin.Context, modelNames []string) (*service.TaskQuotaPool, error) {
+	info := &relaycommon.RelayInfo{
+		UserGroup:  common.GetContextKeyString(c, constant.ContextKeyUserGroup),
+		UsingGroup: common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+	}
+	total := 0
+	for _, modelName := range modelNames {
+		total += service.EstimateTaskQuota(info, modelName)
+	}
+	pool, err := service.ReserveTaskQuotaPool(c.GetInt("id"), c.GetInt("token_id"), c.GetString("token_key"), total)
+	if errors.Is(err, model.ErrQuotaNotEnough) {
+		return nil, fmt.Errorf("额度不足，批次预计需要 %s", logger.FormatQuota(total))
+	}
+	return pool, err
+}
+
+// runVideoBatchItem 经由服务自身路由提交单个任务，提交失败时不会扣费
+func runVideoBatchItem(c *gin.Context, pool *service.TaskQuotaPool, index int, body []byte) VideoBatchItemResult {
+	result := VideoBatchItemResult{Index: index}
+	resp, err := serveInternalRelay(c.Request.Context(), internalRelayRequest{
+		Path:       videoBatchItemPath,
+		Body:       body,
+		Header:     c.Request.Header,
+		RemoteAddr: c.Request.RemoteAddr,
+		Values:     map[constant.ContextKey]any{constant.ContextKeyTaskQuotaPool: pool},
+	})
+	if err != nil {
+		result.StatusCode = http.StatusInternalServerError
+		result.Error = err.Error()
+		return result
+	}
+
+	result.StatusCode = resp.StatusCode()
+	respBody := resp.Body()
+	result.Success = resp.Success()
+	if !result.Success {
+		result.Error = extractVideoBatchError(respBody)
+		return result
+	}
+	result.Data = respBody
+	var taskResp struct {
+		ID     string `json:"id"`
+		TaskID string `json:"task_id"`
+	}
+	if err := common.Unmarshal(respBody, &taskResp); err == nil {
+		result.TaskID = taskResp.TaskID
+		if result.TaskID == "" {
+			result.TaskID = taskResp.ID
+		}
+	}
+	return result
+}
+
+func extractVideoBatchError(body []byte) string {
+	var resp struct {
+		Message string `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := common.Unmarshal(body, &resp); err == nil {
+		if resp.Error.Message != "" {
+			return resp.Error.Message
+		}
+		if resp.Message != "" {
+			return resp.Message
+		}
+	}
+	if len(body) > 0 {
+		return string(body)
+	}
+	return "request failed"
+}
+
+// RelayVideoBatch 批量提交视频生成任务：超过半数任务校验失败时整批拒绝，
+// 否则预留整个批次的额度后并发提交通过校验的任务，失败的任务不扣费
+func RelayVideoBatch(c *gin.Context) {
+	var items []json.RawMessage
+	if err := common.UnmarshalBodyReusable(c, &items); err != nil {
+		if common.IsRequestBodyTooLargeError(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, service.TaskErrorWrapperLocal(err, "request_body_too_large", http.StatusRequestEntityTooLarge))
+			return
+		}
+		common.ApiErrorMsg(c, "request body must be a JSON array of video generation requests")
+		return
+	}
+	if len(items) == 0 || len(items) > VideoBatchMaxItems {
+		common.ApiErrorMsg(c, fmt.Sprintf("batch must contain between 1 and %d items", VideoBatchMaxItems))
+		return
+	}
+
+	results := make([]VideoBatchItemResult, len(items))
+	var valid []int
+	var modelNames []string
+	for i, raw := range items {
+		modelName, err := validateVideoBatchItem(raw)
+		if err != nil {
+			results[i] = VideoBatchItemResult{Index: i, StatusCode: http.StatusBadRequest, Error: err.Error()}
+			continue
+		}
+		valid = append(valid, i)
+		modelNames = append(modelNames, modelName)
+	}
+	if invalid := len(items) - len(valid); invalid*2 > len(items) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("%d of %d items failed validation, batch rejected", invalid, len(items)),
+			"data":    results,
+		})
+		return
+	}
+	pool, err := reserveVideoBatchQuota(c, modelNames)
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	defer pool.Settle()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < videoBatchWorkers && w < len(valid); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = runVideoBatchItem(c, pool, i, items[i])
+			}
+		}()
+	}
+	for _, i := range valid {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	succeeded := 0
+	for _, r := range results {
+		if r.Success {
+			succeeded++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success":   succeeded > 0,
+		"message":   fmt.Sprintf("%d of %d items submitted", succeeded, len(items)),
+		"succeeded": succeeded,
+		"failed":    len(items) - succeeded,
+		"data":      results,
+	})
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRelayVideoBatch(t *testing.T) {
+	setupTestDB(t, &model.User{}, &model.Token{}, &model.QuotaReservation{})
+	gin.SetMode(gin.TestMode)
+	itemQuota := service.EstimateTaskQuota(&relaycommon.RelayInfo{}, "m")
+
+	// 内部提交的任务从批次额度池取用额度，prompt 为 fail 的任务提交失败
+	engine := gin.New()
+	engine.Use(middleware.InternalRequestValues())
+	engine.POST(videoBatchItemPath, func(c *gin.Context) {
+		var req struct {
+			Prompt string `json:"prompt"`
+		}
+		_ = common.UnmarshalBodyReusable(c, &req)
+		pool, ok := common.GetContextKeyType[*service.TaskQuotaPool](c, constant.ContextKeyTaskQuotaPool)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": gin.H{"message": "missing quota pool"}})
+			return
+		}
+		if req.Prompt == "fail" {
+			c.JSON(http.StatusBadGateway, gin.H{"error": gin.H{"message": "upstream failed"}})
+			return
+		}
+		pool.Take(itemQuota)
+		c.JSON(http.StatusOK, gin.H{"task_id": "task_" + req.Prompt})
+	})
+	SetInternalRelayHandler(engine)
+	t.Cleanup(func() { SetInternalRelayHandler(nil) })
+
+	submit := func(body string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/v1/videos/batch", func(c *gin.Context) {
+			c.Set("id", 1)
+			c.Set("token_id", 1)
+			c.Set("token_key", "batch")
+			RelayVideoBatch(c)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/v1/videos/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	quotas := func() (int, int) {
+		var user model.User
+		var token model.Token
+		model.DB.First(&user, 1)
+		model.DB.First(&token, 1)
+		return user.Quota, token.RemainQuota
+	}
+
+	initial := itemQuota * 3
+	model.DB.Create(&model.User{Id: 1, Username: "batch", Quota: initial})
+	model.DB.Create(&model.Token{Id: 1, UserId: 1, Key: "batch", Name: "batch", RemainQuota: initial})
+
+	w := submit(`[{"model":"m","prompt":"a"},{"model":"m","prompt":"fail"},{"model":"m","prompt":"b"}]`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"succeeded":2`) {
+		t.Fatalf("batch status = %d, body %s", w.Code, w.Body.String())
+	}
+	if userQuota, tokenQuota := quotas(); userQuota != initial-2*itemQuota || tokenQuota != initial-2*itemQuota {
+		t.Fatalf("after batch user quota = %d, token quota = %d, want %d", userQuota, tokenQuota, initial-2*itemQuota)
+	}
+
+	// 剩余额度不足以覆盖整个批次时整批拒绝，不扣除额度
+	w = submit(`[{"model":"m","prompt":"c"},{"model":"m","prompt":"d"}]`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("insufficient batch status = %d, body %s", w.Code, w.Body.String())
+	}
+	if userQuota, tokenQuota := quotas(); userQuota != itemQuota || tokenQuota != itemQuota {
+		t.Fatalf("rejected batch changed quota: user %d, token %d", userQuota, tokenQuota)
+	}
+}
@@ -104,8 +104,14 @@ func VideoProxy(c *gin.Context) {
 		return
 	}
 
-	switch channel.Type {
-	case constant.ChannelTypeGemini:
+	// 已转存到任务存储的产出直接从对象存储读取
+	if task.PrivateData.StorageKey != "" {
+		videoURL = service.GetSignedURLProxy().TaskStorageURL(task)
+	}
+
+	switch {
+	case videoURL != "":
+	case channel.Type == constant.ChannelTypeGemini:
 		apiKey := task.PrivateData.Key
 		if apiKey == "" {
 			logger.LogError(c.Request.Context(), fmt.Sprintf("Missing stored API key for Gemini task %s", taskID))
@@ -130,7 +136,7 @@ func VideoProxy(c *gin.Context) {
 			return
 		}
 		req.Header.Set("x-goog-api-key", apiKey)
-	case constant.ChannelTypeOpenAI, constant.ChannelTypeSora:
+	case channel.Type == constant.ChannelTypeOpenAI || channel.Type == constant.ChannelTypeSora:
 		videoURL = fmt.Sprintf("%s/v1/videos/%s/content", baseURL, task.TaskID)
 		req.Header.Set("Authorization", "Bearer "+channel.Key)
 	default:
@@ -3,13 +3,13 @@ package controller
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"strconv"
 	"strings"
 
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay"
+	"github.com/QuantumNous/new-api/service"
 )
 
 func getGeminiVideoURL(channel *model.Channel, task *model.Task, apiKey string) (string, error) {
@@ -45,13 +45,13 @@ func getGeminiVideoURL(channel *model.Channel, task *model.Task, apiKey string)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("read task response failed: %w", err)
 	}
 
 	taskInfo, parseErr := adaptor.ParseTaskResult(body)
-	if parseErr == nil && taskInfo != nil && taskInfo.RemoteUrl != "" {
+	if parseErr == nil && taskInfo != nil && taskInfo.RemoteUrl != "" && !strings.HasPrefix(taskInfo.RemoteUrl, "data:") {
 		return ensureAPIKey(taskInfo.RemoteUrl, apiKey), nil
 	}
 
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// videoStreamKeepAlive 保活注释的发送间隔，同时也是重新读取任务状态的间隔，
+// 以覆盖由其他节点轮询更新的任务
+const videoStreamKeepAlive = 15 * time.Second
+
+type videoStreamProgress struct {
+	TaskID   string `json:"task_id"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+}
+
+func isVideoTaskFinished(task *model.Task) bool {
+	return task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure
+}
+
+func writeVideoStreamEvent(c *gin.Context, v any) {
+	data, err := common.Marshal(v)
+	if err != nil {
+		logger.LogError(c, "failed to marshal video stream event: "+err.Error())
+		return
+	}
+	fmt.Fprintf(c.Writer, "data: %s\n\n", string(data))
+	c.Writer.Flush()
+}
+
+// VideoTaskStream 以 SSE 推送任务进度：状态或进度变化时发送一次事件，
+// 任务进入终态后发送完整的任务信息并结束
+func VideoTaskStream(c *gin.Context) {
+	taskId := c.Param("task_id")
+	userId := c.GetInt("id")
+
+	signal := service.TaskUpdateSignal(taskId)
+	task, exist, err := model.GetByTaskId(userId, taskId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": gin.H{
+				"message": "Failed to query task",
+				"type":    "server_error",
+			},
+		})
+		return
+	}
+	if !exist {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"message": "Task not found",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	keepAlive := time.NewTicker(videoStreamKeepAlive)
+	defer keepAlive.Stop()
+
+	var lastStatus model.TaskStatus
+	var lastProgress string
+	for {
+		if task.Status != lastStatus || task.Progress != lastProgress {
+			lastStatus, lastProgress = task.Status, task.Progress
+			if isVideoTaskFinished(task) {
+				writeVideoStreamEvent(c, relay.TaskModel2Dto(task))
+				return
+			}
+			writeVideoStreamEvent(c, videoStreamProgress{
+				TaskID:   task.TaskID,
+				Status:   string(task.Status),
+				Progress: task.Progress,
+			})
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case <-signal:
+			signal = service.TaskUpdateSignal(taskId)
+		}
+
+		latest, exist, err := model.GetByTaskId(userId, taskId)
+		if err != nil || !exist {
+			continue
+		}
+		task = latest
+	}
+}
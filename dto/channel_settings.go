@@ -1,12 +1,42 @@
 package dto
 
 type ChannelSettings struct {
-	ForceFormat            bool   `json:"force_format,omitempty"`
-	ThinkingToContent      bool   `json:"thinking_to_content,omitempty"`
-	Proxy                  string `json:"proxy"`
-	PassThroughBodyEnabled bool   `json:"pass_through_body_enabled,omitempty"`
-	SystemPrompt           string `json:"system_prompt,omitempty"`
-	SystemPromptOverride   bool   `json:"system_prompt_override,omitempty"`
+	ForceFormat            bool               `json:"force_format,omitempty"`
+	ThinkingToContent      bool               `json:"thinking_to_content,omitempty"`
+	Proxy                  string             `json:"proxy"`
+	PassThroughBodyEnabled bool               `json:"pass_through_body_enabled,omitempty"`
+	SystemPrompt           string             `json:"system_prompt,omitempty"`
+	SystemPromptOverride   bool               `json:"system_prompt_override,omitempty"`
+	TaskCallbackURL        string             `json:"task_callback_url,omitempty"`       // 任务终态回调地址，请求体中的 callback_url 优先
+	TaskRetryPolicy        *TaskRetryPolicy   `json:"task_retry_policy,omitempty"`       // 任务轮询失败的重试策略，为空时使用默认策略
+	RateLimit              int                `json:"rate_limit,omitempty"`              // 多 key 渠道中每个 key 每分钟最大请求数，0 表示不限制
+	TokenRateLimit         int                `json:"token_rate_limit,omitempty"`        // 多 key 渠道中每个 key 每分钟最大 token 数，0 表示不限制
+	FallbackGroup          string             `json:"fallback_group,omitempty"`          // 所属渠道回退链名称，上游返回 5xx 时按链上顺序切换渠道
+	ResponseTransform      *ResponseTransform `json:"response_transform,omitempty"`      // 非流式 JSON 响应返回给客户端前的字段映射
+	HTTP2                  bool               `json:"http2,omitempty"`                   // 使用 HTTP/2 专用客户端请求上游（仅 https），设置代理时以代理为准
+	RequestTimeoutSeconds  int                `json:"request_timeout_seconds,omitempty"` // 等待上游响应头的超时（秒），0 时使用 CHANNEL_REQUEST_TIMEOUT_SECONDS
+	QueryOverride          map[string]string  `json:"query_override,omitempty"`          // 转发上游前覆盖的查询参数，值支持 {api_key} 变量
+	ShadowSampleRate       float64            `json:"shadow_sample_rate,omitempty"`      // 任务提交镜像到影子渠道的比例（0-1），0 表示不镜像
+	ShadowQuotaLimit       int                `json:"shadow_quota_limit,omitempty"`      // 影子渠道每日镜像消耗的额度上限，0 表示不限制
+}
+
+// ResponseTransform 上游响应字段映射规则，按顺序执行
+type ResponseTransform struct {
+	Rules []ResponseTransformRule `json:"rules"`
+}
+
+// ResponseTransformRule 将 From 路径的值写入 To 路径，路径格式如 $.result.video_url、$.data[0].url
+type ResponseTransformRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Move 为 true 时写入后删除源字段，默认保留
+	Move bool `json:"move,omitempty"`
+}
+
+type TaskRetryPolicy struct {
+	MaxRetries       int `json:"max_retries"`
+	BaseDelaySeconds int `json:"base_delay_seconds"`
+	JitterSeconds    int `json:"jitter_seconds"`
 }
 
 type VertexKeyType string
@@ -31,6 +61,7 @@ type ChannelOtherSettings struct {
 	DisableStore          bool          `json:"disable_store,omitempty"`           // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
 	AllowSafetyIdentifier bool          `json:"allow_safety_identifier,omitempty"` // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
 	AwsKeyType            AwsKeyType    `json:"aws_key_type,omitempty"`
+	VertexGcsBucket       string        `json:"vertex_gcs_bucket,omitempty"` // 上传视频使用的 GCS 存储桶，需服务账号具备写入权限
 }
 
 func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
@@ -0,0 +1,12 @@
+package dto
+
+// ErrorCatalogue 错误目录中的一项，Code 与 TaskError.Code 一致；HttpStatus 为 0 表示沿用上游返回的状态码
+type ErrorCatalogue struct {
+	NumericCode int    `json:"numeric_code"`
+	Code        string `json:"code"`
+	HttpStatus  int    `json:"http_status"`
+	// Retryable 客户端稍后重试是否可能成功
+	Retryable bool `json:"retryable"`
+	// Message 面向用户的消息模板，{message} 会被替换为具体的错误信息
+	Message string `json:"message"`
+}
@@ -156,8 +156,15 @@ func (i *ImageRequest) GetTokenCountMeta() *types.TokenCountMeta {
 	}
 }
 
+// IsStream 图片请求的 stream 字段未单独声明，从额外参数中读取
 func (i *ImageRequest) IsStream(c *gin.Context) bool {
-	return false
+	raw, ok := i.Extra["stream"]
+	if !ok {
+		return false
+	}
+	var stream bool
+	_ = common.Unmarshal(raw, &stream)
+	return stream
 }
 
 func (i *ImageRequest) SetModelName(modelName string) {
@@ -9,6 +9,8 @@ type OpenAIModels struct {
 	Created                int                     `json:"created"`
 	OwnedBy                string                  `json:"owned_by"`
 	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
+	// Root 为模型别名指向的目标模型
+	Root string `json:"root,omitempty"`
 }
 
 type AnthropicModel struct {
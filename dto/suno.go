@@ -14,6 +14,7 @@ type SunoSubmitReq struct {
 	Mv                   string  `json:"mv,omitempty"`
 	Title                string  `json:"title,omitempty"`
 	Tags                 string  `json:"tags,omitempty"`
+	Style                string  `json:"style,omitempty"` // tags 的别名
 	ContinueAt           float64 `json:"continue_at,omitempty"`
 	TaskID               string  `json:"task_id,omitempty"`
 	ContinueClipId       string  `json:"continue_clip_id,omitempty"`
@@ -72,6 +73,8 @@ type TaskResponse[T TaskData] struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Data    T      `json:"data"`
+	// NextCursor 列表接口下一页的游标，没有更多数据时为空
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (t *TaskResponse[T]) IsSuccess() bool {
@@ -79,15 +82,21 @@ func (t *TaskResponse[T]) IsSuccess() bool {
 }
 
 type TaskDto struct {
-	TaskID     string          `json:"task_id"` // 第三方id，不一定有/ song id\ Task id
-	Action     string          `json:"action"`  // 任务类型, song, lyrics, description-mode
-	Status     string          `json:"status"`  // 任务状态, submitted, queueing, processing, success, failed
-	FailReason string          `json:"fail_reason"`
-	SubmitTime int64           `json:"submit_time"`
-	StartTime  int64           `json:"start_time"`
-	FinishTime int64           `json:"finish_time"`
-	Progress   string          `json:"progress"`
-	Data       json.RawMessage `json:"data"`
+	TaskID        string              `json:"task_id"` // 第三方id，不一定有/ song id\ Task id
+	Action        string              `json:"action"`  // 任务类型, song, lyrics, description-mode
+	Status        string              `json:"status"`  // 任务状态, submitted, queueing, processing, success, failed
+	FailReason    string              `json:"fail_reason"`
+	SubmitTime    int64               `json:"submit_time"`
+	StartTime     int64               `json:"start_time"`
+	FinishTime    int64               `json:"finish_time"`
+	Progress      string              `json:"progress"`
+	ExpiresAt     int64               `json:"expires_at,omitempty"`
+	StorageURL    string              `json:"storage_url,omitempty"`    // 产出转存到对象存储后的签名链接，fail_reason 仍保留上游原始链接
+	QualityScores *VideoQualityScores `json:"quality_scores,omitempty"` // 视频产出的质量评分，所在分组开启评分且评分完成后返回
+	ThumbnailURL  string              `json:"thumbnail_url,omitempty"`  // 视频产出的缩略图链接，任务成功后异步生成
+	Tags          []string            `json:"tags,omitempty"`
+	ParentTaskID  string              `json:"parent_task_id,omitempty"` // 重放任务对应的原始任务
+	Data          json.RawMessage     `json:"data"`
 }
 
 type SunoGoAPISubmitReq struct {
@@ -1,10 +1,26 @@
 package dto
 
 type TaskError struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	Data       any    `json:"data"`
-	StatusCode int    `json:"-"`
-	LocalError bool   `json:"-"`
-	Error      error  `json:"-"`
+	Code        string `json:"code"`
+	NumericCode int    `json:"numeric_code,omitempty"`
+	Retryable   bool   `json:"retryable"`
+	Message     string `json:"message"`
+	Data        any    `json:"data"`
+	StatusCode  int    `json:"-"`
+	LocalError  bool   `json:"-"`
+	Error       error  `json:"-"`
+}
+
+// TaskMessage 多轮生成中的一轮对话，user 轮携带提示词，model 轮携带上一次生成的视频
+type TaskMessage struct {
+	Role     string `json:"role"`
+	Content  string `json:"content,omitempty"`
+	VideoURI string `json:"video_uri,omitempty"`
+}
+
+// VideoQualityScores 视觉模型对视频产出的评分，各维度取值 1-10
+type VideoQualityScores struct {
+	Motion    int `json:"motion"`
+	Adherence int `json:"adherence"`
+	Visual    int `json:"visual"`
 }
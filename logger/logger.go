@@ -8,10 +8,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -85,7 +87,7 @@ func logHelper(ctx context.Context, level string, msg string) {
 		id = "SYSTEM"
 	}
 	now := time.Now()
-	_, _ = fmt.Fprintf(writer, "[%s] %v | %s | %s \n", level, now.Format("2006/01/02 - 15:04:05"), id, msg)
+	_, _ = fmt.Fprintf(writer, "[%s] %v | %s | %s%s \n", level, now.Format("2006/01/02 - 15:04:05"), id, msg, logFields(ctx, now))
 	logCount++ // we don't need accurate count, so no lock here
 	if logCount > maxLogCount && !setupLogWorking {
 		logCount = 0
@@ -96,6 +98,35 @@ func logHelper(ctx context.Context, level string, msg string) {
 	}
 }
 
+// logFields 从请求上下文中提取 trace_id、channel_id、model、user_id、request_duration_ms，
+// 以 key=value 形式追加到日志末尾，便于日志系统检索
+func logFields(ctx context.Context, now time.Time) string {
+	var b strings.Builder
+	if traceId := common.GetTraceId(ctx); traceId != "" {
+		fmt.Fprintf(&b, " trace_id=%s", traceId)
+	}
+	c, ok := ctx.(*gin.Context)
+	if !ok {
+		return b.String()
+	}
+	if channelId := common.GetContextKeyInt(c, constant.ContextKeyChannelId); channelId != 0 {
+		fmt.Fprintf(&b, " channel_id=%d", channelId)
+	}
+	if modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel); modelName != "" {
+		fmt.Fprintf(&b, " model=%s", modelName)
+	}
+	if userId := common.GetContextKeyInt(c, constant.ContextKeyUserId); userId != 0 {
+		fmt.Fprintf(&b, " user_id=%d", userId)
+	}
+	if startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime); !startTime.IsZero() {
+		fmt.Fprintf(&b, " request_duration_ms=%d", now.Sub(startTime).Milliseconds())
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return " |" + b.String()
+}
+
 func LogQuota(quota int) string {
 	// 新逻辑：根据额度展示类型输出
 	q := float64(quota)
@@ -19,6 +19,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/service/events"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
 	"github.com/bytedance/gopkg/util/gopool"
@@ -102,6 +103,16 @@ func main() {
 
 	go controller.AutomaticallyTestChannels()
 
+	go controller.AutomaticallyWarmUpChannels()
+
+	go controller.AutomaticallyCleanupVideoUploads()
+
+	go service.DefaultChannelHealthMonitor.Run()
+
+	go service.AutomaticallyProbeIdleChannels()
+	go service.AutomaticallyResetModelSpendingCaps()
+	go service.DefaultBillingReconciliationJob.Run()
+
 	if common.IsMasterNode && constant.UpdateTask {
 		gopool.Go(func() {
 			controller.UpdateMidjourneyTaskBulk()
@@ -109,6 +120,10 @@ func main() {
 		gopool.Go(func() {
 			controller.UpdateTaskBulk()
 		})
+		service.DefaultTaskExpiryJob.OnExpired = controller.DispatchTaskFinishedWebhooks
+		go service.DefaultTaskExpiryJob.Run()
+		service.DefaultTaskDependencyJob.Submit = controller.SubmitTaskDependency
+		go service.DefaultTaskDependencyJob.Run()
 	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
 		common.BatchUpdateEnabled = true
@@ -143,6 +158,7 @@ func main() {
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
 	server.Use(middleware.RequestId())
+	server.Use(middleware.TraceId())
 	middleware.SetUpLogger(server)
 	// Initialize session store
 	store := cookie.NewStore([]byte(common.SessionSecret))
@@ -230,6 +246,8 @@ func InitResources() error {
 
 	logger.SetupLogger()
 
+	events.SetupDefaultBus()
+
 	// Initialize model settings
 	ratio_setting.InitRatioSettings()
 
@@ -237,6 +255,12 @@ func InitResources() error {
 
 	service.InitTokenEncoders()
 
+	if constant.TaskErrorI18nFile != "" {
+		if err := service.DefaultI18n.LoadFile(constant.TaskErrorI18nFile); err != nil {
+			common.SysError("failed to load task error templates: " + err.Error())
+		}
+	}
+
 	// Initialize SQL Database
 	err = model.InitDB()
 	if err != nil {
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// StatusError 请求未拿到上游响应（连接失败、超时等）时使用的 status 标签
+const StatusError = "error"
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "new_api_requests_total",
+		Help: "Relay requests by channel, model and response status.",
+	}, []string{"channel", "model", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "new_api_request_duration_seconds",
+		Help: "Relay request duration in seconds by channel and model.",
+		// 覆盖 100ms 到约 7 分钟，兼顾同步请求与长耗时的任务提交
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 13),
+	}, []string{"channel", "model"})
+
+	quotaConsumedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "new_api_quota_consumed_total",
+		Help: "Quota consumed by group and model.",
+	}, []string{"group", "model"})
+
+	taskQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "new_api_task_queue_depth",
+		Help: "Unfinished tasks seen by the last poll, by platform.",
+	}, []string{"platform"})
+
+	channelErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "new_api_channel_errors_total",
+		Help: "Channel errors by channel and error code.",
+	}, []string{"channel", "error_code"})
+
+	responseCacheTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "new_api_response_cache_total",
+		Help: "Response cache lookups by model and result (hit or miss).",
+	}, []string{"model", "result"})
+
+	responseCacheHitRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "new_api_response_cache_hit_ratio",
+		Help: "Response cache hit ratio by model since process start.",
+	}, []string{"model"})
+)
+
+// 计算命中率使用的进程内计数
+var (
+	responseCacheMu     sync.Mutex
+	responseCacheCounts = map[string][2]int{} // model -> [hits, lookups]
+)
+
+func init() {
+	prometheus.MustRegister(requestsTotal, requestDuration, quotaConsumedTotal, taskQueueDepth, channelErrorsTotal,
+		responseCacheTotal, responseCacheHitRatio)
+}
+
+// Handler 返回 Prometheus 抓取使用的 HTTP handler
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func channelLabel(channelId int) string {
+	if channelId == 0 {
+		return ""
+	}
+	return strconv.Itoa(channelId)
+}
+
+// StatusLabel 将 HTTP 状态码转换为 status 标签
+func StatusLabel(statusCode int) string {
+	return strconv.Itoa(statusCode)
+}
+
+// ObserveRequest 记录一次请求的结果与耗时，channelId 为 0 表示未经过渠道（如查询本地任务）
+func ObserveRequest(channelId int, model string, status string, duration time.Duration) {
+	channel := channelLabel(channelId)
+	requestsTotal.WithLabelValues(channel, model, status).Inc()
+	requestDuration.WithLabelValues(channel, model).Observe(duration.Seconds())
+}
+
+// AddQuotaConsumed 累计分组与模型消耗的额度，退款等负数额度不计入
+func AddQuotaConsumed(group string, model string, quota int) {
+	if quota <= 0 {
+		return
+	}
+	quotaConsumedTotal.WithLabelValues(group, model).Add(float64(quota))
+}
+
+// SetTaskQueueDepth 以本轮轮询的结果覆盖各平台的未完成任务数
+func SetTaskQueueDepth(depth map[string]int) {
+	taskQueueDepth.Reset()
+	for platform, n := range depth {
+		taskQueueDepth.WithLabelValues(platform).Set(float64(n))
+	}
+}
+
+// IncChannelError 记录一次渠道错误
+func IncChannelError(channelId int, errorCode string) {
+	channelErrorsTotal.WithLabelValues(channelLabel(channelId), errorCode).Inc()
+}
+
+// ObserveResponseCache 记录一次响应缓存查询，并更新该模型的命中率
+func ObserveResponseCache(model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	responseCacheTotal.WithLabelValues(model, result).Inc()
+
+	responseCacheMu.Lock()
+	counts := responseCacheCounts[model]
+	if hit {
+		counts[0]++
+	}
+	counts[1]++
+	responseCacheCounts[model] = counts
+	responseCacheMu.Unlock()
+	responseCacheHitRatio.WithLabelValues(model).Set(float64(counts[0]) / float64(counts[1]))
+}
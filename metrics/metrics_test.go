@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerExportsMetrics(t *testing.T) {
+	ObserveRequest(7, "gpt-4o", StatusLabel(200), 150*time.Millisecond)
+	AddQuotaConsumed("default", "gpt-4o", 500)
+	AddQuotaConsumed("default", "gpt-4o", -100)
+	SetTaskQueueDepth(map[string]int{"suno": 3})
+	IncChannelError(7, "status_502")
+	ObserveResponseCache("flux-dev", true)
+	ObserveResponseCache("flux-dev", false)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	for _, line := range []string{
+		`new_api_requests_total{channel="7",model="gpt-4o",status="200"} 1`,
+		`new_api_request_duration_seconds_count{channel="7",model="gpt-4o"} 1`,
+		`new_api_quota_consumed_total{group="default",model="gpt-4o"} 500`,
+		`new_api_task_queue_depth{platform="suno"} 3`,
+		`new_api_channel_errors_total{channel="7",error_code="status_502"} 1`,
+		`new_api_response_cache_total{model="flux-dev",result="hit"} 1`,
+		`new_api_response_cache_hit_ratio{model="flux-dev"} 0.5`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("metrics output missing %q", line)
+		}
+	}
+
+	SetTaskQueueDepth(map[string]int{})
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), `new_api_task_queue_depth{platform="suno"}`) {
+		t.Error("queue depth should be reset on each poll")
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net"
 	"net/http"
@@ -9,8 +10,8 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
-	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/scim"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 
@@ -246,20 +247,12 @@ func TokenAuth() func(c *gin.Context) {
 			return
 		}
 
-		allowIps := token.GetIpLimits()
-		if len(allowIps) > 0 {
-			clientIp := c.ClientIP()
-			logger.LogDebug(c, "Token has IP restrictions, checking client IP %s", clientIp)
-			ip := net.ParseIP(clientIp)
-			if ip == nil {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "无法解析客户端 IP 地址")
-				return
-			}
-			if common.IsIpInCIDRList(ip, allowIps) == false {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中")
-				return
-			}
-			logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
+		if !checkTokenIp(c, token) {
+			return
+		}
+
+		if !checkTokenOrigin(c, token) {
+			return
 		}
 
 		userCache, err := model.GetUserCache(token.UserId)
@@ -298,6 +291,12 @@ func TokenAuth() func(c *gin.Context) {
 		if err != nil {
 			return
 		}
+		if !checkTokenScope(c, token.GetScopes()) {
+			return
+		}
+		if !setupCostCenter(c) {
+			return
+		}
 		c.Next()
 	}
 }
@@ -322,6 +321,7 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyTokenScopes, token.GetScopes())
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
@@ -332,3 +332,43 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	return nil
 }
+
+// ScimAuth 校验 SCIM 请求的 Bearer token，使用管理员的系统访问令牌
+func ScimAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		accessToken := c.GetHeader("Authorization")
+		if !strings.HasPrefix(accessToken, "Bearer ") {
+			scim.AbortWithError(c, http.StatusUnauthorized, "", "bearer token is required")
+			return
+		}
+		user := model.ValidateAccessToken(accessToken)
+		if user == nil || user.Status != common.UserStatusEnabled || user.Role < common.RoleAdminUser {
+			scim.AbortWithError(c, http.StatusUnauthorized, "", "invalid bearer token")
+			return
+		}
+		c.Set("id", user.Id)
+		c.Set("role", user.Role)
+		c.Set("username", user.Username)
+		c.Next()
+	}
+}
+
+// MetricsAuth 限制 /metrics 的访问：请求携带 Bearer METRICS_TOKEN，或来源 IP 在 METRICS_ALLOWED_IPS 中
+func MetricsAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if constant.MetricsToken != "" {
+			token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(constant.MetricsToken)) == 1 {
+				c.Next()
+				return
+			}
+		}
+		if len(constant.MetricsAllowedIps) > 0 {
+			if ip := net.ParseIP(c.ClientIP()); ip != nil && common.IsIpInCIDRList(ip, constant.MetricsAllowedIps) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+}
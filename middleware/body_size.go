@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodySize 限制请求体大小（字节），n <= 0 时不限制。
+// 需要注册在 Distribute 等会读取请求体的中间件之前：Content-Length 超限时直接返回 413，
+// 否则用 http.MaxBytesReader 包装请求体，读取超限时由各读取处返回 413
+func MaxBodySize(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if n <= 0 || c.Request.Body == nil || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > n {
+			abortWithRequestBodyTooLarge(c, fmt.Errorf("request body exceeds %d bytes", n))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		c.Next()
+	}
+}
+
+// MaxBodySizeMB 以 MB 为单位的 MaxBodySize
+func MaxBodySizeMB(mb int) gin.HandlerFunc {
+	return MaxBodySize(int64(mb) << 20)
+}
+
+// MaxBodySizeByRoute 按路由模板（c.FullPath()）限制请求体大小（MB），
+// 未在 routeMB 中列出的路由使用 defaultMB，便于同一路由组内的路由使用不同上限
+func MaxBodySizeByRoute(defaultMB int, routeMB map[string]int) gin.HandlerFunc {
+	limits := make(map[string]gin.HandlerFunc, len(routeMB))
+	for route, mb := range routeMB {
+		limits[route] = MaxBodySizeMB(mb)
+	}
+	fallback := MaxBodySizeMB(defaultMB)
+	return func(c *gin.Context) {
+		if limit, ok := limits[c.FullPath()]; ok {
+			limit(c)
+			return
+		}
+		fallback(c)
+	}
+}
+
+// abortWithRequestBodyTooLarge 以 TaskError 格式返回 413
+func abortWithRequestBodyTooLarge(c *gin.Context, err error) {
+	taskErr := service.TaskErrorWrapperLocal(err, "request_body_too_large", http.StatusRequestEntityTooLarge)
+	taskErr.Message = common.MessageWithRequestId(taskErr.Message, c.GetString(common.RequestIdKey))
+	c.JSON(taskErr.StatusCode, taskErr)
+	c.Abort()
+	logger.LogError(c.Request.Context(), fmt.Sprintf("user %d | %s", c.GetInt("id"), err.Error()))
+}
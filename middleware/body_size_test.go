@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupMaxBodySizeRouter(limit int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", MaxBodySize(limit), func(c *gin.Context) {
+		var req map[string]any
+		if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+			c.JSON(common.RequestBodyErrorStatusCode(err), gin.H{"message": err.Error()})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestMaxBodySize(t *testing.T) {
+	r := setupMaxBodySizeRouter(16)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("small body status = %d, want 200", w.Code)
+	}
+
+	// Content-Length 超限时直接以 TaskError 格式拒绝
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"prompt":"a very long prompt"}`)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("large body status = %d, want 413", w.Code)
+	}
+	var taskErr dto.TaskError
+	if err := json.Unmarshal(w.Body.Bytes(), &taskErr); err != nil || taskErr.Code != "request_body_too_large" {
+		t.Fatalf("unexpected error payload: %s", w.Body.String())
+	}
+
+	// 未知长度的请求体在读取时被截断
+	req := httptest.NewRequest(http.MethodPost, "/", io.MultiReader(strings.NewReader(`{"prompt":`), strings.NewReader(`"a very long prompt"}`)))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "exceeds 16 bytes") {
+		t.Fatalf("chunked body status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMaxBodySizeByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	g := r.Group("/v1")
+	g.Use(MaxBodySizeByRoute(1, map[string]int{"/v1/upload": 2}))
+	ok := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(common.RequestBodyErrorStatusCode(err))
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	g.POST("/chat", ok)
+	g.POST("/upload", ok)
+
+	body := strings.Repeat("a", 1<<20+1)
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/v1/chat", http.StatusRequestEntityTooLarge},
+		{"/v1/upload", http.StatusOK},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(body)))
+		if w.Code != tc.want {
+			t.Errorf("%s status = %d, want %d", tc.path, w.Code, tc.want)
+		}
+	}
+}
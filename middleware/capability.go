@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// 内容片段 type 与所需能力的对应关系，覆盖 OpenAI Chat / Responses 与 Claude Messages 格式
+var contentTypeCapabilities = map[string]string{
+	"image_url":   constant.CapabilityVision,
+	"input_image": constant.CapabilityVision,
+	"image":       constant.CapabilityVision,
+	"input_audio": constant.CapabilityAudio,
+}
+
+// setRequiredCapabilities 解析 JSON 请求体中的图片、音频输入、流式与工具调用，写入请求所需的渠道能力
+func setRequiredCapabilities(c *gin.Context) {
+	if !strings.HasPrefix(c.GetHeader("Content-Type"), "application/json") {
+		return
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil || len(body) == 0 {
+		return
+	}
+	required := detectRequiredCapabilities(c.Request.URL.Path, body)
+	if len(required) > 0 {
+		common.SetContextKey(c, constant.ContextKeyRequiredCapabilities, required)
+	}
+}
+
+func detectRequiredCapabilities(path string, body []byte) []string {
+	found := make(map[string]bool)
+	for _, query := range []string{"messages.#.content.#.type", "input.#.content.#.type"} {
+		forEachLeaf(gjson.GetBytes(body, query), func(v gjson.Result) {
+			if capability, ok := contentTypeCapabilities[v.String()]; ok {
+				found[capability] = true
+			}
+		})
+	}
+	// Gemini 格式通过 parts 中的 mime type 区分图片与音频
+	for _, query := range []string{
+		"contents.#.parts.#.inline_data.mime_type", "contents.#.parts.#.inlineData.mimeType",
+		"contents.#.parts.#.file_data.mime_type", "contents.#.parts.#.fileData.mimeType",
+	} {
+		forEachLeaf(gjson.GetBytes(body, query), func(v gjson.Result) {
+			if strings.HasPrefix(v.String(), "image/") {
+				found[constant.CapabilityVision] = true
+			} else if strings.HasPrefix(v.String(), "audio/") {
+				found[constant.CapabilityAudio] = true
+			}
+		})
+	}
+	if gjson.GetBytes(body, "stream").Bool() || strings.Contains(path, ":streamGenerateContent") {
+		found[constant.CapabilityStreaming] = true
+	}
+	if gjson.GetBytes(body, "tools.#").Int() > 0 {
+		found[constant.CapabilityTools] = true
+	}
+
+	var required []string
+	for _, capability := range []string{constant.CapabilityVision, constant.CapabilityAudio, constant.CapabilityStreaming, constant.CapabilityTools} {
+		if found[capability] {
+			required = append(required, capability)
+		}
+	}
+	return required
+}
+
+// forEachLeaf 展开 gjson 多级 # 查询返回的嵌套数组
+func forEachLeaf(result gjson.Result, fn func(gjson.Result)) {
+	if !result.IsArray() {
+		if result.Exists() {
+			fn(result)
+		}
+		return
+	}
+	result.ForEach(func(_, value gjson.Result) bool {
+		forEachLeaf(value, fn)
+		return true
+	})
+}
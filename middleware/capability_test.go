@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDetectRequiredCapabilities(t *testing.T) {
+	cases := []struct {
+		name string
+		path string
+		body string
+		want []string
+	}{
+		{"plain text", "/v1/chat/completions", `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`, nil},
+		{"openai image", "/v1/chat/completions", `{"messages":[{"role":"user","content":[{"type":"text","text":"hi"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}],"stream":true}`, []string{"vision", "streaming"}},
+		{"openai audio and tools", "/v1/chat/completions", `{"messages":[{"role":"user","content":[{"type":"input_audio","input_audio":{"data":"x","format":"wav"}}]}],"tools":[{"type":"function"}]}`, []string{"audio", "tools"}},
+		{"responses image", "/v1/responses", `{"input":[{"role":"user","content":[{"type":"input_image","image_url":"https://example.com/a.png"}]}]}`, []string{"vision"}},
+		{"claude image", "/v1/messages", `{"messages":[{"role":"user","content":[{"type":"image","source":{"type":"base64","data":"x"}}]}]}`, []string{"vision"}},
+		{"gemini inline image", "/v1beta/models/gemini-2.0-flash:streamGenerateContent", `{"contents":[{"parts":[{"text":"hi"},{"inline_data":{"mime_type":"image/png","data":"x"}}]}]}`, []string{"vision", "streaming"}},
+		{"gemini audio file", "/v1beta/models/gemini-2.0-flash:generateContent", `{"contents":[{"parts":[{"fileData":{"mimeType":"audio/mp3","fileUri":"gs://a"}}]}]}`, []string{"audio"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectRequiredCapabilities(tc.path, []byte(tc.body)); !slices.Equal(got, tc.want) {
+				t.Fatalf("detectRequiredCapabilities = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
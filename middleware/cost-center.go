@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const CostCenterHeader = "X-Cost-Center"
+
+// setupCostCenter 读取 X-Cost-Center 请求头并按白名单校验，未配置白名单时忽略该请求头
+func setupCostCenter(c *gin.Context) bool {
+	costCenter := strings.TrimSpace(c.GetHeader(CostCenterHeader))
+	if costCenter == "" || !operation_setting.IsCostCenterEnabled() {
+		return true
+	}
+	if !operation_setting.IsCostCenterAllowed(costCenter) {
+		abortWithOpenAiMessage(c, http.StatusBadRequest, fmt.Sprintf("成本中心 %s 不在允许列表中", costCenter))
+		return false
+	}
+	common.SetContextKey(c, constant.ContextKeyCostCenter, costCenter)
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -15,6 +16,7 @@ import (
 	"github.com/QuantumNous/new-api/model"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 
@@ -32,6 +34,10 @@ func Distribute() func(c *gin.Context) {
 		channelId, ok := common.GetContextKey(c, constant.ContextKeyTokenSpecificChannelId)
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
 		if err != nil {
+			if common.IsRequestBodyTooLargeError(err) {
+				abortWithRequestBodyTooLarge(c, err)
+				return
+			}
 			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request, "+err.Error())
 			return
 		}
@@ -74,6 +80,35 @@ func Distribute() func(c *gin.Context) {
 			}
 
 			if shouldSelectChannel {
+				channel = getResponsesSessionChannel(c, modelRequest.Model)
+			}
+			if shouldSelectChannel && channel == nil {
+				channel, err = getUploadedVideoChannel(c, modelRequest.Model)
+				if err != nil {
+					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, err.Error())
+					return
+				}
+			}
+			if shouldSelectChannel && channel == nil {
+				if userModel := getApprovedUserModel(c, modelRequest.Model); userModel != nil {
+					channel, err = model.CacheGetChannel(userModel.ChannelId)
+					if err != nil || channel.Status != common.ChannelStatusEnabled {
+						abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("自定义模型 %s 绑定的渠道不可用", modelRequest.Model))
+						return
+					}
+					// 注册后用户分组或渠道配置可能已变化，每次请求重新校验
+					userGroup := common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+					if !slices.Contains(channel.GetGroups(), userGroup) || !operation_setting.IsUserModelChannelTypeAllowed(channel.Type) {
+						abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("无权使用自定义模型 %s 绑定的渠道", modelRequest.Model))
+						return
+					}
+					common.SetContextKey(c, constant.ContextKeyUserModelUpstream, userModel.UpstreamModelId)
+					common.SetContextKey(c, constant.ContextKeyUserModelPrice, userModel.PriceOverride)
+					// 自定义模型只能由绑定的渠道处理，不重试其他渠道
+					c.Set("specific_channel_id", strconv.Itoa(channel.Id))
+				}
+			}
+			if shouldSelectChannel && channel == nil {
 				if modelRequest.Model == "" {
 					abortWithOpenAiMessage(c, http.StatusBadRequest, "未指定模型名称，模型名称不能为空")
 					return
@@ -85,6 +120,10 @@ func Distribute() func(c *gin.Context) {
 					playgroundRequest := &dto.PlayGroundRequest{}
 					err = common.UnmarshalBodyReusable(c, playgroundRequest)
 					if err != nil {
+						if common.IsRequestBodyTooLargeError(err) {
+							abortWithRequestBodyTooLarge(c, err)
+							return
+						}
 						abortWithOpenAiMessage(c, http.StatusBadRequest, "无效的playground请求, "+err.Error())
 						return
 					}
@@ -97,6 +136,7 @@ func Distribute() func(c *gin.Context) {
 						common.SetContextKey(c, constant.ContextKeyUsingGroup, usingGroup)
 					}
 				}
+				setRequiredCapabilities(c)
 				channel, selectGroup, err = service.CacheGetRandomSatisfiedChannel(&service.RetryParam{
 					Ctx:        c,
 					ModelName:  modelRequest.Model,
@@ -118,7 +158,11 @@ func Distribute() func(c *gin.Context) {
 					return
 				}
 				if channel == nil {
-					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, fmt.Sprintf("分组 %s 下模型 %s 无可用渠道（distributor）", usingGroup, modelRequest.Model), string(types.ErrorCodeModelNotFound))
+					message := fmt.Sprintf("分组 %s 下模型 %s 无可用渠道（distributor）", usingGroup, modelRequest.Model)
+					if required := common.GetContextKeyStringSlice(c, constant.ContextKeyRequiredCapabilities); len(required) > 0 {
+						message += fmt.Sprintf("，请求需要渠道能力: %s", strings.Join(required, ","))
+					}
+					abortWithOpenAiMessage(c, http.StatusServiceUnavailable, message, string(types.ErrorCodeModelNotFound))
 					return
 				}
 			}
@@ -129,6 +173,102 @@ func Distribute() func(c *gin.Context) {
 	}
 }
 
+// getResponsesSessionChannel 携带 previous_response_id 的 Responses 请求优先回到创建该 response 的渠道，
+// 找不到记录、渠道已不可用或已不属于当前分组时返回 nil，按常规逻辑选择渠道
+func getResponsesSessionChannel(c *gin.Context, modelName string) *model.Channel {
+	if c.Request.Method != http.MethodPost || !strings.HasPrefix(c.Request.URL.Path, "/v1/responses") {
+		return nil
+	}
+	var request struct {
+		PreviousResponseID string `json:"previous_response_id"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil || request.PreviousResponseID == "" {
+		return nil
+	}
+	channelId, ok := service.GetResponsesSessionChannel(request.PreviousResponseID, c.GetInt("id"))
+	if !ok {
+		return nil
+	}
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil || channel.Status != common.ChannelStatusEnabled {
+		return nil
+	}
+	if !slices.Contains(channel.GetModels(), modelName) || !responsesSessionGroupAllowed(c, channel) {
+		return nil
+	}
+	return channel
+}
+
+// uploadedVideoURIPattern 匹配 /v1/files/upload 返回的 gs://{bucket}/uploads/{userId}/{channelId}/{name}
+var uploadedVideoURIPattern = regexp.MustCompile(`^gs://([^/]+)/uploads/(\d+)/(\d+)/[^/]+$`)
+
+// getUploadedVideoChannel 引用已上传视频的任务提交固定到接收该上传的渠道，其他渠道的存储桶中没有该对象。
+// 未引用当前用户上传的视频时返回 nil，交由常规选择；上传渠道不可用时返回错误
+func getUploadedVideoChannel(c *gin.Context, modelName string) (*model.Channel, error) {
+	if c.Request.Method != http.MethodPost || !strings.HasPrefix(c.Request.URL.Path, "/v1/video") {
+		return nil, nil
+	}
+	var request struct {
+		Video    string         `json:"video" form:"video"`
+		Metadata map[string]any `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return nil, nil
+	}
+	video := strings.TrimSpace(request.Video)
+	if video == "" && request.Metadata != nil {
+		video, _ = request.Metadata["video"].(string)
+		video = strings.TrimSpace(video)
+	}
+	matches := uploadedVideoURIPattern.FindStringSubmatch(video)
+	if matches == nil || matches[2] != strconv.Itoa(c.GetInt("id")) {
+		return nil, nil
+	}
+	channelId, _ := strconv.Atoi(matches[3])
+	channel, err := model.CacheGetChannel(channelId)
+	if err != nil || channel.Status != common.ChannelStatusEnabled || channel.GetOtherSettings().VertexGcsBucket != matches[1] ||
+		!slices.Contains(channel.GetModels(), modelName) || !responsesSessionGroupAllowed(c, channel) {
+		return nil, fmt.Errorf("the channel that stored %s is not available for model %s, upload the video again", video, modelName)
+	}
+	// 只有该渠道能读取上传的对象，不重试其他渠道
+	c.Set("specific_channel_id", strconv.Itoa(channel.Id))
+	return channel, nil
+}
+
+// responsesSessionGroupAllowed 校验渠道仍属于当前令牌可用的分组，auto 分组时记录渠道所在的分组
+func responsesSessionGroupAllowed(c *gin.Context, channel *model.Channel) bool {
+	channelGroups := channel.GetGroups()
+	usingGroup := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	if usingGroup != "auto" {
+		return slices.Contains(channelGroups, usingGroup)
+	}
+	userGroup := common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+	for i, autoGroup := range service.GetUserAutoGroup(userGroup) {
+		if slices.Contains(channelGroups, autoGroup) {
+			common.SetContextKey(c, constant.ContextKeyAutoGroup, autoGroup)
+			common.SetContextKey(c, constant.ContextKeyAutoGroupIndex, i)
+			return true
+		}
+	}
+	return false
+}
+
+// getApprovedUserModel 查找当前用户以该名称注册且已审核通过的自定义模型
+func getApprovedUserModel(c *gin.Context, modelName string) *model.UserModel {
+	if modelName == "" {
+		return nil
+	}
+	userModel, ok, err := model.GetApprovedUserModel(c.GetInt("id"), modelName)
+	if err != nil {
+		common.SysLog(fmt.Sprintf("failed to get user model %s: %s", modelName, err.Error()))
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return userModel
+}
+
 // getModelFromRequest 从请求中读取模型信息
 // 根据 Content-Type 自动处理：
 // - application/json
@@ -138,7 +278,7 @@ func getModelFromRequest(c *gin.Context) (*ModelRequest, error) {
 	var modelRequest ModelRequest
 	err := common.UnmarshalBodyReusable(c, &modelRequest)
 	if err != nil {
-		return nil, errors.New("无效的请求, " + err.Error())
+		return nil, fmt.Errorf("无效的请求, %w", err)
 	}
 	return &modelRequest, nil
 }
@@ -158,7 +298,7 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 			midjourneyRequest := dto.MidjourneyRequest{}
 			err = common.UnmarshalBodyReusable(c, &midjourneyRequest)
 			if err != nil {
-				return nil, false, errors.New("无效的midjourney请求, " + err.Error())
+				return nil, false, fmt.Errorf("无效的midjourney请求, %w", err)
 			}
 			midjourneyModel, mjErr, success := service.GetMjRequestModel(relayMode, &midjourneyRequest)
 			if mjErr != nil {
@@ -210,6 +350,15 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 			modelLower := strings.ToLower(modelRequest.Model)
 			if strings.HasPrefix(modelLower, "doubao-seedance") || strings.HasPrefix(modelLower, "wan2-1-14b") {
 				c.Set("platform", string(constant.TaskPlatform(strconv.Itoa(constant.ChannelTypeVolcVideo))))
+			} else if strings.HasPrefix(modelLower, "doubao-seed-tts") {
+				// 方舟语音合成同样走异步任务，使用火山渠道的密钥
+				c.Set("platform", string(constant.TaskPlatformVolcAudio))
+			} else if strings.HasPrefix(modelLower, "gen3a") || strings.HasPrefix(modelLower, "gen4") {
+				c.Set("platform", string(constant.TaskPlatformRunway))
+			} else if strings.HasPrefix(modelLower, "ray-") || strings.HasPrefix(modelLower, "luma") {
+				c.Set("platform", string(constant.TaskPlatformLuma))
+			} else if strings.HasPrefix(modelLower, "udio") {
+				c.Set("platform", string(constant.TaskPlatformUdio))
 			}
 		} else if c.Request.Method == http.MethodGet {
 			relayMode = relayconstant.RelayModeVideoFetchByID
@@ -263,7 +412,7 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/v1/images/generations") {
 		modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "dall-e")
-	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") {
+	} else if strings.HasPrefix(c.Request.URL.Path, "/v1/images/edits") || strings.HasPrefix(c.Request.URL.Path, "/v1/images/variations") {
 		//modelRequest.Model = common.GetStringIfEmpty(c.PostForm("model"), "gpt-image-1")
 		contentType := c.ContentType()
 		if slices.Contains([]string{gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm}, contentType) {
@@ -280,14 +429,22 @@ func getModelRequest(c *gin.Context) (*ModelRequest, bool, error) {
 			modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "tts-1")
 		} else if strings.HasPrefix(c.Request.URL.Path, "/v1/audio/translations") {
 			// 先尝试从请求读取
-			if req, err := getModelFromRequest(c); err == nil && req.Model != "" {
+			req, err := getModelFromRequest(c)
+			if common.IsRequestBodyTooLargeError(err) {
+				return nil, false, err
+			}
+			if err == nil && req.Model != "" {
 				modelRequest.Model = req.Model
 			}
 			modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "whisper-1")
 			relayMode = relayconstant.RelayModeAudioTranslation
 		} else if strings.HasPrefix(c.Request.URL.Path, "/v1/audio/transcriptions") {
 			// 先尝试从请求读取
-			if req, err := getModelFromRequest(c); err == nil && req.Model != "" {
+			req, err := getModelFromRequest(c)
+			if common.IsRequestBodyTooLargeError(err) {
+				return nil, false, err
+			}
+			if err == nil && req.Model != "" {
 				modelRequest.Model = req.Model
 			}
 			modelRequest.Model = common.GetStringIfEmpty(modelRequest.Model, "whisper-1")
@@ -327,6 +484,7 @@ func SetupContextForSelectedChannel(c *gin.Context, channel *model.Channel, mode
 	common.SetContextKey(c, constant.ContextKeyChannelAutoBan, channel.GetAutoBan())
 	common.SetContextKey(c, constant.ContextKeyChannelModelMapping, channel.GetModelMapping())
 	common.SetContextKey(c, constant.ContextKeyChannelStatusCodeMapping, channel.GetStatusCodeMapping())
+	common.SetContextKey(c, constant.ContextKeyChannelConcurrencyLimit, channel.GetConcurrencyLimit())
 
 	key, index, newAPIError := channel.GetNextEnabledKey()
 	if newAPIError != nil {
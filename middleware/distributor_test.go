@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestResponsesSessionGroupAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		usingGroup    string
+		channelGroup  string
+		want          bool
+		wantAutoGroup string
+	}{
+		{"default", "default,vip", true, ""},
+		{"default", "vip", false, ""},
+		{"auto", "vip,default", true, "default"},
+		{"auto", "vip", false, ""},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		common.SetContextKey(c, constant.ContextKeyUsingGroup, tc.usingGroup)
+		common.SetContextKey(c, constant.ContextKeyUserGroup, "default")
+		got := responsesSessionGroupAllowed(c, &model.Channel{Group: tc.channelGroup})
+		if got != tc.want {
+			t.Errorf("using %s, channel %s: got %v, want %v", tc.usingGroup, tc.channelGroup, got, tc.want)
+		}
+		if autoGroup := common.GetContextKeyString(c, constant.ContextKeyAutoGroup); autoGroup != tc.wantAutoGroup {
+			t.Errorf("using %s, channel %s: auto group = %q, want %q", tc.usingGroup, tc.channelGroup, autoGroup, tc.wantAutoGroup)
+		}
+	}
+}
+
+func TestGetUploadedVideoChannelPinsUploadChannel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Channel{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originCache := model.DB, common.MemoryCacheEnabled
+	model.DB, common.MemoryCacheEnabled = db, false
+	t.Cleanup(func() { model.DB, common.MemoryCacheEnabled = originDB, originCache })
+
+	channels := []*model.Channel{
+		{Id: 3, Type: constant.ChannelTypeVertexAi, Status: common.ChannelStatusEnabled, Models: "veo-3.0-generate-001", Group: "default", OtherSettings: `{"vertex_gcs_bucket":"videos"}`},
+		{Id: 4, Type: constant.ChannelTypeVertexAi, Status: common.ChannelStatusManuallyDisabled, Models: "veo-3.0-generate-001", Group: "default", OtherSettings: `{"vertex_gcs_bucket":"videos"}`},
+	}
+	for _, ch := range channels {
+		if err := db.Create(ch).Error; err != nil {
+			t.Fatalf("create channel failed: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		video       string
+		wantChannel int
+		wantErr     bool
+	}{
+		{"upload channel", "gs://videos/uploads/7/3/abc.mp4", 3, false},
+		{"disabled upload channel", "gs://videos/uploads/7/4/abc.mp4", 0, true},
+		{"bucket mismatch", "gs://other/uploads/7/3/abc.mp4", 0, true},
+		{"other user", "gs://videos/uploads/8/3/abc.mp4", 0, false},
+		{"no upload", "https://example.com/abc.mp4", 0, false},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		body := `{"model":"veo-3.0-generate-001","prompt":"a cat","video":"` + tc.video + `"}`
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/video/generations", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("id", 7)
+		common.SetContextKey(c, constant.ContextKeyUsingGroup, "default")
+
+		channel, err := getUploadedVideoChannel(c, "veo-3.0-generate-001")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		gotChannel := 0
+		if channel != nil {
+			gotChannel = channel.Id
+		}
+		if gotChannel != tc.wantChannel {
+			t.Errorf("%s: got channel %d, want %d", tc.name, gotChannel, tc.wantChannel)
+		}
+		if _, pinned := c.Get("specific_channel_id"); pinned != (tc.wantChannel != 0) {
+			t.Errorf("%s: specific_channel_id set = %v", tc.name, pinned)
+		}
+	}
+}
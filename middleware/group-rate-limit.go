@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupRateLimit 按 (分组, 模型) 维度进行 RPM/TPM 限流，需在 Distribute 之后使用
+func GroupRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		limit, ok := ratio_setting.GetGroupModelRateLimit(group, modelName)
+		if !ok || (limit.RPM <= 0 && limit.TPM <= 0) {
+			c.Next()
+			return
+		}
+
+		ctx := context.Background()
+		allowed, retryAfter, err := service.DefaultGroupRateLimiter.Allow(ctx, group, modelName, limit)
+		if err != nil {
+			// 限流存储异常时放行，避免影响正常请求
+			common.SysError(fmt.Sprintf("group rate limit check failed: %v", err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(service.RetryAfterSeconds(retryAfter)))
+			abortWithOpenAiMessage(c, http.StatusTooManyRequests,
+				fmt.Sprintf("分组 %s 的模型 %s 已达到速率限制（RPM %d，TPM %d），请稍后再试", group, modelName, limit.RPM, limit.TPM),
+				"group_rate_limit_exceeded")
+			return
+		}
+
+		c.Next()
+
+		if limit.TPM > 0 {
+			// 按实际消耗的输入与输出 token 计数，请求失败未产生用量时按预估的输入 token 计数
+			tokens, ok := common.GetContextKeyType[int](c, constant.ContextKeyUsageTotalTokens)
+			if !ok {
+				tokens = common.GetContextKeyInt(c, constant.ContextKeyPromptTokens)
+			}
+			if err := service.DefaultGroupRateLimiter.RecordTokens(ctx, group, modelName, tokens); err != nil {
+				common.SysError(fmt.Sprintf("group rate limit record tokens failed: %v", err))
+			}
+		}
+	}
+}
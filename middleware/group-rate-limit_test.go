@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupGroupRateLimitRouter(t testing.TB, limit ratio_setting.GroupModelRateLimit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	setting := ratio_setting.GetGroupRateLimitSetting()
+	prev := *setting
+	setting.Enabled = true
+	setting.Limits = map[string]ratio_setting.GroupModelRateLimit{"bench": limit}
+	setting.ModelLimits = map[string]map[string]ratio_setting.GroupModelRateLimit{}
+	t.Cleanup(func() { *setting = prev })
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		common.SetContextKey(c, constant.ContextKeyUsingGroup, "bench")
+		common.SetContextKey(c, constant.ContextKeyOriginalModel, c.Query("model"))
+	}, GroupRateLimit())
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestGroupRateLimitRetryAfter(t *testing.T) {
+	r := setupGroupRateLimitRouter(t, ratio_setting.GroupModelRateLimit{RPM: 1})
+	model := "rpm-test-" + time.Now().Format("150405.000000")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?model="+model, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?model="+model, nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+}
+
+func TestGroupRateLimitCountsCompletionTokens(t *testing.T) {
+	r := setupGroupRateLimitRouter(t, ratio_setting.GroupModelRateLimit{TPM: 100})
+	r.GET("/usage", func(c *gin.Context) {
+		// 预估的输入 token 远小于实际用量，输出 token 也必须计入
+		common.SetContextKey(c, constant.ContextKeyPromptTokens, 10)
+		common.SetContextKey(c, constant.ContextKeyUsageTotalTokens, 150)
+		c.Status(http.StatusOK)
+	})
+	model := "tpm-test-" + time.Now().Format("150405.000000")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/usage?model="+model, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/usage?model="+model, nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 after exceeding TPM with completion tokens, got %d", w.Code)
+	}
+}
+
+// BenchmarkGroupRateLimit 衡量限流中间件带来的单请求开销，应远小于 1ms
+func BenchmarkGroupRateLimit(b *testing.B) {
+	r := setupGroupRateLimitRouter(b, ratio_setting.GroupModelRateLimit{RPM: 1 << 30, TPM: 1 << 30})
+	req := httptest.NewRequest(http.MethodGet, "/?model=bench-model", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if perOp := time.Since(start) / time.Duration(b.N); perOp > time.Millisecond {
+		b.Fatalf("group rate limit overhead %v exceeds 1ms per request", perOp)
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalRequestValues 将服务内部请求通过 context 附加的键值写入 gin 上下文，需在注册路由之前使用
+func InternalRequestValues() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for key, value := range common.InternalRequestValues(c.Request.Context()) {
+			common.SetContextKey(c, key, value)
+		}
+		c.Next()
+	}
+}
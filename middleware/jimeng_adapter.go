@@ -23,6 +23,10 @@ func JimengRequestConvert() func(c *gin.Context) {
 		// Handle Jimeng official API request
 		var originalReq map[string]interface{}
 		if err := common.UnmarshalBodyReusable(c, &originalReq); err != nil {
+			if common.IsRequestBodyTooLargeError(err) {
+				abortWithRequestBodyTooLarge(c, err)
+				return
+			}
 			abortWithOpenAiMessage(c, http.StatusBadRequest, "Invalid request body")
 			return
 		}
@@ -15,6 +15,10 @@ func KlingRequestConvert() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		var originalReq map[string]interface{}
 		if err := common.UnmarshalBodyReusable(c, &originalReq); err != nil {
+			if common.IsRequestBodyTooLargeError(err) {
+				abortWithRequestBodyTooLarge(c, err)
+				return
+			}
 			c.Next()
 			return
 		}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	TokenInvalidationRateLimitMark = "TI"
+	TokenInvalidationMaxRequests   = 10   // 每小时最多作废10次
+	TokenInvalidationDuration      = 3600 // 1小时时间窗口
+)
+
+func redisTokenInvalidationRateLimiter(c *gin.Context) {
+	ctx := context.Background()
+	rdb := common.RDB
+	key := "tokenInvalidation:" + TokenInvalidationRateLimitMark + ":" + strconv.Itoa(c.GetInt("id"))
+
+	count, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		// fallback
+		memoryTokenInvalidationRateLimiter(c)
+		return
+	}
+
+	if count == 1 {
+		_ = rdb.Expire(ctx, key, time.Duration(TokenInvalidationDuration)*time.Second).Err()
+	}
+
+	if count <= int64(TokenInvalidationMaxRequests) {
+		c.Next()
+		return
+	}
+
+	ttl, err := rdb.TTL(ctx, key).Result()
+	waitSeconds := int64(TokenInvalidationDuration)
+	if err == nil && ttl > 0 {
+		waitSeconds = int64(ttl.Seconds())
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"message": fmt.Sprintf("作废令牌过于频繁，请等待 %d 秒后再试", waitSeconds),
+	})
+	c.Abort()
+}
+
+func memoryTokenInvalidationRateLimiter(c *gin.Context) {
+	key := TokenInvalidationRateLimitMark + ":" + strconv.Itoa(c.GetInt("id"))
+
+	if !inMemoryRateLimiter.Request(key, TokenInvalidationMaxRequests, TokenInvalidationDuration) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "作废令牌过于频繁，请稍后再试",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// TokenInvalidationRateLimit 按用户限制令牌作废频率，需放在设置了 id 的鉴权中间件之后
+func TokenInvalidationRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if common.RedisEnabled {
+			redisTokenInvalidationRateLimiter(c)
+		} else {
+			inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+			memoryTokenInvalidationRateLimiter(c)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTokenOrigin 令牌配置了 allowed_origins 时，按令牌校验浏览器请求的 Origin：
+// 匹配则返回该 Origin 的 CORS 头覆盖全局策略，不匹配返回 403。不带 Origin 的请求（非浏览器客户端）不受限制
+func checkTokenOrigin(c *gin.Context, token *model.Token) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" || len(token.GetAllowedOrigins()) == 0 {
+		return true
+	}
+	if !token.IsOriginAllowed(origin) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌不允许来自 "+origin+" 的跨域请求")
+		return false
+	}
+	header := c.Writer.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Set("Access-Control-Allow-Credentials", "true")
+	header.Add("Vary", "Origin")
+	return true
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckTokenOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	restricted := &model.Token{AllowedOrigins: `["https://app.example.com"]`}
+	cases := []struct {
+		name       string
+		token      *model.Token
+		origin     string
+		wantOK     bool
+		wantOrigin string
+	}{
+		{"matched origin", restricted, "https://app.example.com", true, "https://app.example.com"},
+		{"unmatched origin", restricted, "https://evil.example.com", false, ""},
+		{"no origin header", restricted, "", true, ""},
+		{"token without allowed origins", &model.Token{}, "https://evil.example.com", true, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tc.origin != "" {
+				c.Request.Header.Set("Origin", tc.origin)
+			}
+			if ok := checkTokenOrigin(c, tc.token); ok != tc.wantOK {
+				t.Fatalf("checkTokenOrigin = %v, want %v", ok, tc.wantOK)
+			}
+			if !tc.wantOK && w.Code != http.StatusForbidden {
+				t.Fatalf("status = %d, want 403", w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkTokenIp 令牌配置了 allow_ips 时，客户端 IP 必须匹配其中的 IP 或 CIDR，否则返回 403 ip_not_allowed；未配置时不限制
+func checkTokenIp(c *gin.Context, token *model.Token) bool {
+	allowIps := token.GetIpLimits()
+	if len(allowIps) == 0 {
+		return true
+	}
+	clientIp := c.ClientIP()
+	logger.LogDebug(c, "Token has IP restrictions, checking client IP %s", clientIp)
+	ip := net.ParseIP(clientIp)
+	if ip == nil {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "无法解析客户端 IP 地址", "ip_not_allowed")
+		return false
+	}
+	if !common.IsIpInCIDRList(ip, allowIps) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中", "ip_not_allowed")
+		return false
+	}
+	logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
+	return true
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckTokenIp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	allowIps := "10.0.0.0/8\n192.168.1.10"
+	restricted := &model.Token{AllowIps: &allowIps}
+	cases := []struct {
+		name   string
+		token  *model.Token
+		ip     string
+		wantOK bool
+	}{
+		{"ip in cidr", restricted, "10.1.2.3", true},
+		{"exact ip", restricted, "192.168.1.10", true},
+		{"ip not allowed", restricted, "192.168.1.11", false},
+		{"token without allow_ips", &model.Token{}, "8.8.8.8", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			c.Request.RemoteAddr = tc.ip + ":12345"
+			if ok := checkTokenIp(c, tc.token); ok != tc.wantOK {
+				t.Fatalf("checkTokenIp = %v, want %v", ok, tc.wantOK)
+			}
+			if tc.wantOK {
+				return
+			}
+			var resp struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusForbidden || resp.Error.Code != "ip_not_allowed" {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// tokenScopeAnyScope 任意配置了 scopes 的令牌都可访问的路由
+const tokenScopeAnyScope = ""
+
+// tokenRouteScopes 按 "METHOD 路由模板" 记录路由所需的权限范围。配置了 scopes 的令牌只能访问此处列出的路由，
+// 未列出的路由（如对话、嵌入）一律拒绝；未配置 scopes 的令牌不受限制
+var tokenRouteScopes = buildTokenRouteScopes()
+
+func buildTokenRouteScopes() map[string]string {
+	routes := map[string]string{
+		"GET /v1/models":                            tokenScopeAnyScope,
+		"GET /v1/models/:model":                     tokenScopeAnyScope,
+		"GET /v1beta/models":                        tokenScopeAnyScope,
+		"GET /v1beta/openai/models":                 tokenScopeAnyScope,
+		"DELETE /v1/tokens/self":                    tokenScopeAnyScope,
+		"GET /api/usage/token/":                     tokenScopeAnyScope,
+		"POST /v1/edits":                            constant.TokenScopeImageGenerate,
+		"POST /v1/images/generations":               constant.TokenScopeImageGenerate,
+		"POST /v1/images/edits":                     constant.TokenScopeImageGenerate,
+		"POST /v1/images/variations":                constant.TokenScopeImageGenerate,
+		"POST /v1/audio/speech":                     constant.TokenScopeAudioGenerate,
+		"POST /suno/submit/:action":                 constant.TokenScopeAudioGenerate,
+		"POST /suno/fetch":                          constant.TokenScopeVideoFetch,
+		"GET /suno/fetch/:id":                       constant.TokenScopeVideoFetch,
+		"POST /v1/video/generations":                constant.TokenScopeVideoGenerate,
+		"POST /v1/videos":                           constant.TokenScopeVideoGenerate,
+		"POST /v1/videos/:video_id/remix":           constant.TokenScopeVideoGenerate,
+		"POST /v1/videos/generations":               constant.TokenScopeVideoGenerate,
+		"POST /v1/videos/edits":                     constant.TokenScopeVideoGenerate,
+		"POST /v1/videos/extensions":                constant.TokenScopeVideoGenerate,
+		"POST /v1/videos/batch":                     constant.TokenScopeVideoGenerate,
+		"POST /v1/estimate":                         constant.TokenScopeVideoGenerate,
+		"POST /v1/files/upload":                     constant.TokenScopeVideoGenerate,
+		"POST /v1/tasks/:id/replay":                 constant.TokenScopeVideoGenerate,
+		"PATCH /v1/tasks/:id/tags":                  constant.TokenScopeVideoGenerate,
+		"POST /v1/tasks/:id/cancel":                 constant.TokenScopeTaskCancel,
+		"GET /v1/video/generations/:task_id":        constant.TokenScopeVideoFetch,
+		"GET /v1/videos/:task_id":                   constant.TokenScopeVideoFetch,
+		"GET /v1/videos/:task_id/content":           constant.TokenScopeVideoFetch,
+		"GET /v1/videos/:task_id/stream":            constant.TokenScopeVideoFetch,
+		"GET /v1/videos/:task_id/audio":             constant.TokenScopeVideoFetch,
+		"GET /v1/videos/generations/:task_id":       constant.TokenScopeVideoFetch,
+		"GET /v1/tasks":                             constant.TokenScopeVideoFetch,
+		"GET /v1/tasks/dependencies/:id":            constant.TokenScopeVideoFetch,
+		"POST /kling/v1/videos/text2video":          constant.TokenScopeVideoGenerate,
+		"POST /kling/v1/videos/image2video":         constant.TokenScopeVideoGenerate,
+		"GET /kling/v1/videos/text2video/:task_id":  constant.TokenScopeVideoFetch,
+		"GET /kling/v1/videos/image2video/:task_id": constant.TokenScopeVideoFetch,
+		"POST /jimeng/":                             constant.TokenScopeVideoGenerate,
+	}
+	mjRoutes := map[string]string{
+		"POST /submit/action":                constant.TokenScopeImageGenerate,
+		"POST /submit/shorten":               constant.TokenScopeImageGenerate,
+		"POST /submit/modal":                 constant.TokenScopeImageGenerate,
+		"POST /submit/imagine":               constant.TokenScopeImageGenerate,
+		"POST /submit/change":                constant.TokenScopeImageGenerate,
+		"POST /submit/simple-change":         constant.TokenScopeImageGenerate,
+		"POST /submit/describe":              constant.TokenScopeImageGenerate,
+		"POST /submit/blend":                 constant.TokenScopeImageGenerate,
+		"POST /submit/edits":                 constant.TokenScopeImageGenerate,
+		"POST /submit/video":                 constant.TokenScopeImageGenerate,
+		"POST /submit/upload-discord-images": constant.TokenScopeImageGenerate,
+		"POST /insight-face/swap":            constant.TokenScopeImageGenerate,
+		"GET /task/:id/fetch":                constant.TokenScopeVideoFetch,
+		"GET /task/:id/image-seed":           constant.TokenScopeVideoFetch,
+		"POST /task/list-by-condition":       constant.TokenScopeVideoFetch,
+	}
+	for route, scope := range mjRoutes {
+		method, path, _ := strings.Cut(route, " ")
+		routes[method+" /mj"+path] = scope
+		routes[method+" /:mode/mj"+path] = scope
+	}
+	return routes
+}
+
+// checkTokenScope 校验令牌的权限范围是否允许访问当前路由，拒绝时返回 403 并中止请求。
+// 由 TokenAuth 调用，保证在 Distribute 选择渠道之前完成校验
+func checkTokenScope(c *gin.Context, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	scope, ok := tokenRouteScopes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问此接口")
+		return false
+	}
+	if scope != tokenScopeAnyScope && !lo.Contains(scopes, scope) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌没有 "+scope+" 权限")
+		return false
+	}
+	return true
+}
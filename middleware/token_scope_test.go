@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCheckTokenScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		name     string
+		token    *model.Token
+		method   string
+		path     string
+		wantCode int
+	}{
+		{"token without scopes", &model.Token{}, http.MethodPost, "/v1/chat/completions", http.StatusOK},
+		{"scope granted", &model.Token{Scopes: `["video:fetch","video:generate"]`}, http.MethodPost, "/v1/video/generations", http.StatusOK},
+		{"scope missing", &model.Token{Scopes: `["video:fetch"]`}, http.MethodPost, "/v1/video/generations", http.StatusForbidden},
+		{"unmapped route", &model.Token{Scopes: `["video:generate"]`}, http.MethodPost, "/v1/chat/completions", http.StatusForbidden},
+		{"route open to any scope", &model.Token{Scopes: `["video:fetch"]`}, http.MethodGet, "/v1/models", http.StatusOK},
+		{"task cancel", &model.Token{Scopes: `["video:fetch"]`}, http.MethodPost, "/v1/tasks/task_1/cancel", http.StatusForbidden},
+		{"task tags", &model.Token{Scopes: `["video:generate"]`}, http.MethodPatch, "/v1/tasks/task_1/tags", http.StatusOK},
+		{"mode midjourney route", &model.Token{Scopes: `["image:generate"]`}, http.MethodPost, "/fast/mj/submit/imagine", http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if !checkTokenScope(c, tc.token.GetScopes()) {
+					return
+				}
+				c.Next()
+			})
+			ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+			router.POST("/v1/chat/completions", ok)
+			router.POST("/v1/video/generations", ok)
+			router.GET("/v1/models", ok)
+			router.POST("/v1/tasks/:id/cancel", ok)
+			router.PATCH("/v1/tasks/:id/tags", ok)
+			router.POST("/:mode/mj/submit/imagine", ok)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+			if w.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d", w.Code, tc.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+)
+
+// 客户端传入的追踪 ID 超过该长度时忽略并重新生成
+const maxTraceIdLength = 64
+
+// TraceId 为每个请求生成追踪 ID，客户端已携带 X-Trace-Id 时沿用
+func TraceId() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id := c.GetHeader(common.TraceIdKey)
+		if id == "" || len(id) > maxTraceIdLength {
+			id = common.GetUUID()
+		}
+		c.Set(common.TraceIdKey, id)
+		c.Request = c.Request.WithContext(common.WithTraceId(c.Request.Context(), id))
+		c.Header(common.TraceIdKey, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTraceId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	prevWriter := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	t.Cleanup(func() { gin.DefaultWriter = prevWriter })
+
+	r := gin.New()
+	r.Use(TraceId())
+	var requestTraceId string
+	r.GET("/", func(c *gin.Context) {
+		requestTraceId = common.GetTraceId(c.Request.Context())
+		common.SetContextKey(c, constant.ContextKeyChannelId, 7)
+		common.SetContextKey(c, constant.ContextKeyOriginalModel, "gpt-4o")
+		common.SetContextKey(c, constant.ContextKeyUserId, 3)
+		logger.LogInfo(c, "relay done")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	traceId := w.Header().Get(common.TraceIdKey)
+	if traceId == "" {
+		t.Fatal("trace id header not set")
+	}
+	if requestTraceId != traceId {
+		t.Errorf("request context trace id = %q, want %q", requestTraceId, traceId)
+	}
+	line := logs.String()
+	for _, field := range []string{"trace_id=" + traceId, "channel_id=7", "model=gpt-4o", "user_id=3"} {
+		if !strings.Contains(line, field) {
+			t.Errorf("log line %q missing %s", line, field)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(common.TraceIdKey, "client-trace")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get(common.TraceIdKey); got != "client-trace" {
+		t.Errorf("trace id = %q, want client supplied value", got)
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+const VideoUploadRateLimitMark = "VU"
+
+func videoUploadRateLimitExceeded(c *gin.Context, message string) {
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "rate_limit_error",
+		},
+	})
+	c.Abort()
+}
+
+func redisVideoUploadRateLimiter(c *gin.Context) {
+	ctx := context.Background()
+	rdb := common.RDB
+	key := "videoUpload:" + VideoUploadRateLimitMark + ":" + strconv.Itoa(c.GetInt("token_id"))
+
+	count, err := rdb.Incr(ctx, key).Result()
+	if err != nil {
+		// fallback
+		memoryVideoUploadRateLimiter(c)
+		return
+	}
+
+	if count == 1 {
+		_ = rdb.Expire(ctx, key, time.Duration(common.VideoUploadRateLimitDuration)*time.Second).Err()
+	}
+
+	if count <= int64(common.VideoUploadRateLimitNum) {
+		c.Next()
+		return
+	}
+
+	ttl, err := rdb.TTL(ctx, key).Result()
+	waitSeconds := common.VideoUploadRateLimitDuration
+	if err == nil && ttl > 0 {
+		waitSeconds = int64(ttl.Seconds())
+	}
+	videoUploadRateLimitExceeded(c, fmt.Sprintf("too many file uploads for this token, retry in %d seconds", waitSeconds))
+}
+
+func memoryVideoUploadRateLimiter(c *gin.Context) {
+	key := VideoUploadRateLimitMark + ":" + strconv.Itoa(c.GetInt("token_id"))
+
+	if !inMemoryRateLimiter.Request(key, common.VideoUploadRateLimitNum, common.VideoUploadRateLimitDuration) {
+		videoUploadRateLimitExceeded(c, "too many file uploads for this token, please retry later")
+		return
+	}
+
+	c.Next()
+}
+
+// VideoUploadRateLimit 按令牌限制 /v1/files/upload 的上传次数，需放在 TokenAuth 之后
+func VideoUploadRateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if common.VideoUploadRateLimitNum <= 0 {
+			c.Next()
+			return
+		}
+		if common.RedisEnabled {
+			redisVideoUploadRateLimiter(c)
+		} else {
+			inMemoryRateLimiter.Init(common.RateLimitKeyExpirationDuration)
+			memoryVideoUploadRateLimiter(c)
+		}
+	}
+}
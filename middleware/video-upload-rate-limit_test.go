@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVideoUploadRateLimitIsPerToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prevNum, prevDuration, prevRedis := common.VideoUploadRateLimitNum, common.VideoUploadRateLimitDuration, common.RedisEnabled
+	common.VideoUploadRateLimitNum, common.VideoUploadRateLimitDuration, common.RedisEnabled = 1, 60, false
+	t.Cleanup(func() {
+		common.VideoUploadRateLimitNum, common.VideoUploadRateLimitDuration, common.RedisEnabled = prevNum, prevDuration, prevRedis
+	})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("token_id", common.String2Int(c.Query("token")))
+	}, VideoUploadRateLimit())
+	r.POST("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	upload := func(token string) int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?token="+token, nil))
+		return w.Code
+	}
+	if code := upload("9101"); code != http.StatusOK {
+		t.Fatalf("expected first upload to pass, got %d", code)
+	}
+	if code := upload("9101"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected second upload from the same token to be limited, got %d", code)
+	}
+	if code := upload("9102"); code != http.StatusOK {
+		t.Fatalf("expected another token to be unaffected, got %d", code)
+	}
+}
@@ -143,6 +143,31 @@ func GetChannel(group string, model string, retry int) (*Channel, error) {
 	return &channel, err
 }
 
+// getFilteredChannel 数据库模式下加载该分组与模型的全部可用渠道，过滤后再按优先级与权重选择
+func getFilteredChannel(group string, model string, retry int, filter ChannelFilter) (*Channel, error) {
+	var channelIds []int
+	err := DB.Model(&Ability{}).
+		Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
+		Pluck("channel_id", &channelIds).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(channelIds) == 0 {
+		return nil, nil
+	}
+	var channels []*Channel
+	if err = DB.Where("id in ?", channelIds).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	filtered := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if filter(channel) {
+			filtered = append(filtered, channel)
+		}
+	}
+	return pickChannelByPriority(filtered, group, model, retry)
+}
+
 func (channel *Channel) AddAbilities(tx *gorm.DB) error {
 	models_ := strings.Split(channel.Models, ",")
 	groups_ := strings.Split(channel.Group, ",")
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"gorm.io/gorm/clause"
+)
+
+// BillingDiscrepancy 计费对账发现的差异：任务最终扣费与按提交时价格计算的额度偏差超过阈值
+type BillingDiscrepancy struct {
+	Id           int     `json:"id"`
+	TaskRecordId int64   `json:"task_record_id" gorm:"uniqueIndex"` // tasks.id
+	TaskId       string  `json:"task_id" gorm:"type:varchar(191)"`
+	UserId       int     `json:"user_id" gorm:"index"`
+	ChannelId    int     `json:"channel_id" gorm:"index"`
+	ModelName    string  `json:"model_name" gorm:"type:varchar(191);index"`
+	ModelPrice   float64 `json:"model_price"`
+	GroupRatio   float64 `json:"group_ratio"`
+	OtherRatio   float64 `json:"other_ratio"`
+	// ExpectedQuota 按提交时价格计算的额度，ActualQuota 为任务最终扣费
+	ExpectedQuota int `json:"expected_quota"`
+	ActualQuota   int `json:"actual_quota"`
+	// DeviationPercent 实际扣费相对预期额度的偏差百分比，少扣为负数
+	DeviationPercent float64 `json:"deviation_percent"`
+	TaskFinishTime   int64   `json:"task_finish_time" gorm:"bigint;index"`
+	CreatedAt        int64   `json:"created_at" gorm:"bigint;index"`
+}
+
+// BillingReconciliationRun 一次对账覆盖的任务完成时间窗口 [WindowStart, WindowEnd)，下次对账从 WindowEnd 继续
+type BillingReconciliationRun struct {
+	Id            int   `json:"id"`
+	WindowStart   int64 `json:"window_start" gorm:"bigint"`
+	WindowEnd     int64 `json:"window_end" gorm:"bigint;index"`
+	Discrepancies int   `json:"discrepancies"`
+	CreatedAt     int64 `json:"created_at" gorm:"bigint"`
+}
+
+func (r *BillingReconciliationRun) Insert() error {
+	return DB.Create(r).Error
+}
+
+// GetLastBillingReconciliationEnd 返回最近一次对账的窗口结束时间，从未对账时返回 0
+func GetLastBillingReconciliationEnd() (int64, error) {
+	var end int64
+	err := DB.Model(&BillingReconciliationRun{}).Select("COALESCE(MAX(window_end), 0)").Scan(&end).Error
+	return end, err
+}
+
+// BillingDiscrepancyFilter 查询计费差异的筛选条件，时间范围按任务完成时间
+type BillingDiscrepancyFilter struct {
+	ChannelId      int
+	ModelName      string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// GetFinishedTasksForReconciliation 按 id 分批查询完成时间在 [start, end) 内的成功任务
+func GetFinishedTasksForReconciliation(start, end int64, afterId int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("status = ? AND finish_time >= ? AND finish_time < ? AND id > ?", TaskStatusSuccess, start, end, afterId).
+		Order("id asc").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// CreateBillingDiscrepancies 写入计费差异，同一任务重复对账时忽略
+func CreateBillingDiscrepancies(discrepancies []*BillingDiscrepancy) error {
+	if len(discrepancies) == 0 {
+		return nil
+	}
+	return DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&discrepancies).Error
+}
+
+func GetBillingDiscrepancies(filter BillingDiscrepancyFilter, startIdx int, num int) ([]*BillingDiscrepancy, int64, error) {
+	tx := DB.Model(&BillingDiscrepancy{})
+	if filter.ChannelId != 0 {
+		tx = tx.Where("channel_id = ?", filter.ChannelId)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("model_name = ?", filter.ModelName)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("task_finish_time >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("task_finish_time <= ?", filter.EndTimestamp)
+	}
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var discrepancies []*BillingDiscrepancy
+	err := tx.Order("id desc").Offset(startIdx).Limit(num).Find(&discrepancies).Error
+	return discrepancies, total, err
+}
@@ -0,0 +1,66 @@
+package model
+
+// BillingSnapshot 记录消费日志生成时实际生效的价格与倍率，用于计费争议时还原扣费过程。
+// 与 Log 存放在同一个数据库中，通过 LogId 关联，写入后不再修改。
+type BillingSnapshot struct {
+	Id              int     `json:"id"`
+	LogId           int     `json:"log_id" gorm:"uniqueIndex"`
+	UserId          int     `json:"user_id" gorm:"index"`
+	ModelName       string  `json:"model_name" gorm:"default:''"`
+	FinalModelName  string  `json:"final_model_name" gorm:"default:''"` // 经过模型重定向后实际请求上游的模型
+	ModelPrice      float64 `json:"model_price"`
+	ModelRatio      float64 `json:"model_ratio"`
+	CompletionRatio float64 `json:"completion_ratio"`
+	GroupRatio      float64 `json:"group_ratio"`
+	UserGroupRatio  float64 `json:"user_group_ratio"` // 未设置分组间倍率时为 0
+	Quota           int     `json:"quota"`
+	CapturedAt      int64   `json:"captured_at" gorm:"bigint;index"`
+}
+
+func getOtherFloat(other map[string]interface{}, key string) float64 {
+	switch v := other[key].(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	}
+	return 0
+}
+
+// newBillingSnapshot 从消费日志参数中提取计费信息
+func newBillingSnapshot(log *Log, params RecordConsumeLogParams) *BillingSnapshot {
+	finalModelName := params.ModelName
+	if upstream, ok := params.Other["upstream_model_name"].(string); ok && upstream != "" {
+		finalModelName = upstream
+	}
+	return &BillingSnapshot{
+		LogId:           log.Id,
+		UserId:          log.UserId,
+		ModelName:       params.ModelName,
+		FinalModelName:  finalModelName,
+		ModelPrice:      getOtherFloat(params.Other, "model_price"),
+		ModelRatio:      getOtherFloat(params.Other, "model_ratio"),
+		CompletionRatio: getOtherFloat(params.Other, "completion_ratio"),
+		GroupRatio:      getOtherFloat(params.Other, "group_ratio"),
+		UserGroupRatio:  getOtherFloat(params.Other, "user_group_ratio"),
+		Quota:           params.Quota,
+		CapturedAt:      log.CreatedAt,
+	}
+}
+
+// GetBillingSnapshotByLogId 获取消费日志对应的计费快照，userId 为 0 时不校验所属用户
+func GetBillingSnapshotByLogId(logId int, userId int) (*BillingSnapshot, error) {
+	var snapshot BillingSnapshot
+	tx := LOG_DB.Where("log_id = ?", logId)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if err := tx.First(&snapshot).Error; err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestRecordConsumeLogCapturesBillingSnapshot(t *testing.T) {
+	setupTestDB(t, &User{}, &Log{}, &BillingSnapshot{})
+	originLogDB, originEnabled, originRedis := LOG_DB, common.LogConsumeEnabled, common.RedisEnabled
+	LOG_DB, common.LogConsumeEnabled, common.RedisEnabled = DB, true, false
+	t.Cleanup(func() {
+		LOG_DB, common.LogConsumeEnabled, common.RedisEnabled = originLogDB, originEnabled, originRedis
+	})
+
+	user := &User{Username: "billing", Password: "password"}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	RecordConsumeLog(nil, user.Id, RecordConsumeLogParams{
+		ModelName: "sora-2",
+		Quota:     1500,
+		Other: map[string]interface{}{
+			"model_price":         0.3,
+			"group_ratio":         1.0,
+			"user_group_ratio":    0.5,
+			"upstream_model_name": "sora-2-pro",
+		},
+	})
+
+	var log Log
+	if err := LOG_DB.First(&log).Error; err != nil {
+		t.Fatalf("log not recorded: %v", err)
+	}
+	snapshot, err := GetBillingSnapshotByLogId(log.Id, user.Id)
+	if err != nil {
+		t.Fatalf("snapshot not recorded: %v", err)
+	}
+	if snapshot.ModelName != "sora-2" || snapshot.FinalModelName != "sora-2-pro" ||
+		snapshot.ModelPrice != 0.3 || snapshot.UserGroupRatio != 0.5 || snapshot.Quota != 1500 {
+		t.Fatalf("unexpected snapshot: %+v", snapshot)
+	}
+	if _, err := GetBillingSnapshotByLogId(log.Id, user.Id+1); err == nil {
+		t.Fatal("expected snapshot to be hidden from other users")
+	}
+}
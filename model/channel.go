@@ -5,9 +5,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
+	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
@@ -48,6 +51,13 @@ type Channel struct {
 	ParamOverride     *string `json:"param_override" gorm:"type:text"`
 	HeaderOverride    *string `json:"header_override" gorm:"type:text"`
 	Remark            *string `json:"remark" gorm:"type:varchar(255)" validate:"max=255"`
+	ConcurrencyLimit  *int    `json:"concurrency_limit" gorm:"default:0"` // 渠道最大并发请求数，0 表示不限制
+	ShadowChannelId   *int    `json:"shadow_channel_id" gorm:"default:0"` // 影子渠道，任务提交时异步镜像请求用于对比，0 表示关闭
+	// 能力标签，逗号分隔，如 vision,streaming；为空时使用适配器声明的能力
+	CapabilityTags *string `json:"capability_tags" gorm:"type:varchar(255);default:''"`
+	// 最近一次探活结果（OK/FAIL）及时间，未探活时为空
+	ProbeStatus string `json:"probe_status" gorm:"type:varchar(16);default:''"`
+	ProbeLastAt int64  `json:"probe_last_at" gorm:"bigint;default:0"`
 	// add after v0.8.5
 	ChannelInfo ChannelInfo `json:"channel_info" gorm:"type:json"`
 
@@ -102,6 +112,36 @@ func (channel *Channel) GetKeys() []string {
 	return keys
 }
 
+// getRateLimitedKeys 返回当前不可用的 key 下标：被临时禁用，或当前窗口内的请求数、token 数已达上限
+func (channel *Channel) getRateLimitedKeys(keys []string) map[int]bool {
+	disabledUntil, err := getChannelKeyDisabledUntil(channel.Id)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get key statuses for channel #%d: %s", channel.Id, err.Error()))
+	}
+	setting := channel.GetSetting()
+	keyHashes := make([]string, len(keys))
+	for i, key := range keys {
+		keyHashes[i] = HashChannelKey(key)
+	}
+	var usages map[string]channelKeyUsage
+	if setting.RateLimit > 0 || setting.TokenRateLimit > 0 {
+		if usages, err = getChannelKeyUsages(channel.Id, keyHashes); err != nil {
+			common.SysError(fmt.Sprintf("failed to get key usages for channel #%d: %s", channel.Id, err.Error()))
+		}
+	}
+	if len(disabledUntil) == 0 && len(usages) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	limited := make(map[int]bool)
+	for i, keyHash := range keyHashes {
+		if disabledUntil[keyHash] > now || usages[keyHash].exceeds(setting.RateLimit, setting.TokenRateLimit) {
+			limited[i] = true
+		}
+	}
+	return limited
+}
+
 func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	// If not in multi-key mode, return the original key string directly.
 	if !channel.ChannelInfo.IsMultiKey {
@@ -114,6 +154,8 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		// No keys available, return error, should disable the channel
 		return "", 0, types.NewError(errors.New("no keys available"), types.ErrorCodeChannelNoAvailableKey)
 	}
+	// 去掉 key#weight=N 中的权重后缀，weights 为 nil 表示未配置权重
+	keys, weights := parseKeyWeights(keys)
 
 	lock := GetChannelPollingLock(channel.Id)
 	lock.Lock()
@@ -145,12 +187,43 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
 	}
 
+	// 跳过超出每分钟请求数或被上游 429 临时禁用的 key
+	limited := channel.getRateLimitedKeys(keys)
+	if len(limited) > 0 {
+		availableIdx := make([]int, 0, len(enabledIdx))
+		for _, idx := range enabledIdx {
+			if !limited[idx] {
+				availableIdx = append(availableIdx, idx)
+			}
+		}
+		if len(availableIdx) == 0 {
+			return "", 0, types.NewErrorWithStatusCode(errors.New("all enabled keys are rate limited"), types.ErrorCodeChannelKeysRateLimited, http.StatusTooManyRequests)
+		}
+		enabledIdx = availableIdx
+		baseGetStatus := getStatus
+		getStatus = func(idx int) int {
+			if limited[idx] {
+				return common.ChannelStatusAutoDisabled
+			}
+			return baseGetStatus(idx)
+		}
+	}
+
 	switch channel.ChannelInfo.MultiKeyMode {
 	case constant.MultiKeyModeRandom:
+		if weights != nil {
+			selectedIdx := randomWeightedKeyIndex(enabledIdx, weights)
+			return keys[selectedIdx], selectedIdx, nil
+		}
 		// Randomly pick one enabled key
 		selectedIdx := enabledIdx[rand.Intn(len(enabledIdx))]
 		return keys[selectedIdx], selectedIdx, nil
 	case constant.MultiKeyModePolling:
+		// 配置了权重时使用加权轮询，计数器为原子操作，不需要读写 polling index
+		if weights != nil {
+			selectedIdx := nextWeightedKeyIndex(channel.Id, enabledIdx, weights)
+			return keys[selectedIdx], selectedIdx, nil
+		}
 		// Use channel-specific lock to ensure thread-safe polling
 
 		channelInfo, err := CacheGetChannelInfo(channel.Id)
@@ -406,6 +479,34 @@ func BatchDeleteChannels(ids []int) error {
 	return tx.Commit().Error
 }
 
+func (channel *Channel) GetConcurrencyLimit() int {
+	if channel.ConcurrencyLimit == nil {
+		return 0
+	}
+	return *channel.ConcurrencyLimit
+}
+
+func (channel *Channel) GetShadowChannelId() int {
+	if channel.ShadowChannelId == nil {
+		return 0
+	}
+	return *channel.ShadowChannelId
+}
+
+// GetCapabilityTags 返回管理员为渠道配置的能力标签，未配置时返回空
+func (channel *Channel) GetCapabilityTags() []string {
+	if channel.CapabilityTags == nil || strings.TrimSpace(*channel.CapabilityTags) == "" {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(*channel.CapabilityTags, ",") {
+		if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 func (channel *Channel) GetPriority() int64 {
 	if channel.Priority == nil {
 		return 0
@@ -445,6 +546,66 @@ func (channel *Channel) GetStatusCodeMapping() string {
 	return *channel.StatusCodeMapping
 }
 
+// Clone 深拷贝渠道配置，用于以相同配置创建新渠道。运行时状态（余额、用量、测速、探活）被重置，
+// 新渠道默认为手动禁用状态，需管理员确认后启用
+func (channel *Channel) Clone() *Channel {
+	clone := *channel
+	clone.Id = 0
+	clone.Status = common.ChannelStatusManuallyDisabled
+	clone.CreatedTime = common.GetTimestamp()
+	clone.TestTime = 0
+	clone.ResponseTime = 0
+	clone.Balance = 0
+	clone.BalanceUpdatedTime = 0
+	clone.UsedQuota = 0
+	clone.ProbeStatus = ""
+	clone.ProbeLastAt = 0
+	clone.Keys = nil
+
+	clone.OpenAIOrganization = clonePtr(channel.OpenAIOrganization)
+	clone.TestModel = clonePtr(channel.TestModel)
+	clone.Weight = clonePtr(channel.Weight)
+	clone.BaseURL = clonePtr(channel.BaseURL)
+	clone.ModelMapping = clonePtr(channel.ModelMapping)
+	clone.StatusCodeMapping = clonePtr(channel.StatusCodeMapping)
+	clone.Priority = clonePtr(channel.Priority)
+	clone.AutoBan = clonePtr(channel.AutoBan)
+	clone.Tag = clonePtr(channel.Tag)
+	clone.Setting = clonePtr(channel.Setting)
+	clone.ParamOverride = clonePtr(channel.ParamOverride)
+	clone.HeaderOverride = clonePtr(channel.HeaderOverride)
+	clone.Remark = clonePtr(channel.Remark)
+	clone.ConcurrencyLimit = clonePtr(channel.ConcurrencyLimit)
+	clone.ShadowChannelId = clonePtr(channel.ShadowChannelId)
+	clone.CapabilityTags = clonePtr(channel.CapabilityTags)
+
+	clone.ChannelInfo.MultiKeyStatusList = maps.Clone(channel.ChannelInfo.MultiKeyStatusList)
+	clone.ChannelInfo.MultiKeyDisabledReason = maps.Clone(channel.ChannelInfo.MultiKeyDisabledReason)
+	clone.ChannelInfo.MultiKeyDisabledTime = maps.Clone(channel.ChannelInfo.MultiKeyDisabledTime)
+	clone.ChannelInfo.MultiKeyPollingIndex = 0
+	return &clone
+}
+
+// SetClonedKey 替换克隆渠道的 key，多 key 渠道按新 key 列表重新计算数量并清空原 key 的禁用状态
+func (channel *Channel) SetClonedKey(key string) {
+	channel.Key = key
+	channel.Keys = nil
+	if channel.ChannelInfo.IsMultiKey {
+		channel.ChannelInfo.MultiKeySize = len(channel.GetKeys())
+		channel.ChannelInfo.MultiKeyStatusList = nil
+		channel.ChannelInfo.MultiKeyDisabledReason = nil
+		channel.ChannelInfo.MultiKeyDisabledTime = nil
+	}
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
 func (channel *Channel) Insert() error {
 	var err error
 	err = DB.Create(channel).Error
@@ -514,6 +675,32 @@ func (channel *Channel) UpdateResponseTime(responseTime int64) {
 	}
 }
 
+const (
+	ChannelProbeStatusOK   = "OK"
+	ChannelProbeStatusFail = "FAIL"
+)
+
+func (channel *Channel) UpdateProbeStatus(status string) {
+	channel.ProbeStatus = status
+	channel.ProbeLastAt = common.GetTimestamp()
+	err := DB.Model(channel).Select("probe_status", "probe_last_at").Updates(Channel{
+		ProbeStatus: channel.ProbeStatus,
+		ProbeLastAt: channel.ProbeLastAt,
+	}).Error
+	if err != nil {
+		common.SysLog(fmt.Sprintf("failed to update probe status: channel_id=%d, error=%v", channel.Id, err))
+	}
+}
+
+// GetIdleChannelsForProbe 返回已启用、自 idleSince 起没有真实请求且在 probedBefore 之前未探活的渠道
+func GetIdleChannelsForProbe(idleSince int64, probedBefore int64) ([]*Channel, error) {
+	var channels []*Channel
+	activeChannelIds := DB.Model(&ChannelHealthEvent{}).Distinct("channel_id").Where("window_start >= ?", idleSince)
+	err := DB.Where("status = ? AND probe_last_at < ? AND id NOT IN (?)", common.ChannelStatusEnabled, probedBefore, activeChannelIds).
+		Find(&channels).Error
+	return channels, err
+}
+
 func (channel *Channel) UpdateBalance(balance float64) {
 	err := DB.Model(channel).Select("balance_updated_time", "balance").Updates(Channel{
 		BalanceUpdatedTime: common.GetTimestamp(),
@@ -565,6 +752,7 @@ func CleanupChannelPollingLocks() {
 		channelId := key.(int)
 		if !activeChannelSet[channelId] {
 			channelPollingLocks.Delete(channelId)
+			channelKeyCounters.Delete(channelId)
 		}
 		return true
 	})
@@ -577,7 +765,7 @@ func handlerMultiKeyUpdate(channel *Channel, usingKey string, status int, reason
 	} else {
 		var keyIndex int
 		for i, key := range keys {
-			if key == usingKey {
+			if key, _ := ParseWeightedKey(key); key == usingKey {
 				keyIndex = i
 				break
 			}
@@ -93,42 +93,61 @@ func SyncChannelCache(frequency int) {
 	}
 }
 
+// ChannelFilter 渠道过滤条件，返回 false 的渠道不参与选择
+type ChannelFilter func(channel *Channel) bool
+
 func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel, error) {
+	return GetRandomSatisfiedChannelFiltered(group, model, retry, nil)
+}
+
+// GetRandomSatisfiedChannelFiltered 与 GetRandomSatisfiedChannel 相同，但只在满足 filter 的渠道中按优先级与权重选择
+func GetRandomSatisfiedChannelFiltered(group string, model string, retry int, filter ChannelFilter) (*Channel, error) {
 	// if memory cache is disabled, get channel directly from database
 	if !common.MemoryCacheEnabled {
-		return GetChannel(group, model, retry)
+		if filter == nil {
+			return GetChannel(group, model, retry)
+		}
+		return getFilteredChannel(group, model, retry, filter)
 	}
 
 	channelSyncLock.RLock()
 	defer channelSyncLock.RUnlock()
 
 	// First, try to find channels with the exact model name.
-	channels := group2model2channels[group][model]
+	channelIds := group2model2channels[group][model]
 
 	// If no channels found, try to find channels with the normalized model name.
-	if len(channels) == 0 {
+	if len(channelIds) == 0 {
 		normalizedModel := ratio_setting.FormatMatchingModelName(model)
-		channels = group2model2channels[group][normalizedModel]
+		channelIds = group2model2channels[group][normalizedModel]
 	}
 
+	channels := make([]*Channel, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		channel, ok := channelsIDM[channelId]
+		if !ok {
+			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channelId)
+		}
+		if filter == nil || filter(channel) {
+			channels = append(channels, channel)
+		}
+	}
+	return pickChannelByPriority(channels, group, model, retry)
+}
+
+// pickChannelByPriority 按 retry 选择优先级档位，再在该档位内按权重随机选择渠道
+func pickChannelByPriority(channels []*Channel, group string, model string, retry int) (*Channel, error) {
 	if len(channels) == 0 {
 		return nil, nil
 	}
 
 	if len(channels) == 1 {
-		if channel, ok := channelsIDM[channels[0]]; ok {
-			return channel, nil
-		}
-		return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channels[0])
+		return channels[0], nil
 	}
 
 	uniquePriorities := make(map[int]bool)
-	for _, channelId := range channels {
-		if channel, ok := channelsIDM[channelId]; ok {
-			uniquePriorities[int(channel.GetPriority())] = true
-		} else {
-			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channelId)
-		}
+	for _, channel := range channels {
+		uniquePriorities[int(channel.GetPriority())] = true
 	}
 	var sortedUniquePriorities []int
 	for priority := range uniquePriorities {
@@ -144,14 +163,10 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	// get the priority for the given retry number
 	var sumWeight = 0
 	var targetChannels []*Channel
-	for _, channelId := range channels {
-		if channel, ok := channelsIDM[channelId]; ok {
-			if channel.GetPriority() == targetPriority {
-				sumWeight += channel.GetWeight()
-				targetChannels = append(targetChannels, channel)
-			}
-		} else {
-			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channelId)
+	for _, channel := range channels {
+		if channel.GetPriority() == targetPriority {
+			sumWeight += channel.GetWeight()
+			targetChannels = append(targetChannels, channel)
 		}
 	}
 
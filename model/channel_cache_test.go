@@ -0,0 +1,58 @@
+package model
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestGetRandomSatisfiedChannelFiltered(t *testing.T) {
+	setupTestDB(t, &Channel{}, &Ability{})
+	initCol()
+	originCache := common.MemoryCacheEnabled
+	t.Cleanup(func() { common.MemoryCacheEnabled = originCache })
+
+	vision, textOnly := "vision,streaming", "streaming"
+	high := int64(10)
+	for _, ch := range []*Channel{
+		// 高优先级渠道不支持图片输入，带图请求应跳过它并落到低优先级渠道
+		{Id: 1, Name: "text", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default", Priority: &high, CapabilityTags: &textOnly},
+		{Id: 2, Name: "vision", Status: common.ChannelStatusEnabled, Models: "gpt-4o", Group: "default", CapabilityTags: &vision},
+	} {
+		if err := ch.Insert(); err != nil {
+			t.Fatalf("insert channel: %v", err)
+		}
+	}
+	needVision := func(ch *Channel) bool { return slices.Contains(ch.GetCapabilityTags(), "vision") }
+	rejectAll := func(*Channel) bool { return false }
+
+	for _, cacheEnabled := range []bool{false, true} {
+		common.MemoryCacheEnabled = cacheEnabled
+		InitChannelCache()
+
+		if ch, err := GetRandomSatisfiedChannel("default", "gpt-4o", 0); err != nil || ch == nil || ch.Id != 1 {
+			t.Fatalf("cache=%v unfiltered: got %+v, err %v", cacheEnabled, ch, err)
+		}
+		for retry := 0; retry < 2; retry++ {
+			ch, err := GetRandomSatisfiedChannelFiltered("default", "gpt-4o", retry, needVision)
+			if err != nil || ch == nil || ch.Id != 2 {
+				t.Fatalf("cache=%v retry=%d filtered: got %+v, err %v", cacheEnabled, retry, ch, err)
+			}
+		}
+		if ch, err := GetRandomSatisfiedChannelFiltered("default", "gpt-4o", 0, rejectAll); err != nil || ch != nil {
+			t.Fatalf("cache=%v: expected no channel, got %+v, err %v", cacheEnabled, ch, err)
+		}
+	}
+}
+
+func TestGetCapabilityTags(t *testing.T) {
+	tags := " Vision, streaming,,tools "
+	ch := &Channel{CapabilityTags: &tags}
+	if got := ch.GetCapabilityTags(); !slices.Equal(got, []string{"vision", "streaming", "tools"}) {
+		t.Fatalf("GetCapabilityTags = %v", got)
+	}
+	if got := (&Channel{}).GetCapabilityTags(); got != nil {
+		t.Fatalf("GetCapabilityTags without tags = %v", got)
+	}
+}
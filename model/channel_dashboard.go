@@ -0,0 +1,79 @@
+package model
+
+// ChannelRequestStat 渠道在一段时间内的请求数与错误数
+type ChannelRequestStat struct {
+	ChannelId int   `json:"channel_id"`
+	Total     int64 `json:"total"`
+	Errors    int64 `json:"errors"`
+}
+
+// GetChannelDashboardChannels 获取所有渠道的概览字段，不加载 key 等大字段
+func GetChannelDashboardChannels() ([]*Channel, error) {
+	var channels []*Channel
+	err := DB.Select("id", "name", "type", "status", "priority", "weight", "channel_info", "test_time", "response_time").
+		Order("id asc").Find(&channels).Error
+	return channels, err
+}
+
+// GetInFlightTaskCountByChannel 按渠道统计未结束的任务数
+func GetInFlightTaskCountByChannel() (map[int]int64, error) {
+	var rows []struct {
+		ChannelId int
+		Count     int64
+	}
+	err := DB.Model(&Task{}).Select("channel_id, count(*) as count").
+		Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess}).
+		Group("channel_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ChannelId] = row.Count
+	}
+	return counts, nil
+}
+
+// GetChannelRequestStatsSince 按渠道汇总自 since 起各健康窗口的请求数与错误数
+func GetChannelRequestStatsSince(since int64) (map[int]*ChannelRequestStat, error) {
+	var rows []*ChannelRequestStat
+	err := DB.Model(&ChannelHealthEvent{}).
+		Select("channel_id, sum(count) as total, sum(case when error_code <> ? then count else 0 end) as errors", ChannelHealthSuccessCode).
+		Where("window_start >= ?", since).
+		Group("channel_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[int]*ChannelRequestStat, len(rows))
+	for _, row := range rows {
+		stats[row.ChannelId] = row
+	}
+	return stats, nil
+}
+
+// GetLastConsumeTimeByChannel 按渠道获取自 since 起最后一条消费日志的时间，即最近一次成功请求的时间
+func GetLastConsumeTimeByChannel(since int64) (map[int]int64, error) {
+	var rows []struct {
+		ChannelId int
+		LastAt    int64
+	}
+	err := LOG_DB.Model(&Log{}).Select("channel_id, max(created_at) as last_at").
+		Where("created_at >= ? AND type = ?", since, LogTypeConsume).
+		Group("channel_id").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	lastAt := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		lastAt[row.ChannelId] = row.LastAt
+	}
+	return lastAt, nil
+}
+
+// GetKeyCount 返回渠道的 key 数量，多 key 渠道以 MultiKeySize 为准，不需要加载 key 字段
+func (channel *Channel) GetKeyCount() int {
+	if channel.ChannelInfo.IsMultiKey {
+		return channel.ChannelInfo.MultiKeySize
+	}
+	return 1
+}
@@ -0,0 +1,55 @@
+package model
+
+import "testing"
+
+func TestChannelDashboardAggregates(t *testing.T) {
+	setupTestDB(t, &Task{}, &ChannelHealthEvent{}, &Log{})
+	originLogDB := LOG_DB
+	LOG_DB = DB
+	t.Cleanup(func() { LOG_DB = originLogDB })
+
+	tasks := []*Task{
+		{TaskID: "t1", ChannelId: 1, Status: TaskStatusInProgress},
+		{TaskID: "t2", ChannelId: 1, Status: TaskStatusSubmitted},
+		{TaskID: "t3", ChannelId: 1, Status: TaskStatusSuccess},
+		{TaskID: "t4", ChannelId: 2, Status: TaskStatusFailure},
+	}
+	if err := DB.Create(&tasks).Error; err != nil {
+		t.Fatalf("create tasks failed: %v", err)
+	}
+	inFlight, err := GetInFlightTaskCountByChannel()
+	if err != nil {
+		t.Fatalf("count in flight tasks failed: %v", err)
+	}
+	if inFlight[1] != 2 || inFlight[2] != 0 {
+		t.Fatalf("unexpected in flight counts: %v", inFlight)
+	}
+
+	_ = IncreaseChannelHealthEventCount(1, ChannelHealthSuccessCode, 600, 8)
+	_ = IncreaseChannelHealthEventCount(1, "bad_response", 600, 2)
+	_ = IncreaseChannelHealthEventCount(1, "bad_response", 300, 5)
+	stats, err := GetChannelRequestStatsSince(600)
+	if err != nil {
+		t.Fatalf("get request stats failed: %v", err)
+	}
+	if stat := stats[1]; stat == nil || stat.Total != 10 || stat.Errors != 2 {
+		t.Fatalf("unexpected request stats: %+v", stats[1])
+	}
+
+	logs := []*Log{
+		{ChannelId: 1, Type: LogTypeConsume, CreatedAt: 100},
+		{ChannelId: 1, Type: LogTypeConsume, CreatedAt: 200},
+		{ChannelId: 1, Type: LogTypeError, CreatedAt: 300},
+		{ChannelId: 2, Type: LogTypeConsume, CreatedAt: 50},
+	}
+	if err := DB.Create(&logs).Error; err != nil {
+		t.Fatalf("create logs failed: %v", err)
+	}
+	lastAt, err := GetLastConsumeTimeByChannel(60)
+	if err != nil {
+		t.Fatalf("get last consume time failed: %v", err)
+	}
+	if lastAt[1] != 200 || lastAt[2] != 0 {
+		t.Fatalf("unexpected last consume times: %v", lastAt)
+	}
+}
@@ -0,0 +1,110 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// ChannelGroup 渠道回退链：按顺序排列的渠道 ID 列表。
+// 渠道在渠道设置中通过 fallback_group 指定所属的回退链，
+// 请求在该渠道上遇到非 4xx 错误时，依次尝试链上的后续渠道。
+type ChannelGroup struct {
+	Id          int       `json:"id"`
+	Name        string    `json:"name" gorm:"size:64;not null;uniqueIndex"`
+	ChannelIds  JSONValue `json:"channel_ids" gorm:"type:json"`
+	Description string    `json:"description,omitempty" gorm:"type:varchar(255)"`
+	CreatedTime int64     `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64     `json:"updated_time" gorm:"bigint"`
+}
+
+// GetChannelIdList 解析渠道 ID 列表
+func (g *ChannelGroup) GetChannelIdList() ([]int, error) {
+	if len(g.ChannelIds) == 0 {
+		return []int{}, nil
+	}
+	var ids []int
+	if err := common.Unmarshal(g.ChannelIds, &ids); err != nil {
+		return nil, fmt.Errorf("invalid channel_ids: %w", err)
+	}
+	return ids, nil
+}
+
+// Insert 新建回退链
+func (g *ChannelGroup) Insert() error {
+	now := common.GetTimestamp()
+	g.CreatedTime = now
+	g.UpdatedTime = now
+	return DB.Create(g).Error
+}
+
+// Update 更新回退链
+func (g *ChannelGroup) Update() error {
+	g.UpdatedTime = common.GetTimestamp()
+	return DB.Save(g).Error
+}
+
+// IsChannelGroupNameDuplicated 检查回退链名称是否重复（排除自身 ID）
+func IsChannelGroupNameDuplicated(id int, name string) (bool, error) {
+	if name == "" {
+		return false, nil
+	}
+	var cnt int64
+	err := DB.Model(&ChannelGroup{}).Where("name = ? AND id <> ?", name, id).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func DeleteChannelGroupByID(id int) error {
+	return DB.Delete(&ChannelGroup{}, id).Error
+}
+
+func GetAllChannelGroups() ([]*ChannelGroup, error) {
+	var groups []*ChannelGroup
+	if err := DB.Order("id").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func GetChannelGroupByName(name string) (*ChannelGroup, error) {
+	var group ChannelGroup
+	if err := DB.Where("name = ?", name).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetFallbackChannel 返回回退链中位于 currentChannelId 之后的第一个启用的渠道。
+// currentChannelId 不在链中时从链首开始查找，链上没有可用渠道时返回错误。
+func GetFallbackChannel(groupName string, currentChannelId int) (*Channel, error) {
+	group, err := GetChannelGroupByName(groupName)
+	if err != nil {
+		return nil, fmt.Errorf("channel group %s not found: %w", groupName, err)
+	}
+	ids, err := group.GetChannelIdList()
+	if err != nil {
+		return nil, err
+	}
+	start := 0
+	for i, id := range ids {
+		if id == currentChannelId {
+			start = i + 1
+			break
+		}
+	}
+	for _, id := range ids[start:] {
+		if id == currentChannelId {
+			continue
+		}
+		channel, err := CacheGetChannel(id)
+		if err != nil || channel == nil {
+			continue
+		}
+		if channel.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		return channel, nil
+	}
+	return nil, errors.New("no available fallback channel")
+}
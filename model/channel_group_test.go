@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestGetFallbackChannel(t *testing.T) {
+	setupTestDB(t, &Channel{}, &ChannelGroup{})
+	common.MemoryCacheEnabled = false
+
+	for _, ch := range []*Channel{
+		{Id: 1, Name: "primary", Status: common.ChannelStatusEnabled},
+		{Id: 2, Name: "disabled", Status: common.ChannelStatusManuallyDisabled},
+		{Id: 3, Name: "secondary", Status: common.ChannelStatusEnabled},
+		{Id: 4, Name: "tertiary", Status: common.ChannelStatusEnabled},
+	} {
+		if err := DB.Create(ch).Error; err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+	}
+	group := &ChannelGroup{Name: "video", ChannelIds: JSONValue(`[1,2,3,4]`)}
+	if err := group.Insert(); err != nil {
+		t.Fatalf("insert group: %v", err)
+	}
+
+	cases := []struct {
+		current int
+		want    int
+	}{
+		{current: 1, want: 3}, // 跳过已禁用的渠道
+		{current: 3, want: 4},
+		{current: 99, want: 1}, // 不在链中时从链首开始
+	}
+	for _, tc := range cases {
+		ch, err := GetFallbackChannel("video", tc.current)
+		if err != nil {
+			t.Fatalf("GetFallbackChannel(%d): %v", tc.current, err)
+		}
+		if ch.Id != tc.want {
+			t.Errorf("GetFallbackChannel(%d) = #%d, want #%d", tc.current, ch.Id, tc.want)
+		}
+	}
+
+	if _, err := GetFallbackChannel("video", 4); err == nil {
+		t.Error("expected error at the end of the chain")
+	}
+	if _, err := GetFallbackChannel("missing", 1); err == nil {
+		t.Error("expected error for unknown group")
+	}
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ChannelHealthSuccessCode 用于记录窗口内成功请求数的保留错误码
+const ChannelHealthSuccessCode = "success"
+
+// ChannelHealthEvent 按时间窗口聚合的渠道请求结果，每个 (渠道, 错误码, 窗口) 一行
+type ChannelHealthEvent struct {
+	Id          int    `json:"id"`
+	ChannelId   int    `json:"channel_id" gorm:"uniqueIndex:idx_channel_health_window"`
+	ErrorCode   string `json:"error_code" gorm:"type:varchar(64);uniqueIndex:idx_channel_health_window"`
+	Count       int64  `json:"count" gorm:"default:0"`
+	WindowStart int64  `json:"window_start" gorm:"bigint;uniqueIndex:idx_channel_health_window;index"`
+}
+
+// IncreaseChannelHealthEventCount 累加渠道在指定窗口内某错误码的次数
+func IncreaseChannelHealthEventCount(channelId int, errorCode string, windowStart int64, delta int64) error {
+	result := DB.Model(&ChannelHealthEvent{}).
+		Where("channel_id = ? AND error_code = ? AND window_start = ?", channelId, errorCode, windowStart).
+		Update("count", gorm.Expr("count + ?", delta))
+	if result.Error != nil || result.RowsAffected > 0 {
+		return result.Error
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}, {Name: "error_code"}, {Name: "window_start"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"count": gorm.Expr("count + ?", delta)}),
+	}).Create(&ChannelHealthEvent{
+		ChannelId:   channelId,
+		ErrorCode:   errorCode,
+		Count:       delta,
+		WindowStart: windowStart,
+	}).Error
+}
+
+// GetChannelHealthEvents 获取渠道自 since 起的所有聚合窗口，按窗口时间升序
+func GetChannelHealthEvents(channelId int, since int64) ([]*ChannelHealthEvent, error) {
+	var events []*ChannelHealthEvent
+	err := DB.Where("channel_id = ? AND window_start >= ?", channelId, since).
+		Order("window_start asc").Find(&events).Error
+	return events, err
+}
+
+// DeleteChannelHealthEventsBefore 清理早于 before 的聚合窗口
+func DeleteChannelHealthEventsBefore(before int64) error {
+	return DB.Where("window_start < ?", before).Delete(&ChannelHealthEvent{}).Error
+}
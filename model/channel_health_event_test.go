@@ -0,0 +1,77 @@
+package model
+
+import "testing"
+
+func TestIncreaseChannelHealthEventCount(t *testing.T) {
+	setupTestDB(t, &ChannelHealthEvent{})
+
+	for i := 0; i < 3; i++ {
+		if err := IncreaseChannelHealthEventCount(1, "bad_response", 300, 2); err != nil {
+			t.Fatalf("increase failed: %v", err)
+		}
+	}
+	if err := IncreaseChannelHealthEventCount(1, ChannelHealthSuccessCode, 600, 1); err != nil {
+		t.Fatalf("increase failed: %v", err)
+	}
+
+	events, err := GetChannelHealthEvents(1, 0)
+	if err != nil {
+		t.Fatalf("get events failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].WindowStart != 300 || events[0].Count != 6 {
+		t.Fatalf("unexpected first event: %+v", events[0])
+	}
+
+	if err := DeleteChannelHealthEventsBefore(600); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	events, _ = GetChannelHealthEvents(1, 0)
+	if len(events) != 1 || events[0].WindowStart != 600 {
+		t.Fatalf("expected only the newer window to remain, got %+v", events)
+	}
+}
+
+func TestGetIdleChannelsForProbe(t *testing.T) {
+	setupTestDB(t, &Channel{}, &ChannelHealthEvent{})
+
+	channels := []*Channel{
+		{Id: 1, Name: "active", Status: 1},
+		{Id: 2, Name: "idle", Status: 1},
+		{Id: 3, Name: "recently-probed", Status: 1, ProbeLastAt: 950},
+		{Id: 4, Name: "disabled", Status: 2},
+		{Id: 5, Name: "stale-activity", Status: 1},
+	}
+	for _, channel := range channels {
+		if err := DB.Create(channel).Error; err != nil {
+			t.Fatalf("create channel failed: %v", err)
+		}
+	}
+	if err := IncreaseChannelHealthEventCount(1, ChannelHealthSuccessCode, 1000, 1); err != nil {
+		t.Fatalf("increase failed: %v", err)
+	}
+	if err := IncreaseChannelHealthEventCount(5, ChannelHealthSuccessCode, 100, 1); err != nil {
+		t.Fatalf("increase failed: %v", err)
+	}
+
+	idle, err := GetIdleChannelsForProbe(900, 900)
+	if err != nil {
+		t.Fatalf("get idle channels failed: %v", err)
+	}
+	got := map[int]bool{}
+	for _, channel := range idle {
+		got[channel.Id] = true
+	}
+	if len(got) != 2 || !got[2] || !got[5] {
+		t.Fatalf("idle channels = %v, want [2 5]", got)
+	}
+
+	idle[0].UpdateProbeStatus(ChannelProbeStatusFail)
+	var stored Channel
+	DB.First(&stored, idle[0].Id)
+	if stored.ProbeStatus != ChannelProbeStatusFail || stored.ProbeLastAt == 0 {
+		t.Fatalf("probe status not stored: %+v", stored)
+	}
+}
@@ -0,0 +1,227 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	channelKeyRateWindow   = 60 * time.Second
+	ChannelKeyCooldownTime = 60 * time.Second
+	// 各节点从数据库刷新 key 临时禁用状态的间隔，本节点写入的禁用状态立即生效
+	channelKeyStatusRefreshInterval = 10 * time.Second
+	channelKeyUsageKeyPrefix        = "channel_key_usage:"
+)
+
+// ChannelKeyStatus 记录多 key 渠道中被临时禁用的 key（上游 429 冷却或额度耗尽到计费周期结束）。
+// 每分钟的请求数与 token 数只保存在 Redis 或进程内的窗口中，不写数据库
+type ChannelKeyStatus struct {
+	Id            int    `json:"id"`
+	ChannelId     int    `json:"channel_id" gorm:"uniqueIndex:idx_channel_key_hash"`
+	KeyHash       string `json:"key_hash" gorm:"type:varchar(64);uniqueIndex:idx_channel_key_hash"`
+	DisabledUntil int64  `json:"disabled_until" gorm:"bigint"`
+}
+
+// HashChannelKey 返回 key 的 sha256，避免在状态表中保存明文 key
+func HashChannelKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// channelKeyUsage key 在当前窗口内的请求数与 token 数
+type channelKeyUsage struct {
+	Requests int
+	Tokens   int
+}
+
+// exceeds 判断用量是否已达到每分钟请求数或 token 数上限，上限不大于 0 表示不限制
+func (u channelKeyUsage) exceeds(rpmLimit, tpmLimit int) bool {
+	return (rpmLimit > 0 && u.Requests >= rpmLimit) || (tpmLimit > 0 && u.Tokens >= tpmLimit)
+}
+
+// channelKeyWindow 未启用 Redis 时进程内的固定窗口计数
+type channelKeyWindow struct {
+	mu     sync.Mutex
+	window int64
+	usage  channelKeyUsage
+}
+
+// channelKeyWindows "channelId:keyHash" -> *channelKeyWindow
+var channelKeyWindows sync.Map
+
+// channelKeyCooldowns channel.id -> *channelKeyCooldown，缓存数据库中的临时禁用状态
+var channelKeyCooldowns sync.Map
+
+type channelKeyCooldown struct {
+	mu       sync.Mutex
+	loadedAt time.Time
+	until    map[string]int64
+}
+
+func currentChannelKeyWindow(now time.Time) int64 {
+	return now.Unix() / int64(channelKeyRateWindow/time.Second)
+}
+
+func channelKeyUsageKey(channelId int, keyHash string, window int64) string {
+	return fmt.Sprintf("%s%d:%s:%d", channelKeyUsageKeyPrefix, channelId, keyHash, window)
+}
+
+// RecordChannelKeyUsage 累加 key 在当前一分钟窗口内的请求数与 token 数，
+// 启用 Redis 时在各节点间共享，否则只在本进程内计数
+func RecordChannelKeyUsage(channelId int, key string, tokens int) error {
+	keyHash := HashChannelKey(key)
+	now := time.Now()
+	window := currentChannelKeyWindow(now)
+	if common.RedisEnabled {
+		ctx := context.Background()
+		usageKey := channelKeyUsageKey(channelId, keyHash, window)
+		pipe := common.RDB.Pipeline()
+		pipe.HIncrBy(ctx, usageKey, "requests", 1)
+		pipe.HIncrBy(ctx, usageKey, "tokens", int64(tokens))
+		pipe.Expire(ctx, usageKey, 2*channelKeyRateWindow)
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+	value, _ := channelKeyWindows.LoadOrStore(fmt.Sprintf("%d:%s", channelId, keyHash), &channelKeyWindow{})
+	w := value.(*channelKeyWindow)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.window != window {
+		w.window = window
+		w.usage = channelKeyUsage{}
+	}
+	w.usage.Requests++
+	w.usage.Tokens += tokens
+	return nil
+}
+
+// getChannelKeyUsages 返回各 key 在当前窗口内的用量，以 key hash 为索引
+func getChannelKeyUsages(channelId int, keyHashes []string) (map[string]channelKeyUsage, error) {
+	window := currentChannelKeyWindow(time.Now())
+	result := make(map[string]channelKeyUsage, len(keyHashes))
+	if common.RedisEnabled {
+		ctx := context.Background()
+		pipe := common.RDB.Pipeline()
+		cmds := make([]*redis.StringStringMapCmd, len(keyHashes))
+		for i, keyHash := range keyHashes {
+			cmds[i] = pipe.HGetAll(ctx, channelKeyUsageKey(channelId, keyHash, window))
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		for i, keyHash := range keyHashes {
+			fields := cmds[i].Val()
+			if len(fields) == 0 {
+				continue
+			}
+			requests, _ := strconv.Atoi(fields["requests"])
+			tokens, _ := strconv.Atoi(fields["tokens"])
+			result[keyHash] = channelKeyUsage{Requests: requests, Tokens: tokens}
+		}
+		return result, nil
+	}
+	for _, keyHash := range keyHashes {
+		value, ok := channelKeyWindows.Load(fmt.Sprintf("%d:%s", channelId, keyHash))
+		if !ok {
+			continue
+		}
+		w := value.(*channelKeyWindow)
+		w.mu.Lock()
+		if w.window == window {
+			result[keyHash] = w.usage
+		}
+		w.mu.Unlock()
+	}
+	return result, nil
+}
+
+// getChannelKeyDisabledUntil 返回渠道中被临时禁用的 key 及其禁用截止时间，
+// 每个节点最多每 channelKeyStatusRefreshInterval 从数据库刷新一次
+func getChannelKeyDisabledUntil(channelId int) (map[string]int64, error) {
+	value, _ := channelKeyCooldowns.LoadOrStore(channelId, &channelKeyCooldown{})
+	cooldown := value.(*channelKeyCooldown)
+	cooldown.mu.Lock()
+	defer cooldown.mu.Unlock()
+	if cooldown.until != nil && time.Since(cooldown.loadedAt) < channelKeyStatusRefreshInterval {
+		return cooldown.until, nil
+	}
+	var statuses []*ChannelKeyStatus
+	if err := DB.Where("channel_id = ? AND disabled_until > ?", channelId, time.Now().Unix()).Find(&statuses).Error; err != nil {
+		return nil, err
+	}
+	until := make(map[string]int64, len(statuses))
+	for _, s := range statuses {
+		until[s.KeyHash] = s.DisabledUntil
+	}
+	cooldown.until = until
+	cooldown.loadedAt = time.Now()
+	return until, nil
+}
+
+// DisableChannelKeyUntil 临时禁用 key 直到 until，本节点立即生效，其他节点在下次刷新时生效
+func DisableChannelKeyUntil(channelId int, key string, until time.Time) error {
+	keyHash := HashChannelKey(key)
+	err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "channel_id"}, {Name: "key_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"disabled_until"}),
+	}).Create(&ChannelKeyStatus{
+		ChannelId:     channelId,
+		KeyHash:       keyHash,
+		DisabledUntil: until.Unix(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	if value, ok := channelKeyCooldowns.Load(channelId); ok {
+		cooldown := value.(*channelKeyCooldown)
+		cooldown.mu.Lock()
+		if cooldown.until != nil {
+			updated := make(map[string]int64, len(cooldown.until)+1)
+			for k, v := range cooldown.until {
+				updated[k] = v
+			}
+			updated[keyHash] = until.Unix()
+			cooldown.until = updated
+		}
+		cooldown.mu.Unlock()
+	}
+	return nil
+}
+
+// ChannelKeyBillingPeriodEnd 返回 t 所在计费周期（自然月）的结束时间，即下个月第一天零点
+func ChannelKeyBillingPeriodEnd(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+}
+
+// RotateChannelKey 将额度耗尽的 key 禁用到计费周期结束，并返回渠道中下一个可用的 key
+func RotateChannelKey(channelId int, exhaustedKey string) (string, error) {
+	channel, err := CacheGetChannel(channelId)
+	if err != nil {
+		return "", err
+	}
+	if !channel.ChannelInfo.IsMultiKey {
+		return "", fmt.Errorf("channel #%d is not a multi-key channel", channelId)
+	}
+	if err := DisableChannelKeyUntil(channelId, exhaustedKey, ChannelKeyBillingPeriodEnd(time.Now())); err != nil {
+		return "", err
+	}
+	newKey, _, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return "", apiErr
+	}
+	if newKey == exhaustedKey {
+		return "", errors.New("no other available key")
+	}
+	return newKey, nil
+}
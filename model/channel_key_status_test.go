@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// resetChannelKeyState 清空进程内的 key 用量窗口与禁用状态缓存
+func resetChannelKeyState(t *testing.T) {
+	t.Helper()
+	clear := func() {
+		channelKeyWindows.Range(func(k, _ any) bool { channelKeyWindows.Delete(k); return true })
+		channelKeyCooldowns.Range(func(k, _ any) bool { channelKeyCooldowns.Delete(k); return true })
+	}
+	clear()
+	t.Cleanup(clear)
+}
+
+func TestGetNextEnabledKeySkipsRateLimitedKeys(t *testing.T) {
+	setupTestDB(t, &ChannelKeyStatus{})
+	resetChannelKeyState(t)
+	setting := `{"rate_limit":2}`
+	channel := &Channel{
+		Id:      1,
+		Key:     "key-a\nkey-b",
+		Setting: &setting,
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:   true,
+			MultiKeySize: 2,
+		},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := RecordChannelKeyUsage(channel.Id, "key-a", 100); err != nil {
+			t.Fatalf("RecordChannelKeyUsage returned error: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		key, _, err := channel.GetNextEnabledKey()
+		if err != nil {
+			t.Fatalf("GetNextEnabledKey returned error: %v", err)
+		}
+		if key != "key-b" {
+			t.Fatalf("expected rate limited key-a to be skipped, got %s", key)
+		}
+	}
+
+	usages, _ := getChannelKeyUsages(channel.Id, []string{HashChannelKey("key-a")})
+	if u := usages[HashChannelKey("key-a")]; u.Requests != 2 || u.Tokens != 200 {
+		t.Fatalf("unexpected key-a usage: %+v", u)
+	}
+
+	if err := DisableChannelKeyUntil(channel.Id, "key-b", time.Now().Add(ChannelKeyCooldownTime)); err != nil {
+		t.Fatalf("DisableChannelKeyUntil returned error: %v", err)
+	}
+	if _, _, err := channel.GetNextEnabledKey(); err == nil || err.StatusCode != 429 {
+		t.Fatalf("expected 429 when every key is limited, got %v", err)
+	}
+
+	// 窗口过期后重新计数
+	value, _ := channelKeyWindows.Load(fmt.Sprintf("%d:%s", channel.Id, HashChannelKey("key-a")))
+	value.(*channelKeyWindow).window--
+	if key, _, err := channel.GetNextEnabledKey(); err != nil || key != "key-a" {
+		t.Fatalf("expected key-a after window reset, got %s (%v)", key, err)
+	}
+}
+
+func TestGetNextEnabledKeyEnforcesTokenRateLimit(t *testing.T) {
+	setupTestDB(t, &ChannelKeyStatus{})
+	resetChannelKeyState(t)
+	setting := `{"token_rate_limit":1000}`
+	channel := &Channel{
+		Id:      2,
+		Key:     "key-a\nkey-b",
+		Setting: &setting,
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:   true,
+			MultiKeySize: 2,
+		},
+	}
+	if err := RecordChannelKeyUsage(channel.Id, "key-a", 1000); err != nil {
+		t.Fatalf("RecordChannelKeyUsage returned error: %v", err)
+	}
+	if err := RecordChannelKeyUsage(channel.Id, "key-b", 999); err != nil {
+		t.Fatalf("RecordChannelKeyUsage returned error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if key, _, err := channel.GetNextEnabledKey(); err != nil || key != "key-b" {
+			t.Fatalf("expected key-b under the token limit, got %s (%v)", key, err)
+		}
+	}
+}
+
+func TestRotateChannelKey(t *testing.T) {
+	setupTestDB(t, &Channel{}, &ChannelKeyStatus{})
+	resetChannelKeyState(t)
+	channel := &Channel{
+		Id:  1,
+		Key: "key-a\nkey-b",
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:   true,
+			MultiKeySize: 2,
+		},
+	}
+	if err := DB.Create(channel).Error; err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	newKey, err := RotateChannelKey(channel.Id, "key-a")
+	if err != nil || newKey != "key-b" {
+		t.Fatalf("RotateChannelKey = %q, %v; want key-b", newKey, err)
+	}
+	var status ChannelKeyStatus
+	DB.Where("channel_id = ? AND key_hash = ?", channel.Id, HashChannelKey("key-a")).First(&status)
+	if status.DisabledUntil != ChannelKeyBillingPeriodEnd(time.Now()).Unix() {
+		t.Fatalf("unexpected key-a status: %+v", status)
+	}
+
+	// 所有 key 都耗尽时返回错误
+	if _, err := RotateChannelKey(channel.Id, "key-b"); err == nil {
+		t.Fatal("expected error when every key is exhausted")
+	}
+
+	end := ChannelKeyBillingPeriodEnd(time.Date(2025, time.December, 15, 10, 0, 0, 0, time.UTC))
+	if !end.Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("billing period end = %s", end)
+	}
+}
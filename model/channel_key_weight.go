@@ -0,0 +1,86 @@
+package model
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// channelKeyWeightSeparator 权重后缀的分隔符，使用显式标记避免误截断本身以 :数字 结尾的 key
+	channelKeyWeightSeparator = "#weight="
+	// maxChannelKeyWeight 权重上限，超出范围的后缀视为 key 本身的一部分
+	maxChannelKeyWeight = 100
+)
+
+// channelKeyCounters 每个渠道加权轮询的计数器，channel.id -> *atomic.Uint64
+var channelKeyCounters sync.Map
+
+// ParseWeightedKey 解析多 key 列表中形如 key#weight=N 的一行，N 为 1 到 100 的整数；
+// 没有合法权重后缀时返回原始 key 与权重 1
+func ParseWeightedKey(raw string) (string, int) {
+	idx := strings.LastIndex(raw, channelKeyWeightSeparator)
+	if idx <= 0 {
+		return raw, 1
+	}
+	weight, err := strconv.Atoi(raw[idx+len(channelKeyWeightSeparator):])
+	if err != nil || weight < 1 || weight > maxChannelKeyWeight {
+		return raw, 1
+	}
+	return raw[:idx], weight
+}
+
+// parseKeyWeights 去掉 key 的权重后缀，所有 key 的权重都为 1 时 weights 返回 nil
+func parseKeyWeights(keys []string) (plainKeys []string, weights []int) {
+	plainKeys = make([]string, len(keys))
+	weights = make([]int, len(keys))
+	weighted := false
+	for i, raw := range keys {
+		plainKeys[i], weights[i] = ParseWeightedKey(raw)
+		if weights[i] != 1 {
+			weighted = true
+		}
+	}
+	if !weighted {
+		return plainKeys, nil
+	}
+	return plainKeys, weights
+}
+
+func getChannelKeyCounter(channelId int) *atomic.Uint64 {
+	if counter, ok := channelKeyCounters.Load(channelId); ok {
+		return counter.(*atomic.Uint64)
+	}
+	actual, _ := channelKeyCounters.LoadOrStore(channelId, &atomic.Uint64{})
+	return actual.(*atomic.Uint64)
+}
+
+// cumulativeKeyWeights 返回可用 key 的累计权重，用于按落点查找 key
+func cumulativeKeyWeights(enabledIdx []int, weights []int) []int {
+	cumulative := make([]int, len(enabledIdx))
+	total := 0
+	for i, idx := range enabledIdx {
+		total += weights[idx]
+		cumulative[i] = total
+	}
+	return cumulative
+}
+
+// nextWeightedKeyIndex 加权轮询：每个渠道一个原子计数器，计数对总权重取模后落在哪个 key 的区间即选中该 key，
+// 权重为 3 的 key 被选中的次数是权重为 1 的 key 的 3 倍
+func nextWeightedKeyIndex(channelId int, enabledIdx []int, weights []int) int {
+	cumulative := cumulativeKeyWeights(enabledIdx, weights)
+	total := cumulative[len(cumulative)-1]
+	point := int((getChannelKeyCounter(channelId).Add(1) - 1) % uint64(total))
+	return enabledIdx[sort.SearchInts(cumulative, point+1)]
+}
+
+// randomWeightedKeyIndex 按权重随机选择 key
+func randomWeightedKeyIndex(enabledIdx []int, weights []int) int {
+	cumulative := cumulativeKeyWeights(enabledIdx, weights)
+	point := rand.Intn(cumulative[len(cumulative)-1])
+	return enabledIdx[sort.SearchInts(cumulative, point+1)]
+}
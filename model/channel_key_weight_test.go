@@ -0,0 +1,66 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+)
+
+func TestParseWeightedKey(t *testing.T) {
+	cases := []struct {
+		raw    string
+		key    string
+		weight int
+	}{
+		{"sk-a#weight=3", "sk-a", 3},
+		{"sk-a", "sk-a", 1},
+		{"ak|sk#weight=10", "ak|sk", 10},
+		{"sk-a#weight=0", "sk-a#weight=0", 1},
+		{"sk-a#weight=101", "sk-a#weight=101", 1},
+		{"sk-a#weight=abc", "sk-a#weight=abc", 1},
+		{"sk-a#weight=", "sk-a#weight=", 1},
+		{"#weight=3", "#weight=3", 1},
+		// 本身以 :数字 结尾的 key 不会被截断
+		{"proj:region:42", "proj:region:42", 1},
+		{"proj:region:42#weight=2", "proj:region:42", 2},
+	}
+	for _, tc := range cases {
+		if key, weight := ParseWeightedKey(tc.raw); key != tc.key || weight != tc.weight {
+			t.Errorf("ParseWeightedKey(%q) = %q, %d, want %q, %d", tc.raw, key, weight, tc.key, tc.weight)
+		}
+	}
+}
+
+func TestGetNextEnabledKeyWeightedPolling(t *testing.T) {
+	setupTestDB(t, &ChannelKeyStatus{})
+	channel := &Channel{
+		Id:  100,
+		Key: "key-a#weight=3\nkey-b\nkey-c#weight=2",
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:         true,
+			MultiKeySize:       3,
+			MultiKeyMode:       constant.MultiKeyModePolling,
+			MultiKeyStatusList: map[int]int{},
+		},
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 60; i++ {
+		key, _, err := channel.GetNextEnabledKey()
+		if err != nil {
+			t.Fatalf("GetNextEnabledKey returned error: %v", err)
+		}
+		counts[key]++
+	}
+	if counts["key-a"] != 30 || counts["key-b"] != 10 || counts["key-c"] != 20 {
+		t.Fatalf("unexpected weighted distribution: %v", counts)
+	}
+
+	// 禁用的 key 不参与加权
+	channel.ChannelInfo.MultiKeyStatusList[0] = 2
+	for i := 0; i < 9; i++ {
+		if key, idx, err := channel.GetNextEnabledKey(); err != nil || key == "key-a" || idx == 0 {
+			t.Fatalf("disabled key should be skipped, got %s (%v)", key, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestChannelClone(t *testing.T) {
+	setupTestDB(t, &Channel{}, &Ability{})
+
+	mapping := `{"gpt-4":"gpt-4o"}`
+	priority := int64(5)
+	origin := &Channel{
+		Type:         1,
+		Key:          "sk-a\nsk-b",
+		Name:         "origin",
+		Status:       common.ChannelStatusEnabled,
+		Models:       "gpt-4",
+		Group:        "vip",
+		ModelMapping: &mapping,
+		Priority:     &priority,
+		UsedQuota:    100,
+		Balance:      3.5,
+		ProbeStatus:  ChannelProbeStatusOK,
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:         true,
+			MultiKeySize:       2,
+			MultiKeyStatusList: map[int]int{1: common.ChannelStatusAutoDisabled},
+		},
+	}
+	if err := origin.Insert(); err != nil {
+		t.Fatalf("insert origin failed: %v", err)
+	}
+
+	clone := origin.Clone()
+	*clone.ModelMapping = `{}`
+	if *origin.ModelMapping != mapping {
+		t.Fatal("clone should not share model mapping with origin")
+	}
+	clone.ChannelInfo.MultiKeyStatusList[0] = common.ChannelStatusManuallyDisabled
+	if len(origin.ChannelInfo.MultiKeyStatusList) != 1 {
+		t.Fatal("clone should not share multi key status with origin")
+	}
+
+	clone.SetClonedKey("sk-c\nsk-d\nsk-e")
+	if clone.ChannelInfo.MultiKeySize != 3 || clone.ChannelInfo.MultiKeyStatusList != nil {
+		t.Fatalf("unexpected multi key info: %+v", clone.ChannelInfo)
+	}
+	if err := clone.Insert(); err != nil {
+		t.Fatalf("insert clone failed: %v", err)
+	}
+
+	stored, err := GetChannelById(clone.Id, true)
+	if err != nil {
+		t.Fatalf("get clone failed: %v", err)
+	}
+	if stored.Id == origin.Id || stored.Status != common.ChannelStatusManuallyDisabled {
+		t.Fatalf("clone should be a new disabled channel: %+v", stored)
+	}
+	if stored.Key != "sk-c\nsk-d\nsk-e" || stored.Group != "vip" || stored.GetPriority() != 5 {
+		t.Fatalf("clone config not preserved: %+v", stored)
+	}
+	if stored.UsedQuota != 0 || stored.Balance != 0 || stored.ProbeStatus != "" {
+		t.Fatalf("clone runtime state not reset: %+v", stored)
+	}
+}
@@ -0,0 +1,30 @@
+package model
+
+// ChannelWarmupResult 渠道预热记录
+type ChannelWarmupResult struct {
+	Id        int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	ChannelId int    `json:"channel_id" gorm:"index"`
+	ModelName string `json:"model" gorm:"type:varchar(255)"`
+	LatencyMs int64  `json:"warm_up_latency_ms"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty" gorm:"type:text"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (ChannelWarmupResult) TableName() string {
+	return "channel_warmup_results"
+}
+
+func (r *ChannelWarmupResult) Insert() error {
+	return DB.Create(r).Error
+}
+
+// GetChannelWarmupHistory 获取渠道最近的预热记录
+func GetChannelWarmupHistory(channelId int, limit int) ([]*ChannelWarmupResult, error) {
+	var results []*ChannelWarmupResult
+	err := DB.Where("channel_id = ?", channelId).
+		Order("id desc").
+		Limit(limit).
+		Find(&results).Error
+	return results, err
+}
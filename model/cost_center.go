@@ -0,0 +1,25 @@
+package model
+
+// CostCenterUsage 成本中心在统计区间内的消费汇总
+type CostCenterUsage struct {
+	CostCenter       string `json:"cost_center"`
+	Quota            int64  `json:"quota"`
+	RequestCount     int64  `json:"request_count"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+}
+
+// GetCostCenterUsage 按成本中心汇总 [startTimestamp, endTimestamp] 内的消费日志，userId 为 0 时统计所有用户
+func GetCostCenterUsage(startTimestamp int64, endTimestamp int64, userId int) ([]*CostCenterUsage, error) {
+	var usages []*CostCenterUsage
+	tx := LOG_DB.Table("logs").
+		Select("cost_center, sum(quota) as quota, count(*) as request_count, "+
+			"sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens").
+		Where("type = ? AND cost_center <> ''", LogTypeConsume).
+		Where("created_at >= ? AND created_at <= ?", startTimestamp, endTimestamp)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	err := tx.Group("cost_center").Order("quota desc").Scan(&usages).Error
+	return usages, err
+}
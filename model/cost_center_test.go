@@ -0,0 +1,68 @@
+package model
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetCostCenterUsage(t *testing.T) {
+	setupTestDB(t, &User{}, &Log{}, &BillingSnapshot{})
+	originLogDB, originEnabled, originRedis := LOG_DB, common.LogConsumeEnabled, common.RedisEnabled
+	LOG_DB, common.LogConsumeEnabled, common.RedisEnabled = DB, true, false
+	t.Cleanup(func() {
+		LOG_DB, common.LogConsumeEnabled, common.RedisEnabled = originLogDB, originEnabled, originRedis
+	})
+
+	users := []*User{{Username: "finance-a", Password: "password", AffCode: "fa"}, {Username: "finance-b", Password: "password", AffCode: "fb"}}
+	for _, user := range users {
+		if err := DB.Create(user).Error; err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+	}
+	record := func(userId int, costCenter string, quota int) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+		if costCenter != "" {
+			common.SetContextKey(c, constant.ContextKeyCostCenter, costCenter)
+		}
+		RecordConsumeLog(c, userId, RecordConsumeLogParams{ModelName: "gpt-4o", Quota: quota, PromptTokens: 10, CompletionTokens: 5})
+	}
+	record(users[0].Id, "rd", 100)
+	record(users[0].Id, "rd", 50)
+	record(users[0].Id, "marketing", 30)
+	record(users[1].Id, "rd", 500)
+	record(users[1].Id, "", 1000)
+
+	var log Log
+	LOG_DB.Where("cost_center = ?", "marketing").First(&log)
+	if log.Other == "" || log.CostCenter != "marketing" {
+		t.Fatalf("cost center not recorded: %+v", log)
+	}
+
+	now := common.GetTimestamp()
+	usages, err := GetCostCenterUsage(now-60, now+60, 0)
+	if err != nil {
+		t.Fatalf("get usage failed: %v", err)
+	}
+	if len(usages) != 2 || usages[0].CostCenter != "rd" || usages[0].Quota != 650 || usages[0].RequestCount != 3 {
+		t.Fatalf("unexpected usages: %+v", usages)
+	}
+
+	usages, err = GetCostCenterUsage(now-60, now+60, users[0].Id)
+	if err != nil {
+		t.Fatalf("get user usage failed: %v", err)
+	}
+	if len(usages) != 2 || usages[0].Quota != 150 || usages[1].CostCenter != "marketing" {
+		t.Fatalf("unexpected user usages: %+v", usages)
+	}
+
+	usages, _ = GetCostCenterUsage(now+120, now+180, 0)
+	if len(usages) != 0 {
+		t.Fatalf("expected no usage outside range, got %+v", usages)
+	}
+}
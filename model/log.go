@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -37,6 +39,7 @@ type Log struct {
 	Group            string `json:"group" gorm:"index"`
 	Ip               string `json:"ip" gorm:"index;default:''"`
 	Other            string `json:"other"`
+	CostCenter       string `json:"cost_center" gorm:"type:varchar(64);index;default:''"`
 }
 
 // don't use iota, avoid change log type value
@@ -154,6 +157,7 @@ type RecordConsumeLogParams struct {
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	metrics.AddQuotaConsumed(params.Group, params.ModelName, params.Quota)
 	if !common.LogConsumeEnabled {
 		return
 	}
@@ -171,6 +175,16 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 			username = user.Username
 		}
 	}
+	var costCenter string
+	if c != nil {
+		costCenter = common.GetContextKeyString(c, constant.ContextKeyCostCenter)
+	}
+	if costCenter != "" {
+		if params.Other == nil {
+			params.Other = map[string]interface{}{}
+		}
+		params.Other["cost_center"] = costCenter
+	}
 	otherStr := common.MapToJsonStr(params.Other)
 	// 判断是否需要记录 IP
 	needRecordIp := false
@@ -201,11 +215,14 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 			}
 			return ""
 		}(),
-		Other: otherStr,
+		Other:      otherStr,
+		CostCenter: costCenter,
 	}
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		common.SysLog("failed to record log: " + err.Error())
+	} else if err = LOG_DB.Create(newBillingSnapshot(log, params)).Error; err != nil {
+		common.SysLog("failed to record billing snapshot: " + err.Error())
 	}
 	if common.DataExportEnabled {
 		gopool.Go(func() {
@@ -411,6 +428,9 @@ func DeleteOldLog(ctx context.Context, targetTimestamp int64, limit int) (int64,
 		if nil != result.Error {
 			return total, result.Error
 		}
+		if err := LOG_DB.Where("captured_at < ?", targetTimestamp).Limit(limit).Delete(&BillingSnapshot{}).Error; err != nil {
+			return total, err
+		}
 
 		total += result.RowsAffected
 
@@ -257,6 +257,7 @@ func migrateDB() error {
 		&Redemption{},
 		&Ability{},
 		&Log{},
+		&BillingSnapshot{},
 		&Midjourney{},
 		&TopUp{},
 		&QuotaData{},
@@ -268,11 +269,26 @@ func migrateDB() error {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&Checkin{},
+		&ChannelWarmupResult{},
+		&QuotaReservation{},
+		&ChannelKeyStatus{},
+		&ChannelHealthEvent{},
+		&ChannelGroup{},
+		&QuotaTransfer{},
+		&ModelSpendingCap{},
+		&ModelAlias{},
+		&TaskDependency{},
+		&ShadowResult{},
+		&UserModel{},
+		&Payment{},
+		&BillingDiscrepancy{},
+		&BillingReconciliationRun{},
+		&VideoUpload{},
 	)
 	if err != nil {
 		return err
 	}
-	return nil
+	return migrateTaskTagsIndex()
 }
 
 func migrateDBFast() error {
@@ -291,6 +307,7 @@ func migrateDBFast() error {
 		{&Redemption{}, "Redemption"},
 		{&Ability{}, "Ability"},
 		{&Log{}, "Log"},
+		{&BillingSnapshot{}, "BillingSnapshot"},
 		{&Midjourney{}, "Midjourney"},
 		{&TopUp{}, "TopUp"},
 		{&QuotaData{}, "QuotaData"},
@@ -302,6 +319,21 @@ func migrateDBFast() error {
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
 		{&Checkin{}, "Checkin"},
+		{&ChannelWarmupResult{}, "ChannelWarmupResult"},
+		{&QuotaReservation{}, "QuotaReservation"},
+		{&ChannelKeyStatus{}, "ChannelKeyStatus"},
+		{&ChannelHealthEvent{}, "ChannelHealthEvent"},
+		{&ChannelGroup{}, "ChannelGroup"},
+		{&QuotaTransfer{}, "QuotaTransfer"},
+		{&ModelSpendingCap{}, "ModelSpendingCap"},
+		{&ModelAlias{}, "ModelAlias"},
+		{&TaskDependency{}, "TaskDependency"},
+		{&ShadowResult{}, "ShadowResult"},
+		{&UserModel{}, "UserModel"},
+		{&Payment{}, "Payment"},
+		{&BillingDiscrepancy{}, "BillingDiscrepancy"},
+		{&BillingReconciliationRun{}, "BillingReconciliationRun"},
+		{&VideoUpload{}, "VideoUpload"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -326,13 +358,16 @@ func migrateDBFast() error {
 			return err
 		}
 	}
+	if err := migrateTaskTagsIndex(); err != nil {
+		return err
+	}
 	common.SysLog("database migrated")
 	return nil
 }
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &BillingSnapshot{}); err != nil {
 		return err
 	}
 	return nil
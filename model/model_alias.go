@@ -0,0 +1,145 @@
+package model
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+var ErrModelAliasPinned = errors.New("model alias is pinned")
+
+// modelAliasCache 别名内存缓存，nil 表示需要从数据库重新加载。
+// 缓存超过 SyncFrequency 后重新加载，使其他节点上的修改也能生效
+var (
+	modelAliasCache       []*ModelAlias
+	modelAliasCacheLoaded time.Time
+	modelAliasCacheLock   sync.RWMutex
+)
+
+// ModelAlias 全局模型别名：请求中的 Alias 在渠道 model_mapping 之后被替换为 TargetModel。
+// ChannelType 为 0 表示适用于所有渠道类型，否则仅对该类型的渠道生效且优先于通用别名。
+// PinnedUntil 之前别名被锁定，目标模型不可修改或删除，用于保证结果可复现
+type ModelAlias struct {
+	Id          int    `json:"id"`
+	Alias       string `json:"alias" gorm:"type:varchar(255);not null;uniqueIndex:idx_model_alias_channel_type"`
+	TargetModel string `json:"target_model" gorm:"type:varchar(255);not null"`
+	ChannelType int    `json:"channel_type" gorm:"default:0;uniqueIndex:idx_model_alias_channel_type"`
+	PinnedUntil int64  `json:"pinned_until" gorm:"bigint;default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64  `json:"updated_time" gorm:"bigint"`
+}
+
+func (a *ModelAlias) IsPinned() bool {
+	return a.PinnedUntil > common.GetTimestamp()
+}
+
+// Insert 新建别名
+func (a *ModelAlias) Insert() error {
+	now := common.GetTimestamp()
+	a.CreatedTime = now
+	a.UpdatedTime = now
+	defer InvalidateModelAliasCache()
+	return DB.Create(a).Error
+}
+
+// Update 更新别名，锁定期内不允许修改目标模型
+func (a *ModelAlias) Update() error {
+	origin, err := GetModelAliasById(a.Id)
+	if err != nil {
+		return err
+	}
+	if origin.IsPinned() && (origin.TargetModel != a.TargetModel || origin.Alias != a.Alias || origin.ChannelType != a.ChannelType) {
+		return ErrModelAliasPinned
+	}
+	a.CreatedTime = origin.CreatedTime
+	a.UpdatedTime = common.GetTimestamp()
+	defer InvalidateModelAliasCache()
+	return DB.Save(a).Error
+}
+
+// IsModelAliasDuplicated 检查同一渠道类型下别名是否重复（排除自身 ID）
+func IsModelAliasDuplicated(id int, alias string, channelType int) (bool, error) {
+	var cnt int64
+	err := DB.Model(&ModelAlias{}).Where("alias = ? AND channel_type = ? AND id <> ?", alias, channelType, id).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func GetModelAliasById(id int) (*ModelAlias, error) {
+	var alias ModelAlias
+	if err := DB.First(&alias, id).Error; err != nil {
+		return nil, err
+	}
+	return &alias, nil
+}
+
+// DeleteModelAliasByID 删除别名，锁定期内不允许删除
+func DeleteModelAliasByID(id int) error {
+	alias, err := GetModelAliasById(id)
+	if err != nil {
+		return err
+	}
+	if alias.IsPinned() {
+		return ErrModelAliasPinned
+	}
+	defer InvalidateModelAliasCache()
+	return DB.Delete(&ModelAlias{}, id).Error
+}
+
+func GetAllModelAliases() ([]*ModelAlias, error) {
+	var aliases []*ModelAlias
+	if err := DB.Order("alias, channel_type").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// InvalidateModelAliasCache 清空别名缓存，下次读取时从数据库重新加载
+func InvalidateModelAliasCache() {
+	modelAliasCacheLock.Lock()
+	modelAliasCache = nil
+	modelAliasCacheLock.Unlock()
+}
+
+// GetCachedModelAliases 返回缓存的全部别名，缓存为空时从数据库加载。返回的切片不可修改
+func GetCachedModelAliases() ([]*ModelAlias, error) {
+	modelAliasCacheLock.RLock()
+	aliases, loaded := modelAliasCache, modelAliasCacheLoaded
+	modelAliasCacheLock.RUnlock()
+	if aliases != nil && time.Since(loaded) < time.Duration(common.SyncFrequency)*time.Second {
+		return aliases, nil
+	}
+	aliases, err := GetAllModelAliases()
+	if err != nil {
+		return nil, err
+	}
+	if aliases == nil {
+		aliases = []*ModelAlias{}
+	}
+	modelAliasCacheLock.Lock()
+	modelAliasCache, modelAliasCacheLoaded = aliases, time.Now()
+	modelAliasCacheLock.Unlock()
+	return aliases, nil
+}
+
+// ResolveModelAlias 返回别名在指定渠道类型下的目标模型，渠道类型专属别名优先于通用别名
+func ResolveModelAlias(alias string, channelType int) (string, bool, error) {
+	aliases, err := GetCachedModelAliases()
+	if err != nil {
+		return "", false, err
+	}
+	target := ""
+	for _, a := range aliases {
+		if a.Alias != alias || a.TargetModel == "" {
+			continue
+		}
+		if a.ChannelType == channelType {
+			return a.TargetModel, true, nil
+		}
+		if a.ChannelType == 0 {
+			target = a.TargetModel
+		}
+	}
+	return target, target != "", nil
+}
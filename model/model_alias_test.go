@@ -0,0 +1,107 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestResolveModelAlias(t *testing.T) {
+	setupTestDB(t, &ModelAlias{})
+	for _, a := range []*ModelAlias{
+		{Alias: "seedance-pro", TargetModel: "doubao-seedance-1-0-pro-250528"},
+		{Alias: "seedance-pro", TargetModel: "doubao-seedance-1-0-pro-volc", ChannelType: 101},
+	} {
+		if err := a.Insert(); err != nil {
+			t.Fatalf("insert alias failed: %v", err)
+		}
+	}
+	cases := []struct {
+		alias       string
+		channelType int
+		want        string
+		found       bool
+	}{
+		{"seedance-pro", 54, "doubao-seedance-1-0-pro-250528", true},
+		{"seedance-pro", 101, "doubao-seedance-1-0-pro-volc", true},
+		{"unknown", 54, "", false},
+	}
+	for _, tc := range cases {
+		target, found, err := ResolveModelAlias(tc.alias, tc.channelType)
+		if err != nil || target != tc.want || found != tc.found {
+			t.Errorf("ResolveModelAlias(%s, %d) = %q, %v, %v", tc.alias, tc.channelType, target, found, err)
+		}
+	}
+}
+
+func TestPinnedModelAlias(t *testing.T) {
+	setupTestDB(t, &ModelAlias{})
+	alias := &ModelAlias{Alias: "kling-latest", TargetModel: "kling-v2-1", PinnedUntil: common.GetTimestamp() + 3600}
+	if err := alias.Insert(); err != nil {
+		t.Fatalf("insert alias failed: %v", err)
+	}
+
+	changed := *alias
+	changed.TargetModel = "kling-v2-5"
+	if err := changed.Update(); !errors.Is(err, ErrModelAliasPinned) {
+		t.Errorf("update pinned target: want ErrModelAliasPinned, got %v", err)
+	}
+	if err := DeleteModelAliasByID(alias.Id); !errors.Is(err, ErrModelAliasPinned) {
+		t.Errorf("delete pinned alias: want ErrModelAliasPinned, got %v", err)
+	}
+
+	// 锁定期内仍可修改锁定时间，解除锁定后可以修改目标模型
+	unpinned := *alias
+	unpinned.PinnedUntil = 0
+	if err := unpinned.Update(); err != nil {
+		t.Fatalf("unpin alias failed: %v", err)
+	}
+	changed.PinnedUntil = 0
+	if err := changed.Update(); err != nil {
+		t.Fatalf("update unpinned alias failed: %v", err)
+	}
+	target, _, _ := ResolveModelAlias("kling-latest", 0)
+	if target != "kling-v2-5" {
+		t.Errorf("target after unpin = %s, want kling-v2-5", target)
+	}
+	if err := DeleteModelAliasByID(alias.Id); err != nil {
+		t.Errorf("delete unpinned alias failed: %v", err)
+	}
+}
+
+func TestModelAliasCache(t *testing.T) {
+	setupTestDB(t, &ModelAlias{})
+	originFrequency := common.SyncFrequency
+	common.SyncFrequency = 60
+	t.Cleanup(func() { common.SyncFrequency = originFrequency })
+
+	alias := &ModelAlias{Alias: "veo-latest", TargetModel: "veo-3.0"}
+	if err := alias.Insert(); err != nil {
+		t.Fatalf("insert alias failed: %v", err)
+	}
+	if target, _, _ := ResolveModelAlias("veo-latest", 0); target != "veo-3.0" {
+		t.Fatalf("target = %s, want veo-3.0", target)
+	}
+
+	// 绕过 CRUD 直接修改数据库，缓存有效期内仍返回旧值
+	DB.Model(&ModelAlias{}).Where("id = ?", alias.Id).Update("target_model", "veo-3.1")
+	if target, _, _ := ResolveModelAlias("veo-latest", 0); target != "veo-3.0" {
+		t.Errorf("cached target = %s, want veo-3.0", target)
+	}
+
+	// 通过 Update 修改时清空缓存
+	alias.TargetModel = "veo-3.1-fast"
+	if err := alias.Update(); err != nil {
+		t.Fatalf("update alias failed: %v", err)
+	}
+	if target, _, _ := ResolveModelAlias("veo-latest", 0); target != "veo-3.1-fast" {
+		t.Errorf("target after update = %s, want veo-3.1-fast", target)
+	}
+	if err := DeleteModelAliasByID(alias.Id); err != nil {
+		t.Fatalf("delete alias failed: %v", err)
+	}
+	if _, found, _ := ResolveModelAlias("veo-latest", 0); found {
+		t.Error("alias still resolved after delete")
+	}
+}
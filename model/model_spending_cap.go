@@ -0,0 +1,166 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ModelSpendingCapPeriodDay   = "day"
+	ModelSpendingCapPeriodWeek  = "week"
+	ModelSpendingCapPeriodMonth = "month"
+)
+
+var ErrModelSpendingCapExceeded = errors.New("model spending cap exceeded")
+
+// ModelSpendingCap 用户在某个模型上每个周期（日/周/月）可消耗的额度上限，
+// UsedQuota 为当前周期内已消耗额度，周期开始时清零
+type ModelSpendingCap struct {
+	Id          int    `json:"id"`
+	UserId      int    `json:"user_id" gorm:"uniqueIndex:idx_spending_cap_user_model_period"`
+	ModelName   string `json:"model_name" gorm:"type:varchar(255);uniqueIndex:idx_spending_cap_user_model_period"`
+	Period      string `json:"period" gorm:"type:varchar(16);uniqueIndex:idx_spending_cap_user_model_period"`
+	CapQuota    int    `json:"cap_quota"`
+	UsedQuota   int    `json:"used_quota"`
+	PeriodStart int64  `json:"period_start" gorm:"bigint"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func IsValidModelSpendingCapPeriod(period string) bool {
+	switch period {
+	case ModelSpendingCapPeriodDay, ModelSpendingCapPeriodWeek, ModelSpendingCapPeriodMonth:
+		return true
+	}
+	return false
+}
+
+// ModelSpendingCapPeriodStart 返回 t 所在周期的起始时间戳，周从周一开始
+func ModelSpendingCapPeriodStart(period string, t time.Time) int64 {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch period {
+	case ModelSpendingCapPeriodWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset).Unix()
+	case ModelSpendingCapPeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).Unix()
+	default:
+		return day.Unix()
+	}
+}
+
+// SetModelSpendingCap 创建或更新用户的模型消费上限，capQuota 不大于 0 时删除该上限
+func SetModelSpendingCap(userId int, modelName, period string, capQuota int) (*ModelSpendingCap, error) {
+	if !IsValidModelSpendingCapPeriod(period) {
+		return nil, fmt.Errorf("invalid period: %s", period)
+	}
+	if capQuota <= 0 {
+		err := DB.Where("user_id = ? AND model_name = ? AND period = ?", userId, modelName, period).
+			Delete(&ModelSpendingCap{}).Error
+		return nil, err
+	}
+	now := time.Now()
+	spendingCap := &ModelSpendingCap{
+		UserId:      userId,
+		ModelName:   modelName,
+		Period:      period,
+		CapQuota:    capQuota,
+		PeriodStart: ModelSpendingCapPeriodStart(period, now),
+		CreatedAt:   now.Unix(),
+		UpdatedAt:   now.Unix(),
+	}
+	err := DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "model_name"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cap_quota", "updated_at"}),
+	}).Create(spendingCap).Error
+	if err != nil {
+		return nil, err
+	}
+	err = DB.Where("user_id = ? AND model_name = ? AND period = ?", userId, modelName, period).First(spendingCap).Error
+	return spendingCap, err
+}
+
+func GetModelSpendingCaps(userId int) ([]*ModelSpendingCap, error) {
+	var caps []*ModelSpendingCap
+	err := DB.Where("user_id = ?", userId).Order("model_name asc, period asc").Find(&caps).Error
+	return caps, err
+}
+
+// CheckModelSpendingCap 以条件更新原子地检查并累加用户在该模型上的周期消耗，
+// 任一周期的上限将被超出时回滚全部修改并返回 ErrModelSpendingCapExceeded
+func CheckModelSpendingCap(userId int, modelName string, required int) error {
+	if required <= 0 {
+		return nil
+	}
+	now := time.Now()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var caps []ModelSpendingCap
+		if err := tx.Where("user_id = ? AND model_name = ?", userId, modelName).Find(&caps).Error; err != nil {
+			return err
+		}
+		for _, spendingCap := range caps {
+			// 定时任务尚未清零时按新周期处理
+			if start := ModelSpendingCapPeriodStart(spendingCap.Period, now); spendingCap.PeriodStart < start {
+				if err := tx.Model(&ModelSpendingCap{}).Where("id = ? AND period_start < ?", spendingCap.Id, start).
+					Updates(map[string]interface{}{
+						"used_quota":   0,
+						"period_start": start,
+						"updated_at":   now.Unix(),
+					}).Error; err != nil {
+					return err
+				}
+			}
+			result := tx.Model(&ModelSpendingCap{}).Where("id = ? AND used_quota + ? <= cap_quota", spendingCap.Id, required).
+				Updates(map[string]interface{}{
+					"used_quota": gorm.Expr("used_quota + ?", required),
+					"updated_at": now.Unix(),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: %s %s cap %d, required %d", ErrModelSpendingCapExceeded,
+					modelName, spendingCap.Period, spendingCap.CapQuota, required)
+			}
+		}
+		return nil
+	})
+}
+
+// ReleaseModelSpendingCap 退回 CheckModelSpendingCap 累加的消耗，用于请求失败或任务退款时
+func ReleaseModelSpendingCap(userId int, modelName string, amount int) error {
+	if amount <= 0 {
+		return nil
+	}
+	return DB.Model(&ModelSpendingCap{}).
+		Where("user_id = ? AND model_name = ? AND used_quota >= ?", userId, modelName, amount).
+		Updates(map[string]interface{}{
+			"used_quota": gorm.Expr("used_quota - ?", amount),
+			"updated_at": common.GetTimestamp(),
+		}).Error
+}
+
+// ResetExpiredModelSpendingCaps 将已进入新周期的上限的已用额度清零
+func ResetExpiredModelSpendingCaps(now time.Time) (int64, error) {
+	var total int64
+	for _, period := range []string{ModelSpendingCapPeriodDay, ModelSpendingCapPeriodWeek, ModelSpendingCapPeriodMonth} {
+		start := ModelSpendingCapPeriodStart(period, now)
+		result := DB.Model(&ModelSpendingCap{}).Where("period = ? AND period_start < ?", period, start).
+			Updates(map[string]interface{}{
+				"used_quota":   0,
+				"period_start": start,
+				"updated_at":   now.Unix(),
+			})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+	}
+	return total, nil
+}
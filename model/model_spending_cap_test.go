@@ -0,0 +1,74 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckModelSpendingCap(t *testing.T) {
+	setupTestDB(t, &ModelSpendingCap{})
+	if err := CheckModelSpendingCap(1, "veo-4k", 500); err != nil {
+		t.Fatalf("check without caps failed: %v", err)
+	}
+	if _, err := SetModelSpendingCap(1, "veo-4k", ModelSpendingCapPeriodDay, 100); err != nil {
+		t.Fatalf("set daily cap failed: %v", err)
+	}
+	if _, err := SetModelSpendingCap(1, "veo-4k", ModelSpendingCapPeriodMonth, 150); err != nil {
+		t.Fatalf("set monthly cap failed: %v", err)
+	}
+	used := func(period string) int {
+		var spendingCap ModelSpendingCap
+		DB.Where("user_id = ? AND model_name = ? AND period = ?", 1, "veo-4k", period).First(&spendingCap)
+		return spendingCap.UsedQuota
+	}
+
+	if err := CheckModelSpendingCap(1, "veo-4k", 60); err != nil {
+		t.Fatalf("check within caps failed: %v", err)
+	}
+	if err := CheckModelSpendingCap(1, "veo-4k", 60); !errors.Is(err, ErrModelSpendingCapExceeded) {
+		t.Fatalf("expected daily cap exceeded, got %v", err)
+	}
+	if used(ModelSpendingCapPeriodDay) != 60 || used(ModelSpendingCapPeriodMonth) != 60 {
+		t.Errorf("rejected check must not change usage: day %d, month %d", used(ModelSpendingCapPeriodDay), used(ModelSpendingCapPeriodMonth))
+	}
+	if err := ReleaseModelSpendingCap(1, "veo-4k", 60); err != nil || used(ModelSpendingCapPeriodDay) != 0 {
+		t.Errorf("release failed: used %d, err %v", used(ModelSpendingCapPeriodDay), err)
+	}
+
+	// 已进入新的一天：日上限清零，月上限保留
+	if err := CheckModelSpendingCap(1, "veo-4k", 90); err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	DB.Model(&ModelSpendingCap{}).Where("period = ?", ModelSpendingCapPeriodDay).
+		Update("period_start", ModelSpendingCapPeriodStart(ModelSpendingCapPeriodDay, time.Now().AddDate(0, 0, -1)))
+	count, err := ResetExpiredModelSpendingCaps(time.Now())
+	if err != nil || count != 1 {
+		t.Fatalf("reset count = %d, err %v", count, err)
+	}
+	if used(ModelSpendingCapPeriodDay) != 0 || used(ModelSpendingCapPeriodMonth) != 90 {
+		t.Errorf("after reset: day %d, month %d", used(ModelSpendingCapPeriodDay), used(ModelSpendingCapPeriodMonth))
+	}
+
+	// 删除上限后不再限制
+	if spendingCap, err := SetModelSpendingCap(1, "veo-4k", ModelSpendingCapPeriodMonth, 0); err != nil || spendingCap != nil {
+		t.Fatalf("remove cap failed: %v", err)
+	}
+	if err := CheckModelSpendingCap(1, "veo-4k", 90); err != nil {
+		t.Errorf("check after removing monthly cap failed: %v", err)
+	}
+}
+
+func TestModelSpendingCapPeriodStart(t *testing.T) {
+	now := time.Date(2026, 10, 15, 13, 30, 0, 0, time.Local) // 周四
+	cases := map[string]time.Time{
+		ModelSpendingCapPeriodDay:   time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local),
+		ModelSpendingCapPeriodWeek:  time.Date(2026, 10, 12, 0, 0, 0, 0, time.Local),
+		ModelSpendingCapPeriodMonth: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local),
+	}
+	for period, want := range cases {
+		if got := ModelSpendingCapPeriodStart(period, now); got != want.Unix() {
+			t.Errorf("%s start = %s, want %s", period, time.Unix(got, 0), want)
+		}
+	}
+}
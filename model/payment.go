@@ -0,0 +1,68 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+
+	"gorm.io/gorm"
+)
+
+// Payment Stripe 额度套餐的支付记录，stripe_session_id 唯一，同一个 Checkout Session 只入账一次
+type Payment struct {
+	Id              int    `json:"id"`
+	StripeSessionId string `json:"stripe_session_id" gorm:"type:varchar(255);uniqueIndex"`
+	UserId          int    `json:"user_id" gorm:"index"`
+	PackageId       string `json:"package_id" gorm:"type:varchar(64)"`
+	Amount          int64  `json:"amount"` // 实付金额，单位为货币最小单位（如美分）
+	Currency        string `json:"currency" gorm:"type:varchar(16)"`
+	QuotaGranted    int    `json:"quota_granted"`
+	CreatedAt       int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+var ErrPaymentAlreadyProcessed = errors.New("payment already processed")
+
+// CreditStripePayment 在同一事务中记录支付、增加用户额度并保存 Stripe 客户 ID（customerId 为空时不修改），
+// 同一个 session 重复回调时返回 ErrPaymentAlreadyProcessed
+func CreditStripePayment(payment *Payment, customerId string) error {
+	if payment.StripeSessionId == "" {
+		return errors.New("未提供支付会话")
+	}
+	if payment.QuotaGranted <= 0 {
+		return errors.New("充值额度无效")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Payment{}).Where("stripe_session_id = ?", payment.StripeSessionId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrPaymentAlreadyProcessed
+		}
+		payment.CreatedAt = common.GetTimestamp()
+		// 并发回调时由唯一索引保证只有一条记录写入成功
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		updates := map[string]any{"quota": gorm.Expr("quota + ?", payment.QuotaGranted)}
+		if customerId != "" {
+			updates["stripe_customer"] = customerId
+		}
+		return tx.Model(&User{}).Where("id = ?", payment.UserId).Updates(updates).Error
+	})
+	if err != nil {
+		return err
+	}
+	// 事务成功后更新缓存
+	if common.RedisEnabled {
+		go func() {
+			_ = cacheIncrUserQuota(payment.UserId, int64(payment.QuotaGranted))
+		}()
+	}
+	RecordLog(payment.UserId, LogTypeTopup, fmt.Sprintf("Stripe 套餐充值成功，充值额度: %s，支付金额：%.2f %s",
+		logger.FormatQuota(payment.QuotaGranted), float64(payment.Amount)/100, strings.ToUpper(payment.Currency)))
+	return nil
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestCreditStripePaymentIsIdempotent(t *testing.T) {
+	setupTestDB(t, &User{}, &Log{}, &Payment{})
+	originLogDB, originRedis := LOG_DB, common.RedisEnabled
+	LOG_DB, common.RedisEnabled = DB, false
+	t.Cleanup(func() {
+		LOG_DB, common.RedisEnabled = originLogDB, originRedis
+	})
+
+	user := &User{Username: "payment", Password: "password", Quota: 100}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	newPayment := func() *Payment {
+		return &Payment{
+			StripeSessionId: "cs_test_1",
+			UserId:          user.Id,
+			PackageId:       "basic",
+			Amount:          199,
+			Currency:        "usd",
+			QuotaGranted:    500,
+		}
+	}
+
+	if err := CreditStripePayment(newPayment(), "cus_test_1"); err != nil {
+		t.Fatalf("CreditStripePayment returned error: %v", err)
+	}
+	if err := CreditStripePayment(newPayment(), "cus_test_1"); !errors.Is(err, ErrPaymentAlreadyProcessed) {
+		t.Fatalf("err = %v, want ErrPaymentAlreadyProcessed", err)
+	}
+
+	quota, err := GetUserQuota(user.Id, true)
+	if err != nil {
+		t.Fatalf("get user quota failed: %v", err)
+	}
+	if quota != 600 {
+		t.Fatalf("user quota = %d, want 600", quota)
+	}
+	var stored User
+	if err := DB.First(&stored, user.Id).Error; err != nil || stored.StripeCustomer != "cus_test_1" {
+		t.Fatalf("stripe customer = %q, %v, want cus_test_1", stored.StripeCustomer, err)
+	}
+	var count int64
+	DB.Model(&Payment{}).Where("user_id = ?", user.Id).Count(&count)
+	if count != 1 {
+		t.Fatalf("payment count = %d, want 1", count)
+	}
+}
@@ -0,0 +1,270 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	QuotaReservationStatusPending   = "pending"
+	QuotaReservationStatusConfirmed = "confirmed"
+	QuotaReservationStatusReleased  = "released"
+)
+
+var (
+	ErrQuotaNotEnough        = errors.New("quota is not enough")
+	ErrReservationNotPending = errors.New("reservation is not pending")
+)
+
+// QuotaReservation 额度预留记录。预留时即从用户或令牌额度中扣除，
+// 确认后保持扣除，释放时退回。TokenId 为 0 表示用户额度预留。
+type QuotaReservation struct {
+	Id        int64  `json:"id" gorm:"primaryKey"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	TokenId   int    `json:"token_id" gorm:"index"`
+	Amount    int    `json:"amount"`
+	Status    string `json:"status" gorm:"type:varchar(20);index"`
+	TaskId    string `json:"task_id" gorm:"type:varchar(191);index"` // 预留所属任务的 task_id，任务写入后记录
+	CreatedAt int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (QuotaReservation) TableName() string {
+	return "reservations"
+}
+
+// forUpdate 为查询加行锁，SQLite 不支持 FOR UPDATE，写事务本身是串行的
+func forUpdate(tx *gorm.DB) *gorm.DB {
+	if common.UsingSQLite {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// ReserveUserQuota 在行锁保护下检查并扣除用户额度，返回预留记录 ID。
+// amount 为 0 时不创建预留，返回的 ID 为 0。
+func ReserveUserQuota(userId, amount int) (reservationId int64, err error) {
+	if amount < 0 {
+		return 0, errors.New("quota 不能为负数！")
+	}
+	if amount == 0 {
+		return 0, nil
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := forUpdate(tx).Select("id", "quota").Where("id = ?", userId).First(&user).Error; err != nil {
+			return err
+		}
+		if user.Quota < amount {
+			return ErrQuotaNotEnough
+		}
+		if err := tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota - ?", amount)).Error; err != nil {
+			return err
+		}
+		reservation := newQuotaReservation(userId, 0, amount)
+		if err := tx.Create(reservation).Error; err != nil {
+			return err
+		}
+		reservationId = reservation.Id
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	gopool.Go(func() {
+		if err := cacheDecrUserQuota(userId, int64(amount)); err != nil {
+			common.SysLog("failed to decrease user quota cache: " + err.Error())
+		}
+	})
+	return reservationId, nil
+}
+
+// ReserveTokenQuota 在行锁保护下检查并扣除令牌额度，无限额度令牌只记录用量
+func ReserveTokenQuota(tokenId, amount int) (reservationId int64, err error) {
+	if amount < 0 {
+		return 0, errors.New("quota 不能为负数！")
+	}
+	if amount == 0 {
+		return 0, nil
+	}
+	var token Token
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Where("id = ?", tokenId).First(&token).Error; err != nil {
+			return err
+		}
+		if !token.UnlimitedQuota && token.RemainQuota < amount {
+			return ErrQuotaNotEnough
+		}
+		if err := tx.Model(&Token{}).Where("id = ?", tokenId).Updates(map[string]interface{}{
+			"remain_quota":  gorm.Expr("remain_quota - ?", amount),
+			"used_quota":    gorm.Expr("used_quota + ?", amount),
+			"accessed_time": common.GetTimestamp(),
+		}).Error; err != nil {
+			return err
+		}
+		reservation := newQuotaReservation(token.UserId, tokenId, amount)
+		if err := tx.Create(reservation).Error; err != nil {
+			return err
+		}
+		reservationId = reservation.Id
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if common.RedisEnabled {
+		gopool.Go(func() {
+			if err := cacheDecrTokenQuota(token.Key, int64(amount)); err != nil {
+				common.SysLog("failed to decrease token quota cache: " + err.Error())
+			}
+		})
+	}
+	return reservationId, nil
+}
+
+// ConfirmReservation 确认预留，额度保持扣除
+func ConfirmReservation(reservationId int64) error {
+	if reservationId == 0 {
+		return nil
+	}
+	result := DB.Model(&QuotaReservation{}).
+		Where("id = ? AND status = ?", reservationId, QuotaReservationStatusPending).
+		Updates(map[string]interface{}{
+			"status":     QuotaReservationStatusConfirmed,
+			"updated_at": common.GetTimestamp(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %d", ErrReservationNotPending, reservationId)
+	}
+	return nil
+}
+
+// ReleaseReservation 释放预留并退回额度
+func ReleaseReservation(reservationId int64) error {
+	_, err := releaseReservation(reservationId, false)
+	return err
+}
+
+// releaseReservation 释放预留并退回额度，skipTaskReservation 为 true 时跳过已关联任务的预留，
+// 返回是否释放
+func releaseReservation(reservationId int64, skipTaskReservation bool) (bool, error) {
+	if reservationId == 0 {
+		return false, nil
+	}
+	released := false
+	var reservation QuotaReservation
+	var tokenKey string
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Where("id = ?", reservationId).First(&reservation).Error; err != nil {
+			return err
+		}
+		if reservation.Status != QuotaReservationStatusPending {
+			return fmt.Errorf("%w: %d", ErrReservationNotPending, reservationId)
+		}
+		if skipTaskReservation && reservation.TaskId != "" {
+			return nil
+		}
+		released = true
+		if reservation.TokenId != 0 {
+			if err := tx.Model(&Token{}).Where("id = ?", reservation.TokenId).Updates(map[string]interface{}{
+				"remain_quota":  gorm.Expr("remain_quota + ?", reservation.Amount),
+				"used_quota":    gorm.Expr("used_quota - ?", reservation.Amount),
+				"accessed_time": common.GetTimestamp(),
+			}).Error; err != nil {
+				return err
+			}
+			var token Token
+			if err := tx.Where("id = ?", reservation.TokenId).First(&token).Error; err != nil {
+				return err
+			}
+			tokenKey = token.Key
+		} else {
+			if err := tx.Model(&User{}).Where("id = ?", reservation.UserId).Update("quota", gorm.Expr("quota + ?", reservation.Amount)).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&reservation).Updates(map[string]interface{}{
+			"status":     QuotaReservationStatusReleased,
+			"updated_at": common.GetTimestamp(),
+		}).Error
+	})
+	if err != nil || !released {
+		return false, err
+	}
+	gopool.Go(func() {
+		var err error
+		if reservation.TokenId != 0 {
+			if common.RedisEnabled {
+				err = cacheIncrTokenQuota(tokenKey, int64(reservation.Amount))
+			}
+		} else {
+			err = cacheIncrUserQuota(reservation.UserId, int64(reservation.Amount))
+		}
+		if err != nil {
+			common.SysLog("failed to restore quota cache: " + err.Error())
+		}
+	})
+	return true, nil
+}
+
+// ReleaseStaleReservations 释放创建时间早于 before 仍未确认的预留并退回额度，
+// 用于回收提交过程中进程崩溃或请求中断后遗留的预留，返回释放的数量。
+// 已关联任务的预留说明任务已提交成功，只是确认失败，不会被释放
+func ReleaseStaleReservations(before int64, limit int) (int, error) {
+	var ids []int64
+	if err := DB.Model(&QuotaReservation{}).
+		Where("status = ? AND created_at < ? AND (task_id IS NULL OR task_id = '')", QuotaReservationStatusPending, before).
+		Order("id").Limit(limit).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	released := 0
+	for _, id := range ids {
+		// 查询后可能已被确认、释放或关联任务，releaseReservation 在行锁内会再次检查
+		ok, err := releaseReservation(id, true)
+		if err != nil {
+			if !errors.Is(err, ErrReservationNotPending) {
+				common.SysError(fmt.Sprintf("failed to release stale reservation %d: %s", id, err.Error()))
+			}
+			continue
+		}
+		if ok {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// AttachReservationsToTask 在任务写入的事务中将额度预留关联到任务
+func AttachReservationsToTask(tx *gorm.DB, taskId string, reservationIds ...int64) error {
+	ids := make([]int64, 0, len(reservationIds))
+	for _, id := range reservationIds {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Model(&QuotaReservation{}).Where("id IN ?", ids).Update("task_id", taskId).Error
+}
+
+func newQuotaReservation(userId, tokenId, amount int) *QuotaReservation {
+	now := common.GetTimestamp()
+	return &QuotaReservation{
+		UserId:    userId,
+		TokenId:   tokenId,
+		Amount:    amount,
+		Status:    QuotaReservationStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
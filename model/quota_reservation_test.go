@@ -0,0 +1,184 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	// 额度变更后的缓存更新在 goroutine 中执行，可能晚于测试结束，因此不恢复 RedisEnabled
+	common.RedisEnabled = false
+	originDB, originSQLite := DB, common.UsingSQLite
+	DB, common.UsingSQLite = db, true
+	t.Cleanup(func() {
+		DB, common.UsingSQLite = originDB, originSQLite
+	})
+}
+
+func TestReserveUserQuota(t *testing.T) {
+	setupTestDB(t, &User{}, &Token{}, &QuotaReservation{})
+	user := &User{Username: "reserve", Password: "password", Quota: 100}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	quota := func() int {
+		var q int
+		DB.Model(&User{}).Where("id = ?", user.Id).Select("quota").Find(&q)
+		return q
+	}
+
+	first, err := ReserveUserQuota(user.Id, 60)
+	if err != nil {
+		t.Fatalf("first reservation failed: %v", err)
+	}
+	if _, err := ReserveUserQuota(user.Id, 60); !errors.Is(err, ErrQuotaNotEnough) {
+		t.Fatalf("expected ErrQuotaNotEnough, got %v", err)
+	}
+	if err := ReleaseReservation(first); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	if q := quota(); q != 100 {
+		t.Fatalf("expected quota 100 after release, got %d", q)
+	}
+
+	second, err := ReserveUserQuota(user.Id, 60)
+	if err != nil {
+		t.Fatalf("second reservation failed: %v", err)
+	}
+	if err := ConfirmReservation(second); err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	if q := quota(); q != 40 {
+		t.Fatalf("expected quota 40 after confirm, got %d", q)
+	}
+	if err := ReleaseReservation(second); err == nil {
+		t.Fatal("expected releasing a confirmed reservation to fail")
+	}
+	if err := ReleaseReservation(first); err == nil {
+		t.Fatal("expected releasing a reservation twice to fail")
+	}
+}
+
+func TestReserveTokenQuota(t *testing.T) {
+	setupTestDB(t, &User{}, &Token{}, &QuotaReservation{})
+	limited := &Token{UserId: 1, Key: "reserve-limited", Name: "limited", RemainQuota: 50}
+	unlimited := &Token{UserId: 1, Key: "reserve-unlimited", Name: "unlimited", UnlimitedQuota: true}
+	if err := DB.Create(limited).Error; err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+	if err := DB.Create(unlimited).Error; err != nil {
+		t.Fatalf("create token failed: %v", err)
+	}
+
+	if _, err := ReserveTokenQuota(limited.Id, 60); !errors.Is(err, ErrQuotaNotEnough) {
+		t.Fatalf("expected ErrQuotaNotEnough, got %v", err)
+	}
+	id, err := ReserveTokenQuota(limited.Id, 30)
+	if err != nil {
+		t.Fatalf("reservation failed: %v", err)
+	}
+	if err := ReleaseReservation(id); err != nil {
+		t.Fatalf("release failed: %v", err)
+	}
+	var token Token
+	DB.First(&token, limited.Id)
+	if token.RemainQuota != 50 || token.UsedQuota != 0 {
+		t.Fatalf("expected quota restored, got remain=%d used=%d", token.RemainQuota, token.UsedQuota)
+	}
+	if _, err := ReserveTokenQuota(unlimited.Id, 1000); err != nil {
+		t.Fatalf("unlimited token reservation failed: %v", err)
+	}
+}
+
+func TestReleaseStaleReservations(t *testing.T) {
+	setupTestDB(t, &User{}, &Token{}, &QuotaReservation{})
+	user := &User{Username: "stale", Password: "password", Quota: 100}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	stale, err := ReserveUserQuota(user.Id, 30)
+	if err != nil {
+		t.Fatalf("reservation failed: %v", err)
+	}
+	confirmed, err := ReserveUserQuota(user.Id, 20)
+	if err != nil {
+		t.Fatalf("reservation failed: %v", err)
+	}
+	if err := ConfirmReservation(confirmed); err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	fresh, err := ReserveUserQuota(user.Id, 10)
+	if err != nil {
+		t.Fatalf("reservation failed: %v", err)
+	}
+	now := common.GetTimestamp()
+	DB.Model(&QuotaReservation{}).Where("id IN ?", []int64{stale, confirmed}).Update("created_at", now-3600)
+
+	released, err := ReleaseStaleReservations(now-1800, 100)
+	if err != nil || released != 1 {
+		t.Fatalf("released = %d, err = %v, want 1", released, err)
+	}
+	var statuses []string
+	DB.Model(&QuotaReservation{}).Order("id").Pluck("status", &statuses)
+	want := []string{QuotaReservationStatusReleased, QuotaReservationStatusConfirmed, QuotaReservationStatusPending}
+	for i, id := range []int64{stale, confirmed, fresh} {
+		if statuses[i] != want[i] {
+			t.Errorf("reservation %d status = %s, want %s", id, statuses[i], want[i])
+		}
+	}
+	var quota int
+	DB.Model(&User{}).Where("id = ?", user.Id).Select("quota").Find(&quota)
+	if quota != 70 {
+		t.Fatalf("expected quota 70 after releasing the stale reservation, got %d", quota)
+	}
+}
+
+func TestReleaseStaleReservationsSkipsInsertedTask(t *testing.T) {
+	setupTestDB(t, &User{}, &Token{}, &QuotaReservation{}, &Task{})
+	user := &User{Username: "attached", Password: "password", Quota: 100}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	reservationId, err := ReserveUserQuota(user.Id, 30)
+	if err != nil {
+		t.Fatalf("reservation failed: %v", err)
+	}
+	// 任务已写入，但预留确认失败，仍为待确认状态
+	task := &Task{TaskID: "task_attached", UserId: user.Id, Status: TaskStatusSubmitted, Quota: 30}
+	if err := task.InsertWithReservations(reservationId, 0); err != nil {
+		t.Fatalf("insert task failed: %v", err)
+	}
+	now := common.GetTimestamp()
+	DB.Model(&QuotaReservation{}).Where("id = ?", reservationId).Update("created_at", now-3600)
+
+	released, err := ReleaseStaleReservations(now-1800, 100)
+	if err != nil || released != 0 {
+		t.Fatalf("released = %d, err = %v, want 0", released, err)
+	}
+	var reservation QuotaReservation
+	DB.First(&reservation, reservationId)
+	if reservation.Status != QuotaReservationStatusPending || reservation.TaskId != "task_attached" {
+		t.Fatalf("reservation = %+v", reservation)
+	}
+	var quota int
+	DB.Model(&User{}).Where("id = ?", user.Id).Select("quota").Find(&quota)
+	if quota != 70 {
+		t.Fatalf("expected quota 70 for a running task, got %d", quota)
+	}
+}
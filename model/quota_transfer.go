@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrQuotaTransferInsufficient  = errors.New("转出用户额度不足")
+	ErrQuotaTransferUserNotExists = errors.New("用户不存在")
+)
+
+// QuotaTransfer 管理员在用户之间划转额度的记录
+type QuotaTransfer struct {
+	Id         int    `json:"id"`
+	FromUserId int    `json:"from_user_id" gorm:"index"`
+	ToUserId   int    `json:"to_user_id" gorm:"index"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason" gorm:"type:varchar(255)"`
+	OperatorId int    `json:"operator_id"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+// TransferUserQuota 在一个事务中扣减转出用户额度、增加转入用户额度并写入划转记录，
+// 转出用户额度不足时整体回滚
+func TransferUserQuota(fromUserId, toUserId, amount, operatorId int, reason string) (*QuotaTransfer, error) {
+	if amount <= 0 {
+		return nil, errors.New("划转额度必须大于 0")
+	}
+	if fromUserId == toUserId {
+		return nil, errors.New("转出用户与转入用户不能相同")
+	}
+	transfer := &QuotaTransfer{
+		FromUserId: fromUserId,
+		ToUserId:   toUserId,
+		Amount:     amount,
+		Reason:     reason,
+		OperatorId: operatorId,
+		CreatedAt:  common.GetTimestamp(),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", fromUserId, amount).
+			Update("quota", gorm.Expr("quota - ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&User{}).Where("id = ?", fromUserId).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrQuotaTransferUserNotExists
+			}
+			return ErrQuotaTransferInsufficient
+		}
+		result = tx.Model(&User{}).Where("id = ?", toUserId).
+			Update("quota", gorm.Expr("quota + ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrQuotaTransferUserNotExists
+		}
+		return tx.Create(transfer).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务成功后更新缓存
+	go func() {
+		_ = cacheDecrUserQuota(fromUserId, int64(amount))
+		_ = cacheIncrUserQuota(toUserId, int64(amount))
+	}()
+	return transfer, nil
+}
+
+// GetQuotaTransfers 分页获取划转记录，userId 不为 0 时只返回与该用户相关的记录
+func GetQuotaTransfers(userId int, startIdx int, num int) ([]*QuotaTransfer, int64, error) {
+	var transfers []*QuotaTransfer
+	var total int64
+	query := DB.Model(&QuotaTransfer{})
+	if userId != 0 {
+		query = query.Where("from_user_id = ? OR to_user_id = ?", userId, userId)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id desc").Limit(num).Offset(startIdx).Find(&transfers).Error; err != nil {
+		return nil, 0, err
+	}
+	return transfers, total, nil
+}
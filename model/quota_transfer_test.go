@@ -0,0 +1,51 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestTransferUserQuota(t *testing.T) {
+	setupTestDB(t, &User{}, &QuotaTransfer{})
+	common.RedisEnabled = false
+	from := &User{Username: "pool", Password: "password", AffCode: "pool", Quota: 100}
+	to := &User{Username: "customer", Password: "password", AffCode: "customer", Quota: 5}
+	for _, u := range []*User{from, to} {
+		if err := DB.Create(u).Error; err != nil {
+			t.Fatalf("create user failed: %v", err)
+		}
+	}
+	quota := func(id int) int {
+		var u User
+		DB.First(&u, id)
+		return u.Quota
+	}
+
+	if _, err := TransferUserQuota(from.Id, to.Id, 60, 1, "refund"); err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if quota(from.Id) != 40 || quota(to.Id) != 65 {
+		t.Fatalf("after transfer: from=%d to=%d, want 40/65", quota(from.Id), quota(to.Id))
+	}
+
+	if _, err := TransferUserQuota(from.Id, to.Id, 41, 1, ""); !errors.Is(err, ErrQuotaTransferInsufficient) {
+		t.Fatalf("expected insufficient quota error, got %v", err)
+	}
+	if _, err := TransferUserQuota(from.Id, 999, 10, 1, ""); !errors.Is(err, ErrQuotaTransferUserNotExists) {
+		t.Fatalf("expected missing user error, got %v", err)
+	}
+	// 失败的划转必须整体回滚
+	if quota(from.Id) != 40 || quota(to.Id) != 65 {
+		t.Fatalf("failed transfers changed balances: from=%d to=%d", quota(from.Id), quota(to.Id))
+	}
+
+	transfers, total, err := GetQuotaTransfers(to.Id, 0, 10)
+	if err != nil {
+		t.Fatalf("GetQuotaTransfers: %v", err)
+	}
+	if total != 1 || len(transfers) != 1 || transfers[0].Amount != 60 || transfers[0].Reason != "refund" {
+		t.Fatalf("unexpected transfer history: total=%d %+v", total, transfers)
+	}
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// ShadowResult 影子模式下一次任务提交的对比结果：主渠道提交结果与镜像到影子渠道的提交结果
+type ShadowResult struct {
+	Id               int64  `json:"id"`
+	ChannelId        int    `json:"channel_id" gorm:"index:idx_shadow_channel_created"`
+	ShadowChannelId  int    `json:"shadow_channel_id" gorm:"index"`
+	UserId           int    `json:"user_id"`
+	ModelName        string `json:"model_name" gorm:"type:varchar(255)"`
+	Action           string `json:"action" gorm:"type:varchar(40)"`
+	PrimarySuccess   bool   `json:"primary_success"`
+	PrimaryErrorCode string `json:"primary_error_code" gorm:"type:varchar(64)"`
+	ShadowSuccess    bool   `json:"shadow_success"`
+	ShadowStatusCode int    `json:"shadow_status_code"`
+	ShadowErrorCode  string `json:"shadow_error_code" gorm:"type:varchar(64)"`
+	ShadowError      string `json:"shadow_error" gorm:"type:text"`
+	// ShadowTaskId 影子渠道返回的上游任务 ID，仅用于排查，不会写入任务表
+	ShadowTaskId    string `json:"shadow_task_id" gorm:"type:varchar(191)"`
+	ShadowLatencyMs int64  `json:"shadow_latency_ms"`
+	// ShadowQuota 影子提交按主渠道相同价格计算的额度，不向用户扣费，计入影子渠道已用额度
+	ShadowQuota int   `json:"shadow_quota"`
+	CreatedAt   int64 `json:"created_at" gorm:"bigint;index:idx_shadow_channel_created"`
+}
+
+// ShadowResultStats 主渠道与影子渠道的提交成功次数汇总
+type ShadowResultStats struct {
+	ShadowChannelId  int     `json:"shadow_channel_id"`
+	Total            int64   `json:"total"`
+	PrimarySuccess   int64   `json:"primary_success"`
+	ShadowSuccess    int64   `json:"shadow_success"`
+	Mismatch         int64   `json:"mismatch"`
+	AvgShadowLatency float64 `json:"avg_shadow_latency_ms"`
+}
+
+func (r *ShadowResult) Insert() error {
+	if r.CreatedAt == 0 {
+		r.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(r).Error
+}
+
+// SumShadowQuota 汇总主渠道自 since 起镜像到影子渠道消耗的额度
+func SumShadowQuota(channelId int, since int64) (int64, error) {
+	var total int64
+	err := DB.Model(&ShadowResult{}).
+		Select("coalesce(sum(shadow_quota), 0)").
+		Where("channel_id = ? AND created_at >= ?", channelId, since).
+		Scan(&total).Error
+	return total, err
+}
+
+// GetShadowResultStats 按影子渠道汇总主渠道自 since 起的对比结果
+func GetShadowResultStats(channelId int, since int64) ([]*ShadowResultStats, error) {
+	var stats []*ShadowResultStats
+	err := DB.Model(&ShadowResult{}).
+		Select("shadow_channel_id, count(*) as total, "+
+			"sum(case when primary_success then 1 else 0 end) as primary_success, "+
+			"sum(case when shadow_success then 1 else 0 end) as shadow_success, "+
+			"sum(case when primary_success <> shadow_success then 1 else 0 end) as mismatch, "+
+			"avg(shadow_latency_ms) as avg_shadow_latency").
+		Where("channel_id = ? AND created_at >= ?", channelId, since).
+		Group("shadow_channel_id").
+		Order("shadow_channel_id asc").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// GetShadowResults 分页获取主渠道自 since 起的对比明细，按时间倒序
+func GetShadowResults(channelId int, since int64, startIdx int, num int) ([]*ShadowResult, error) {
+	var results []*ShadowResult
+	err := DB.Where("channel_id = ? AND created_at >= ?", channelId, since).
+		Order("id desc").Offset(startIdx).Limit(num).Find(&results).Error
+	return results, err
+}
@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestGetShadowResultStats(t *testing.T) {
+	setupTestDB(t, &ShadowResult{})
+
+	results := []*ShadowResult{
+		{ChannelId: 1, ShadowChannelId: 2, PrimarySuccess: true, ShadowSuccess: true, ShadowLatencyMs: 100, CreatedAt: 1000},
+		{ChannelId: 1, ShadowChannelId: 2, PrimarySuccess: true, ShadowSuccess: false, ShadowLatencyMs: 300, CreatedAt: 1001},
+		{ChannelId: 1, ShadowChannelId: 2, PrimarySuccess: false, ShadowSuccess: true, ShadowLatencyMs: 200, CreatedAt: 1002},
+		{ChannelId: 1, ShadowChannelId: 3, PrimarySuccess: true, ShadowSuccess: true, ShadowLatencyMs: 50, CreatedAt: 1003},
+		{ChannelId: 1, ShadowChannelId: 2, PrimarySuccess: true, ShadowSuccess: true, CreatedAt: 10},
+		{ChannelId: 4, ShadowChannelId: 2, PrimarySuccess: true, ShadowSuccess: true, CreatedAt: 1000},
+	}
+	for _, r := range results {
+		if err := r.Insert(); err != nil {
+			t.Fatalf("insert shadow result failed: %v", err)
+		}
+	}
+
+	stats, err := GetShadowResultStats(1, 1000)
+	if err != nil {
+		t.Fatalf("get stats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 shadow channels, got %d", len(stats))
+	}
+	s := stats[0]
+	if s.ShadowChannelId != 2 || s.Total != 3 || s.PrimarySuccess != 2 || s.ShadowSuccess != 2 || s.Mismatch != 2 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if s.AvgShadowLatency != 200 {
+		t.Fatalf("avg latency = %v, want 200", s.AvgShadowLatency)
+	}
+
+	list, err := GetShadowResults(1, 1000, 0, 10)
+	if err != nil {
+		t.Fatalf("get results failed: %v", err)
+	}
+	if len(list) != 4 || list[0].ShadowChannelId != 3 {
+		t.Fatalf("unexpected results: %+v", list)
+	}
+}
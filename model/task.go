@@ -2,12 +2,22 @@ package model
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	commonRelay "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
 )
 
 type TaskStatus string
@@ -45,18 +55,40 @@ type Task struct {
 	UpdatedAt  int64                 `json:"updated_at"`
 	TaskID     string                `json:"task_id" gorm:"type:varchar(191);index"` // 第三方id，不一定有/ song id\ Task id
 	Platform   constant.TaskPlatform `json:"platform" gorm:"type:varchar(30);index"` // 平台
-	UserId     int                   `json:"user_id" gorm:"index"`
+	UserId     int                   `json:"user_id" gorm:"index;index:idx_tasks_user_submit_time,priority:1"`
 	Group      string                `json:"group" gorm:"type:varchar(50)"` // 修正计费用
 	ChannelId  int                   `json:"channel_id" gorm:"index"`
 	Quota      int                   `json:"quota"`
 	Action     string                `json:"action" gorm:"type:varchar(40);index"` // 任务类型, song, lyrics, description-mode
 	Status     TaskStatus            `json:"status" gorm:"type:varchar(20);index"` // 任务状态
 	FailReason string                `json:"fail_reason"`
-	SubmitTime int64                 `json:"submit_time" gorm:"index"`
+	SubmitTime int64                 `json:"submit_time" gorm:"index;index:idx_tasks_user_submit_time,priority:2"`
 	StartTime  int64                 `json:"start_time" gorm:"index"`
 	FinishTime int64                 `json:"finish_time" gorm:"index"`
 	Progress   string                `json:"progress" gorm:"type:varchar(20);index"`
 	Properties Properties            `json:"properties" gorm:"type:json"`
+	// 任务进入终态后回调的地址
+	CallbackURL string `json:"callback_url,omitempty" gorm:"type:varchar(512)"`
+	// 任务状态或进度变化时回调的地址
+	ProgressCallbackURL string `json:"progress_callback_url,omitempty" gorm:"type:varchar(512)"`
+	// 轮询上游失败的次数及下次重试时间
+	RetryCount  int   `json:"retry_count" gorm:"default:0"`
+	NextRetryAt int64 `json:"next_retry_at" gorm:"bigint;default:0"`
+	// 下次轮询上游状态的时间，调度器只处理已到期的任务
+	NextPollAt int64 `json:"next_poll_at" gorm:"bigint;default:0;index"`
+	// 上游连续返回空状态的次数，收到非空状态时清零
+	EmptyStatusCount int `json:"empty_status_count" gorm:"default:0"`
+	// 轮询优先级，0 普通，1 高，2 紧急，由用户分组决定
+	Priority int `json:"priority" gorm:"default:0;index"`
+	// 用户自定义的任务标签
+	Tags TaskTags `json:"tags,omitempty"`
+	// 重放任务对应的原始任务 task_id，重放的任务再次重放时仍指向最初的任务
+	ParentTaskID string `json:"parent_task_id,omitempty" gorm:"type:varchar(191);index"`
+	// 原始任务已占用的重放名额，以条件更新递增，保证并发重放不超过上限
+	ReplayCount int `json:"-" gorm:"default:0"`
+	// 提交任务时的请求路径与 JSON 请求体，用于失败后重放，任务成功或超过保留期后清除
+	RequestPath string          `json:"-" gorm:"type:varchar(191)"`
+	RequestBody json.RawMessage `json:"-" gorm:"type:json"`
 	// 禁止返回给用户，内部可能包含key等隐私信息
 	PrivateData TaskPrivateData `json:"-" gorm:"column:private_data;type:json"`
 	Data        json.RawMessage `json:"data" gorm:"type:json"`
@@ -73,9 +105,20 @@ func (t *Task) GetData(v any) error {
 }
 
 type Properties struct {
-	Input             string `json:"input"`
-	UpstreamModelName string `json:"upstream_model_name,omitempty"`
-	OriginModelName   string `json:"origin_model_name,omitempty"`
+	Input                    string `json:"input"`
+	UpstreamModelName        string `json:"upstream_model_name,omitempty"`
+	OriginModelName          string `json:"origin_model_name,omitempty"`
+	ExecutionExpiresAfterSec int64  `json:"execution_expires_after_sec,omitempty"`
+	// 视频产出的质量评分，仅在所在分组开启评分后写入
+	QualityScores *dto.VideoQualityScores `json:"quality_scores,omitempty"`
+	// 提交时请求了同步音频（generate_audio），用于判断能否提取音轨
+	GenerateAudio bool `json:"generate_audio,omitempty"`
+	// 视频产出的缩略图地址，任务成功后异步生成
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	// 提交时的按次价格、生效的分组倍率与附加倍率，用于计费对账
+	ModelPrice float64 `json:"model_price,omitempty"`
+	GroupRatio float64 `json:"group_ratio,omitempty"`
+	OtherRatio float64 `json:"other_ratio,omitempty"`
 }
 
 func (m *Properties) Scan(val interface{}) error {
@@ -99,6 +142,16 @@ type TaskPrivateData struct {
 	TokenId   int    `json:"token_id,omitempty"`
 	TokenKey  string `json:"token_key,omitempty"`
 	TokenName string `json:"token_name,omitempty"`
+	// 回调签名密钥，提交时为每个任务随机生成并通过响应头返回
+	CallbackSecret string `json:"callback_secret,omitempty"`
+	// 任务产出的视频转存到对象存储后的 object key
+	StorageKey string `json:"storage_key,omitempty"`
+	// 从视频中提取的音轨转存到对象存储后的 object key
+	AudioStorageKey string `json:"audio_storage_key,omitempty"`
+	// 提交时累加模型周期消费上限所用的模型名，退款时据此退回上限额度
+	SpendingCapModel string `json:"spending_cap_model,omitempty"`
+	// 音轨提取是否已扣费，之后的提取不再重复扣费
+	AudioExtractionCharged bool `json:"audio_extraction_charged,omitempty"`
 }
 
 func (p *TaskPrivateData) Scan(val interface{}) error {
@@ -127,6 +180,22 @@ type SyncTaskQueryParams struct {
 	StartTimestamp int64
 	EndTimestamp   int64
 	UserIDs        []int
+	// 只返回包含全部指定标签的任务
+	Tags []string
+}
+
+// ExpiresAt 返回用户指定的任务过期时间戳，未指定时返回 0
+func (t *Task) ExpiresAt() int64 {
+	if t.Properties.ExecutionExpiresAfterSec <= 0 || t.SubmitTime <= 0 {
+		return 0
+	}
+	return t.SubmitTime + t.Properties.ExecutionExpiresAfterSec
+}
+
+// IsExpired 判断任务在 now 时刻是否已超过用户指定的过期时间
+func (t *Task) IsExpired(now int64) bool {
+	expiresAt := t.ExpiresAt()
+	return expiresAt > 0 && now > expiresAt
 }
 
 func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo) *Task {
@@ -143,6 +212,9 @@ func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo)
 			properties.OriginModelName = relayInfo.OriginModelName
 		}
 	}
+	if relayInfo != nil && relayInfo.TaskRelayInfo != nil {
+		properties.ExecutionExpiresAfterSec = relayInfo.ExecutionExpiresAfterSec
+	}
 
 	t := &Task{
 		UserId:      relayInfo.UserId,
@@ -154,6 +226,7 @@ func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo)
 		Platform:    platform,
 		Properties:  properties,
 		PrivateData: privateData,
+		Priority:    ratio_setting.GetGroupTaskPriority(relayInfo.UserGroup),
 	}
 	return t
 }
@@ -194,6 +267,66 @@ func TaskGetAllUserTask(userId int, startIdx int, num int, queryParams SyncTaskQ
 	return tasks
 }
 
+// TaskCursor 用户任务列表的游标，按 (submit_time, id) 倒序翻页
+type TaskCursor struct {
+	SubmitTime int64
+	ID         int64
+}
+
+// Encode 将游标编码为对外返回的不透明字符串
+func (c TaskCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", c.SubmitTime, c.ID)))
+}
+
+func ParseTaskCursor(s string) (*TaskCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	submitTime, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, errors.New("invalid cursor")
+	}
+	cursor := &TaskCursor{}
+	if cursor.SubmitTime, err = strconv.ParseInt(submitTime, 10, 64); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	if cursor.ID, err = strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// GetTasksByUser 按提交时间倒序查询用户任务，cursor 为上一页最后一条任务的位置，
+// 查询走 (user_id, submit_time) 联合索引
+func GetTasksByUser(userId int, queryParams SyncTaskQueryParams, cursor *TaskCursor, limit int) ([]*Task, error) {
+	var tasks []*Task
+	query := DB.Where("user_id = ?", userId)
+
+	if queryParams.Action != "" {
+		query = query.Where("action = ?", queryParams.Action)
+	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.Platform != "" {
+		query = query.Where("platform = ?", queryParams.Platform)
+	}
+	if queryParams.StartTimestamp != 0 {
+		query = query.Where("submit_time >= ?", queryParams.StartTimestamp)
+	}
+	if queryParams.EndTimestamp != 0 {
+		query = query.Where("submit_time <= ?", queryParams.EndTimestamp)
+	}
+	query = whereTaskHasTags(query, queryParams.Tags)
+	if cursor != nil {
+		query = query.Where("submit_time < ? OR (submit_time = ? AND id < ?)", cursor.SubmitTime, cursor.SubmitTime, cursor.ID)
+	}
+
+	err := query.Omit("channel_id").Order("submit_time desc, id desc").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
 func TaskGetAllTasks(startIdx int, num int, queryParams SyncTaskQueryParams) []*Task {
 	var tasks []*Task
 	var err error
@@ -242,14 +375,202 @@ func TaskGetAllTasks(startIdx int, num int, queryParams SyncTaskQueryParams) []*
 func GetAllUnFinishSyncTasks(limit int) []*Task {
 	var tasks []*Task
 	var err error
-	// get all tasks progress is not 100%
-	err = DB.Where("progress != ?", "100%").Where("status != ?", TaskStatusFailure).Where("status != ?", TaskStatusSuccess).Limit(limit).Order("id").Find(&tasks).Error
+	// get all tasks progress is not 100% and due for polling
+	err = DB.Where("progress != ?", "100%").Where("status != ?", TaskStatusFailure).Where("status != ?", TaskStatusSuccess).
+		Where("next_poll_at <= ?", time.Now().Unix()).Limit(limit).Order("priority desc, id").Find(&tasks).Error
 	if err != nil {
 		return nil
 	}
 	return tasks
 }
 
+// GetPendingTasksByPriorityAndChannel 返回渠道下指定的未完成任务，按优先级从高到低、提交时间从早到晚排序
+func GetPendingTasksByPriorityAndChannel(channelId int, taskIds []string) ([]*Task, error) {
+	var tasks []*Task
+	if len(taskIds) == 0 {
+		return tasks, nil
+	}
+	err := DB.Where("channel_id = ?", channelId).
+		Where("task_id IN ?", taskIds).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess}).
+		Order("priority desc, submit_time asc, id asc").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// UpdateTaskPriority 修改未完成任务的优先级，返回是否有任务被更新
+func UpdateTaskPriority(id int64, priority int) (bool, error) {
+	result := DB.Model(&Task{}).
+		Where("id = ?", id).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess}).
+		Update("priority", priority)
+	return result.RowsAffected > 0, result.Error
+}
+
+// updatePrivateData 在事务中加锁读取最新的 private_data，修改后写回，避免并发更新不同字段时互相覆盖
+func (t *Task) updatePrivateData(modify func(*TaskPrivateData)) error {
+	var latest Task
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Select("id", "private_data").Where("id = ?", t.ID).First(&latest).Error; err != nil {
+			return err
+		}
+		modify(&latest.PrivateData)
+		return tx.Model(&Task{}).Where("id = ?", t.ID).Update("private_data", latest.PrivateData).Error
+	})
+	if err != nil {
+		return err
+	}
+	t.PrivateData = latest.PrivateData
+	return nil
+}
+
+// updateProperties 在事务中加锁读取最新的 properties，修改后写回，避免并发更新不同字段时互相覆盖
+func (t *Task) updateProperties(modify func(*Properties)) error {
+	var latest Task
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Select("id", "properties").Where("id = ?", t.ID).First(&latest).Error; err != nil {
+			return err
+		}
+		modify(&latest.Properties)
+		return tx.Model(&Task{}).Where("id = ?", t.ID).Update("properties", latest.Properties).Error
+	})
+	if err != nil {
+		return err
+	}
+	t.Properties = latest.Properties
+	return nil
+}
+
+// UpdateStorageKey 记录任务产出转存到对象存储后的 object key
+func (t *Task) UpdateStorageKey(key string) error {
+	return t.updatePrivateData(func(p *TaskPrivateData) {
+		p.StorageKey = key
+	})
+}
+
+// UpdateAudioStorageKey 记录提取的音轨转存到对象存储后的 object key
+func (t *Task) UpdateAudioStorageKey(key string) error {
+	return t.updatePrivateData(func(p *TaskPrivateData) {
+		p.AudioStorageKey = key
+	})
+}
+
+// ChargeAudioExtraction 在同一事务中扣除用户额度并标记音轨提取已扣费，二者同时成功或同时回滚。
+// 返回 false 表示此前已扣费，余额不足时返回 ErrQuotaNotEnough
+func (t *Task) ChargeAudioExtraction(quota int) (bool, error) {
+	if quota < 0 {
+		return false, errors.New("quota 不能为负数！")
+	}
+	var latest Task
+	charged := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Select("id", "private_data").Where("id = ?", t.ID).First(&latest).Error; err != nil {
+			return err
+		}
+		if latest.PrivateData.AudioExtractionCharged {
+			return nil
+		}
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", t.UserId, quota).Update("quota", gorm.Expr("quota - ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrQuotaNotEnough
+		}
+		latest.PrivateData.AudioExtractionCharged = true
+		if err := tx.Model(&Task{}).Where("id = ?", t.ID).Update("private_data", latest.PrivateData).Error; err != nil {
+			return err
+		}
+		charged = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	t.PrivateData = latest.PrivateData
+	if charged && common.RedisEnabled {
+		gopool.Go(func() {
+			if err := cacheDecrUserQuota(t.UserId, int64(quota)); err != nil {
+				common.SysLog("failed to decrease user quota: " + err.Error())
+			}
+		})
+	}
+	return charged, nil
+}
+
+// UpdateQualityScores 写入任务产出的质量评分
+func (t *Task) UpdateQualityScores(scores *dto.VideoQualityScores) error {
+	return t.updateProperties(func(p *Properties) {
+		p.QualityScores = scores
+	})
+}
+
+// UpdateThumbnailURL 写入任务产出的缩略图地址
+func (t *Task) UpdateThumbnailURL(thumbnailURL string) error {
+	return t.updateProperties(func(p *Properties) {
+		p.ThumbnailURL = thumbnailURL
+	})
+}
+
+// GetStaleUnfinishedTasks 返回提交时间早于 submitBefore 且仍未完成的任务，按提交时间从早到晚排序
+func GetStaleUnfinishedTasks(submitBefore int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess}).
+		Where("submit_time > 0 AND submit_time < ?", submitBefore).
+		Order("submit_time asc, id asc").
+		Limit(limit).
+		Find(&tasks).Error
+	return tasks, err
+}
+
+// FailIfUnfinished 将未完成的任务标记为失败并清零额度，返回是否更新成功。
+// 任务已被轮询更新为终态时不做修改，避免重复退款
+func (t *Task) FailIfUnfinished(reason string, finishTime int64) (bool, error) {
+	return t.failIf(nil, reason, finishTime)
+}
+
+// failIf 将未完成且满足附加条件 cond（可为 nil）的任务标记为失败并清零额度，已结束的任务不会被修改
+func (t *Task) failIf(cond *gorm.DB, reason string, finishTime int64) (bool, error) {
+	query := DB.Model(&Task{}).
+		Where("id = ?", t.ID).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess})
+	if cond != nil {
+		query = query.Where(cond)
+	}
+	result := query.Updates(map[string]any{
+		"status":      TaskStatusFailure,
+		"progress":    "100%",
+		"finish_time": finishTime,
+		"fail_reason": reason,
+		"quota":       0,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	t.Status = TaskStatusFailure
+	t.Progress = "100%"
+	t.FinishTime = finishTime
+	t.FailReason = reason
+	t.Quota = 0
+	return true, nil
+}
+
+// taskPollColumns 轮询上游状态时写入的列，priority 与 tags 可由用户在轮询期间修改，不在其中
+var taskPollColumns = []string{
+	"status", "progress", "start_time", "finish_time", "fail_reason", "quota", "data",
+	"retry_count", "next_retry_at", "empty_status_count",
+}
+
+// UpdatePollResult 保存轮询结果，只写入轮询负责的列，且仅在任务尚未结束时更新，返回是否更新成功。
+// 任务已被取消或过期时不做修改，避免覆盖终态与已退还的额度
+func (t *Task) UpdatePollResult() (bool, error) {
+	result := DB.Model(t).
+		Where("status NOT IN ?", []TaskStatus{TaskStatusFailure, TaskStatusSuccess}).
+		Select(taskPollColumns).
+		Updates(t)
+	return result.RowsAffected > 0, result.Error
+}
+
 func GetByOnlyTaskId(taskId string) (*Task, bool, error) {
 	if taskId == "" {
 		return nil, false, nil
@@ -303,6 +624,17 @@ func (Task *Task) Insert() error {
 	return err
 }
 
+// InsertWithReservations 写入任务，并在同一事务中将提交时的额度预留关联到该任务，
+// 之后即使预留确认失败，过期回收也不会为仍在执行的任务退款
+func (t *Task) InsertWithReservations(reservationIds ...int64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(t).Error; err != nil {
+			return err
+		}
+		return AttachReservationsToTask(tx, t.TaskID, reservationIds...)
+	})
+}
+
 func (Task *Task) Update() error {
 	var err error
 	err = DB.Save(Task).Error
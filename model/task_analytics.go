@@ -0,0 +1,157 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+const taskAnalyticsTopModels = 10
+
+// TaskCountStat 按某个维度统计的任务数
+type TaskCountStat struct {
+	Key   string `json:"key" gorm:"column:stat_key"`
+	Count int64  `json:"count"`
+}
+
+// TaskDurationStat 平台已结束任务从提交到结束的平均耗时（秒）
+type TaskDurationStat struct {
+	Platform   string  `json:"platform"`
+	AvgSeconds float64 `json:"avg_seconds"`
+}
+
+// ChannelFailureStat 渠道任务失败率
+type ChannelFailureStat struct {
+	ChannelId   int     `json:"channel_id"`
+	Total       int64   `json:"total"`
+	Failed      int64   `json:"failed"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+// TaskLatencyStat 成功任务从提交到完成耗时（秒）的分位数
+type TaskLatencyStat struct {
+	Samples int   `json:"samples"`
+	P50     int64 `json:"p50"`
+	P95     int64 `json:"p95"`
+	P99     int64 `json:"p99"`
+}
+
+// ModelQuotaStat 模型消耗的额度
+type ModelQuotaStat struct {
+	Model string `json:"model"`
+	Quota int64  `json:"quota"`
+	Count int64  `json:"count"`
+}
+
+// TaskAnalytics 管理后台任务统计面板数据，统计 [StartTimestamp, EndTimestamp] 内提交的任务
+type TaskAnalytics struct {
+	StartTimestamp        int64                `json:"start_timestamp"`
+	EndTimestamp          int64                `json:"end_timestamp"`
+	CountByStatus         []TaskCountStat      `json:"count_by_status"`
+	CountByPlatform       []TaskCountStat      `json:"count_by_platform"`
+	CountByModel          []TaskCountStat      `json:"count_by_model"`
+	CountByDay            []TaskCountStat      `json:"count_by_day"`
+	AvgDurationByPlatform []TaskDurationStat   `json:"avg_duration_by_platform"`
+	FailureRateByChannel  []ChannelFailureStat `json:"failure_rate_by_channel"`
+	CompletionLatency     TaskLatencyStat      `json:"completion_latency"`
+	TopModelsByQuota      []ModelQuotaStat     `json:"top_models_by_quota"`
+}
+
+// taskModelNameExpr 从 properties JSON 中取出模型名的 SQL 表达式，缺失时为空字符串
+func taskModelNameExpr() string {
+	if common.UsingMySQL {
+		return "COALESCE(JSON_UNQUOTE(JSON_EXTRACT(properties, '$.origin_model_name')), '')"
+	}
+	if common.UsingPostgreSQL {
+		return "COALESCE(properties::json->>'origin_model_name', '')"
+	}
+	return "COALESCE(json_extract(properties, '$.origin_model_name'), '')"
+}
+
+// GetTaskAnalytics 聚合统计指定时间范围内提交的任务
+func GetTaskAnalytics(startTimestamp int64, endTimestamp int64) (*TaskAnalytics, error) {
+	analytics := &TaskAnalytics{StartTimestamp: startTimestamp, EndTimestamp: endTimestamp}
+	scope := func() *gorm.DB {
+		return DB.Model(&Task{}).Where("submit_time >= ? AND submit_time <= ?", startTimestamp, endTimestamp)
+	}
+	modelExpr := taskModelNameExpr()
+
+	if err := scope().Select("status as stat_key, count(*) as count").Group("status").Order("count desc").Scan(&analytics.CountByStatus).Error; err != nil {
+		return nil, err
+	}
+	if err := scope().Select("platform as stat_key, count(*) as count").Group("platform").Order("count desc").Scan(&analytics.CountByPlatform).Error; err != nil {
+		return nil, err
+	}
+	if err := scope().Select(modelExpr + " as stat_key, count(*) as count").Group(modelExpr).Order("count desc").Scan(&analytics.CountByModel).Error; err != nil {
+		return nil, err
+	}
+
+	// 按 UTC 自然日分桶，避免依赖各数据库的日期函数
+	var days []struct {
+		Day   int64
+		Count int64
+	}
+	dayExpr := "submit_time - submit_time % 86400"
+	if err := scope().Select(dayExpr + " as day, count(*) as count").Group(dayExpr).Order("day").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range days {
+		analytics.CountByDay = append(analytics.CountByDay, TaskCountStat{
+			Key:   time.Unix(d.Day, 0).UTC().Format("2006-01-02"),
+			Count: d.Count,
+		})
+	}
+
+	if err := scope().Select("platform, avg(finish_time - submit_time) as avg_seconds").
+		Where("finish_time > 0").Group("platform").Order("platform").Scan(&analytics.AvgDurationByPlatform).Error; err != nil {
+		return nil, err
+	}
+
+	if err := scope().Select("channel_id, count(*) as total, sum(case when status = ? then 1 else 0 end) as failed", TaskStatusFailure).
+		Group("channel_id").Order("channel_id").Scan(&analytics.FailureRateByChannel).Error; err != nil {
+		return nil, err
+	}
+	for i := range analytics.FailureRateByChannel {
+		stat := &analytics.FailureRateByChannel[i]
+		if stat.Total > 0 {
+			stat.FailureRate = float64(stat.Failed) / float64(stat.Total)
+		}
+	}
+
+	// 分位数没有跨数据库的 SQL 写法，取出耗时后在内存中计算
+	var latencies []int64
+	if err := scope().Where("status = ? AND finish_time > 0", TaskStatusSuccess).
+		Pluck("finish_time - submit_time", &latencies).Error; err != nil {
+		return nil, err
+	}
+	analytics.CompletionLatency = computeTaskLatencyStat(latencies)
+
+	if err := scope().Select(modelExpr + " as model, sum(quota) as quota, count(*) as count").
+		Group(modelExpr).Order("quota desc").Limit(taskAnalyticsTopModels).Scan(&analytics.TopModelsByQuota).Error; err != nil {
+		return nil, err
+	}
+	return analytics, nil
+}
+
+func computeTaskLatencyStat(latencies []int64) TaskLatencyStat {
+	stat := TaskLatencyStat{Samples: len(latencies)}
+	if len(latencies) == 0 {
+		return stat
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) int64 {
+		idx := int(math.Ceil(p*float64(len(latencies)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return latencies[idx]
+	}
+	stat.P50 = percentile(0.50)
+	stat.P95 = percentile(0.95)
+	stat.P99 = percentile(0.99)
+	return stat
+}
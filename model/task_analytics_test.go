@@ -0,0 +1,59 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTaskAnalytics(t *testing.T) {
+	setupTestDB(t, &Task{})
+	day := time.Date(2025, time.March, 1, 8, 0, 0, 0, time.UTC).Unix()
+	tasks := []*Task{
+		{TaskID: "a", Platform: "suno", ChannelId: 1, Status: TaskStatusSuccess, Quota: 100, SubmitTime: day, FinishTime: day + 10, Properties: Properties{OriginModelName: "suno_music"}},
+		{TaskID: "b", Platform: "suno", ChannelId: 1, Status: TaskStatusFailure, Quota: 0, SubmitTime: day + 60, FinishTime: day + 90, Properties: Properties{OriginModelName: "suno_music"}},
+		{TaskID: "c", Platform: "kling", ChannelId: 2, Status: TaskStatusSuccess, Quota: 500, SubmitTime: day + 86400, FinishTime: day + 86400 + 100, Properties: Properties{OriginModelName: "kling-v1"}},
+		{TaskID: "d", Platform: "kling", ChannelId: 2, Status: TaskStatusInProgress, Quota: 500, SubmitTime: day + 86400},
+		// 时间范围之外
+		{TaskID: "e", Platform: "kling", ChannelId: 2, Status: TaskStatusSuccess, Quota: 900, SubmitTime: day - 86400*10, FinishTime: day},
+	}
+	for _, task := range tasks {
+		if err := DB.Create(task).Error; err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+	}
+
+	analytics, err := GetTaskAnalytics(day-3600, day+86400*2)
+	if err != nil {
+		t.Fatalf("GetTaskAnalytics: %v", err)
+	}
+	counts := func(stats []TaskCountStat) map[string]int64 {
+		m := make(map[string]int64)
+		for _, s := range stats {
+			m[s.Key] = s.Count
+		}
+		return m
+	}
+	if got := counts(analytics.CountByStatus); got[TaskStatusSuccess] != 2 || got[TaskStatusFailure] != 1 || got[TaskStatusInProgress] != 1 {
+		t.Errorf("count by status = %v", got)
+	}
+	if got := counts(analytics.CountByModel); got["suno_music"] != 2 || got["kling-v1"] != 1 || got[""] != 1 {
+		t.Errorf("count by model = %v", got)
+	}
+	if got := counts(analytics.CountByDay); got["2025-03-01"] != 2 || got["2025-03-02"] != 2 {
+		t.Errorf("count by day = %v", got)
+	}
+	for _, stat := range analytics.AvgDurationByPlatform {
+		if stat.Platform == "suno" && stat.AvgSeconds != 20 {
+			t.Errorf("suno avg duration = %v, want 20", stat.AvgSeconds)
+		}
+	}
+	if len(analytics.FailureRateByChannel) != 2 || analytics.FailureRateByChannel[0].FailureRate != 0.5 {
+		t.Errorf("failure rate by channel = %+v", analytics.FailureRateByChannel)
+	}
+	if latency := analytics.CompletionLatency; latency.Samples != 2 || latency.P50 != 10 || latency.P99 != 100 {
+		t.Errorf("completion latency = %+v", latency)
+	}
+	if top := analytics.TopModelsByQuota; len(top) == 0 || top[0].Quota != 500 {
+		t.Errorf("top models by quota = %+v", top)
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"errors"
+)
+
+// TaskCancelReasonAdmin 管理员批量取消任务时写入的失败原因
+const TaskCancelReasonAdmin = "cancelled by admin"
+
+// TaskCancelReasonUser 用户通过令牌取消任务时写入的失败原因
+const TaskCancelReasonUser = "cancelled by user"
+
+// TaskCancelReasonWarmup 渠道预热任务提交后立即取消时写入的失败原因
+const TaskCancelReasonWarmup = "cancelled after warm-up"
+
+// TaskCancelUserRefund 批量取消中单个用户被取消的任务数及退还额度
+type TaskCancelUserRefund struct {
+	UserId int `json:"user_id"`
+	Count  int `json:"count"`
+	Quota  int `json:"quota"`
+}
+
+// TaskCancelSummary 批量取消任务的结果
+type TaskCancelSummary struct {
+	Count         int                    `json:"count"`
+	RefundedQuota int                    `json:"refunded_quota"`
+	Users         []TaskCancelUserRefund `json:"users"`
+}
+
+// ValidateCancelTaskStatuses 校验批量取消的任务状态，只接受未完成的状态
+func ValidateCancelTaskStatuses(statuses []TaskStatus) error {
+	if len(statuses) == 0 {
+		return errors.New("status is required")
+	}
+	for _, status := range statuses {
+		if status == TaskStatusSuccess || status == TaskStatusFailure {
+			return errors.New("finished tasks cannot be cancelled")
+		}
+	}
+	return nil
+}
+
+// GetTasksByChannelAndStatus 按 id 升序分页返回渠道下处于 statuses 状态的任务，afterId 为上一页最后一个任务的 id
+func GetTasksByChannelAndStatus(channelId int, statuses []TaskStatus, afterId int64, limit int) ([]*Task, error) {
+	var tasks []*Task
+	err := DB.Where("channel_id = ? AND status IN ? AND id > ?", channelId, statuses, afterId).
+		Order("id asc").Limit(limit).Find(&tasks).Error
+	return tasks, err
+}
+
+// FailIfStatusIn 仅当任务仍处于 statuses 中的某个未完成状态时以条件更新将其标记为失败，
+// 返回 false 表示任务状态已变化，调用方不应退款
+func (t *Task) FailIfStatusIn(statuses []TaskStatus, reason string, finishTime int64) (bool, error) {
+	return t.failIf(DB.Where("status IN ?", statuses), reason, finishTime)
+}
@@ -0,0 +1,64 @@
+package model
+
+import "testing"
+
+func TestGetTasksByChannelAndStatus(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "a", ChannelId: 7, Status: TaskStatusQueued},
+		{TaskID: "b", ChannelId: 7, Status: TaskStatusSubmitted},
+		{TaskID: "c", ChannelId: 7, Status: TaskStatusInProgress},
+		{TaskID: "d", ChannelId: 8, Status: TaskStatusQueued},
+		{TaskID: "e", ChannelId: 7, Status: TaskStatusSubmitted},
+	}
+	for _, task := range tasks {
+		if err := DB.Create(task).Error; err != nil {
+			t.Fatalf("create task: %v", err)
+		}
+	}
+
+	statuses := []TaskStatus{TaskStatusQueued, TaskStatusSubmitted}
+	page, err := GetTasksByChannelAndStatus(7, statuses, 0, 2)
+	if err != nil || len(page) != 2 || page[0].TaskID != "a" || page[1].TaskID != "b" {
+		t.Fatalf("first page = %+v, %v", page, err)
+	}
+	page, err = GetTasksByChannelAndStatus(7, statuses, page[1].ID, 2)
+	if err != nil || len(page) != 1 || page[0].TaskID != "e" {
+		t.Fatalf("second page = %+v, %v", page, err)
+	}
+}
+
+func TestFailIfStatusIn(t *testing.T) {
+	setupTestDB(t, &Task{})
+	task := &Task{TaskID: "a", Status: TaskStatusQueued, Quota: 100}
+	if err := DB.Create(task).Error; err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+
+	// 任务已离开指定状态时不会被取消
+	updated, err := task.FailIfStatusIn([]TaskStatus{TaskStatusSubmitted}, TaskCancelReasonAdmin, 1)
+	if err != nil || updated {
+		t.Fatalf("cancel with other status = %v, %v; want false", updated, err)
+	}
+	updated, err = task.FailIfStatusIn([]TaskStatus{TaskStatusQueued}, TaskCancelReasonAdmin, 1)
+	if err != nil || !updated {
+		t.Fatalf("cancel = %v, %v; want true", updated, err)
+	}
+	var stored Task
+	DB.First(&stored, task.ID)
+	if stored.Status != TaskStatusFailure || stored.Quota != 0 || stored.FailReason != TaskCancelReasonAdmin {
+		t.Fatalf("unexpected task after cancel: %+v", stored)
+	}
+}
+
+func TestValidateCancelTaskStatuses(t *testing.T) {
+	if err := ValidateCancelTaskStatuses(nil); err == nil {
+		t.Error("expected error for empty statuses")
+	}
+	if err := ValidateCancelTaskStatuses([]TaskStatus{TaskStatusQueued, TaskStatusSuccess}); err == nil {
+		t.Error("expected error when cancelling finished tasks")
+	}
+	if err := ValidateCancelTaskStatuses([]TaskStatus{TaskStatusQueued}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
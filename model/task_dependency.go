@@ -0,0 +1,79 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	TaskDependencyStatusPending   = "pending"
+	TaskDependencyStatusSubmitted = "submitted"
+	TaskDependencyStatusFailed    = "failed"
+)
+
+// TaskDependency 依赖上游任务产出的待提交任务。上游任务成功后，由后台任务将其产出链接写入请求体再提交
+type TaskDependency struct {
+	Id              int64  `json:"id"`
+	UserId          int    `json:"user_id" gorm:"index"`
+	TokenId         int    `json:"token_id"`
+	DependsOnTaskId string `json:"depends_on_task_id" gorm:"type:varchar(191);index"`
+	RequestPath     string `json:"request_path" gorm:"type:varchar(255)"`
+	RequestBody     string `json:"-" gorm:"type:text"`
+	ClientIp        string `json:"-" gorm:"type:varchar(64)"`
+	InputField      string `json:"-" gorm:"type:varchar(64)"` // 上游任务产出链接写入的请求字段，由下游任务的适配器决定
+	Status          string `json:"status" gorm:"type:varchar(20);index"`
+	// TaskId 下游任务提交成功后的任务 ID
+	TaskId     string `json:"task_id" gorm:"type:varchar(191)"`
+	FailReason string `json:"fail_reason" gorm:"type:text"`
+	CreatedAt  int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt  int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (d *TaskDependency) Insert() error {
+	now := common.GetTimestamp()
+	d.CreatedAt, d.UpdatedAt = now, now
+	if d.Status == "" {
+		d.Status = TaskDependencyStatusPending
+	}
+	return DB.Create(d).Error
+}
+
+// GetPendingTaskDependencies 按创建时间从早到晚返回待提交的依赖任务
+func GetPendingTaskDependencies(limit int) ([]*TaskDependency, error) {
+	var deps []*TaskDependency
+	err := DB.Where("status = ?", TaskDependencyStatusPending).Order("id asc").Limit(limit).Find(&deps).Error
+	return deps, err
+}
+
+// GetUserTaskDependency 查询用户的依赖任务
+func GetUserTaskDependency(userId int, id int64) (*TaskDependency, bool, error) {
+	var dep TaskDependency
+	result := DB.Where("id = ? AND user_id = ?", id, userId).Limit(1).Find(&dep)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	return &dep, result.RowsAffected > 0, nil
+}
+
+// Finish 将仍处于 pending 的依赖任务更新为终态，返回是否更新成功，避免多个节点重复提交
+func (d *TaskDependency) Finish(status string, taskId string, failReason string) (bool, error) {
+	now := common.GetTimestamp()
+	result := DB.Model(&TaskDependency{}).Where("id = ? AND status = ?", d.Id, TaskDependencyStatusPending).
+		Updates(map[string]any{"status": status, "task_id": taskId, "fail_reason": failReason, "updated_at": now})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		d.Status, d.TaskId, d.FailReason, d.UpdatedAt = status, taskId, failReason, now
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SetSubmitResult 记录下游任务的提交结果，failReason 非空时标记为失败
+func (d *TaskDependency) SetSubmitResult(taskId string, failReason string) error {
+	updates := map[string]any{"task_id": taskId, "updated_at": common.GetTimestamp()}
+	if failReason != "" {
+		updates["status"] = TaskDependencyStatusFailed
+		updates["fail_reason"] = failReason
+	}
+	return DB.Model(&TaskDependency{}).Where("id = ?", d.Id).Updates(updates).Error
+}
@@ -0,0 +1,37 @@
+package model
+
+import "testing"
+
+func TestTaskDependencyFinish(t *testing.T) {
+	setupTestDB(t, &TaskDependency{})
+	dep := &TaskDependency{UserId: 1, DependsOnTaskId: "task_a", RequestBody: `{"prompt":"next"}`}
+	if err := dep.Insert(); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	pending, err := GetPendingTaskDependencies(10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %d err = %v", len(pending), err)
+	}
+
+	claimed, err := dep.Finish(TaskDependencyStatusSubmitted, "", "")
+	if err != nil || !claimed {
+		t.Fatalf("first Finish claimed = %v err = %v", claimed, err)
+	}
+	// 已离开 pending 的依赖不能被再次抢占
+	if claimed, _ = (&TaskDependency{Id: dep.Id}).Finish(TaskDependencyStatusSubmitted, "", ""); claimed {
+		t.Fatal("second Finish should not claim the dependency")
+	}
+	if err := dep.SetSubmitResult("", "upstream rejected"); err != nil {
+		t.Fatalf("SetSubmitResult: %v", err)
+	}
+	got, exist, err := GetUserTaskDependency(1, dep.Id)
+	if err != nil || !exist {
+		t.Fatalf("GetUserTaskDependency exist = %v err = %v", exist, err)
+	}
+	if got.Status != TaskDependencyStatusFailed || got.FailReason != "upstream rejected" {
+		t.Fatalf("unexpected dependency: %+v", got)
+	}
+	if _, exist, _ = GetUserTaskDependency(2, dep.Id); exist {
+		t.Fatal("other users should not see the dependency")
+	}
+}
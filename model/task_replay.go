@@ -0,0 +1,67 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// MaxTaskReplays 每个原始任务最多可重放的次数
+const MaxTaskReplays = 3
+
+// taskRequestPruneBatchSize 每批清除请求体的任务数
+const taskRequestPruneBatchSize = 500
+
+// ReplayRootTaskID 返回重放所对应的原始任务 ID，重放产生的任务指向其 parent_task_id
+func (t *Task) ReplayRootTaskID() string {
+	if t.ParentTaskID != "" {
+		return t.ParentTaskID
+	}
+	return t.TaskID
+}
+
+// CanReplay 判断任务是否保存了可用于重放的请求
+func (t *Task) CanReplay() bool {
+	return t.RequestPath != "" && len(t.RequestBody) > 0
+}
+
+// ReserveTaskReplay 以条件更新占用原始任务的一个重放名额，名额已用完时返回 false
+func ReserveTaskReplay(userId int, rootTaskId string) (bool, error) {
+	result := DB.Model(&Task{}).
+		Where("user_id = ? AND task_id = ? AND replay_count < ?", userId, rootTaskId, MaxTaskReplays).
+		Update("replay_count", gorm.Expr("replay_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseTaskReplay 重放提交失败时归还占用的名额
+func ReleaseTaskReplay(userId int, rootTaskId string) error {
+	return DB.Model(&Task{}).
+		Where("user_id = ? AND task_id = ? AND replay_count > 0", userId, rootTaskId).
+		Update("replay_count", gorm.Expr("replay_count - 1")).Error
+}
+
+// PruneTaskRequestBodies 清除不再需要重放的任务保存的请求体：成功的任务立即清除，
+// 失败的任务在 failedBefore 之前结束的才清除。返回清除的任务数
+func PruneTaskRequestBodies(failedBefore int64) (int64, error) {
+	var total int64
+	for {
+		var ids []int64
+		err := DB.Model(&Task{}).
+			Where("request_body IS NOT NULL").
+			Where("status = ? OR (status = ? AND finish_time > 0 AND finish_time < ?)", TaskStatusSuccess, TaskStatusFailure, failedBefore).
+			Limit(taskRequestPruneBatchSize).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return total, err
+		}
+		result := DB.Model(&Task{}).Where("id IN ?", ids).Updates(map[string]any{
+			"request_path": "",
+			"request_body": nil,
+		})
+		if result.Error != nil {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < taskRequestPruneBatchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReserveTaskReplay(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "origin", UserId: 1, Status: TaskStatusFailure, RequestPath: "/v1/video/generations", RequestBody: json.RawMessage(`{"model":"m","prompt":"p"}`)},
+		{TaskID: "replay-1", UserId: 1, ParentTaskID: "origin"},
+	}
+	if err := DB.Create(&tasks).Error; err != nil {
+		t.Fatalf("create tasks failed: %v", err)
+	}
+	if got := tasks[1].ReplayRootTaskID(); got != "origin" {
+		t.Fatalf("ReplayRootTaskID of replay = %q, want origin", got)
+	}
+	if got := tasks[0].ReplayRootTaskID(); got != "origin" {
+		t.Fatalf("ReplayRootTaskID of origin = %q, want origin", got)
+	}
+
+	if ok, err := ReserveTaskReplay(2, "origin"); err != nil || ok {
+		t.Fatalf("other user reserved a replay: %v, %v", ok, err)
+	}
+	for i := 0; i < MaxTaskReplays; i++ {
+		if ok, err := ReserveTaskReplay(1, "origin"); err != nil || !ok {
+			t.Fatalf("reserve #%d = %v, %v", i+1, ok, err)
+		}
+	}
+	if ok, _ := ReserveTaskReplay(1, "origin"); ok {
+		t.Fatal("expected replay limit to be enforced")
+	}
+	if err := ReleaseTaskReplay(1, "origin"); err != nil {
+		t.Fatalf("ReleaseTaskReplay: %v", err)
+	}
+	if ok, _ := ReserveTaskReplay(1, "origin"); !ok {
+		t.Fatal("expected a released slot to be reusable")
+	}
+
+	stored, exist, err := GetByTaskId(1, "origin")
+	if err != nil || !exist {
+		t.Fatalf("GetByTaskId failed: %v", err)
+	}
+	if !stored.CanReplay() {
+		t.Fatal("expected stored request to be replayable")
+	}
+	if tasks[1].CanReplay() {
+		t.Fatal("expected task without stored request to be not replayable")
+	}
+}
+
+func TestPruneTaskRequestBodies(t *testing.T) {
+	setupTestDB(t, &Task{})
+	body := json.RawMessage(`{"model":"m","prompt":"p"}`)
+	tasks := []*Task{
+		{TaskID: "success", Status: TaskStatusSuccess, FinishTime: 900, RequestPath: "/v1/videos", RequestBody: body},
+		{TaskID: "old-failure", Status: TaskStatusFailure, FinishTime: 100, RequestPath: "/v1/videos", RequestBody: body},
+		{TaskID: "recent-failure", Status: TaskStatusFailure, FinishTime: 900, RequestPath: "/v1/videos", RequestBody: body},
+		{TaskID: "running", Status: TaskStatusInProgress, RequestPath: "/v1/videos", RequestBody: body},
+	}
+	if err := DB.Create(&tasks).Error; err != nil {
+		t.Fatalf("create tasks failed: %v", err)
+	}
+	count, err := PruneTaskRequestBodies(500)
+	if err != nil || count != 2 {
+		t.Fatalf("PruneTaskRequestBodies = %d, %v, want 2", count, err)
+	}
+	want := map[string]bool{"success": false, "old-failure": false, "recent-failure": true, "running": true}
+	for taskId, replayable := range want {
+		var task Task
+		if err := DB.Where("task_id = ?", taskId).First(&task).Error; err != nil {
+			t.Fatalf("reload %s: %v", taskId, err)
+		}
+		if task.CanReplay() != replayable {
+			t.Errorf("%s CanReplay = %v, want %v", taskId, task.CanReplay(), replayable)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	MaxTaskTags      = 10
+	MaxTaskTagLength = 32
+)
+
+var taskTagPattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
+// TaskTags 用户为任务设置的标签，以 JSON 数组存储
+type TaskTags []string
+
+func (t *TaskTags) Scan(val interface{}) error {
+	var bytesValue []byte
+	switch v := val.(type) {
+	case []byte:
+		bytesValue = v
+	case string:
+		bytesValue = []byte(v)
+	}
+	if len(bytesValue) == 0 {
+		*t = nil
+		return nil
+	}
+	return json.Unmarshal(bytesValue, t)
+}
+
+func (t TaskTags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(t)
+}
+
+func (TaskTags) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType PostgreSQL 使用 jsonb 以支持 GIN 索引，其他数据库使用 json
+func (TaskTags) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "jsonb"
+	}
+	return "json"
+}
+
+// NormalizeTaskTags 校验并去重标签：只允许字母、数字与连字符，每个不超过 32 个字符，最多 10 个
+func NormalizeTaskTags(tags []string) (TaskTags, error) {
+	normalized := make(TaskTags, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if len(tag) == 0 || len(tag) > MaxTaskTagLength || !taskTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: tags must be 1-%d characters of letters, digits or hyphens", tag, MaxTaskTagLength)
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTaskTags {
+		return nil, fmt.Errorf("a task can have at most %d tags", MaxTaskTags)
+	}
+	return normalized, nil
+}
+
+// whereTaskHasTags 过滤包含全部指定标签的任务
+func whereTaskHasTags(query *gorm.DB, tags []string) *gorm.DB {
+	if len(tags) == 0 {
+		return query
+	}
+	if common.UsingPostgreSQL {
+		data, _ := json.Marshal(tags)
+		return query.Where("tags @> ?::jsonb", string(data))
+	}
+	if common.UsingMySQL {
+		data, _ := json.Marshal(tags)
+		return query.Where("JSON_CONTAINS(tags, ?)", string(data))
+	}
+	for _, tag := range tags {
+		query = query.Where("EXISTS (SELECT 1 FROM json_each(tasks.tags) WHERE json_each.value = ?)", tag)
+	}
+	return query
+}
+
+// UpdateUserTaskTags 替换用户任务的标签，任务不存在或不属于该用户时返回 gorm.ErrRecordNotFound
+func UpdateUserTaskTags(userId int, taskId string, tags TaskTags) (*Task, error) {
+	task, exist, err := GetByTaskId(userId, taskId)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := DB.Model(task).Update("tags", tags).Error; err != nil {
+		return nil, err
+	}
+	task.Tags = tags
+	return task, nil
+}
+
+// migrateTaskTagsIndex 为 PostgreSQL 的 tags 列创建 GIN 索引，MySQL 与 SQLite 的 JSON 列无法直接建立普通索引
+func migrateTaskTagsIndex() error {
+	if !common.UsingPostgreSQL {
+		return nil
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_tasks_tags ON tasks USING GIN (tags)").Error; err != nil {
+		return fmt.Errorf("failed to create task tags index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestNormalizeTaskTags(t *testing.T) {
+	tags, err := NormalizeTaskTags([]string{"project-alpha", "demo", "demo"})
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("expected deduplicated tags, got %v, err %v", tags, err)
+	}
+	invalid := [][]string{
+		{"has space"},
+		{"under_score"},
+		{""},
+		{strings.Repeat("a", MaxTaskTagLength+1)},
+		{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"},
+	}
+	for _, tags := range invalid {
+		if _, err := NormalizeTaskTags(tags); err == nil {
+			t.Errorf("expected error for %v", tags)
+		}
+	}
+}
+
+func TestGetTasksByUserFilterByTags(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "t1", UserId: 1, SubmitTime: 1, Tags: TaskTags{"project-alpha", "demo"}},
+		{TaskID: "t2", UserId: 1, SubmitTime: 2, Tags: TaskTags{"project-alpha"}},
+		{TaskID: "t3", UserId: 1, SubmitTime: 3},
+		{TaskID: "t4", UserId: 2, SubmitTime: 4, Tags: TaskTags{"demo"}},
+	}
+	if err := DB.Create(&tasks).Error; err != nil {
+		t.Fatalf("create tasks failed: %v", err)
+	}
+
+	taskIds := func(tags ...string) []string {
+		found, err := GetTasksByUser(1, SyncTaskQueryParams{Tags: tags}, nil, 10)
+		if err != nil {
+			t.Fatalf("GetTasksByUser: %v", err)
+		}
+		ids := make([]string, 0, len(found))
+		for _, task := range found {
+			ids = append(ids, task.TaskID)
+		}
+		return ids
+	}
+	if ids := taskIds("project-alpha"); strings.Join(ids, ",") != "t2,t1" {
+		t.Fatalf("project-alpha: got %v", ids)
+	}
+	if ids := taskIds("project-alpha", "demo"); strings.Join(ids, ",") != "t1" {
+		t.Fatalf("project-alpha+demo: got %v", ids)
+	}
+	if ids := taskIds(); len(ids) != 3 {
+		t.Fatalf("no filter: got %v", ids)
+	}
+
+	task, err := UpdateUserTaskTags(1, "t3", TaskTags{"production"})
+	if err != nil || len(task.Tags) != 1 {
+		t.Fatalf("update tags failed: %+v, %v", task, err)
+	}
+	if ids := taskIds("production"); strings.Join(ids, ",") != "t3" {
+		t.Fatalf("production: got %v", ids)
+	}
+	if _, err := UpdateUserTaskTags(1, "t4", TaskTags{"demo"}); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("expected not found for another user's task, got %v", err)
+	}
+}
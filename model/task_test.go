@@ -0,0 +1,267 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestTaskIsExpiredBoundary(t *testing.T) {
+	task := &Task{
+		SubmitTime: 1_000_000,
+		Properties: Properties{ExecutionExpiresAfterSec: 60},
+	}
+	if got := task.ExpiresAt(); got != 1_000_060 {
+		t.Fatalf("expected expires_at 1000060, got %d", got)
+	}
+	cases := []struct {
+		now  int64
+		want bool
+	}{
+		{now: 1_000_059, want: false},
+		{now: 1_000_060, want: false},
+		{now: 1_000_061, want: true},
+	}
+	for _, tc := range cases {
+		if got := task.IsExpired(tc.now); got != tc.want {
+			t.Errorf("IsExpired(%d) = %v, want %v", tc.now, got, tc.want)
+		}
+	}
+}
+
+func TestTaskIsExpiredWithoutDeadline(t *testing.T) {
+	task := &Task{SubmitTime: 1_000_000}
+	if task.ExpiresAt() != 0 {
+		t.Fatalf("expected no expires_at, got %d", task.ExpiresAt())
+	}
+	if task.IsExpired(2_000_000) {
+		t.Fatal("task without execution_expires_after must not expire on its own")
+	}
+}
+
+func TestGetPendingTasksByPriorityAndChannel(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "normal-old", ChannelId: 1, Status: TaskStatusInProgress, SubmitTime: 100},
+		{TaskID: "critical", ChannelId: 1, Status: TaskStatusSubmitted, SubmitTime: 300, Priority: 2},
+		{TaskID: "high", ChannelId: 1, Status: TaskStatusQueued, SubmitTime: 200, Priority: 1},
+		{TaskID: "normal-new", ChannelId: 1, Status: TaskStatusInProgress, SubmitTime: 150},
+		{TaskID: "done", ChannelId: 1, Status: TaskStatusSuccess, SubmitTime: 50, Priority: 2},
+		{TaskID: "other-channel", ChannelId: 2, Status: TaskStatusInProgress, SubmitTime: 10, Priority: 2},
+	}
+	for _, task := range tasks {
+		if err := task.Insert(); err != nil {
+			t.Fatalf("insert task: %v", err)
+		}
+	}
+
+	got, err := GetPendingTasksByPriorityAndChannel(1, []string{"normal-old", "critical", "high", "normal-new", "done", "other-channel"})
+	if err != nil {
+		t.Fatalf("GetPendingTasksByPriorityAndChannel: %v", err)
+	}
+	want := []string{"critical", "high", "normal-old", "normal-new"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d tasks, want %d", len(got), len(want))
+	}
+	for i, task := range got {
+		if task.TaskID != want[i] {
+			t.Errorf("position %d = %s, want %s", i, task.TaskID, want[i])
+		}
+	}
+
+	updated, err := UpdateTaskPriority(tasks[0].ID, 2)
+	if err != nil || !updated {
+		t.Fatalf("UpdateTaskPriority pending task = %v, %v", updated, err)
+	}
+	updated, err = UpdateTaskPriority(tasks[4].ID, 0)
+	if err != nil || updated {
+		t.Fatalf("UpdateTaskPriority finished task = %v, %v, want not updated", updated, err)
+	}
+}
+
+func TestGetStaleUnfinishedTasksAndFail(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "stale-queued", Status: TaskStatusQueued, SubmitTime: 100, Quota: 10},
+		{TaskID: "stale-submitted", Status: TaskStatusSubmitted, SubmitTime: 50, Quota: 10},
+		{TaskID: "fresh", Status: TaskStatusInProgress, SubmitTime: 900},
+		{TaskID: "done", Status: TaskStatusSuccess, SubmitTime: 10},
+		{TaskID: "not-submitted", Status: TaskStatusNotStart},
+	}
+	for _, task := range tasks {
+		if err := task.Insert(); err != nil {
+			t.Fatalf("insert task: %v", err)
+		}
+	}
+
+	got, err := GetStaleUnfinishedTasks(500, 10)
+	if err != nil {
+		t.Fatalf("GetStaleUnfinishedTasks: %v", err)
+	}
+	if len(got) != 2 || got[0].TaskID != "stale-submitted" || got[1].TaskID != "stale-queued" {
+		t.Fatalf("unexpected stale tasks: %+v", got)
+	}
+
+	failed, err := got[0].FailIfUnfinished("timed out", 1000)
+	if err != nil || !failed {
+		t.Fatalf("FailIfUnfinished = %v, %v", failed, err)
+	}
+	if got[0].Status != TaskStatusFailure || got[0].Quota != 0 || got[0].FinishTime != 1000 {
+		t.Errorf("task not updated in place: %+v", got[0])
+	}
+	// 已是终态的任务不会被再次更新
+	failed, err = got[0].FailIfUnfinished("timed out", 1000)
+	if err != nil || failed {
+		t.Fatalf("second FailIfUnfinished = %v, %v, want not updated", failed, err)
+	}
+	task, _, _ := GetByOnlyTaskId("stale-submitted")
+	if task.Status != TaskStatusFailure || task.Quota != 0 || task.FailReason != "timed out" {
+		t.Errorf("task not persisted: %+v", task)
+	}
+}
+
+func TestGetTasksByUserCursor(t *testing.T) {
+	setupTestDB(t, &Task{})
+	tasks := []*Task{
+		{TaskID: "a", UserId: 1, Status: TaskStatusSuccess, Platform: "runway", SubmitTime: 100},
+		{TaskID: "b", UserId: 1, Status: TaskStatusFailure, Platform: "runway", SubmitTime: 200},
+		{TaskID: "c", UserId: 1, Status: TaskStatusSuccess, Platform: "runway", SubmitTime: 200},
+		{TaskID: "d", UserId: 1, Status: TaskStatusSuccess, Platform: "suno", SubmitTime: 300},
+		{TaskID: "other-user", UserId: 2, Status: TaskStatusSuccess, Platform: "runway", SubmitTime: 250},
+	}
+	for _, task := range tasks {
+		if err := task.Insert(); err != nil {
+			t.Fatalf("insert task: %v", err)
+		}
+	}
+
+	var got []string
+	var cursor *TaskCursor
+	for {
+		page, err := GetTasksByUser(1, SyncTaskQueryParams{}, cursor, 2)
+		if err != nil {
+			t.Fatalf("GetTasksByUser: %v", err)
+		}
+		for _, task := range page {
+			got = append(got, task.TaskID)
+		}
+		if len(page) < 2 {
+			break
+		}
+		last := page[len(page)-1]
+		encoded := TaskCursor{SubmitTime: last.SubmitTime, ID: last.ID}.Encode()
+		if cursor, err = ParseTaskCursor(encoded); err != nil {
+			t.Fatalf("ParseTaskCursor: %v", err)
+		}
+	}
+	want := []string{"d", "c", "b", "a"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	filtered, err := GetTasksByUser(1, SyncTaskQueryParams{Platform: "runway", Status: string(TaskStatusSuccess), StartTimestamp: 150}, nil, 10)
+	if err != nil || len(filtered) != 1 || filtered[0].TaskID != "c" {
+		t.Fatalf("filtered tasks = %v, %v", filtered, err)
+	}
+
+	if _, err := ParseTaskCursor("not-a-cursor"); err == nil {
+		t.Fatal("expected invalid cursor error")
+	}
+}
+
+func TestGetAllUnFinishSyncTasksSkipsNotDue(t *testing.T) {
+	setupTestDB(t, &Task{})
+	now := time.Now().Unix()
+	tasks := []*Task{
+		{TaskID: "never-polled", Status: TaskStatusSubmitted, Progress: "10%"},
+		{TaskID: "due", Status: TaskStatusInProgress, Progress: "30%", NextPollAt: now - 1},
+		{TaskID: "not-due", Status: TaskStatusInProgress, Progress: "30%", NextPollAt: now + 60},
+		{TaskID: "done", Status: TaskStatusSuccess, Progress: "100%"},
+	}
+	for _, task := range tasks {
+		if err := task.Insert(); err != nil {
+			t.Fatalf("insert task: %v", err)
+		}
+	}
+	got := GetAllUnFinishSyncTasks(10)
+	if len(got) != 2 || got[0].TaskID != "never-polled" || got[1].TaskID != "due" {
+		t.Fatalf("unexpected due tasks: %+v", got)
+	}
+}
+
+func TestTaskJSONFieldUpdatesKeepConcurrentChanges(t *testing.T) {
+	setupTestDB(t, &Task{})
+	task := &Task{TaskID: "json-fields", Status: TaskStatusSuccess}
+	if err := task.Insert(); err != nil {
+		t.Fatalf("insert task: %v", err)
+	}
+	// 两个副本模拟质量评分与缩略图提取各自持有的旧任务对象
+	scorer, thumbnailer := *task, *task
+	if err := scorer.UpdateQualityScores(&dto.VideoQualityScores{Motion: 80}); err != nil {
+		t.Fatalf("UpdateQualityScores: %v", err)
+	}
+	if err := thumbnailer.UpdateThumbnailURL("https://cdn.example.com/thumb.jpg"); err != nil {
+		t.Fatalf("UpdateThumbnailURL: %v", err)
+	}
+	uploader, extractor := *task, *task
+	if err := uploader.UpdateStorageKey("tasks/1/video.mp4"); err != nil {
+		t.Fatalf("UpdateStorageKey: %v", err)
+	}
+	if err := extractor.UpdateAudioStorageKey("tasks/1/audio.mp3"); err != nil {
+		t.Fatalf("UpdateAudioStorageKey: %v", err)
+	}
+
+	var stored Task
+	if err := DB.First(&stored, task.ID).Error; err != nil {
+		t.Fatalf("reload task: %v", err)
+	}
+	if stored.Properties.QualityScores == nil || stored.Properties.ThumbnailURL == "" {
+		t.Fatalf("properties lost a concurrent update: %+v", stored.Properties)
+	}
+	if stored.PrivateData.StorageKey == "" || stored.PrivateData.AudioStorageKey == "" {
+		t.Fatalf("private_data lost a concurrent update: %+v", stored.PrivateData)
+	}
+}
+
+func TestChargeAudioExtractionIsAtomic(t *testing.T) {
+	setupTestDB(t, &Task{}, &User{})
+	if err := DB.Create(&User{Id: 1, Username: "audio", Quota: 500}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	task := &Task{TaskID: "task_audio", UserId: 1, Status: TaskStatusSuccess}
+	if err := task.Insert(); err != nil {
+		t.Fatalf("insert task failed: %v", err)
+	}
+	userQuota := func() int {
+		var user User
+		DB.First(&user, 1)
+		return user.Quota
+	}
+
+	// 余额不足时既不扣费也不标记
+	if charged, err := task.ChargeAudioExtraction(1000); !errors.Is(err, ErrQuotaNotEnough) || charged {
+		t.Fatalf("expected ErrQuotaNotEnough, got charged=%v err=%v", charged, err)
+	}
+	var reloaded Task
+	DB.First(&reloaded, task.ID)
+	if reloaded.PrivateData.AudioExtractionCharged || userQuota() != 500 {
+		t.Fatalf("failed charge should leave the flag and quota unchanged, flag=%v quota=%d", reloaded.PrivateData.AudioExtractionCharged, userQuota())
+	}
+
+	if charged, err := task.ChargeAudioExtraction(300); err != nil || !charged {
+		t.Fatalf("expected charge to succeed, got charged=%v err=%v", charged, err)
+	}
+	if charged, err := task.ChargeAudioExtraction(300); err != nil || charged {
+		t.Fatalf("expected second charge to be skipped, got charged=%v err=%v", charged, err)
+	}
+	if got := userQuota(); got != 200 {
+		t.Fatalf("expected quota 200 after a single charge, got %d", got)
+	}
+}
@@ -26,7 +26,9 @@ type Token struct {
 	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
+	CrossGroupRetry    bool           `json:"cross_group_retry"`                // 跨分组重试，仅auto分组有效
+	AllowedOrigins     string         `json:"allowed_origins" gorm:"type:text"` // 浏览器跨域允许的 Origin，JSON 数组，为空时使用全局 CORS 策略
+	Scopes             string         `json:"scopes" gorm:"type:text"`          // 令牌权限范围，JSON 数组，为空时拥有全部权限
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -56,6 +58,102 @@ func (token *Token) GetIpLimits() []string {
 	return ipLimits
 }
 
+// UpdateTokenAllowIps 更新令牌允许访问的 IP 列表（单个 IP 或 CIDR），ips 为空时不限制来源 IP
+func UpdateTokenAllowIps(token *Token, ips []string) error {
+	allowIps := strings.Join(ips, "\n")
+	token.AllowIps = &allowIps
+	if err := DB.Model(token).Update("allow_ips", allowIps).Error; err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		if err := cacheSetToken(*token); err != nil {
+			common.SysLog("failed to update token cache: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// GetAllowedOrigins 解析令牌允许的跨域来源，格式错误时视为未配置
+func (token *Token) GetAllowedOrigins() []string {
+	if strings.TrimSpace(token.AllowedOrigins) == "" {
+		return nil
+	}
+	var origins []string
+	if err := common.UnmarshalJsonStr(token.AllowedOrigins, &origins); err != nil {
+		return nil
+	}
+	return origins
+}
+
+// IsOriginAllowed 判断 Origin 是否在令牌允许的跨域来源中，"*" 表示允许任意来源
+func (token *Token) IsOriginAllowed(origin string) bool {
+	origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+	for _, allowed := range token.GetAllowedOrigins() {
+		if allowed == "*" || strings.TrimSuffix(strings.ToLower(allowed), "/") == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateTokenAllowedOrigins 更新令牌允许的跨域来源，origins 为空时恢复使用全局 CORS 策略
+func UpdateTokenAllowedOrigins(id int, origins []string) (*Token, error) {
+	token, err := GetTokenById(id)
+	if err != nil {
+		return nil, err
+	}
+	token.AllowedOrigins = ""
+	if len(origins) > 0 {
+		data, err := common.Marshal(origins)
+		if err != nil {
+			return nil, err
+		}
+		token.AllowedOrigins = string(data)
+	}
+	if err := DB.Model(token).Update("allowed_origins", token.AllowedOrigins).Error; err != nil {
+		return nil, err
+	}
+	if common.RedisEnabled {
+		if err := cacheSetToken(*token); err != nil {
+			common.SysLog("failed to update token cache: " + err.Error())
+		}
+	}
+	return token, nil
+}
+
+// GetScopes 解析令牌的权限范围，未配置或格式错误时返回 nil，表示拥有全部权限
+func (token *Token) GetScopes() []string {
+	if strings.TrimSpace(token.Scopes) == "" {
+		return nil
+	}
+	var scopes []string
+	if err := common.UnmarshalJsonStr(token.Scopes, &scopes); err != nil {
+		return nil
+	}
+	return scopes
+}
+
+// UpdateTokenScopes 更新令牌的权限范围，scopes 为空时恢复为全部权限
+func UpdateTokenScopes(token *Token, scopes []string) error {
+	token.Scopes = ""
+	if len(scopes) > 0 {
+		data, err := common.Marshal(scopes)
+		if err != nil {
+			return err
+		}
+		token.Scopes = string(data)
+	}
+	if err := DB.Model(token).Update("scopes", token.Scopes).Error; err != nil {
+		return err
+	}
+	if common.RedisEnabled {
+		if err := cacheSetToken(*token); err != nil {
+			common.SysLog("failed to update token cache: " + err.Error())
+		}
+	}
+	return nil
+}
+
 func GetAllUserTokens(userId int, startIdx int, num int) ([]*Token, error) {
 	var tokens []*Token
 	var err error
@@ -367,3 +465,31 @@ func BatchDeleteTokens(ids []int, userId int) (int, error) {
 
 	return len(tokens), nil
 }
+
+// InvalidateToken 原子地禁用令牌并同步清除其 Redis 缓存（含缓存中的剩余额度），用于令牌泄露后的紧急作废。
+// userId 为 0 时不校验令牌归属；changed 表示本次调用是否将令牌从非禁用状态改为禁用
+func InvalidateToken(id int, userId int) (token *Token, changed bool, err error) {
+	if id == 0 {
+		return nil, false, errors.New("id 为空！")
+	}
+	token = &Token{}
+	query := DB.Where("id = ?", id)
+	if userId != 0 {
+		query = query.Where("user_id = ?", userId)
+	}
+	if err = query.First(token).Error; err != nil {
+		return nil, false, err
+	}
+	result := DB.Model(&Token{}).Where("id = ? AND status <> ?", token.Id, common.TokenStatusDisabled).
+		Update("status", common.TokenStatusDisabled)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	token.Status = common.TokenStatusDisabled
+	if common.RedisEnabled {
+		if err := cacheDeleteToken(token.Key); err != nil {
+			common.SysLog("failed to delete token cache: " + err.Error())
+		}
+	}
+	return token, result.RowsAffected > 0, nil
+}
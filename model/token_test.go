@@ -0,0 +1,105 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestInvalidateToken(t *testing.T) {
+	setupTestDB(t, &Token{})
+	originRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = originRedis })
+	token := &Token{UserId: 1, Key: "leaked", Name: "t1", Status: common.TokenStatusEnabled}
+	if err := DB.Create(token).Error; err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	if _, _, err := InvalidateToken(token.Id, 2); err == nil {
+		t.Fatal("invalidating another user's token should fail")
+	}
+
+	got, changed, err := InvalidateToken(token.Id, 1)
+	if err != nil {
+		t.Fatalf("InvalidateToken: %v", err)
+	}
+	if !changed || got.Status != common.TokenStatusDisabled {
+		t.Fatalf("changed = %v status = %d", changed, got.Status)
+	}
+	var stored Token
+	DB.First(&stored, token.Id)
+	if stored.Status != common.TokenStatusDisabled {
+		t.Fatalf("stored status = %d", stored.Status)
+	}
+
+	// 重复作废保持幂等，且不视为状态变更
+	if _, changed, err = InvalidateToken(token.Id, 0); err != nil || changed {
+		t.Fatalf("second InvalidateToken changed = %v err = %v", changed, err)
+	}
+}
+
+func TestTokenAllowedOrigins(t *testing.T) {
+	setupTestDB(t, &Token{})
+	originRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = originRedis })
+	token := &Token{UserId: 1, Key: "browser", Name: "web", Status: common.TokenStatusEnabled}
+	if err := DB.Create(token).Error; err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	if token.IsOriginAllowed("https://app.example.com") || token.GetAllowedOrigins() != nil {
+		t.Fatal("token without allowed_origins should not match any origin")
+	}
+
+	updated, err := UpdateTokenAllowedOrigins(token.Id, []string{"https://app.example.com", "http://localhost:3000"})
+	if err != nil {
+		t.Fatalf("UpdateTokenAllowedOrigins: %v", err)
+	}
+	for origin, want := range map[string]bool{
+		"https://app.example.com":  true,
+		"https://APP.example.com/": true,
+		"http://localhost:3000":    true,
+		"https://evil.example.com": false,
+		"http://app.example.com":   false,
+	} {
+		if got := updated.IsOriginAllowed(origin); got != want {
+			t.Errorf("IsOriginAllowed(%s) = %v, want %v", origin, got, want)
+		}
+	}
+
+	if _, err := UpdateTokenAllowedOrigins(token.Id, nil); err != nil {
+		t.Fatalf("clear allowed origins: %v", err)
+	}
+	stored, _ := GetTokenById(token.Id)
+	if stored.AllowedOrigins != "" {
+		t.Fatalf("allowed_origins = %q, want empty", stored.AllowedOrigins)
+	}
+}
+
+func TestUpdateTokenAllowIps(t *testing.T) {
+	setupTestDB(t, &Token{})
+	originRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = originRedis })
+	token := &Token{UserId: 1, Key: "pipeline", Name: "ci", Status: common.TokenStatusEnabled}
+	if err := DB.Create(token).Error; err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+
+	if err := UpdateTokenAllowIps(token, []string{"10.0.0.0/8", "192.168.1.10"}); err != nil {
+		t.Fatalf("UpdateTokenAllowIps: %v", err)
+	}
+	stored, _ := GetTokenById(token.Id)
+	if limits := stored.GetIpLimits(); len(limits) != 2 || limits[0] != "10.0.0.0/8" || limits[1] != "192.168.1.10" {
+		t.Fatalf("ip limits = %v", limits)
+	}
+
+	if err := UpdateTokenAllowIps(token, nil); err != nil {
+		t.Fatalf("clear allow ips: %v", err)
+	}
+	stored, _ = GetTokenById(token.Id)
+	if len(stored.GetIpLimits()) != 0 {
+		t.Fatalf("ip limits = %v, want empty", stored.GetIpLimits())
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	UserModelStatusPending  = 1
+	UserModelStatusApproved = 2
+	UserModelStatusRejected = 3
+)
+
+// userModelCacheTTL 多节点部署时其他节点的修改最迟在该时间后生效
+const userModelCacheTTL = time.Minute
+
+// UserModel 用户注册的自定义模型（如 Replicate 微调版本、Fal 私有模型）：请求 Alias 时固定使用 ChannelId 渠道，
+// 模型名在渠道 model_mapping 之前替换为 UpstreamModelId，并按 PriceOverride 按次计费
+type UserModel struct {
+	Id              int     `json:"id"`
+	UserId          int     `json:"user_id" gorm:"not null;uniqueIndex:idx_user_model_alias"`
+	Alias           string  `json:"alias" gorm:"type:varchar(128);not null;uniqueIndex:idx_user_model_alias"`
+	ChannelId       int     `json:"channel_id" gorm:"not null"`
+	UpstreamModelId string  `json:"upstream_model_id" gorm:"type:varchar(255);not null"`
+	PriceOverride   float64 `json:"price_override" gorm:"default:0"`
+	Status          int     `json:"status" gorm:"default:1;index"`
+	CreatedTime     int64   `json:"created_time" gorm:"bigint"`
+	UpdatedTime     int64   `json:"updated_time" gorm:"bigint"`
+}
+
+func (m *UserModel) Insert() error {
+	now := common.GetTimestamp()
+	m.CreatedTime = now
+	m.UpdatedTime = now
+	err := DB.Create(m).Error
+	InvalidateUserModelCache()
+	return err
+}
+
+// IsUserModelAliasTaken 检查用户是否已注册同名别名
+func IsUserModelAliasTaken(userId int, alias string) (bool, error) {
+	var cnt int64
+	err := DB.Model(&UserModel{}).Where("user_id = ? AND alias = ?", userId, alias).Count(&cnt).Error
+	return cnt > 0, err
+}
+
+func GetUserModelById(id int) (*UserModel, error) {
+	var m UserModel
+	if err := DB.First(&m, id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func GetUserModelsByUserId(userId int) ([]*UserModel, error) {
+	var models []*UserModel
+	err := DB.Where("user_id = ?", userId).Order("id desc").Find(&models).Error
+	return models, err
+}
+
+// GetUserModels 按状态分页查询所有用户的自定义模型，status 为 0 时不过滤
+func GetUserModels(status int, startIdx int, num int) ([]*UserModel, int64, error) {
+	var models []*UserModel
+	var total int64
+	tx := DB.Model(&UserModel{})
+	if status != 0 {
+		tx = tx.Where("status = ?", status)
+	}
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := tx.Order("id desc").Offset(startIdx).Limit(num).Find(&models).Error
+	return models, total, err
+}
+
+func UpdateUserModelStatus(id int, status int) error {
+	err := DB.Model(&UserModel{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       status,
+		"updated_time": common.GetTimestamp(),
+	}).Error
+	InvalidateUserModelCache()
+	return err
+}
+
+// DeleteUserModel 删除用户自己的自定义模型
+func DeleteUserModel(id int, userId int) (bool, error) {
+	result := DB.Where("id = ? AND user_id = ?", id, userId).Delete(&UserModel{})
+	InvalidateUserModelCache()
+	return result.RowsAffected > 0, result.Error
+}
+
+var userModelCache struct {
+	sync.RWMutex
+	loadedAt time.Time
+	models   map[int]map[string]*UserModel
+}
+
+func InvalidateUserModelCache() {
+	userModelCache.Lock()
+	userModelCache.models = nil
+	userModelCache.Unlock()
+}
+
+// GetApprovedUserModel 返回用户已审核通过的自定义模型，已审核的模型缓存在内存中，避免每次请求查询数据库
+func GetApprovedUserModel(userId int, alias string) (*UserModel, bool, error) {
+	userModelCache.RLock()
+	models, loadedAt := userModelCache.models, userModelCache.loadedAt
+	userModelCache.RUnlock()
+	if models == nil || time.Since(loadedAt) > userModelCacheTTL {
+		var err error
+		if models, err = loadApprovedUserModels(); err != nil {
+			return nil, false, err
+		}
+	}
+	m, ok := models[userId][alias]
+	return m, ok, nil
+}
+
+func loadApprovedUserModels() (map[int]map[string]*UserModel, error) {
+	var list []*UserModel
+	if err := DB.Where("status = ?", UserModelStatusApproved).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	models := make(map[int]map[string]*UserModel)
+	for _, m := range list {
+		if models[m.UserId] == nil {
+			models[m.UserId] = make(map[string]*UserModel)
+		}
+		models[m.UserId][m.Alias] = m
+	}
+	userModelCache.Lock()
+	userModelCache.models = models
+	userModelCache.loadedAt = time.Now()
+	userModelCache.Unlock()
+	return models, nil
+}
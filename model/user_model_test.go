@@ -0,0 +1,41 @@
+package model
+
+import "testing"
+
+func TestGetApprovedUserModel(t *testing.T) {
+	setupTestDB(t, &UserModel{})
+	InvalidateUserModelCache()
+
+	pending := &UserModel{UserId: 1, Alias: "my-flux", ChannelId: 3, UpstreamModelId: "alice/flux-lora:abc123", PriceOverride: 0.05, Status: UserModelStatusPending}
+	if err := pending.Insert(); err != nil {
+		t.Fatalf("insert user model failed: %v", err)
+	}
+	if _, ok, err := GetApprovedUserModel(1, "my-flux"); err != nil || ok {
+		t.Fatalf("pending model should not resolve: ok=%v err=%v", ok, err)
+	}
+
+	// 审核通过后缓存立即失效，且别名只对注册的用户生效
+	if err := UpdateUserModelStatus(pending.Id, UserModelStatusApproved); err != nil {
+		t.Fatalf("approve user model failed: %v", err)
+	}
+	m, ok, err := GetApprovedUserModel(1, "my-flux")
+	if err != nil || !ok || m.UpstreamModelId != "alice/flux-lora:abc123" || m.ChannelId != 3 {
+		t.Fatalf("GetApprovedUserModel = %+v, %v, %v", m, ok, err)
+	}
+	if _, ok, _ := GetApprovedUserModel(2, "my-flux"); ok {
+		t.Fatal("alias should not resolve for other users")
+	}
+
+	if taken, err := IsUserModelAliasTaken(1, "my-flux"); err != nil || !taken {
+		t.Fatalf("IsUserModelAliasTaken = %v, %v", taken, err)
+	}
+	if deleted, err := DeleteUserModel(pending.Id, 2); err != nil || deleted {
+		t.Fatalf("other user should not delete the model: deleted=%v err=%v", deleted, err)
+	}
+	if deleted, err := DeleteUserModel(pending.Id, 1); err != nil || !deleted {
+		t.Fatalf("DeleteUserModel = %v, %v", deleted, err)
+	}
+	if _, ok, _ := GetApprovedUserModel(1, "my-flux"); ok {
+		t.Fatal("deleted model should not resolve")
+	}
+}
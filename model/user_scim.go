@@ -0,0 +1,16 @@
+package model
+
+// FindScimUsers 供 SCIM 同步查询用户，userName 不为空时按邮箱或用户名精确匹配
+func FindScimUsers(userName string, startIdx int, num int) ([]*User, int64, error) {
+	var users []*User
+	var total int64
+	query := DB.Model(&User{})
+	if userName != "" {
+		query = query.Where("email = ? OR username = ?", userName, userName)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("password").Order("id asc").Offset(startIdx).Limit(num).Find(&users).Error
+	return users, total, err
+}
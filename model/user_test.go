@@ -0,0 +1,24 @@
+package model
+
+import "testing"
+
+func TestDecreaseUserQuotaIfEnough(t *testing.T) {
+	setupTestDB(t, &User{})
+	if err := DB.Create(&User{Id: 1, Username: "decrease", Quota: 100}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	quota := func() int {
+		var user User
+		DB.First(&user, 1)
+		return user.Quota
+	}
+	if ok, err := DecreaseUserQuotaIfEnough(1, 60); err != nil || !ok {
+		t.Fatalf("decrease within balance: ok %v, err %v", ok, err)
+	}
+	if ok, err := DecreaseUserQuotaIfEnough(1, 60); err != nil || ok {
+		t.Fatalf("decrease beyond balance: ok %v, err %v", ok, err)
+	}
+	if got := quota(); got != 40 {
+		t.Fatalf("quota = %d, want 40", got)
+	}
+}
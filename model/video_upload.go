@@ -0,0 +1,36 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// VideoUpload 记录通过 /v1/files/upload 转存到渠道 GCS 存储桶的视频对象，
+// 过期后由清理任务删除对象及记录
+type VideoUpload struct {
+	Id        int64  `json:"id" gorm:"primaryKey"`
+	UserId    int    `json:"user_id" gorm:"index"`
+	TokenId   int    `json:"token_id" gorm:"index"`
+	ChannelId int    `json:"channel_id" gorm:"index"`
+	Bucket    string `json:"bucket" gorm:"type:varchar(255)"`
+	Object    string `json:"object" gorm:"type:varchar(512)"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+func (u *VideoUpload) Insert() error {
+	if u.CreatedAt == 0 {
+		u.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(u).Error
+}
+
+// GetExpiredVideoUploads 返回创建时间早于 before 的上传记录，按创建时间升序
+func GetExpiredVideoUploads(before int64, limit int) ([]*VideoUpload, error) {
+	var uploads []*VideoUpload
+	err := DB.Where("created_at < ?", before).Order("created_at asc").Limit(limit).Find(&uploads).Error
+	return uploads, err
+}
+
+func DeleteVideoUploadById(id int64) error {
+	return DB.Delete(&VideoUpload{}, id).Error
+}
@@ -0,0 +1,28 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KlingTokenTTL Kling 鉴权 token 的有效期
+const KlingTokenTTL = 30 * time.Minute
+
+// GenerateKlingJWT 使用 Kling 的 AccessKey/SecretKey 生成 HS256 签名的 Bearer token，
+// iss 为 AccessKey，nbf 提前 5 秒以容忍时钟偏差。签名失败时返回空字符串
+func GenerateKlingJWT(accessKey, secret string) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": accessKey,
+		"exp": now.Add(KlingTokenTTL).Unix(),
+		"nbf": now.Add(-5 * time.Second).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["typ"] = "JWT"
+	signed, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return ""
+	}
+	return signed
+}
@@ -29,6 +29,33 @@ type Adaptor interface {
 	GetChannelName() string
 	ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error)
 	ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error)
+	// IsAsyncImageGeneration reports whether image generation may answer with
+	// a pending upstream task; such adaptors must implement AsyncImageAdaptor.
+	IsAsyncImageGeneration() bool
+}
+
+// AsyncImageAdaptor lets the image handler poll an unfinished image
+// generation task until the upstream response carries the final images.
+type AsyncImageAdaptor interface {
+	// ParseImageTask returns the upstream task ID when body describes a task
+	// that has not finished yet, or "" when body is already final.
+	ParseImageTask(body []byte) (taskID string, err error)
+	FetchTask(c *gin.Context, info *relaycommon.RelayInfo, taskID string) (*http.Response, error)
+}
+
+// CapabilityAdaptor is implemented by adaptors that advertise which request
+// features (see constant.Capability*) their upstream supports. Channels whose
+// adaptor does not implement it are treated as capable of everything unless an
+// admin configures capability tags on the channel.
+type CapabilityAdaptor interface {
+	GetCapabilities() []string
+}
+
+// ImageVariationAdaptor is implemented by adaptors that can serve
+// /v1/images/variations. Variation requests routed to any other adaptor are
+// rejected before reaching the upstream.
+type ImageVariationAdaptor interface {
+	SupportsImageVariations() bool
 }
 
 type TaskAdaptor interface {
@@ -52,6 +79,25 @@ type TaskAdaptor interface {
 	ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error)
 }
 
+// BatchTaskAdaptor is implemented by task adaptors whose upstream can report
+// the status of several tasks in a single request.
+type BatchTaskAdaptor interface {
+	FetchTaskBatch(baseUrl, key string, taskIds []string, proxy string) ([]*relaycommon.TaskInfo, error)
+}
+
+// TaskCanceller is implemented by task adaptors whose upstream can cancel a
+// submitted task before it runs.
+type TaskCanceller interface {
+	CancelTask(baseUrl, key, taskID string, proxy string) error
+}
+
+// TaskDependencyInputAdaptor is implemented by task adaptors whose requests
+// take a reference media URL under a field other than the generic "image".
+// The output URL of a task named in depends_on_task_id is written there.
+type TaskDependencyInputAdaptor interface {
+	DependencyInputField() string
+}
+
 type OpenAIVideoConverter interface {
 	ConvertToOpenAIVideo(originTask *model.Task) ([]byte, error)
 }
@@ -1,17 +1,23 @@
 package channel
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	common2 "github.com/QuantumNous/new-api/common"
+	constant2 "github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
+	"github.com/QuantumNous/new-api/relay/channel/transform"
 	"github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
@@ -36,6 +42,21 @@ func SetupApiRequestHeader(info *common.RelayInfo, c *gin.Context, req *http.Hea
 			req.Set("Accept", "text/event-stream")
 		}
 	}
+	setTraceIdHeader(info, *req)
+}
+
+// setTraceIdHeader 将请求追踪 ID 透传给上游
+func setTraceIdHeader(info *common.RelayInfo, header http.Header) {
+	if info.TraceID != "" {
+		header.Set(common2.TraceIdKey, info.TraceID)
+	}
+}
+
+// setAcceptEncodingHeader 声明支持 gzip 与 brotli 压缩，响应在 doRequest 中统一解压
+func setAcceptEncodingHeader(header http.Header) {
+	if header.Get("Accept-Encoding") == "" {
+		header.Set("Accept-Encoding", service.UpstreamAcceptEncoding)
+	}
 }
 
 // processHeaderOverride 处理请求头覆盖，支持变量替换
@@ -58,6 +79,18 @@ func processHeaderOverride(info *common.RelayInfo) (map[string]string, error) {
 	return headerOverride, nil
 }
 
+// applyQueryOverride 按渠道 query_override 设置覆盖上游请求的查询参数，支持的变量：{api_key}
+func applyQueryOverride(info *common.RelayInfo, req *http.Request) {
+	if info.ChannelMeta == nil || len(info.ChannelSetting.QueryOverride) == 0 {
+		return
+	}
+	query := req.URL.Query()
+	for key, value := range info.ChannelSetting.QueryOverride {
+		query.Set(key, strings.ReplaceAll(value, "{api_key}", info.ApiKey))
+	}
+	req.URL.RawQuery = query.Encode()
+}
+
 func DoApiRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBody io.Reader) (*http.Response, error) {
 	fullRequestURL, err := a.GetRequestURL(info)
 	if err != nil {
@@ -82,13 +115,63 @@ func DoApiRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBody
 	if err != nil {
 		return nil, fmt.Errorf("setup request header failed: %w", err)
 	}
+	setTraceIdHeader(info, headers)
+	setAcceptEncodingHeader(headers)
+	applyQueryOverride(info, req)
+	releaseProbe, err := allowChannelRequest(c, info)
+	if err != nil {
+		return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeChannelCircuitBreakerOpen, http.StatusServiceUnavailable)
+	}
+	defer releaseProbe()
+	release, err := acquireChannelConcurrency(c, info)
+	if err != nil {
+		return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeChannelConcurrencyLimit, http.StatusTooManyRequests)
+	}
+	startTime := time.Now()
 	resp, err := doRequest(c, req, info)
+	recordChannelResult(c, info, resp, err)
+	observeChannelRequest(info, startTime, resp, err)
 	if err != nil {
+		release()
 		return nil, fmt.Errorf("do request failed: %w", err)
 	}
+	forwardRateLimitHeaders(c, resp)
+	if err := applyResponseTransform(c, info, resp); err != nil {
+		release()
+		return nil, err
+	}
+	releaseOnClose(c, resp, release)
 	return resp, nil
 }
 
+// applyResponseTransform 按渠道 response_transform 配置改写非流式的 JSON 成功响应，
+// 改写失败时记录日志并返回原始响应体
+func applyResponseTransform(c *gin.Context, info *common.RelayInfo, resp *http.Response) error {
+	transformer, err := transform.NewFromSetting(info.ChannelSetting.ResponseTransform)
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("invalid response transform for channel #%d: %s", info.ChannelId, err.Error()))
+		return nil
+	}
+	if transformer == nil || info.IsStream || resp.StatusCode >= http.StatusBadRequest ||
+		!strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return nil
+	}
+	body, err := service.ReadCompressedBody(resp)
+	_ = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("read response body failed: %w", err)
+	}
+	if transformed, err := transformer.TransformResponse(body, info); err != nil {
+		logger.LogWarn(c, fmt.Sprintf("response transform failed for channel #%d: %s", info.ChannelId, err.Error()))
+	} else {
+		body = transformed
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
 func DoFormRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBody io.Reader) (*http.Response, error) {
 	fullRequestURL, err := a.GetRequestURL(info)
 	if err != nil {
@@ -115,10 +198,14 @@ func DoFormRequest(a Adaptor, c *gin.Context, info *common.RelayInfo, requestBod
 	if err != nil {
 		return nil, fmt.Errorf("setup request header failed: %w", err)
 	}
+	setTraceIdHeader(info, headers)
+	setAcceptEncodingHeader(headers)
+	applyQueryOverride(info, req)
 	resp, err := doRequest(c, req, info)
 	if err != nil {
 		return nil, fmt.Errorf("do request failed: %w", err)
 	}
+	forwardRateLimitHeaders(c, resp)
 	return resp, nil
 }
 
@@ -260,6 +347,8 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 		if err != nil {
 			return nil, fmt.Errorf("new proxy http client failed: %w", err)
 		}
+	} else if info.ChannelSetting.HTTP2 {
+		client = service.GetHTTP2Client()
 	} else {
 		client = service.GetHttpClient()
 	}
@@ -284,20 +373,81 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 		}
 	}
 
+	req, cancel := withRequestTimeout(c, req, info)
 	resp, err := client.Do(req)
 	if err != nil {
+		cancel()
 		logger.LogError(c, "do request failed: "+err.Error())
+		if errors.Is(context.Cause(req.Context()), context.DeadlineExceeded) {
+			return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeDoRequestFailed, http.StatusGatewayTimeout, types.ErrOptionWithHideErrMsg("upstream error: request timed out"))
+		}
 		return nil, types.NewError(err, types.ErrorCodeDoRequestFailed, types.ErrOptionWithHideErrMsg("upstream error: do request failed"))
 	}
 	if resp == nil {
+		cancel()
 		return nil, errors.New("resp is nil")
 	}
+	resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+	if err := service.DecompressResponseBody(resp); err != nil {
+		_ = resp.Body.Close()
+		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
 
 	_ = req.Body.Close()
 	_ = c.Request.Body.Close()
 	return resp, nil
 }
 
+// requestTimeout 返回渠道上游请求的超时时间，渠道未配置时使用 CHANNEL_REQUEST_TIMEOUT_SECONDS，0 表示不限制
+func requestTimeout(info *common.RelayInfo) time.Duration {
+	seconds := constant2.ChannelRequestTimeoutSeconds
+	if info.ChannelMeta != nil && info.ChannelSetting.RequestTimeoutSeconds > 0 {
+		seconds = info.ChannelSetting.RequestTimeoutSeconds
+	}
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// withRequestTimeout 基于客户端请求的 context 为上游请求设置响应头超时，并在超时前（80%）记录一次警告。
+// 超时只覆盖到收到上游首字节为止，之后读取响应体不再受限，避免长时间生成的非流式响应或流被截断。
+// 返回的 cancel 会在响应体关闭时调用
+func withRequestTimeout(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http.Request, context.CancelFunc) {
+	timeout := requestTimeout(info)
+	if timeout <= 0 {
+		return req, func() {}
+	}
+	ctx, cancelCause := context.WithCancelCause(c.Request.Context())
+	deadline := time.AfterFunc(timeout, func() { cancelCause(context.DeadlineExceeded) })
+	warn := time.AfterFunc(timeout*4/5, func() {
+		logger.LogWarn(c, fmt.Sprintf("upstream request to channel #%d is about to time out after %s", info.ChannelId, timeout))
+	})
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotFirstResponseByte: func() {
+			deadline.Stop()
+			warn.Stop()
+		},
+	})
+	return req.WithContext(ctx), func() {
+		warn.Stop()
+		deadline.Stop()
+		cancelCause(context.Canceled)
+	}
+}
+
+// cancelOnCloseBody 在响应体关闭时释放请求超时的 context
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 func DoTaskApiRequest(a TaskAdaptor, c *gin.Context, info *common.RelayInfo, requestBody io.Reader) (*http.Response, error) {
 	fullRequestURL, err := a.BuildRequestURL(info)
 	if err != nil {
@@ -311,13 +461,95 @@ func DoTaskApiRequest(a TaskAdaptor, c *gin.Context, info *common.RelayInfo, req
 		return io.NopCloser(requestBody), nil
 	}
 
+	headerOverride, err := processHeaderOverride(info)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headerOverride {
+		req.Header.Set(key, value)
+	}
 	err = a.BuildRequestHeader(c, req, info)
 	if err != nil {
 		return nil, fmt.Errorf("setup request header failed: %w", err)
 	}
+	setTraceIdHeader(info, req.Header)
+	setAcceptEncodingHeader(req.Header)
+	applyQueryOverride(info, req)
+	releaseProbe, err := allowChannelRequest(c, info)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseProbe()
+	release, err := acquireChannelConcurrency(c, info)
+	if err != nil {
+		return nil, err
+	}
 	resp, err := doRequest(c, req, info)
+	recordChannelResult(c, info, resp, err)
 	if err != nil {
+		release()
 		return nil, fmt.Errorf("do request failed: %w", err)
 	}
+	releaseOnClose(c, resp, release)
 	return resp, nil
 }
+
+// acquireChannelConcurrency 在请求上游前占用渠道并发名额
+func acquireChannelConcurrency(c *gin.Context, info *common.RelayInfo) (func(), error) {
+	if info.ChannelMeta == nil {
+		return func() {}, nil
+	}
+	return common.DefaultChannelConcurrencyGuard.Acquire(c.Request.Context(), info.ChannelId, info.ConcurrencyLimit)
+}
+
+// releaseOnCloseBody 在响应体关闭时释放渠道并发名额
+type releaseOnCloseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// releaseOnClose 使并发名额一直占用到响应体读取完毕并关闭（流式响应即整个流结束），
+// 调用方未关闭响应体时在请求结束后兜底释放
+func releaseOnClose(c *gin.Context, resp *http.Response, release func()) {
+	stop := context.AfterFunc(c.Request.Context(), release)
+	resp.Body = &releaseOnCloseBody{ReadCloser: resp.Body, release: func() {
+		stop()
+		release()
+	}}
+}
+
+// allowChannelRequest 渠道熔断时直接拒绝，避免继续请求已故障的上游。返回的 release 在请求结束时调用，
+// 用于未记录结果时归还半开状态的探测名额
+func allowChannelRequest(c *gin.Context, info *common.RelayInfo) (func(), error) {
+	if info.ChannelMeta == nil {
+		return func() {}, nil
+	}
+	return service.DefaultCircuitBreaker.Allow(c.Request.Context(), info.ChannelId)
+}
+
+// recordChannelResult 将上游请求结果计入渠道熔断统计，连接失败或 5xx 视为失败，客户端主动断开不计入
+func recordChannelResult(c *gin.Context, info *common.RelayInfo, resp *http.Response, err error) {
+	if info.ChannelMeta == nil || c.Request.Context().Err() != nil {
+		return
+	}
+	success := err == nil && resp.StatusCode < http.StatusInternalServerError
+	service.DefaultCircuitBreaker.Record(c.Request.Context(), info.ChannelId, success)
+}
+
+// observeChannelRequest 记录上游请求的 Prometheus 指标
+func observeChannelRequest(info *common.RelayInfo, startTime time.Time, resp *http.Response, err error) {
+	if info.ChannelMeta == nil {
+		return
+	}
+	status := metrics.StatusError
+	if err == nil {
+		status = metrics.StatusLabel(resp.StatusCode)
+	}
+	metrics.ObserveRequest(info.ChannelId, info.OriginModelName, status, time.Since(startTime))
+}
@@ -0,0 +1,134 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTimeoutTestRequest(t *testing.T, url string, stream bool) (*gin.Context, *http.Request, *common.RelayInfo) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader("{}"))
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	info := &common.RelayInfo{
+		IsStream:    stream,
+		DisablePing: true,
+		ChannelMeta: &common.ChannelMeta{ChannelSetting: dto.ChannelSettings{RequestTimeoutSeconds: 1}},
+	}
+	return c, req, info
+}
+
+func TestDoRequestTimeout(t *testing.T) {
+	service.InitHttpClient()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+			return
+		}
+		// 先返回响应头，之后的数据超过超时时间才到达
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		time.Sleep(1500 * time.Millisecond)
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	c, req, info := newTimeoutTestRequest(t, server.URL+"/slow", false)
+	_, err := DoRequest(c, req, info)
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	var apiErr *types.NewAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("err = %v, want 504 NewAPIError", err)
+	}
+
+	// 超时只限制到收到响应头为止，流式与非流式响应体的读取都不受影响
+	for _, stream := range []bool{true, false} {
+		c, req, info = newTimeoutTestRequest(t, server.URL+"/body", stream)
+		resp, err := DoRequest(c, req, info)
+		if err != nil {
+			t.Fatalf("stream=%v request: %v", stream, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil || string(body) != "data: [DONE]\n\n" {
+			t.Fatalf("stream=%v body = %q, err = %v", stream, body, err)
+		}
+	}
+}
+
+func TestReleaseOnClose(t *testing.T) {
+	guard := common.NewChannelConcurrencyGuard(10 * time.Millisecond)
+	newResponse := func() (*gin.Context, context.CancelFunc, *http.Response, func()) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		ctx, cancel := context.WithCancel(context.Background())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil).WithContext(ctx)
+		release, err := guard.Acquire(ctx, 1, 1)
+		if err != nil {
+			t.Fatalf("acquire: %v", err)
+		}
+		return c, cancel, &http.Response{Body: io.NopCloser(strings.NewReader("data: [DONE]\n\n"))}, release
+	}
+
+	c, cancel, resp, release := newResponse()
+	defer cancel()
+	releaseOnClose(c, resp, release)
+	// 流式响应读取期间名额保持占用
+	if _, err := io.ReadAll(resp.Body); err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+	if guard.InFlight(1) != 1 {
+		t.Fatalf("in flight = %d before close, want 1", guard.InFlight(1))
+	}
+	_ = resp.Body.Close()
+	if guard.InFlight(1) != 0 {
+		t.Fatalf("in flight = %d after close, want 0", guard.InFlight(1))
+	}
+
+	// 未关闭响应体时请求结束后释放
+	c, cancel, resp, release = newResponse()
+	releaseOnClose(c, resp, release)
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for guard.InFlight(1) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if guard.InFlight(1) != 0 {
+		t.Fatalf("in flight = %d after request ended, want 0", guard.InFlight(1))
+	}
+}
+
+func TestApplyQueryOverride(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/v1/videos?foo=bar&api-version=old", strings.NewReader("{}"))
+	info := &common.RelayInfo{
+		ChannelMeta: &common.ChannelMeta{ApiKey: "sk-upstream", ChannelSetting: dto.ChannelSettings{
+			QueryOverride: map[string]string{"api-version": "2025-01-01", "key": "{api_key}"},
+		}},
+	}
+	applyQueryOverride(info, req)
+	query := req.URL.Query()
+	if query.Get("foo") != "bar" || query.Get("api-version") != "2025-01-01" || query.Get("key") != "sk-upstream" {
+		t.Fatalf("query = %s", req.URL.RawQuery)
+	}
+}
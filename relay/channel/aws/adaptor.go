@@ -180,3 +180,7 @@ func (a *Adaptor) GetModelList() (models []string) {
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
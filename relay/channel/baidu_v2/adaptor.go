@@ -128,3 +128,7 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
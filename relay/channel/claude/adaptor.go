@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
@@ -21,8 +22,12 @@ const (
 	RequestModeMessage    = 2
 )
 
+// InterleavedThinkingBeta 开启 extended thinking 时追加的 anthropic-beta，允许在工具调用之间穿插思考块
+const InterleavedThinkingBeta = "interleaved-thinking-2025-05-14"
+
 type Adaptor struct {
-	RequestMode int
+	RequestMode     int
+	ThinkingEnabled bool
 }
 
 func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
@@ -31,9 +36,14 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	a.ThinkingEnabled = isThinkingEnabled(request)
 	return request, nil
 }
 
+func isThinkingEnabled(request *dto.ClaudeRequest) bool {
+	return request != nil && request.Thinking != nil && request.Thinking.Type == "enabled"
+}
+
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
 	//TODO implement me
 	return nil, errors.New("not implemented")
@@ -74,6 +84,21 @@ func CommonClaudeHeadersOperation(c *gin.Context, req *http.Header, info *relayc
 	model_setting.GetClaudeSettings().WriteHeaders(info.OriginModelName, req)
 }
 
+// appendAnthropicBeta 将 beta 合并进已有的 anthropic-beta 头，已存在时不重复添加
+func appendAnthropicBeta(req *http.Header, beta string) {
+	existing := req.Get("anthropic-beta")
+	if existing == "" {
+		req.Set("anthropic-beta", beta)
+		return
+	}
+	for _, b := range strings.Split(existing, ",") {
+		if strings.TrimSpace(b) == beta {
+			return
+		}
+	}
+	req.Set("anthropic-beta", existing+","+beta)
+}
+
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
 	channel.SetupApiRequestHeader(info, c, req)
 	req.Set("x-api-key", info.ApiKey)
@@ -83,6 +108,9 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *rel
 	}
 	req.Set("anthropic-version", anthropicVersion)
 	CommonClaudeHeadersOperation(c, req, info)
+	if a.ThinkingEnabled {
+		appendAnthropicBeta(req, InterleavedThinkingBeta)
+	}
 	return nil
 }
 
@@ -92,9 +120,13 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	}
 	if a.RequestMode == RequestModeCompletion {
 		return RequestOpenAI2ClaudeComplete(*request), nil
-	} else {
-		return RequestOpenAI2ClaudeMessage(c, *request)
 	}
+	claudeRequest, err := RequestOpenAI2ClaudeMessage(c, *request)
+	if err != nil {
+		return nil, err
+	}
+	a.ThinkingEnabled = isThinkingEnabled(claudeRequest)
+	return claudeRequest, nil
 }
 
 func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
@@ -121,13 +153,20 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	} else {
 		return ClaudeHandler(c, resp, info, a.RequestMode)
 	}
-	return
 }
 
 func (a *Adaptor) GetModelList() []string {
 	return ModelList
 }
 
+func (a *Adaptor) GetCapabilities() []string {
+	return []string{constant.CapabilityVision, constant.CapabilityStreaming, constant.CapabilityTools}
+}
+
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
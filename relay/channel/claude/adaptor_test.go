@@ -0,0 +1,35 @@
+package claude
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestAppendAnthropicBeta(t *testing.T) {
+	header := http.Header{}
+	appendAnthropicBeta(&header, InterleavedThinkingBeta)
+	if got := header.Get("anthropic-beta"); got != InterleavedThinkingBeta {
+		t.Fatalf("anthropic-beta = %s", got)
+	}
+
+	header.Set("anthropic-beta", "context-1m-2025-08-07")
+	appendAnthropicBeta(&header, InterleavedThinkingBeta)
+	appendAnthropicBeta(&header, InterleavedThinkingBeta)
+	if got := header.Get("anthropic-beta"); got != "context-1m-2025-08-07,"+InterleavedThinkingBeta {
+		t.Fatalf("anthropic-beta = %s", got)
+	}
+}
+
+func TestIsThinkingEnabled(t *testing.T) {
+	if isThinkingEnabled(&dto.ClaudeRequest{}) {
+		t.Fatal("request without thinking should not enable thinking")
+	}
+	if isThinkingEnabled(&dto.ClaudeRequest{Thinking: &dto.Thinking{Type: "disabled"}}) {
+		t.Fatal("disabled thinking should not enable thinking")
+	}
+	if !isThinkingEnabled(&dto.ClaudeRequest{Thinking: &dto.Thinking{Type: "enabled"}}) {
+		t.Fatal("enabled thinking should enable thinking")
+	}
+}
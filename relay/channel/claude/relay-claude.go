@@ -3,7 +3,6 @@ package claude
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
@@ -74,27 +73,8 @@ func RequestOpenAI2ClaudeComplete(textRequest dto.GeneralOpenAIRequest) *dto.Cla
 
 func RequestOpenAI2ClaudeMessage(c *gin.Context, textRequest dto.GeneralOpenAIRequest) (*dto.ClaudeRequest, error) {
 	claudeTools := make([]any, 0, len(textRequest.Tools))
-
-	for _, tool := range textRequest.Tools {
-		if params, ok := tool.Function.Parameters.(map[string]any); ok {
-			claudeTool := dto.Tool{
-				Name:        tool.Function.Name,
-				Description: tool.Function.Description,
-			}
-			claudeTool.InputSchema = make(map[string]interface{})
-			if params["type"] != nil {
-				claudeTool.InputSchema["type"] = params["type"].(string)
-			}
-			claudeTool.InputSchema["properties"] = params["properties"]
-			claudeTool.InputSchema["required"] = params["required"]
-			for s, a := range params {
-				if s == "type" || s == "properties" || s == "required" {
-					continue
-				}
-				claudeTool.InputSchema[s] = a
-			}
-			claudeTools = append(claudeTools, &claudeTool)
-		}
+	for _, tool := range ConvertOpenAIToolsToClaudeTools(textRequest.Tools) {
+		claudeTools = append(claudeTools, tool)
 	}
 
 	// Web search tool
@@ -388,7 +368,7 @@ func RequestOpenAI2ClaudeMessage(c *gin.Context, textRequest dto.GeneralOpenAIRe
 					for _, toolCall := range message.ParseToolCalls() {
 						inputObj := make(map[string]any)
 						if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &inputObj); err != nil {
-							common.SysLog("tool call function arguments is not a map[string]any: " + fmt.Sprintf("%v", toolCall.Function.Arguments))
+							logger.LogInfo(c, "tool call function arguments is not a map[string]any: "+fmt.Sprintf("%v", toolCall.Function.Arguments))
 							continue
 						}
 						claudeMediaMessages = append(claudeMediaMessages, dto.ClaudeMediaMessage{
@@ -415,19 +395,13 @@ func RequestOpenAI2ClaudeMessage(c *gin.Context, textRequest dto.GeneralOpenAIRe
 	return &claudeRequest, nil
 }
 
-func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto.ChatCompletionsStreamResponse {
+// StreamResponseClaude2OpenAI 将 Claude 流式事件转换为 OpenAI chunk，tool_use 内容块通过 claudeInfo 记录的下标转换为 tool_calls 增量
+func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse, claudeInfo *ClaudeResponseInfo) *dto.ChatCompletionsStreamResponse {
 	var response dto.ChatCompletionsStreamResponse
 	response.Object = "chat.completion.chunk"
 	response.Model = claudeResponse.Model
 	response.Choices = make([]dto.ChatCompletionsStreamResponseChoice, 0)
 	tools := make([]dto.ToolCallResponse, 0)
-	fcIdx := 0
-	if claudeResponse.Index != nil {
-		fcIdx = *claudeResponse.Index - 1
-		if fcIdx < 0 {
-			fcIdx = 0
-		}
-	}
 	var choice dto.ChatCompletionsStreamResponseChoice
 	if reqMode == RequestModeCompletion {
 		choice.Delta.SetContentString(claudeResponse.Completion)
@@ -448,16 +422,13 @@ func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse
 				if claudeResponse.ContentBlock.Type == "text" && claudeResponse.ContentBlock.Text != nil {
 					choice.Delta.SetContentString(*claudeResponse.ContentBlock.Text)
 				}
+				// 思考块的首段明文推理作为 reasoning_content 输出
+				if claudeResponse.ContentBlock.Type == "thinking" && claudeResponse.ContentBlock.Thinking != nil {
+					choice.Delta.ReasoningContent = claudeResponse.ContentBlock.Thinking
+				}
 				if claudeResponse.ContentBlock.Type == "tool_use" {
-					tools = append(tools, dto.ToolCallResponse{
-						Index: common.GetPointer(fcIdx),
-						ID:    claudeResponse.ContentBlock.Id,
-						Type:  "function",
-						Function: dto.FunctionResponse{
-							Name:      claudeResponse.ContentBlock.Name,
-							Arguments: "",
-						},
-					})
+					call := claudeInfo.toolCalls.start(claudeResponse.GetIndex(), claudeResponse.ContentBlock.Id, claudeResponse.ContentBlock.Name)
+					tools = append(tools, call.toolCallDelta("", true))
 				}
 			} else {
 				return nil
@@ -467,13 +438,10 @@ func StreamResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse
 				choice.Delta.Content = claudeResponse.Delta.Text
 				switch claudeResponse.Delta.Type {
 				case "input_json_delta":
-					tools = append(tools, dto.ToolCallResponse{
-						Type:  "function",
-						Index: common.GetPointer(fcIdx),
-						Function: dto.FunctionResponse{
-							Arguments: *claudeResponse.Delta.PartialJson,
-						},
-					})
+					if claudeResponse.Delta.PartialJson != nil {
+						call := claudeInfo.toolCalls.get(claudeResponse.GetIndex())
+						tools = append(tools, call.toolCallDelta(*claudeResponse.Delta.PartialJson, false))
+					}
 				case "signature_delta":
 					// 加密的不处理
 					signatureContent := "\n"
@@ -539,15 +507,7 @@ func ResponseClaude2OpenAI(reqMode int, claudeResponse *dto.ClaudeResponse) *dto
 		for _, message := range claudeResponse.Content {
 			switch message.Type {
 			case "tool_use":
-				args, _ := json.Marshal(message.Input)
-				tools = append(tools, dto.ToolCallResponse{
-					ID:   message.Id,
-					Type: "function", // compatible with other OpenAI derivative applications
-					Function: dto.FunctionResponse{
-						Name:      message.Name,
-						Arguments: string(args),
-					},
-				})
+				tools = append(tools, ConvertClaudeToolUseToOpenAI(message))
 			case "thinking":
 				// 加密的不管， 只输出明文的推理过程
 				if message.Thinking != nil {
@@ -584,8 +544,12 @@ type ClaudeResponseInfo struct {
 	Created      int64
 	Model        string
 	ResponseText strings.Builder
+	// 思考块的明文推理，用于统计 reasoning tokens
+	ThinkingText strings.Builder
 	Usage        *dto.Usage
 	Done         bool
+	// 流式 tool_use 内容块对应的 OpenAI tool_calls 下标
+	toolCalls claudeToolCallTracker
 }
 
 func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeResponse, oaiResponse *dto.ChatCompletionsStreamResponse, claudeInfo *ClaudeResponseInfo) bool {
@@ -609,6 +573,10 @@ func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeRespons
 			}
 			if claudeResponse.Delta.Thinking != nil {
 				claudeInfo.ResponseText.WriteString(*claudeResponse.Delta.Thinking)
+				claudeInfo.ThinkingText.WriteString(*claudeResponse.Delta.Thinking)
+			}
+			if claudeResponse.Delta.PartialJson != nil {
+				claudeInfo.ResponseText.WriteString(*claudeResponse.Delta.PartialJson)
 			}
 		} else if claudeResponse.Type == "message_delta" {
 			// 最终的usage获取
@@ -622,6 +590,10 @@ func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeRespons
 			// 判断是否完整
 			claudeInfo.Done = true
 		} else if claudeResponse.Type == "content_block_start" {
+			if claudeResponse.ContentBlock != nil && claudeResponse.ContentBlock.Type == "thinking" && claudeResponse.ContentBlock.Thinking != nil {
+				claudeInfo.ResponseText.WriteString(*claudeResponse.ContentBlock.Thinking)
+				claudeInfo.ThinkingText.WriteString(*claudeResponse.ContentBlock.Thinking)
+			}
 		} else {
 			return false
 		}
@@ -638,7 +610,7 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 	var claudeResponse dto.ClaudeResponse
 	err := common.UnmarshalJsonStr(data, &claudeResponse)
 	if err != nil {
-		common.SysLog("error unmarshalling stream response: " + err.Error())
+		logger.LogError(c, "error unmarshalling stream response: "+err.Error())
 		return types.NewError(err, types.ErrorCodeBadResponseBody)
 	}
 	if claudeError := claudeResponse.GetClaudeError(); claudeError != nil && claudeError.Type != "" {
@@ -658,7 +630,7 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		}
 		helper.ClaudeChunkData(c, claudeResponse, data)
 	} else if info.RelayFormat == types.RelayFormatOpenAI {
-		response := StreamResponseClaude2OpenAI(requestMode, &claudeResponse)
+		response := StreamResponseClaude2OpenAI(requestMode, &claudeResponse, claudeInfo)
 
 		if !FormatClaudeResponseInfo(requestMode, &claudeResponse, response, claudeInfo) {
 			return nil
@@ -682,10 +654,14 @@ func HandleStreamFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, clau
 		}
 		if claudeInfo.Usage.CompletionTokens == 0 || !claudeInfo.Done {
 			if common.DebugEnabled {
-				common.SysLog("claude response usage is not complete, maybe upstream error")
+				logger.LogError(c, "claude response usage is not complete, maybe upstream error")
 			}
 			claudeInfo.Usage = service.ResponseText2Usage(c, claudeInfo.ResponseText.String(), info.UpstreamModelName, claudeInfo.Usage.PromptTokens)
 		}
+		// 上游的 output_tokens 已包含思考部分，这里仅拆分出 reasoning tokens 供日志与统计
+		if claudeInfo.ThinkingText.Len() > 0 {
+			claudeInfo.Usage.CompletionTokenDetails.ReasoningTokens = service.CountTextToken(claudeInfo.ThinkingText.String(), info.UpstreamModelName)
+		}
 	}
 
 	if info.RelayFormat == types.RelayFormatClaude {
@@ -695,7 +671,7 @@ func HandleStreamFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, clau
 			response := helper.GenerateFinalUsageResponse(claudeInfo.ResponseId, claudeInfo.Created, info.UpstreamModelName, *claudeInfo.Usage)
 			err := helper.ObjectData(c, response)
 			if err != nil {
-				common.SysLog("send final response failed: " + err.Error())
+				logger.LogError(c, "send final response failed: "+err.Error())
 			}
 		}
 		helper.Done(c)
@@ -745,6 +721,14 @@ func HandleClaudeResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		claudeInfo.Usage.PromptTokensDetails.CachedCreationTokens = claudeResponse.Usage.CacheCreationInputTokens
 		claudeInfo.Usage.ClaudeCacheCreation5mTokens = claudeResponse.Usage.GetCacheCreation5mTokens()
 		claudeInfo.Usage.ClaudeCacheCreation1hTokens = claudeResponse.Usage.GetCacheCreation1hTokens()
+		for _, content := range claudeResponse.Content {
+			if content.Type == "thinking" && content.Thinking != nil {
+				claudeInfo.ThinkingText.WriteString(*content.Thinking)
+			}
+		}
+		if claudeInfo.ThinkingText.Len() > 0 {
+			claudeInfo.Usage.CompletionTokenDetails.ReasoningTokens = service.CountTextToken(claudeInfo.ThinkingText.String(), info.UpstreamModelName)
+		}
 	}
 	var responseData []byte
 	switch info.RelayFormat {
@@ -777,7 +761,7 @@ func ClaudeHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayI
 		ResponseText: strings.Builder{},
 		Usage:        &dto.Usage{},
 	}
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
 	}
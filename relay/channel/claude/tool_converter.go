@@ -0,0 +1,102 @@
+package claude
+
+import (
+	"encoding/json"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// ConvertOpenAIToolsToClaudeTools 将 OpenAI 的 function 工具定义转换为 Claude 工具，parameters 不是 JSON 对象的工具会被忽略
+func ConvertOpenAIToolsToClaudeTools(tools []dto.ToolCallRequest) []*dto.Tool {
+	claudeTools := make([]*dto.Tool, 0, len(tools))
+	for _, tool := range tools {
+		params, ok := tool.Function.Parameters.(map[string]any)
+		if !ok {
+			continue
+		}
+		claudeTool := &dto.Tool{
+			Name:        tool.Function.Name,
+			Description: tool.Function.Description,
+			InputSchema: make(map[string]interface{}, len(params)),
+		}
+		if schemaType, ok := params["type"].(string); ok {
+			claudeTool.InputSchema["type"] = schemaType
+		}
+		claudeTool.InputSchema["properties"] = params["properties"]
+		claudeTool.InputSchema["required"] = params["required"]
+		for s, a := range params {
+			if s == "type" || s == "properties" || s == "required" {
+				continue
+			}
+			claudeTool.InputSchema[s] = a
+		}
+		claudeTools = append(claudeTools, claudeTool)
+	}
+	return claudeTools
+}
+
+// ConvertClaudeToolUseToOpenAI 将 Claude 的 tool_use 内容块转换为 OpenAI 的 tool_call，input 序列化为 arguments 字符串
+func ConvertClaudeToolUseToOpenAI(content dto.ClaudeMediaMessage) dto.ToolCallResponse {
+	args := "{}"
+	if content.Input != nil {
+		if data, err := json.Marshal(content.Input); err == nil {
+			args = string(data)
+		}
+	}
+	return dto.ToolCallResponse{
+		ID:   content.Id,
+		Type: "function", // compatible with other OpenAI derivative applications
+		Function: dto.FunctionResponse{
+			Name:      content.Name,
+			Arguments: args,
+		},
+	}
+}
+
+// claudeStreamToolCall 流式响应中一个 tool_use 内容块对应的工具调用
+type claudeStreamToolCall struct {
+	index int
+	id    string
+	name  string
+}
+
+// claudeToolCallTracker 记录流式响应中 tool_use 内容块与 OpenAI tool_calls 下标的对应关系。
+// Claude 的内容块下标包含文本与思考块，并行的多个工具调用需要重新从 0 开始编号
+type claudeToolCallTracker struct {
+	calls map[int]*claudeStreamToolCall
+}
+
+// start 在 content_block_start 时登记新的工具调用，下标按出现顺序递增
+func (t *claudeToolCallTracker) start(blockIndex int, id string, name string) *claudeStreamToolCall {
+	if t.calls == nil {
+		t.calls = make(map[int]*claudeStreamToolCall)
+	}
+	call := &claudeStreamToolCall{index: len(t.calls), id: id, name: name}
+	t.calls[blockIndex] = call
+	return call
+}
+
+// get 返回 input_json_delta 所属的工具调用，缺少 content_block_start 时按新的工具调用登记
+func (t *claudeToolCallTracker) get(blockIndex int) *claudeStreamToolCall {
+	if call, ok := t.calls[blockIndex]; ok {
+		return call
+	}
+	return t.start(blockIndex, "", "")
+}
+
+// toolCallDelta 生成 OpenAI 格式的 tool_calls 增量，首个增量携带 id 与函数名
+func (call *claudeStreamToolCall) toolCallDelta(arguments string, first bool) dto.ToolCallResponse {
+	delta := dto.ToolCallResponse{
+		Index: common.GetPointer(call.index),
+		Type:  "function",
+		Function: dto.FunctionResponse{
+			Arguments: arguments,
+		},
+	}
+	if first {
+		delta.ID = call.id
+		delta.Function.Name = call.name
+	}
+	return delta
+}
@@ -0,0 +1,96 @@
+package claude
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestConvertOpenAIToolsToClaudeTools(t *testing.T) {
+	tools := []dto.ToolCallRequest{
+		{
+			Type: "function",
+			Function: dto.FunctionRequest{
+				Name:        "get_weather",
+				Description: "Get the weather",
+				Parameters: map[string]any{
+					"type":                 "object",
+					"properties":           map[string]any{"city": map[string]any{"type": "string"}},
+					"required":             []any{"city"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		{Type: "function", Function: dto.FunctionRequest{Name: "no_params"}},
+	}
+	claudeTools := ConvertOpenAIToolsToClaudeTools(tools)
+	if len(claudeTools) != 1 {
+		t.Fatalf("len = %d, want 1", len(claudeTools))
+	}
+	tool := claudeTools[0]
+	if tool.Name != "get_weather" || tool.Description != "Get the weather" {
+		t.Fatalf("unexpected tool %+v", tool)
+	}
+	if tool.InputSchema["type"] != "object" || tool.InputSchema["additionalProperties"] != false {
+		t.Fatalf("unexpected input schema %+v", tool.InputSchema)
+	}
+}
+
+func TestConvertClaudeToolUseToOpenAI(t *testing.T) {
+	call := ConvertClaudeToolUseToOpenAI(dto.ClaudeMediaMessage{
+		Type:  "tool_use",
+		Id:    "toolu_1",
+		Name:  "get_weather",
+		Input: map[string]any{"city": "Paris"},
+	})
+	if call.ID != "toolu_1" || call.Type != "function" || call.Function.Name != "get_weather" {
+		t.Fatalf("unexpected tool call %+v", call)
+	}
+	if call.Function.Arguments != `{"city":"Paris"}` {
+		t.Fatalf("arguments = %s", call.Function.Arguments)
+	}
+	if empty := ConvertClaudeToolUseToOpenAI(dto.ClaudeMediaMessage{Type: "tool_use", Id: "toolu_2", Name: "now"}); empty.Function.Arguments != "{}" {
+		t.Fatalf("arguments without input = %s", empty.Function.Arguments)
+	}
+}
+
+func TestStreamResponseClaude2OpenAIParallelToolUse(t *testing.T) {
+	events := []string{
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"get_time","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{}"}}`,
+	}
+	claudeInfo := &ClaudeResponseInfo{Usage: &dto.Usage{}}
+	ids := map[int]string{}
+	args := map[int]string{}
+	for _, event := range events {
+		var claudeResponse dto.ClaudeResponse
+		if err := common.UnmarshalJsonStr(event, &claudeResponse); err != nil {
+			t.Fatalf("unmarshal event failed: %v", err)
+		}
+		response := StreamResponseClaude2OpenAI(RequestModeMessage, &claudeResponse, claudeInfo)
+		if response == nil || len(response.Choices) == 0 {
+			continue
+		}
+		for _, call := range response.Choices[0].Delta.ToolCalls {
+			if call.Index == nil {
+				t.Fatalf("tool call delta without index: %+v", call)
+			}
+			if call.ID != "" {
+				ids[*call.Index] = call.ID
+			}
+			args[*call.Index] += call.Function.Arguments
+		}
+	}
+	if ids[0] != "toolu_1" || ids[1] != "toolu_2" {
+		t.Fatalf("tool call ids = %v", ids)
+	}
+	if args[0] != `{"city":"Paris"}` || args[1] != "{}" {
+		t.Fatalf("tool call arguments = %v", args)
+	}
+}
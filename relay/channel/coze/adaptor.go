@@ -11,6 +11,7 @@ import (
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	"github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -75,7 +76,7 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *common.RelayInfo, requestBody
 	}
 	// 解析 resp
 	var cozeResponse CozeChatResponse
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +117,10 @@ func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
 
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
+
 // GetModelList implements channel.Adaptor.
 func (a *Adaptor) GetModelList() []string {
 	return ModelList
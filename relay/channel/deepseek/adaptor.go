@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strings"
 
+	channelconstant "github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	"github.com/QuantumNous/new-api/relay/channel/claude"
@@ -110,6 +111,14 @@ func (a *Adaptor) GetModelList() []string {
 	return ModelList
 }
 
+func (a *Adaptor) GetCapabilities() []string {
+	return []string{channelconstant.CapabilityStreaming, channelconstant.CapabilityTools}
+}
+
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
@@ -0,0 +1,283 @@
+package fal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Adaptor relays OpenAI image generation requests to the fal.ai queue API.
+// Submissions are always queued upstream; the image handler polls FetchTask
+// until the result is available.
+type Adaptor struct {
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	if info == nil {
+		return "", errors.New("fal adaptor: relay info is nil")
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL(info), "/"), normalizeModelID(info.UpstreamModelName)), nil
+}
+
+func baseURL(info *relaycommon.RelayInfo) string {
+	if info.ChannelBaseUrl != "" {
+		return info.ChannelBaseUrl
+	}
+	return constant.ChannelBaseURLs[constant.ChannelTypeFal]
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
+	if info == nil {
+		return errors.New("fal adaptor: relay info is nil")
+	}
+	if info.ApiKey == "" {
+		return errors.New("fal adaptor: api key is required")
+	}
+	channel.SetupApiRequestHeader(info, c, req)
+	req.Set("Authorization", "Key "+info.ApiKey)
+	req.Set("Content-Type", "application/json")
+	req.Set("Accept", "application/json")
+	return nil
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	if info == nil {
+		return nil, errors.New("fal adaptor: relay info is nil")
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		return nil, errors.New("fal adaptor: prompt is required")
+	}
+	if strings.TrimSpace(info.UpstreamModelName) == "" {
+		info.UpstreamModelName = request.Model
+	}
+	if strings.TrimSpace(info.UpstreamModelName) == "" {
+		info.UpstreamModelName = ModelFluxDev
+	}
+
+	input := map[string]any{
+		"prompt": request.Prompt,
+	}
+	if request.N > 0 {
+		input["num_images"] = int(request.N)
+	}
+	if size := strings.TrimSpace(request.Size); size != "" {
+		width, height, ok := parseSize(size)
+		if !ok {
+			return nil, fmt.Errorf("fal adaptor: invalid size %q, expected WIDTHxHEIGHT", size)
+		}
+		input["image_size"] = map[string]int{"width": width, "height": height}
+	}
+	if len(request.OutputFormat) > 0 {
+		var outputFormat string
+		if err := common.Unmarshal(request.OutputFormat, &outputFormat); err == nil && outputFormat != "" {
+			input["output_format"] = outputFormat
+		}
+	}
+	if imageURL := firstImage(request.Image); imageURL != "" {
+		input["image_url"] = imageURL
+	}
+	// sync_mode 让上游直接返回 data URI，避免再次下载图片
+	if strings.EqualFold(request.ResponseFormat, "b64_json") {
+		input["sync_mode"] = true
+	}
+
+	// 其余参数（seed、guidance_scale 等）原样透传给上游模型
+	for key, raw := range request.Extra {
+		if raw == nil {
+			continue
+		}
+		var val any
+		if err := common.Unmarshal(raw, &val); err != nil {
+			return nil, fmt.Errorf("fal adaptor: failed to decode extra field %s: %w", key, err)
+		}
+		input[key] = val
+	}
+	return input, nil
+}
+
+func parseSize(size string) (int, int, bool) {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if !ok {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 {
+		return 0, 0, false
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil || height <= 0 {
+		return 0, 0, false
+	}
+	return width, height, true
+}
+
+// firstImage 兼容 image 字段为字符串或字符串数组
+func firstImage(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var single string
+	if err := common.Unmarshal(raw, &single); err == nil {
+		return strings.TrimSpace(single)
+	}
+	var list []string
+	if err := common.Unmarshal(raw, &list); err == nil && len(list) > 0 {
+		return strings.TrimSpace(list[0])
+	}
+	return ""
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (any, *types.NewAPIError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
+	_ = resp.Body.Close()
+
+	var result ImageResult
+	if err := common.Unmarshal(responseBody, &result); err != nil {
+		return nil, types.NewError(fmt.Errorf("fal adaptor: failed to decode response: %w", err), types.ErrorCodeBadResponseBody)
+	}
+
+	wantsBase64 := false
+	if req, ok := info.Request.(*dto.ImageRequest); ok {
+		wantsBase64 = strings.EqualFold(req.ResponseFormat, "b64_json")
+	}
+
+	imageResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+		Data:    make([]dto.ImageData, 0, len(result.Images)),
+	}
+	for _, image := range result.Images {
+		if image.URL == "" {
+			continue
+		}
+		if !wantsBase64 {
+			imageResponse.Data = append(imageResponse.Data, dto.ImageData{Url: image.URL})
+			continue
+		}
+		if _, data, ok := strings.Cut(image.URL, ";base64,"); ok && strings.HasPrefix(image.URL, "data:") {
+			imageResponse.Data = append(imageResponse.Data, dto.ImageData{B64Json: data})
+			continue
+		}
+		_, data, err := service.GetImageFromUrl(image.URL)
+		if err != nil {
+			return nil, types.NewError(fmt.Errorf("fal adaptor: failed to download image: %w", err), types.ErrorCodeBadResponse)
+		}
+		imageResponse.Data = append(imageResponse.Data, dto.ImageData{B64Json: data})
+	}
+	if len(imageResponse.Data) == 0 {
+		return nil, types.NewError(errors.New("fal adaptor: empty image output"), types.ErrorCodeBadResponseBody)
+	}
+
+	responseBytes, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(fmt.Errorf("fal adaptor: encode response failed: %w", err), types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(responseBytes)
+
+	return &dto.Usage{}, nil
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return true
+}
+
+// ParseImageTask returns the encoded request path while the request is
+// still queued or running upstream, and "" once body holds the result.
+func (a *Adaptor) ParseImageTask(body []byte) (string, error) {
+	var qr QueueResponse
+	if err := common.Unmarshal(body, &qr); err != nil {
+		return "", fmt.Errorf("fal adaptor: failed to decode response: %w", err)
+	}
+	switch qr.Status {
+	case queueStatusInQueue, queueStatusInProgress:
+		path, err := requestPath(&qr, "")
+		if err != nil {
+			return "", err
+		}
+		return encodeTaskID(path), nil
+	case queueStatusFailed:
+		if qr.Error != "" {
+			return "", fmt.Errorf("fal adaptor: request failed: %s", qr.Error)
+		}
+		return "", errors.New("fal adaptor: request failed")
+	}
+	return "", nil
+}
+
+// FetchTask returns the queue status while the request is pending, and the
+// result once the request is completed.
+func (a *Adaptor) FetchTask(c *gin.Context, info *relaycommon.RelayInfo, taskID string) (*http.Response, error) {
+	path, err := decodeTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	do := func(req *http.Request) (*http.Response, error) {
+		return channel.DoRequest(c, req.WithContext(c.Request.Context()), info)
+	}
+	qr, resp, err := fetchQueueStatus(do, baseURL(info), info.ApiKey, path)
+	if err != nil || qr == nil || qr.Status != queueStatusCompleted {
+		return resp, err
+	}
+	return fetchQueueResult(do, baseURL(info), info.ApiKey, path)
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeneralOpenAIRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertOpenAIRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertRerankRequest(*gin.Context, int, dto.RerankRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertRerankRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(*gin.Context, *relaycommon.RelayInfo, dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertEmbeddingRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(*gin.Context, *relaycommon.RelayInfo, dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("fal adaptor: ConvertAudioRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(*gin.Context, *relaycommon.RelayInfo, dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertOpenAIResponsesRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertClaudeRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("fal adaptor: ConvertGeminiRequest is not implemented")
+}
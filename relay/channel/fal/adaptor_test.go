@@ -0,0 +1,165 @@
+package fal
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestPathAndTaskID(t *testing.T) {
+	if got := normalizeModelID("flux/dev"); got != "fal-ai/flux/dev" {
+		t.Errorf("normalizeModelID = %s", got)
+	}
+	if got := appID("fal-ai/kling-video/v2.1/master/text-to-video"); got != "fal-ai/kling-video" {
+		t.Errorf("appID = %s", got)
+	}
+
+	path, err := requestPath(&QueueResponse{RequestID: "abc", ResponseURL: "https://queue.fal.run/fal-ai/flux/requests/abc"}, "")
+	if err != nil || path != "fal-ai/flux/requests/abc" {
+		t.Errorf("requestPath from response_url = %q, err %v", path, err)
+	}
+	path, err = requestPath(&QueueResponse{RequestID: "abc"}, "fal-ai/flux/dev")
+	if err != nil || path != "fal-ai/flux/requests/abc" {
+		t.Errorf("requestPath fallback = %q, err %v", path, err)
+	}
+	if _, err := requestPath(&QueueResponse{}, "fal-ai/flux/dev"); err == nil {
+		t.Error("expected error without request_id")
+	}
+
+	decoded, err := decodeTaskID(encodeTaskID(path))
+	if err != nil || decoded != path {
+		t.Errorf("decodeTaskID = %q, err %v", decoded, err)
+	}
+	if _, err := decodeTaskID("not-a-task"); err == nil {
+		t.Error("expected error for invalid task id")
+	}
+}
+
+func TestParseImageTask(t *testing.T) {
+	a := &Adaptor{}
+	taskID, err := a.ParseImageTask([]byte(`{"request_id":"abc","status":"IN_QUEUE","response_url":"https://queue.fal.run/fal-ai/flux/requests/abc"}`))
+	if err != nil || taskID != encodeTaskID("fal-ai/flux/requests/abc") {
+		t.Errorf("queued task id = %q, err %v", taskID, err)
+	}
+	taskID, err = a.ParseImageTask([]byte(`{"images":[{"url":"https://example.com/a.png"}]}`))
+	if err != nil || taskID != "" {
+		t.Errorf("final result task id = %q, err %v", taskID, err)
+	}
+	if _, err := a.ParseImageTask([]byte(`{"request_id":"abc","status":"FAILED","error":"nsfw"}`)); err == nil {
+		t.Error("expected error for failed request")
+	}
+}
+
+func TestConvertImageRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "flux/dev"}}
+	req := dto.ImageRequest{
+		Prompt: "a cat",
+		N:      2,
+		Size:   "1024x768",
+		Image:  json.RawMessage(`["https://example.com/cat.png"]`),
+		Extra:  map[string]json.RawMessage{"seed": json.RawMessage(`42`)},
+	}
+	converted, err := (&Adaptor{}).ConvertImageRequest(c, info, req)
+	if err != nil {
+		t.Fatalf("ConvertImageRequest: %v", err)
+	}
+	data, _ := json.Marshal(converted)
+	var input map[string]any
+	_ = json.Unmarshal(data, &input)
+	size, _ := input["image_size"].(map[string]any)
+	if input["prompt"] != "a cat" || input["num_images"] != float64(2) || input["seed"] != float64(42) ||
+		input["image_url"] != "https://example.com/cat.png" || size["width"] != float64(1024) || size["height"] != float64(768) {
+		t.Errorf("unexpected input: %s", data)
+	}
+	if url, _ := (&Adaptor{}).GetRequestURL(info); url != "https://queue.fal.run/fal-ai/flux/dev" {
+		t.Errorf("url = %s", url)
+	}
+
+	req.Size = "large"
+	if _, err := (&Adaptor{}).ConvertImageRequest(c, info, req); err == nil {
+		t.Error("expected error for invalid size")
+	}
+}
+
+func TestParseTaskResultStatus(t *testing.T) {
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"request_id":"abc","status":"IN_QUEUE"}`, string(model.TaskStatusQueued)},
+		{`{"request_id":"abc","status":"IN_PROGRESS"}`, string(model.TaskStatusInProgress)},
+		{`{"request_id":"abc","status":"COMPLETED","response":{"video":{"url":"https://example.com/v.mp4"}}}`, string(model.TaskStatusSuccess)},
+		{`{"request_id":"abc","status":"COMPLETED","response":{}}`, string(model.TaskStatusFailure)},
+		{`{"request_id":"abc","status":"FAILED","error":"bad prompt"}`, string(model.TaskStatusFailure)},
+		{`{"request_id":"abc"}`, string(model.TaskStatusUnknown)},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		info, err := a.ParseTaskResult([]byte(tc.body))
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%s): %v", tc.body, err)
+		}
+		if info.Status != tc.want {
+			t.Errorf("%s: status = %s, want %s", tc.body, info.Status, tc.want)
+		}
+	}
+}
+
+func TestFetchTaskCombinesStatusAndResult(t *testing.T) {
+	service.InitHttpClient()
+	resultStatus := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Key secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/fal-ai/kling-video/requests/abc/status":
+			_, _ = w.Write([]byte(`{"request_id":"abc","status":"COMPLETED"}`))
+		case "/fal-ai/kling-video/requests/abc":
+			w.WriteHeader(resultStatus)
+			if resultStatus == http.StatusOK {
+				_, _ = w.Write([]byte(`{"video":{"url":"https://example.com/v.mp4"}}`))
+			} else {
+				_, _ = w.Write([]byte(`{"detail":"content policy violation"}`))
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	a := &TaskAdaptor{}
+	taskID := encodeTaskID("fal-ai/kling-video/requests/abc")
+	fetch := func() *relaycommon.TaskInfo {
+		resp, err := a.FetchTask(server.URL, "secret", map[string]any{"task_id": taskID}, "")
+		if err != nil {
+			t.Fatalf("FetchTask: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		info, err := a.ParseTaskResult(body)
+		if err != nil {
+			t.Fatalf("ParseTaskResult: %v", err)
+		}
+		return info
+	}
+
+	if info := fetch(); info.Status != string(model.TaskStatusSuccess) || info.Url != "https://example.com/v.mp4" {
+		t.Errorf("completed task = %+v", info)
+	}
+	resultStatus = http.StatusUnprocessableEntity
+	if info := fetch(); info.Status != string(model.TaskStatusFailure) || info.Reason != "content policy violation" {
+		t.Errorf("failed task = %+v", info)
+	}
+}
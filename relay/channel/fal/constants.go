@@ -0,0 +1,21 @@
+package fal
+
+const (
+	// ChannelName identifies the fal.ai channel.
+	ChannelName = "fal"
+	// ModelFluxDev is the default image generation model supported by this channel.
+	ModelFluxDev = "fal-ai/flux/dev"
+)
+
+var ModelList = []string{
+	ModelFluxDev,
+	"fal-ai/flux/schnell",
+	"fal-ai/flux-pro/v1.1",
+}
+
+var VideoModelList = []string{
+	"fal-ai/kling-video/v2.1/master/text-to-video",
+	"fal-ai/kling-video/v2.1/master/image-to-video",
+	"fal-ai/minimax/hailuo-02/standard/text-to-video",
+	"fal-ai/minimax/hailuo-02/standard/image-to-video",
+}
@@ -0,0 +1,52 @@
+package fal
+
+import "encoding/json"
+
+// Queue status values reported by fal.ai.
+const (
+	queueStatusInQueue    = "IN_QUEUE"
+	queueStatusInProgress = "IN_PROGRESS"
+	queueStatusCompleted  = "COMPLETED"
+	queueStatusFailed     = "FAILED"
+)
+
+// QueueResponse is returned by both the queue submit and the status endpoints.
+type QueueResponse struct {
+	RequestID     string `json:"request_id"`
+	Status        string `json:"status"`
+	ResponseURL   string `json:"response_url,omitempty"`
+	StatusURL     string `json:"status_url,omitempty"`
+	QueuePosition *int   `json:"queue_position,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+type File struct {
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// ImageResult is the result payload of fal.ai text-to-image models.
+type ImageResult struct {
+	Images []File `json:"images"`
+	Seed   int64  `json:"seed,omitempty"`
+}
+
+// VideoResult is the result payload of fal.ai video models.
+type VideoResult struct {
+	Video *File `json:"video,omitempty"`
+}
+
+// taskResult combines the queue status with the result payload once the
+// request is completed, so that a single FetchTask call yields everything
+// ParseTaskResult needs.
+type taskResult struct {
+	QueueResponse
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// errorResponse is returned by fal.ai when a request is rejected or fails.
+type errorResponse struct {
+	Detail json.RawMessage `json:"detail"`
+}
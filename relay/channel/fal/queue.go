@@ -0,0 +1,126 @@
+package fal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// normalizeModelID 补全 fal 模型 ID 的命名空间，例如 flux/dev -> fal-ai/flux/dev
+func normalizeModelID(model string) string {
+	model = strings.Trim(strings.TrimSpace(model), "/")
+	if model == "" || strings.HasPrefix(model, "fal-ai/") {
+		return model
+	}
+	return "fal-ai/" + model
+}
+
+// appID 返回状态与结果查询使用的应用 ID，即模型 ID 的前两段，例如
+// fal-ai/kling-video/v2.1/master/text-to-video -> fal-ai/kling-video
+func appID(modelID string) string {
+	parts := strings.SplitN(modelID, "/", 3)
+	if len(parts) < 2 {
+		return modelID
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// requestPath 返回排队请求的相对路径 {app}/requests/{request_id}，
+// 优先使用上游返回的 response_url，保证与上游实际路由一致
+func requestPath(qr *QueueResponse, modelID string) (string, error) {
+	if qr.ResponseURL != "" {
+		u, err := url.Parse(qr.ResponseURL)
+		if err == nil && strings.Contains(u.Path, "/requests/") {
+			return strings.Trim(u.Path, "/"), nil
+		}
+	}
+	if qr.RequestID == "" || modelID == "" {
+		return "", errors.New("fal: queue response without request_id")
+	}
+	return fmt.Sprintf("%s/requests/%s", appID(modelID), qr.RequestID), nil
+}
+
+// 本地任务 ID 编码了请求路径，轮询时无需额外保存模型信息，且保证 URL 安全
+func encodeTaskID(path string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(path))
+}
+
+func decodeTaskID(taskID string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(taskID)
+	if err != nil {
+		return "", fmt.Errorf("fal: invalid task id: %w", err)
+	}
+	path := string(b)
+	if !strings.Contains(path, "/requests/") {
+		return "", fmt.Errorf("fal: invalid task id: %s", taskID)
+	}
+	return path, nil
+}
+
+type doFunc func(req *http.Request) (*http.Response, error)
+
+func newQueueRequest(baseURL, key, path string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", strings.TrimSuffix(baseURL, "/"), path), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Key "+key)
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// fetchQueueStatus 查询排队请求状态，非 200 响应原样返回交给调用方处理
+func fetchQueueStatus(do doFunc, baseURL, key, path string) (*QueueResponse, *http.Response, error) {
+	req, err := newQueueRequest(baseURL, key, path+"/status")
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp, nil
+	}
+	body, err := service.ReadCompressedBody(resp)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	var qr QueueResponse
+	if err := common.Unmarshal(body, &qr); err != nil {
+		return nil, nil, fmt.Errorf("fal: failed to decode queue status: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return &qr, resp, nil
+}
+
+// fetchQueueResult 获取已完成请求的结果
+func fetchQueueResult(do doFunc, baseURL, key, path string) (*http.Response, error) {
+	req, err := newQueueRequest(baseURL, key, path)
+	if err != nil {
+		return nil, err
+	}
+	return do(req)
+}
+
+// errorDetail 提取 fal 错误响应中的 detail，detail 可能是字符串或校验错误数组
+func errorDetail(body []byte) string {
+	var er errorResponse
+	if err := common.Unmarshal(body, &er); err == nil && len(er.Detail) > 0 {
+		var detail string
+		if err := common.Unmarshal(er.Detail, &detail); err == nil {
+			return detail
+		}
+		return string(er.Detail)
+	}
+	return strings.TrimSpace(string(body))
+}
@@ -0,0 +1,253 @@
+package fal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// videoRequest 客户端请求结构，不嵌入 TaskSubmitReq 以保留 aspect_ratio 等扩展字段
+type videoRequest struct {
+	Model       string         `json:"model"`
+	Prompt      string         `json:"prompt,omitempty"`
+	Image       string         `json:"image,omitempty"`
+	Images      []string       `json:"images,omitempty"`
+	Duration    int            `json:"duration,omitempty"`
+	AspectRatio string         `json:"aspect_ratio,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
+}
+
+func (r *videoRequest) imageURL() string {
+	if strings.TrimSpace(r.Image) != "" {
+		return r.Image
+	}
+	if len(r.Images) > 0 {
+		return r.Images[0]
+	}
+	return ""
+}
+
+// TaskAdaptor relays video generation requests to the fal.ai queue API.
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	if a.baseURL == "" {
+		a.baseURL = constant.ChannelBaseURLs[constant.ChannelTypeFal]
+	}
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	req := videoRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt is required"), "invalid_request", http.StatusBadRequest)
+	}
+	if req.Duration < 0 {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("duration must be positive"), "invalid_request", http.StatusBadRequest)
+	}
+
+	info.Action = constant.TaskActionTextGenerate
+	if req.imageURL() != "" {
+		info.Action = constant.TaskActionGenerate
+	}
+	// 指定时长时按视频秒数计费，否则按次计费
+	if req.Duration > 0 {
+		info.PriceData.OtherRatios = map[string]float64{
+			"seconds": float64(req.Duration),
+		}
+	}
+
+	c.Set("fal_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(a.baseURL, "/"), normalizeModelID(info.UpstreamModelName)), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Key "+a.apiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("fal_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(videoRequest)
+
+	// metadata 中的模型专有参数原样透传，显式字段优先
+	input := make(map[string]any, len(req.Metadata)+4)
+	for key, val := range req.Metadata {
+		input[key] = val
+	}
+	input["prompt"] = req.Prompt
+	if imageURL := req.imageURL(); imageURL != "" {
+		input["image_url"] = imageURL
+	}
+	// fal 视频模型的 duration 多为字符串枚举，例如 "5"、"10"
+	if req.Duration > 0 {
+		input["duration"] = strconv.Itoa(req.Duration)
+	}
+	if req.AspectRatio != "" {
+		input["aspect_ratio"] = req.AspectRatio
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var qr QueueResponse
+	if err := json.Unmarshal(responseBody, &qr); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	path, err := requestPath(&qr, normalizeModelID(info.UpstreamModelName))
+	if err != nil {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("%w, response: %s", err, string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+
+	taskID = encodeTaskID(path)
+	c.JSON(http.StatusOK, gin.H{"task_id": taskID})
+	return taskID, responseBody, nil
+}
+
+// FetchTask 查询排队状态，完成后一并获取结果，组合为 taskResult 交给 ParseTaskResult
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	path, err := decodeTaskID(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl == "" {
+		baseUrl = constant.ChannelBaseURLs[constant.ChannelTypeFal]
+	}
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+
+	qr, resp, err := fetchQueueStatus(client.Do, baseUrl, key, path)
+	if err != nil || qr == nil || qr.Status != queueStatusCompleted {
+		return resp, err
+	}
+
+	resultResp, err := fetchQueueResult(client.Do, baseUrl, key, path)
+	if err != nil {
+		return nil, err
+	}
+	if resultResp.StatusCode >= http.StatusInternalServerError {
+		return resultResp, nil
+	}
+	resultBody, err := service.ReadCompressedBody(resultResp)
+	_ = resultResp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	tr := taskResult{QueueResponse: *qr}
+	if resultResp.StatusCode == http.StatusOK {
+		tr.Response = resultBody
+	} else {
+		// 请求已完成但模型执行出错时，结果接口返回 4xx 与错误详情
+		tr.Status = queueStatusFailed
+		tr.Error = errorDetail(resultBody)
+	}
+	data, err := common.Marshal(tr)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	resp.ContentLength = int64(len(data))
+	return resp, nil
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return VideoModelList
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var tr taskResult
+	if err := json.Unmarshal(respBody, &tr); err != nil {
+		return nil, err
+	}
+	res := &relaycommon.TaskInfo{TaskID: tr.RequestID}
+
+	switch tr.Status {
+	case queueStatusInQueue:
+		res.Status = model.TaskStatusQueued
+		res.Progress = "10%"
+	case queueStatusInProgress:
+		res.Status = model.TaskStatusInProgress
+		res.Progress = "50%"
+	case queueStatusCompleted:
+		var result VideoResult
+		if err := json.Unmarshal(tr.Response, &result); err != nil || result.Video == nil || result.Video.URL == "" {
+			res.Status = model.TaskStatusFailure
+			res.Progress = "100%"
+			res.Reason = "任务结果中没有视频"
+			break
+		}
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		res.Url = result.Video.URL
+	case queueStatusFailed:
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = tr.Error
+		if res.Reason == "" {
+			res.Reason = "任务执行失败"
+		}
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", tr.Status)
+	}
+	return res, nil
+}
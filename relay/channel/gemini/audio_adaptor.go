@@ -0,0 +1,191 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTTSVoice = "Kore"
+	// Gemini TTS 默认输出 16 位单声道 24kHz PCM
+	defaultTTSSampleRate = 24000
+)
+
+// OpenAI 内置音色到 Gemini 预置音色的映射，其他音色名原样透传
+var openAIVoiceToGemini = map[string]string{
+	"alloy":   "Kore",
+	"echo":    "Puck",
+	"fable":   "Charon",
+	"onyx":    "Fenrir",
+	"nova":    "Aoede",
+	"shimmer": "Leda",
+}
+
+type geminiSpeechConfig struct {
+	VoiceConfig geminiVoiceConfig `json:"voiceConfig"`
+}
+
+type geminiVoiceConfig struct {
+	PrebuiltVoiceConfig geminiPrebuiltVoiceConfig `json:"prebuiltVoiceConfig"`
+}
+
+type geminiPrebuiltVoiceConfig struct {
+	VoiceName string `json:"voiceName"`
+}
+
+// convertTTSRequest 将 OpenAI 格式的语音合成请求转换为 Gemini generateContent 的 speechConfig 请求
+func convertTTSRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
+	if info.RelayMode != constant.RelayModeAudioSpeech {
+		return nil, errors.New("unsupported audio relay mode")
+	}
+	if strings.TrimSpace(request.Input) == "" {
+		return nil, errors.New("input is required")
+	}
+	voice := request.Voice
+	if voice == "" {
+		voice = defaultTTSVoice
+	} else if mapped, ok := openAIVoiceToGemini[strings.ToLower(voice)]; ok {
+		voice = mapped
+	}
+	speechConfig, err := common.Marshal(geminiSpeechConfig{
+		VoiceConfig: geminiVoiceConfig{PrebuiltVoiceConfig: geminiPrebuiltVoiceConfig{VoiceName: voice}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Gemini TTS 通过提示词控制语气，instructions 作为前缀
+	text := request.Input
+	if request.Instructions != "" {
+		text = request.Instructions + ": " + request.Input
+	}
+	geminiRequest := dto.GeminiChatRequest{
+		Contents: []dto.GeminiChatContent{
+			{Role: "user", Parts: []dto.GeminiPart{{Text: text}}},
+		},
+		GenerationConfig: dto.GeminiChatGenerationConfig{
+			ResponseModalities: []string{"AUDIO"},
+			SpeechConfig:       speechConfig,
+		},
+	}
+	jsonData, err := common.Marshal(geminiRequest)
+	if err != nil {
+		return nil, err
+	}
+	c.Set("response_format", request.ResponseFormat)
+	return bytes.NewReader(jsonData), nil
+}
+
+// GeminiTTSHandler 解码响应中的 base64 音频并返回给客户端，按 usageMetadata 计费。
+// 上游返回 PCM 时默认封装为 wav，response_format 为 pcm 时返回原始 PCM；上游返回 mp3 时以 audio/mpeg 透传
+func GeminiTTSHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (*dto.Usage, *types.NewAPIError) {
+	body, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var geminiResponse dto.GeminiChatResponse
+	if err := common.Unmarshal(body, &geminiResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	var inlineData *dto.GeminiInlineData
+	for _, candidate := range geminiResponse.Candidates {
+		for _, part := range candidate.Content.Parts {
+			if part.InlineData != nil && part.InlineData.Data != "" {
+				inlineData = part.InlineData
+				break
+			}
+		}
+		if inlineData != nil {
+			break
+		}
+	}
+	if inlineData == nil {
+		return nil, types.NewOpenAIError(errors.New("no audio data in gemini tts response"), types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+	audio, err := base64.StdEncoding.DecodeString(inlineData.Data)
+	if err != nil {
+		return nil, types.NewOpenAIError(fmt.Errorf("failed to decode gemini audio data: %w", err), types.ErrorCodeBadResponse, http.StatusInternalServerError)
+	}
+
+	contentType, audio := encodeTTSAudio(inlineData.MimeType, c.GetString("response_format"), audio)
+	c.Data(http.StatusOK, contentType, audio)
+
+	usage := &dto.Usage{
+		PromptTokens:     geminiResponse.UsageMetadata.PromptTokenCount,
+		CompletionTokens: geminiResponse.UsageMetadata.CandidatesTokenCount,
+		TotalTokens:      geminiResponse.UsageMetadata.TotalTokenCount,
+	}
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = info.GetEstimatePromptTokens()
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage, nil
+}
+
+// encodeTTSAudio 根据上游 mime type 与请求的 response_format 决定返回的音频格式
+func encodeTTSAudio(mimeType string, responseFormat string, audio []byte) (string, []byte) {
+	mimeType = strings.ToLower(mimeType)
+	if strings.HasPrefix(mimeType, "audio/mpeg") || strings.HasPrefix(mimeType, "audio/mp3") {
+		return "audio/mpeg", audio
+	}
+	if !strings.Contains(mimeType, "pcm") && !strings.HasPrefix(mimeType, "audio/l16") {
+		return mimeType, audio
+	}
+	if responseFormat == "pcm" {
+		return "audio/pcm", audio
+	}
+	return "audio/wav", pcmToWav(audio, parsePCMSampleRate(mimeType))
+}
+
+// parsePCMSampleRate 解析形如 audio/L16;codec=pcm;rate=24000 的采样率
+func parsePCMSampleRate(mimeType string) int {
+	for _, param := range strings.Split(mimeType, ";") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(param), "rate="); ok {
+			if rate, err := strconv.Atoi(value); err == nil && rate > 0 {
+				return rate
+			}
+		}
+	}
+	return defaultTTSSampleRate
+}
+
+// pcmToWav 为 16 位单声道 PCM 数据添加 wav 文件头
+func pcmToWav(pcm []byte, sampleRate int) []byte {
+	const channels, bitsPerSample = 1, 16
+	byteRate := sampleRate * channels * bitsPerSample / 8
+	buf := bytes.NewBuffer(make([]byte, 0, 44+len(pcm)))
+	buf.WriteString("RIFF")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(buf, binary.LittleEndian, uint16(channels))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint32(byteRate))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(channels*bitsPerSample/8))
+	_ = binary.Write(buf, binary.LittleEndian, uint16(bitsPerSample))
+	buf.WriteString("data")
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConvertTTSRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{RelayMode: constant.RelayModeAudioSpeech}
+	reader, err := (&Adaptor{}).ConvertAudioRequest(c, info, dto.AudioRequest{Input: "hello", Voice: "nova", Instructions: "Say cheerfully", ResponseFormat: "wav"})
+	if err != nil {
+		t.Fatalf("ConvertAudioRequest: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	var got struct {
+		Contents []struct {
+			Parts []struct{ Text string } `json:"parts"`
+		} `json:"contents"`
+		GenerationConfig struct {
+			ResponseModalities []string `json:"responseModalities"`
+			SpeechConfig       struct {
+				VoiceConfig struct {
+					PrebuiltVoiceConfig struct{ VoiceName string } `json:"prebuiltVoiceConfig"`
+				} `json:"voiceConfig"`
+			} `json:"speechConfig"`
+		} `json:"generationConfig"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Contents[0].Parts[0].Text != "Say cheerfully: hello" ||
+		got.GenerationConfig.ResponseModalities[0] != "AUDIO" ||
+		got.GenerationConfig.SpeechConfig.VoiceConfig.PrebuiltVoiceConfig.VoiceName != "Aoede" {
+		t.Fatalf("unexpected request body: %s", data)
+	}
+}
+
+func TestGeminiTTSHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pcm := []byte{1, 2, 3, 4}
+	body := `{"candidates":[{"content":{"parts":[{"inlineData":{"mimeType":"audio/L16;codec=pcm;rate=16000","data":"` +
+		base64.StdEncoding.EncodeToString(pcm) + `"}}]}}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":30,"totalTokenCount":37}}`
+
+	for _, tc := range []struct {
+		format      string
+		contentType string
+		size        int
+	}{
+		{"wav", "audio/wav", 44 + len(pcm)},
+		{"pcm", "audio/pcm", len(pcm)},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("response_format", tc.format)
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewBufferString(body))}
+		usage, apiErr := GeminiTTSHandler(c, resp, &relaycommon.RelayInfo{})
+		if apiErr != nil {
+			t.Fatalf("%s: GeminiTTSHandler: %v", tc.format, apiErr)
+		}
+		if usage.PromptTokens != 7 || usage.CompletionTokens != 30 {
+			t.Fatalf("%s: unexpected usage %+v", tc.format, usage)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != tc.contentType || w.Body.Len() != tc.size {
+			t.Fatalf("%s: content-type %s, size %d", tc.format, ct, w.Body.Len())
+		}
+	}
+	if got := parsePCMSampleRate("audio/L16;codec=pcm;rate=16000"); got != 16000 {
+		t.Fatalf("parsePCMSampleRate = %d", got)
+	}
+}
@@ -0,0 +1,16 @@
+package openai
+
+import (
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// recordResponsesSession 记录上游返回的 response id 所属渠道，
+// 后续携带 previous_response_id 的请求由 distributor 路由回该渠道
+func recordResponsesSession(info *relaycommon.RelayInfo, response *dto.OpenAIResponsesResponse) {
+	if info == nil || response == nil || response.ID == "" {
+		return
+	}
+	service.RecordResponsesSession(response.ID, info.UserId, info.ChannelId)
+}
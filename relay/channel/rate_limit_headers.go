@@ -0,0 +1,64 @@
+package channel
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// upstreamHeaderPrefix 转发给客户端的上游响应头统一加此前缀，避免与本系统自身的限流头混淆
+const upstreamHeaderPrefix = "X-Upstream-"
+
+// rateLimitHeaderPrefixes 需要转发的上游限流响应头前缀（小写）
+var rateLimitHeaderPrefixes = []string{
+	"x-ratelimit-",
+	"anthropic-ratelimit-",
+}
+
+// blockedUpstreamHeaders 任何情况下都不转发的响应头（小写）
+var blockedUpstreamHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"x-api-key":           true,
+	"api-key":             true,
+	"x-goog-api-key":      true,
+	"cookie":              true,
+	"set-cookie":          true,
+}
+
+func isForwardableRateLimitHeader(key string) bool {
+	lower := strings.ToLower(key)
+	if blockedUpstreamHeaders[lower] {
+		return false
+	}
+	if lower == "retry-after" {
+		return true
+	}
+	for _, prefix := range rateLimitHeaderPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardRateLimitHeaders 将上游返回的限流相关响应头加上 X-Upstream- 前缀写入客户端响应，
+// 便于客户端自行退避。重试切换渠道时先清除上一次转发的头
+func forwardRateLimitHeaders(c *gin.Context, resp *http.Response) {
+	if c == nil || c.Writer == nil || resp == nil {
+		return
+	}
+	header := c.Writer.Header()
+	for key := range header {
+		if strings.HasPrefix(key, upstreamHeaderPrefix) {
+			header.Del(key)
+		}
+	}
+	for key, values := range resp.Header {
+		if len(values) == 0 || !isForwardableRateLimitHeader(key) {
+			continue
+		}
+		header.Set(upstreamHeaderPrefix+key, values[0])
+	}
+}
@@ -0,0 +1,72 @@
+package channel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForwardRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Writer.Header().Set("X-Upstream-X-Ratelimit-Remaining", "stale")
+	c.Writer.Header().Set("X-Request-Id", "req-1")
+
+	resp := &http.Response{Header: http.Header{}}
+	resp.Header.Set("X-RateLimit-Remaining", "0")
+	resp.Header.Set("X-RateLimit-Reset", "1700000000")
+	resp.Header.Set("x-ratelimit-remaining-tokens", "1200")
+	resp.Header.Set("Anthropic-Ratelimit-Requests-Remaining", "3")
+	resp.Header.Set("Retry-After", "20")
+	resp.Header.Set("Authorization", "Bearer sk-secret")
+	resp.Header.Set("X-Api-Key", "sk-secret")
+	resp.Header.Set("Set-Cookie", "session=1")
+	resp.Header.Set("Content-Type", "application/json")
+	forwardRateLimitHeaders(c, resp)
+
+	header := c.Writer.Header()
+	want := map[string]string{
+		"X-Upstream-X-Ratelimit-Remaining":                  "0",
+		"X-Upstream-X-Ratelimit-Reset":                      "1700000000",
+		"X-Upstream-X-Ratelimit-Remaining-Tokens":           "1200",
+		"X-Upstream-Anthropic-Ratelimit-Requests-Remaining": "3",
+		"X-Upstream-Retry-After":                            "20",
+		"X-Request-Id":                                      "req-1",
+	}
+	for k, v := range want {
+		if got := header.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	for _, k := range []string{"X-Upstream-Authorization", "X-Upstream-X-Api-Key", "X-Upstream-Set-Cookie", "X-Upstream-Content-Type", "Retry-After"} {
+		if got := header.Get(k); got != "" {
+			t.Errorf("%s should not be forwarded, got %q", k, got)
+		}
+	}
+
+	// 重试到其他渠道时，上一个渠道转发的头应被清除
+	forwardRateLimitHeaders(c, &http.Response{Header: http.Header{"Retry-After": {"5"}}})
+	if header.Get("X-Upstream-X-Ratelimit-Remaining") != "" || header.Get("X-Upstream-Retry-After") != "5" {
+		t.Errorf("unexpected headers after retry: %v", header)
+	}
+}
+
+func TestIsForwardableRateLimitHeader(t *testing.T) {
+	cases := map[string]bool{
+		"Retry-After":             true,
+		"X-RateLimit-Limit":       true,
+		"anthropic-ratelimit-foo": true,
+		"Authorization":           false,
+		"X-API-Key":               false,
+		"X-Request-Id":            false,
+		"RateLimit-Policy":        false,
+	}
+	for key, want := range cases {
+		if got := isForwardableRateLimitHeader(key); got != want {
+			t.Errorf("isForwardableRateLimitHeader(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
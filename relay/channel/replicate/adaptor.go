@@ -179,7 +179,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		return nil, types.NewError(errors.New("replicate adaptor: empty response"), types.ErrorCodeBadResponse)
 	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
 	}
@@ -298,6 +298,41 @@ func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
 
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return true
+}
+
+// ParseImageTask returns the prediction ID while the prediction is still
+// starting or processing, e.g. when Prefer: wait timed out upstream.
+func (a *Adaptor) ParseImageTask(body []byte) (string, error) {
+	var prediction PredictionResponse
+	if err := common.Unmarshal(body, &prediction); err != nil {
+		return "", fmt.Errorf("replicate adaptor: failed to decode response: %w", err)
+	}
+	switch strings.ToLower(prediction.Status) {
+	case "starting", "processing":
+		if prediction.ID == "" {
+			return "", errors.New("replicate adaptor: pending prediction without id")
+		}
+		return prediction.ID, nil
+	}
+	return "", nil
+}
+
+func (a *Adaptor) FetchTask(c *gin.Context, info *relaycommon.RelayInfo, taskID string) (*http.Response, error) {
+	baseURL := info.ChannelBaseUrl
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[info.ChannelType]
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, fmt.Sprintf("%s/v1/predictions/%s", strings.TrimSuffix(baseURL, "/"), taskID), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	req.Header.Set("Accept", "application/json")
+	return channel.DoRequest(c, req, info)
+}
+
 func downloadImagesToBase64(urls []string) ([]string, error) {
 	results := make([]string, 0, len(urls))
 	for _, url := range urls {
@@ -483,7 +518,7 @@ func uploadFileFromForm(c *gin.Context, info *relaycommon.RelayInfo, fieldCandid
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("replicate adaptor: read upload response failed: %w", err)
 	}
@@ -1,6 +1,7 @@
 package replicate
 
 type PredictionResponse struct {
+	ID     string           `json:"id"`
 	Status string           `json:"status"`
 	Output any              `json:"output"`
 	Error  *PredictionError `json:"error"`
@@ -23,7 +23,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type Adaptor struct{}
+// Adaptor 每次请求新建，ConvertImageRequest 解析出的模型与版本保存在适配器上供 GetRequestURL 使用，
+// 不修改共享的 RelayInfo，避免影响在其他渠道上的重试
+type Adaptor struct {
+	modelName string
+	version   string
+}
 
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 }
@@ -32,11 +37,42 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	if info == nil {
 		return "", errors.New("replicate2 adaptor: relay info is nil")
 	}
-	if info.ChannelBaseUrl == "" {
-		info.ChannelBaseUrl = constant.ChannelBaseURLs[constant.ChannelTypeReplicate2]
+	baseURL := info.ChannelBaseUrl
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[constant.ChannelTypeReplicate2]
+	}
+	modelName := a.modelName
+	if modelName == "" {
+		modelName = strings.TrimSpace(info.UpstreamModelName)
+	}
+	// 私有部署使用 /v1/deployments/{owner}/{name}/predictions 端点
+	if isDeploymentModel(modelName) {
+		path, err := deploymentPredictionsPath(modelName)
+		if err != nil {
+			return "", err
+		}
+		return relaycommon.GetFullRequestURL(baseURL, path, info.ChannelType), nil
 	}
-	// img2img 使用 /v1/predictions 端点
-	return relaycommon.GetFullRequestURL(info.ChannelBaseUrl, "/v1/predictions", info.ChannelType), nil
+	// 未指定版本的文生图请求使用 /v1/models/{owner}/{model}/predictions 端点
+	if a.modelName != "" && a.version == "" {
+		return relaycommon.GetFullRequestURL(baseURL, fmt.Sprintf("/v1/models/%s/predictions", a.modelName), info.ChannelType), nil
+	}
+	// 指定版本的请求使用 /v1/predictions 端点
+	return relaycommon.GetFullRequestURL(baseURL, "/v1/predictions", info.ChannelType), nil
+}
+
+// isDeploymentModel 判断模型名是否为私有部署 (deployments/owner/name)
+func isDeploymentModel(modelName string) bool {
+	return strings.HasPrefix(strings.TrimSpace(modelName), "deployments/")
+}
+
+// deploymentPredictionsPath 将 deployments/owner/name 转换为部署的 predictions 端点
+func deploymentPredictionsPath(modelName string) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(modelName), "deployments/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("replicate2 adaptor: invalid deployment model name %q, expected deployments/owner/name", modelName)
+	}
+	return fmt.Sprintf("/v1/deployments/%s/%s/predictions", parts[0], parts[1]), nil
 }
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
@@ -48,7 +84,10 @@ func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *rel
 	}
 	channel.SetupApiRequestHeader(info, c, req)
 	req.Set("Authorization", "Bearer "+info.ApiKey)
-	req.Set("Prefer", "wait")
+	// 流式请求通过 SSE 获取输出，不阻塞等待 prediction 完成
+	if !info.IsStream {
+		req.Set("Prefer", "wait")
+	}
 	if req.Get("Content-Type") == "" {
 		req.Set("Content-Type", "application/json")
 	}
@@ -69,19 +108,25 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 		modelName = strings.TrimSpace(request.Model)
 	}
 
-	// 从模型名中提取版本 ID
-	// 格式可能是: owner/model:version 或 owner/model-img2img:version
-	version := extractVersionFromModel(modelName)
-
-	// 如果模型名中没有版本，尝试从 Extra 字段获取
-	if version == "" {
-		if v, ok := getExtraField(request.Extra, "version"); ok {
-			version = v
+	// 私有部署 (deployments/owner/name) 固定了模型版本，不需要版本 ID
+	deployment := isDeploymentModel(modelName)
+	if deployment {
+		if _, err := deploymentPredictionsPath(modelName); err != nil {
+			return nil, err
 		}
 	}
 
-	if version == "" {
-		return nil, errors.New("replicate2 adaptor: version is required for img2img, please specify in model name (owner/model:version) or in 'version' field")
+	// 从模型名中提取版本 ID
+	// 格式可能是: owner/model:version 或 owner/model-img2img:version
+	version := ""
+	if !deployment {
+		version = extractVersionFromModel(modelName)
+		// 如果模型名中没有版本，尝试从 Extra 字段获取
+		if version == "" {
+			if v, ok := getExtraField(request.Extra, "version"); ok {
+				version = v
+			}
+		}
 	}
 
 	// 构建 input payload
@@ -98,22 +143,31 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 		inputPayload["prompt"] = prompt
 	}
 
-	// 处理图片 - 从多个来源获取
+	// 处理图片 - 从多个来源获取，没有图片时按文生图处理
 	imageURL, err := getImageFromRequest(c, info, request)
 	if err != nil {
 		return nil, err
 	}
-	if imageURL == "" {
-		return nil, errors.New("replicate2 adaptor: image is required for img2img")
-	}
-	inputPayload["image"] = imageURL
+	if imageURL != "" {
+		if version == "" && !deployment {
+			return nil, errors.New("replicate2 adaptor: version is required for img2img, please specify in model name (owner/model:version) or in 'version' field")
+		}
+		inputPayload["image"] = imageURL
 
-	// 处理 strength (图生图强度)
-	if strength, ok := getExtraFieldFloat(request.Extra, "strength"); ok {
-		inputPayload["strength"] = strength
+		// 处理 strength (图生图强度)
+		if strength, ok := getExtraFieldFloat(request.Extra, "strength"); ok {
+			inputPayload["strength"] = strength
+		} else {
+			// 默认 strength
+			inputPayload["strength"] = 0.6
+		}
 	} else {
-		// 默认 strength
-		inputPayload["strength"] = 0.6
+		if prompt == "" {
+			return nil, errors.New("replicate2 adaptor: prompt is required for text-to-image")
+		}
+		if request.N > 1 {
+			inputPayload["num_outputs"] = request.N
+		}
 	}
 
 	// 处理 output_format
@@ -156,7 +210,7 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 			continue
 		}
 		// 跳过已处理的字段
-		if key == "version" || key == "strength" || contains(extraParams, key) {
+		if key == "version" || key == "strength" || key == "stream" || contains(extraParams, key) {
 			continue
 		}
 		if raw == nil {
@@ -168,9 +222,13 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 		}
 	}
 
+	// 官方模型可以不指定版本，由 GetRequestURL 改用模型的 predictions 端点
+	a.modelName, a.version = modelName, version
+
 	return PredictionRequest{
 		Version: version,
 		Input:   inputPayload,
+		Stream:  isStreamRequest(request),
 	}, nil
 }
 
@@ -183,7 +241,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		return nil, types.NewError(errors.New("replicate2 adaptor: empty response"), types.ErrorCodeBadResponse)
 	}
 
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
 	}
@@ -195,54 +253,90 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	}
 
 	if prediction.Error != nil {
-		errMsg := prediction.Error.Message
-		if errMsg == "" {
-			errMsg = prediction.Error.Detail
-		}
-		if errMsg == "" {
-			errMsg = prediction.Error.Code
-		}
-		if errMsg == "" {
-			errMsg = "replicate2 adaptor: prediction error"
-		}
-		return nil, types.NewError(errors.New(errMsg), types.ErrorCodeBadResponse)
+		return nil, types.NewError(errors.New(prediction.Error.String()), types.ErrorCodeBadResponse)
+	}
+
+	if info != nil && info.IsStream {
+		return predictionStreamHandler(c, info, &prediction)
 	}
 
 	if prediction.Status != "" && !strings.EqualFold(prediction.Status, "succeeded") {
 		return nil, types.NewError(fmt.Errorf("replicate2 adaptor: prediction status %q", prediction.Status), types.ErrorCodeBadResponse)
 	}
 
-	var urls []string
+	imageResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+	}
+	data, newAPIError := buildImageData(flattenOutput(prediction.Output), info)
+	if newAPIError != nil {
+		return nil, newAPIError
+	}
+	imageResponse.Data = data
 
-	appendOutput := func(value string) {
-		value = strings.TrimSpace(value)
-		if value == "" {
-			return
-		}
-		urls = append(urls, value)
+	if len(imageResponse.Data) == 0 {
+		return nil, types.NewError(errors.New("replicate2 adaptor: no usable image data"), types.ErrorCodeBadResponse)
 	}
 
-	switch output := prediction.Output.(type) {
-	case string:
-		appendOutput(output)
-	case []any:
-		for _, item := range output {
-			if str, ok := item.(string); ok {
-				appendOutput(str)
-			}
-		}
-	case nil:
-		// no output
-	default:
-		if str, ok := output.(fmt.Stringer); ok {
-			appendOutput(str.String())
+	responseBytes, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(fmt.Errorf("replicate2 adaptor: encode response failed: %w", err), types.ErrorCodeBadResponseBody)
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(responseBytes)
+
+	usage := &dto.Usage{}
+	return usage, nil
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return true
+}
+
+// ParseImageTask returns the prediction ID while the prediction is still
+// starting or processing, e.g. when Prefer: wait timed out upstream.
+func (a *Adaptor) ParseImageTask(body []byte) (string, error) {
+	var prediction PredictionResponse
+	if err := common.Unmarshal(body, &prediction); err != nil {
+		return "", fmt.Errorf("replicate2 adaptor: failed to decode response: %w", err)
+	}
+	switch strings.ToLower(prediction.Status) {
+	case "starting", "processing":
+		if prediction.ID == "" {
+			return "", errors.New("replicate2 adaptor: pending prediction without id")
 		}
+		return prediction.ID, nil
 	}
+	return "", nil
+}
 
-	if len(urls) == 0 {
-		return nil, types.NewError(errors.New("replicate2 adaptor: empty prediction output"), types.ErrorCodeBadResponseBody)
+func (a *Adaptor) FetchTask(c *gin.Context, info *relaycommon.RelayInfo, taskID string) (*http.Response, error) {
+	baseURL := info.ChannelBaseUrl
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[info.ChannelType]
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, fmt.Sprintf("%s/v1/predictions/%s", strings.TrimSuffix(baseURL, "/"), taskID), http.NoBody)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	req.Header.Set("Accept", "application/json")
+	return channel.DoRequest(c, req, info)
+}
+
+// Helper functions
 
+// buildImageData 将 prediction 输出转换为 OpenAI 图片数据
+func buildImageData(outputs []any, info *relaycommon.RelayInfo) ([]dto.ImageData, *types.NewAPIError) {
 	var imageReq *dto.ImageRequest
 	if info != nil {
 		if req, ok := info.Request.(*dto.ImageRequest); ok {
@@ -250,13 +344,45 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		}
 	}
 
-	wantsBase64 := imageReq != nil && strings.EqualFold(imageReq.ResponseFormat, "b64_json")
+	data := make([]dto.ImageData, 0)
+	outputType := detectOutputType(outputs)
+	if info != nil && isJSONOutputModel(info.UpstreamModelName) {
+		outputType = OutputTypeJSON
+	}
 
-	imageResponse := dto.ImageResponse{
-		Created: common.GetTimestamp(),
-		Data:    make([]dto.ImageData, 0),
+	if outputType == OutputTypeJSON {
+		jsonData, convErr := jsonOutputToImageData(outputs)
+		if convErr != nil {
+			return nil, types.NewError(convErr, types.ErrorCodeBadResponseBody)
+		}
+		return jsonData, nil
 	}
 
+	var urls []string
+	for _, item := range outputs {
+		str, ok := item.(string)
+		if !ok {
+			continue
+		}
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+		// data: URL 直接作为 base64 返回
+		if strings.HasPrefix(str, "data:") {
+			if idx := strings.Index(str, ","); idx != -1 {
+				data = append(data, dto.ImageData{B64Json: str[idx+1:]})
+			}
+			continue
+		}
+		urls = append(urls, str)
+	}
+
+	if len(urls) == 0 && len(data) == 0 {
+		return nil, types.NewError(errors.New("replicate2 adaptor: empty prediction output"), types.ErrorCodeBadResponseBody)
+	}
+
+	wantsBase64 := imageReq != nil && strings.EqualFold(imageReq.ResponseFormat, "b64_json")
 	if wantsBase64 {
 		converted, convErr := downloadImagesToBase64(urls)
 		if convErr != nil {
@@ -266,44 +392,82 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 			if content == "" {
 				continue
 			}
-			imageResponse.Data = append(imageResponse.Data, dto.ImageData{B64Json: content})
+			data = append(data, dto.ImageData{B64Json: content})
 		}
 	} else {
 		for _, url := range urls {
-			if url == "" {
-				continue
-			}
-			imageResponse.Data = append(imageResponse.Data, dto.ImageData{Url: url})
+			data = append(data, dto.ImageData{Url: url})
 		}
 	}
+	return data, nil
+}
 
-	if len(imageResponse.Data) == 0 {
-		return nil, types.NewError(errors.New("replicate2 adaptor: no usable image data"), types.ErrorCodeBadResponse)
+// flattenOutput 将 prediction.Output 统一为列表，并去除空值
+func flattenOutput(output any) []any {
+	switch v := output.(type) {
+	case nil:
+		return nil
+	case []any:
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				items = append(items, item)
+			}
+		}
+		return items
+	case fmt.Stringer:
+		return []any{v.String()}
+	default:
+		return []any{v}
 	}
+}
 
-	responseBytes, err := common.Marshal(imageResponse)
-	if err != nil {
-		return nil, types.NewError(fmt.Errorf("replicate2 adaptor: encode response failed: %w", err), types.ErrorCodeBadResponseBody)
+// detectOutputType 根据第一个非空输出判断输出类型
+func detectOutputType(outputs []any) string {
+	for _, item := range outputs {
+		switch v := item.(type) {
+		case map[string]any:
+			return OutputTypeJSON
+		case string:
+			v = strings.TrimSpace(v)
+			if strings.HasPrefix(v, "data:") {
+				return OutputTypeBase64
+			}
+			if strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "http://") {
+				return OutputTypeURL
+			}
+			if v == "" {
+				continue
+			}
+			return ""
+		default:
+			return ""
+		}
 	}
-
-	c.Writer.Header().Set("Content-Type", "application/json")
-	c.Writer.WriteHeader(http.StatusOK)
-	_, _ = c.Writer.Write(responseBytes)
-
-	usage := &dto.Usage{}
-	return usage, nil
+	return ""
 }
 
-func (a *Adaptor) GetModelList() []string {
-	return ModelList
+func isJSONOutputModel(modelName string) bool {
+	// 去掉版本号后匹配 owner/model
+	if idx := strings.Index(modelName, ":"); idx != -1 {
+		modelName = modelName[:idx]
+	}
+	return contains(JSONOutputModels, modelName)
 }
 
-func (a *Adaptor) GetChannelName() string {
-	return ChannelName
+// jsonOutputToImageData 将 JSON 输出序列化为 base64 编码的 application/json 内容
+func jsonOutputToImageData(outputs []any) ([]dto.ImageData, error) {
+	data := make([]dto.ImageData, 0, len(outputs))
+	for _, item := range outputs {
+		encoded, err := common.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("replicate2 adaptor: encode json output failed: %w", err)
+		}
+		data = append(data, dto.ImageData{B64Json: base64.StdEncoding.EncodeToString(encoded)})
+	}
+	return data, nil
 }
 
-// Helper functions
-
 func extractVersionFromModel(model string) string {
 	// 格式: owner/model:version_hash
 	if idx := strings.LastIndex(model, ":"); idx != -1 {
@@ -325,6 +489,19 @@ func getExtraField(extra map[string]json.RawMessage, key string) (string, bool)
 	return "", false
 }
 
+func getExtraFieldBool(extra map[string]json.RawMessage, key string) (bool, bool) {
+	if extra == nil {
+		return false, false
+	}
+	if raw, ok := extra[key]; ok {
+		var b bool
+		if err := common.Unmarshal(raw, &b); err == nil {
+			return b, true
+		}
+	}
+	return false, false
+}
+
 func getExtraFieldFloat(extra map[string]json.RawMessage, key string) (float64, bool) {
 	if extra == nil {
 		return 0, false
@@ -515,7 +692,7 @@ func uploadToReplicate(info *relaycommon.RelayInfo, fileContent []byte, filename
 	}
 	defer resp.Body.Close()
 
-	respBody, err := io.ReadAll(resp.Body)
+	respBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", fmt.Errorf("replicate2 adaptor: read upload response failed: %w", err)
 	}
@@ -0,0 +1,217 @@
+package replicate2
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type capturedRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]any
+}
+
+func TestConvertImageRequestBodyShape(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	var captured capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		captured = capturedRequest{Path: r.URL.Path, Header: r.Header.Clone()}
+		_ = json.Unmarshal(body, &captured.Body)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"p-1","status":"succeeded","output":["https://replicate.delivery/out.png"]}`))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		model     string
+		request   dto.ImageRequest
+		wantPath  string
+		wantInput map[string]any
+		wantNoKey []string
+	}{
+		{
+			name:      "text-to-image official model",
+			model:     "black-forest-labs/flux-schnell",
+			request:   dto.ImageRequest{Prompt: "a red fox", N: 2},
+			wantPath:  "/v1/models/black-forest-labs/flux-schnell/predictions",
+			wantInput: map[string]any{"prompt": "a red fox", "num_outputs": float64(2)},
+			wantNoKey: []string{"image", "strength"},
+		},
+		{
+			name:      "text-to-image versioned model",
+			model:     "stability-ai/sdxl:39ed52f2",
+			request:   dto.ImageRequest{Prompt: "a red fox", N: 1},
+			wantPath:  "/v1/predictions",
+			wantInput: map[string]any{"prompt": "a red fox"},
+			wantNoKey: []string{"image", "strength", "num_outputs"},
+		},
+		{
+			name:      "img2img",
+			model:     "prunaai/z-image-turbo-img2img:abc123",
+			request:   dto.ImageRequest{Prompt: "make it blue", Image: json.RawMessage(`"https://example.com/in.png"`)},
+			wantPath:  "/v1/predictions",
+			wantInput: map[string]any{"prompt": "make it blue", "image": "https://example.com/in.png", "strength": 0.6},
+		},
+		{
+			name:      "text-to-image deployment",
+			model:     "deployments/acme/flux-private",
+			request:   dto.ImageRequest{Prompt: "a red fox", N: 1},
+			wantPath:  "/v1/deployments/acme/flux-private/predictions",
+			wantInput: map[string]any{"prompt": "a red fox"},
+			wantNoKey: []string{"image", "strength"},
+		},
+		{
+			name:      "img2img deployment without version",
+			model:     "deployments/acme/img2img",
+			request:   dto.ImageRequest{Prompt: "make it blue", Image: json.RawMessage(`"https://example.com/in.png"`)},
+			wantPath:  "/v1/deployments/acme/img2img/predictions",
+			wantInput: map[string]any{"prompt": "make it blue", "image": "https://example.com/in.png"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil)
+			c.Request.Header.Set("Content-Type", "application/json")
+			info := &relaycommon.RelayInfo{
+				RequestURLPath: "/v1/images/generations",
+				Request:        &tt.request,
+				ChannelMeta: &relaycommon.ChannelMeta{
+					ChannelBaseUrl:    server.URL,
+					ApiKey:            "r8-test",
+					UpstreamModelName: tt.model,
+				},
+			}
+			a := &Adaptor{}
+			converted, err := a.ConvertImageRequest(c, info, tt.request)
+			if err != nil {
+				t.Fatalf("ConvertImageRequest returned error: %v", err)
+			}
+			body, err := common.Marshal(converted)
+			if err != nil {
+				t.Fatalf("marshal request failed: %v", err)
+			}
+			resp, err := a.DoRequest(c, info, bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("DoRequest returned error: %v", err)
+			}
+			_ = resp.(*http.Response).Body.Close()
+
+			if captured.Path != tt.wantPath {
+				t.Fatalf("expected path %s, got %s", tt.wantPath, captured.Path)
+			}
+			if captured.Header.Get("Authorization") != "Bearer r8-test" || captured.Header.Get("Prefer") != "wait" {
+				t.Fatalf("unexpected headers: %v", captured.Header)
+			}
+			if _, hasVersion := captured.Body["version"]; hasVersion != (tt.wantPath == "/v1/predictions") {
+				t.Fatalf("unexpected version field in body: %v", captured.Body)
+			}
+			input, _ := captured.Body["input"].(map[string]any)
+			for k, want := range tt.wantInput {
+				if input[k] != want {
+					t.Fatalf("input[%s]: expected %v, got %v", k, want, input[k])
+				}
+			}
+			for _, k := range tt.wantNoKey {
+				if _, ok := input[k]; ok {
+					t.Fatalf("input should not contain %s: %v", k, input)
+				}
+			}
+		})
+	}
+}
+
+func TestDeploymentPredictionsPath(t *testing.T) {
+	if path, err := deploymentPredictionsPath("deployments/acme/sdxl"); err != nil || path != "/v1/deployments/acme/sdxl/predictions" {
+		t.Fatalf("unexpected path %q, err %v", path, err)
+	}
+	for _, name := range []string{"deployments/acme", "deployments/acme/sdxl/extra", "deployments//sdxl"} {
+		if _, err := deploymentPredictionsPath(name); err == nil {
+			t.Fatalf("expected error for %q", name)
+		}
+	}
+}
+
+func TestDetectOutputType(t *testing.T) {
+	cases := []struct {
+		name    string
+		outputs []any
+		want    string
+	}{
+		{"png url", []any{"https://replicate.delivery/out-0.png"}, OutputTypeURL},
+		{"jpg url", []any{"https://replicate.delivery/out-0.jpg"}, OutputTypeURL},
+		{"jpeg url", []any{"https://replicate.delivery/out-0.jpeg"}, OutputTypeURL},
+		{"webp url", []any{"https://replicate.delivery/out-0.webp"}, OutputTypeURL},
+		{"gif url", []any{"https://replicate.delivery/out-0.gif"}, OutputTypeURL},
+		{"mp4 url", []any{"https://replicate.delivery/out-0.mp4"}, OutputTypeURL},
+		{"json url", []any{"https://replicate.delivery/out-0.json"}, OutputTypeURL},
+		{"plain http url", []any{"http://127.0.0.1/out-0.png?sig=1"}, OutputTypeURL},
+		{"png data uri", []any{"data:image/png;base64,iVBORw0KGgo="}, OutputTypeBase64},
+		{"jpeg data uri", []any{"data:image/jpeg;base64,/9j/4AAQ"}, OutputTypeBase64},
+		{"webp data uri", []any{"data:image/webp;base64,UklGRg=="}, OutputTypeBase64},
+		{"gif data uri", []any{"data:image/gif;base64,R0lGODlh"}, OutputTypeBase64},
+		{"mp4 data uri", []any{"data:video/mp4;base64,AAAAIGZ0eXA="}, OutputTypeBase64},
+		{"json data uri", []any{"data:application/json;base64,e30="}, OutputTypeBase64},
+		{"json object", []any{map[string]any{"caption": "a cat"}}, OutputTypeJSON},
+		{"skips empty strings", []any{"", "  ", "https://replicate.delivery/out-0.png"}, OutputTypeURL},
+		{"plain text", []any{"a cat sitting on a mat"}, ""},
+		{"number", []any{0.87}, ""},
+		{"empty", nil, ""},
+	}
+	for _, tc := range cases {
+		if got := detectOutputType(tc.outputs); got != tc.want {
+			t.Errorf("%s: detectOutputType = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseImageTaskAndFetch(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	a := &Adaptor{}
+	if id, err := a.ParseImageTask([]byte(`{"id":"p-1","status":"processing"}`)); err != nil || id != "p-1" {
+		t.Fatalf("processing prediction: id %q, err %v", id, err)
+	}
+	if id, err := a.ParseImageTask([]byte(`{"id":"p-1","status":"succeeded","output":["https://replicate.delivery/out.png"]}`)); err != nil || id != "" {
+		t.Fatalf("succeeded prediction should be final: id %q, err %v", id, err)
+	}
+	if _, err := a.ParseImageTask([]byte(`{"status":"starting"}`)); err == nil {
+		t.Fatal("expected error for pending prediction without id")
+	}
+
+	var captured capturedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = capturedRequest{Path: r.URL.Path, Header: r.Header.Clone()}
+		_, _ = w.Write([]byte(`{"id":"p-1","status":"succeeded"}`))
+	}))
+	defer server.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil)
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{ChannelBaseUrl: server.URL, ApiKey: "r8-test"}}
+	resp, err := a.FetchTask(c, info, "p-1")
+	if err != nil {
+		t.Fatalf("FetchTask returned error: %v", err)
+	}
+	_ = resp.Body.Close()
+	if captured.Path != "/v1/predictions/p-1" || captured.Header.Get("Authorization") != "Bearer r8-test" {
+		t.Fatalf("unexpected fetch request: %+v", captured)
+	}
+}
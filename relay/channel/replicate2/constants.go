@@ -1,12 +1,31 @@
 package replicate2
 
 const (
-	// ChannelName identifies the replicate2 channel (img2img).
+	// ChannelName identifies the replicate2 channel (img2img and text-to-image).
 	ChannelName = "replicate2"
 )
 
-// ModelList contains supported img2img models
+// ModelList contains supported img2img and text-to-image models
 var ModelList = []string{
 	"prunaai/z-image-turbo-img2img",
+	// text-to-image
+	"black-forest-labs/flux-schnell",
+	"black-forest-labs/flux-dev",
+	"black-forest-labs/flux-1.1-pro",
+	"black-forest-labs/flux-1.1-pro-ultra",
+	"stability-ai/stable-diffusion-3.5-large",
+	"stability-ai/stable-diffusion-3.5-large-turbo",
+	"stability-ai/stable-diffusion-3.5-medium",
+	// Private deployments are addressed as "deployments/{owner}/{name}" and
+	// routed to /v1/deployments/{owner}/{name}/predictions without a version
+	// hash. They are account specific, so add them to the channel's model
+	// list instead of this default list.
 }
 
+// JSONOutputModels lists models known to return JSON objects (metadata,
+// analysis results) instead of image URLs.
+var JSONOutputModels = []string{
+	"yorickvp/llava-13b",
+	"andreasjansson/clip-features",
+	"salesforce/blip",
+}
@@ -0,0 +1,225 @@
+package replicate2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newImageContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil)
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+func rawExtra(fields map[string]string) map[string]json.RawMessage {
+	extra := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		extra[k] = json.RawMessage(v)
+	}
+	return extra
+}
+
+func TestConvertImageRequestInput(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		model    string
+		request  dto.ImageRequest
+		wantPath string
+		want     PredictionRequest
+	}{
+		{
+			name:  "output format and extra params",
+			model: "black-forest-labs/flux-dev",
+			request: dto.ImageRequest{
+				Prompt:       "a red fox",
+				OutputFormat: json.RawMessage(`"webp"`),
+				ExtraFields:  json.RawMessage(`{"aspect_ratio":"16:9","version":"ignored"}`),
+				Extra: rawExtra(map[string]string{
+					"guidance_scale": `3.5`,
+					"seed":           `7`,
+					"input":          `{"go_fast":true}`,
+					"megapixels":     `"1"`,
+					"stream":         `false`,
+				}),
+			},
+			wantPath: "/v1/models/black-forest-labs/flux-dev/predictions",
+			want: PredictionRequest{Input: map[string]any{
+				"prompt":         "a red fox",
+				"output_format":  "webp",
+				"guidance_scale": 3.5,
+				"seed":           float64(7),
+				"aspect_ratio":   "16:9",
+				"go_fast":        true,
+				"megapixels":     "1",
+			}},
+		},
+		{
+			name:  "version and strength from extra",
+			model: "prunaai/img2img",
+			request: dto.ImageRequest{
+				Prompt: "make it blue",
+				Extra: rawExtra(map[string]string{
+					"version":  `"abc123"`,
+					"image":    `"https://example.com/in.png"`,
+					"strength": `0.3`,
+				}),
+			},
+			wantPath: "/v1/predictions",
+			want: PredictionRequest{Version: "abc123", Input: map[string]any{
+				"prompt":   "make it blue",
+				"image":    "https://example.com/in.png",
+				"strength": 0.3,
+			}},
+		},
+		{
+			name:  "stream request",
+			model: "stability-ai/sdxl:39ed52f2",
+			request: dto.ImageRequest{
+				Prompt: "a red fox",
+				Extra:  rawExtra(map[string]string{"stream": `true`}),
+			},
+			wantPath: "/v1/predictions",
+			want:     PredictionRequest{Version: "stability-ai/sdxl:39ed52f2", Stream: true, Input: map[string]any{"prompt": "a red fox"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &relaycommon.RelayInfo{
+				RequestURLPath: "/v1/images/generations",
+				ChannelMeta:    &relaycommon.ChannelMeta{UpstreamModelName: tt.model},
+			}
+			a := &Adaptor{}
+			converted, err := a.ConvertImageRequest(newImageContext(), info, tt.request)
+			if err != nil {
+				t.Fatalf("ConvertImageRequest returned error: %v", err)
+			}
+			// 统一经过 JSON 往返后比较，避免数值类型差异
+			var got, want map[string]any
+			gotBytes, _ := json.Marshal(converted)
+			wantBytes, _ := json.Marshal(tt.want)
+			_ = json.Unmarshal(gotBytes, &got)
+			_ = json.Unmarshal(wantBytes, &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected prediction request:\n got %s\nwant %s", gotBytes, wantBytes)
+			}
+			if info.IsStream || info.RequestURLPath != "/v1/images/generations" {
+				t.Fatalf("relay info should not be modified: stream=%v path=%s", info.IsStream, info.RequestURLPath)
+			}
+			url, err := a.GetRequestURL(info)
+			if err != nil || !strings.HasSuffix(url, tt.wantPath) {
+				t.Fatalf("request url = %s, %v; want path %s", url, err, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestConvertImageRequestErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	a := &Adaptor{}
+	if _, err := a.ConvertImageRequest(newImageContext(), nil, dto.ImageRequest{Prompt: "a red fox"}); err == nil {
+		t.Fatal("expected error for nil relay info")
+	}
+
+	tests := []struct {
+		name    string
+		model   string
+		request dto.ImageRequest
+		wantErr string
+	}{
+		{
+			name:    "invalid deployment",
+			model:   "deployments/acme",
+			request: dto.ImageRequest{Prompt: "a red fox"},
+			wantErr: "deployment",
+		},
+		{
+			name:    "img2img without version",
+			model:   "prunaai/img2img",
+			request: dto.ImageRequest{Prompt: "make it blue", Image: json.RawMessage(`"https://example.com/in.png"`)},
+			wantErr: "version is required",
+		},
+		{
+			name:    "text-to-image without prompt",
+			model:   "black-forest-labs/flux-schnell",
+			request: dto.ImageRequest{},
+			wantErr: "prompt is required",
+		},
+		{
+			name:    "invalid data url",
+			model:   "prunaai/img2img:abc123",
+			request: dto.ImageRequest{Prompt: "make it blue", Image: json.RawMessage(`"data:image/png;base64"`)},
+			wantErr: "invalid data URL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: tt.model}}
+			_, err := a.ConvertImageRequest(newImageContext(), info, tt.request)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConvertImageRequestUploadsBase64Image(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	var uploadPath, uploadType, uploadAuth string
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{
+		{
+			StatusCode: http.StatusCreated,
+			Body:       `{"urls":{"get":"https://api.replicate.com/v1/files/f-1"}}`,
+			OnRequest: func(r *http.Request, _ []byte) {
+				uploadPath = r.URL.Path
+				uploadType = r.Header.Get("Content-Type")
+				uploadAuth = r.Header.Get("Authorization")
+			},
+		},
+		{StatusCode: http.StatusUnauthorized, Body: `{"detail":"invalid token"}`},
+	})
+
+	// "aGVsbG8=" 为 "hello" 的 base64
+	request := dto.ImageRequest{Prompt: "make it blue", Image: json.RawMessage(`"data:image/png;base64,aGVsbG8="`)}
+	newInfo := func() *relaycommon.RelayInfo {
+		return &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelBaseUrl:    server.URL,
+			ApiKey:            "r8-test",
+			UpstreamModelName: "prunaai/img2img:abc123",
+		}}
+	}
+
+	a := &Adaptor{}
+	converted, err := a.ConvertImageRequest(newImageContext(), newInfo(), request)
+	if err != nil {
+		t.Fatalf("ConvertImageRequest returned error: %v", err)
+	}
+	input := converted.(PredictionRequest).Input
+	if input["image"] != "https://api.replicate.com/v1/files/f-1" || input["strength"] != 0.6 {
+		t.Fatalf("unexpected input: %v", input)
+	}
+	if uploadPath != "/v1/files" || uploadAuth != "Bearer r8-test" || !strings.HasPrefix(uploadType, "multipart/form-data") {
+		t.Fatalf("unexpected upload request: path=%s auth=%s type=%s", uploadPath, uploadAuth, uploadType)
+	}
+
+	// 第二次上传返回 401，错误应透传给调用方
+	if _, err := a.ConvertImageRequest(newImageContext(), newInfo(), request); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected upload failure, got %v", err)
+	}
+}
@@ -1,9 +1,11 @@
 package replicate2
 
-// PredictionRequest represents the request body for Replicate img2img predictions
+// PredictionRequest represents the request body for Replicate predictions.
+// Version is omitted when calling an official model's predictions endpoint.
 type PredictionRequest struct {
-	Version string         `json:"version"`
+	Version string         `json:"version,omitempty"`
 	Input   map[string]any `json:"input"`
+	Stream  bool           `json:"stream,omitempty"`
 }
 
 // PredictionResponse represents the response from Replicate predictions API
@@ -12,6 +14,14 @@ type PredictionResponse struct {
 	Status string           `json:"status"`
 	Output any              `json:"output"`
 	Error  *PredictionError `json:"error"`
+	Urls   PredictionURLs   `json:"urls"`
+}
+
+// PredictionURLs contains the API endpoints related to a prediction
+type PredictionURLs struct {
+	Get    string `json:"get"`
+	Cancel string `json:"cancel"`
+	Stream string `json:"stream"`
 }
 
 // PredictionError represents an error in the prediction response
@@ -21,3 +31,29 @@ type PredictionError struct {
 	Detail  string `json:"detail"`
 }
 
+// Output types detected from PredictionResponse.Output
+const (
+	OutputTypeURL    = "url"
+	OutputTypeBase64 = "base64"
+	OutputTypeJSON   = "json"
+)
+
+func (e *PredictionError) String() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Detail != "":
+		return e.Detail
+	case e.Code != "":
+		return e.Code
+	default:
+		return "replicate2 adaptor: prediction error"
+	}
+}
+
+// SSE event names sent by /v1/predictions/{id}/stream
+const (
+	StreamEventOutput = "output"
+	StreamEventError  = "error"
+	StreamEventDone   = "done"
+)
@@ -0,0 +1,182 @@
+package replicate2
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isStreamRequest 判断图片请求是否设置了 stream: true
+func isStreamRequest(request dto.ImageRequest) bool {
+	stream, _ := getExtraFieldBool(request.Extra, "stream")
+	return stream
+}
+
+// streamEvent 是 Replicate SSE 中的一个事件
+type streamEvent struct {
+	Event string
+	Data  string
+}
+
+// predictionStreamHandler 连接 prediction 的 SSE 端点，将每个 output 事件转换为图片数据块推送给客户端，
+// 最后发送 [DONE] 并汇总所有输出用于计费。
+func predictionStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, prediction *PredictionResponse) (any, *types.NewAPIError) {
+	streamURL := prediction.Urls.Stream
+	if streamURL == "" {
+		if prediction.ID == "" {
+			return nil, types.NewError(errors.New("replicate2 adaptor: prediction id is missing"), types.ErrorCodeBadResponseBody)
+		}
+		baseURL := info.ChannelBaseUrl
+		if baseURL == "" {
+			baseURL = constant.ChannelBaseURLs[constant.ChannelTypeReplicate2]
+		}
+		streamURL = relaycommon.GetFullRequestURL(baseURL, "/v1/predictions/"+prediction.ID+"/stream", info.ChannelType)
+	}
+
+	resp, err := openPredictionStream(c, info, streamURL)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeDoRequestFailed)
+	}
+	defer service.CloseResponseBodyGracefully(resp)
+
+	finalResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+		Data:    make([]dto.ImageData, 0),
+	}
+	// SSE 响应头在首个数据块前写入，此前的错误仍按普通 HTTP 错误返回，可以重试或退款
+	started := false
+	var streamText strings.Builder
+	var streamErr *types.NewAPIError
+	readPredictionStream(resp, func(event streamEvent) bool {
+		switch event.Event {
+		case StreamEventOutput:
+			data, newAPIError := buildImageData(flattenOutput(parseStreamOutput(event.Data)), info)
+			if newAPIError != nil {
+				logger.LogWarn(c, "replicate2 stream: skip output event: "+newAPIError.Error())
+				return true
+			}
+			if !started {
+				helper.SetEventStreamHeaders(c)
+				started = true
+			}
+			streamText.WriteString(event.Data)
+			finalResponse.Data = append(finalResponse.Data, data...)
+			if err := helper.ObjectData(c, dto.ImageResponse{Created: finalResponse.Created, Data: data}); err != nil {
+				logger.LogError(c, "replicate2 stream: write chunk failed: "+err.Error())
+				return false
+			}
+		case StreamEventError:
+			var predictionErr PredictionError
+			if err := common.UnmarshalJsonStr(event.Data, &predictionErr); err != nil || predictionErr.String() == "" {
+				predictionErr.Detail = event.Data
+			}
+			streamErr = types.NewError(errors.New(predictionErr.String()), types.ErrorCodeBadResponse, types.ErrOptionWithSkipRetry())
+			return false
+		case StreamEventDone:
+			return false
+		}
+		return true
+	})
+
+	if !started {
+		if streamErr != nil {
+			return nil, streamErr
+		}
+		return nil, types.NewError(errors.New("replicate2 adaptor: no usable image data"), types.ErrorCodeBadResponse, types.ErrOptionWithSkipRetry())
+	}
+	// 已开始推流，错误只能作为 SSE 事件发送，已推送的输出照常计费
+	if streamErr != nil {
+		logger.LogError(c, "replicate2 stream: prediction failed after streaming started: "+streamErr.Error())
+		_ = helper.ObjectData(c, gin.H{"error": streamErr.ToOpenAIError()})
+	}
+	helper.Done(c)
+
+	if common.DebugEnabled {
+		if finalBytes, err := common.Marshal(finalResponse); err == nil {
+			logger.LogDebug(c, "replicate2 stream final response: "+string(finalBytes))
+		}
+	}
+	return service.ResponseText2Usage(c, streamText.String(), info.UpstreamModelName, info.GetEstimatePromptTokens()), nil
+}
+
+func openPredictionStream(c *gin.Context, info *relaycommon.RelayInfo, streamURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("replicate2 adaptor: create stream request failed: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+info.ApiKey)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-store")
+
+	client := service.GetHttpClient()
+	if info.ChannelSetting.Proxy != "" {
+		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("replicate2 adaptor: new proxy http client failed: %w", err)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("replicate2 adaptor: connect to prediction stream failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		service.CloseResponseBodyGracefully(resp)
+		return nil, fmt.Errorf("replicate2 adaptor: prediction stream returned status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// readPredictionStream 按 SSE 协议解析事件，handler 返回 false 时停止读取
+func readPredictionStream(resp *http.Response, handler func(event streamEvent) bool) {
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var event streamEvent
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if event.Event != "" || len(data) > 0 {
+				event.Data = strings.Join(data, "\n")
+				if event.Event == "" {
+					event.Event = StreamEventOutput
+				}
+				if !handler(event) {
+					return
+				}
+			}
+			event = streamEvent{}
+			data = data[:0]
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// 注释行
+		case strings.HasPrefix(line, "event:"):
+			event.Event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+}
+
+// parseStreamOutput 解析 output 事件数据，非 JSON 内容按字符串处理
+func parseStreamOutput(data string) any {
+	var output any
+	if err := common.UnmarshalJsonStr(data, &output); err == nil {
+		return output
+	}
+	return data
+}
@@ -0,0 +1,128 @@
+package replicate2
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPredictionStreamHandler(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/predictions/p-1/stream" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprint(w, "event: output\ndata: https://replicate.delivery/a.png\n\n")
+		fmt.Fprint(w, "event: output\ndata: https://replicate.delivery/b.png\n\n")
+		fmt.Fprint(w, "event: done\ndata: {}\n\n")
+	}))
+	defer server.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/edits", nil)
+	info := &relaycommon.RelayInfo{
+		IsStream: true,
+		Request:  &dto.ImageRequest{},
+		ChannelMeta: &relaycommon.ChannelMeta{
+			ChannelBaseUrl: server.URL,
+			ApiKey:         "r8-test",
+		},
+	}
+
+	usage, newAPIError := predictionStreamHandler(c, info, &PredictionResponse{ID: "p-1", Status: "starting"})
+	if newAPIError != nil {
+		t.Fatalf("predictionStreamHandler returned error: %v", newAPIError)
+	}
+	if u, ok := usage.(*dto.Usage); !ok || u.CompletionTokens == 0 {
+		t.Fatalf("expected usage computed from streamed output, got %#v", usage)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+	body := w.Body.String()
+	chunks := strings.Count(body, "data: {")
+	if chunks != 2 {
+		t.Fatalf("expected 2 output chunks, got %d: %s", chunks, body)
+	}
+	if !strings.Contains(body, `"url":"https://replicate.delivery/b.png"`) {
+		t.Fatalf("missing second output chunk: %s", body)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Fatalf("expected stream to end with [DONE]: %s", body)
+	}
+}
+
+func TestPredictionStreamHandlerError(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: error\ndata: {\"detail\":\"NSFW content detected\"}\n\n")
+	}))
+	defer server.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/edits", nil)
+	info := &relaycommon.RelayInfo{
+		IsStream:    true,
+		ChannelMeta: &relaycommon.ChannelMeta{ApiKey: "r8-test"},
+	}
+
+	_, newAPIError := predictionStreamHandler(c, info, &PredictionResponse{ID: "p-1", Urls: PredictionURLs{Stream: server.URL + "/stream"}})
+	if newAPIError == nil || !strings.Contains(newAPIError.Error(), "NSFW") {
+		t.Fatalf("expected upstream error to be returned, got %v", newAPIError)
+	}
+}
+
+func TestPredictionStreamHandlerErrorAfterOutput(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: output\ndata: https://replicate.delivery/a.png\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"detail\":\"NSFW content detected\"}\n\n")
+	}))
+	defer server.Close()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/edits", nil)
+	info := &relaycommon.RelayInfo{
+		IsStream:    true,
+		Request:     &dto.ImageRequest{},
+		ChannelMeta: &relaycommon.ChannelMeta{ApiKey: "r8-test"},
+	}
+
+	// 已推送输出后的错误作为 SSE 事件发送，已推送部分照常计费
+	usage, newAPIError := predictionStreamHandler(c, info, &PredictionResponse{ID: "p-1", Urls: PredictionURLs{Stream: server.URL + "/stream"}})
+	if newAPIError != nil {
+		t.Fatalf("expected error to be sent as SSE event, got %v", newAPIError)
+	}
+	if u, ok := usage.(*dto.Usage); !ok || u.CompletionTokens == 0 {
+		t.Fatalf("expected usage for streamed output, got %#v", usage)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `"error":{`) || !strings.Contains(body, "NSFW") {
+		t.Fatalf("missing SSE error event: %s", body)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(body), "data: [DONE]") {
+		t.Fatalf("expected stream to end with [DONE]: %s", body)
+	}
+}
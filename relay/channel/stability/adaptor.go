@@ -0,0 +1,284 @@
+package stability
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+const generatePath = "/v2beta/stable-image/generate/sd3"
+
+// Adaptor relays OpenAI image generation requests to Stability AI's SD3
+// endpoint. The upstream answers with raw image bytes when the client asks
+// for response_format=image/*, and with base64 JSON otherwise.
+type Adaptor struct {
+}
+
+func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
+}
+
+func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	if info == nil {
+		return "", errors.New("stability adaptor: relay info is nil")
+	}
+	baseURL := info.ChannelBaseUrl
+	if baseURL == "" {
+		baseURL = constant.ChannelBaseURLs[constant.ChannelTypeStability]
+	}
+	return strings.TrimSuffix(baseURL, "/") + generatePath, nil
+}
+
+func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
+	if info == nil {
+		return errors.New("stability adaptor: relay info is nil")
+	}
+	if info.ApiKey == "" {
+		return errors.New("stability adaptor: api key is required")
+	}
+	channel.SetupApiRequestHeader(info, c, req)
+	req.Set("Authorization", "Bearer "+info.ApiKey)
+	if wantsBinary(info) {
+		req.Set("Accept", "image/*")
+	} else {
+		req.Set("Accept", "application/json")
+	}
+	return nil
+}
+
+// wantsBinary 客户端指定 response_format=image/png 等 MIME 类型时直接返回图片二进制
+func wantsBinary(info *relaycommon.RelayInfo) bool {
+	req, ok := info.Request.(*dto.ImageRequest)
+	return ok && strings.HasPrefix(strings.ToLower(req.ResponseFormat), "image/")
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	if info == nil {
+		return nil, errors.New("stability adaptor: relay info is nil")
+	}
+	if strings.TrimSpace(request.Prompt) == "" {
+		return nil, errors.New("stability adaptor: prompt is required")
+	}
+	if request.N > 1 {
+		return nil, errors.New("stability adaptor: only n=1 is supported")
+	}
+	if strings.TrimSpace(info.UpstreamModelName) == "" {
+		info.UpstreamModelName = request.Model
+	}
+	if strings.TrimSpace(info.UpstreamModelName) == "" {
+		info.UpstreamModelName = ModelSD35Large
+	}
+
+	fields := map[string]string{
+		"prompt": request.Prompt,
+		"model":  info.UpstreamModelName,
+		"mode":   "text-to-image",
+	}
+	for _, key := range []string{"negative_prompt", "style_preset"} {
+		value, err := extraString(request.Extra, key)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			fields[key] = value
+		}
+	}
+
+	aspectRatio, err := extraString(request.Extra, "aspect_ratio")
+	if err != nil {
+		return nil, err
+	}
+	if aspectRatio == "" {
+		aspectRatio = sizeToAspectRatio(request.Size)
+	}
+	if aspectRatio != "" {
+		if !lo.Contains(supportedAspectRatios, aspectRatio) {
+			return nil, fmt.Errorf("stability adaptor: unsupported aspect_ratio %q", aspectRatio)
+		}
+		fields["aspect_ratio"] = aspectRatio
+	}
+
+	if raw, ok := request.Extra["seed"]; ok && len(raw) > 0 {
+		var seed int64
+		if err := common.Unmarshal(raw, &seed); err != nil || seed < 0 || seed > 4294967294 {
+			return nil, errors.New("stability adaptor: seed must be an integer between 0 and 4294967294")
+		}
+		fields["seed"] = strconv.FormatInt(seed, 10)
+	}
+
+	if len(request.OutputFormat) > 0 {
+		var outputFormat string
+		if err := common.Unmarshal(request.OutputFormat, &outputFormat); err != nil {
+			return nil, errors.New("stability adaptor: output_format must be a string")
+		}
+		if outputFormat != "" {
+			if !lo.Contains(supportedOutputFormats, outputFormat) {
+				return nil, fmt.Errorf("stability adaptor: unsupported output_format %q", outputFormat)
+			}
+			fields["output_format"] = outputFormat
+		}
+	}
+
+	// SD3 接口只接受 multipart/form-data
+	var requestBody bytes.Buffer
+	writer := multipart.NewWriter(&requestBody)
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return nil, fmt.Errorf("stability adaptor: write form field %s failed: %w", key, err)
+		}
+	}
+	writer.Close()
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return &requestBody, nil
+}
+
+func extraString(extra map[string]json.RawMessage, key string) (string, error) {
+	raw, ok := extra[key]
+	if !ok || len(raw) == 0 {
+		return "", nil
+	}
+	var value string
+	if err := common.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("stability adaptor: %s must be a string", key)
+	}
+	return strings.TrimSpace(value), nil
+}
+
+// sizeToAspectRatio 将 OpenAI 的 size 换算为 SD3 的 aspect_ratio，无法对应时返回空字符串由上游使用默认比例
+func sizeToAspectRatio(size string) string {
+	w, h, ok := strings.Cut(strings.ToLower(strings.TrimSpace(size)), "x")
+	if !ok {
+		return ""
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 {
+		return ""
+	}
+	height, err := strconv.Atoi(h)
+	if err != nil || height <= 0 {
+		return ""
+	}
+	divisor := gcd(width, height)
+	ratio := fmt.Sprintf("%d:%d", width/divisor, height/divisor)
+	if !lo.Contains(supportedAspectRatios, ratio) {
+		return ""
+	}
+	return ratio
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
+	return channel.DoApiRequest(a, c, info, requestBody)
+}
+
+func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (any, *types.NewAPIError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
+	_ = resp.Body.Close()
+
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "image/") {
+		if resp.Header.Get("Finish-Reason") == finishReasonContentFiltered {
+			return nil, types.NewError(errors.New("stability adaptor: image was filtered by content moderation"), types.ErrorCodeBadResponse)
+		}
+		if len(responseBody) == 0 {
+			return nil, types.NewError(errors.New("stability adaptor: empty image output"), types.ErrorCodeBadResponseBody)
+		}
+		c.Writer.Header().Set("Content-Type", contentType)
+		if seed := resp.Header.Get("Seed"); seed != "" {
+			c.Writer.Header().Set("Seed", seed)
+		}
+		c.Writer.WriteHeader(http.StatusOK)
+		_, _ = c.Writer.Write(responseBody)
+		return &dto.Usage{}, nil
+	}
+
+	var result ImageResponse
+	if err := common.Unmarshal(responseBody, &result); err != nil {
+		return nil, types.NewError(fmt.Errorf("stability adaptor: failed to decode response: %w", err), types.ErrorCodeBadResponseBody)
+	}
+	if result.FinishReason == finishReasonContentFiltered {
+		return nil, types.NewError(errors.New("stability adaptor: image was filtered by content moderation"), types.ErrorCodeBadResponse)
+	}
+	if result.Image == "" {
+		return nil, types.NewError(errors.New("stability adaptor: empty image output"), types.ErrorCodeBadResponseBody)
+	}
+
+	imageResponse := dto.ImageResponse{
+		Created: common.GetTimestamp(),
+		Data:    []dto.ImageData{{B64Json: result.Image}},
+	}
+	responseBytes, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(fmt.Errorf("stability adaptor: encode response failed: %w", err), types.ErrorCodeBadResponseBody)
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(http.StatusOK)
+	_, _ = c.Writer.Write(responseBytes)
+
+	return &dto.Usage{}, nil
+}
+
+func (a *Adaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *Adaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
+
+func (a *Adaptor) ConvertOpenAIRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeneralOpenAIRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertOpenAIRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertRerankRequest(*gin.Context, int, dto.RerankRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertRerankRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertEmbeddingRequest(*gin.Context, *relaycommon.RelayInfo, dto.EmbeddingRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertEmbeddingRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertAudioRequest(*gin.Context, *relaycommon.RelayInfo, dto.AudioRequest) (io.Reader, error) {
+	return nil, errors.New("stability adaptor: ConvertAudioRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertOpenAIResponsesRequest(*gin.Context, *relaycommon.RelayInfo, dto.OpenAIResponsesRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertOpenAIResponsesRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertClaudeRequest is not implemented")
+}
+
+func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
+	return nil, errors.New("stability adaptor: ConvertGeminiRequest is not implemented")
+}
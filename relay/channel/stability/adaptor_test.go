@@ -0,0 +1,147 @@
+package stability
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestContext() (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/generations", nil)
+	return c, w
+}
+
+func readForm(t *testing.T, c *gin.Context, body any) map[string]string {
+	t.Helper()
+	buf, ok := body.(*bytes.Buffer)
+	if !ok {
+		t.Fatalf("converted request type = %T, want *bytes.Buffer", body)
+	}
+	_, params, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("parse content type: %v", err)
+	}
+	form, err := multipart.NewReader(buf, params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("read form: %v", err)
+	}
+	fields := make(map[string]string)
+	for key, values := range form.Value {
+		fields[key] = values[0]
+	}
+	return fields
+}
+
+func TestConvertImageRequest(t *testing.T) {
+	c, _ := newTestContext()
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}
+	request := dto.ImageRequest{
+		Model:        "sd3.5-medium",
+		Prompt:       "a lighthouse at dusk",
+		Size:         "1792x1024",
+		OutputFormat: json.RawMessage(`"jpeg"`),
+		Extra: map[string]json.RawMessage{
+			"negative_prompt": json.RawMessage(`"blurry"`),
+			"aspect_ratio":    json.RawMessage(`"16:9"`),
+			"style_preset":    json.RawMessage(`"photographic"`),
+			"seed":            json.RawMessage(`42`),
+		},
+	}
+	body, err := (&Adaptor{}).ConvertImageRequest(c, info, request)
+	if err != nil {
+		t.Fatalf("ConvertImageRequest: %v", err)
+	}
+	fields := readForm(t, c, body)
+	want := map[string]string{
+		"prompt":          "a lighthouse at dusk",
+		"model":           "sd3.5-medium",
+		"mode":            "text-to-image",
+		"negative_prompt": "blurry",
+		"aspect_ratio":    "16:9",
+		"style_preset":    "photographic",
+		"seed":            "42",
+		"output_format":   "jpeg",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, fields[key], value)
+		}
+	}
+
+	// aspect_ratio 缺省时由 size 推导
+	c, _ = newTestContext()
+	body, err = (&Adaptor{}).ConvertImageRequest(c, &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}, dto.ImageRequest{Prompt: "cat", Size: "1024x1024"})
+	if err != nil {
+		t.Fatalf("ConvertImageRequest: %v", err)
+	}
+	fields = readForm(t, c, body)
+	if fields["aspect_ratio"] != "1:1" || fields["model"] != ModelSD35Large {
+		t.Errorf("derived fields = %v", fields)
+	}
+
+	invalid := []dto.ImageRequest{
+		{Prompt: ""},
+		{Prompt: "cat", N: 2},
+		{Prompt: "cat", Extra: map[string]json.RawMessage{"aspect_ratio": json.RawMessage(`"7:3"`)}},
+		{Prompt: "cat", Extra: map[string]json.RawMessage{"seed": json.RawMessage(`-1`)}},
+		{Prompt: "cat", OutputFormat: json.RawMessage(`"gif"`)},
+	}
+	for i, req := range invalid {
+		c, _ = newTestContext()
+		if _, err := (&Adaptor{}).ConvertImageRequest(c, &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}, req); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestDoResponse(t *testing.T) {
+	c, w := newTestContext()
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"image":"aGVsbG8=","finish_reason":"SUCCESS","seed":1}`)),
+	}
+	if _, apiErr := (&Adaptor{}).DoResponse(c, resp, &relaycommon.RelayInfo{}); apiErr != nil {
+		t.Fatalf("DoResponse json: %v", apiErr)
+	}
+	var imageResponse dto.ImageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &imageResponse); err != nil || len(imageResponse.Data) != 1 || imageResponse.Data[0].B64Json != "aGVsbG8=" {
+		t.Fatalf("unexpected json response: %s", w.Body.String())
+	}
+
+	c, w = newTestContext()
+	resp = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"image/png"}, "Finish-Reason": []string{"SUCCESS"}},
+		Body:       io.NopCloser(bytes.NewBufferString("\x89PNG")),
+	}
+	if _, apiErr := (&Adaptor{}).DoResponse(c, resp, &relaycommon.RelayInfo{}); apiErr != nil {
+		t.Fatalf("DoResponse binary: %v", apiErr)
+	}
+	if w.Header().Get("Content-Type") != "image/png" || w.Body.String() != "\x89PNG" {
+		t.Fatalf("unexpected binary response: %s %q", w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	c, _ = newTestContext()
+	resp = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(`{"image":"","finish_reason":"CONTENT_FILTERED"}`)),
+	}
+	if _, apiErr := (&Adaptor{}).DoResponse(c, resp, &relaycommon.RelayInfo{}); apiErr == nil {
+		t.Fatal("expected error for filtered image")
+	}
+}
@@ -0,0 +1,23 @@
+package stability
+
+const (
+	// ChannelName identifies the Stability AI channel.
+	ChannelName = "stability"
+	// ModelSD35Large is the default SD3 model used when the request does not name one.
+	ModelSD35Large = "sd3.5-large"
+)
+
+var ModelList = []string{
+	ModelSD35Large,
+	"sd3.5-large-turbo",
+	"sd3.5-medium",
+	"sd3-large",
+	"sd3-large-turbo",
+	"sd3-medium",
+}
+
+// supportedAspectRatios lists the aspect_ratio values accepted by the SD3 endpoint.
+var supportedAspectRatios = []string{"16:9", "1:1", "21:9", "2:3", "3:2", "4:5", "5:4", "9:16", "9:21"}
+
+// supportedOutputFormats lists the output_format values accepted by the SD3 endpoint.
+var supportedOutputFormats = []string{"png", "jpeg"}
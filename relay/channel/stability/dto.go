@@ -0,0 +1,14 @@
+package stability
+
+// Finish reasons reported by Stability AI.
+const (
+	finishReasonSuccess         = "SUCCESS"
+	finishReasonContentFiltered = "CONTENT_FILTERED"
+)
+
+// ImageResponse is returned by the SD3 endpoint when Accept is application/json.
+type ImageResponse struct {
+	Image        string `json:"image"`
+	FinishReason string `json:"finish_reason"`
+	Seed         int64  `json:"seed"`
+}
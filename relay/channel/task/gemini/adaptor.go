@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
-	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay/channel"
@@ -40,23 +39,56 @@ type GeminiVideoGenerationConfig struct {
 
 // GeminiVideoRequest represents a single video generation instance
 type GeminiVideoRequest struct {
-	Prompt string `json:"prompt"`
+	Prompt string            `json:"prompt"`
+	Image  *GeminiVideoImage `json:"image,omitempty"`
+}
+
+// GeminiVideoFileData references a previously generated video
+type GeminiVideoFileData struct {
+	MimeType string `json:"mimeType"`
+	FileURI  string `json:"fileUri"`
+}
+
+// GeminiVideoPart is a text or file part of a conversation turn
+type GeminiVideoPart struct {
+	Text     string               `json:"text,omitempty"`
+	FileData *GeminiVideoFileData `json:"fileData,omitempty"`
+}
+
+// GeminiVideoContent is a single turn used for multi-turn refinement
+type GeminiVideoContent struct {
+	Role  string            `json:"role"`
+	Parts []GeminiVideoPart `json:"parts"`
 }
 
 // GeminiVideoPayload represents the complete video generation request payload
 type GeminiVideoPayload struct {
 	Instances  []GeminiVideoRequest        `json:"instances"`
 	Parameters GeminiVideoGenerationConfig `json:"parameters,omitempty"`
+	// Contents carries earlier turns followed by the current prompt when the
+	// request refines a previous generation via previous_task_id.
+	Contents []GeminiVideoContent `json:"contents,omitempty"`
 }
 
 type submitResponse struct {
 	Name string `json:"name"`
 }
 
+// operationVideo is a video entry in a Veo 2 operation response
 type operationVideo struct {
 	MimeType           string `json:"mimeType"`
 	BytesBase64Encoded string `json:"bytesBase64Encoded"`
 	Encoding           string `json:"encoding"`
+	GcsUri             string `json:"gcsUri"`
+	URI                string `json:"uri"`
+}
+
+// generatedSample is a video entry in Gemini API and Imagen Video responses
+type generatedSample struct {
+	Video struct {
+		URI      string `json:"uri"`
+		Encoding string `json:"encoding"`
+	} `json:"video"`
 }
 
 type operationResponse struct {
@@ -69,12 +101,11 @@ type operationResponse struct {
 		BytesBase64Encoded    string           `json:"bytesBase64Encoded"`
 		Encoding              string           `json:"encoding"`
 		Video                 string           `json:"video"`
+		// Imagen Video
+		GeneratedSamples []generatedSample `json:"generatedSamples"`
+		// Gemini API (Veo)
 		GenerateVideoResponse struct {
-			GeneratedSamples []struct {
-				Video struct {
-					URI string `json:"uri"`
-				} `json:"video"`
-			} `json:"generatedSamples"`
+			GeneratedSamples []generatedSample `json:"generatedSamples"`
 		} `json:"generateVideoResponse"`
 	} `json:"response"`
 	Error struct {
@@ -98,12 +129,6 @@ func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
 	a.apiKey = info.ApiKey
 }
 
-// ValidateRequestAndSetAction parses body, validates fields and sets default action.
-func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
-	// Use the standard validation method for TaskSubmitReq
-	return relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate)
-}
-
 // BuildRequestURL constructs the upstream URL.
 func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	modelName := info.OriginModelName
@@ -142,6 +167,14 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 			{Prompt: req.Prompt},
 		},
 		Parameters: GeminiVideoGenerationConfig{},
+		Contents:   buildConversationContents(info, req.Prompt),
+	}
+	if len(req.Images) > 0 {
+		image, err := buildVideoImage(req.Images[0])
+		if err != nil {
+			return nil, errors.Wrap(err, "load image failed")
+		}
+		body.Instances[0].Image = image
 	}
 
 	metadata := req.Metadata
@@ -168,7 +201,7 @@ func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, req
 
 // DoResponse handles upstream response, returns taskID etc.
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 	}
@@ -192,7 +225,7 @@ func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *rela
 }
 
 func (a *TaskAdaptor) GetModelList() []string {
-	return []string{"veo-3.0-generate-001", "veo-3.1-generate-preview", "veo-3.1-fast-generate-preview"}
+	return []string{"veo-2.0-generate-001", "veo-3.0-generate-001", "veo-3.1-generate-preview", "veo-3.1-fast-generate-preview"}
 }
 
 func (a *TaskAdaptor) GetChannelName() string {
@@ -258,16 +291,46 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 	ti.TaskID = taskID
 	ti.Url = fmt.Sprintf("%s/v1/videos/%s/content", system_setting.ServerAddress, taskID)
 
-	// Extract URL from generateVideoResponse if available
-	if len(op.Response.GenerateVideoResponse.GeneratedSamples) > 0 {
-		if uri := op.Response.GenerateVideoResponse.GeneratedSamples[0].Video.URI; uri != "" {
-			ti.RemoteUrl = uri
+	ti.RemoteUrl = op.remoteVideoURL()
+	if ti.RemoteUrl != "" {
+		return ti, nil
+	}
+	if mimeType, data := op.inlineVideo(); data != "" {
+		// 未配置 storageUri 时 Veo 2 直接返回视频内容，由轮询转存到任务存储后替换为对象地址
+		if mimeType == "" {
+			mimeType = "video/mp4"
 		}
+		ti.RemoteUrl = "data:" + mimeType + ";base64," + data
+	} else if op.Response.RaiMediaFilteredCount > 0 {
+		ti.Status = model.TaskStatusFailure
+		ti.Reason = fmt.Sprintf("%d video(s) filtered by responsible AI policy", op.Response.RaiMediaFilteredCount)
+		ti.Url = ""
 	}
 
 	return ti, nil
 }
 
+// remoteVideoURL returns the first video URI across the Gemini API (Veo),
+// Veo 2 and Imagen Video response schemas.
+func (op *operationResponse) remoteVideoURL() string {
+	for _, samples := range [][]generatedSample{op.Response.GenerateVideoResponse.GeneratedSamples, op.Response.GeneratedSamples} {
+		for _, sample := range samples {
+			if sample.Video.URI != "" {
+				return sample.Video.URI
+			}
+		}
+	}
+	for _, video := range op.Response.Videos {
+		if video.URI != "" {
+			return video.URI
+		}
+		if video.GcsUri != "" {
+			return video.GcsUri
+		}
+	}
+	return ""
+}
+
 func (a *TaskAdaptor) ConvertToOpenAIVideo(task *model.Task) ([]byte, error) {
 	upstreamName, err := decodeLocalTaskID(task.TaskID)
 	if err != nil {
@@ -297,6 +360,29 @@ func (a *TaskAdaptor) ConvertToOpenAIVideo(task *model.Task) ([]byte, error) {
 // helpers
 // ============================
 
+// buildConversationContents converts the conversation history into Gemini
+// contents and appends the current prompt as the last user turn.
+func buildConversationContents(info *relaycommon.RelayInfo, prompt string) []GeminiVideoContent {
+	if info.TaskRelayInfo == nil || len(info.ConversationHistory) == 0 {
+		return nil
+	}
+	contents := make([]GeminiVideoContent, 0, len(info.ConversationHistory)+1)
+	for _, msg := range info.ConversationHistory {
+		var parts []GeminiVideoPart
+		if msg.Content != "" {
+			parts = append(parts, GeminiVideoPart{Text: msg.Content})
+		}
+		if msg.VideoURI != "" {
+			parts = append(parts, GeminiVideoPart{FileData: &GeminiVideoFileData{MimeType: "video/mp4", FileURI: msg.VideoURI}})
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		contents = append(contents, GeminiVideoContent{Role: msg.Role, Parts: parts})
+	}
+	return append(contents, GeminiVideoContent{Role: "user", Parts: []GeminiVideoPart{{Text: prompt}}})
+}
+
 func encodeLocalTaskID(name string) string {
 	return base64.RawURLEncoding.EncodeToString([]byte(name))
 }
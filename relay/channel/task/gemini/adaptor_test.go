@@ -0,0 +1,162 @@
+package gemini
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseTaskResultSchemas(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus string
+		wantRemote string
+		wantUrl    string
+	}{
+		{
+			name:       "pending",
+			body:       `{"name":"models/veo-2.0-generate-001/operations/op1","done":false}`,
+			wantStatus: model.TaskStatusInProgress,
+		},
+		{
+			name:       "gemini api veo",
+			body:       `{"name":"models/veo-3.0-generate-001/operations/op1","done":true,"response":{"generateVideoResponse":{"generatedSamples":[{"video":{"uri":"https://generativelanguage.googleapis.com/v1beta/files/abc:download"}}]}}}`,
+			wantStatus: model.TaskStatusSuccess,
+			wantRemote: "https://generativelanguage.googleapis.com/v1beta/files/abc:download",
+		},
+		{
+			name:       "veo 2",
+			body:       `{"name":"projects/p/locations/us-central1/publishers/google/models/veo-2.0-generate-001/operations/op1","done":true,"response":{"@type":"type.googleapis.com/cloud.ai.large_models.vision.GenerateVideoResponse","videos":[{"gcsUri":"gs://bucket/sample_0.mp4","mimeType":"video/mp4"}]}}`,
+			wantStatus: model.TaskStatusSuccess,
+			wantRemote: "gs://bucket/sample_0.mp4",
+		},
+		{
+			name:       "imagen video",
+			body:       `{"name":"projects/p/locations/us-central1/publishers/google/models/imagen-video/operations/op1","done":true,"response":{"generatedSamples":[{"video":{"uri":"gs://bucket/imagen.mp4","encoding":"video/mp4"}}]}}`,
+			wantStatus: model.TaskStatusSuccess,
+			wantRemote: "gs://bucket/imagen.mp4",
+		},
+		{
+			name:       "veo 2 inline video",
+			body:       `{"name":"models/veo-2.0-generate-001/operations/op1","done":true,"response":{"videos":[{"bytesBase64Encoded":"AAAA","mimeType":"video/mp4"}]}}`,
+			wantStatus: model.TaskStatusSuccess,
+			wantRemote: "data:video/mp4;base64,AAAA",
+		},
+		{
+			name:       "filtered",
+			body:       `{"name":"models/veo-2.0-generate-001/operations/op1","done":true,"response":{"raiMediaFilteredCount":1}}`,
+			wantStatus: model.TaskStatusFailure,
+		},
+		{
+			name:       "error",
+			body:       `{"name":"models/veo-2.0-generate-001/operations/op1","done":true,"error":{"message":"quota exceeded"}}`,
+			wantStatus: model.TaskStatusFailure,
+		},
+	}
+	a := &TaskAdaptor{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ti, err := a.ParseTaskResult([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseTaskResult returned error: %v", err)
+			}
+			if ti.Status != tt.wantStatus {
+				t.Fatalf("expected status %s, got %s", tt.wantStatus, ti.Status)
+			}
+			if ti.RemoteUrl != tt.wantRemote {
+				t.Fatalf("expected remote url %q, got %q", tt.wantRemote, ti.RemoteUrl)
+			}
+			if tt.wantUrl != "" && ti.Url != tt.wantUrl {
+				t.Fatalf("expected url %q, got %q", tt.wantUrl, ti.Url)
+			}
+		})
+	}
+}
+
+func TestBuildRequestBodyWithConversationHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("task_request", relaycommon.TaskSubmitReq{Prompt: "make it night"})
+	info := &relaycommon.RelayInfo{TaskRelayInfo: &relaycommon.TaskRelayInfo{
+		ConversationHistory: []dto.TaskMessage{
+			{Role: "user", Content: "a city street"},
+			{Role: "model", VideoURI: "https://generativelanguage.googleapis.com/v1beta/files/abc:download"},
+		},
+	}}
+
+	reader, err := (&TaskAdaptor{}).BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	var payload GeminiVideoPayload
+	if err := json.NewDecoder(reader).Decode(&payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if len(payload.Contents) != 3 {
+		t.Fatalf("contents = %+v, want 3 turns", payload.Contents)
+	}
+	if payload.Contents[0].Parts[0].Text != "a city street" {
+		t.Errorf("first turn = %+v", payload.Contents[0])
+	}
+	if fd := payload.Contents[1].Parts[0].FileData; payload.Contents[1].Role != "model" || fd == nil || fd.FileURI != info.ConversationHistory[1].VideoURI {
+		t.Errorf("model turn = %+v", payload.Contents[1])
+	}
+	if last := payload.Contents[2]; last.Role != "user" || last.Parts[0].Text != "make it night" {
+		t.Errorf("current turn = %+v", last)
+	}
+
+	// 无历史时不输出 contents
+	info.ConversationHistory = nil
+	reader, err = (&TaskAdaptor{}).BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if strings.Contains(string(body), "contents") {
+		t.Errorf("unexpected contents without history: %s", body)
+	}
+}
+
+func TestValidateRequestAndSetActionVeoParams(t *testing.T) {
+	c := testutil.NewTaskContext("/v1/video/generations", `{"model":"veo-2.0-generate-001","prompt":"a cat","image":"https://example.com/cat.png","aspect_ratio":"9:16","duration_seconds":6,"person_generation":"allow_adult"}`)
+	info := testutil.NewTaskRelayInfo()
+	if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	if info.Action != constant.TaskActionGenerate {
+		t.Errorf("action = %s, want %s", info.Action, constant.TaskActionGenerate)
+	}
+	req, _ := relaycommon.GetTaskRequest(c)
+	if req.Metadata["aspectRatio"] != "9:16" || req.Metadata["durationSeconds"] != 6 || req.Metadata["personGeneration"] != "allow_adult" {
+		t.Errorf("metadata = %+v", req.Metadata)
+	}
+
+	c = testutil.NewTaskContext("/v1/video/generations", `{"model":"veo-3.1-generate-preview","prompt":"a cat","duration_seconds":4}`)
+	if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, testutil.NewTaskRelayInfo()); taskErr != nil {
+		t.Fatalf("veo 3.1 with 4s: %v", taskErr.Message)
+	}
+
+	for _, body := range []string{
+		`{"model":"veo-2.0-generate-001","prompt":"a cat","aspect_ratio":"1:1"}`,
+		`{"model":"veo-2.0-generate-001","prompt":"a cat","duration_seconds":4}`,
+		`{"model":"veo-3.0-generate-001","prompt":"a cat","duration_seconds":5}`,
+		`{"model":"veo-3.1-generate-preview","prompt":"a cat","duration_seconds":10}`,
+		`{"prompt":"a cat","person_generation":"everyone"}`,
+	} {
+		info := testutil.NewTaskRelayInfo()
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(testutil.NewTaskContext("/v1/video/generations", body), info); taskErr == nil {
+			t.Errorf("expected error for %s", body)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package gemini
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// veoLimits 单个 Veo 模型支持的时长与宽高比
+// https://ai.google.dev/gemini-api/docs/video
+type veoLimits struct {
+	DurationSeconds []int
+	AspectRatios    []string
+}
+
+// veoModelLimits 按模型名前缀配置参数取值范围，未匹配的模型不校验时长与宽高比，交由上游校验
+var veoModelLimits = map[string]veoLimits{
+	"veo-2.0": {DurationSeconds: []int{5, 6, 7, 8}, AspectRatios: []string{"16:9", "9:16"}},
+	"veo-3.0": {DurationSeconds: []int{4, 6, 8}, AspectRatios: []string{"16:9", "9:16"}},
+	"veo-3.1": {DurationSeconds: []int{4, 6, 8}, AspectRatios: []string{"16:9", "9:16"}},
+}
+
+var veoPersonGenerations = []string{"dont_allow", "allow_adult", "allow_all"}
+
+// getVeoLimits 返回与模型名最长匹配的前缀对应的取值范围
+func getVeoLimits(modelName string) (veoLimits, bool) {
+	var limits veoLimits
+	matched := ""
+	for prefix, l := range veoModelLimits {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(matched) {
+			limits, matched = l, prefix
+		}
+	}
+	return limits, matched != ""
+}
+
+// veoVideoRequest 请求体顶层的 Veo 参数，multipart 请求时从表单字段（metadata）读取
+type veoVideoRequest struct {
+	AspectRatio      string `json:"aspect_ratio,omitempty"`
+	DurationSeconds  int    `json:"duration_seconds,omitempty"`
+	PersonGeneration string `json:"person_generation,omitempty"`
+	NegativePrompt   string `json:"negative_prompt,omitempty"`
+}
+
+// GeminiVideoImage 图生视频的首帧图片
+type GeminiVideoImage struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded"`
+	MimeType           string `json:"mimeType"`
+}
+
+// ValidateRequestAndSetAction 校验 prompt 与 Veo 参数，并写入 metadata 供 BuildRequestBody 生成 parameters；
+// 带图片时为图生视频
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
+	if taskErr = relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate); taskErr != nil {
+		return taskErr
+	}
+	req, err := relaycommon.GetTaskRequest(c)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+
+	var params veoVideoRequest
+	if strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data") {
+		data, _ := common.Marshal(req.Metadata)
+		err = common.Unmarshal(data, &params)
+	} else {
+		err = common.UnmarshalBodyReusable(c, &params)
+	}
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if params.DurationSeconds == 0 {
+		params.DurationSeconds = req.Duration
+	}
+	modelName := info.OriginModelName
+	if modelName == "" {
+		modelName = req.Model
+	}
+	if err := params.validate(modelName); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]interface{})
+	}
+	params.applyTo(req.Metadata)
+	if len(req.Images) > 0 {
+		info.Action = constant.TaskActionGenerate
+	}
+	c.Set("task_request", req)
+	return nil
+}
+
+func (p *veoVideoRequest) validate(modelName string) error {
+	if limits, ok := getVeoLimits(modelName); ok {
+		if p.AspectRatio != "" && !lo.Contains(limits.AspectRatios, p.AspectRatio) {
+			return fmt.Errorf("aspect_ratio for %s must be one of %s", modelName, strings.Join(limits.AspectRatios, ", "))
+		}
+		if p.DurationSeconds != 0 && !lo.Contains(limits.DurationSeconds, p.DurationSeconds) {
+			return fmt.Errorf("duration_seconds for %s must be one of %s", modelName, strings.Join(lo.Map(limits.DurationSeconds, func(d int, _ int) string { return strconv.Itoa(d) }), ", "))
+		}
+	}
+	if p.DurationSeconds < 0 {
+		return fmt.Errorf("duration_seconds must be positive")
+	}
+	if p.PersonGeneration != "" && !lo.Contains(veoPersonGenerations, p.PersonGeneration) {
+		return fmt.Errorf("person_generation must be one of %s", strings.Join(veoPersonGenerations, ", "))
+	}
+	return nil
+}
+
+// applyTo 将顶层参数写入 metadata，键名与 GeminiVideoGenerationConfig 一致，覆盖 metadata 中的同名参数
+func (p *veoVideoRequest) applyTo(metadata map[string]interface{}) {
+	if p.AspectRatio != "" {
+		metadata["aspectRatio"] = p.AspectRatio
+	}
+	if p.DurationSeconds != 0 {
+		metadata["durationSeconds"] = p.DurationSeconds
+	}
+	if p.PersonGeneration != "" {
+		metadata["personGeneration"] = p.PersonGeneration
+	}
+	if p.NegativePrompt != "" {
+		metadata["negativePrompt"] = p.NegativePrompt
+	}
+}
+
+// buildVideoImage 将图片链接或 base64（可带 data URL 前缀）转换为 Veo 的 image 输入
+func buildVideoImage(image string) (*GeminiVideoImage, error) {
+	var mimeType, data string
+	var err error
+	if strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://") {
+		mimeType, data, err = service.GetImageFromUrl(image)
+	} else {
+		mimeType, data, err = service.DecodeBase64FileData(image)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &GeminiVideoImage{BytesBase64Encoded: data, MimeType: mimeType}, nil
+}
+
+// inlineVideo returns the mime type and base64 data of the first inline video,
+// used by Veo 2 when no storageUri is configured for the request.
+func (op *operationResponse) inlineVideo() (mimeType string, data string) {
+	for _, video := range op.Response.Videos {
+		if video.BytesBase64Encoded != "" {
+			return firstNonEmpty(video.MimeType, video.Encoding), video.BytesBase64Encoded
+		}
+	}
+	if op.Response.BytesBase64Encoded != "" {
+		return op.Response.Encoding, op.Response.BytesBase64Encoded
+	}
+	// some variants use `video` as base64
+	if op.Response.Video != "" {
+		return op.Response.Encoding, op.Response.Video
+	}
+	return "", ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -15,11 +15,11 @@ import (
 	"github.com/samber/lo"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/pkg/errors"
 
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/pkg/jwt"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/service"
@@ -92,6 +92,9 @@ type responsePayload struct {
 	} `json:"data"`
 }
 
+// supportedDurations Kling 支持的视频时长（秒）
+var supportedDurations = map[int]bool{5: true, 10: true}
+
 // ============================
 // Adaptor implementation
 // ============================
@@ -110,10 +113,30 @@ func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
 	// apiKey format: "access_key|secret_key"
 }
 
-// ValidateRequestAndSetAction parses body, validates fields and sets default action.
+// ValidateRequestAndSetAction parses body, validates fields and sets action:
+// image-to-video when a first/tail frame image is given, otherwise text-to-video.
 func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
 	// Use the standard validation method for TaskSubmitReq
-	return relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionGenerate)
+	if taskErr = relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionGenerate); taskErr != nil {
+		return taskErr
+	}
+	req, err := relaycommon.GetTaskRequest(c)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "get_task_request_failed", http.StatusBadRequest)
+	}
+	if !hasImageInput(&req) {
+		info.Action = constant.TaskActionTextGenerate
+	}
+	if req.Duration != 0 && !supportedDurations[req.Duration] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("duration must be 5 or 10"), "invalid_request", http.StatusBadRequest)
+	}
+	if v, ok := req.Metadata["cfg_scale"]; ok {
+		cfgScale, ok := v.(float64)
+		if !ok || cfgScale < 0 || cfgScale > 1 {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("cfg_scale must be a number between 0 and 1"), "invalid_request", http.StatusBadRequest)
+		}
+	}
+	return nil
 }
 
 // BuildRequestURL constructs the upstream URL.
@@ -153,8 +176,10 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 	if err != nil {
 		return nil, err
 	}
-	if body.Image == "" && body.ImageTail == "" {
-		c.Set("action", constant.TaskActionTextGenerate)
+	// 使用映射后的模型名称
+	if info.UpstreamModelName != "" && info.IsModelMapped {
+		body.ModelName = info.UpstreamModelName
+		body.Model = info.UpstreamModelName
 	}
 	data, err := json.Marshal(body)
 	if err != nil {
@@ -165,15 +190,12 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 
 // DoRequest delegates to common helper.
 func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
-	if action := c.GetString("action"); action != "" {
-		info.Action = action
-	}
 	return channel.DoTaskApiRequest(a, c, info, requestBody)
 }
 
 // DoResponse handles upstream response, returns taskID etc.
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 		return
@@ -248,9 +270,13 @@ func (a *TaskAdaptor) GetChannelName() string {
 // ============================
 
 func (a *TaskAdaptor) convertToRequestPayload(req *relaycommon.TaskSubmitReq) (*requestPayload, error) {
+	image := req.Image
+	if image == "" && len(req.Images) > 0 {
+		image = req.Images[0]
+	}
 	r := requestPayload{
 		Prompt:         req.Prompt,
-		Image:          req.Image,
+		Image:          image,
 		Mode:           defaultString(req.Mode, "std"),
 		Duration:       fmt.Sprintf("%d", defaultInt(req.Duration, 5)),
 		AspectRatio:    a.getAspectRatio(req.Size),
@@ -291,6 +317,19 @@ func (a *TaskAdaptor) getAspectRatio(size string) string {
 	}
 }
 
+// hasImageInput 请求或 metadata 中带有首帧/尾帧图片时为图生视频
+func hasImageInput(req *relaycommon.TaskSubmitReq) bool {
+	if req.HasImage() || strings.TrimSpace(req.Image) != "" {
+		return true
+	}
+	for _, key := range []string{"image", "image_tail"} {
+		if s, ok := req.Metadata[key].(string); ok && strings.TrimSpace(s) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func defaultString(s, def string) string {
 	if strings.TrimSpace(s) == "" {
 		return def
@@ -321,20 +360,11 @@ func (a *TaskAdaptor) createJWTTokenWithKey(apiKey string) (string, error) {
 	if len(keyParts) != 2 {
 		return "", errors.New("invalid api_key, required format is accessKey|secretKey")
 	}
-	accessKey := strings.TrimSpace(keyParts[0])
-	if len(keyParts) == 1 {
-		return accessKey, nil
-	}
-	secretKey := strings.TrimSpace(keyParts[1])
-	now := time.Now().Unix()
-	claims := jwt.MapClaims{
-		"iss": accessKey,
-		"exp": now + 1800, // 30 minutes
-		"nbf": now - 5,
+	token := jwt.GenerateKlingJWT(strings.TrimSpace(keyParts[0]), strings.TrimSpace(keyParts[1]))
+	if token == "" {
+		return "", errors.New("sign jwt token failed")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token.Header["typ"] = "JWT"
-	return token.SignedString([]byte(secretKey))
+	return token, nil
 }
 
 func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
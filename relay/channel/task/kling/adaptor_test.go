@@ -0,0 +1,154 @@
+package kling
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestValidateAndBuildRequest(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		wantAction string
+		want       map[string]any
+	}{
+		{
+			name:       "text to video",
+			body:       `{"model":"kling-v1-6","prompt":"a cat","duration":10,"size":"1280x720","metadata":{"cfg_scale":0.7}}`,
+			wantAction: constant.TaskActionTextGenerate,
+			want:       map[string]any{"prompt": "a cat", "model_name": "kling-v1-6", "duration": "10", "aspect_ratio": "16:9", "cfg_scale": 0.7, "mode": "std"},
+		},
+		{
+			name:       "image to video",
+			body:       `{"model":"kling-v2-master","prompt":"a cat","images":["https://example.com/cat.png"]}`,
+			wantAction: constant.TaskActionGenerate,
+			want:       map[string]any{"image": "https://example.com/cat.png", "duration": "5", "aspect_ratio": "1:1", "cfg_scale": 0.5},
+		},
+		{
+			name:       "tail frame in metadata",
+			body:       `{"model":"kling-v1","prompt":"a cat","metadata":{"image_tail":"https://example.com/end.png","aspect_ratio":"9:16"}}`,
+			wantAction: constant.TaskActionGenerate,
+			want:       map[string]any{"image_tail": "https://example.com/end.png", "aspect_ratio": "9:16"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testutil.NewTaskContext("/v1/videos", tc.body)
+			info := testutil.NewTaskRelayInfo()
+			a := &TaskAdaptor{}
+			if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+				t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+			}
+			if info.Action != tc.wantAction {
+				t.Fatalf("action = %s, want %s", info.Action, tc.wantAction)
+			}
+			reader, err := a.BuildRequestBody(c, info)
+			if err != nil {
+				t.Fatalf("BuildRequestBody: %v", err)
+			}
+			var got map[string]any
+			data, _ := io.ReadAll(reader)
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+			for k, v := range tc.want {
+				if got[k] != v {
+					t.Errorf("%s = %v, want %v (body %s)", k, got[k], v, data)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateRequestErrors(t *testing.T) {
+	for _, body := range []string{
+		`{"model":"kling-v1"}`,
+		`{"model":"kling-v1","prompt":"a cat","duration":7}`,
+		`{"model":"kling-v1","prompt":"a cat","metadata":{"cfg_scale":1.5}}`,
+		`{"model":"kling-v1","prompt":"a cat","metadata":{"cfg_scale":"high"}}`,
+	} {
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(testutil.NewTaskContext("/v1/videos", body), testutil.NewTaskRelayInfo()); taskErr == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
+
+func TestCreateJWTToken(t *testing.T) {
+	a := &TaskAdaptor{}
+	signed, err := a.createJWTTokenWithKey("ak-123|sk-secret")
+	if err != nil {
+		t.Fatalf("createJWTTokenWithKey: %v", err)
+	}
+	token, err := jwt.Parse(signed, func(*jwt.Token) (any, error) { return []byte("sk-secret"), nil })
+	if err != nil || !token.Valid {
+		t.Fatalf("parse token: %v", err)
+	}
+	if iss, _ := token.Claims.GetIssuer(); iss != "ak-123" {
+		t.Fatalf("iss = %s", iss)
+	}
+	if _, err := a.createJWTTokenWithKey("no-secret"); err == nil {
+		t.Fatal("expected error for key without secret")
+	}
+	if key, _ := a.createJWTTokenWithKey("sk-relay"); key != "sk-relay" {
+		t.Fatalf("new api relay key should pass through, got %s", key)
+	}
+}
+
+func TestParseTaskResult(t *testing.T) {
+	cases := []struct {
+		status     string
+		wantStatus string
+	}{
+		{"submitted", model.TaskStatusSubmitted},
+		{"processing", model.TaskStatusInProgress},
+		{"succeed", model.TaskStatusSuccess},
+		{"failed", model.TaskStatusFailure},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		body := `{"code":0,"data":{"task_id":"t-1","task_status":"` + tc.status + `","task_result":{"videos":[{"url":"https://example.com/v.mp4"}]}}}`
+		info, err := a.ParseTaskResult([]byte(body))
+		if err != nil || info.Status != tc.wantStatus || info.TaskID != "t-1" || info.Url != "https://example.com/v.mp4" {
+			t.Errorf("%s: got %+v, err %v", tc.status, info, err)
+		}
+	}
+	if _, err := a.ParseTaskResult([]byte(`{"code":0,"data":{"task_status":"paused"}}`)); err == nil {
+		t.Fatal("expected error for unknown status")
+	}
+}
+
+func TestFetchTask(t *testing.T) {
+	service.InitHttpClient()
+
+	var path, auth string
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{{
+		Body: `{"code":0,"data":{"task_id":"t-1","task_status":"succeed","task_result":{"videos":[{"url":"https://example.com/v.mp4"}]}}}`,
+		OnRequest: func(r *http.Request, _ []byte) {
+			path, auth = r.URL.Path, r.Header.Get("Authorization")
+		},
+	}})
+
+	a := &TaskAdaptor{}
+	resp, err := a.FetchTask(server.URL, "ak-123|sk-secret", map[string]any{"task_id": "t-1", "action": constant.TaskActionTextGenerate}, "")
+	if err != nil {
+		t.Fatalf("FetchTask: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if path != "/v1/videos/text2video/t-1" || !strings.HasPrefix(auth, "Bearer ey") {
+		t.Fatalf("unexpected fetch request: path=%s auth=%s", path, auth)
+	}
+	if info, err := a.ParseTaskResult(data); err != nil || info.Status != model.TaskStatusSuccess {
+		t.Fatalf("fetch result: %+v, err %v", info, err)
+	}
+}
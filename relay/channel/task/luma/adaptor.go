@@ -0,0 +1,338 @@
+package luma
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// ============================
+// Request / Response structures (Luma Dream Machine API)
+// ============================
+
+const (
+	defaultBaseURL  = "https://api.lumalabs.ai"
+	generationsPath = "/dream-machine/v1/generations"
+
+	defaultDuration    = 5
+	defaultAspectRatio = "16:9"
+)
+
+var supportedDurations = map[int]bool{5: true, 9: true}
+
+var supportedAspectRatios = map[string]bool{
+	"1:1": true, "16:9": true, "9:16": true, "4:3": true, "3:4": true, "21:9": true, "9:21": true,
+}
+
+type keyframe struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type submitRequest struct {
+	Model        string              `json:"model"`
+	Prompt       string              `json:"prompt,omitempty"`
+	AspectRatio  string              `json:"aspect_ratio,omitempty"`
+	Loop         bool                `json:"loop,omitempty"`
+	ExpandPrompt *bool               `json:"expand_prompt,omitempty"`
+	Duration     string              `json:"duration,omitempty"`
+	Resolution   string              `json:"resolution,omitempty"`
+	Keyframes    map[string]keyframe `json:"keyframes,omitempty"`
+}
+
+type generationResponse struct {
+	ID            string `json:"id"`
+	State         string `json:"state"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	Assets        *struct {
+		Video string `json:"video,omitempty"`
+		Image string `json:"image,omitempty"`
+	} `json:"assets,omitempty"`
+	// Detail 请求参数错误时 Luma 返回 {"detail": "..."}
+	Detail json.RawMessage `json:"detail,omitempty"`
+}
+
+// lumaRequest 客户端请求结构，image_url 作为首帧，兼容 image/images 字段
+type lumaRequest struct {
+	Model        string          `json:"model"`
+	Prompt       string          `json:"prompt,omitempty"`
+	ImageURL     string          `json:"image_url,omitempty"`
+	Image        string          `json:"image,omitempty"`
+	Images       []string        `json:"images,omitempty"`
+	Loop         bool            `json:"loop,omitempty"`
+	AspectRatio  string          `json:"aspect_ratio,omitempty"`
+	ExpandPrompt *bool           `json:"expand_prompt,omitempty"`
+	Duration     json.RawMessage `json:"duration,omitempty"`
+	Resolution   string          `json:"resolution,omitempty"`
+
+	// seconds 为解析后的视频秒数
+	seconds int
+}
+
+func (r *lumaRequest) imageURL() string {
+	if strings.TrimSpace(r.ImageURL) != "" {
+		return r.ImageURL
+	}
+	if strings.TrimSpace(r.Image) != "" {
+		return r.Image
+	}
+	if len(r.Images) > 0 {
+		return r.Images[0]
+	}
+	return ""
+}
+
+// parseDuration 解析视频时长，兼容 5、"5" 与 "5s" 三种写法
+func parseDuration(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return defaultDuration, nil
+	}
+	var seconds int
+	if json.Unmarshal(raw, &seconds) == nil {
+		return seconds, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", string(raw))
+	}
+	seconds, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(s), "s"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return seconds, nil
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	if a.baseURL == "" {
+		a.baseURL = defaultBaseURL
+	}
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	req := lumaRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("model is required"), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 带图片时以图片为首帧生成，否则为文生视频，文生视频必须提供 prompt
+	if req.imageURL() != "" {
+		info.Action = constant.TaskActionGenerate
+	} else {
+		if strings.TrimSpace(req.Prompt) == "" {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("prompt is required"), "invalid_request", http.StatusBadRequest)
+		}
+		info.Action = constant.TaskActionTextGenerate
+	}
+
+	seconds, err := parseDuration(req.Duration)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if !supportedDurations[seconds] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("duration must be 5 or 9"), "invalid_request", http.StatusBadRequest)
+	}
+	req.seconds = seconds
+	if req.AspectRatio == "" {
+		req.AspectRatio = defaultAspectRatio
+	}
+	if !supportedAspectRatios[req.AspectRatio] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("invalid aspect_ratio: %s, expected one of 1:1, 16:9, 9:16, 4:3, 3:4, 21:9, 9:21", req.AspectRatio), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 按视频秒数计费
+	info.PriceData.OtherRatios = map[string]float64{
+		"seconds": float64(seconds),
+	}
+
+	c.Set("luma_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return a.baseURL + generationsPath, nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("luma_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(lumaRequest)
+
+	// 使用映射后的模型名称
+	modelName := req.Model
+	if info.UpstreamModelName != "" {
+		modelName = info.UpstreamModelName
+	}
+
+	body := submitRequest{
+		Model:        modelName,
+		Prompt:       req.Prompt,
+		AspectRatio:  req.AspectRatio,
+		Loop:         req.Loop,
+		ExpandPrompt: req.ExpandPrompt,
+		Duration:     fmt.Sprintf("%ds", req.seconds),
+		Resolution:   req.Resolution,
+	}
+	if imageURL := req.imageURL(); imageURL != "" {
+		body.Keyframes = map[string]keyframe{
+			"frame0": {Type: "image", URL: imageURL},
+		}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var gr generationResponse
+	if err := json.Unmarshal(responseBody, &gr); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if len(gr.Detail) > 0 {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("%s", detailMessage(gr.Detail)), "luma_error", http.StatusBadRequest)
+	}
+	if gr.ID == "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("empty generation id, response: %s", string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": gr.ID})
+	return gr.ID, responseBody, nil
+}
+
+// detailMessage detail 可能为字符串或校验错误列表
+func detailMessage(detail json.RawMessage) string {
+	var s string
+	if json.Unmarshal(detail, &s) == nil {
+		return s
+	}
+	return string(detail)
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	if baseUrl == "" {
+		baseUrl = defaultBaseURL
+	}
+	uri := fmt.Sprintf("%s%s/%s", baseUrl, generationsPath, taskID)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{
+		"ray-2",
+		"ray-flash-2",
+		"ray-1-6",
+	}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "luma"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var gr generationResponse
+	if err := json.Unmarshal(respBody, &gr); err != nil {
+		return nil, err
+	}
+	return parseGeneration(&gr), nil
+}
+
+// parseGeneration 将 Luma 生成任务详情转换为通用任务信息
+func parseGeneration(gr *generationResponse) *relaycommon.TaskInfo {
+	res := &relaycommon.TaskInfo{TaskID: gr.ID}
+
+	switch gr.State {
+	case "queued":
+		res.Status = model.TaskStatusQueued
+		res.Progress = "10%"
+	case "dreaming":
+		res.Status = model.TaskStatusInProgress
+		res.Progress = "50%"
+	case "completed":
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		if gr.Assets != nil {
+			res.Url = gr.Assets.Video
+		}
+	case "failed":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = gr.FailureReason
+		if res.Reason == "" {
+			res.Reason = "任务执行失败"
+		}
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", gr.State)
+	}
+	return res
+}
@@ -0,0 +1,183 @@
+package luma
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	"github.com/QuantumNous/new-api/service"
+)
+
+func TestParseTaskResult(t *testing.T) {
+	cases := []struct {
+		body       string
+		wantStatus string
+		wantUrl    string
+		wantReason string
+	}{
+		{`{"id":"g-1","state":"queued"}`, model.TaskStatusQueued, "", ""},
+		{`{"id":"g-1","state":"dreaming"}`, model.TaskStatusInProgress, "", ""},
+		{`{"id":"g-1","state":"completed","assets":{"video":"https://storage.cdn-luma.com/v.mp4"}}`, model.TaskStatusSuccess, "https://storage.cdn-luma.com/v.mp4", ""},
+		{`{"id":"g-1","state":"failed","failure_reason":"prompt rejected"}`, model.TaskStatusFailure, "", "prompt rejected"},
+		{`{"id":"g-1","state":"failed"}`, model.TaskStatusFailure, "", "任务执行失败"},
+		{`{"id":"g-1"}`, model.TaskStatusUnknown, "", "未知响应格式"},
+		{`{"id":"g-1","state":"paused"}`, model.TaskStatusUnknown, "", "未知状态: paused"},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		info, err := a.ParseTaskResult([]byte(tc.body))
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%s): %v", tc.body, err)
+		}
+		if info.Status != tc.wantStatus || info.Url != tc.wantUrl || info.Reason != tc.wantReason || info.TaskID != "g-1" {
+			t.Errorf("%s: got status=%s url=%q reason=%q", tc.body, info.Status, info.Url, info.Reason)
+		}
+	}
+	if _, err := a.ParseTaskResult([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid body")
+	}
+}
+
+func TestValidateAndBuildRequest(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		wantAction  string
+		wantSeconds float64
+		want        string
+	}{
+		{
+			name:        "text to video defaults",
+			body:        `{"model":"ray-2","prompt":"a cat"}`,
+			wantAction:  constant.TaskActionTextGenerate,
+			wantSeconds: 5,
+			want:        `{"model":"ray-2","prompt":"a cat","aspect_ratio":"16:9","duration":"5s"}`,
+		},
+		{
+			name:        "image to video with options",
+			body:        `{"model":"ray-2","prompt":"a cat","image_url":"https://example.com/cat.png","loop":true,"aspect_ratio":"9:16","expand_prompt":false,"duration":"9s","resolution":"1080p"}`,
+			wantAction:  constant.TaskActionGenerate,
+			wantSeconds: 9,
+			want:        `{"model":"ray-2","prompt":"a cat","aspect_ratio":"9:16","loop":true,"expand_prompt":false,"duration":"9s","resolution":"1080p","keyframes":{"frame0":{"type":"image","url":"https://example.com/cat.png"}}}`,
+		},
+		{
+			name:        "images fallback and numeric duration",
+			body:        `{"model":"ray-flash-2","images":["https://example.com/cat.png"],"duration":9}`,
+			wantAction:  constant.TaskActionGenerate,
+			wantSeconds: 9,
+			want:        `{"model":"ray-flash-2","aspect_ratio":"16:9","duration":"9s","keyframes":{"frame0":{"type":"image","url":"https://example.com/cat.png"}}}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testutil.NewTaskContext("/v1/videos", tc.body)
+			info := testutil.NewTaskRelayInfo()
+			a := &TaskAdaptor{}
+			if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+				t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+			}
+			if info.Action != tc.wantAction || info.PriceData.OtherRatios["seconds"] != tc.wantSeconds {
+				t.Fatalf("action = %s, seconds = %v", info.Action, info.PriceData.OtherRatios["seconds"])
+			}
+			reader, err := a.BuildRequestBody(c, info)
+			if err != nil {
+				t.Fatalf("BuildRequestBody: %v", err)
+			}
+			data, _ := io.ReadAll(reader)
+			if string(data) != tc.want {
+				t.Fatalf("body:\n got %s\nwant %s", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestErrors(t *testing.T) {
+	for _, body := range []string{
+		`{"prompt":"a cat"}`,
+		`{"model":"ray-2"}`,
+		`{"model":"ray-2","prompt":"a cat","duration":10}`,
+		`{"model":"ray-2","prompt":"a cat","duration":"long"}`,
+		`{"model":"ray-2","prompt":"a cat","aspect_ratio":"2:1"}`,
+	} {
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(testutil.NewTaskContext("/v1/videos", body), testutil.NewTaskRelayInfo()); taskErr == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
+
+func TestSubmitAndFetch(t *testing.T) {
+	service.InitHttpClient()
+
+	var submitPath, submitAuth, fetchPath string
+	var submitted map[string]any
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{
+		{
+			StatusCode: http.StatusCreated,
+			Body:       `{"id":"g-1","state":"queued"}`,
+			OnRequest: func(r *http.Request, body []byte) {
+				submitPath, submitAuth = r.URL.Path, r.Header.Get("Authorization")
+				_ = json.Unmarshal(body, &submitted)
+			},
+		},
+		{
+			Body: `{"id":"g-1","state":"completed","assets":{"video":"https://storage.cdn-luma.com/v.mp4"}}`,
+			OnRequest: func(r *http.Request, _ []byte) {
+				fetchPath = r.URL.Path
+			},
+		},
+		{StatusCode: http.StatusBadRequest, Body: `{"detail":"Invalid aspect ratio"}`},
+	})
+
+	c := testutil.NewTaskContext("/v1/videos", `{"model":"luma-video","prompt":"a cat"}`)
+	info := testutil.NewTaskRelayInfo()
+	info.ChannelBaseUrl = server.URL
+	info.ApiKey = "luma-test"
+	info.UpstreamModelName = "ray-2"
+	a := &TaskAdaptor{}
+	a.Init(info)
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	body, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	resp, err := a.DoRequest(c, info, body)
+	if err != nil {
+		t.Fatalf("DoRequest: %v", err)
+	}
+	taskID, _, taskErr := a.DoResponse(c, resp, info)
+	if taskErr != nil || taskID != "g-1" {
+		t.Fatalf("submit: id=%q err=%v", taskID, taskErr)
+	}
+	if submitPath != "/dream-machine/v1/generations" || submitAuth != "Bearer luma-test" || submitted["model"] != "ray-2" {
+		t.Fatalf("unexpected submit request: path=%s auth=%s body=%v", submitPath, submitAuth, submitted)
+	}
+
+	resp, err = a.FetchTask(server.URL, "luma-test", map[string]any{"task_id": taskID}, "")
+	if err != nil {
+		t.Fatalf("FetchTask: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	result, err := a.ParseTaskResult(data)
+	if err != nil || result.Status != model.TaskStatusSuccess || result.Url != "https://storage.cdn-luma.com/v.mp4" {
+		t.Fatalf("fetch result: %+v, err %v", result, err)
+	}
+	if fetchPath != "/dream-machine/v1/generations/g-1" {
+		t.Fatalf("fetch path = %s", fetchPath)
+	}
+
+	resp, err = a.DoRequest(testutil.NewTaskContext("/v1/videos", `{}`), info, strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("DoRequest: %v", err)
+	}
+	if _, _, taskErr = a.DoResponse(c, resp, info); taskErr == nil || taskErr.Message != "Invalid aspect ratio" {
+		t.Fatalf("expected luma error, got %v", taskErr)
+	}
+}
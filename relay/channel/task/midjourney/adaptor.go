@@ -0,0 +1,297 @@
+package midjourney
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// ============================
+// Request / Response structures (midjourney-proxy API)
+// ============================
+
+// midjourney-proxy 提交接口的返回码
+const (
+	codeSubmitted     = 1
+	codeNoAccount     = 3
+	codeRequestError  = 4
+	codeAlreadyExists = 21
+	codeQueued        = 22
+)
+
+// mjRequest 客户端请求结构，model 与 action 至少提供一个
+type mjRequest struct {
+	Model string `json:"model"`
+	dto.MidjourneyRequest
+	Base64 string `json:"base64,omitempty"`
+}
+
+type imagineRequest struct {
+	Prompt      string   `json:"prompt"`
+	BotType     string   `json:"botType,omitempty"`
+	Base64Array []string `json:"base64Array,omitempty"`
+}
+
+type changeRequest struct {
+	Action string `json:"action"`
+	Index  int    `json:"index"`
+	TaskId string `json:"taskId"`
+}
+
+type describeRequest struct {
+	BotType string `json:"botType,omitempty"`
+	Base64  string `json:"base64"`
+}
+
+var submitPaths = map[string]string{
+	constant.MjActionImagine:   "/mj/submit/imagine",
+	constant.MjActionUpscale:   "/mj/submit/change",
+	constant.MjActionVariation: "/mj/submit/change",
+	constant.MjActionDescribe:  "/mj/submit/describe",
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	a.apiKey = strings.TrimPrefix(info.ApiKey, "Bearer ")
+}
+
+// resolveAction 根据 action 字段或 mj_* 模型名确定操作，两者同时提供时必须一致
+func resolveAction(req *mjRequest) (string, error) {
+	action := strings.ToUpper(strings.TrimSpace(req.Action))
+	if modelAction, ok := constant.MidjourneyModel2Action[req.Model]; ok {
+		if action != "" && action != modelAction {
+			return "", fmt.Errorf("action %s does not match model %s", action, req.Model)
+		}
+		action = modelAction
+	}
+	if action == "" {
+		return "", fmt.Errorf("action is required")
+	}
+	if _, ok := submitPaths[action]; !ok {
+		return "", fmt.Errorf("unsupported action: %s", action)
+	}
+	return action, nil
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	req := mjRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	action, err := resolveAction(&req)
+	if err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+
+	switch action {
+	case constant.MjActionImagine:
+		if strings.TrimSpace(req.Prompt) == "" {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("prompt is required"), "invalid_request", http.StatusBadRequest)
+		}
+	case constant.MjActionUpscale, constant.MjActionVariation:
+		if req.TaskId == "" {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("taskId is required"), "invalid_request", http.StatusBadRequest)
+		}
+		if req.Index < 1 || req.Index > 4 {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("index must be between 1 and 4"), "invalid_request", http.StatusBadRequest)
+		}
+	case constant.MjActionDescribe:
+		if req.Base64 == "" && len(req.Base64Array) > 0 {
+			req.Base64 = req.Base64Array[0]
+		}
+		if req.Base64 == "" {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("base64 is required"), "invalid_request", http.StatusBadRequest)
+		}
+	}
+	req.Action = action
+	info.Action = action
+
+	c.Set("mj_task_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	path, ok := submitPaths[info.Action]
+	if !ok {
+		return "", fmt.Errorf("unsupported action: %s", info.Action)
+	}
+	return fmt.Sprintf("%s%s", strings.TrimSuffix(a.baseURL, "/"), path), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("mj-api-secret", a.apiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("mj_task_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(mjRequest)
+
+	var body any
+	switch req.Action {
+	case constant.MjActionImagine:
+		body = imagineRequest{Prompt: req.Prompt, BotType: req.BotType, Base64Array: req.Base64Array}
+	case constant.MjActionUpscale, constant.MjActionVariation:
+		body = changeRequest{Action: req.Action, Index: req.Index, TaskId: req.TaskId}
+	case constant.MjActionDescribe:
+		body = describeRequest{BotType: req.BotType, Base64: req.Base64}
+	default:
+		return nil, fmt.Errorf("unsupported action: %s", req.Action)
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var mjResp dto.MidjourneyResponse
+	if err := json.Unmarshal(responseBody, &mjResp); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	switch mjResp.Code {
+	case codeSubmitted, codeAlreadyExists, codeQueued:
+	default:
+		return "", nil, midjourneyTaskError(&mjResp)
+	}
+	if mjResp.Result == "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("empty task id, response: %s", string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": mjResp.Result})
+	return mjResp.Result, responseBody, nil
+}
+
+// midjourneyTaskError 将提交失败的返回码转换为任务错误，Data 中保留原始的 Midjourney 响应
+func midjourneyTaskError(mjResp *dto.MidjourneyResponse) *dto.TaskError {
+	desc := mjResp.Description
+	if desc == "" {
+		desc = fmt.Sprintf("midjourney submit failed with code %d", mjResp.Code)
+	}
+	var taskErr *dto.TaskError
+	switch mjResp.Code {
+	case codeRequestError:
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("%s", desc), "midjourney_request_error", http.StatusBadRequest)
+	case codeNoAccount:
+		// 无可用账号实例，交给上层重试其他渠道
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("%s", desc), "midjourney_no_available_account", http.StatusServiceUnavailable)
+	default:
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("%s", desc), "midjourney_error", http.StatusInternalServerError)
+	}
+	taskErr.Data = service.MidjourneyErrorWrapper(mjResp.Code, desc)
+	return taskErr
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	uri := fmt.Sprintf("%s/mj/task/%s/fetch", strings.TrimSuffix(baseUrl, "/"), taskID)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("mj-api-secret", strings.TrimPrefix(key, "Bearer "))
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{
+		"mj_imagine",
+		"mj_upscale",
+		"mj_variation",
+		"mj_describe",
+	}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "midjourney"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var mjTask dto.MidjourneyDto
+	if err := json.Unmarshal(respBody, &mjTask); err != nil {
+		return nil, err
+	}
+	res := &relaycommon.TaskInfo{TaskID: mjTask.MjId, Progress: mjTask.Progress}
+
+	switch mjTask.Status {
+	case "NOT_START", "SUBMITTED", "PENDING":
+		res.Status = model.TaskStatusQueued
+		if res.Progress == "" {
+			res.Progress = "10%"
+		}
+	case "IN_PROGRESS":
+		res.Status = model.TaskStatusInProgress
+		if res.Progress == "" {
+			res.Progress = "50%"
+		}
+	case "SUCCESS":
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		res.Url = mjTask.ImageUrl
+	case "FAILURE":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = mjTask.FailReason
+		if res.Reason == "" {
+			res.Reason = "任务执行失败"
+		}
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", mjTask.Status)
+	}
+	return res, nil
+}
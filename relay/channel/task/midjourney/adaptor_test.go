@@ -0,0 +1,116 @@
+package midjourney
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+)
+
+func TestValidateRequestAndSetAction(t *testing.T) {
+	cases := []struct {
+		body    string
+		action  string
+		path    string
+		wantErr bool
+	}{
+		{`{"model":"mj_imagine","prompt":"a cat"}`, constant.MjActionImagine, "/mj/submit/imagine", false},
+		{`{"action":"upscale","taskId":"123","index":2}`, constant.MjActionUpscale, "/mj/submit/change", false},
+		{`{"model":"mj_variation","taskId":"123","index":1}`, constant.MjActionVariation, "/mj/submit/change", false},
+		{`{"model":"mj_describe","base64Array":["data:image/png;base64,AAAA"]}`, constant.MjActionDescribe, "/mj/submit/describe", false},
+		{`{"model":"mj_imagine"}`, "", "", true},
+		{`{"model":"mj_upscale","taskId":"123","index":5}`, "", "", true},
+		{`{"model":"mj_imagine","action":"UPSCALE","prompt":"a cat"}`, "", "", true},
+		{`{"action":"BLEND"}`, "", "", true},
+	}
+	for _, tc := range cases {
+		c := testutil.NewTaskContext("/v1/videos", tc.body)
+		info := testutil.NewTaskRelayInfo()
+		info.ChannelBaseUrl = "https://mj.example.com"
+		a := &TaskAdaptor{}
+		a.Init(info)
+		taskErr := a.ValidateRequestAndSetAction(c, info)
+		if tc.wantErr {
+			if taskErr == nil {
+				t.Errorf("expected validation error for %s", tc.body)
+			}
+			continue
+		}
+		if taskErr != nil {
+			t.Fatalf("ValidateRequestAndSetAction(%s): %v", tc.body, taskErr.Message)
+		}
+		if info.Action != tc.action {
+			t.Errorf("%s: action = %s, want %s", tc.body, info.Action, tc.action)
+		}
+		if url, _ := a.BuildRequestURL(info); url != "https://mj.example.com"+tc.path {
+			t.Errorf("%s: url = %s", tc.body, url)
+		}
+		if _, err := a.BuildRequestBody(c, info); err != nil {
+			t.Errorf("%s: BuildRequestBody: %v", tc.body, err)
+		}
+	}
+}
+
+func TestDoResponse(t *testing.T) {
+	a := &TaskAdaptor{}
+	newResp := func(body string) *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	c := testutil.NewTaskContext("/v1/videos", "")
+	taskID, _, taskErr := a.DoResponse(c, newResp(`{"code":22,"description":"queued","result":"1712345678"}`), nil)
+	if taskErr != nil || taskID != "1712345678" {
+		t.Errorf("queued submit: task id %q, err %v", taskID, taskErr)
+	}
+
+	_, _, taskErr = a.DoResponse(c, newResp(`{"code":4,"description":"prompt contains banned word"}`), nil)
+	if taskErr == nil || taskErr.StatusCode != http.StatusBadRequest || !taskErr.LocalError {
+		t.Fatalf("request error: %+v", taskErr)
+	}
+	mjResp, ok := taskErr.Data.(*dto.MidjourneyResponse)
+	if !ok || mjResp.Code != 4 || mjResp.Description != "prompt contains banned word" {
+		t.Errorf("error data = %#v", taskErr.Data)
+	}
+
+	_, _, taskErr = a.DoResponse(c, newResp(`{"code":3,"description":"No available account instance"}`), nil)
+	if taskErr == nil || taskErr.StatusCode != http.StatusServiceUnavailable || taskErr.LocalError {
+		t.Errorf("no account error: %+v", taskErr)
+	}
+}
+
+func TestParseTaskResult(t *testing.T) {
+	cases := []struct {
+		body   string
+		status string
+	}{
+		{`{"id":"1","status":"NOT_START"}`, string(model.TaskStatusQueued)},
+		{`{"id":"1","status":"PENDING"}`, string(model.TaskStatusQueued)},
+		{`{"id":"1","status":"IN_PROGRESS","progress":"35%"}`, string(model.TaskStatusInProgress)},
+		{`{"id":"1","status":"SUCCESS","imageUrl":"https://cdn.example.com/1.png"}`, string(model.TaskStatusSuccess)},
+		{`{"id":"1","status":"FAILURE","failReason":"banned prompt"}`, string(model.TaskStatusFailure)},
+		{`{"id":"1","status":"MODAL"}`, string(model.TaskStatusUnknown)},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		info, err := a.ParseTaskResult([]byte(tc.body))
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%s): %v", tc.body, err)
+		}
+		if info.Status != tc.status {
+			t.Errorf("%s: status = %s, want %s", tc.body, info.Status, tc.status)
+		}
+	}
+	info, _ := a.ParseTaskResult([]byte(`{"id":"1","status":"IN_PROGRESS","progress":"35%"}`))
+	if info.Progress != "35%" {
+		t.Errorf("progress = %s", info.Progress)
+	}
+	info, _ = a.ParseTaskResult([]byte(`{"id":"1","status":"SUCCESS","imageUrl":"https://cdn.example.com/1.png"}`))
+	if info.Url != "https://cdn.example.com/1.png" {
+		t.Errorf("url = %s", info.Url)
+	}
+}
@@ -0,0 +1,367 @@
+package runway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// ============================
+// Request / Response structures (Runway API)
+// ============================
+
+const (
+	defaultBaseURL = "https://api.dev.runwayml.com"
+	// Runway 要求每个请求携带 API 版本头
+	apiVersion = "2024-11-06"
+
+	defaultDuration = 5
+	defaultRatio    = "1280:720"
+)
+
+var supportedDurations = map[int]bool{5: true, 10: true}
+
+// supportedCameraDirections camera_direction 允许的取值
+var supportedCameraDirections = map[string]bool{
+	"left": true, "right": true, "up": true, "down": true,
+	"in": true, "out": true, "clockwise": true, "counterclockwise": true,
+}
+
+type submitRequest struct {
+	Model       string `json:"model"`
+	PromptText  string `json:"promptText,omitempty"`
+	PromptImage string `json:"promptImage,omitempty"`
+	Ratio       string `json:"ratio"`
+	Duration    int    `json:"duration"`
+	Seed        *int64 `json:"seed,omitempty"`
+	// 镜头运动控制
+	CameraMotion    string   `json:"cameraMotion,omitempty"`
+	CameraDirection string   `json:"cameraDirection,omitempty"`
+	CameraVelocity  *float64 `json:"cameraVelocity,omitempty"`
+}
+
+type submitResponse struct {
+	ID    string `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+type fetchResponse struct {
+	ID          string   `json:"id"`
+	Status      string   `json:"status"`
+	CreatedAt   string   `json:"createdAt"`
+	Output      []string `json:"output,omitempty"`
+	Failure     string   `json:"failure,omitempty"`
+	FailureCode string   `json:"failureCode,omitempty"`
+	// Progress 取值 0~1，仅 RUNNING 状态返回
+	Progress *float64 `json:"progress,omitempty"`
+}
+
+// runwayRequest 客户端请求结构，不嵌入 TaskSubmitReq 以保留 ratio、seed 等扩展字段
+type runwayRequest struct {
+	Model    string   `json:"model"`
+	Prompt   string   `json:"prompt,omitempty"`
+	Image    string   `json:"image,omitempty"`
+	Images   []string `json:"images,omitempty"`
+	Duration int      `json:"duration,omitempty"`
+	Ratio    string   `json:"ratio,omitempty"`
+	Seed     *int64   `json:"seed,omitempty"`
+
+	CameraMotion    string   `json:"camera_motion,omitempty"`
+	CameraDirection string   `json:"camera_direction,omitempty"`
+	CameraVelocity  *float64 `json:"camera_velocity,omitempty"`
+	// Metadata 兼容通过 metadata 传递镜头参数的客户端
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func (r *runwayRequest) promptImage() string {
+	if strings.TrimSpace(r.Image) != "" {
+		return r.Image
+	}
+	if len(r.Images) > 0 {
+		return r.Images[0]
+	}
+	return ""
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	if a.baseURL == "" {
+		a.baseURL = defaultBaseURL
+	}
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	req := runwayRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("model is required"), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 带图片时走图生视频，否则为文生视频，文生视频必须提供 prompt
+	if req.promptImage() != "" {
+		info.Action = constant.TaskActionGenerate
+	} else {
+		if strings.TrimSpace(req.Prompt) == "" {
+			return service.TaskErrorWrapperLocal(fmt.Errorf("prompt is required"), "invalid_request", http.StatusBadRequest)
+		}
+		info.Action = constant.TaskActionTextGenerate
+	}
+
+	if req.Duration == 0 {
+		req.Duration = defaultDuration
+	}
+	if !supportedDurations[req.Duration] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("duration must be 5 or 10"), "invalid_request", http.StatusBadRequest)
+	}
+	if req.Ratio == "" {
+		req.Ratio = defaultRatio
+	}
+	if !isValidRatio(req.Ratio) {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("invalid ratio: %s, expected format like 1280:720", req.Ratio), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 镜头参数优先取顶层字段，其次取 metadata
+	req.CameraMotion = getStringParam(req.CameraMotion, req.Metadata, "camera_motion")
+	req.CameraDirection = getStringParam(req.CameraDirection, req.Metadata, "camera_direction")
+	req.CameraVelocity = getFloatPtrParam(req.CameraVelocity, req.Metadata, "camera_velocity")
+	if req.CameraDirection != "" && !supportedCameraDirections[req.CameraDirection] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("invalid camera_direction: %s, expected one of left, right, up, down, in, out, clockwise, counterclockwise", req.CameraDirection), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 按视频秒数计费
+	info.PriceData.OtherRatios = map[string]float64{
+		"seconds": float64(req.Duration),
+	}
+
+	c.Set("runway_request", req)
+	return nil
+}
+
+// isValidRatio 校验 "宽:高" 格式的分辨率比例
+func isValidRatio(ratio string) bool {
+	w, h, ok := strings.Cut(ratio, ":")
+	if !ok {
+		return false
+	}
+	width, err := strconv.Atoi(w)
+	if err != nil || width <= 0 {
+		return false
+	}
+	height, err := strconv.Atoi(h)
+	return err == nil && height > 0
+}
+
+// getStringParam 获取字符串参数，优先从直接字段获取，其次从 metadata 获取
+func getStringParam(direct string, metadata map[string]interface{}, key string) string {
+	if direct != "" {
+		return direct
+	}
+	if v, ok := metadata[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// getFloatPtrParam 获取 float 指针参数
+func getFloatPtrParam(direct *float64, metadata map[string]interface{}, key string) *float64 {
+	if direct != nil {
+		return direct
+	}
+	if v, ok := metadata[key].(float64); ok {
+		return &v
+	}
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	if info.Action == constant.TaskActionTextGenerate {
+		return fmt.Sprintf("%s/v1/text_to_video", a.baseURL), nil
+	}
+	return fmt.Sprintf("%s/v1/image_to_video", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set("X-Runway-Version", apiVersion)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("runway_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(runwayRequest)
+
+	// 使用映射后的模型名称
+	modelName := req.Model
+	if info.UpstreamModelName != "" {
+		modelName = info.UpstreamModelName
+	}
+
+	body := submitRequest{
+		Model:      modelName,
+		PromptText: req.Prompt,
+		Ratio:      req.Ratio,
+		Duration:   req.Duration,
+		Seed:       req.Seed,
+
+		CameraMotion:    req.CameraMotion,
+		CameraDirection: req.CameraDirection,
+		CameraVelocity:  req.CameraVelocity,
+	}
+	if info.Action != constant.TaskActionTextGenerate {
+		body.PromptImage = req.promptImage()
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var sr submitResponse
+	if err := json.Unmarshal(responseBody, &sr); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if sr.Error != "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("%s", sr.Error), "runway_error", http.StatusBadRequest)
+	}
+	if sr.ID == "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("empty task id, response: %s", string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": sr.ID})
+	return sr.ID, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	if baseUrl == "" {
+		baseUrl = defaultBaseURL
+	}
+	uri := fmt.Sprintf("%s/v1/tasks/%s", baseUrl, taskID)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("X-Runway-Version", apiVersion)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{
+		"gen3a_turbo",
+		"gen4_turbo",
+		"gen4.5",
+	}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "runway"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var fr fetchResponse
+	if err := json.Unmarshal(respBody, &fr); err != nil {
+		return nil, err
+	}
+	return parseFetchResponse(&fr), nil
+}
+
+// parseFetchResponse 将 Runway 任务详情转换为通用任务信息
+func parseFetchResponse(fr *fetchResponse) *relaycommon.TaskInfo {
+	res := &relaycommon.TaskInfo{TaskID: fr.ID}
+
+	switch fr.Status {
+	case "PENDING", "THROTTLED":
+		res.Status = model.TaskStatusQueued
+		res.Progress = "10%"
+	case "RUNNING":
+		res.Status = model.TaskStatusInProgress
+		res.Progress = "50%"
+		if fr.Progress != nil {
+			res.Progress = fmt.Sprintf("%d%%", int(*fr.Progress*100))
+		}
+	case "SUCCEEDED":
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		if len(fr.Output) > 0 {
+			res.Url = fr.Output[0]
+		}
+	case "FAILED":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = fr.Failure
+		if res.Reason == "" {
+			res.Reason = "任务执行失败"
+		}
+		if fr.FailureCode != "" {
+			res.Reason = fmt.Sprintf("%s: %s", fr.FailureCode, res.Reason)
+		}
+	case "CANCELLED":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = "任务已取消"
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", fr.Status)
+	}
+	return res
+}
@@ -0,0 +1,158 @@
+package runway
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func TestParseTaskResultStatus(t *testing.T) {
+	cases := []struct {
+		status string
+		want   string
+	}{
+		{"PENDING", string(model.TaskStatusQueued)},
+		{"THROTTLED", string(model.TaskStatusQueued)},
+		{"RUNNING", string(model.TaskStatusInProgress)},
+		{"SUCCEEDED", string(model.TaskStatusSuccess)},
+		{"FAILED", string(model.TaskStatusFailure)},
+		{"CANCELLED", string(model.TaskStatusFailure)},
+		{"", string(model.TaskStatusUnknown)},
+		{"PAUSED", string(model.TaskStatusUnknown)},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		body, _ := json.Marshal(map[string]any{"id": "task-1", "status": tc.status})
+		info, err := a.ParseTaskResult(body)
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%q): %v", tc.status, err)
+		}
+		if info.Status != tc.want {
+			t.Errorf("status %q = %s, want %s", tc.status, info.Status, tc.want)
+		}
+	}
+}
+
+func TestParseTaskResultDetails(t *testing.T) {
+	a := &TaskAdaptor{}
+	info, err := a.ParseTaskResult([]byte(`{"id":"task-1","status":"RUNNING","progress":0.42}`))
+	if err != nil || info.Progress != "42%" {
+		t.Errorf("running progress = %q, err %v", info.Progress, err)
+	}
+	info, err = a.ParseTaskResult([]byte(`{"id":"task-1","status":"SUCCEEDED","output":["https://example.com/v.mp4"]}`))
+	if err != nil || info.Url != "https://example.com/v.mp4" {
+		t.Errorf("succeeded url = %q, err %v", info.Url, err)
+	}
+	info, err = a.ParseTaskResult([]byte(`{"id":"task-1","status":"FAILED","failure":"moderation","failureCode":"SAFETY"}`))
+	if err != nil || info.Reason != "SAFETY: moderation" {
+		t.Errorf("failed reason = %q, err %v", info.Reason, err)
+	}
+}
+
+func TestValidateAndBuildImageToVideo(t *testing.T) {
+	c := testutil.NewTaskContext("/v1/videos", `{"model":"gen4_turbo","prompt":"a cat","image":"https://example.com/cat.png","duration":10,"ratio":"720:1280","seed":7}`)
+	info := testutil.NewTaskRelayInfo()
+	a := &TaskAdaptor{}
+	a.Init(info)
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	if info.Action != constant.TaskActionGenerate {
+		t.Errorf("action = %s, want %s", info.Action, constant.TaskActionGenerate)
+	}
+	if got := info.PriceData.OtherRatios["seconds"]; got != 10 {
+		t.Errorf("seconds ratio = %v, want 10", got)
+	}
+	if url, _ := a.BuildRequestURL(info); url != defaultBaseURL+"/v1/image_to_video" {
+		t.Errorf("url = %s", url)
+	}
+
+	reader, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	var req submitRequest
+	if err := common.Unmarshal(data, &req); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if req.Model != "gen4_turbo" || req.PromptText != "a cat" || req.PromptImage != "https://example.com/cat.png" ||
+		req.Ratio != "720:1280" || req.Duration != 10 || req.Seed == nil || *req.Seed != 7 {
+		t.Errorf("unexpected request body: %s", data)
+	}
+}
+
+func TestValidateTextToVideoDefaults(t *testing.T) {
+	c := testutil.NewTaskContext("/v1/videos", `{"model":"gen4.5","prompt":"a cat"}`)
+	info := testutil.NewTaskRelayInfo()
+	info.ChannelBaseUrl = "https://runway.example.com"
+	a := &TaskAdaptor{}
+	a.Init(info)
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	if url, _ := a.BuildRequestURL(info); url != "https://runway.example.com/v1/text_to_video" {
+		t.Errorf("url = %s", url)
+	}
+	reader, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	var req submitRequest
+	_ = common.Unmarshal(data, &req)
+	if req.Duration != defaultDuration || req.Ratio != defaultRatio || req.PromptImage != "" {
+		t.Errorf("unexpected defaults: %s", data)
+	}
+}
+
+func TestValidateRejectsInvalidRequest(t *testing.T) {
+	bodies := []string{
+		`{"prompt":"a cat"}`,
+		`{"model":"gen4.5"}`,
+		`{"model":"gen4.5","prompt":"a cat","duration":7}`,
+		`{"model":"gen4.5","prompt":"a cat","ratio":"16x9"}`,
+		`{"model":"gen4.5","prompt":"a cat","camera_direction":"sideways"}`,
+		`{"model":"gen4.5","prompt":"a cat","metadata":{"camera_direction":"sideways"}}`,
+	}
+	for _, body := range bodies {
+		c := testutil.NewTaskContext("/v1/videos", body)
+		info := &relaycommon.RelayInfo{TaskRelayInfo: &relaycommon.TaskRelayInfo{}}
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, info); taskErr == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
+
+func TestBuildCameraControls(t *testing.T) {
+	bodies := []string{
+		`{"model":"gen4.5","prompt":"a cat","camera_motion":"pan","camera_direction":"left","camera_velocity":0.5}`,
+		`{"model":"gen4.5","prompt":"a cat","metadata":{"camera_motion":"pan","camera_direction":"left","camera_velocity":0.5}}`,
+		`{"model":"gen4.5","prompt":"a cat","camera_direction":"left","metadata":{"camera_motion":"pan","camera_direction":"right","camera_velocity":0.5}}`,
+	}
+	for _, body := range bodies {
+		c := testutil.NewTaskContext("/v1/videos", body)
+		info := testutil.NewTaskRelayInfo()
+		a := &TaskAdaptor{}
+		a.Init(info)
+		if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+			t.Fatalf("ValidateRequestAndSetAction(%s): %v", body, taskErr.Message)
+		}
+		reader, err := a.BuildRequestBody(c, info)
+		if err != nil {
+			t.Fatalf("BuildRequestBody: %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		var req submitRequest
+		_ = common.Unmarshal(data, &req)
+		if req.CameraMotion != "pan" || req.CameraDirection != "left" || req.CameraVelocity == nil || *req.CameraVelocity != 0.5 {
+			t.Errorf("unexpected camera controls for %s: %s", body, data)
+		}
+	}
+}
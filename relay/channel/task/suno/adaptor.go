@@ -13,9 +13,11 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,8 +26,80 @@ type TaskAdaptor struct {
 	ChannelType int
 }
 
-func (a *TaskAdaptor) ParseTaskResult([]byte) (*relaycommon.TaskInfo, error) {
-	return nil, fmt.Errorf("not implement") // todo implement this method if needed
+// clipStatusMap 将上游片段状态映射为任务状态
+var clipStatusMap = map[string]string{
+	"submitted":  model.TaskStatusSubmitted,
+	"queued":     model.TaskStatusQueued,
+	"queueing":   model.TaskStatusQueued,
+	"streaming":  model.TaskStatusInProgress,
+	"processing": model.TaskStatusInProgress,
+	"complete":   model.TaskStatusSuccess,
+	"success":    model.TaskStatusSuccess,
+	"error":      model.TaskStatusFailure,
+	"failed":     model.TaskStatusFailure,
+}
+
+func convertClipStatus(status string) string {
+	upper := strings.ToUpper(status)
+	switch upper {
+	case model.TaskStatusSubmitted, model.TaskStatusQueued, model.TaskStatusInProgress,
+		model.TaskStatusSuccess, model.TaskStatusFailure, string(model.TaskStatusNotStart):
+		return upper
+	}
+	if s, ok := clipStatusMap[strings.ToLower(status)]; ok {
+		return s
+	}
+	return model.TaskStatusUnknown
+}
+
+// ParseTaskResult 解析单个任务的查询结果，兼容 /suno/fetch 返回的列表格式
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var item dto.SunoDataResponse
+	var listResp dto.TaskResponse[[]dto.SunoDataResponse]
+	if err := json.Unmarshal(respBody, &listResp); err == nil && listResp.Code != "" {
+		if !listResp.IsSuccess() {
+			return nil, fmt.Errorf("fetch task failed: %s", listResp.Message)
+		}
+		if len(listResp.Data) == 0 {
+			return nil, fmt.Errorf("task not found")
+		}
+		item = listResp.Data[0]
+	} else if err := json.Unmarshal(respBody, &item); err != nil {
+		return nil, fmt.Errorf("unmarshal task result failed: %w", err)
+	}
+
+	taskInfo := &relaycommon.TaskInfo{
+		TaskID: item.TaskID,
+		Status: convertClipStatus(item.Status),
+		Reason: item.FailReason,
+	}
+
+	var songs []dto.SunoSong
+	if len(item.Data) > 0 && json.Unmarshal(item.Data, &songs) == nil && len(songs) > 0 {
+		// 以片段状态为准：任一片段失败即失败，全部完成才算成功
+		complete := 0
+		for _, song := range songs {
+			switch convertClipStatus(song.Status) {
+			case model.TaskStatusFailure:
+				taskInfo.Status = model.TaskStatusFailure
+				if taskInfo.Reason == "" {
+					taskInfo.Reason = fmt.Sprintf("clip %s failed", song.ID)
+				}
+			case model.TaskStatusSuccess:
+				complete++
+			}
+		}
+		if taskInfo.Status != model.TaskStatusFailure && complete == len(songs) {
+			taskInfo.Status = model.TaskStatusSuccess
+		}
+		taskInfo.Url = songs[0].AudioURL
+	}
+
+	switch taskInfo.Status {
+	case model.TaskStatusSuccess, model.TaskStatusFailure:
+		taskInfo.Progress = "100%"
+	}
+	return taskInfo, nil
 }
 
 func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
@@ -38,7 +112,7 @@ func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycom
 	var sunoRequest *dto.SunoSubmitReq
 	err := common.UnmarshalBodyReusable(c, &sunoRequest)
 	if err != nil {
-		taskErr = service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		taskErr = service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
 		return
 	}
 	err = actionValidate(c, sunoRequest, action)
@@ -55,6 +129,15 @@ func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycom
 		info.OriginTaskID = sunoRequest.TaskID
 	}
 
+	if action == constant.SunoActionMusic {
+		if setting := model_setting.GetSunoSettings(); setting.BillByClip {
+			if info.PriceData.OtherRatios == nil {
+				info.PriceData.OtherRatios = map[string]float64{}
+			}
+			info.PriceData.OtherRatios["clips"] = float64(setting.ClipsPerGeneration)
+		}
+	}
+
 	info.Action = action
 	c.Set("task_request", sunoRequest)
 	return nil
@@ -93,7 +176,7 @@ func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, req
 }
 
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 		return
@@ -166,6 +249,10 @@ func actionValidate(c *gin.Context, sunoRequest *dto.SunoSubmitReq, action strin
 		if sunoRequest.Mv == "" {
 			sunoRequest.Mv = "chirp-v3-0"
 		}
+		if sunoRequest.Tags == "" {
+			sunoRequest.Tags = sunoRequest.Style
+		}
+		sunoRequest.Style = ""
 	case constant.SunoActionLyrics:
 		if sunoRequest.Prompt == "" {
 			err = fmt.Errorf("prompt_empty")
@@ -0,0 +1,59 @@
+package suno
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestParseTaskResult(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus string
+		wantUrl    string
+	}{
+		{
+			name:       "fetch list in progress",
+			body:       `{"code":"success","data":[{"task_id":"t1","status":"IN_PROGRESS","data":[{"id":"c1","status":"streaming"},{"id":"c2","status":"queued"}]}]}`,
+			wantStatus: model.TaskStatusInProgress,
+		},
+		{
+			name:       "all clips complete",
+			body:       `{"task_id":"t1","status":"processing","data":[{"id":"c1","status":"complete","audio_url":"https://cdn/c1.mp3"},{"id":"c2","status":"complete"}]}`,
+			wantStatus: model.TaskStatusSuccess,
+			wantUrl:    "https://cdn/c1.mp3",
+		},
+		{
+			name:       "clip error",
+			body:       `{"task_id":"t1","status":"IN_PROGRESS","data":[{"id":"c1","status":"complete"},{"id":"c2","status":"error"}]}`,
+			wantStatus: model.TaskStatusFailure,
+		},
+		{
+			name:       "task failed",
+			body:       `{"task_id":"t1","status":"FAILURE","fail_reason":"banned words"}`,
+			wantStatus: model.TaskStatusFailure,
+		},
+	}
+	a := &TaskAdaptor{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := a.ParseTaskResult([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseTaskResult returned error: %v", err)
+			}
+			if info.Status != tt.wantStatus {
+				t.Fatalf("expected status %s, got %s", tt.wantStatus, info.Status)
+			}
+			if info.Url != tt.wantUrl {
+				t.Fatalf("expected url %q, got %q", tt.wantUrl, info.Url)
+			}
+		})
+	}
+}
+
+func TestParseTaskResultFetchFailed(t *testing.T) {
+	if _, err := (&TaskAdaptor{}).ParseTaskResult([]byte(`{"code":"error","message":"bad key"}`)); err == nil {
+		t.Fatal("expected error for unsuccessful fetch response")
+	}
+}
@@ -0,0 +1,278 @@
+package udio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// ============================
+// Request / Response structures (Udio API)
+// ============================
+
+const (
+	defaultBaseURL = "https://api.udio.com"
+	generatePath   = "/generate"
+	songsPath      = "/songs"
+
+	defaultClipLength = 32
+	// 按单个片段计费，每次只向上游请求一首歌曲
+	numTracksPerRequest = 1
+)
+
+// supportedClipLengths Udio 支持 32 秒与 130 秒两种片段长度
+var supportedClipLengths = map[int]bool{32: true, 130: true}
+
+type generateRequest struct {
+	Model            string   `json:"model"`
+	Prompt           string   `json:"prompt,omitempty"`
+	Lyrics           string   `json:"lyrics,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	MakeInstrumental bool     `json:"make_instrumental,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	ClipLength       int      `json:"clip_length"`
+	NumTracks        int      `json:"num_tracks"`
+}
+
+type generateResponse struct {
+	ID       string   `json:"id,omitempty"`
+	TrackIDs []string `json:"track_ids,omitempty"`
+	Error    *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// songID 兼容 id 与 track_ids 两种返回格式，请求时已限定只生成一首歌曲
+func (r *generateResponse) songID() string {
+	if r.ID != "" {
+		return r.ID
+	}
+	if len(r.TrackIDs) > 0 {
+		return r.TrackIDs[0]
+	}
+	return ""
+}
+
+type songResponse struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	SongPath     string `json:"song_path,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// udioRequest 客户端请求结构，tags 为风格标签
+type udioRequest struct {
+	Model            string   `json:"model"`
+	Prompt           string   `json:"prompt,omitempty"`
+	Lyrics           string   `json:"lyrics,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	MakeInstrumental bool     `json:"make_instrumental,omitempty"`
+	Seed             *int64   `json:"seed,omitempty"`
+	ClipLength       int      `json:"clip_length,omitempty"`
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	if a.baseURL == "" {
+		a.baseURL = defaultBaseURL
+	}
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	req := udioRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("model is required"), "invalid_request", http.StatusBadRequest)
+	}
+	if strings.TrimSpace(req.Prompt) == "" && strings.TrimSpace(req.Lyrics) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt or lyrics is required"), "invalid_request", http.StatusBadRequest)
+	}
+	if req.MakeInstrumental && strings.TrimSpace(req.Lyrics) != "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("lyrics cannot be used with make_instrumental"), "invalid_request", http.StatusBadRequest)
+	}
+	if req.ClipLength == 0 {
+		req.ClipLength = defaultClipLength
+	}
+	if !supportedClipLengths[req.ClipLength] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("clip_length must be 32 or 130"), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 按片段计费，每次请求生成一个片段，不设置按秒的附加倍率
+	info.Action = constant.TaskActionGenerate
+	c.Set("udio_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return a.baseURL + generatePath, nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("udio_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(udioRequest)
+
+	// 使用映射后的模型名称
+	modelName := req.Model
+	if info.UpstreamModelName != "" {
+		modelName = info.UpstreamModelName
+	}
+
+	body := generateRequest{
+		Model:            modelName,
+		Prompt:           req.Prompt,
+		Lyrics:           req.Lyrics,
+		Tags:             req.Tags,
+		MakeInstrumental: req.MakeInstrumental,
+		Seed:             req.Seed,
+		ClipLength:       req.ClipLength,
+		NumTracks:        numTracksPerRequest,
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var gr generateResponse
+	if err := json.Unmarshal(responseBody, &gr); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if gr.Error != nil && gr.Error.Message != "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("%s", gr.Error.Message), "udio_error", http.StatusBadRequest)
+	}
+	songID := gr.songID()
+	if songID == "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("empty song id, response: %s", string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+	if len(gr.TrackIDs) > numTracksPerRequest {
+		common.SysLog(fmt.Sprintf("udio returned %d tracks for a single-track request, only %s is tracked", len(gr.TrackIDs), songID))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": songID})
+	return songID, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	if baseUrl == "" {
+		baseUrl = defaultBaseURL
+	}
+	uri := fmt.Sprintf("%s%s/%s", baseUrl, songsPath, taskID)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{
+		"udio-v1.5",
+		"udio-v1",
+	}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "udio"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var sr songResponse
+	if err := json.Unmarshal(respBody, &sr); err != nil {
+		return nil, err
+	}
+	return parseSong(&sr), nil
+}
+
+// parseSong 将 Udio 歌曲生成状态转换为通用任务信息
+func parseSong(sr *songResponse) *relaycommon.TaskInfo {
+	res := &relaycommon.TaskInfo{TaskID: sr.ID}
+
+	switch strings.ToLower(sr.Status) {
+	case "queued", "pending":
+		res.Status = model.TaskStatusQueued
+		res.Progress = "10%"
+	case "processing", "generating":
+		res.Status = model.TaskStatusInProgress
+		res.Progress = "50%"
+	case "complete", "completed":
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		res.Url = sr.SongPath
+	case "failed", "error":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = sr.ErrorMessage
+		if res.Reason == "" {
+			res.Reason = "任务执行失败"
+		}
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", sr.Status)
+	}
+	return res
+}
@@ -0,0 +1,174 @@
+package udio
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	"github.com/QuantumNous/new-api/service"
+)
+
+func TestParseTaskResult(t *testing.T) {
+	cases := []struct {
+		body       string
+		wantStatus string
+		wantUrl    string
+		wantReason string
+	}{
+		{`{"id":"s-1","status":"queued"}`, model.TaskStatusQueued, "", ""},
+		{`{"id":"s-1","status":"processing"}`, model.TaskStatusInProgress, "", ""},
+		{`{"id":"s-1","status":"complete","song_path":"https://storage.udio.com/s-1.mp3"}`, model.TaskStatusSuccess, "https://storage.udio.com/s-1.mp3", ""},
+		{`{"id":"s-1","status":"failed","error_message":"lyrics rejected"}`, model.TaskStatusFailure, "", "lyrics rejected"},
+		{`{"id":"s-1","status":"error"}`, model.TaskStatusFailure, "", "任务执行失败"},
+		{`{"id":"s-1"}`, model.TaskStatusUnknown, "", "未知响应格式"},
+		{`{"id":"s-1","status":"paused"}`, model.TaskStatusUnknown, "", "未知状态: paused"},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		info, err := a.ParseTaskResult([]byte(tc.body))
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%s): %v", tc.body, err)
+		}
+		if info.Status != tc.wantStatus || info.Url != tc.wantUrl || info.Reason != tc.wantReason || info.TaskID != "s-1" {
+			t.Errorf("%s: got status=%s url=%q reason=%q", tc.body, info.Status, info.Url, info.Reason)
+		}
+	}
+	if _, err := a.ParseTaskResult([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid body")
+	}
+}
+
+func TestValidateAndBuildRequest(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "prompt defaults",
+			body: `{"model":"udio-v1.5","prompt":"a jazz song"}`,
+			want: `{"model":"udio-v1.5","prompt":"a jazz song","clip_length":32,"num_tracks":1}`,
+		},
+		{
+			name: "lyrics with options",
+			body: `{"model":"udio-v1.5","prompt":"a jazz song","lyrics":"la la la","tags":["jazz","piano"],"seed":42,"clip_length":130}`,
+			want: `{"model":"udio-v1.5","prompt":"a jazz song","lyrics":"la la la","tags":["jazz","piano"],"seed":42,"clip_length":130,"num_tracks":1}`,
+		},
+		{
+			name: "instrumental",
+			body: `{"model":"udio-v1","prompt":"lofi beats","make_instrumental":true}`,
+			want: `{"model":"udio-v1","prompt":"lofi beats","make_instrumental":true,"clip_length":32,"num_tracks":1}`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := testutil.NewTaskContext("/v1/videos", tc.body)
+			info := testutil.NewTaskRelayInfo()
+			a := &TaskAdaptor{}
+			if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+				t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+			}
+			// 按片段计费，不应设置附加倍率
+			if info.Action != constant.TaskActionGenerate || len(info.PriceData.OtherRatios) != 0 {
+				t.Fatalf("action = %s, other ratios = %v", info.Action, info.PriceData.OtherRatios)
+			}
+			reader, err := a.BuildRequestBody(c, info)
+			if err != nil {
+				t.Fatalf("BuildRequestBody: %v", err)
+			}
+			data, _ := io.ReadAll(reader)
+			if string(data) != tc.want {
+				t.Fatalf("body:\n got %s\nwant %s", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestErrors(t *testing.T) {
+	for _, body := range []string{
+		`{"prompt":"a jazz song"}`,
+		`{"model":"udio-v1.5"}`,
+		`{"model":"udio-v1.5","lyrics":"la la la","make_instrumental":true}`,
+		`{"model":"udio-v1.5","prompt":"a jazz song","clip_length":60}`,
+	} {
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(testutil.NewTaskContext("/v1/videos", body), testutil.NewTaskRelayInfo()); taskErr == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
+
+func TestSubmitAndFetch(t *testing.T) {
+	service.InitHttpClient()
+
+	var submitPath, submitAuth, fetchPath string
+	var submitted map[string]any
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{
+		{
+			Body: `{"track_ids":["s-1"]}`,
+			OnRequest: func(r *http.Request, body []byte) {
+				submitPath, submitAuth = r.URL.Path, r.Header.Get("Authorization")
+				_ = json.Unmarshal(body, &submitted)
+			},
+		},
+		{
+			Body: `{"id":"s-1","status":"complete","song_path":"https://storage.udio.com/s-1.mp3"}`,
+			OnRequest: func(r *http.Request, _ []byte) {
+				fetchPath = r.URL.Path
+			},
+		},
+		{StatusCode: http.StatusBadRequest, Body: `{"error":{"message":"Invalid clip length"}}`},
+	})
+
+	c := testutil.NewTaskContext("/v1/videos", `{"model":"udio-music","prompt":"a jazz song"}`)
+	info := testutil.NewTaskRelayInfo()
+	info.ChannelBaseUrl = server.URL
+	info.ApiKey = "udio-test"
+	info.UpstreamModelName = "udio-v1.5"
+	a := &TaskAdaptor{}
+	a.Init(info)
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	body, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	resp, err := a.DoRequest(c, info, body)
+	if err != nil {
+		t.Fatalf("DoRequest: %v", err)
+	}
+	taskID, _, taskErr := a.DoResponse(c, resp, info)
+	if taskErr != nil || taskID != "s-1" {
+		t.Fatalf("submit: id=%q err=%v", taskID, taskErr)
+	}
+	if submitPath != "/generate" || submitAuth != "Bearer udio-test" || submitted["model"] != "udio-v1.5" || submitted["num_tracks"] != float64(1) {
+		t.Fatalf("unexpected submit request: path=%s auth=%s body=%v", submitPath, submitAuth, submitted)
+	}
+
+	resp, err = a.FetchTask(server.URL, "udio-test", map[string]any{"task_id": taskID}, "")
+	if err != nil {
+		t.Fatalf("FetchTask: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	result, err := a.ParseTaskResult(data)
+	if err != nil || result.Status != model.TaskStatusSuccess || result.Url != "https://storage.udio.com/s-1.mp3" {
+		t.Fatalf("fetch result: %+v, err %v", result, err)
+	}
+	if fetchPath != "/songs/s-1" {
+		t.Fatalf("fetch path = %s", fetchPath)
+	}
+
+	resp, err = a.DoRequest(testutil.NewTaskContext("/v1/videos", `{}`), info, strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("DoRequest: %v", err)
+	}
+	if _, _, taskErr = a.DoResponse(c, resp, info); taskErr == nil || taskErr.Message != "Invalid clip length" {
+		t.Fatalf("expected udio error, got %v", taskErr)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"regexp"
 	"strings"
 
@@ -76,7 +77,14 @@ func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
 // ValidateRequestAndSetAction parses body, validates fields and sets default action.
 func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
 	// Use the standard validation method for TaskSubmitReq
-	return relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate)
+	if taskErr = relaycommon.ValidateBasicTaskRequest(c, info, constant.TaskActionTextGenerate); taskErr != nil {
+		return taskErr
+	}
+	req, _ := c.Get("task_request")
+	if video := requestVideoURI(c, req.(relaycommon.TaskSubmitReq)); video != "" && !isUploadedVideoURI(info, video) {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("video must be a gs:// URI returned by /v1/files/upload"), "invalid_request", http.StatusBadRequest)
+	}
+	return nil
 }
 
 // BuildRequestURL constructs the upstream URL.
@@ -161,6 +169,12 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 	if _, ok := body.Parameters["sampleCount"]; !ok {
 		body.Parameters["sampleCount"] = 1
 	}
+	if video := requestVideoURI(c, req); video != "" {
+		body.Instances[0]["video"] = map[string]any{
+			"gcsUri":   video,
+			"mimeType": videoMimeType(video),
+		}
+	}
 
 	if body.Parameters["sampleCount"].(int) <= 0 {
 		return nil, fmt.Errorf("sampleCount must be greater than 0")
@@ -191,6 +205,50 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 	return bytes.NewReader(data), nil
 }
 
+// requestVideoURI returns the input video URI, read from the top-level video
+// field (as returned by /v1/files/upload) or metadata.video.
+func requestVideoURI(c *gin.Context, req relaycommon.TaskSubmitReq) string {
+	var video string
+	if body, err := common.GetRequestBody(c); err == nil {
+		var raw struct {
+			Video string `json:"video"`
+		}
+		if common.Unmarshal(body, &raw) == nil {
+			video = raw.Video
+		}
+	}
+	if video == "" && req.Metadata != nil {
+		video, _ = req.Metadata["video"].(string)
+	}
+	return strings.TrimSpace(video)
+}
+
+// isUploadedVideoURI reports whether uri points into the caller's own upload
+// prefix gs://{bucket}/uploads/{userId}/{channelId}/ on the channel's bucket.
+func isUploadedVideoURI(info *relaycommon.RelayInfo, uri string) bool {
+	if info.ChannelMeta == nil {
+		return false
+	}
+	bucket := strings.TrimSpace(info.ChannelOtherSettings.VertexGcsBucket)
+	if bucket == "" || info.UserId == 0 || info.ChannelId == 0 {
+		return false
+	}
+	prefix := fmt.Sprintf("gs://%s/uploads/%d/%d/", bucket, info.UserId, info.ChannelId)
+	name := strings.TrimPrefix(uri, prefix)
+	return name != uri && name != "" && !strings.Contains(name, "/") && !strings.Contains(name, "..")
+}
+
+func videoMimeType(uri string) string {
+	switch strings.ToLower(path.Ext(uri)) {
+	case ".mov":
+		return "video/quicktime"
+	case ".webm":
+		return "video/webm"
+	default:
+		return "video/mp4"
+	}
+}
+
 // DoRequest delegates to common helper.
 func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
 	return channel.DoTaskApiRequest(a, c, info, requestBody)
@@ -198,7 +256,7 @@ func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, req
 
 // DoResponse handles upstream response, returns taskID etc.
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 	}
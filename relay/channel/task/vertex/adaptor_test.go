@@ -0,0 +1,72 @@
+package vertex
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func newTestInfo(bucket string, userId int) *relaycommon.RelayInfo {
+	info := testutil.NewTaskRelayInfo()
+	info.UserId = userId
+	info.ChannelId = 3
+	info.ChannelOtherSettings = dto.ChannelOtherSettings{VertexGcsBucket: bucket}
+	return info
+}
+
+func TestIsUploadedVideoURI(t *testing.T) {
+	info := newTestInfo("videos", 7)
+	cases := []struct {
+		uri  string
+		want bool
+	}{
+		{"gs://videos/uploads/7/3/abc.mp4", true},
+		{"gs://videos/uploads/8/3/abc.mp4", false},
+		{"gs://videos/uploads/7/4/abc.mp4", false},
+		{"gs://videos/uploads/7/abc.mp4", false},
+		{"gs://videos/uploads/7/3/", false},
+		{"gs://videos/uploads/7/3/nested/abc.mp4", false},
+		{"gs://videos/uploads/7/3/../4/abc.mp4", false},
+		{"gs://other/uploads/7/3/abc.mp4", false},
+		{"gs://videos/private/abc.mp4", false},
+		{"https://example.com/abc.mp4", false},
+	}
+	for _, tc := range cases {
+		if got := isUploadedVideoURI(info, tc.uri); got != tc.want {
+			t.Errorf("isUploadedVideoURI(%q) = %v, want %v", tc.uri, got, tc.want)
+		}
+	}
+	if isUploadedVideoURI(newTestInfo("", 7), "gs://videos/uploads/7/3/abc.mp4") {
+		t.Error("expected rejection when the channel has no upload bucket")
+	}
+}
+
+func TestValidateRequestRejectsForeignVideo(t *testing.T) {
+	cases := []struct {
+		video      string
+		wantStatus int
+	}{
+		{"gs://videos/uploads/7/3/abc.mp4", 0},
+		{"gs://videos/uploads/8/3/abc.mp4", http.StatusBadRequest},
+		{"gs://videos/uploads/7/4/abc.mp4", http.StatusBadRequest},
+		{"gs://videos/secret.mp4", http.StatusBadRequest},
+		{"https://example.com/abc.mp4", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		c := testutil.NewTaskContext("/v1/videos", `{"model":"veo-3.0-generate-001","prompt":"a cat","video":"`+tc.video+`"}`)
+
+		taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, newTestInfo("videos", 7))
+		if tc.wantStatus == 0 {
+			if taskErr != nil {
+				t.Errorf("video %q: unexpected error %s", tc.video, taskErr.Message)
+			}
+			continue
+		}
+		if taskErr == nil || taskErr.StatusCode != tc.wantStatus {
+			t.Errorf("video %q: expected status %d, got %+v", tc.video, tc.wantStatus, taskErr)
+		}
+	}
+}
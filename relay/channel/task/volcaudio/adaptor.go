@@ -0,0 +1,313 @@
+package volcaudio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+)
+
+// ============================
+// Request / Response structures (Volc Ark Audio)
+// ============================
+
+// submitRequest 火山音频生成请求结构
+type submitRequest struct {
+	Model   string   `json:"model"`
+	Text    string   `json:"text"`
+	VoiceID string   `json:"voice_id,omitempty"`
+	Speed   *float64 `json:"speed,omitempty"`
+	Format  string   `json:"format,omitempty"` // mp3, wav, pcm, ogg_opus
+}
+
+type submitResponse struct {
+	ID    string `json:"id"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type fetchResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Status  string `json:"status"`
+	Content struct {
+		AudioURL string `json:"audio_url"`
+		Duration int    `json:"duration,omitempty"`
+	} `json:"content"`
+	Usage struct {
+		// 输入文本的字符数
+		TotalTokens int `json:"total_tokens"`
+	} `json:"usage"`
+	CreatedAt int64 `json:"created_at"`
+	UpdatedAt int64 `json:"updated_at"`
+	Error     struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// volcAudioRequest 用于解析客户端请求，text 为空时使用 prompt。
+// 不嵌入 TaskSubmitReq，避免其自定义 UnmarshalJSON 吞掉扩展字段
+type volcAudioRequest struct {
+	Model   string   `json:"model"`
+	Prompt  string   `json:"prompt,omitempty"`
+	Text    string   `json:"text,omitempty"`
+	VoiceID string   `json:"voice_id,omitempty"`
+	Speed   *float64 `json:"speed,omitempty"`
+	Format  string   `json:"format,omitempty"`
+}
+
+func (r *volcAudioRequest) inputText() string {
+	if strings.TrimSpace(r.Text) != "" {
+		return r.Text
+	}
+	return r.Prompt
+}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+// speed 允许范围
+const (
+	minSpeed = 0.5
+	maxSpeed = 2.0
+)
+
+var supportedFormats = map[string]bool{
+	"mp3":      true,
+	"wav":      true,
+	"pcm":      true,
+	"ogg_opus": true,
+}
+
+type TaskAdaptor struct {
+	ChannelType int
+	baseURL     string
+	apiKey      string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = info.ChannelBaseUrl
+	a.apiKey = info.ApiKey
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	info.Action = constant.TaskActionGenerate
+
+	req := volcAudioRequest{}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("model is required"), "invalid_request", http.StatusBadRequest)
+	}
+	text := req.inputText()
+	if strings.TrimSpace(text) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("text is required"), "invalid_request", http.StatusBadRequest)
+	}
+	if req.Speed != nil && (*req.Speed < minSpeed || *req.Speed > maxSpeed) {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("speed must be between %.1f and %.1f", minSpeed, maxSpeed), "invalid_request", http.StatusBadRequest)
+	}
+	if req.Format != "" && !supportedFormats[strings.ToLower(req.Format)] {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("unsupported format: %s", req.Format), "invalid_request", http.StatusBadRequest)
+	}
+
+	// 按输入字符数预扣费，任务完成后按上游返回的 usage.total_tokens 结算
+	info.PriceData.OtherRatios = map[string]float64{
+		"characters": float64(utf8.RuneCountInString(text)),
+	}
+
+	c.Set("volc_audio_request", req)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return fmt.Sprintf("%s/api/v3/contents/generations/audio", a.baseURL), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("volc_audio_request")
+	if !ok {
+		return nil, fmt.Errorf("request not found in context")
+	}
+	req := v.(volcAudioRequest)
+
+	// 使用映射后的模型名称
+	modelName := req.Model
+	if info.UpstreamModelName != "" {
+		modelName = info.UpstreamModelName
+	}
+
+	body := submitRequest{
+		Model:   modelName,
+		Text:    req.inputText(),
+		VoiceID: req.VoiceID,
+		Speed:   req.Speed,
+		Format:  strings.ToLower(req.Format),
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+	}
+	_ = resp.Body.Close()
+
+	var sr submitResponse
+	if err := json.Unmarshal(responseBody, &sr); err != nil {
+		return "", nil, service.TaskErrorWrapper(err, "unmarshal_response_body_failed", http.StatusInternalServerError)
+	}
+	if sr.Error != nil && sr.Error.Code != "" {
+		return "", nil, service.TaskErrorWrapperLocal(
+			fmt.Errorf("%s: %s", sr.Error.Code, sr.Error.Message),
+			sr.Error.Code,
+			http.StatusBadRequest,
+		)
+	}
+	if sr.ID == "" {
+		return "", nil, service.TaskErrorWrapperLocal(fmt.Errorf("empty task id, response: %s", string(responseBody)), "invalid_response", http.StatusInternalServerError)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task_id": sr.ID})
+	return sr.ID, responseBody, nil
+}
+
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, _ := body["task_id"].(string)
+	if taskID == "" {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	uri := fmt.Sprintf("%s/api/v3/contents/generations/audio/%s", baseUrl, taskID)
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return []string{
+		"doubao-seed-tts-1-0",
+		"doubao-seed-tts-1-0-pro",
+	}
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return "volcaudio"
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	var fr fetchResponse
+	if err := json.Unmarshal(respBody, &fr); err != nil {
+		return nil, err
+	}
+	return parseFetchResponse(&fr), nil
+}
+
+// parseFetchResponse 将火山音频任务详情转换为通用任务信息
+func parseFetchResponse(fr *fetchResponse) *relaycommon.TaskInfo {
+	res := &relaycommon.TaskInfo{TaskID: fr.ID}
+
+	if fr.Error.Code != "" && !strings.EqualFold(fr.Status, "succeeded") {
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = fmt.Sprintf("%s: %s", fr.Error.Code, fr.Error.Message)
+		return res
+	}
+
+	switch strings.ToLower(fr.Status) {
+	case "queued":
+		res.Status = model.TaskStatusQueued
+		res.Progress = "20%"
+	case "running":
+		res.Status = model.TaskStatusInProgress
+		res.Progress = "60%"
+	case "succeeded":
+		res.Status = model.TaskStatusSuccess
+		res.Progress = "100%"
+		res.Url = fr.Content.AudioURL
+		// Reason 中携带模型名，供完成后按字符数结算时使用
+		successData := map[string]interface{}{
+			"id":         fr.ID,
+			"model":      fr.Model,
+			"status":     fr.Status,
+			"content":    fr.Content,
+			"usage":      fr.Usage,
+			"created_at": fr.CreatedAt,
+			"updated_at": fr.UpdatedAt,
+		}
+		if dataBytes, err := json.Marshal(successData); err == nil {
+			res.Reason = string(dataBytes)
+		}
+		// usage.total_tokens 为输入文本的字符数
+		if fr.Usage.TotalTokens > 0 {
+			res.TotalTokens = fr.Usage.TotalTokens
+		}
+	case "failed":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		if fr.Error.Message != "" {
+			res.Reason = fr.Error.Message
+		} else {
+			res.Reason = "任务执行失败"
+		}
+	case "expired":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = "任务超时"
+	case "cancelled":
+		res.Status = model.TaskStatusFailure
+		res.Progress = "100%"
+		res.Reason = "任务已取消"
+	case "":
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = "未知响应格式"
+	default:
+		res.Status = model.TaskStatusUnknown
+		res.Progress = "0%"
+		res.Reason = fmt.Sprintf("未知状态: %s", fr.Status)
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package volcaudio
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func TestParseTaskResultStatus(t *testing.T) {
+	cases := []struct {
+		status string
+		want   string
+	}{
+		{"queued", string(model.TaskStatusQueued)},
+		{"running", string(model.TaskStatusInProgress)},
+		{"succeeded", string(model.TaskStatusSuccess)},
+		{"failed", string(model.TaskStatusFailure)},
+		{"expired", string(model.TaskStatusFailure)},
+		{"cancelled", string(model.TaskStatusFailure)},
+		{"", string(model.TaskStatusUnknown)},
+		{"paused", string(model.TaskStatusUnknown)},
+	}
+	a := &TaskAdaptor{}
+	for _, tc := range cases {
+		body, _ := json.Marshal(map[string]any{"id": "cgt-1", "status": tc.status})
+		info, err := a.ParseTaskResult(body)
+		if err != nil {
+			t.Fatalf("ParseTaskResult(%q): %v", tc.status, err)
+		}
+		if info.Status != tc.want {
+			t.Errorf("status %q = %s, want %s", tc.status, info.Status, tc.want)
+		}
+	}
+}
+
+func TestParseTaskResultSucceededUsage(t *testing.T) {
+	body := `{"id":"cgt-1","model":"doubao-seed-tts-1-0","status":"succeeded","content":{"audio_url":"https://example.com/a.mp3"},"usage":{"total_tokens":42}}`
+	info, err := (&TaskAdaptor{}).ParseTaskResult([]byte(body))
+	if err != nil {
+		t.Fatalf("ParseTaskResult: %v", err)
+	}
+	if info.Url != "https://example.com/a.mp3" {
+		t.Errorf("url = %q", info.Url)
+	}
+	if info.TotalTokens != 42 {
+		t.Errorf("total tokens = %d, want 42", info.TotalTokens)
+	}
+	var reason map[string]any
+	if err := json.Unmarshal([]byte(info.Reason), &reason); err != nil || reason["model"] != "doubao-seed-tts-1-0" {
+		t.Errorf("reason must carry the model name for settlement, got %q", info.Reason)
+	}
+}
+
+func TestValidateAndBuildRequestBody(t *testing.T) {
+	c := testutil.NewTaskContext("/v1/videos", `{"model":"doubao-seed-tts-1-0","text":"你好，world","voice_id":"zh_female_1","speed":1.2,"format":"MP3"}`)
+	info := testutil.NewTaskRelayInfo()
+	a := &TaskAdaptor{}
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction: %v", taskErr.Message)
+	}
+	if got := info.PriceData.OtherRatios["characters"]; got != 8 {
+		t.Errorf("characters ratio = %v, want 8", got)
+	}
+
+	info.UpstreamModelName = "doubao-seed-tts-1-0-pro"
+	reader, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	var req submitRequest
+	if err := common.Unmarshal(data, &req); err != nil {
+		t.Fatalf("decode body: %v", err)
+	}
+	if req.Model != "doubao-seed-tts-1-0-pro" || req.Text != "你好，world" || req.VoiceID != "zh_female_1" || req.Format != "mp3" {
+		t.Errorf("unexpected request body: %s", data)
+	}
+	if req.Speed == nil || *req.Speed != 1.2 {
+		t.Errorf("speed not forwarded: %s", data)
+	}
+}
+
+func TestValidateRejectsInvalidRequest(t *testing.T) {
+	bodies := []string{
+		`{"model":"doubao-seed-tts-1-0"}`,
+		`{"model":"doubao-seed-tts-1-0","text":"hi","speed":3}`,
+		`{"model":"doubao-seed-tts-1-0","text":"hi","format":"flac"}`,
+	}
+	for _, body := range bodies {
+		c := testutil.NewTaskContext("/v1/videos", body)
+		info := &relaycommon.RelayInfo{TaskRelayInfo: &relaycommon.TaskRelayInfo{}}
+		if taskErr := (&TaskAdaptor{}).ValidateRequestAndSetAction(c, info); taskErr == nil {
+			t.Errorf("expected validation error for %s", body)
+		}
+	}
+}
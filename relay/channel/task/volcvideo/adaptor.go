@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -99,11 +101,24 @@ type fetchResponse struct {
 	} `json:"error,omitempty"`
 }
 
+// listResponse 批量查询任务的响应结构
+type listResponse struct {
+	Items []json.RawMessage `json:"items"`
+	Total int               `json:"total"`
+}
+
 // volcVideoRequest 用于解析客户端请求的扩展结构
 type volcVideoRequest struct {
 	relaycommon.TaskSubmitReq
+	params volcVideoParams
+
+	// 首尾帧生视频支持
+	Images []volcVideoImage `json:"images,omitempty"`
+}
 
-	// 视频格式参数（支持直接传递或通过 metadata 传递）
+// volcVideoParams 火山视频扩展参数（支持直接传递或通过 metadata 传递）
+type volcVideoParams struct {
+	// 视频格式参数
 	Resolution string `json:"resolution,omitempty"`
 	Ratio      string `json:"ratio,omitempty"`
 	Duration   *int   `json:"duration,omitempty"`
@@ -125,18 +140,57 @@ type volcVideoRequest struct {
 	// 回调和尾帧
 	CallbackURL     string `json:"callback_url,omitempty"`
 	ReturnLastFrame *bool  `json:"return_last_frame,omitempty"`
+}
 
-	// 首尾帧生视频支持
-	Images []struct {
-		URL  string `json:"url"`
-		Role string `json:"role,omitempty"` // first_frame, last_frame
-	} `json:"images,omitempty"`
+// volcVideoImage 首尾帧图片，兼容 "url" 字符串与 {"url": "...", "role": "..."} 对象两种写法
+type volcVideoImage struct {
+	URL  string `json:"url"`
+	Role string `json:"role,omitempty"` // first_frame, last_frame
+}
+
+func (i *volcVideoImage) UnmarshalJSON(data []byte) error {
+	var url string
+	if json.Unmarshal(data, &url) == nil {
+		i.URL = url
+		return nil
+	}
+	type alias volcVideoImage
+	return json.Unmarshal(data, (*alias)(i))
+}
+
+// UnmarshalJSON 嵌入的 TaskSubmitReq 自定义了反序列化，会导致扩展参数被忽略，这里分别解析。
+// images 的元素可能为对象，与 TaskSubmitReq.Images 类型不同，解析基础字段前需移除
+func (r *volcVideoRequest) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := common.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if raw, ok := fields["images"]; ok {
+		if err := common.Unmarshal(raw, &r.Images); err != nil {
+			return err
+		}
+		delete(fields, "images")
+	}
+	base, err := common.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := common.Unmarshal(base, &r.TaskSubmitReq); err != nil {
+		return err
+	}
+	return common.Unmarshal(base, &r.params)
 }
 
 // ============================
 // Adaptor implementation
 // ============================
 
+// execution_expires_after 允许范围：1 分钟 ~ 1 周
+const (
+	minExecutionExpiresAfter = 60
+	maxExecutionExpiresAfter = 604800
+)
+
 type TaskAdaptor struct {
 	ChannelType int
 	baseURL     string
@@ -155,7 +209,7 @@ func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycom
 	// 解析扩展请求
 	req := volcVideoRequest{}
 	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
-		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		return service.TaskErrorWrapperLocal(err, "invalid_request", common.RequestBodyErrorStatusCode(err))
 	}
 	if strings.TrimSpace(req.Model) == "" {
 		return service.TaskErrorWrapperLocal(fmt.Errorf("model is required"), "invalid_request", http.StatusBadRequest)
@@ -164,6 +218,15 @@ func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycom
 		return service.TaskErrorWrapperLocal(fmt.Errorf("prompt or image is required"), "invalid_request", http.StatusBadRequest)
 	}
 
+	if expiresAfter := getIntPtrParam(req.params.ExecutionExpiresAfter, req.Metadata, "execution_expires_after"); expiresAfter != nil {
+		if *expiresAfter < minExecutionExpiresAfter || *expiresAfter > maxExecutionExpiresAfter {
+			return service.TaskErrorWrapperLocal(
+				fmt.Errorf("execution_expires_after must be between %d and %d seconds", minExecutionExpiresAfter, maxExecutionExpiresAfter),
+				"invalid_request", http.StatusBadRequest)
+		}
+		info.ExecutionExpiresAfterSec = int64(*expiresAfter)
+	}
+
 	c.Set("volc_video_request", req)
 	return nil
 }
@@ -236,11 +299,11 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 	if imgs, ok := req.Metadata["images"].([]any); ok {
 		for _, it := range imgs {
 			m, _ := it.(map[string]any)
-			u := fmt.Sprint(m["url"])
+			u, _ := m["url"].(string)
 			if u == "" {
 				continue
 			}
-			role := fmt.Sprint(m["role"])
+			role, _ := m["role"].(string)
 			if role == "" {
 				role = "first_frame"
 			}
@@ -254,54 +317,54 @@ func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayIn
 
 	// ========== 设置视频格式参数 ==========
 	// Resolution
-	body.Resolution = getStringParam(req.Resolution, req.Metadata, "resolution", "")
+	body.Resolution = getStringParam(req.params.Resolution, req.Metadata, "resolution", "")
 
 	// Ratio
-	body.Ratio = getStringParam(req.Ratio, req.Metadata, "ratio", "")
+	body.Ratio = getStringParam(req.params.Ratio, req.Metadata, "ratio", "")
 
 	// Duration
-	body.Duration = getIntPtrParam(req.Duration, req.Metadata, "duration")
+	body.Duration = getIntPtrParam(req.params.Duration, req.Metadata, "duration")
 
 	// Frames
-	body.Frames = getIntPtrParam(req.Frames, req.Metadata, "frames")
+	body.Frames = getIntPtrParam(req.params.Frames, req.Metadata, "frames")
 
 	// ========== 设置生成控制参数 ==========
 	// Seed
-	body.Seed = getIntPtrParam(req.Seed, req.Metadata, "seed")
+	body.Seed = getIntPtrParam(req.params.Seed, req.Metadata, "seed")
 
 	// CameraFixed
-	body.CameraFixed = getBoolPtrParam(req.CameraFixed, req.Metadata, "camera_fixed")
+	body.CameraFixed = getBoolPtrParam(req.params.CameraFixed, req.Metadata, "camera_fixed")
 
 	// Watermark - 默认 false（无水印）
-	watermark := getBoolPtrParam(req.Watermark, req.Metadata, "watermark")
+	watermark := getBoolPtrParam(req.params.Watermark, req.Metadata, "watermark")
 	if watermark == nil {
 		watermark = boolPtr(false) // 默认无水印
 	}
 	body.Watermark = watermark
 
 	// GenerateAudio - 默认 false（不生成音频）
-	generateAudio := getBoolPtrParam(req.GenerateAudio, req.Metadata, "generate_audio")
+	generateAudio := getBoolPtrParam(req.params.GenerateAudio, req.Metadata, "generate_audio")
 	if generateAudio == nil {
 		generateAudio = boolPtr(false) // 默认不生成音频
 	}
 	body.GenerateAudio = generateAudio
 
 	// Draft
-	body.Draft = getBoolPtrParam(req.Draft, req.Metadata, "draft")
+	body.Draft = getBoolPtrParam(req.params.Draft, req.Metadata, "draft")
 
 	// ========== 设置服务参数 ==========
 	// ServiceTier
-	body.ServiceTier = getStringParam(req.ServiceTier, req.Metadata, "service_tier", "")
+	body.ServiceTier = getStringParam(req.params.ServiceTier, req.Metadata, "service_tier", "")
 
 	// ExecutionExpiresAfter
-	body.ExecutionExpiresAfter = getIntPtrParam(req.ExecutionExpiresAfter, req.Metadata, "execution_expires_after")
+	body.ExecutionExpiresAfter = getIntPtrParam(req.params.ExecutionExpiresAfter, req.Metadata, "execution_expires_after")
 
 	// ========== 设置回调参数 ==========
 	// CallbackURL
-	body.CallbackURL = getStringParam(req.CallbackURL, req.Metadata, "callback_url", "")
+	body.CallbackURL = getStringParam(req.params.CallbackURL, req.Metadata, "callback_url", "")
 
 	// ReturnLastFrame
-	body.ReturnLastFrame = getBoolPtrParam(req.ReturnLastFrame, req.Metadata, "return_last_frame")
+	body.ReturnLastFrame = getBoolPtrParam(req.params.ReturnLastFrame, req.Metadata, "return_last_frame")
 
 	data, err := json.Marshal(body)
 	if err != nil {
@@ -315,7 +378,7 @@ func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, req
 }
 
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, _ *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 	}
@@ -358,6 +421,88 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy
 	return service.GetHttpClient().Do(req)
 }
 
+// FetchTaskBatch 通过任务列表接口一次查询多个任务的状态
+func (a *TaskAdaptor) FetchTaskBatch(baseUrl, key string, taskIds []string, proxy string) ([]*relaycommon.TaskInfo, error) {
+	if len(taskIds) == 0 {
+		return nil, nil
+	}
+	query := url.Values{}
+	query.Set("page_num", "1")
+	query.Set("page_size", strconv.Itoa(len(taskIds)))
+	for _, id := range taskIds {
+		query.Add("filter.task_ids", id)
+	}
+	uri := fmt.Sprintf("%s/api/v3/contents/generations/tasks?%s", baseUrl, query.Encode())
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("batch query failed with status %d: %s", resp.StatusCode, string(responseBody))
+	}
+
+	var lr listResponse
+	if err := json.Unmarshal(responseBody, &lr); err != nil {
+		return nil, err
+	}
+	results := make([]*relaycommon.TaskInfo, 0, len(lr.Items))
+	for _, item := range lr.Items {
+		var fr fetchResponse
+		if err := json.Unmarshal(item, &fr); err != nil {
+			return nil, err
+		}
+		res := parseFetchResponse(&fr)
+		res.Data = item
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// CancelTask 取消排队中的任务（火山仅支持取消 queued 状态的任务）
+func (a *TaskAdaptor) CancelTask(baseUrl, key, taskID string, proxy string) error {
+	if taskID == "" {
+		return fmt.Errorf("invalid task_id")
+	}
+	uri := fmt.Sprintf("%s/api/v3/contents/generations/tasks/%s", baseUrl, taskID)
+	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := service.ReadCompressedBody(resp)
+		return fmt.Errorf("cancel task failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
 func (a *TaskAdaptor) GetModelList() []string {
 	return []string{
 		// Seedance 1.0 pro
@@ -395,6 +540,11 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 	if err := json.Unmarshal(respBody, &fr); err != nil {
 		return nil, err
 	}
+	return parseFetchResponse(&fr), nil
+}
+
+// parseFetchResponse 将火山任务详情转换为通用任务信息
+func parseFetchResponse(fr *fetchResponse) *relaycommon.TaskInfo {
 	res := &relaycommon.TaskInfo{}
 	res.TaskID = fr.ID
 
@@ -403,7 +553,7 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 		res.Status = model.TaskStatusFailure
 		res.Progress = "100%"
 		res.Reason = fmt.Sprintf("%s: %s", fr.Error.Code, fr.Error.Message)
-		return res, nil
+		return res
 	}
 
 	// 检查是否有状态信息（正常任务响应）
@@ -411,7 +561,7 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 		res.Status = model.TaskStatusUnknown
 		res.Progress = "0%"
 		res.Reason = "未知响应格式"
-		return res, nil
+		return res
 	}
 
 	switch strings.ToLower(fr.Status) {
@@ -467,7 +617,7 @@ func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, e
 		res.Reason = fmt.Sprintf("未知状态: %s", fr.Status)
 	}
 
-	return res, nil
+	return res
 }
 
 // ========== 辅助函数 ==========
@@ -0,0 +1,190 @@
+package volcvideo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFetchTaskBatch(t *testing.T) {
+	service.InitHttpClient()
+
+	statuses := []string{"queued", "running", "succeeded", "failed", "expired"}
+	tasks := make(map[string]fetchResponse)
+	taskIds := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		id := fmt.Sprintf("cgt-%03d", i)
+		fr := fetchResponse{ID: id, Model: "doubao-seedance-1-0-pro-250528", Status: statuses[i%len(statuses)]}
+		if fr.Status == "succeeded" {
+			fr.Content.VideoURL = "https://example.com/" + id + ".mp4"
+		}
+		tasks[id] = fr
+		taskIds = append(taskIds, id)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		lr := listResponse{}
+		for _, id := range r.URL.Query()["filter.task_ids"] {
+			if fr, ok := tasks[id]; ok {
+				item, _ := json.Marshal(fr)
+				lr.Items = append(lr.Items, item)
+			}
+		}
+		lr.Total = len(lr.Items)
+		_ = json.NewEncoder(w).Encode(lr)
+	}))
+	defer server.Close()
+
+	a := &TaskAdaptor{}
+	const batchSize = 20
+	got := 0
+	for start := 0; start < len(taskIds); start += batchSize {
+		batch := taskIds[start:min(start+batchSize, len(taskIds))]
+		results, err := a.FetchTaskBatch(server.URL, "sk-test", batch, "")
+		if err != nil {
+			t.Fatalf("FetchTaskBatch returned error: %v", err)
+		}
+		if len(results) != len(batch) {
+			t.Fatalf("expected %d results, got %d", len(batch), len(results))
+		}
+		for _, res := range results {
+			want := parseFetchResponse(ptr(tasks[res.TaskID]))
+			if res.Status != want.Status || res.Url != want.Url {
+				t.Errorf("task %s: got status=%s url=%s, want status=%s url=%s", res.TaskID, res.Status, res.Url, want.Status, want.Url)
+			}
+			var data fetchResponse
+			if err := json.Unmarshal(res.Data, &data); err != nil || data.ID != res.TaskID {
+				t.Errorf("task %s: unexpected raw data %s", res.TaskID, res.Data)
+			}
+			got++
+		}
+	}
+	if got != len(taskIds) {
+		t.Fatalf("expected %d parsed tasks, got %d", len(taskIds), got)
+	}
+	if requests != 3 {
+		t.Fatalf("expected 3 upstream requests, got %d", requests)
+	}
+	if s := parseFetchResponse(ptr(tasks["cgt-002"])).Status; s != model.TaskStatusSuccess {
+		t.Fatalf("expected succeeded task to map to %s, got %s", model.TaskStatusSuccess, s)
+	}
+}
+
+func TestFetchTaskBatchUpstreamError(t *testing.T) {
+	service.InitHttpClient()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"code":"InternalServiceError"}}`))
+	}))
+	defer server.Close()
+
+	a := &TaskAdaptor{}
+	if _, err := a.FetchTaskBatch(server.URL, "sk-test", []string{"cgt-000"}, ""); err == nil {
+		t.Fatal("expected error for non-200 upstream response")
+	}
+}
+
+func ptr(fr fetchResponse) *fetchResponse {
+	return &fr
+}
+
+func TestDoRequestChannelConcurrencyLimit(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		inFlight.Add(-1)
+		_, _ = w.Write([]byte(`{"id":"cgt-000"}`))
+	}))
+	defer server.Close()
+
+	doRequest := func(channelId int) error {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/video/generations", nil)
+		info := &relaycommon.RelayInfo{
+			TaskRelayInfo: &relaycommon.TaskRelayInfo{},
+			ChannelMeta: &relaycommon.ChannelMeta{
+				ChannelId:        channelId,
+				ChannelBaseUrl:   server.URL,
+				ApiKey:           "sk-test",
+				ConcurrencyLimit: 1,
+			},
+		}
+		a := &TaskAdaptor{}
+		a.Init(info)
+		resp, err := a.DoRequest(c, info, strings.NewReader(`{}`))
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		return nil
+	}
+	run := func(channelId int) []error {
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				errs[i] = doRequest(channelId)
+			}(i)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// 等待时间足够时两个请求依次完成
+	relaycommon.DefaultChannelConcurrencyGuard.WaitTimeout = time.Second
+	for _, err := range run(9001) {
+		if err != nil {
+			t.Fatalf("expected both requests to succeed, got %v", err)
+		}
+	}
+	if maxInFlight.Load() != 1 {
+		t.Fatalf("expected at most 1 concurrent upstream request, got %d", maxInFlight.Load())
+	}
+
+	// 等待超时的请求返回 ErrChannelConcurrencyLimit
+	relaycommon.DefaultChannelConcurrencyGuard.WaitTimeout = 20 * time.Millisecond
+	limited := 0
+	for _, err := range run(9002) {
+		if errors.Is(err, relaycommon.ErrChannelConcurrencyLimit) {
+			limited++
+		} else if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if limited != 1 {
+		t.Fatalf("expected exactly 1 request to hit the concurrency limit, got %d", limited)
+	}
+}
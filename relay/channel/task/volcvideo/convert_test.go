@@ -0,0 +1,297 @@
+package volcvideo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBuildRequestBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		request  string
+		upstream string
+		want     string
+	}{
+		{
+			name:    "text to video with defaults",
+			request: `{"model":"doubao-seedance-1-0-pro","prompt":"a cat"}`,
+			want:    `{"model":"doubao-seedance-1-0-pro","content":[{"type":"text","text":"a cat"}],"watermark":false,"generate_audio":false}`,
+		},
+		{
+			name:     "mapped model and direct params",
+			request:  `{"model":"seedance","prompt":"a cat","resolution":"1080p","ratio":"16:9","duration":5,"seed":42,"camera_fixed":true,"watermark":true,"generate_audio":true,"draft":true,"service_tier":"flex","execution_expires_after":3600,"callback_url":"https://example.com/cb","return_last_frame":true}`,
+			upstream: "doubao-seedance-1-5-pro-251215",
+			want:     `{"model":"doubao-seedance-1-5-pro-251215","content":[{"type":"text","text":"a cat"}],"resolution":"1080p","ratio":"16:9","duration":5,"seed":42,"camera_fixed":true,"watermark":true,"generate_audio":true,"draft":true,"service_tier":"flex","execution_expires_after":3600,"callback_url":"https://example.com/cb","return_last_frame":true}`,
+		},
+		{
+			name:    "params from metadata",
+			request: `{"model":"seedance","prompt":"a cat","metadata":{"resolution":"720p","ratio":"9:16","duration":10,"frames":121,"seed":-1,"camera_fixed":false,"watermark":true,"generate_audio":true,"service_tier":"default","callback_url":"https://example.com/cb"}}`,
+			want:    `{"model":"seedance","content":[{"type":"text","text":"a cat"}],"resolution":"720p","ratio":"9:16","duration":10,"frames":121,"seed":-1,"camera_fixed":false,"watermark":true,"generate_audio":true,"service_tier":"default","callback_url":"https://example.com/cb"}`,
+		},
+		{
+			name:    "first frame image with metadata role",
+			request: `{"model":"seedance","prompt":"a cat","image":"https://example.com/a.png","metadata":{"role":"last_frame"}}`,
+			want:    `{"model":"seedance","content":[{"type":"text","text":"a cat"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"},"role":"last_frame"}],"watermark":false,"generate_audio":false}`,
+		},
+		{
+			name:    "first and last frame images",
+			request: `{"model":"seedance","images":[{"url":"https://example.com/a.png"},{"url":""},{"url":"https://example.com/b.png","role":"last_frame"}]}`,
+			want:    `{"model":"seedance","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"},"role":"first_frame"},{"type":"image_url","image_url":{"url":"https://example.com/b.png"},"role":"last_frame"}],"watermark":false,"generate_audio":false}`,
+		},
+		{
+			name:    "images from metadata",
+			request: `{"model":"seedance","prompt":"a cat","metadata":{"images":[{"url":"https://example.com/a.png","role":"first_frame"},{"url":"https://example.com/b.png","role":"last_frame"},{"role":"last_frame"},{"url":"https://example.com/c.png"}]}}`,
+			want:    `{"model":"seedance","content":[{"type":"text","text":"a cat"},{"type":"image_url","image_url":{"url":"https://example.com/a.png"},"role":"first_frame"},{"type":"image_url","image_url":{"url":"https://example.com/b.png"},"role":"last_frame"},{"type":"image_url","image_url":{"url":"https://example.com/c.png"},"role":"first_frame"}],"watermark":false,"generate_audio":false}`,
+		},
+		{
+			name:    "image url strings",
+			request: `{"model":"seedance","images":["https://example.com/a.png"]}`,
+			want:    `{"model":"seedance","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"},"role":"first_frame"}],"watermark":false,"generate_audio":false}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testutil.NewTaskContext("/v1/video/generations", tt.request)
+			info := &relaycommon.RelayInfo{
+				TaskRelayInfo: &relaycommon.TaskRelayInfo{},
+				ChannelMeta:   &relaycommon.ChannelMeta{UpstreamModelName: tt.upstream},
+			}
+			a := &TaskAdaptor{}
+			if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+				t.Fatalf("ValidateRequestAndSetAction returned error: %s", taskErr.Message)
+			}
+			reader, err := a.BuildRequestBody(c, info)
+			if err != nil {
+				t.Fatalf("BuildRequestBody returned error: %v", err)
+			}
+			data, _ := io.ReadAll(reader)
+			var got, want map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("invalid request body: %v", err)
+			}
+			_ = json.Unmarshal([]byte(tt.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("unexpected request body:\n got %s\nwant %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRequestAndSetActionErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		request string
+	}{
+		{name: "invalid json", request: `{`},
+		{name: "missing model", request: `{"prompt":"a cat"}`},
+		{name: "missing prompt and image", request: `{"model":"seedance"}`},
+		{name: "expires too short", request: `{"model":"seedance","prompt":"a cat","execution_expires_after":10}`},
+		{name: "expires too long in metadata", request: `{"model":"seedance","prompt":"a cat","metadata":{"execution_expires_after":700000}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testutil.NewTaskContext("/v1/video/generations", tt.request)
+			info := testutil.NewTaskRelayInfo()
+			a := &TaskAdaptor{}
+			if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr == nil {
+				t.Fatal("expected validation error")
+			}
+		})
+	}
+
+	a := &TaskAdaptor{}
+	c := testutil.NewTaskContext("/v1/video/generations", `{}`)
+	if _, err := a.BuildRequestBody(c, &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{}}); err == nil {
+		t.Fatal("expected error when request was not validated")
+	}
+}
+
+func TestParseTaskResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   string
+		wantProgress string
+		wantUrl      string
+		wantReason   string
+		wantTokens   int
+	}{
+		{
+			name:         "api error",
+			body:         `{"error":{"code":"InvalidParameter","message":"bad ratio"}}`,
+			wantStatus:   model.TaskStatusFailure,
+			wantProgress: "100%",
+			wantReason:   "InvalidParameter: bad ratio",
+		},
+		{
+			name:         "empty status",
+			body:         `{"id":"cgt-1"}`,
+			wantStatus:   model.TaskStatusUnknown,
+			wantProgress: "0%",
+			wantReason:   "未知响应格式",
+		},
+		{
+			name:         "queued",
+			body:         `{"id":"cgt-1","status":"queued"}`,
+			wantStatus:   model.TaskStatusQueued,
+			wantProgress: "20%",
+		},
+		{
+			name:         "running",
+			body:         `{"id":"cgt-1","status":"Running"}`,
+			wantStatus:   model.TaskStatusInProgress,
+			wantProgress: "60%",
+		},
+		{
+			name:         "succeeded",
+			body:         `{"id":"cgt-1","status":"succeeded","content":{"video_url":"https://example.com/v.mp4"},"usage":{"completion_tokens":1000,"total_tokens":1000}}`,
+			wantStatus:   model.TaskStatusSuccess,
+			wantProgress: "100%",
+			wantUrl:      "https://example.com/v.mp4",
+			wantTokens:   1000,
+		},
+		{
+			name:         "failed with message",
+			body:         `{"id":"cgt-1","status":"failed","error":{"message":"content risk"}}`,
+			wantStatus:   model.TaskStatusFailure,
+			wantProgress: "100%",
+			wantReason:   "content risk",
+		},
+		{
+			name:         "failed without message",
+			body:         `{"id":"cgt-1","status":"failed"}`,
+			wantStatus:   model.TaskStatusFailure,
+			wantProgress: "100%",
+			wantReason:   "任务执行失败",
+		},
+		{
+			name:         "expired",
+			body:         `{"id":"cgt-1","status":"expired"}`,
+			wantStatus:   model.TaskStatusFailure,
+			wantProgress: "100%",
+			wantReason:   "任务超时",
+		},
+		{
+			name:         "unknown status",
+			body:         `{"id":"cgt-1","status":"cancelled"}`,
+			wantStatus:   model.TaskStatusUnknown,
+			wantProgress: "0%",
+			wantReason:   "未知状态: cancelled",
+		},
+	}
+	a := &TaskAdaptor{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := a.ParseTaskResult([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseTaskResult returned error: %v", err)
+			}
+			if info.Status != tt.wantStatus || info.Progress != tt.wantProgress || info.Url != tt.wantUrl || info.TotalTokens != tt.wantTokens {
+				t.Fatalf("got status=%s progress=%s url=%q tokens=%d", info.Status, info.Progress, info.Url, info.TotalTokens)
+			}
+			// 成功时 Reason 为任务详情 JSON
+			if tt.wantStatus == model.TaskStatusSuccess {
+				var detail map[string]any
+				if err := json.Unmarshal([]byte(info.Reason), &detail); err != nil || detail["id"] != "cgt-1" {
+					t.Fatalf("unexpected success detail: %s", info.Reason)
+				}
+			} else if info.Reason != tt.wantReason {
+				t.Fatalf("expected reason %q, got %q", tt.wantReason, info.Reason)
+			}
+		})
+	}
+
+	if _, err := a.ParseTaskResult([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid body")
+	}
+}
+
+func TestSubmitAndFetchTask(t *testing.T) {
+	service.InitHttpClient()
+	gin.SetMode(gin.TestMode)
+
+	var submitted map[string]any
+	var fetchPath string
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{
+		{
+			Body: `{"id":"cgt-1"}`,
+			OnRequest: func(r *http.Request, body []byte) {
+				_ = json.Unmarshal(body, &submitted)
+			},
+		},
+		{
+			Body: `{"id":"cgt-1","status":"succeeded","content":{"video_url":"https://example.com/v.mp4"}}`,
+			OnRequest: func(r *http.Request, _ []byte) {
+				fetchPath = r.URL.Path
+			},
+		},
+		{Body: `{"error":{"code":"InvalidParameter","message":"bad ratio"}}`},
+		{Body: `{}`},
+	})
+
+	c := testutil.NewTaskContext("/v1/video/generations", `{"model":"seedance","prompt":"a cat"}`)
+	info := &relaycommon.RelayInfo{
+		TaskRelayInfo: &relaycommon.TaskRelayInfo{},
+		ChannelMeta:   &relaycommon.ChannelMeta{ChannelBaseUrl: server.URL, ApiKey: "sk-test"},
+	}
+	a := &TaskAdaptor{}
+	a.Init(info)
+	if taskErr := a.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		t.Fatalf("ValidateRequestAndSetAction returned error: %s", taskErr.Message)
+	}
+	body, err := a.BuildRequestBody(c, info)
+	if err != nil {
+		t.Fatalf("BuildRequestBody returned error: %v", err)
+	}
+	resp, err := a.DoRequest(c, info, body)
+	if err != nil {
+		t.Fatalf("DoRequest returned error: %v", err)
+	}
+	taskID, _, taskErr := a.DoResponse(c, resp, info)
+	if taskErr != nil || taskID != "cgt-1" {
+		t.Fatalf("unexpected submit result: id=%q err=%v", taskID, taskErr)
+	}
+	if submitted["model"] != "seedance" {
+		t.Fatalf("unexpected submitted body: %v", submitted)
+	}
+
+	resp, err = a.FetchTask(server.URL, "sk-test", map[string]any{"task_id": taskID}, "")
+	if err != nil {
+		t.Fatalf("FetchTask returned error: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	result, err := a.ParseTaskResult(data)
+	if err != nil || result.Status != model.TaskStatusSuccess || result.Url != "https://example.com/v.mp4" {
+		t.Fatalf("unexpected fetch result: %+v, err %v", result, err)
+	}
+	if fetchPath != "/api/v3/contents/generations/tasks/cgt-1" {
+		t.Fatalf("unexpected fetch path %s", fetchPath)
+	}
+
+	// 上游返回错误或缺少任务 ID 时提交失败
+	for _, code := range []string{"InvalidParameter", "invalid_response"} {
+		resp, err = a.DoRequest(testutil.NewTaskContext("/v1/video/generations", `{}`), info, strings.NewReader(`{}`))
+		if err != nil {
+			t.Fatalf("DoRequest returned error: %v", err)
+		}
+		if _, _, taskErr = a.DoResponse(c, resp, info); taskErr == nil || taskErr.Code != code {
+			t.Fatalf("expected task error %s, got %v", code, taskErr)
+		}
+	}
+}
@@ -222,7 +222,7 @@ func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, req
 }
 
 func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := service.ReadCompressedBody(resp)
 	if err != nil {
 		return "", nil, service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
 	}
@@ -292,7 +292,7 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy
 	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
 		// Transient errors (429 rate limit, 5xx server errors): return synthetic
 		// "pending" so the task stays in its current state and will be retried.
-		respBody, _ := io.ReadAll(resp.Body)
+		respBody, _ := service.ReadCompressedBody(resp)
 		resp.Body.Close()
 		common.SysLog(fmt.Sprintf("xAI video poll transient HTTP %d for task %s: %s", resp.StatusCode, taskID, string(respBody)))
 		pending := `{"status":"pending"}`
@@ -302,7 +302,7 @@ func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy
 		}, nil
 	}
 	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
+		respBody, _ := service.ReadCompressedBody(resp)
 		resp.Body.Close()
 		return nil, fmt.Errorf("xAI video poll returned HTTP %d: %s", resp.StatusCode, string(respBody))
 	}
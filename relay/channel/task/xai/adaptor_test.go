@@ -0,0 +1,141 @@
+package xai
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	"github.com/QuantumNous/new-api/service"
+)
+
+func TestParseTaskResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   string
+		wantUrl      string
+		wantReason   string
+		wantDuration float64
+		wantCost     int
+	}{
+		{
+			name:       "error response",
+			body:       `{"code":"invalid_argument","error":"prompt violates policy"}`,
+			wantStatus: model.TaskStatusFailure,
+			wantReason: "prompt violates policy",
+		},
+		{
+			name:       "pending",
+			body:       `{"status":"pending"}`,
+			wantStatus: model.TaskStatusQueued,
+		},
+		{
+			name:         "done with usage",
+			body:         `{"status":"done","video":{"url":"https://vidgen.x.ai/out.mp4","duration":6},"usage":{"cost_in_usd_ticks":4800000000}}`,
+			wantStatus:   model.TaskStatusSuccess,
+			wantUrl:      "https://vidgen.x.ai/out.mp4",
+			wantDuration: 6,
+			wantCost:     int(0.48 * common.QuotaPerUnit),
+		},
+		{
+			name:       "done without video",
+			body:       `{"status":"done"}`,
+			wantStatus: model.TaskStatusSuccess,
+		},
+		{
+			name:       "expired",
+			body:       `{"status":"expired"}`,
+			wantStatus: model.TaskStatusFailure,
+			wantReason: "request expired",
+		},
+		{
+			name:         "unknown status with video",
+			body:         `{"status":"completed","video":{"url":"https://vidgen.x.ai/out.mp4","duration":8},"usage":{"cost_in_usd_ticks":1000000000}}`,
+			wantStatus:   model.TaskStatusSuccess,
+			wantUrl:      "https://vidgen.x.ai/out.mp4",
+			wantDuration: 8,
+			wantCost:     int(0.1 * common.QuotaPerUnit),
+		},
+		{
+			name:       "error status with message",
+			body:       `{"status":"error","error":"generation failed"}`,
+			wantStatus: model.TaskStatusFailure,
+			wantReason: "generation failed",
+		},
+		{
+			name:       "error status without message",
+			body:       `{"status":"error"}`,
+			wantStatus: model.TaskStatusFailure,
+			wantReason: "unexpected status: error",
+		},
+		{
+			name:       "empty status",
+			body:       `{}`,
+			wantStatus: model.TaskStatusQueued,
+		},
+	}
+	a := &TaskAdaptor{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := a.ParseTaskResult([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseTaskResult returned error: %v", err)
+			}
+			if info.Status != tt.wantStatus || info.Url != tt.wantUrl || info.Reason != tt.wantReason {
+				t.Fatalf("got status=%s url=%q reason=%q, want status=%s url=%q reason=%q",
+					info.Status, info.Url, info.Reason, tt.wantStatus, tt.wantUrl, tt.wantReason)
+			}
+			if info.Duration != tt.wantDuration || info.CostQuota != tt.wantCost {
+				t.Fatalf("got duration=%v cost=%d, want duration=%v cost=%d", info.Duration, info.CostQuota, tt.wantDuration, tt.wantCost)
+			}
+		})
+	}
+
+	if _, err := a.ParseTaskResult([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for invalid body")
+	}
+}
+
+func TestFetchAndParseTaskResult(t *testing.T) {
+	service.InitHttpClient()
+
+	var paths, auths []string
+	record := func(r *http.Request, _ []byte) {
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+	}
+	server := testutil.MockUpstreamServer(t, []testutil.MockResponse{
+		{Body: `{"status":"pending"}`, OnRequest: record},
+		{Body: `{"status":"done","video":{"url":"https://vidgen.x.ai/out.mp4","duration":6}}`, OnRequest: record},
+	})
+
+	a := &TaskAdaptor{}
+	want := []string{model.TaskStatusQueued, model.TaskStatusSuccess}
+	for i, status := range want {
+		resp, err := a.FetchTask(server.URL+"/v1/", "xai-key-1\nxai-key-2", map[string]any{"task_id": "req-1"}, "")
+		if err != nil {
+			t.Fatalf("FetchTask returned error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		info, err := a.ParseTaskResult(body)
+		if err != nil {
+			t.Fatalf("ParseTaskResult returned error: %v", err)
+		}
+		if info.Status != status {
+			t.Fatalf("poll %d: expected status %s, got %s", i, status, info.Status)
+		}
+	}
+	for i := range paths {
+		if paths[i] != "/v1/videos/req-1" || auths[i] != "Bearer xai-key-1" {
+			t.Fatalf("unexpected upstream request: path=%s auth=%s", paths[i], auths[i])
+		}
+	}
+
+	if _, err := a.FetchTask(server.URL, "xai-key", map[string]any{}, ""); err == nil {
+		t.Fatal("expected error for missing task_id")
+	}
+}
@@ -0,0 +1,54 @@
+package testutil
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// MockResponse 模拟上游的一次响应，OnRequest 可用于在测试中检查收到的请求
+type MockResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+	OnRequest  func(r *http.Request, body []byte)
+}
+
+// MockUpstreamServer 启动一个按顺序循环返回预设响应的测试服务器，测试结束时自动关闭
+func MockUpstreamServer(t testing.TB, responses []MockResponse) *httptest.Server {
+	t.Helper()
+	if len(responses) == 0 {
+		t.Fatal("MockUpstreamServer requires at least one response")
+	}
+	var mu sync.Mutex
+	next := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		resp := responses[next%len(responses)]
+		next++
+		mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		if resp.OnRequest != nil {
+			resp.OnRequest(r, body)
+		}
+		for k, values := range resp.Header {
+			for _, v := range values {
+				w.Header().Add(k, v)
+			}
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		status := resp.StatusCode
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, resp.Body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
@@ -0,0 +1,25 @@
+package testutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewTaskContext 构造向 path 提交 JSON 请求体的测试上下文，用于任务适配器的校验与请求构建测试
+func NewTaskContext(path, body string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c
+}
+
+// NewTaskRelayInfo 返回带有空 TaskRelayInfo 与 ChannelMeta 的 RelayInfo，测试按需设置渠道字段
+func NewTaskRelayInfo() *relaycommon.RelayInfo {
+	return &relaycommon.RelayInfo{TaskRelayInfo: &relaycommon.TaskRelayInfo{}, ChannelMeta: &relaycommon.ChannelMeta{}}
+}
@@ -0,0 +1,89 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// ResponseTransformer 在上游响应返回给客户端前改写响应体
+type ResponseTransformer interface {
+	TransformResponse(body []byte, info *relaycommon.RelayInfo) ([]byte, error)
+}
+
+// JSONPathRemapper 按 JSON 路径复制或移动字段，如 $.result.video_url → $.url
+type JSONPathRemapper struct {
+	rules []remapRule
+}
+
+type remapRule struct {
+	from string
+	to   string
+	move bool
+}
+
+var arrayIndexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// NewFromSetting 根据渠道的 response_transform 配置创建转换器，未配置规则时返回 nil
+func NewFromSetting(setting *dto.ResponseTransform) (ResponseTransformer, error) {
+	if setting == nil || len(setting.Rules) == 0 {
+		return nil, nil
+	}
+	remapper := &JSONPathRemapper{rules: make([]remapRule, 0, len(setting.Rules))}
+	for _, rule := range setting.Rules {
+		from, err := toGJSONPath(rule.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := toGJSONPath(rule.To)
+		if err != nil {
+			return nil, err
+		}
+		remapper.rules = append(remapper.rules, remapRule{from: from, to: to, move: rule.Move})
+	}
+	return remapper, nil
+}
+
+// toGJSONPath 将 $.a.b[0].c 形式的路径转换为 gjson/sjson 使用的 a.b.0.c
+func toGJSONPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if !strings.HasPrefix(path, "$.") || len(path) == 2 {
+		return "", fmt.Errorf("invalid response transform path %q, expected format like $.result.url", path)
+	}
+	converted := arrayIndexPattern.ReplaceAllString(path[2:], ".$1")
+	if strings.ContainsAny(converted, "*?#|@") || strings.Contains(converted, "..") {
+		return "", fmt.Errorf("unsupported response transform path %q", path)
+	}
+	return converted, nil
+}
+
+// TransformResponse 依次执行映射规则，源路径不存在的规则会被跳过；响应体不是 JSON 时原样返回
+func (r *JSONPathRemapper) TransformResponse(body []byte, _ *relaycommon.RelayInfo) ([]byte, error) {
+	if !gjson.ValidBytes(body) {
+		return body, nil
+	}
+	var err error
+	for _, rule := range r.rules {
+		value := gjson.GetBytes(body, rule.from)
+		if !value.Exists() {
+			continue
+		}
+		body, err = sjson.SetRawBytes(body, rule.to, []byte(value.Raw))
+		if err != nil {
+			return nil, fmt.Errorf("set %s failed: %w", rule.to, err)
+		}
+		if rule.move && rule.from != rule.to {
+			body, err = sjson.DeleteBytes(body, rule.from)
+			if err != nil {
+				return nil, fmt.Errorf("delete %s failed: %w", rule.from, err)
+			}
+		}
+	}
+	return body, nil
+}
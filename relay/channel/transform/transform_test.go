@@ -0,0 +1,43 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestJSONPathRemapper(t *testing.T) {
+	transformer, err := NewFromSetting(&dto.ResponseTransform{Rules: []dto.ResponseTransformRule{
+		{From: "$.result.video_url", To: "$.url"},
+		{From: "$.result.items[1].id", To: "$.second_id", Move: true},
+		{From: "$.missing", To: "$.should_not_exist"},
+	}})
+	if err != nil {
+		t.Fatalf("NewFromSetting: %v", err)
+	}
+	body := []byte(`{"result":{"video_url":"https://example.com/v.mp4","items":[{"id":"a"},{"id":"b"}]}}`)
+	got, err := transformer.TransformResponse(body, nil)
+	if err != nil {
+		t.Fatalf("TransformResponse: %v", err)
+	}
+	want := `{"result":{"video_url":"https://example.com/v.mp4","items":[{"id":"a"},{}]},"url":"https://example.com/v.mp4","second_id":"b"}`
+	if string(got) != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+
+	// 非 JSON 响应原样返回
+	if got, _ := transformer.TransformResponse([]byte("not json"), nil); string(got) != "not json" {
+		t.Errorf("non-json body changed: %s", got)
+	}
+}
+
+func TestNewFromSetting(t *testing.T) {
+	if transformer, err := NewFromSetting(nil); transformer != nil || err != nil {
+		t.Errorf("nil setting = %v, %v", transformer, err)
+	}
+	for _, path := range []string{"result.url", "$.", "$.data[*].url", "$.a..b"} {
+		if _, err := NewFromSetting(&dto.ResponseTransform{Rules: []dto.ResponseTransformRule{{From: path, To: "$.url"}}}); err == nil {
+			t.Errorf("expected error for path %q", path)
+		}
+	}
+}
@@ -51,6 +51,8 @@ const anthropicVersion = "vertex-2023-10-16"
 type Adaptor struct {
 	RequestMode        int
 	AccountCredentials Credentials
+	// 输入包含图片时使用多模态 embedding，记录输出与 instance 的对应关系
+	multimodalEmbedding *multimodalEmbeddingPlan
 }
 
 func (a *Adaptor) ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error) {
@@ -360,6 +362,9 @@ func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dt
 }
 
 func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
+	if hasEmbeddingImageInput(request.Input) {
+		return a.convertMultimodalEmbeddingRequest(c, request)
+	}
 	geminiAdaptor := gemini.Adaptor{}
 	return geminiAdaptor.ConvertEmbeddingRequest(c, info, request)
 }
@@ -370,7 +375,7 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
-	if a.RequestMode == RequestModeGemini && strings.Contains(c.Request.URL.Path, "embed") {
+	if a.RequestMode == RequestModeGemini && strings.Contains(c.Request.URL.Path, "embed") && a.multimodalEmbedding == nil {
 		bodyBytes, err := io.ReadAll(requestBody)
 		if err != nil {
 			return nil, err
@@ -463,6 +468,9 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 				if strings.HasPrefix(info.UpstreamModelName, "imagen") {
 					return gemini.GeminiImageHandler(c, info, resp)
 				}
+				if a.multimodalEmbedding != nil {
+					return vertexMultimodalEmbeddingHandler(c, resp, info, a.multimodalEmbedding)
+				}
 				return gemini.GeminiChatHandler(c, info, resp)
 			}
 		case RequestModeLlama:
@@ -492,3 +500,7 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
@@ -35,3 +35,35 @@ func copyRequest(req *dto.ClaudeRequest, version string) *VertexAIClaudeRequest
 		Thinking:         req.Thinking,
 	}
 }
+
+// VertexMultimodalEmbeddingImage 图片可以是 base64 数据或 GCS 地址
+type VertexMultimodalEmbeddingImage struct {
+	BytesBase64Encoded string `json:"bytesBase64Encoded,omitempty"`
+	GcsUri             string `json:"gcsUri,omitempty"`
+	MimeType           string `json:"mimeType,omitempty"`
+}
+
+// VertexMultimodalEmbeddingInstance 每个 instance 最多包含一段文本和一张图片
+type VertexMultimodalEmbeddingInstance struct {
+	Text  string                          `json:"text,omitempty"`
+	Image *VertexMultimodalEmbeddingImage `json:"image,omitempty"`
+}
+
+type VertexMultimodalEmbeddingParameters struct {
+	Dimension int `json:"dimension,omitempty"`
+}
+
+type VertexMultimodalEmbeddingRequest struct {
+	Instances  []VertexMultimodalEmbeddingInstance  `json:"instances"`
+	Parameters *VertexMultimodalEmbeddingParameters `json:"parameters,omitempty"`
+}
+
+type VertexMultimodalEmbeddingResponse struct {
+	Predictions []struct {
+		TextEmbedding  []float64 `json:"textEmbedding"`
+		ImageEmbedding []float64 `json:"imageEmbedding"`
+	} `json:"predictions"`
+	Metadata struct {
+		BillableCharacterCount int `json:"billableCharacterCount"`
+	} `json:"metadata"`
+}
@@ -0,0 +1,79 @@
+package vertex
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/QuantumNous/new-api/service"
+)
+
+// UploadToGCS 使用服务账号凭据以流式方式将 body 上传到 GCS，返回对象的 gs:// URI
+func UploadToGCS(creds Credentials, proxy, bucket, object, contentType string, body io.Reader) (string, error) {
+	token, err := AcquireAccessToken(creds, proxy)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire access token: %w", err)
+	}
+
+	uploadURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?uploadType=media&name=%s",
+		url.PathEscape(bucket), url.QueryEscape(object))
+	req, err := http.NewRequest(http.MethodPost, uploadURL, body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	if creds.ProjectID != "" {
+		req.Header.Set("x-goog-user-project", creds.ProjectID)
+	}
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return "", fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("gcs upload failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return fmt.Sprintf("gs://%s/%s", bucket, object), nil
+}
+
+// DeleteFromGCS 删除 GCS 对象，对象不存在时视为已删除
+func DeleteFromGCS(creds Credentials, proxy, bucket, object string) error {
+	token, err := AcquireAccessToken(creds, proxy)
+	if err != nil {
+		return fmt.Errorf("failed to acquire access token: %w", err)
+	}
+
+	deleteURL := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s",
+		url.PathEscape(bucket), url.PathEscape(object))
+	req, err := http.NewRequest(http.MethodDelete, deleteURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if creds.ProjectID != "" {
+		req.Header.Set("x-goog-user-project", creds.ProjectID)
+	}
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("gcs delete failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package vertex
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Vertex 多模态 embedding 按字符和图片计费，一张图片的价格相当于 500 个计费字符
+const multimodalEmbeddingImageTokens = 500
+
+// multimodalEmbeddingOutput 记录第 i 个输入对应的 instance 以及取文本还是图片向量
+type multimodalEmbeddingOutput struct {
+	instance int
+	image    bool
+}
+
+type multimodalEmbeddingPlan struct {
+	outputs   []multimodalEmbeddingOutput
+	textChars int
+	images    int
+}
+
+// hasEmbeddingImageInput 判断 embedding 输入中是否包含图片
+func hasEmbeddingImageInput(input any) bool {
+	items, ok := input.([]any)
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if _, ok := embeddingImageUrl(item); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddingImageUrl 支持 {"type":"image_url","image_url":{"url":"..."}} 与 {"type":"image_url","image_url":"..."}
+func embeddingImageUrl(item any) (string, bool) {
+	m, ok := item.(map[string]any)
+	if !ok || m["type"] != "image_url" {
+		return "", false
+	}
+	switch v := m["image_url"].(type) {
+	case string:
+		return v, v != ""
+	case map[string]any:
+		url, _ := v["url"].(string)
+		return url, url != ""
+	}
+	return "", false
+}
+
+func embeddingText(item any) (string, bool) {
+	switch v := item.(type) {
+	case string:
+		return v, true
+	case map[string]any:
+		if v["type"] != "text" {
+			return "", false
+		}
+		text, ok := v["text"].(string)
+		return text, ok
+	}
+	return "", false
+}
+
+func buildMultimodalEmbeddingImage(c *gin.Context, url string) (*VertexMultimodalEmbeddingImage, error) {
+	if strings.HasPrefix(url, "gs://") {
+		return &VertexMultimodalEmbeddingImage{GcsUri: url}, nil
+	}
+	if strings.HasPrefix(url, "data:") {
+		header, data, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !found {
+			return nil, errors.New("invalid image data url")
+		}
+		mimeType, _, _ := strings.Cut(header, ";")
+		return &VertexMultimodalEmbeddingImage{BytesBase64Encoded: data, MimeType: mimeType}, nil
+	}
+	fileData, err := service.GetFileBase64FromUrl(c, url, "formatting image for Vertex embedding")
+	if err != nil {
+		return nil, fmt.Errorf("get image from url failed: %w", err)
+	}
+	return &VertexMultimodalEmbeddingImage{BytesBase64Encoded: fileData.Base64Data, MimeType: fileData.MimeType}, nil
+}
+
+// convertMultimodalEmbeddingRequest 将包含图片的 embedding 输入转换为 Vertex 多模态请求，
+// 相邻的文本与图片合并到同一个 instance，输出顺序与输入保持一致
+func (a *Adaptor) convertMultimodalEmbeddingRequest(c *gin.Context, request dto.EmbeddingRequest) (*VertexMultimodalEmbeddingRequest, error) {
+	items, _ := request.Input.([]any)
+	plan := &multimodalEmbeddingPlan{}
+	vertexRequest := &VertexMultimodalEmbeddingRequest{}
+	current := -1
+	for i, item := range items {
+		if url, ok := embeddingImageUrl(item); ok {
+			image, err := buildMultimodalEmbeddingImage(c, url)
+			if err != nil {
+				return nil, err
+			}
+			if current < 0 || vertexRequest.Instances[current].Image != nil {
+				vertexRequest.Instances = append(vertexRequest.Instances, VertexMultimodalEmbeddingInstance{})
+				current = len(vertexRequest.Instances) - 1
+			}
+			vertexRequest.Instances[current].Image = image
+			plan.outputs = append(plan.outputs, multimodalEmbeddingOutput{instance: current, image: true})
+			plan.images++
+			continue
+		}
+		text, ok := embeddingText(item)
+		if !ok {
+			return nil, fmt.Errorf("unsupported embedding input at index %d", i)
+		}
+		if current < 0 || vertexRequest.Instances[current].Text != "" {
+			vertexRequest.Instances = append(vertexRequest.Instances, VertexMultimodalEmbeddingInstance{})
+			current = len(vertexRequest.Instances) - 1
+		}
+		vertexRequest.Instances[current].Text = text
+		plan.outputs = append(plan.outputs, multimodalEmbeddingOutput{instance: current})
+		plan.textChars += utf8.RuneCountInString(text)
+	}
+	if request.Dimensions > 0 {
+		vertexRequest.Parameters = &VertexMultimodalEmbeddingParameters{Dimension: request.Dimensions}
+	}
+	a.multimodalEmbedding = plan
+	return vertexRequest, nil
+}
+
+// vertexMultimodalEmbeddingHandler 将 Vertex 多模态 embedding 响应转换为 OpenAI 格式，
+// 按 billableCharacterCount 加图片数量计费
+func vertexMultimodalEmbeddingHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, plan *multimodalEmbeddingPlan) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	if common.DebugEnabled {
+		logger.LogDebug(c, "Vertex multimodal embedding response body: "+string(responseBody))
+	}
+
+	var vertexResponse VertexMultimodalEmbeddingResponse
+	if err := common.Unmarshal(responseBody, &vertexResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	openAIResponse := dto.OpenAIEmbeddingResponse{
+		Object: "list",
+		Data:   make([]dto.OpenAIEmbeddingResponseItem, 0, len(plan.outputs)),
+		Model:  info.UpstreamModelName,
+	}
+	for i, output := range plan.outputs {
+		if output.instance >= len(vertexResponse.Predictions) {
+			return nil, types.NewOpenAIError(fmt.Errorf("missing prediction for input %d", i), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		prediction := vertexResponse.Predictions[output.instance]
+		embedding := prediction.TextEmbedding
+		if output.image {
+			embedding = prediction.ImageEmbedding
+		}
+		openAIResponse.Data = append(openAIResponse.Data, dto.OpenAIEmbeddingResponseItem{
+			Object:    "embedding",
+			Embedding: embedding,
+			Index:     i,
+		})
+	}
+
+	textTokens := vertexResponse.Metadata.BillableCharacterCount
+	if textTokens == 0 {
+		textTokens = plan.textChars
+	}
+	imageTokens := plan.images * multimodalEmbeddingImageTokens
+	usage := &dto.Usage{
+		PromptTokens: textTokens + imageTokens,
+		TotalTokens:  textTokens + imageTokens,
+	}
+	usage.PromptTokensDetails.TextTokens = textTokens
+	usage.PromptTokensDetails.ImageTokens = imageTokens
+	openAIResponse.Usage = *usage
+
+	jsonResponse, err := common.Marshal(openAIResponse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	service.IOCopyBytesGracefully(c, resp, jsonResponse)
+	return usage, nil
+}
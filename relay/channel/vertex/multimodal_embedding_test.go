@@ -0,0 +1,86 @@
+package vertex
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMultimodalEmbedding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	var input any
+	if err := json.Unmarshal([]byte(`[
+		"a red car",
+		{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}},
+		{"type": "image_url", "image_url": "gs://bucket/car.jpg"}
+	]`), &input); err != nil {
+		t.Fatal(err)
+	}
+	request := dto.EmbeddingRequest{Model: "multimodalembedding@001", Input: input, Dimensions: 512}
+	if !hasEmbeddingImageInput(request.Input) {
+		t.Fatal("image input not detected")
+	}
+
+	a := &Adaptor{}
+	converted, err := a.ConvertEmbeddingRequest(c, &relaycommon.RelayInfo{}, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vertexRequest := converted.(*VertexMultimodalEmbeddingRequest)
+	if len(vertexRequest.Instances) != 2 {
+		t.Fatalf("instances = %d, want 2", len(vertexRequest.Instances))
+	}
+	first := vertexRequest.Instances[0]
+	if first.Text != "a red car" || first.Image == nil || first.Image.BytesBase64Encoded != "aGVsbG8=" || first.Image.MimeType != "image/png" {
+		t.Errorf("unexpected first instance: %+v", first)
+	}
+	if got := vertexRequest.Instances[1].Image; got == nil || got.GcsUri != "gs://bucket/car.jpg" {
+		t.Errorf("unexpected second instance image: %+v", got)
+	}
+	if vertexRequest.Parameters == nil || vertexRequest.Parameters.Dimension != 512 {
+		t.Errorf("dimension not forwarded: %+v", vertexRequest.Parameters)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body: io.NopCloser(strings.NewReader(`{"predictions":[
+			{"textEmbedding":[0.1],"imageEmbedding":[0.2]},
+			{"imageEmbedding":[0.3]}
+		],"metadata":{"billableCharacterCount":9}}`)),
+	}
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "multimodalembedding@001"}}
+	usage, apiErr := vertexMultimodalEmbeddingHandler(c, resp, info, a.multimodalEmbedding)
+	if apiErr != nil {
+		t.Fatal(apiErr)
+	}
+	if usage.PromptTokensDetails.TextTokens != 9 || usage.PromptTokensDetails.ImageTokens != 2*multimodalEmbeddingImageTokens || usage.PromptTokens != 9+2*multimodalEmbeddingImageTokens {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	var out dto.OpenAIEmbeddingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0.1, 0.2, 0.3}
+	if len(out.Data) != len(want) {
+		t.Fatalf("data = %d items, want %d", len(out.Data), len(want))
+	}
+	for i, item := range out.Data {
+		if item.Index != i || len(item.Embedding) != 1 || item.Embedding[0] != want[i] {
+			t.Errorf("data[%d] = %+v, want embedding %v", i, item, want[i])
+		}
+	}
+}
@@ -1,13 +1,11 @@
 package xai
 
 import (
-	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"strings"
 
-	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/relay/channel"
 	"github.com/QuantumNous/new-api/relay/channel/openai"
@@ -38,59 +36,6 @@ func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInf
 	return nil, errors.New("not available")
 }
 
-func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
-	xaiRequest := ImageRequest{
-		Model:          request.Model,
-		Prompt:         request.Prompt,
-		N:              int(request.N),
-		ResponseFormat: request.ResponseFormat,
-		Image:          request.Image,
-	}
-
-	// Re-read raw body to reliably extract xAI-specific fields.
-	// The Extra map populated via dto.ImageRequest.UnmarshalJSON may lose data
-	// during DeepCopy (copier IgnoreEmpty), so parsing from cached body is safer.
-	body, err := common.GetRequestBody(c)
-	if err == nil {
-		var raw struct {
-			AspectRatio string          `json:"aspect_ratio"`
-			Resolution  string          `json:"resolution"`
-			Images      json.RawMessage `json:"images"`
-		}
-		if json.Unmarshal(body, &raw) == nil {
-			if raw.AspectRatio != "" {
-				xaiRequest.AspectRatio = raw.AspectRatio
-			}
-			if raw.Resolution != "" {
-				xaiRequest.Resolution = raw.Resolution
-			}
-			if raw.Images != nil {
-				xaiRequest.Images = raw.Images
-			}
-		}
-	}
-
-	// Count input images for xAI per-image input billing (grok-imagine-image only)
-	if strings.HasPrefix(request.Model, "grok-imagine-image") {
-		inputImageCount := 0
-		if len(xaiRequest.Image) > 0 {
-			inputImageCount = 1
-		}
-		if xaiRequest.Images != nil {
-			var imageArr []json.RawMessage
-			if json.Unmarshal(xaiRequest.Images, &imageArr) == nil {
-				inputImageCount = len(imageArr)
-			}
-		}
-		if inputImageCount > 0 {
-			c.Set("xai_input_image_count", inputImageCount)
-			c.Set("xai_input_image_price", 0.002) // $0.002 per input image
-		}
-	}
-
-	return xaiRequest, nil
-}
-
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 }
 
@@ -100,6 +45,10 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
 	channel.SetupApiRequestHeader(info, c, req)
+	// multipart 图片编辑、变体请求已转换为 JSON
+	if info.RelayMode == constant.RelayModeImagesEdits || info.RelayMode == constant.RelayModeImagesVariations {
+		req.Set("Content-Type", "application/json")
+	}
 	req.Set("Authorization", "Bearer "+info.ApiKey)
 	return nil
 }
@@ -157,8 +106,8 @@ func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, request
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
 	switch info.RelayMode {
-	case constant.RelayModeImagesGenerations, constant.RelayModeImagesEdits:
-		usage, err = openai.OpenaiHandlerWithUsage(c, info, resp)
+	case constant.RelayModeImagesGenerations, constant.RelayModeImagesEdits, constant.RelayModeImagesVariations:
+		usage, err = xAIImageHandler(c, info, resp)
 	case constant.RelayModeResponses:
 		if info.IsStream {
 			usage, err = openai.OaiResponsesStreamHandler(c, info, resp)
@@ -182,3 +131,7 @@ func (a *Adaptor) GetModelList() []string {
 func (a *Adaptor) GetChannelName() string {
 	return ChannelName
 }
+
+func (a *Adaptor) IsAsyncImageGeneration() bool {
+	return false
+}
@@ -19,11 +19,25 @@ type ChatCompletionResponse struct {
 
 type ImageRequest struct {
 	Model          string          `json:"model"`
-	Prompt         string          `json:"prompt" binding:"required"`
+	Prompt         string          `json:"prompt,omitempty"`
 	N              int             `json:"n,omitempty"`
 	ResponseFormat string          `json:"response_format,omitempty"`
 	AspectRatio    string          `json:"aspect_ratio,omitempty"`
 	Resolution     string          `json:"resolution,omitempty"`
 	Image          json.RawMessage `json:"image,omitempty"`
 	Images         json.RawMessage `json:"images,omitempty"`
+	Mask           json.RawMessage `json:"mask,omitempty"`
+}
+
+// ImageResponse represents the response from XAI image generation API
+type ImageResponse struct {
+	Created int64               `json:"created"`
+	Data    []ImageResponseData `json:"data"`
+	Usage   *dto.Usage          `json:"usage,omitempty"`
+}
+
+type ImageResponseData struct {
+	Url           string `json:"url,omitempty"`
+	B64Json       string `json:"b64_json,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
 }
@@ -0,0 +1,191 @@
+package xai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImageVariations is the largest n accepted by the xAI image API.
+const maxImageVariations = 10
+
+// SupportsImageVariations xAI 没有变体接口，变体请求转换为以上传图片为输入的图片编辑
+func (a *Adaptor) SupportsImageVariations() bool {
+	return true
+}
+
+func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
+	if info.RelayMode == constant.RelayModeImagesVariations {
+		return convertImageVariationRequest(c, request)
+	}
+	if info.RelayMode == constant.RelayModeImagesEdits && isMultipartImageEdit(c) {
+		return convertImageEditRequest(c, request)
+	}
+	xaiRequest := ImageRequest{
+		Model:          request.Model,
+		Prompt:         request.Prompt,
+		N:              int(request.N),
+		ResponseFormat: request.ResponseFormat,
+		Image:          request.Image,
+	}
+
+	// Re-read raw body to reliably extract xAI-specific fields.
+	// The Extra map populated via dto.ImageRequest.UnmarshalJSON may lose data
+	// during DeepCopy (copier IgnoreEmpty), so parsing from cached body is safer.
+	body, err := common.GetRequestBody(c)
+	if err == nil {
+		var raw struct {
+			AspectRatio string          `json:"aspect_ratio"`
+			Resolution  string          `json:"resolution"`
+			Images      json.RawMessage `json:"images"`
+		}
+		if json.Unmarshal(body, &raw) == nil {
+			if raw.AspectRatio != "" {
+				xaiRequest.AspectRatio = raw.AspectRatio
+			}
+			if raw.Resolution != "" {
+				xaiRequest.Resolution = raw.Resolution
+			}
+			if raw.Images != nil {
+				xaiRequest.Images = raw.Images
+			}
+		}
+	}
+
+	// Count input images for xAI per-image input billing (grok-imagine-image only)
+	if strings.HasPrefix(request.Model, "grok-imagine-image") {
+		inputImageCount := 0
+		if len(xaiRequest.Image) > 0 {
+			inputImageCount = 1
+		}
+		if xaiRequest.Images != nil {
+			var imageArr []json.RawMessage
+			if json.Unmarshal(xaiRequest.Images, &imageArr) == nil {
+				inputImageCount = len(imageArr)
+			}
+		}
+		if inputImageCount > 0 {
+			c.Set("xai_input_image_count", inputImageCount)
+			c.Set("xai_input_image_price", 0.002) // $0.002 per input image
+		}
+	}
+
+	return xaiRequest, nil
+}
+
+// convertImageVariationRequest translates an OpenAI multipart image variation request (image, n, size, response_format)
+// into the JSON body accepted by the xAI variations API. The uploaded image is inlined as a base64 data URL.
+func convertImageVariationRequest(c *gin.Context, request dto.ImageRequest) (*ImageRequest, error) {
+	if request.N > maxImageVariations {
+		return nil, fmt.Errorf("n must be between 1 and %d", maxImageVariations)
+	}
+	if request.ResponseFormat != "" && request.ResponseFormat != "url" && request.ResponseFormat != "b64_json" {
+		return nil, errors.New("response_format must be url or b64_json")
+	}
+	mf := c.Request.MultipartForm
+	if mf == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, fmt.Errorf("failed to parse image variation form request: %w", err)
+		}
+		mf = c.Request.MultipartForm
+	}
+	imageFiles := imageEditFormFiles(mf)
+	if len(imageFiles) == 0 {
+		return nil, errors.New("image is required")
+	}
+	dataURL, err := fileHeaderToDataURL(imageFiles[0])
+	if err != nil {
+		return nil, err
+	}
+	image, err := common.Marshal(imageEditInput{Url: dataURL, Type: "image_url"})
+	if err != nil {
+		return nil, err
+	}
+	return &ImageRequest{
+		Model:          request.Model,
+		N:              int(request.N),
+		ResponseFormat: request.ResponseFormat,
+		AspectRatio:    sizeToAspectRatio(request.Size),
+		Image:          image,
+	}, nil
+}
+
+func responseXAI2OpenAIImage(response *ImageResponse, info *relaycommon.RelayInfo) *dto.ImageResponse {
+	imageResponse := dto.ImageResponse{
+		Created: response.Created,
+		Data:    make([]dto.ImageData, 0, len(response.Data)),
+	}
+	if imageResponse.Created == 0 {
+		imageResponse.Created = info.StartTime.Unix()
+	}
+	for _, data := range response.Data {
+		imageResponse.Data = append(imageResponse.Data, dto.ImageData{
+			Url:           data.Url,
+			B64Json:       data.B64Json,
+			RevisedPrompt: data.RevisedPrompt,
+		})
+	}
+	return &imageResponse
+}
+
+// xAIImageHandler converts the xAI image response into the OpenAI image format.
+// xAI bills images per call, so usage is only forwarded when upstream reports it.
+func xAIImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := service.ReadCompressedBody(resp)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	service.CloseResponseBodyGracefully(resp)
+
+	var xaiResponse ImageResponse
+	if err := common.Unmarshal(responseBody, &xaiResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	imageResponse := responseXAI2OpenAIImage(&xaiResponse, info)
+	fillImageB64Json(c, info, imageResponse)
+	jsonResponse, err := common.Marshal(imageResponse)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	service.IOCopyBytesGracefully(c, resp, jsonResponse)
+
+	usage := &dto.Usage{}
+	if xaiResponse.Usage != nil {
+		usage = xaiResponse.Usage
+	}
+	return usage, nil
+}
+
+// fillImageB64Json downloads url-only images when the client asked for b64_json,
+// so the response matches the requested format even if upstream ignored it.
+func fillImageB64Json(c *gin.Context, info *relaycommon.RelayInfo, response *dto.ImageResponse) {
+	request, ok := info.Request.(*dto.ImageRequest)
+	if !ok || request.ResponseFormat != "b64_json" {
+		return
+	}
+	for i := range response.Data {
+		data := &response.Data[i]
+		if data.B64Json != "" || data.Url == "" {
+			continue
+		}
+		_, b64, err := service.GetImageFromUrl(data.Url)
+		if err != nil {
+			logger.LogWarn(c, fmt.Sprintf("failed to convert xai image url to b64_json: %s", err.Error()))
+			continue
+		}
+		data.B64Json, data.Url = b64, ""
+	}
+}
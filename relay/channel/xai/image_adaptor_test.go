@@ -0,0 +1,170 @@
+package xai
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestXAIImageHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	body := `{"data":[{"url":"https://example.com/a.jpg","revised_prompt":"a cat"},{"b64_json":"aGVsbG8="}]}`
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}
+	info := &relaycommon.RelayInfo{StartTime: time.Unix(1700000000, 0)}
+
+	usage, apiErr := xAIImageHandler(c, info, resp)
+	if apiErr != nil {
+		t.Fatalf("unexpected error: %v", apiErr)
+	}
+	if usage == nil {
+		t.Fatal("expected non-nil usage")
+	}
+
+	var imageResp dto.ImageResponse
+	if err := common.Unmarshal(recorder.Body.Bytes(), &imageResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if imageResp.Created != 1700000000 {
+		t.Errorf("created = %d, want start time", imageResp.Created)
+	}
+	if len(imageResp.Data) != 2 {
+		t.Fatalf("len(data) = %d, want 2", len(imageResp.Data))
+	}
+	if imageResp.Data[0].Url != "https://example.com/a.jpg" || imageResp.Data[0].RevisedPrompt != "a cat" {
+		t.Errorf("unexpected first image: %+v", imageResp.Data[0])
+	}
+	if imageResp.Data[1].B64Json != "aGVsbG8=" {
+		t.Errorf("unexpected second image: %+v", imageResp.Data[1])
+	}
+}
+
+func TestConvertMultipartImageEditRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	pngHeader := []byte("\x89PNG\r\n\x1a\n0000")
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("model", "grok-2-image")
+	_ = writer.WriteField("prompt", "add a hat")
+	_ = writer.WriteField("size", "1024x1536")
+	_ = writer.WriteField("response_format", "b64_json")
+	part, _ := writer.CreateFormFile("image", "cat.png")
+	_, _ = part.Write(pngHeader)
+	part, _ = writer.CreateFormFile("mask", "mask.png")
+	_, _ = part.Write(pngHeader)
+	_ = writer.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/edits", &form)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	info := &relaycommon.RelayInfo{RelayMode: constant.RelayModeImagesEdits}
+	request := dto.ImageRequest{Model: "grok-2-image", Prompt: "add a hat", Size: "1024x1536", ResponseFormat: "b64_json"}
+
+	converted, err := (&Adaptor{}).ConvertImageRequest(c, info, request)
+	if err != nil {
+		t.Fatalf("ConvertImageRequest: %v", err)
+	}
+	data, _ := common.Marshal(converted)
+	var got struct {
+		Model          string         `json:"model"`
+		Prompt         string         `json:"prompt"`
+		ResponseFormat string         `json:"response_format"`
+		AspectRatio    string         `json:"aspect_ratio"`
+		Image          imageEditInput `json:"image"`
+		Mask           imageEditInput `json:"mask"`
+		Size           string         `json:"size"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Model != "grok-2-image" || got.Prompt != "add a hat" || got.ResponseFormat != "b64_json" {
+		t.Errorf("unexpected request: %s", data)
+	}
+	if got.AspectRatio != "2:3" || got.Size != "" {
+		t.Errorf("size should map to aspect_ratio: %s", data)
+	}
+	if !strings.HasPrefix(got.Image.Url, "data:image/png;base64,") || got.Image.Type != "image_url" {
+		t.Errorf("unexpected image: %+v", got.Image)
+	}
+	if !strings.HasPrefix(got.Mask.Url, "data:image/png;base64,") {
+		t.Errorf("unexpected mask: %+v", got.Mask)
+	}
+}
+
+func TestConvertImageEditRequestRequiresImage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("prompt", "add a hat")
+	_ = writer.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/edits", &form)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	info := &relaycommon.RelayInfo{RelayMode: constant.RelayModeImagesEdits}
+
+	if _, err := (&Adaptor{}).ConvertImageRequest(c, info, dto.ImageRequest{Prompt: "add a hat"}); err == nil {
+		t.Fatal("expected error when no image is uploaded")
+	}
+}
+
+func TestConvertMultipartImageVariationRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	_ = writer.WriteField("model", "grok-2-image")
+	_ = writer.WriteField("n", "3")
+	part, _ := writer.CreateFormFile("image", "cat.png")
+	_, _ = part.Write([]byte("\x89PNG\r\n\x1a\n0000"))
+	_ = writer.Close()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/images/variations", &form)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	info := &relaycommon.RelayInfo{RelayMode: constant.RelayModeImagesVariations}
+
+	converted, err := (&Adaptor{}).ConvertImageRequest(c, info, dto.ImageRequest{Model: "grok-2-image", N: 3, ResponseFormat: "b64_json"})
+	if err != nil {
+		t.Fatalf("ConvertImageRequest: %v", err)
+	}
+	data, _ := common.Marshal(converted)
+	var got struct {
+		N              int            `json:"n"`
+		Prompt         *string        `json:"prompt"`
+		ResponseFormat string         `json:"response_format"`
+		Image          imageEditInput `json:"image"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.N != 3 || got.ResponseFormat != "b64_json" || got.Prompt != nil {
+		t.Errorf("unexpected request: %s", data)
+	}
+	if !strings.HasPrefix(got.Image.Url, "data:image/png;base64,") || got.Image.Type != "image_url" {
+		t.Errorf("unexpected image: %+v", got.Image)
+	}
+
+	if _, err := (&Adaptor{}).ConvertImageRequest(c, info, dto.ImageRequest{Model: "grok-2-image", N: 11}); err == nil {
+		t.Error("expected error when n exceeds the xAI limit")
+	}
+}
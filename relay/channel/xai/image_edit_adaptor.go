@@ -0,0 +1,135 @@
+package xai
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageEditInput is the xAI representation of an input image for /v1/images/edits.
+type imageEditInput struct {
+	Url  string `json:"url"`
+	Type string `json:"type"`
+}
+
+// isMultipartImageEdit reports whether the client sent an OpenAI style multipart edit request.
+func isMultipartImageEdit(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// convertImageEditRequest translates an OpenAI multipart image edit request (image, mask, prompt, n, size)
+// into the JSON body accepted by the xAI image edit API. Uploaded files are inlined as base64 data URLs.
+func convertImageEditRequest(c *gin.Context, request dto.ImageRequest) (*ImageRequest, error) {
+	if strings.TrimSpace(request.Prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+	mf := c.Request.MultipartForm
+	if mf == nil {
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, fmt.Errorf("failed to parse image edit form request: %w", err)
+		}
+		mf = c.Request.MultipartForm
+	}
+
+	imageFiles := imageEditFormFiles(mf)
+	if len(imageFiles) == 0 {
+		return nil, errors.New("image is required")
+	}
+	images := make([]imageEditInput, 0, len(imageFiles))
+	for _, file := range imageFiles {
+		dataURL, err := fileHeaderToDataURL(file)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, imageEditInput{Url: dataURL, Type: "image_url"})
+	}
+
+	xaiRequest := &ImageRequest{
+		Model:          request.Model,
+		Prompt:         request.Prompt,
+		N:              int(request.N),
+		ResponseFormat: request.ResponseFormat,
+		AspectRatio:    sizeToAspectRatio(request.Size),
+	}
+	if ratio := strings.TrimSpace(c.Request.FormValue("aspect_ratio")); ratio != "" {
+		xaiRequest.AspectRatio = ratio
+	}
+	var err error
+	if len(images) == 1 {
+		xaiRequest.Image, err = common.Marshal(images[0])
+	} else {
+		xaiRequest.Images, err = common.Marshal(images)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maskFiles := mf.File["mask"]; len(maskFiles) > 0 {
+		dataURL, err := fileHeaderToDataURL(maskFiles[0])
+		if err != nil {
+			return nil, err
+		}
+		if xaiRequest.Mask, err = common.Marshal(imageEditInput{Url: dataURL, Type: "image_url"}); err != nil {
+			return nil, err
+		}
+	}
+	return xaiRequest, nil
+}
+
+// imageEditFormFiles collects the uploaded images from the "image", "image[]" or "image[n]" form fields.
+func imageEditFormFiles(mf *multipart.Form) []*multipart.FileHeader {
+	if files := mf.File["image"]; len(files) > 0 {
+		return files
+	}
+	if files := mf.File["image[]"]; len(files) > 0 {
+		return files
+	}
+	var files []*multipart.FileHeader
+	for fieldName, fieldFiles := range mf.File {
+		if strings.HasPrefix(fieldName, "image[") {
+			files = append(files, fieldFiles...)
+		}
+	}
+	return files
+}
+
+func fileHeaderToDataURL(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open image file: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("failed to read image file: %w", err)
+	}
+	return fmt.Sprintf("data:%s;base64,%s", http.DetectContentType(data), base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// sizeToAspectRatio maps an OpenAI size such as "1024x1536" to an xAI aspect ratio such as "2:3".
+// xAI does not accept explicit pixel sizes, so unparseable sizes are dropped.
+func sizeToAspectRatio(size string) string {
+	w, h, ok := strings.Cut(strings.ToLower(size), "x")
+	if !ok {
+		return ""
+	}
+	width, err1 := strconv.Atoi(strings.TrimSpace(w))
+	height, err2 := strconv.Atoi(strings.TrimSpace(h))
+	if err1 != nil || err2 != nil || width <= 0 || height <= 0 {
+		return ""
+	}
+	a, b := width, height
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return fmt.Sprintf("%d:%d", width/a, height/a)
+}
@@ -153,6 +153,11 @@ func ClaudeHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		}
 	}
 
+	if monitor := service.NewStreamQuotaMonitor(c, info); monitor != nil {
+		info.StreamQuota = monitor
+		defer monitor.Finish()
+	}
+
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
 	//log.Printf("usage: %v", usage)
 	if newAPIError != nil {
@@ -0,0 +1,74 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrChannelConcurrencyLimit = errors.New("channel concurrency limit reached")
+
+const defaultChannelConcurrencyWaitTimeout = 30 * time.Second
+
+type channelSemaphore struct {
+	limit int
+	slots chan struct{}
+}
+
+// ChannelConcurrencyGuard 按渠道 ID 限制同时发往上游的请求数
+type ChannelConcurrencyGuard struct {
+	semaphores  sync.Map // channelId -> *channelSemaphore
+	WaitTimeout time.Duration
+}
+
+var DefaultChannelConcurrencyGuard = NewChannelConcurrencyGuard(defaultChannelConcurrencyWaitTimeout)
+
+func NewChannelConcurrencyGuard(waitTimeout time.Duration) *ChannelConcurrencyGuard {
+	return &ChannelConcurrencyGuard{WaitTimeout: waitTimeout}
+}
+
+// semaphore 获取渠道对应的信号量，渠道并发上限变更时重新创建
+func (g *ChannelConcurrencyGuard) semaphore(channelId int, limit int) *channelSemaphore {
+	for {
+		v, loaded := g.semaphores.LoadOrStore(channelId, &channelSemaphore{limit: limit, slots: make(chan struct{}, limit)})
+		sem := v.(*channelSemaphore)
+		if !loaded || sem.limit == limit {
+			return sem
+		}
+		// 已占用的名额会释放回旧的信号量，不影响新的上限
+		g.semaphores.CompareAndSwap(channelId, sem, &channelSemaphore{limit: limit, slots: make(chan struct{}, limit)})
+	}
+}
+
+// Acquire 等待渠道空闲名额，limit <= 0 表示不限制。
+// 超过 WaitTimeout 仍未获取到名额时返回 ErrChannelConcurrencyLimit。
+func (g *ChannelConcurrencyGuard) Acquire(ctx context.Context, channelId int, limit int) (release func(), err error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	sem := g.semaphore(channelId, limit)
+	timer := time.NewTimer(g.WaitTimeout)
+	defer timer.Stop()
+	select {
+	case sem.slots <- struct{}{}:
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: channel #%d allows %d concurrent requests", ErrChannelConcurrencyLimit, channelId, limit)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-sem.slots })
+	}, nil
+}
+
+// InFlight 返回渠道当前占用的名额数
+func (g *ChannelConcurrencyGuard) InFlight(channelId int) int {
+	v, ok := g.semaphores.Load(channelId)
+	if !ok {
+		return 0
+	}
+	return len(v.(*channelSemaphore).slots)
+}
@@ -0,0 +1,40 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannelConcurrencyGuardUnlimited(t *testing.T) {
+	g := NewChannelConcurrencyGuard(10 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		if _, err := g.Acquire(context.Background(), 1, 0); err != nil {
+			t.Fatalf("unlimited channel returned error: %v", err)
+		}
+	}
+}
+
+func TestChannelConcurrencyGuardTimeout(t *testing.T) {
+	g := NewChannelConcurrencyGuard(20 * time.Millisecond)
+	release, err := g.Acquire(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatalf("first acquire returned error: %v", err)
+	}
+	if _, err := g.Acquire(context.Background(), 1, 1); !errors.Is(err, ErrChannelConcurrencyLimit) {
+		t.Fatalf("expected ErrChannelConcurrencyLimit, got %v", err)
+	}
+	// 其他渠道不受影响
+	if _, err := g.Acquire(context.Background(), 2, 1); err != nil {
+		t.Fatalf("other channel returned error: %v", err)
+	}
+	release()
+	release()
+	if g.InFlight(1) != 0 {
+		t.Fatalf("expected 0 in flight after release, got %d", g.InFlight(1))
+	}
+	if _, err := g.Acquire(context.Background(), 1, 1); err != nil {
+		t.Fatalf("acquire after release returned error: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidateModelMapping 检查渠道的 model_mapping 中是否存在重定向循环。
+// 自映射（a -> a）在请求时会被忽略，因此不视为循环。
+func ValidateModelMapping(mapping string) error {
+	mapping = strings.TrimSpace(mapping)
+	if mapping == "" || mapping == "{}" {
+		return nil
+	}
+	modelMap := make(map[string]string)
+	if err := json.Unmarshal([]byte(mapping), &modelMap); err != nil {
+		return fmt.Errorf("unmarshal model mapping failed: %w", err)
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(modelMap))
+	var path []string
+	var dfs func(model string) []string
+	dfs = func(model string) []string {
+		state[model] = visiting
+		path = append(path, model)
+		if next := modelMap[model]; next != "" && next != model {
+			switch state[next] {
+			case visiting:
+				for i, m := range path {
+					if m == next {
+						return append(append([]string{}, path[i:]...), next)
+					}
+				}
+			case unvisited:
+				if cycle := dfs(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[model] = visited
+		return nil
+	}
+
+	// 按名称排序，保证错误信息稳定
+	models := make([]string, 0, len(modelMap))
+	for model := range modelMap {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		if state[model] != unvisited {
+			continue
+		}
+		if cycle := dfs(model); cycle != nil {
+			return fmt.Errorf("model mapping contains cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateModelMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		cycle   string
+	}{
+		{name: "empty", mapping: ""},
+		{name: "self map", mapping: `{"gpt-4o":"gpt-4o"}`},
+		{name: "chain ending in self map", mapping: `{"a":"b","b":"b"}`},
+		{name: "multi-hop chain", mapping: `{"a":"b","b":"c","c":"d","x":"c"}`},
+		{name: "two-node cycle", mapping: `{"a":"b","b":"a"}`, cycle: "a -> b -> a"},
+		{name: "multi-hop cycle", mapping: `{"a":"b","b":"c","c":"d","d":"b"}`, cycle: "b -> c -> d -> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateModelMapping(tt.mapping)
+			if tt.cycle == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasSuffix(err.Error(), tt.cycle) {
+				t.Fatalf("expected cycle %q, got %v", tt.cycle, err)
+			}
+		})
+	}
+
+	if err := ValidateModelMapping(`{"a":`); err == nil {
+		t.Fatal("expected error for invalid json")
+	}
+}
@@ -122,6 +122,7 @@ type ChannelMeta struct {
 	UpstreamModelName    string
 	IsModelMapped        bool
 	SupportStreamOptions bool // 是否支持流式选项
+	ConcurrencyLimit     int  // 渠道最大并发请求数，0 表示不限制
 }
 
 type TokenCountMeta struct {
@@ -129,6 +130,11 @@ type TokenCountMeta struct {
 	estimatePromptTokens int
 }
 
+// StreamQuotaMonitor 在流式响应过程中按输出分段扣费，OnStreamChunk 返回错误表示额度不足，需要中断流
+type StreamQuotaMonitor interface {
+	OnStreamChunk(data string) error
+}
+
 type RelayInfo struct {
 	TokenId           int
 	TokenKey          string
@@ -136,6 +142,8 @@ type RelayInfo struct {
 	UserId            int
 	UsingGroup        string // 使用的分组，当auto跨分组重试时，会变动
 	UserGroup         string // 用户所在分组
+	TraceID           string // 请求追踪 ID，通过 X-Trace-Id 透传给上游
+	Language          string // 根据 Accept-Language 匹配的错误信息语言，为空时保持默认信息
 	TokenUnlimited    bool
 	StartTime         time.Time
 	FirstResponseTime time.Time
@@ -147,6 +155,8 @@ type RelayInfo struct {
 	UsePrice               bool
 	RelayMode              int
 	OriginModelName        string
+	UserModelUpstream      string  // 用户自定义模型对应的上游模型 ID，在渠道模型映射之前替换
+	UserModelPrice         float64 // 用户自定义模型的单次价格，大于 0 时覆盖模型定价
 	RequestURLPath         string
 	ShouldIncludeUsage     bool
 	DisablePing            bool // 是否禁止向下游发送自定义 Ping
@@ -170,6 +180,9 @@ type RelayInfo struct {
 
 	Request dto.Request
 
+	// 流式响应的分段扣费，为 nil 时只在流结束后结算
+	StreamQuota StreamQuotaMonitor
+
 	ThinkingContentInfo
 	TokenCountMeta
 	*ClaudeConvertInfo
@@ -200,6 +213,7 @@ func (info *RelayInfo) InitChannelMeta(c *gin.Context) {
 		UpstreamModelName:    common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
 		IsModelMapped:        false,
 		SupportStreamOptions: false,
+		ConcurrencyLimit:     common.GetContextKeyInt(c, constant.ContextKeyChannelConcurrencyLimit),
 	}
 
 	if channelType == constant.ChannelTypeAzure {
@@ -448,12 +462,14 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 		Request: request,
 
 		UserId:     common.GetContextKeyInt(c, constant.ContextKeyUserId),
+		TraceID:    c.GetString(common.TraceIdKey),
 		UsingGroup: common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
 		UserGroup:  common.GetContextKeyString(c, constant.ContextKeyUserGroup),
 		UserQuota:  common.GetContextKeyInt(c, constant.ContextKeyUserQuota),
 		UserEmail:  common.GetContextKeyString(c, constant.ContextKeyUserEmail),
 
-		OriginModelName: common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+		OriginModelName:   common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+		UserModelUpstream: common.GetContextKeyString(c, constant.ContextKeyUserModelUpstream),
 
 		TokenId:        common.GetContextKeyInt(c, constant.ContextKeyTokenId),
 		TokenKey:       common.GetContextKeyString(c, constant.ContextKeyTokenKey),
@@ -491,6 +507,9 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 	if ok {
 		info.UserSetting = userSetting
 	}
+	if price, ok := common.GetContextKeyType[float64](c, constant.ContextKeyUserModelPrice); ok {
+		info.UserModelPrice = price
+	}
 
 	return info
 }
@@ -558,6 +577,12 @@ type TaskRelayInfo struct {
 	OriginTaskID string
 
 	ConsumeQuota bool
+
+	// ExecutionExpiresAfterSec 用户指定的任务过期时间（秒），0 表示使用全局超时
+	ExecutionExpiresAfterSec int64
+
+	// ConversationHistory 通过 previous_task_id 引用的历史轮次，按时间顺序排列
+	ConversationHistory []dto.TaskMessage
 }
 
 type TaskSubmitReq struct {
@@ -655,6 +680,21 @@ type TaskInfo struct {
 	TotalTokens      int     `json:"total_tokens,omitempty"`      // 用于按倍率计费
 	Duration         float64 `json:"duration,omitempty"`          // actual video duration (seconds)
 	CostQuota        int     `json:"cost_quota,omitempty"`        // xAI cost_in_usd_ticks converted to quota
+	// 上游连续返回空状态时最多容忍的轮询次数，0 表示使用 DefaultMaxEmptyStatusRetries
+	MaxEmptyStatusRetries int `json:"max_empty_status_retries,omitempty"`
+	// 批量查询时该任务在上游响应中的原始数据，写入 task.Data
+	Data []byte `json:"-"`
+}
+
+// DefaultMaxEmptyStatusRetries 上游连续返回空状态时默认容忍的轮询次数，超过后任务判定为失败
+const DefaultMaxEmptyStatusRetries = 5
+
+// EmptyStatusRetryLimit 返回本次结果允许的空状态轮询次数
+func (t *TaskInfo) EmptyStatusRetryLimit() int {
+	if t.MaxEmptyStatusRetries > 0 {
+		return t.MaxEmptyStatusRetries
+	}
+	return DefaultMaxEmptyStatusRetries
 }
 
 func FailTaskInfo(reason string) *TaskInfo {
@@ -127,7 +127,7 @@ func ValidateMultipartDirect(c *gin.Context, info *RelayInfo) *dto.TaskError {
 
 	var req TaskSubmitReq
 	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
-		return createTaskError(err, "invalid_json", http.StatusBadRequest, true)
+		return createTaskError(err, "invalid_request", common.RequestBodyErrorStatusCode(err), true)
 	}
 
 	prompt = req.Prompt
@@ -142,7 +142,7 @@ func ValidateMultipartDirect(c *gin.Context, info *RelayInfo) *dto.TaskError {
 	}
 
 	if strings.TrimSpace(req.Model) == "" {
-		return createTaskError(fmt.Errorf("model field is required"), "missing_model", http.StatusBadRequest, true)
+		return createTaskError(fmt.Errorf("model field is required"), "invalid_request", http.StatusBadRequest, true)
 	}
 
 	if req.HasImage() {
@@ -168,10 +168,10 @@ func ValidateMultipartDirect(c *gin.Context, info *RelayInfo) *dto.TaskError {
 		}
 
 		if model == "sora-2" && !lo.Contains([]string{"720x1280", "1280x720"}, size) {
-			return createTaskError(fmt.Errorf("sora-2 size is invalid"), "invalid_size", http.StatusBadRequest, true)
+			return createTaskError(fmt.Errorf("sora-2 size is invalid"), "invalid_request", http.StatusBadRequest, true)
 		}
 		if model == "sora-2-pro" && !lo.Contains([]string{"720x1280", "1280x720", "1792x1024", "1024x1792"}, size) {
-			return createTaskError(fmt.Errorf("sora-2 size is invalid"), "invalid_size", http.StatusBadRequest, true)
+			return createTaskError(fmt.Errorf("sora-2 size is invalid"), "invalid_request", http.StatusBadRequest, true)
 		}
 		info.PriceData.OtherRatios = map[string]float64{
 			"seconds": float64(seconds),
@@ -189,14 +189,15 @@ func ValidateMultipartDirect(c *gin.Context, info *RelayInfo) *dto.TaskError {
 
 func isKnownTaskField(field string) bool {
 	knownFields := map[string]bool{
-		"prompt":          true,
-		"model":           true,
-		"mode":            true,
-		"image":           true,
-		"images":          true,
-		"size":            true,
-		"duration":        true,
-		"input_reference": true, // Sora 特有字段
+		"prompt":           true,
+		"model":            true,
+		"mode":             true,
+		"image":            true,
+		"images":           true,
+		"size":             true,
+		"duration":         true,
+		"input_reference":  true, // Sora 特有字段
+		"previous_task_id": true,
 	}
 	return knownFields[field]
 }
@@ -208,10 +209,10 @@ func ValidateBasicTaskRequest(c *gin.Context, info *RelayInfo, action string) *d
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		req, err = validateMultipartTaskRequest(c, info, action)
 		if err != nil {
-			return createTaskError(err, "invalid_multipart_form", http.StatusBadRequest, true)
+			return createTaskError(err, "invalid_request", common.RequestBodyErrorStatusCode(err), true)
 		}
 	} else if err := common.UnmarshalBodyReusable(c, &req); err != nil {
-		return createTaskError(err, "invalid_request", http.StatusBadRequest, true)
+		return createTaskError(err, "invalid_request", common.RequestBodyErrorStatusCode(err), true)
 	}
 
 	if taskErr := validatePrompt(req.Prompt); taskErr != nil {
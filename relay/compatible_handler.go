@@ -198,6 +198,11 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		}
 	}
 
+	if monitor := service.NewStreamQuotaMonitor(c, info); monitor != nil {
+		info.StreamQuota = monitor
+		defer monitor.Finish()
+	}
+
 	usage, newApiErr := adaptor.DoResponse(c, httpResp, info)
 	if newApiErr != nil {
 		// reset status code 重置状态码
@@ -225,6 +230,9 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 		}
 		extraContent = append(extraContent, "上游无计费信息")
 	}
+	if common.GetContextKeyBool(ctx, constant.ContextKeyStreamQuotaExceeded) {
+		extraContent = append(extraContent, "流式输出过程中额度不足，已中断")
+	}
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	cacheTokens := usage.PromptTokensDetails.CachedTokens
@@ -591,6 +599,7 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 		other["xai_input_image_count"] = xaiInputImageCount
 		other["xai_input_image_price"] = xaiInputImagePrice
 	}
+	common.SetContextKey(ctx, constant.ContextKeyUsageTotalTokens, promptTokens+completionTokens)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     promptTokens,
@@ -187,6 +187,11 @@ func GeminiHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 		}
 	}
 
+	if monitor := service.NewStreamQuotaMonitor(c, info); monitor != nil {
+		info.StreamQuota = monitor
+		defer monitor.Finish()
+	}
+
 	usage, openaiErr := adaptor.DoResponse(c, resp.(*http.Response), info)
 	if openaiErr != nil {
 		service.ResetStatusCode(openaiErr, statusCodeMappingStr)
@@ -11,6 +11,11 @@ import (
 )
 
 func ModelMappedHelper(c *gin.Context, info *common.RelayInfo, request dto.Request) error {
+	// 用户自定义模型先替换为上游模型 ID，再应用渠道映射
+	if info.UserModelUpstream != "" {
+		info.IsModelMapped = true
+		info.UpstreamModelName = info.UserModelUpstream
+	}
 	// map model name
 	modelMapping := c.GetString("model_mapping")
 	if modelMapping != "" && modelMapping != "{}" {
@@ -22,6 +27,9 @@ func ModelMappedHelper(c *gin.Context, info *common.RelayInfo, request dto.Reque
 
 		// 支持链式模型重定向，最终使用链尾的模型
 		currentModel := info.OriginModelName
+		if info.UserModelUpstream != "" {
+			currentModel = info.UserModelUpstream
+		}
 		visitedModels := map[string]bool{
 			currentModel: true,
 		}
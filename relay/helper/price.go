@@ -46,7 +46,13 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 }
 
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, meta *types.TokenCountMeta) (types.PriceData, error) {
+	pricingModel := info.OriginModelName
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
+	// 用户自定义模型按上游模型定价：上游按量计费时使用上游倍率，否则按注册价格按次计费
+	if info.UserModelPrice > 0 {
+		pricingModel = info.UserModelUpstream
+		modelPrice, usePrice = ratio_setting.GetUserModelPrice(info.UserModelUpstream, info.UserModelPrice)
+	}
 
 	groupRatioInfo := HandleGroupRatio(c, info)
 
@@ -69,7 +75,7 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		}
 		var success bool
 		var matchName string
-		modelRatio, success, matchName = ratio_setting.GetModelRatio(info.OriginModelName)
+		modelRatio, success, matchName = ratio_setting.GetModelRatio(pricingModel)
 		if !success {
 			acceptUnsetRatio := false
 			if info.UserSetting.AcceptUnsetRatioModel {
@@ -79,16 +85,16 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 				return types.PriceData{}, fmt.Errorf("模型 %s 倍率或价格未配置，请联系管理员设置或开始自用模式；Model %s ratio or price not set, please set or start self-use mode", matchName, matchName)
 			}
 		}
-		completionRatio = ratio_setting.GetCompletionRatio(info.OriginModelName)
-		cacheRatio, _ = ratio_setting.GetCacheRatio(info.OriginModelName)
-		cacheCreationRatio, _ = ratio_setting.GetCreateCacheRatio(info.OriginModelName)
+		completionRatio = ratio_setting.GetCompletionRatio(pricingModel)
+		cacheRatio, _ = ratio_setting.GetCacheRatio(pricingModel)
+		cacheCreationRatio, _ = ratio_setting.GetCreateCacheRatio(pricingModel)
 		cacheCreationRatio5m = cacheCreationRatio
 		// 固定1h和5min缓存写入价格的比例
 		cacheCreationRatio1h = cacheCreationRatio * claudeCacheCreation1hMultiplier
-		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
-		imageOutputRatio, _ = ratio_setting.GetImageOutputRatio(info.OriginModelName)
-		audioRatio = ratio_setting.GetAudioRatio(info.OriginModelName)
-		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(info.OriginModelName)
+		imageRatio, _ = ratio_setting.GetImageRatio(pricingModel)
+		imageOutputRatio, _ = ratio_setting.GetImageOutputRatio(pricingModel)
+		audioRatio = ratio_setting.GetAudioRatio(pricingModel)
+		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(pricingModel)
 		ratio := modelRatio * groupRatioInfo.GroupRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
@@ -147,6 +153,12 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) types.
 	groupRatioInfo := HandleGroupRatio(c, info)
 
 	modelPrice, success := ratio_setting.GetModelPrice(info.OriginModelName, true)
+	if info.UserModelPrice > 0 {
+		modelPrice, success = ratio_setting.GetUserModelPrice(info.UserModelUpstream, info.UserModelPrice)
+		if !success {
+			modelPrice, success = info.UserModelPrice, true
+		}
+	}
 	// 如果没有配置价格，则使用默认价格
 	if !success {
 		defaultPrice, ok := ratio_setting.GetDefaultModelPriceMap()[info.OriginModelName]
@@ -25,8 +25,23 @@ const (
 	InitialScannerBufferSize    = 64 << 10 // 64KB (64*1024)
 	DefaultMaxScannerBufferSize = 64 << 20 // 64MB (64*1024*1024) default SSE buffer size
 	DefaultPingInterval         = 10 * time.Second
+	// StreamQuotaExceededEvent 流式输出过程中额度耗尽时发送给下游的事件
+	StreamQuotaExceededEvent = "[QUOTA_EXCEEDED]"
 )
 
+// discardStreamWriter 丢弃之后的所有写入，用于额度耗尽后结束向下游的输出
+type discardStreamWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *discardStreamWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardStreamWriter) WriteString(s string) (int, error) {
+	return len(s), nil
+}
+
 func getScannerBufferSize() int {
 	if constant.StreamScannerMaxBufferMB > 0 {
 		return constant.StreamScannerMaxBufferMB << 20
@@ -233,6 +248,17 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 					if !success {
 						return
 					}
+					if info.StreamQuota != nil {
+						if err := info.StreamQuota.OnStreamChunk(data); err != nil {
+							logger.LogWarn(c, "stop streaming: "+err.Error())
+							writeMutex.Lock()
+							_ = StringData(c, StreamQuotaExceededEvent)
+							common.SetContextKey(c, constant.ContextKeyStreamQuotaExceeded, true)
+							c.Writer = &discardStreamWriter{ResponseWriter: c.Writer}
+							writeMutex.Unlock()
+							return
+						}
+					}
 				case <-time.After(10 * time.Second):
 					logger.LogError(c, "data handler timeout")
 					return
@@ -129,6 +129,24 @@ func GetAndValidOpenAIImageRequest(c *gin.Context, relayMode int) (*dto.ImageReq
 	imageRequest := &dto.ImageRequest{}
 
 	switch relayMode {
+	case relayconstant.RelayModeImagesVariations:
+		if !strings.Contains(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
+			return nil, errors.New("image variation request must be multipart/form-data")
+		}
+		if _, err := c.MultipartForm(); err != nil {
+			return nil, fmt.Errorf("failed to parse image variation form request: %w", err)
+		}
+		formData := c.Request.PostForm
+		imageRequest.Model = formData.Get("model")
+		imageRequest.N = uint(common.String2Int(formData.Get("n")))
+		imageRequest.Size = formData.Get("size")
+		imageRequest.ResponseFormat = formData.Get("response_format")
+		if imageRequest.Model == "" {
+			return nil, errors.New("model is required")
+		}
+		if imageRequest.N == 0 {
+			imageRequest.N = 1
+		}
 	case relayconstant.RelayModeImagesEdits:
 		if strings.Contains(c.Request.Header.Get("Content-Type"), "multipart/form-data") {
 			_, err := c.MultipartForm()
@@ -0,0 +1,83 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 异步图片任务轮询的退避间隔
+var (
+	asyncImagePollInitialInterval = time.Second
+	asyncImagePollMaxInterval     = 10 * time.Second
+)
+
+// waitAsyncImageResult 在上游返回未完成的任务时轮询 FetchTask，直到拿到最终结果或超时。
+// 返回的响应体为最终结果，交由 adaptor.DoResponse 按同步响应处理
+func waitAsyncImageResult(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.AsyncImageAdaptor, resp *http.Response) (*http.Response, *types.NewAPIError) {
+	body, err := service.ReadCompressedBody(resp)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+	}
+	taskID, err := adaptor.ParseImageTask(body)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	if taskID == "" {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+
+	timeout := time.Duration(constant.AsyncImageTimeoutSeconds) * time.Second
+	deadline := time.Now().Add(timeout)
+	interval := asyncImagePollInitialInterval
+	logger.LogInfo(c, fmt.Sprintf("image task %s is pending upstream, polling for up to %s", taskID, timeout))
+
+	for {
+		wait := min(interval, time.Until(deadline))
+		if wait <= 0 {
+			return nil, types.NewErrorWithStatusCode(fmt.Errorf("image task %s did not finish within %s", taskID, timeout), types.ErrorCodeBadResponse, http.StatusGatewayTimeout)
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return nil, types.NewError(errors.New("client disconnected while waiting for image task"), types.ErrorCodeDoRequestFailed, types.ErrOptionWithSkipRetry())
+		case <-time.After(wait):
+		}
+		interval = min(interval*2, asyncImagePollMaxInterval)
+
+		fetchResp, err := adaptor.FetchTask(c, info, taskID)
+		if err != nil {
+			logger.LogWarn(c, fmt.Sprintf("fetch image task %s failed: %s", taskID, err.Error()))
+			continue
+		}
+		if fetchResp.StatusCode != http.StatusOK {
+			return nil, service.RelayErrorHandler(c.Request.Context(), fetchResp, false)
+		}
+		body, err = service.ReadCompressedBody(fetchResp)
+		_ = fetchResp.Body.Close()
+		if err != nil {
+			return nil, types.NewError(err, types.ErrorCodeReadResponseBodyFailed)
+		}
+		pendingID, err := adaptor.ParseImageTask(body)
+		if err != nil {
+			return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+		}
+		if pendingID == "" {
+			fetchResp.Body = io.NopCloser(bytes.NewReader(body))
+			return fetchResp, nil
+		}
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -42,6 +44,9 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 	if adaptor == nil {
 		return types.NewError(fmt.Errorf("invalid api type: %d", info.ApiType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
 	}
+	if info.RelayMode == relayconstant.RelayModeImagesVariations && !supportsImageVariations(adaptor) {
+		return types.NewErrorWithStatusCode(fmt.Errorf("image variations are not supported by channel type %d", info.ChannelType), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
 	adaptor.Init(info)
 
 	var requestBody io.Reader
@@ -105,6 +110,17 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 		}
 	}
 
+	// 异步生成的渠道可能只返回未完成的任务，轮询到最终结果后再按同步响应处理
+	if httpResp != nil && !info.IsStream && adaptor.IsAsyncImageGeneration() {
+		if asyncAdaptor, ok := adaptor.(channel.AsyncImageAdaptor); ok {
+			httpResp, newAPIError = waitAsyncImageResult(c, info, asyncAdaptor, httpResp)
+			if newAPIError != nil {
+				service.ResetStatusCode(newAPIError, statusCodeMappingStr)
+				return newAPIError
+			}
+		}
+	}
+
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
 	if newAPIError != nil {
 		// reset status code 重置状态码
@@ -144,3 +160,25 @@ func ImageHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *type
 	postConsumeQuota(c, info, usage.(*dto.Usage), logContent...)
 	return nil
 }
+
+// supportsImageVariations 判断适配器是否支持图片变体请求，未声明支持的适配器一律拒绝
+func supportsImageVariations(adaptor channel.Adaptor) bool {
+	variationAdaptor, ok := adaptor.(channel.ImageVariationAdaptor)
+	return ok && variationAdaptor.SupportsImageVariations()
+}
+
+// responseRecorder 在写出响应的同时记录响应体，用于写入任务提交幂等记录
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
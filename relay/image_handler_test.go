@@ -0,0 +1,25 @@
+package relay
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+)
+
+func TestSupportsImageVariations(t *testing.T) {
+	cases := map[int]bool{
+		constant.APITypeOpenAI:    true,
+		constant.APITypeXai:       true,
+		constant.APITypeAnthropic: false,
+		constant.APITypeGemini:    false,
+	}
+	for apiType, want := range cases {
+		adaptor := GetAdaptor(apiType)
+		if adaptor == nil {
+			t.Fatalf("no adaptor for api type %d", apiType)
+		}
+		if got := supportsImageVariations(adaptor); got != want {
+			t.Errorf("api type %d: supportsImageVariations = %v, want %v", apiType, got, want)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package relay
 import (
 	"strconv"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay/channel"
 	"github.com/QuantumNous/new-api/relay/channel/ali"
 	"github.com/QuantumNous/new-api/relay/channel/aws"
@@ -15,6 +17,7 @@ import (
 	"github.com/QuantumNous/new-api/relay/channel/coze"
 	"github.com/QuantumNous/new-api/relay/channel/deepseek"
 	"github.com/QuantumNous/new-api/relay/channel/dify"
+	"github.com/QuantumNous/new-api/relay/channel/fal"
 	"github.com/QuantumNous/new-api/relay/channel/gemini"
 	"github.com/QuantumNous/new-api/relay/channel/jimeng"
 	"github.com/QuantumNous/new-api/relay/channel/jina"
@@ -29,6 +32,7 @@ import (
 	"github.com/QuantumNous/new-api/relay/channel/replicate"
 	"github.com/QuantumNous/new-api/relay/channel/replicate2"
 	"github.com/QuantumNous/new-api/relay/channel/siliconflow"
+	"github.com/QuantumNous/new-api/relay/channel/stability"
 	"github.com/QuantumNous/new-api/relay/channel/submodel"
 	taskali "github.com/QuantumNous/new-api/relay/channel/task/ali"
 	taskdoubao "github.com/QuantumNous/new-api/relay/channel/task/doubao"
@@ -36,10 +40,15 @@ import (
 	"github.com/QuantumNous/new-api/relay/channel/task/hailuo"
 	taskjimeng "github.com/QuantumNous/new-api/relay/channel/task/jimeng"
 	"github.com/QuantumNous/new-api/relay/channel/task/kling"
+	taskluma "github.com/QuantumNous/new-api/relay/channel/task/luma"
+	taskmidjourney "github.com/QuantumNous/new-api/relay/channel/task/midjourney"
+	taskrunway "github.com/QuantumNous/new-api/relay/channel/task/runway"
 	tasksora "github.com/QuantumNous/new-api/relay/channel/task/sora"
 	"github.com/QuantumNous/new-api/relay/channel/task/suno"
+	taskudio "github.com/QuantumNous/new-api/relay/channel/task/udio"
 	taskvertex "github.com/QuantumNous/new-api/relay/channel/task/vertex"
 	taskVidu "github.com/QuantumNous/new-api/relay/channel/task/vidu"
+	taskvolcaudio "github.com/QuantumNous/new-api/relay/channel/task/volcaudio"
 	taskvolcvideo "github.com/QuantumNous/new-api/relay/channel/task/volcvideo"
 	taskxai "github.com/QuantumNous/new-api/relay/channel/task/xai"
 	"github.com/QuantumNous/new-api/relay/channel/tencent"
@@ -122,6 +131,10 @@ func GetAdaptor(apiType int) channel.Adaptor {
 		return &replicate.Adaptor{}
 	case constant.APITypeReplicate2:
 		return &replicate2.Adaptor{}
+	case constant.APITypeFal:
+		return &fal.Adaptor{}
+	case constant.APITypeStability:
+		return &stability.Adaptor{}
 	}
 	return nil
 }
@@ -140,6 +153,16 @@ func GetTaskAdaptor(platform constant.TaskPlatform) channel.TaskAdaptor {
 	//	return &aiproxy.Adaptor{}
 	case constant.TaskPlatformSuno:
 		return &suno.TaskAdaptor{}
+	case constant.TaskPlatformVolcAudio:
+		return &taskvolcaudio.TaskAdaptor{}
+	case constant.TaskPlatformRunway:
+		return &taskrunway.TaskAdaptor{}
+	case constant.TaskPlatformLuma:
+		return &taskluma.TaskAdaptor{}
+	case constant.TaskPlatformUdio:
+		return &taskudio.TaskAdaptor{}
+	case constant.TaskPlatformMidjourney:
+		return &taskmidjourney.TaskAdaptor{}
 	}
 	if channelType, err := strconv.ParseInt(string(platform), 10, 64); err == nil {
 		switch channelType {
@@ -165,7 +188,24 @@ func GetTaskAdaptor(platform constant.TaskPlatform) channel.TaskAdaptor {
 			return &taskvolcvideo.TaskAdaptor{}
 		case constant.ChannelTypeXai:
 			return &taskxai.TaskAdaptor{}
+		case constant.ChannelTypeFal:
+			return &fal.TaskAdaptor{}
+		case constant.ChannelTypeMidjourney, constant.ChannelTypeMidjourneyPlus:
+			return &taskmidjourney.TaskAdaptor{}
 		}
 	}
 	return nil
 }
+
+// GetChannelCapabilities 返回渠道适配器通过 channel.CapabilityAdaptor 声明的能力，未声明时 ok 为 false
+func GetChannelCapabilities(ch *model.Channel) ([]string, bool) {
+	apiType, ok := common.ChannelType2APIType(ch.Type)
+	if !ok {
+		return nil, false
+	}
+	capabilityAdaptor, ok := GetAdaptor(apiType).(channel.CapabilityAdaptor)
+	if !ok {
+		return nil, false
+	}
+	return capabilityAdaptor.GetCapabilities(), true
+}
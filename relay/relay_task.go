@@ -15,12 +15,17 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/metrics"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/service/events"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/QuantumNous/new-api/setting/system_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +35,11 @@ Task 任务通过平台、Action 区分任务
 */
 func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.TaskError) {
 	info.InitChannelMeta(c)
+	info.Language = service.MatchLanguage(c.GetHeader("Accept-Language"))
+	startTime := time.Now()
+	defer func() {
+		observeTaskRequest(info.ChannelId, info.OriginModelName, startTime, taskErr)
+	}()
 	// ensure TaskRelayInfo is initialized to avoid nil dereference when accessing embedded fields
 	if info.TaskRelayInfo == nil {
 		info.TaskRelayInfo = &relaycommon.TaskRelayInfo{}
@@ -64,7 +74,7 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 			return
 		}
 		if !exist {
-			taskErr = service.TaskErrorWrapperLocal(errors.New("task_origin_not_exist"), "task_not_exist", http.StatusBadRequest)
+			taskErr = service.TaskErrorWrapperLocal(errors.New("task_origin_not_exist"), "task_not_exist", http.StatusNotFound)
 			return
 		}
 		if info.OriginModelName == "" {
@@ -144,106 +154,150 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		return service.TaskErrorWrapperLocal(err, "model_mapping_failed", http.StatusBadRequest)
 	}
 
+	// 依赖其他任务产出的请求：上游未完成时暂存，完成后由后台任务自动提交
+	var deferred bool
+	if deferred, taskErr = deferTaskDependency(c, info, adaptor); taskErr != nil || deferred {
+		return
+	}
+
 	// get & validate taskRequest 获取并验证文本请求
 	taskErr = adaptor.ValidateRequestAndSetAction(c, info)
 	if taskErr != nil {
 		return
 	}
-
-	modelName := info.OriginModelName
-	if modelName == "" {
-		modelName = service.CoverTaskActionToModelName(platform, info.Action)
+	taskTags, err := getTaskTags(c)
+	if err != nil {
+		taskErr = service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		return
 	}
-	modelPrice, success := ratio_setting.GetModelPrice(modelName, true)
-	if !success {
-		defaultPrice, ok := ratio_setting.GetDefaultModelPriceMap()[modelName]
-		if !ok {
-			modelPrice = 0.1
-		} else {
-			modelPrice = defaultPrice
+
+	// 相同请求在短时间内重复提交时直接返回首次提交的响应
+	idempotencyKey := taskIdempotencyKey(c, info)
+	if record, acquired := service.DefaultTaskIdempotency.Acquire(idempotencyKey); !acquired {
+		if record == nil {
+			return service.TaskErrorWrapperLocal(errors.New("an identical task submission is still in progress"), "task_submission_in_progress", http.StatusConflict)
 		}
+		logger.LogInfo(c, fmt.Sprintf("duplicate task submission, returning existing task %s", record.TaskID))
+		c.Data(record.StatusCode, record.ContentType, []byte(record.Body))
+		return nil
 	}
-
-	// 处理 auto 分组：从 context 获取实际选中的分组
-	// 当使用 auto 分组时，Distribute 中间件会将实际选中的分组存储在 ContextKeyAutoGroup 中
-	if autoGroup, exists := common.GetContextKey(c, constant.ContextKeyAutoGroup); exists {
-		if groupStr, ok := autoGroup.(string); ok && groupStr != "" {
-			info.UsingGroup = groupStr
+	idempotencyRecorded := false
+	defer func() {
+		if !idempotencyRecorded {
+			service.DefaultTaskIdempotency.Release(idempotencyKey)
 		}
+	}()
+
+	// 多轮生成：加载 previous_task_id 引用的上一轮结果
+	if taskErr = loadConversationHistory(c, info); taskErr != nil {
+		return
 	}
 
-	// 预扣
-	groupRatio := ratio_setting.GetGroupRatio(info.UsingGroup)
-	var ratio float64
-	userGroupRatio, hasUserGroupRatio := ratio_setting.GetGroupGroupRatio(info.UserGroup, info.UsingGroup)
-	if hasUserGroupRatio {
-		ratio = modelPrice * userGroupRatio
-	} else {
-		ratio = modelPrice * groupRatio
-	}
-	// FIXME: 临时修补，支持任务仅按次计费
-	if !common.StringsContains(constant.TaskPricePatches, modelName) {
-		if len(info.PriceData.OtherRatios) > 0 {
-			for _, ra := range info.PriceData.OtherRatios {
-				if 1.0 != ra {
-					ratio *= ra
-				}
-			}
-		}
+	modelName := info.OriginModelName
+	if modelName == "" {
+		modelName = service.CoverTaskActionToModelName(platform, info.Action)
 	}
-	println(fmt.Sprintf("model: %s, model_price: %.4f, group: %s, group_ratio: %.4f, final_ratio: %.4f", modelName, modelPrice, info.UsingGroup, groupRatio, ratio))
-	userQuota, err := model.GetUserQuota(info.UserId, false)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "get_user_quota_failed", http.StatusInternalServerError)
+
+	applyTaskAutoGroup(c, info)
+
+	// 提交上游前的内容审核，需在预扣额度之前完成
+	if taskErr = moderateTaskPrompt(c, info); taskErr != nil {
 		return
 	}
-	quota := int(ratio * common.QuotaPerUnit)
 
-	// xAI input image billing (additive, set by xAI video task adaptor)
+	// 预扣
+	price := service.ComputeTaskPrice(info, modelName)
+	modelPrice, groupRatio := price.ModelPrice, price.GroupRatio
+	userGroupRatio, hasUserGroupRatio := price.UserGroupRatio, price.HasUserGroupRatio
+	println(fmt.Sprintf("model: %s, model_price: %.4f, group: %s, group_ratio: %.4f, final_ratio: %.4f", modelName, modelPrice, info.UsingGroup, groupRatio, price.Ratio))
+	quota := price.Quota + taskInputMediaQuota(c, price)
 	xaiInputImageCount := c.GetInt("xai_input_image_count")
 	xaiInputImagePrice := c.GetFloat64("xai_input_image_price")
-	if xaiInputImageCount > 0 && xaiInputImagePrice > 0 {
-		effectiveGroupRatio := groupRatio
-		if hasUserGroupRatio {
-			effectiveGroupRatio = userGroupRatio
-		}
-		xaiInputImageQuota := int(xaiInputImagePrice * float64(xaiInputImageCount) * effectiveGroupRatio * common.QuotaPerUnit)
-		quota += xaiInputImageQuota
+
+	// 影子提交：额度只用于记录成本，不预扣也不计入用户消费
+	if shadowResult, ok := common.GetContextKeyType[*model.ShadowResult](c, constant.ContextKeyTaskShadowResult); ok {
+		return submitShadowTask(c, info, adaptor, shadowResult, quota)
 	}
 
-	// xAI input video billing (additive, set by xAI video task adaptor for video edits)
-	xaiInputVideoSeconds := c.GetFloat64("xai_input_video_seconds")
-	xaiInputVideoPrice := c.GetFloat64("xai_input_video_price")
-	if xaiInputVideoSeconds > 0 && xaiInputVideoPrice > 0 {
-		effectiveGroupRatio := groupRatio
-		if hasUserGroupRatio {
-			effectiveGroupRatio = userGroupRatio
+	// 模型周期消费上限：先累加已用额度，提交失败时退回
+	if err := model.CheckModelSpendingCap(info.UserId, modelName, quota); err != nil {
+		if errors.Is(err, model.ErrModelSpendingCapExceeded) {
+			taskErr = service.TaskErrorWrapperLocal(err, "model_spending_cap_exceeded", http.StatusForbidden)
+		} else {
+			taskErr = service.TaskErrorWrapper(err, "check_model_spending_cap_failed", http.StatusInternalServerError)
 		}
-		xaiInputVideoQuota := int(xaiInputVideoPrice * xaiInputVideoSeconds * effectiveGroupRatio * common.QuotaPerUnit)
-		quota += xaiInputVideoQuota
+		return
 	}
+	defer func() {
+		if info.ConsumeQuota && taskErr == nil {
+			return
+		}
+		if err := model.ReleaseModelSpendingCap(info.UserId, modelName, quota); err != nil {
+			logger.LogError(c, "error releasing model spending cap: "+err.Error())
+		}
+	}()
 
-	if userQuota-quota < 0 {
-		taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
-		return
+	// 预留额度：成功提交后确认，否则释放。批量提交时优先从批次预留的额度池中取用
+	var userReservationId, tokenReservationId int64
+	if quotaPool, ok := common.GetContextKeyType[*service.TaskQuotaPool](c, constant.ContextKeyTaskQuotaPool); ok && quotaPool.Take(quota) {
+		defer func() {
+			if info.ConsumeQuota && taskErr == nil {
+				return
+			}
+			quotaPool.Return(quota)
+		}()
+	} else {
+		userReservationId, err = model.ReserveUserQuota(info.UserId, quota)
+		if err != nil {
+			if errors.Is(err, model.ErrQuotaNotEnough) {
+				taskErr = service.TaskErrorWrapperLocal(errors.New("user quota is not enough"), "quota_not_enough", http.StatusForbidden)
+			} else {
+				taskErr = service.TaskErrorWrapper(err, "reserve_user_quota_failed", http.StatusInternalServerError)
+			}
+			return
+		}
+		if !info.IsPlayground {
+			tokenReservationId, err = model.ReserveTokenQuota(info.TokenId, quota)
+			if err != nil {
+				service.ReleaseQuotaReservation(userReservationId)
+				if errors.Is(err, model.ErrQuotaNotEnough) {
+					taskErr = service.TaskErrorWrapperLocal(errors.New("token quota is not enough"), "quota_not_enough", http.StatusForbidden)
+				} else {
+					taskErr = service.TaskErrorWrapper(err, "reserve_token_quota_failed", http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+		defer func() {
+			// 成功提交的任务已在下方确认预留
+			if info.ConsumeQuota && taskErr == nil {
+				return
+			}
+			service.ReleaseQuotaReservation(userReservationId, tokenReservationId)
+		}()
 	}
 
-	// build body
-	requestBody, err := adaptor.BuildRequestBody(c, info)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "build_request_failed", http.StatusInternalServerError)
+	if !service.TaskSubmitConcurrency.TryAcquire(info.ChannelId, operation_setting.GetMaxConcurrentTasks(info.ChannelId)) {
+		taskErr = service.TaskErrorWrapperLocal(fmt.Errorf("channel #%d is at capacity", info.ChannelId), "channel_concurrency_limit", http.StatusTooManyRequests)
 		return
 	}
-	// do request
-	resp, err := adaptor.DoRequest(c, info, requestBody)
-	if err != nil {
-		taskErr = service.TaskErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
-		return
+	defer service.TaskSubmitConcurrency.Release(info.ChannelId)
+
+	resp, taskErr := doTaskRequest(c, info, adaptor)
+	// 多 key 渠道上游额度耗尽时换用下一个 key 透明重试一次
+	if taskErr != nil && info.ChannelIsMultiKey && taskErr.Code == "fail_to_fetch_task" && service.IsQuotaExhaustedError(taskErr.StatusCode, "", taskErr.Message) {
+		newKey, err := model.RotateChannelKey(info.ChannelId, info.ApiKey)
+		if err != nil {
+			logger.LogWarn(c, fmt.Sprintf("channel #%d key quota exhausted, rotate key failed: %s", info.ChannelId, err.Error()))
+		} else {
+			logger.LogWarn(c, fmt.Sprintf("channel #%d key quota exhausted, retrying with next key", info.ChannelId))
+			info.ApiKey = newKey
+			common.SetContextKey(c, constant.ContextKeyChannelKey, newKey)
+			adaptor.Init(info)
+			resp, taskErr = doTaskRequest(c, info, adaptor)
+		}
 	}
-	// handle response
-	if resp != nil && resp.StatusCode != http.StatusOK {
-		responseBody, _ := io.ReadAll(resp.Body)
-		taskErr = service.TaskErrorWrapper(fmt.Errorf("%s", string(responseBody)), "fail_to_fetch_task", resp.StatusCode)
+	if taskErr != nil {
 		return
 	}
 
@@ -252,9 +306,9 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		// release quota
 		if info.ConsumeQuota && taskErr == nil {
 
-			err := service.PostConsumeQuota(info, quota, 0, true)
+			err := service.ConfirmQuotaReservation(info, quota, userReservationId, tokenReservationId)
 			if err != nil {
-				common.SysLog("error consuming token remain quota: " + err.Error())
+				logger.LogError(c, "error confirming quota reservation: "+err.Error())
 			}
 			// Video edit: defer billing log to task completion (actual duration known then)
 			if quota != 0 && info.Action != constant.TaskActionEdit && info.Action != constant.TaskActionExtend {
@@ -285,6 +339,10 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 				if hasUserGroupRatio {
 					other["user_group_ratio"] = userGroupRatio
 				}
+				if info.IsModelMapped {
+					other["is_model_mapped"] = true
+					other["upstream_model_name"] = info.UpstreamModelName
+				}
 				if seconds, ok := info.PriceData.OtherRatios["seconds"]; ok && seconds > 0 {
 					other["xai_video_generation"] = true
 					other["xai_video_seconds"] = seconds
@@ -318,7 +376,26 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 		}
 	}()
 
+	// 每个任务使用独立的回调签名密钥，在写入响应前通过响应头返回给调用方
+	callbackURL, progressCallbackURL := getTaskCallbackURL(c, info), getTaskProgressCallbackURL(c)
+	var callbackSecret string
+	if callbackURL != "" || progressCallbackURL != "" {
+		if callbackSecret, err = common.GenerateRandomCharsKey(32); err != nil {
+			taskErr = service.TaskErrorWrapper(err, "generate_callback_secret_failed", http.StatusInternalServerError)
+			return
+		}
+		c.Header(service.TaskWebhookSecretHeader, callbackSecret)
+	}
+
+	var recorder *responseRecorder
+	if idempotencyKey != "" {
+		recorder = &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+	}
 	taskID, taskData, taskErr := adaptor.DoResponse(c, resp, info)
+	if recorder != nil {
+		c.Writer = recorder.ResponseWriter
+	}
 	if taskErr != nil {
 		return
 	}
@@ -331,16 +408,51 @@ func RelayTaskSubmit(c *gin.Context, info *relaycommon.RelayInfo) (taskErr *dto.
 	task.Quota = quota
 	task.Data = taskData
 	task.Action = info.Action
-	if info.Action == constant.TaskActionEdit || info.Action == constant.TaskActionExtend {
+	if taskReq, err := relaycommon.GetTaskRequest(c); err == nil {
+		task.Properties.Input = taskReq.Prompt
+	}
+	task.Properties.GenerateAudio = taskRequestsAudio(c)
+	task.Properties.ModelPrice = price.ModelPrice
+	task.Properties.GroupRatio = price.EffectiveGroupRatio()
+	task.Properties.OtherRatio = price.OtherRatio
+	task.PrivateData.SpendingCapModel = modelName
+	// 记录提交令牌，退款与补扣时同时调整令牌额度
+	if !info.IsPlayground {
 		task.PrivateData.TokenId = info.TokenId
 		task.PrivateData.TokenKey = info.TokenKey
 		task.PrivateData.TokenName = c.GetString("token_name")
 	}
-	err = task.Insert()
+	task.CallbackURL = callbackURL
+	task.ProgressCallbackURL = progressCallbackURL
+	task.PrivateData.CallbackSecret = callbackSecret
+	task.Tags = taskTags
+	task.RequestPath, task.RequestBody = getTaskReplayRequest(c)
+	task.ParentTaskID = common.GetContextKeyString(c, constant.ContextKeyTaskReplayParentId)
+	err = task.InsertWithReservations(userReservationId, tokenReservationId)
 	if err != nil {
 		taskErr = service.TaskErrorWrapper(err, "insert_task_failed", http.StatusInternalServerError)
 		return
 	}
+	// 任务已创建，占位标记保留至过期，期间的重复提交不会再次提交上游
+	idempotencyRecorded = true
+	if recorder != nil && recorder.Status() < http.StatusBadRequest {
+		service.DefaultTaskIdempotency.Set(idempotencyKey, &service.TaskIdempotencyRecord{
+			TaskID:      taskID,
+			StatusCode:  recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.String(),
+		})
+	}
+	events.Publish(&events.TaskSubmittedEvent{
+		TaskID:    task.TaskID,
+		Platform:  string(platform),
+		Action:    task.Action,
+		UserId:    task.UserId,
+		ChannelId: task.ChannelId,
+		ModelName: modelName,
+		Quota:     quota,
+		Timestamp: time.Now().Unix(),
+	})
 	return nil
 }
 
@@ -351,6 +463,10 @@ var fetchRespBuilders = map[int]func(c *gin.Context) (respBody []byte, taskResp
 }
 
 func RelayTaskFetch(c *gin.Context, relayMode int) (taskResp *dto.TaskError) {
+	startTime := time.Now()
+	defer func() {
+		observeTaskRequest(0, "", startTime, taskResp)
+	}()
 	respBuilder, ok := fetchRespBuilders[relayMode]
 	if !ok {
 		taskResp = service.TaskErrorWrapperLocal(errors.New("invalid_relay_mode"), "invalid_relay_mode", http.StatusBadRequest)
@@ -373,6 +489,280 @@ func RelayTaskFetch(c *gin.Context, relayMode int) (taskResp *dto.TaskError) {
 	return
 }
 
+// observeTaskRequest 记录任务提交与查询的 Prometheus 指标，查询不经过渠道，channelId 为 0
+func observeTaskRequest(channelId int, modelName string, startTime time.Time, taskErr *dto.TaskError) {
+	status := http.StatusOK
+	if taskErr != nil {
+		status = taskErr.StatusCode
+	}
+	metrics.ObserveRequest(channelId, modelName, metrics.StatusLabel(status), time.Since(startTime))
+}
+
+// taskIdempotencyKey 计算任务提交的幂等键，未启用 Redis 或无法读取请求体时返回空字符串
+func taskIdempotencyKey(c *gin.Context, info *relaycommon.RelayInfo) string {
+	if !common.RedisEnabled {
+		return ""
+	}
+	// 影子提交与主渠道提交的请求相同，不参与幂等
+	if _, ok := common.GetContextKeyType[*model.ShadowResult](c, constant.ContextKeyTaskShadowResult); ok {
+		return ""
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return ""
+	}
+	return service.DefaultTaskIdempotency.Key(info.UserId, c.Request.URL.Path, c.GetHeader(service.TaskIdempotencyKeyHeader), body)
+}
+
+// taskRequestsAudio 判断提交请求是否开启了同步音频（generate_audio 或 metadata.generate_audio）
+func taskRequestsAudio(c *gin.Context) bool {
+	if strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data") {
+		return c.PostForm("generate_audio") == "true"
+	}
+	var req struct {
+		GenerateAudio *bool `json:"generate_audio"`
+		Metadata      struct {
+			GenerateAudio *bool `json:"generate_audio"`
+		} `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return false
+	}
+	if req.GenerateAudio != nil {
+		return *req.GenerateAudio
+	}
+	return req.Metadata.GenerateAudio != nil && *req.Metadata.GenerateAudio
+}
+
+// applyTaskAutoGroup 处理 auto 分组：从 context 获取实际选中的分组
+// 当使用 auto 分组时，Distribute 中间件会将实际选中的分组存储在 ContextKeyAutoGroup 中
+func applyTaskAutoGroup(c *gin.Context, info *relaycommon.RelayInfo) {
+	if autoGroup, exists := common.GetContextKey(c, constant.ContextKeyAutoGroup); exists {
+		if groupStr, ok := autoGroup.(string); ok && groupStr != "" {
+			info.UsingGroup = groupStr
+		}
+	}
+}
+
+// moderateTaskPrompt 按分组配置审核任务 prompt，违规时返回 content_policy_violation；
+// dry run 模式只记录日志。审核接口异常时放行，避免审核服务故障影响任务提交
+func moderateTaskPrompt(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	cfg, ok := ratio_setting.GetGroupModerationConfig(info.UsingGroup)
+	if !ok {
+		return nil
+	}
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil || strings.TrimSpace(req.Prompt) == "" {
+		return nil
+	}
+	result, err := service.ModeratePrompt(c.Request.Context(), req.Prompt, cfg.Threshold)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("task prompt moderation failed: %s", err.Error()))
+		return nil
+	}
+	if !result.Violated {
+		return nil
+	}
+	categories := strings.Join(result.Categories, ", ")
+	if cfg.DryRun {
+		logger.LogWarn(c, fmt.Sprintf("task prompt flagged by moderation (dry run), group: %s, categories: %s, max_score: %.4f", info.UsingGroup, categories, result.MaxScore))
+		return nil
+	}
+	logger.LogWarn(c, fmt.Sprintf("task prompt rejected by moderation, group: %s, categories: %s, max_score: %.4f", info.UsingGroup, categories, result.MaxScore))
+	return service.TaskErrorWrapperLocal(fmt.Errorf("prompt violates content policy: %s", categories), "content_policy_violation", http.StatusBadRequest)
+}
+
+// applyTaskParamOverride 按渠道 param_override 覆盖 JSON 任务请求体，未配置时不读取请求体，非 JSON 请求体（如 multipart）保持不变
+func applyTaskParamOverride(info *relaycommon.RelayInfo, requestBody io.Reader) (io.Reader, error) {
+	if len(info.ParamOverride) == 0 || requestBody == nil {
+		return requestBody, nil
+	}
+	body, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return bytes.NewReader(body), nil
+	}
+	body, err = relaycommon.ApplyParamOverride(body, info.ParamOverride, relaycommon.BuildParamOverrideContext(info))
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(body), nil
+}
+
+// doTaskRequest 构建请求体并提交到上游，上游返回非 200 时以响应体作为错误信息
+func doTaskRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.TaskAdaptor) (*http.Response, *dto.TaskError) {
+	requestBody, err := adaptor.BuildRequestBody(c, info)
+	if err != nil {
+		if common.IsRequestBodyTooLargeError(err) {
+			return nil, service.TaskErrorWrapperLocal(err, "request_body_too_large", http.StatusRequestEntityTooLarge)
+		}
+		return nil, service.TaskErrorWrapper(err, "build_request_failed", http.StatusInternalServerError)
+	}
+	requestBody, err = applyTaskParamOverride(info, requestBody)
+	if err != nil {
+		return nil, service.TaskErrorWrapperLocal(err, "channel:param_override_invalid", http.StatusInternalServerError)
+	}
+	resp, err := adaptor.DoRequest(c, info, requestBody)
+	if err != nil {
+		if errors.Is(err, relaycommon.ErrChannelConcurrencyLimit) {
+			return nil, service.TaskErrorWrapperLocal(err, "channel_concurrency_limit", http.StatusTooManyRequests)
+		}
+		if errors.Is(err, service.ErrCircuitBreakerOpen) {
+			return nil, service.TaskErrorWrapperLocal(err, "channel:circuit_breaker_open", http.StatusServiceUnavailable)
+		}
+		return nil, service.TaskErrorWrapper(err, "do_request_failed", http.StatusInternalServerError)
+	}
+	if resp != nil && resp.StatusCode != http.StatusOK {
+		responseBody, _ := service.ReadCompressedBody(resp)
+		return nil, service.TaskErrorWrapper(fmt.Errorf("%s", string(responseBody)), "fail_to_fetch_task", resp.StatusCode)
+	}
+	if resp != nil && system_setting.GetUpstreamSetting().StrictResponseValidation {
+		if err := service.ValidateUpstreamResponse(resp, "application/json"); err != nil {
+			return nil, service.TaskErrorWrapperLocal(err, "invalid_upstream_response", http.StatusBadGateway)
+		}
+	}
+	return resp, nil
+}
+
+// deferTaskDependency 处理请求中的 depends_on_task_id：上游任务已成功时将其产出链接写入请求体后继续提交，
+// 上游任务未完成时保存为待提交的依赖任务并直接返回，上游任务已失败时拒绝请求
+func deferTaskDependency(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.TaskAdaptor) (bool, *dto.TaskError) {
+	if strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data") {
+		return false, nil
+	}
+	var req struct {
+		DependsOnTaskID string `json:"depends_on_task_id"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil || strings.TrimSpace(req.DependsOnTaskID) == "" {
+		return false, nil
+	}
+	// 暂存的请求稍后以普通任务提交，影子提交不能暂存
+	if _, ok := common.GetContextKeyType[*model.ShadowResult](c, constant.ContextKeyTaskShadowResult); ok {
+		return false, service.TaskErrorWrapperLocal(errors.New("shadow submission does not support task dependencies"), "invalid_request", http.StatusBadRequest)
+	}
+	upstream, exist, err := model.GetByTaskId(info.UserId, req.DependsOnTaskID)
+	if err != nil {
+		return false, service.TaskErrorWrapper(err, "get_dependency_task_failed", http.StatusInternalServerError)
+	}
+	if !exist {
+		return false, service.TaskErrorWrapperLocal(errors.New("dependency task not exist"), "task_not_exist", http.StatusNotFound)
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return false, service.TaskErrorWrapperLocal(err, "read_request_body_failed", http.StatusBadRequest)
+	}
+	switch upstream.Status {
+	case model.TaskStatusFailure:
+		return false, service.TaskErrorWrapperLocal(fmt.Errorf("dependency task %s failed", req.DependsOnTaskID), "dependency_task_failed", http.StatusBadRequest)
+	case model.TaskStatusSuccess:
+		body, err = service.ApplyTaskDependencyOutput(body, taskDependencyInputField(adaptor), upstream.FailReason)
+		if err != nil {
+			return false, service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+		}
+		c.Set(common.KeyRequestBody, body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		return false, nil
+	}
+	dep := &model.TaskDependency{
+		UserId:          info.UserId,
+		TokenId:         info.TokenId,
+		DependsOnTaskId: req.DependsOnTaskID,
+		RequestPath:     c.Request.URL.Path,
+		RequestBody:     string(body),
+		ClientIp:        c.ClientIP(),
+		InputField:      taskDependencyInputField(adaptor),
+	}
+	if err := dep.Insert(); err != nil {
+		return false, service.TaskErrorWrapper(err, "insert_task_dependency_failed", http.StatusInternalServerError)
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"id":                 dep.Id,
+		"object":             "task.dependency",
+		"status":             dep.Status,
+		"depends_on_task_id": dep.DependsOnTaskId,
+	})
+	return true, nil
+}
+
+// taskDependencyInputField 返回上游任务产出链接写入的请求字段：优先使用管理员配置，
+// 其次由适配器指定，默认写入通用的 image 字段
+func taskDependencyInputField(adaptor channel.TaskAdaptor) string {
+	if field := operation_setting.GetDependencyInputField(); field != "" {
+		return field
+	}
+	if inputAdaptor, ok := adaptor.(channel.TaskDependencyInputAdaptor); ok {
+		return inputAdaptor.DependencyInputField()
+	}
+	return "image"
+}
+
+// loadConversationHistory 读取请求中的 previous_task_id，将引用任务的提示词和生成结果
+// 作为历史轮次加入 ConversationHistory，引用的任务必须属于当前用户且已成功
+func loadConversationHistory(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	var req struct {
+		PreviousTaskID string `json:"previous_task_id"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil || strings.TrimSpace(req.PreviousTaskID) == "" {
+		return nil
+	}
+	previousTask, exist, err := model.GetByTaskId(info.UserId, req.PreviousTaskID)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "get_previous_task_failed", http.StatusInternalServerError)
+	}
+	if !exist {
+		return service.TaskErrorWrapperLocal(errors.New("previous task not exist"), "task_not_exist", http.StatusNotFound)
+	}
+	if previousTask.Status != model.TaskStatusSuccess {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("previous task %s is not finished successfully", req.PreviousTaskID), "invalid_request", http.StatusBadRequest)
+	}
+	adaptor := GetTaskAdaptor(previousTask.Platform)
+	if adaptor == nil {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("invalid api platform: %s", previousTask.Platform), "invalid_api_platform", http.StatusBadRequest)
+	}
+	result, err := adaptor.ParseTaskResult(previousTask.Data)
+	if err != nil {
+		return service.TaskErrorWrapper(err, "parse_previous_task_failed", http.StatusInternalServerError)
+	}
+	videoURI := result.RemoteUrl
+	if videoURI == "" {
+		videoURI = result.Url
+	}
+	if videoURI == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("previous task %s has no video output", req.PreviousTaskID), "invalid_request", http.StatusBadRequest)
+	}
+
+	messages := make([]dto.TaskMessage, 0, 2)
+	if previousTask.Properties.Input != "" {
+		messages = append(messages, dto.TaskMessage{Role: "user", Content: previousTask.Properties.Input})
+	}
+	messages = append(messages, dto.TaskMessage{Role: "model", VideoURI: videoURI})
+	info.ConversationHistory = append(messages, info.ConversationHistory...)
+	return nil
+}
+
+// taskInputMediaQuota 计算 xAI 视频任务适配器设置的输入图片/视频附加额度
+func taskInputMediaQuota(c *gin.Context, price service.TaskPriceData) int {
+	quota := 0
+	// xAI input image billing (additive, set by xAI video task adaptor)
+	xaiInputImageCount := c.GetInt("xai_input_image_count")
+	xaiInputImagePrice := c.GetFloat64("xai_input_image_price")
+	if xaiInputImageCount > 0 && xaiInputImagePrice > 0 {
+		quota += int(xaiInputImagePrice * float64(xaiInputImageCount) * price.EffectiveGroupRatio() * common.QuotaPerUnit)
+	}
+
+	// xAI input video billing (additive, set by xAI video task adaptor for video edits)
+	xaiInputVideoSeconds := c.GetFloat64("xai_input_video_seconds")
+	xaiInputVideoPrice := c.GetFloat64("xai_input_video_price")
+	if xaiInputVideoSeconds > 0 && xaiInputVideoPrice > 0 {
+		quota += int(xaiInputVideoPrice * xaiInputVideoSeconds * price.EffectiveGroupRatio() * common.QuotaPerUnit)
+	}
+	return quota
+}
+
 func sunoFetchRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *dto.TaskError) {
 	userId := c.GetInt("id")
 	var condition = struct {
@@ -414,7 +804,7 @@ func sunoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *dt
 		return
 	}
 	if !exist {
-		taskResp = service.TaskErrorWrapperLocal(errors.New("task_not_exist"), "task_not_exist", http.StatusBadRequest)
+		taskResp = service.TaskErrorWrapperLocal(errors.New("task_not_exist"), "task_not_exist", http.StatusNotFound)
 		return
 	}
 
@@ -438,7 +828,7 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 		return
 	}
 	if !exist {
-		taskResp = service.TaskErrorWrapperLocal(errors.New("task_not_exist"), "task_not_exist", http.StatusBadRequest)
+		taskResp = service.TaskErrorWrapperLocal(errors.New("task_not_exist"), "task_not_exist", http.StatusNotFound)
 		return
 	}
 
@@ -448,7 +838,7 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 		}
 		channelModel, err2 := model.GetChannelById(originTask.ChannelId, true)
 		if err2 != nil {
-			common.SysLog(fmt.Sprintf("[video-poll] GetChannelById(%d) failed: %v", originTask.ChannelId, err2))
+			logger.LogError(c, fmt.Sprintf("[video-poll] GetChannelById(%d) failed: %v", originTask.ChannelId, err2))
 			return
 		}
 		if channelModel.Type != constant.ChannelTypeVertexAi && channelModel.Type != constant.ChannelTypeGemini && channelModel.Type != constant.ChannelTypeXai {
@@ -461,7 +851,7 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 		proxy := channelModel.GetSetting().Proxy
 		adaptor := GetTaskAdaptor(constant.TaskPlatform(strconv.Itoa(channelModel.Type)))
 		if adaptor == nil {
-			common.SysLog(fmt.Sprintf("[video-poll] GetTaskAdaptor(%d) returned nil", channelModel.Type))
+			logger.LogInfo(c, fmt.Sprintf("[video-poll] GetTaskAdaptor(%d) returned nil", channelModel.Type))
 			return
 		}
 		resp, err2 := adaptor.FetchTask(baseURL, channelModel.Key, map[string]any{
@@ -469,18 +859,18 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 			"action":  originTask.Action,
 		}, proxy)
 		if err2 != nil {
-			common.SysLog(fmt.Sprintf("[video-poll] FetchTask(%s) failed: %v", originTask.TaskID, err2))
+			logger.LogError(c, fmt.Sprintf("[video-poll] FetchTask(%s) failed: %v", originTask.TaskID, err2))
 			return
 		}
 		if resp == nil {
 			return
 		}
 		defer resp.Body.Close()
-		body, err2 := io.ReadAll(resp.Body)
+		body, err2 := service.ReadCompressedBody(resp)
 		if err2 != nil {
 			return
 		}
-		common.SysLog(fmt.Sprintf("[video-poll] task=%s resp=%s", originTask.TaskID, string(body)))
+		logger.LogInfo(c, fmt.Sprintf("[video-poll] task=%s resp=%s", originTask.TaskID, string(body)))
 		ti, err2 := adaptor.ParseTaskResult(body)
 		if err2 == nil && ti != nil {
 			now := time.Now().Unix()
@@ -611,23 +1001,153 @@ func videoFetchByIDRespBodyBuilder(c *gin.Context) (respBody []byte, taskResp *d
 	return
 }
 
+// getTaskCallbackURL 优先使用请求体中的 callback_url，其次使用渠道设置的 task_callback_url
+func getTaskCallbackURL(c *gin.Context, info *relaycommon.RelayInfo) string {
+	var req struct {
+		CallbackURL string `json:"callback_url"`
+		Metadata    struct {
+			CallbackURL string `json:"callback_url"`
+		} `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err == nil {
+		if req.CallbackURL != "" {
+			return req.CallbackURL
+		}
+		if req.Metadata.CallbackURL != "" {
+			return req.Metadata.CallbackURL
+		}
+	}
+	if info.ChannelMeta != nil {
+		return info.ChannelSetting.TaskCallbackURL
+	}
+	return ""
+}
+
+// getTaskProgressCallbackURL 读取请求体或 metadata 中的 progress_callback_url
+func getTaskProgressCallbackURL(c *gin.Context) string {
+	var req struct {
+		ProgressCallbackURL string `json:"progress_callback_url"`
+		Metadata            struct {
+			ProgressCallbackURL string `json:"progress_callback_url"`
+		} `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return ""
+	}
+	if req.ProgressCallbackURL != "" {
+		return req.ProgressCallbackURL
+	}
+	return req.Metadata.ProgressCallbackURL
+}
+
+// getTaskTags 读取并校验请求体或 metadata 中的 tags
+func getTaskTags(c *gin.Context) (model.TaskTags, error) {
+	var req struct {
+		Tags     []string `json:"tags"`
+		Metadata struct {
+			Tags []string `json:"tags"`
+		} `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return nil, nil
+	}
+	tags := req.Tags
+	if len(tags) == 0 {
+		tags = req.Metadata.Tags
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return model.NormalizeTaskTags(tags)
+}
+
+// maxTaskReplayRequestBytes 为重放保存的请求体大小上限，超出时该任务不支持重放
+const maxTaskReplayRequestBytes = 256 << 10
+
+// getTaskReplayRequest 返回需要为重放保存的请求路径与请求体。只保存 /v1 下的 JSON 请求，
+// multipart 请求、remix 以及经过请求转换的 kling、即梦接口不支持重放
+func getTaskReplayRequest(c *gin.Context) (string, json.RawMessage) {
+	path := c.Request.URL.Path
+	if !strings.HasPrefix(path, "/v1/") || strings.HasSuffix(path, "/remix") {
+		return "", nil
+	}
+	if !strings.HasPrefix(c.ContentType(), "application/json") {
+		return "", nil
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil || len(body) == 0 || len(body) > maxTaskReplayRequestBytes || !json.Valid(body) {
+		return "", nil
+	}
+	return path, json.RawMessage(body)
+}
+
+// BuildTaskWebhookPayload 构造任务回调内容，与 /v1/videos/{task_id} 的返回保持一致
+func BuildTaskWebhookPayload(task *model.Task) ([]byte, error) {
+	if adaptor := GetTaskAdaptor(task.Platform); adaptor != nil {
+		if converter, ok := adaptor.(channel.OpenAIVideoConverter); ok {
+			return converter.ConvertToOpenAIVideo(task)
+		}
+	}
+	return json.Marshal(dto.TaskResponse[any]{
+		Code: "success",
+		Data: TaskModel2Dto(task),
+	})
+}
+
 func TaskModel2Dto(task *model.Task) *dto.TaskDto {
 	return &dto.TaskDto{
-		TaskID:     task.TaskID,
-		Action:     task.Action,
-		Status:     string(task.Status),
-		FailReason: task.FailReason,
-		SubmitTime: task.SubmitTime,
-		StartTime:  task.StartTime,
-		FinishTime: task.FinishTime,
-		Progress:   task.Progress,
-		Data:       task.Data,
+		TaskID:        task.TaskID,
+		Action:        task.Action,
+		Status:        string(task.Status),
+		FailReason:    task.FailReason,
+		SubmitTime:    task.SubmitTime,
+		StartTime:     task.StartTime,
+		FinishTime:    task.FinishTime,
+		Progress:      task.Progress,
+		ExpiresAt:     task.ExpiresAt(),
+		StorageURL:    service.GetSignedURLProxy().TaskStorageURL(task),
+		QualityScores: task.Properties.QualityScores,
+		ThumbnailURL:  service.GetSignedURLProxy().TaskThumbnailURL(task),
+		Tags:          task.Tags,
+		ParentTaskID:  task.ParentTaskID,
+		Data:          task.Data,
 	}
 }
 
-// applyTaskModelMapping 处理任务请求的模型映射
-// 从渠道配置的 model_mapping 中获取映射关系，将原始模型名映射到上游模型名
+// applyTaskModelMapping 处理任务请求的模型映射：先应用渠道 model_mapping，再查询全局模型别名
 func applyTaskModelMapping(c *gin.Context, info *relaycommon.RelayInfo) error {
+	if err := applyChannelTaskModelMapping(c, info); err != nil {
+		return err
+	}
+	return applyTaskModelAlias(c, info)
+}
+
+// applyTaskModelAlias 将渠道映射后的模型名按全局模型别名替换为目标模型
+func applyTaskModelAlias(c *gin.Context, info *relaycommon.RelayInfo) error {
+	currentModel := info.OriginModelName
+	if info.IsModelMapped {
+		currentModel = info.UpstreamModelName
+	}
+	target, ok, err := model.ResolveModelAlias(currentModel, info.ChannelType)
+	if err != nil {
+		return fmt.Errorf("resolve model alias failed: %w", err)
+	}
+	if !ok || target == currentModel {
+		return nil
+	}
+	info.IsModelMapped = true
+	info.UpstreamModelName = target
+	logger.LogInfo(c, fmt.Sprintf("Task model alias: %s -> %s", currentModel, target))
+	return nil
+}
+
+// applyChannelTaskModelMapping 从渠道配置的 model_mapping 中获取映射关系，将原始模型名映射到上游模型名
+func applyChannelTaskModelMapping(c *gin.Context, info *relaycommon.RelayInfo) error {
+	// 用户自定义模型先替换为上游模型 ID，再应用渠道映射
+	if info.UserModelUpstream != "" {
+		info.IsModelMapped = true
+		info.UpstreamModelName = info.UserModelUpstream
+	}
 	modelMapping := c.GetString("model_mapping")
 	if modelMapping == "" || modelMapping == "{}" {
 		return nil
@@ -640,6 +1160,9 @@ func applyTaskModelMapping(c *gin.Context, info *relaycommon.RelayInfo) error {
 
 	// 支持链式模型重定向，最终使用链尾的模型
 	currentModel := info.OriginModelName
+	if info.UserModelUpstream != "" {
+		currentModel = info.UserModelUpstream
+	}
 	visitedModels := map[string]bool{
 		currentModel: true,
 	}
@@ -670,8 +1193,52 @@ func applyTaskModelMapping(c *gin.Context, info *relaycommon.RelayInfo) error {
 
 	if info.IsModelMapped {
 		info.UpstreamModelName = currentModel
-		common.SysLog(fmt.Sprintf("Task model mapping: %s -> %s", info.OriginModelName, info.UpstreamModelName))
+		logger.LogInfo(c, fmt.Sprintf("Task model mapping: %s -> %s", info.OriginModelName, info.UpstreamModelName))
 	}
 
 	return nil
 }
+
+// TaskQuotaEstimate 任务提交前的额度预估结果
+type TaskQuotaEstimate struct {
+	ModelName      string             `json:"model_name"`
+	EstimatedQuota int                `json:"estimated_quota"`
+	GroupRatio     float64            `json:"group_ratio"`
+	ModelPrice     float64            `json:"model_price"`
+	OtherRatios    map[string]float64 `json:"other_ratios,omitempty"`
+}
+
+// EstimateTaskQuota 使用与 RelayTaskSubmit 相同的校验与计费逻辑预估任务额度，不请求上游也不扣费
+func EstimateTaskQuota(c *gin.Context, info *relaycommon.RelayInfo) (*TaskQuotaEstimate, *dto.TaskError) {
+	info.InitChannelMeta(c)
+	if info.TaskRelayInfo == nil {
+		info.TaskRelayInfo = &relaycommon.TaskRelayInfo{}
+	}
+	platform := GetTaskPlatform(c)
+	adaptor := GetTaskAdaptor(platform)
+	if adaptor == nil {
+		return nil, service.TaskErrorWrapperLocal(fmt.Errorf("invalid api platform: %s", platform), "invalid_api_platform", http.StatusBadRequest)
+	}
+	adaptor.Init(info)
+	if err := applyTaskModelMapping(c, info); err != nil {
+		return nil, service.TaskErrorWrapperLocal(err, "model_mapping_failed", http.StatusBadRequest)
+	}
+	if taskErr := adaptor.ValidateRequestAndSetAction(c, info); taskErr != nil {
+		return nil, taskErr
+	}
+
+	modelName := info.OriginModelName
+	if modelName == "" {
+		modelName = service.CoverTaskActionToModelName(platform, info.Action)
+	}
+	applyTaskAutoGroup(c, info)
+
+	price := service.ComputeTaskPrice(info, modelName)
+	return &TaskQuotaEstimate{
+		ModelName:      modelName,
+		EstimatedQuota: price.Quota + taskInputMediaQuota(c, price),
+		GroupRatio:     price.EffectiveGroupRatio(),
+		ModelPrice:     price.ModelPrice,
+		OtherRatios:    info.PriceData.OtherRatios,
+	}, nil
+}
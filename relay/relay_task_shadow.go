@@ -0,0 +1,35 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// submitShadowTask 将影子模式镜像的请求提交到影子渠道。影子提交不向用户扣费、不写入任务表，
+// 上游受理后按主渠道相同价格计算的额度写入对比结果并计入影子渠道已用额度
+func submitShadowTask(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.TaskAdaptor, result *model.ShadowResult, quota int) *dto.TaskError {
+	if !service.TaskSubmitConcurrency.TryAcquire(info.ChannelId, operation_setting.GetMaxConcurrentTasks(info.ChannelId)) {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("channel #%d is at capacity", info.ChannelId), "channel_concurrency_limit", http.StatusTooManyRequests)
+	}
+	defer service.TaskSubmitConcurrency.Release(info.ChannelId)
+
+	resp, taskErr := doTaskRequest(c, info, adaptor)
+	if taskErr != nil {
+		return taskErr
+	}
+	// 上游已受理，无论响应能否解析都已产生费用
+	result.ShadowQuota = quota
+	model.UpdateChannelUsedQuota(info.ChannelId, quota)
+	taskID, _, taskErr := adaptor.DoResponse(c, resp, info)
+	result.ShadowTaskId = taskID
+	return taskErr
+}
@@ -0,0 +1,42 @@
+package relay
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/relay/channel"
+	"github.com/QuantumNous/new-api/relay/channel/testutil"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// concurrencyLimitedAdaptor 模拟渠道并发已满时 DoTaskApiRequest 返回的错误
+type concurrencyLimitedAdaptor struct {
+	channel.TaskAdaptor
+}
+
+func (concurrencyLimitedAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	return strings.NewReader(`{}`), nil
+}
+
+func (concurrencyLimitedAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return nil, relaycommon.ErrChannelConcurrencyLimit
+}
+
+func TestDoTaskRequestConcurrencyLimitCode(t *testing.T) {
+	c := testutil.NewTaskContext("/v1/video/generations", `{}`)
+	_, taskErr := doTaskRequest(c, testutil.NewTaskRelayInfo(), concurrencyLimitedAdaptor{})
+	if taskErr == nil {
+		t.Fatal("expected a concurrency limit error")
+	}
+	if taskErr.Code != string(types.ErrorCodeChannelConcurrencyLimit) || taskErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got code %q status %d, want %q 429", taskErr.Code, taskErr.StatusCode, types.ErrorCodeChannelConcurrencyLimit)
+	}
+	if taskErr.Code != "channel_concurrency_limit" {
+		t.Fatalf("unexpected code %q", taskErr.Code)
+	}
+}
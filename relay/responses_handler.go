@@ -97,6 +97,11 @@ func ResponsesHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		}
 	}
 
+	if monitor := service.NewStreamQuotaMonitor(c, info); monitor != nil {
+		info.StreamQuota = monitor
+		defer monitor.Finish()
+	}
+
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
 	if newAPIError != nil {
 		// reset status code 重置状态码
@@ -0,0 +1,380 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 200
+	maxUsernameLen  = 20
+)
+
+var userNameFilterPattern = regexp.MustCompile(`(?i)^\s*userName\s+eq\s+"([^"]*)"\s*$`)
+
+// ToScimUser 将 model.User 转换为 SCIM 用户：email 对应 userName 与 emails，显示名称（未设置时为用户名）对应 displayName，
+// 用户状态对应 active，用户分组对应 groups
+func ToScimUser(user *model.User) *User {
+	active := user.Status == common.UserStatusEnabled
+	scimUser := &User{
+		Schemas:     []string{SchemaUser},
+		Id:          strconv.Itoa(user.Id),
+		UserName:    user.Email,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: "User",
+			Location:     fmt.Sprintf("/scim/v2/Users/%d", user.Id),
+		},
+	}
+	if scimUser.UserName == "" {
+		scimUser.UserName = user.Username
+	}
+	if scimUser.DisplayName == "" {
+		scimUser.DisplayName = user.Username
+	}
+	if user.Email != "" {
+		scimUser.Emails = []Email{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Group != "" {
+		scimUser.Groups = []GroupRef{{Value: user.Group, Display: user.Group}}
+	}
+	return scimUser
+}
+
+// ServiceProviderConfigHandler GET /scim/v2/ServiceProviderConfig
+func ServiceProviderConfigHandler(c *gin.Context) {
+	writeJSON(c, http.StatusOK, ServiceProviderConfig{
+		Schemas: []string{SchemaServiceProviderConfig},
+		Patch:   supported{Supported: true},
+		Filter:  filterSupported{Supported: true, MaxResults: maxPageSize},
+		AuthenticationSchemes: []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with the access token of an administrator",
+			Primary:     true,
+		}},
+	})
+}
+
+// ListUsers GET /scim/v2/Users，仅支持 userName eq "..." 过滤
+func ListUsers(c *gin.Context) {
+	var userName string
+	if filter := c.Query("filter"); filter != "" {
+		matches := userNameFilterPattern.FindStringSubmatch(filter)
+		if matches == nil {
+			AbortWithError(c, http.StatusBadRequest, "invalidFilter", "only 'userName eq' filter is supported")
+			return
+		}
+		userName = matches[1]
+	}
+	startIndex, _ := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", strconv.Itoa(defaultPageSize)))
+	if err != nil || count < 0 {
+		count = defaultPageSize
+	}
+	count = min(count, maxPageSize)
+
+	users, total, err := model.FindScimUsers(userName, startIndex-1, count)
+	if err != nil {
+		AbortWithError(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	resources := make([]*User, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, ToScimUser(user))
+	}
+	writeJSON(c, http.StatusOK, ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// GetUser GET /scim/v2/Users/:id
+func GetUser(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok {
+		return
+	}
+	writeJSON(c, http.StatusOK, ToScimUser(user))
+}
+
+// CreateUser POST /scim/v2/Users，用户名取自 displayName，未提供时取邮箱前缀，重名时追加随机后缀
+func CreateUser(c *gin.Context) {
+	var req User
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	email := req.PrimaryEmail()
+	if email == "" && strings.Contains(req.UserName, "@") {
+		email = req.UserName
+	}
+	if email == "" {
+		AbortWithError(c, http.StatusBadRequest, "invalidValue", "an email address is required")
+		return
+	}
+	if model.IsEmailAlreadyTaken(email) {
+		AbortWithError(c, http.StatusConflict, "uniqueness", "a user with this email already exists")
+		return
+	}
+	user := &model.User{
+		Username:    uniqueUsername(req.DisplayName, email),
+		DisplayName: truncateUsername(req.DisplayName),
+		Email:       email,
+		Password:    common.GetRandomString(16),
+		Role:        common.RoleCommonUser,
+		Status:      common.UserStatusEnabled,
+		Group:       "default",
+	}
+	if req.Active != nil && !*req.Active {
+		user.Status = common.UserStatusDisabled
+	}
+	if group, ok := resolveGroup(req.Groups); ok {
+		user.Group = group
+	}
+	if err := user.Insert(0); err != nil {
+		AbortWithError(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Header("Location", fmt.Sprintf("/scim/v2/Users/%d", user.Id))
+	writeJSON(c, http.StatusCreated, ToScimUser(user))
+}
+
+// PatchUser PATCH /scim/v2/Users/:id
+func PatchUser(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok || !checkManageable(c, user) {
+		return
+	}
+	var req PatchRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		AbortWithError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return
+	}
+	for _, op := range req.Operations {
+		if err := ApplyPatchOperation(user, op); err != nil {
+			AbortWithError(c, http.StatusBadRequest, "invalidValue", err.Error())
+			return
+		}
+	}
+	if err := user.Update(false); err != nil {
+		AbortWithError(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	writeJSON(c, http.StatusOK, ToScimUser(user))
+}
+
+// DeleteUser DELETE /scim/v2/Users/:id，软删除用户
+func DeleteUser(c *gin.Context) {
+	user, ok := loadUser(c)
+	if !ok || !checkManageable(c, user) {
+		return
+	}
+	if err := user.Delete(); err != nil {
+		AbortWithError(c, http.StatusInternalServerError, "", err.Error())
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ApplyPatchOperation 将单个 PATCH 操作应用到用户上，支持 active、displayName、userName、emails 与 groups，
+// 未指定 path 时 value 为属性集合
+func ApplyPatchOperation(user *model.User, op PatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	if opName != "add" && opName != "replace" && opName != "remove" {
+		return fmt.Errorf("unsupported patch op: %s", op.Op)
+	}
+	if op.Path == "" {
+		attrs, ok := op.Value.(map[string]any)
+		if !ok {
+			return errors.New("patch value must be an object when path is empty")
+		}
+		for path, value := range attrs {
+			if err := applyPatchPath(user, opName, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return applyPatchPath(user, opName, op.Path, op.Value)
+}
+
+func applyPatchPath(user *model.User, op string, path string, value any) error {
+	attr := strings.ToLower(path)
+	if i := strings.IndexAny(attr, "[."); i >= 0 {
+		attr = attr[:i]
+	}
+	switch attr {
+	case "active":
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		if active {
+			user.Status = common.UserStatusEnabled
+		} else {
+			user.Status = common.UserStatusDisabled
+		}
+	case "displayname":
+		if name, ok := value.(string); ok && op != "remove" {
+			user.DisplayName = truncateUsername(name)
+		}
+	case "username", "emails":
+		if op == "remove" {
+			return nil
+		}
+		if email := patchEmailValue(value); email != "" && email != user.Email {
+			if model.IsEmailAlreadyTaken(email) {
+				return fmt.Errorf("email %s is already taken", email)
+			}
+			user.Email = email
+		}
+	case "groups":
+		if op == "remove" {
+			user.Group = "default"
+			return nil
+		}
+		if group, ok := resolveGroup(patchGroupRefs(value)); ok {
+			user.Group = group
+		}
+	case "externalid", "name", "title", "locale", "timezone":
+		// 不存储的属性
+	default:
+		return fmt.Errorf("unsupported patch path: %s", path)
+	}
+	return nil
+}
+
+func parseBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.ToLower(v))
+	}
+	return false, fmt.Errorf("invalid boolean value: %v", value)
+}
+
+func patchEmailValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		var emails []Email
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				email, _ := m["value"].(string)
+				primary, _ := m["primary"].(bool)
+				emails = append(emails, Email{Value: email, Primary: primary})
+			}
+		}
+		return (&User{Emails: emails}).PrimaryEmail()
+	}
+	return ""
+}
+
+func patchGroupRefs(value any) []GroupRef {
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	var refs []GroupRef
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			refs = append(refs, GroupRef{Value: v})
+		case map[string]any:
+			ref := GroupRef{}
+			ref.Value, _ = v["value"].(string)
+			ref.Display, _ = v["display"].(string)
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// resolveGroup 返回第一个在系统中存在的分组，按 display 优先、value 其次匹配
+func resolveGroup(refs []GroupRef) (string, bool) {
+	for _, ref := range refs {
+		for _, name := range []string{ref.Display, ref.Value} {
+			if name != "" && ratio_setting.ContainsGroupRatio(name) {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+func truncateUsername(name string) string {
+	name = strings.TrimSpace(name)
+	for utf8.RuneCountInString(name) > maxUsernameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+func uniqueUsername(displayName string, email string) string {
+	base := truncateUsername(strings.ReplaceAll(displayName, " ", ""))
+	if base == "" {
+		base = truncateUsername(strings.SplitN(email, "@", 2)[0])
+	}
+	if base != "" && !model.IsUsernameAlreadyTaken(base) {
+		return base
+	}
+	for {
+		suffix := "_" + common.GetRandomString(4)
+		runes := []rune(base)
+		name := string(runes[:min(len(runes), maxUsernameLen-len(suffix))]) + suffix
+		if !model.IsUsernameAlreadyTaken(name) {
+			return name
+		}
+	}
+}
+
+func loadUser(c *gin.Context) (*model.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		writeNotFound(c)
+		return nil, false
+	}
+	user, err := model.GetUserById(id, false)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeNotFound(c)
+		} else {
+			AbortWithError(c, http.StatusInternalServerError, "", err.Error())
+		}
+		return nil, false
+	}
+	return user, true
+}
+
+// checkManageable 与后台管理一致，不允许修改同级或更高权限的用户
+func checkManageable(c *gin.Context, user *model.User) bool {
+	if user.Role >= c.GetInt("role") {
+		AbortWithError(c, http.StatusForbidden, "", "cannot manage a user with the same or higher role")
+		return false
+	}
+	return true
+}
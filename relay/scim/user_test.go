@@ -0,0 +1,70 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestApplyPatchOperation(t *testing.T) {
+	user := &model.User{Id: 1, Username: "alice", Email: "alice@example.com", Status: common.UserStatusEnabled, Group: "default"}
+
+	// Azure AD 以字符串形式发送布尔值
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "Replace", Path: "active", Value: "False"}); err != nil {
+		t.Fatalf("patch active failed: %v", err)
+	}
+	if user.Status != common.UserStatusDisabled {
+		t.Fatalf("status = %d, want disabled", user.Status)
+	}
+
+	// Okta 不带 path，value 为属性集合
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "replace", Value: map[string]any{"active": true, "displayName": "Alice Liddell"}}); err != nil {
+		t.Fatalf("patch without path failed: %v", err)
+	}
+	if user.Status != common.UserStatusEnabled || user.DisplayName != "Alice Liddell" {
+		t.Fatalf("unexpected user after patch: %+v", user)
+	}
+
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "add", Path: "groups", Value: []any{
+		map[string]any{"value": "00g1", "display": "unknown"},
+		map[string]any{"value": "00g2", "display": "vip"},
+	}}); err != nil {
+		t.Fatalf("patch groups failed: %v", err)
+	}
+	if user.Group != "vip" {
+		t.Fatalf("group = %s, want vip", user.Group)
+	}
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "remove", Path: "groups"}); err != nil {
+		t.Fatalf("remove groups failed: %v", err)
+	}
+	if user.Group != "default" {
+		t.Fatalf("group = %s, want default", user.Group)
+	}
+
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "replace", Path: "nickName", Value: "x"}); err == nil {
+		t.Fatal("expected error for unsupported path")
+	}
+	if err := ApplyPatchOperation(user, PatchOperation{Op: "move", Path: "active", Value: true}); err == nil {
+		t.Fatal("expected error for unsupported op")
+	}
+}
+
+func TestToScimUser(t *testing.T) {
+	scimUser := ToScimUser(&model.User{Id: 7, Username: "bob", Status: common.UserStatusDisabled, Group: "svip"})
+	if scimUser.Id != "7" || scimUser.UserName != "bob" || scimUser.DisplayName != "bob" {
+		t.Fatalf("unexpected scim user: %+v", scimUser)
+	}
+	if scimUser.Active == nil || *scimUser.Active {
+		t.Fatal("disabled user should be inactive")
+	}
+	if len(scimUser.Groups) != 1 || scimUser.Groups[0].Value != "svip" {
+		t.Fatalf("unexpected groups: %+v", scimUser.Groups)
+	}
+}
+
+func TestTruncateUsername(t *testing.T) {
+	if got := truncateUsername("  一二三四五六七八九十一二三四五六七八九十一二  "); got != "一二三四五六七八九十一二三四五六七八九十" {
+		t.Fatalf("truncateUsername = %q", got)
+	}
+}
@@ -18,6 +18,9 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/status", controller.GetStatus)
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
+		apiRouter.POST("/models/register", middleware.UserAuth(), controller.RegisterUserModel)
+		apiRouter.GET("/models/registered", middleware.UserAuth(), controller.GetSelfUserModels)
+		apiRouter.DELETE("/models/registered/:id", middleware.UserAuth(), controller.DeleteSelfUserModel)
 		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
 		apiRouter.GET("/notice", controller.GetNotice)
 		apiRouter.GET("/user-agreement", controller.GetUserAgreement)
@@ -44,6 +47,12 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.POST("/stripe/webhook", controller.StripeWebhook)
 		apiRouter.POST("/creem/webhook", controller.CreemWebhook)
 
+		paymentRoute := apiRouter.Group("/payments")
+		{
+			paymentRoute.GET("/packages", middleware.UserAuth(), controller.GetStripeQuotaPackages)
+			paymentRoute.POST("/create-checkout-session", middleware.UserAuth(), middleware.CriticalRateLimit(), controller.CreateStripeCheckoutSession)
+		}
+
 		// Universal secure verification routes
 		apiRouter.POST("/verify", middleware.UserAuth(), middleware.CriticalRateLimit(), controller.UniversalVerify)
 		apiRouter.GET("/verify/status", middleware.UserAuth(), controller.GetVerificationStatus)
@@ -140,6 +149,8 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
 			channelRoute.GET("/:id", controller.GetChannel)
+			channelRoute.GET("/:id/health", controller.GetChannelHealth)
+			channelRoute.GET("/:id/shadow", controller.GetChannelShadowResults)
 			channelRoute.POST("/:id/key", middleware.RootAuth(), middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.GetChannelKey)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
@@ -171,9 +182,12 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.GET("/", controller.GetAllTokens)
 			tokenRoute.GET("/search", controller.SearchTokens)
 			tokenRoute.GET("/:id", controller.GetToken)
+			tokenRoute.GET("/:id/usage", controller.GetTokenUsageById)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
 			tokenRoute.DELETE("/:id", controller.DeleteToken)
+			tokenRoute.PATCH("/:id/scopes", controller.UpdateTokenScopes)
+			tokenRoute.PUT("/:id/allow_ips", controller.UpdateTokenAllowIps)
 			tokenRoute.POST("/batch", controller.DeleteTokenBatch)
 		}
 
@@ -203,9 +217,12 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
+		logRoute.GET("/self/cost-centers", middleware.UserAuth(), controller.GetSelfCostCenterReport)
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
+		logRoute.GET("/self/:id/billing-detail", middleware.UserAuth(), controller.GetUserLogBillingDetail)
+		logRoute.GET("/:id/billing-detail", middleware.AdminAuth(), controller.GetLogBillingDetail)
 
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
@@ -230,6 +247,15 @@ func SetApiRouter(router *gin.Engine) {
 			prefillGroupRoute.DELETE("/:id", controller.DeletePrefillGroup)
 		}
 
+		channelGroupRoute := apiRouter.Group("/channel_group")
+		channelGroupRoute.Use(middleware.AdminAuth())
+		{
+			channelGroupRoute.GET("/", controller.GetChannelGroups)
+			channelGroupRoute.POST("/", controller.CreateChannelGroup)
+			channelGroupRoute.PUT("/", controller.UpdateChannelGroup)
+			channelGroupRoute.DELETE("/:id", controller.DeleteChannelGroup)
+		}
+
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
@@ -238,6 +264,37 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserTask)
 			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.PATCH("/:id/priority", middleware.AdminAuth(), controller.UpdateTaskPriority)
+		}
+
+		adminRoute := apiRouter.Group("/admin")
+		adminRoute.Use(middleware.AdminAuth())
+		{
+			adminRoute.GET("/event-stream", controller.GetEventStream)
+			adminRoute.GET("/dashboard", controller.GetChannelDashboard)
+			adminRoute.POST("/channels/benchmark", controller.BenchmarkChannels)
+			adminRoute.POST("/channels/:id/warm-up", controller.WarmUpChannel)
+			adminRoute.GET("/channels/:id/warmup-history", controller.GetChannelWarmupHistory)
+			adminRoute.POST("/channels/:id/clone", controller.CloneChannel)
+			adminRoute.POST("/quota/transfer", controller.TransferQuota)
+			adminRoute.GET("/quota/transfer-history", controller.GetQuotaTransferHistory)
+			adminRoute.GET("/users/:id/spending-caps", controller.GetUserSpendingCaps)
+			adminRoute.POST("/users/:id/spending-caps", controller.SetUserSpendingCap)
+			adminRoute.GET("/model-aliases", controller.GetModelAliases)
+			adminRoute.POST("/model-aliases", controller.CreateModelAlias)
+			adminRoute.PUT("/model-aliases/:id", controller.UpdateModelAlias)
+			adminRoute.DELETE("/model-aliases/:id", controller.DeleteModelAlias)
+			adminRoute.GET("/user-models", controller.GetAllUserModels)
+			adminRoute.POST("/user-models/:id/approve", controller.ApproveUserModel)
+			adminRoute.POST("/user-models/:id/reject", controller.RejectUserModel)
+			adminRoute.GET("/analytics/tasks", controller.GetTaskAnalytics)
+			adminRoute.GET("/analytics/tasks/export", controller.ExportTaskAnalytics)
+			adminRoute.GET("/reports/cost-centers", controller.GetCostCenterReport)
+			adminRoute.GET("/billing/discrepancies", controller.GetBillingDiscrepancies)
+			adminRoute.POST("/tasks/cancel-bulk", controller.CancelTasksBulk)
+			adminRoute.DELETE("/tokens/:id", middleware.TokenInvalidationRateLimit(), controller.AdminInvalidateToken)
+			adminRoute.PUT("/tokens/:id/cors", controller.UpdateTokenCors)
+			adminRoute.PUT("/tokens/:id/allow_ips", controller.UpdateTokenAllowIps)
 		}
 
 		vendorRoute := apiRouter.Group("/vendors")
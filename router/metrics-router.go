@@ -0,0 +1,12 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/metrics"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	router.GET("/metrics", middleware.MetricsAuth(), gin.WrapH(metrics.Handler()))
+}
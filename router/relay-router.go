@@ -55,26 +55,34 @@ func SetRelayRouter(router *gin.Engine) {
 		})
 	}
 
+	// 错误目录供 SDK 开发者查询，无需鉴权
+	router.GET("/v1/error-codes", controller.GetErrorCodes)
+
 	playgroundRouter := router.Group("/pg")
-	playgroundRouter.Use(middleware.UserAuth(), middleware.Distribute())
+	playgroundRouter.Use(middleware.UserAuth(), middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.Distribute())
 	{
 		playgroundRouter.POST("/chat/completions", controller.Playground)
 	}
 	relayV1Router := router.Group("/v1")
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
+	{
+		// 令牌泄露时由持有者自行作废当前令牌
+		relayV1Router.DELETE("/tokens/self", middleware.TokenInvalidationRateLimit(), controller.InvalidateSelfToken)
+	}
 	{
 		// WebSocket 路由（统一到 Relay）
 		wsRouter := relayV1Router.Group("")
-		wsRouter.Use(middleware.Distribute())
+		wsRouter.Use(middleware.Distribute(), middleware.GroupRateLimit())
 		wsRouter.GET("/realtime", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
 	}
 	{
 		//http router
+		// 请求体大小限制需在 Distribute 读取请求体之前生效，按路由模板选择上限
 		httpRouter := relayV1Router.Group("")
-		httpRouter.Use(middleware.Distribute())
+		httpRouter.Use(middleware.MaxBodySizeByRoute(constant.MaxTextRequestBodyMB, relayBodySizeLimits(httpRouter.BasePath())), middleware.Distribute(), middleware.GroupRateLimit())
 
 		// claude related routes
 		httpRouter.POST("/messages", func(c *gin.Context) {
@@ -104,6 +112,9 @@ func SetRelayRouter(router *gin.Engine) {
 		httpRouter.POST("/images/edits", func(c *gin.Context) {
 			controller.Relay(c, types.RelayFormatOpenAIImage)
 		})
+		httpRouter.POST("/images/variations", func(c *gin.Context) {
+			controller.Relay(c, types.RelayFormatOpenAIImage)
+		})
 
 		// embedding related routes
 		httpRouter.POST("/embeddings", func(c *gin.Context) {
@@ -140,7 +151,6 @@ func SetRelayRouter(router *gin.Engine) {
 		})
 
 		// not implemented
-		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
 		httpRouter.GET("/files", controller.RelayNotImplemented)
 		httpRouter.POST("/files", controller.RelayNotImplemented)
 		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
@@ -162,7 +172,7 @@ func SetRelayRouter(router *gin.Engine) {
 	//relayMjRouter.Use()
 
 	relaySunoRouter := router.Group("/suno")
-	relaySunoRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relaySunoRouter.Use(middleware.TokenAuth(), middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		relaySunoRouter.POST("/submit/:action", controller.RelayTask)
 		relaySunoRouter.POST("/fetch", controller.RelayTask)
@@ -172,7 +182,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relayGeminiRouter := router.Group("/v1beta")
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
-	relayGeminiRouter.Use(middleware.Distribute())
+	relayGeminiRouter.Use(middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
 		relayGeminiRouter.POST("/models/*path", func(c *gin.Context) {
@@ -181,9 +191,26 @@ func SetRelayRouter(router *gin.Engine) {
 	}
 }
 
+// relayBodySizeLimits 图片与 multipart 路由的请求体上限（MB），其余路由使用 MAX_TEXT_REQUEST_BODY_MB
+func relayBodySizeLimits(basePath string) map[string]int {
+	limits := map[string]int{
+		"/edits":                constant.MaxImageRequestBodyMB,
+		"/images/generations":   constant.MaxImageRequestBodyMB,
+		"/images/edits":         constant.MaxMultipartRequestBodyMB,
+		"/images/variations":    constant.MaxMultipartRequestBodyMB,
+		"/audio/transcriptions": constant.MaxMultipartRequestBodyMB,
+		"/audio/translations":   constant.MaxMultipartRequestBodyMB,
+	}
+	routes := make(map[string]int, len(limits))
+	for route, mb := range limits {
+		routes[basePath+route] = mb
+	}
+	return routes
+}
+
 func registerMjRouterGroup(relayMjRouter *gin.RouterGroup) {
 	relayMjRouter.GET("/image/:id", relay.RelayMidjourneyImage)
-	relayMjRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+	relayMjRouter.Use(middleware.TokenAuth(), middleware.MaxBodySizeMB(constant.MaxImageRequestBodyMB), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		relayMjRouter.POST("/submit/action", controller.RelayMidjourney)
 		relayMjRouter.POST("/submit/shorten", controller.RelayMidjourney)
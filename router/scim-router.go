@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/relay/scim"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SetScimRouter(router *gin.Engine) {
+	scimRouter := router.Group("/scim/v2")
+	scimRouter.Use(middleware.ScimAuth())
+	{
+		scimRouter.GET("/ServiceProviderConfig", scim.ServiceProviderConfigHandler)
+		scimRouter.GET("/Users", scim.ListUsers)
+		scimRouter.POST("/Users", scim.CreateUser)
+		scimRouter.GET("/Users/:id", scim.GetUser)
+		scimRouter.PATCH("/Users/:id", scim.PatchUser)
+		scimRouter.DELETE("/Users/:id", scim.DeleteUser)
+	}
+}
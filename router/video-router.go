@@ -1,6 +1,7 @@
 package router
 
 import (
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/middleware"
 
@@ -9,17 +10,23 @@ import (
 
 func SetVideoRouter(router *gin.Engine) {
 	videoV1Router := router.Group("/v1")
-	videoV1Router.Use(middleware.TokenAuth(), middleware.Distribute())
+	videoV1Router.Use(middleware.TokenAuth(), middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		videoV1Router.GET("/videos/:task_id/content", controller.VideoProxy)
+		videoV1Router.GET("/videos/:task_id/stream", controller.VideoTaskStream)
+		videoV1Router.GET("/videos/:task_id/audio", controller.VideoAudio)
 		videoV1Router.POST("/video/generations", controller.RelayTask)
 		videoV1Router.GET("/video/generations/:task_id", controller.RelayTask)
 		videoV1Router.POST("/videos/:video_id/remix", controller.RelayTask)
+		videoV1Router.POST("/estimate", controller.EstimateTask)
 	}
 	// openai compatible API video routes
 	// docs: https://platform.openai.com/docs/api-reference/videos/create
+	// 创建接口支持 multipart 上传参考图
+	videoMultipartRouter := router.Group("/v1")
+	videoMultipartRouter.Use(middleware.TokenAuth(), middleware.MaxBodySizeMB(constant.MaxMultipartRequestBodyMB), middleware.Distribute(), middleware.GroupRateLimit())
 	{
-		videoV1Router.POST("/videos", controller.RelayTask)
+		videoMultipartRouter.POST("/videos", controller.RelayTask)
 		videoV1Router.GET("/videos/:task_id", controller.RelayTask)
 	}
 	// xAI native video routes
@@ -32,8 +39,33 @@ func SetVideoRouter(router *gin.Engine) {
 		videoV1Router.POST("/videos/extensions", controller.RelayTask)
 	}
 
+	// 批量提交的请求体为数组，由处理函数逐个进行渠道分发
+	videoBatchRouter := router.Group("/v1")
+	videoBatchRouter.Use(middleware.TokenAuth(), middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB))
+	{
+		videoBatchRouter.POST("/videos/batch", controller.RelayVideoBatch)
+	}
+
+	// 用户任务列表只读数据库，不需要渠道分发
+	taskListRouter := router.Group("/v1")
+	taskListRouter.Use(middleware.TokenAuth())
+	{
+		taskListRouter.GET("/tasks", controller.ListUserTasks)
+		taskListRouter.GET("/tasks/dependencies/:id", controller.GetTaskDependency)
+		taskListRouter.PATCH("/tasks/:id/tags", controller.UpdateUserTaskTags)
+		taskListRouter.POST("/tasks/:id/replay", controller.ReplayUserTask)
+		taskListRouter.POST("/tasks/:id/cancel", controller.CancelUserTask)
+	}
+
+	// 视频文件上传以流式转存到 GCS，不经过会缓存请求体的 Distribute
+	fileUploadRouter := router.Group("/v1")
+	fileUploadRouter.Use(middleware.TokenAuth(), middleware.VideoUploadRateLimit())
+	{
+		fileUploadRouter.POST("/files/upload", controller.UploadVideoFile)
+	}
+
 	klingV1Router := router.Group("/kling/v1")
-	klingV1Router.Use(middleware.KlingRequestConvert(), middleware.TokenAuth(), middleware.Distribute())
+	klingV1Router.Use(middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.KlingRequestConvert(), middleware.TokenAuth(), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		klingV1Router.POST("/videos/text2video", controller.RelayTask)
 		klingV1Router.POST("/videos/image2video", controller.RelayTask)
@@ -43,7 +75,7 @@ func SetVideoRouter(router *gin.Engine) {
 
 	// Jimeng official API routes - direct mapping to official API format
 	jimengOfficialGroup := router.Group("jimeng")
-	jimengOfficialGroup.Use(middleware.JimengRequestConvert(), middleware.TokenAuth(), middleware.Distribute())
+	jimengOfficialGroup.Use(middleware.MaxBodySizeMB(constant.MaxTextRequestBodyMB), middleware.JimengRequestConvert(), middleware.TokenAuth(), middleware.Distribute(), middleware.GroupRateLimit())
 	{
 		// Maps to: /?Action=CVSync2AsyncSubmitTask&Version=2022-08-31 and /?Action=CVSync2AsyncGetResult&Version=2022-08-31
 		jimengOfficialGroup.POST("/", controller.RelayTask)
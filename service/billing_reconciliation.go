@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+)
+
+// BillingReconciliationJob 每天在固定时间核对自上次对账以来完成的任务：按提交时记录的模型价格、分组倍率与附加倍率
+// 计算预期额度，与任务最终扣费（预扣费及完成时的多退少补）比较，偏差超过阈值的写入 BillingDiscrepancy
+type BillingReconciliationJob struct {
+	// RunAt 每天执行对账的时间，为相对当天零点（本地时区）的偏移
+	RunAt time.Duration
+	// Window 从未对账时首次对账覆盖的任务完成时间范围
+	Window time.Duration
+	// RetryInterval 对账失败后重试的间隔
+	RetryInterval time.Duration
+
+	once sync.Once
+	now  func() time.Time
+}
+
+var DefaultBillingReconciliationJob = &BillingReconciliationJob{RunAt: 3 * time.Hour, Window: 24 * time.Hour, RetryInterval: time.Hour, now: time.Now}
+
+// Run 在主节点上每天定时执行对账，启动时若错过了最近一次计划时间则立即补跑
+func (j *BillingReconciliationJob) Run() {
+	if !common.IsMasterNode {
+		return
+	}
+	j.once.Do(func() {
+		for {
+			time.Sleep(j.untilNextRun())
+			count, err := j.RunOnce(context.Background())
+			if err != nil {
+				common.SysError("billing reconciliation failed: " + err.Error())
+				time.Sleep(j.RetryInterval)
+				continue
+			}
+			if count > 0 {
+				common.SysLog(fmt.Sprintf("billing reconciliation found %d discrepancies", count))
+			}
+		}
+	})
+}
+
+// untilNextRun 返回距下次对账的等待时间。上次对账的窗口结束早于最近一次计划时间（如服务重启错过了对账）时返回 0
+func (j *BillingReconciliationJob) untilNextRun() time.Duration {
+	now := j.now()
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Add(j.RunAt)
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+	lastEnd, err := model.GetLastBillingReconciliationEnd()
+	if err != nil {
+		common.SysError("failed to query last billing reconciliation: " + err.Error())
+	} else if lastEnd < scheduled.Unix() {
+		return 0
+	}
+	return scheduled.AddDate(0, 0, 1).Sub(now)
+}
+
+// RunOnce 核对上次对账窗口结束至今完成的任务（从未对账时为最近 Window 内），记录本次窗口并返回新发现的差异数
+func (j *BillingReconciliationJob) RunOnce(ctx context.Context) (int, error) {
+	end := j.now().Unix()
+	start, err := model.GetLastBillingReconciliationEnd()
+	if err != nil {
+		return 0, err
+	}
+	if start == 0 {
+		start = end - int64(j.Window.Seconds())
+	}
+	threshold := float64(constant.BillingDiscrepancyThresholdPercent)
+	var afterId int64
+	count := 0
+	for {
+		tasks, err := model.GetFinishedTasksForReconciliation(start, end, afterId, constant.TaskQueryLimit)
+		if err != nil {
+			return count, err
+		}
+		if len(tasks) == 0 {
+			break
+		}
+		afterId = tasks[len(tasks)-1].ID
+
+		var discrepancies []*model.BillingDiscrepancy
+		for _, task := range tasks {
+			if discrepancy := reconcileTaskBilling(task, threshold); discrepancy != nil {
+				discrepancy.CreatedAt = end
+				discrepancies = append(discrepancies, discrepancy)
+			}
+		}
+		if err := model.CreateBillingDiscrepancies(discrepancies); err != nil {
+			return count, err
+		}
+		count += len(discrepancies)
+		if len(tasks) < constant.TaskQueryLimit {
+			break
+		}
+	}
+	run := &model.BillingReconciliationRun{WindowStart: start, WindowEnd: end, Discrepancies: count, CreatedAt: end}
+	return count, run.Insert()
+}
+
+// ExpectedTaskQuota 按任务提交时记录的计费参数计算预期额度，未记录价格的任务返回 false
+func ExpectedTaskQuota(task *model.Task) (int, bool) {
+	props := task.Properties
+	if props.ModelPrice <= 0 {
+		return 0, false
+	}
+	otherRatio := props.OtherRatio
+	if otherRatio == 0 {
+		otherRatio = 1
+	}
+	return int(props.ModelPrice * props.GroupRatio * otherRatio * common.QuotaPerUnit), true
+}
+
+// reconcileTaskBilling 比较任务的预期额度与实际扣费，偏差超过阈值（百分比）时返回差异记录
+func reconcileTaskBilling(task *model.Task, threshold float64) *model.BillingDiscrepancy {
+	expected, ok := ExpectedTaskQuota(task)
+	if !ok || expected == task.Quota {
+		return nil
+	}
+	var deviation float64
+	if expected == 0 {
+		// 免费分组等预期为 0 的任务产生了扣费
+		deviation = 100
+	} else {
+		deviation = float64(task.Quota-expected) / float64(expected) * 100
+	}
+	if math.Abs(deviation) <= threshold {
+		return nil
+	}
+	return &model.BillingDiscrepancy{
+		TaskRecordId:     task.ID,
+		TaskId:           task.TaskID,
+		UserId:           task.UserId,
+		ChannelId:        task.ChannelId,
+		ModelName:        task.Properties.OriginModelName,
+		ModelPrice:       task.Properties.ModelPrice,
+		GroupRatio:       task.Properties.GroupRatio,
+		OtherRatio:       task.Properties.OtherRatio,
+		ExpectedQuota:    expected,
+		ActualQuota:      task.Quota,
+		DeviationPercent: math.Round(deviation*100) / 100,
+		TaskFinishTime:   task.FinishTime,
+	}
+}
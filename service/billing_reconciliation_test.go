@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestReconcileTaskBilling(t *testing.T) {
+	props := model.Properties{OriginModelName: "sora-2", ModelPrice: 0.1, GroupRatio: 1, OtherRatio: 2}
+	expected := int(0.2 * common.QuotaPerUnit)
+	cases := []struct {
+		name  string
+		task  *model.Task
+		found bool
+	}{
+		{"matches", &model.Task{Quota: expected, Properties: props}, false},
+		{"within threshold", &model.Task{Quota: expected * 104 / 100, Properties: props}, false},
+		{"overcharged", &model.Task{Quota: expected * 2, Properties: props}, true},
+		{"undercharged", &model.Task{Quota: expected / 2, Properties: props}, true},
+		{"price not recorded", &model.Task{Quota: 1}, false},
+	}
+	for _, tc := range cases {
+		discrepancy := reconcileTaskBilling(tc.task, 5)
+		if (discrepancy != nil) != tc.found {
+			t.Errorf("%s: discrepancy = %+v, want found %v", tc.name, discrepancy, tc.found)
+			continue
+		}
+		if discrepancy != nil && (discrepancy.ExpectedQuota != expected || discrepancy.ModelName != "sora-2") {
+			t.Errorf("%s: unexpected discrepancy %+v", tc.name, discrepancy)
+		}
+	}
+	if d := reconcileTaskBilling(&model.Task{Quota: expected / 2, Properties: props}, 5); d.DeviationPercent != -50 {
+		t.Errorf("deviation = %v, want -50", d.DeviationPercent)
+	}
+}
+
+func setupBillingReconciliationTest(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.Task{}, &model.BillingDiscrepancy{}, &model.BillingReconciliationRun{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originLimit := model.DB, constant.TaskQueryLimit
+	model.DB, constant.TaskQueryLimit = db, 100
+	t.Cleanup(func() { model.DB, constant.TaskQueryLimit = originDB, originLimit })
+	return db
+}
+
+func TestBillingReconciliationResumesFromLastWindow(t *testing.T) {
+	db := setupBillingReconciliationTest(t)
+	now := time.Date(2026, 10, 16, 3, 0, 0, 0, time.Local)
+	job := &BillingReconciliationJob{RunAt: 3 * time.Hour, Window: 24 * time.Hour, now: func() time.Time { return now }}
+
+	props := model.Properties{OriginModelName: "sora-2", ModelPrice: 0.1, GroupRatio: 1}
+	for i, finished := range []time.Time{now.Add(-30 * time.Hour), now.Add(-time.Hour)} {
+		task := &model.Task{TaskID: fmt.Sprintf("task_%d", i), Status: model.TaskStatusSuccess, FinishTime: finished.Unix(), Quota: 1, Properties: props}
+		if err := db.Create(task).Error; err != nil {
+			t.Fatalf("create task failed: %v", err)
+		}
+	}
+
+	// 首次对账只覆盖最近 Window 内完成的任务
+	if count, err := job.RunOnce(context.Background()); err != nil || count != 1 {
+		t.Fatalf("first run = %d, %v, want 1", count, err)
+	}
+
+	// 两天未对账后从上次窗口结束处继续，期间完成的任务都被覆盖
+	task := &model.Task{TaskID: "task_late", Status: model.TaskStatusSuccess, FinishTime: now.Add(40 * time.Hour).Unix(), Quota: 1, Properties: props}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatalf("create task failed: %v", err)
+	}
+	now = now.Add(48 * time.Hour)
+	if count, err := job.RunOnce(context.Background()); err != nil || count != 1 {
+		t.Fatalf("second run = %d, %v, want 1", count, err)
+	}
+	var runs []model.BillingReconciliationRun
+	db.Order("id").Find(&runs)
+	if len(runs) != 2 || runs[1].WindowStart != runs[0].WindowEnd || runs[1].WindowEnd != now.Unix() {
+		t.Fatalf("unexpected runs %+v", runs)
+	}
+}
+
+func TestBillingReconciliationUntilNextRun(t *testing.T) {
+	db := setupBillingReconciliationTest(t)
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local)
+	job := &BillingReconciliationJob{RunAt: 3 * time.Hour, now: func() time.Time { return now }}
+
+	// 从未对账时立即执行
+	if wait := job.untilNextRun(); wait != 0 {
+		t.Fatalf("wait = %s, want 0 before the first run", wait)
+	}
+	// 已覆盖今天 03:00 的计划时间，等到明天 03:00
+	db.Create(&model.BillingReconciliationRun{WindowEnd: now.Add(-6 * time.Hour).Unix()})
+	if wait := job.untilNextRun(); wait != 17*time.Hour {
+		t.Fatalf("wait = %s, want 17h", wait)
+	}
+	// 重启后发现错过了今天的计划时间，立即补跑
+	db.Where("1 = 1").Delete(&model.BillingReconciliationRun{})
+	db.Create(&model.BillingReconciliationRun{WindowEnd: now.Add(-20 * time.Hour).Unix()})
+	if wait := job.untilNextRun(); wait != 0 {
+		t.Fatalf("wait = %s, want 0 after a missed run", wait)
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelCapabilityResolver 返回渠道适配器声明的能力，ok 为 false 表示适配器未声明；
+// 由 controller 注册，避免 service 依赖 relay
+var ChannelCapabilityResolver func(channel *model.Channel) (capabilities []string, ok bool)
+
+// GetChannelCapabilities 返回渠道的能力标签：优先使用管理员配置的标签，其次使用适配器声明的能力，
+// 两者都没有时 ok 为 false，表示能力未知
+func GetChannelCapabilities(channel *model.Channel) ([]string, bool) {
+	if tags := channel.GetCapabilityTags(); len(tags) > 0 {
+		return tags, true
+	}
+	if ChannelCapabilityResolver == nil {
+		return nil, false
+	}
+	return ChannelCapabilityResolver(channel)
+}
+
+// ChannelSupportsCapabilities 判断渠道是否具备全部所需能力，能力未知的渠道视为满足以保持兼容
+func ChannelSupportsCapabilities(channel *model.Channel, required []string) bool {
+	capabilities, ok := GetChannelCapabilities(channel)
+	if !ok {
+		return true
+	}
+	for _, capability := range required {
+		if !slices.Contains(capabilities, capability) {
+			return false
+		}
+	}
+	return true
+}
+
+// capabilityFilter 根据请求所需能力构造渠道过滤条件，无要求时返回 nil
+func capabilityFilter(c *gin.Context) model.ChannelFilter {
+	if c == nil {
+		return nil
+	}
+	required := common.GetContextKeyStringSlice(c, constant.ContextKeyRequiredCapabilities)
+	if len(required) == 0 {
+		return nil
+	}
+	return func(channel *model.Channel) bool {
+		return ChannelSupportsCapabilities(channel, required)
+	}
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestChannelSupportsCapabilities(t *testing.T) {
+	origin := ChannelCapabilityResolver
+	t.Cleanup(func() { ChannelCapabilityResolver = origin })
+	ChannelCapabilityResolver = func(channel *model.Channel) ([]string, bool) {
+		switch channel.Type {
+		case constant.ChannelTypeOpenAI:
+			return []string{constant.CapabilityVision, constant.CapabilityStreaming}, true
+		case constant.ChannelTypeDeepSeek:
+			return []string{constant.CapabilityStreaming}, true
+		}
+		return nil, false
+	}
+
+	textOnly := "streaming"
+	vision := []string{constant.CapabilityVision}
+	cases := []struct {
+		name    string
+		channel *model.Channel
+		want    bool
+	}{
+		{"adaptor advertises vision", &model.Channel{Type: constant.ChannelTypeOpenAI}, true},
+		{"adaptor lacks vision", &model.Channel{Type: constant.ChannelTypeDeepSeek}, false},
+		{"explicit tags override adaptor", &model.Channel{Type: constant.ChannelTypeOpenAI, CapabilityTags: &textOnly}, false},
+		{"unknown capabilities stay eligible", &model.Channel{Type: constant.ChannelTypeCustom}, true},
+	}
+	for _, tc := range cases {
+		if got := ChannelSupportsCapabilities(tc.channel, vision); got != tc.want {
+			t.Errorf("%s: ChannelSupportsCapabilities = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ChannelConcurrency 按渠道统计进行中的请求数
+type ChannelConcurrency struct {
+	counters sync.Map // channelId -> *atomic.Int64
+}
+
+// TaskSubmitConcurrency 统计各渠道正在提交中的任务
+var TaskSubmitConcurrency = &ChannelConcurrency{}
+
+func (cc *ChannelConcurrency) counter(channelId int) *atomic.Int64 {
+	if v, ok := cc.counters.Load(channelId); ok {
+		return v.(*atomic.Int64)
+	}
+	v, _ := cc.counters.LoadOrStore(channelId, &atomic.Int64{})
+	return v.(*atomic.Int64)
+}
+
+// TryAcquire 占用一个并发名额，limit <= 0 表示不限制；超过上限时返回 false 且不占用名额
+func (cc *ChannelConcurrency) TryAcquire(channelId int, limit int) bool {
+	c := cc.counter(channelId)
+	n := c.Add(1)
+	if limit > 0 && n > int64(limit) {
+		c.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (cc *ChannelConcurrency) Release(channelId int) {
+	cc.counter(channelId).Add(-1)
+}
+
+func (cc *ChannelConcurrency) InFlight(channelId int) int64 {
+	return cc.counter(channelId).Load()
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChannelConcurrencyLimitNeverExceeded(t *testing.T) {
+	cc := &ChannelConcurrency{}
+	const (
+		channelId = 7
+		limit     = 3
+		workers   = 64
+	)
+	var (
+		wg       sync.WaitGroup
+		peak     atomic.Int64
+		current  atomic.Int64
+		admitted atomic.Int64
+		rejected atomic.Int64
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !cc.TryAcquire(channelId, limit) {
+				rejected.Add(1)
+				return
+			}
+			admitted.Add(1)
+			n := current.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			current.Add(-1)
+			cc.Release(channelId)
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > limit {
+		t.Fatalf("in-flight peak %d exceeded limit %d", peak.Load(), limit)
+	}
+	if admitted.Load()+rejected.Load() != workers {
+		t.Fatalf("expected %d attempts, got %d", workers, admitted.Load()+rejected.Load())
+	}
+	if cc.InFlight(channelId) != 0 {
+		t.Fatalf("expected no in-flight tasks after release, got %d", cc.InFlight(channelId))
+	}
+}
+
+func TestChannelConcurrencyUnlimited(t *testing.T) {
+	cc := &ChannelConcurrency{}
+	for i := 0; i < 10; i++ {
+		if !cc.TryAcquire(1, 0) {
+			t.Fatal("expected unlimited channel to always admit")
+		}
+	}
+	if cc.InFlight(1) != 10 {
+		t.Fatalf("expected 10 in-flight, got %d", cc.InFlight(1))
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	channelDashboardCacheKey = "channel_dashboard:aggregates"
+	// ChannelDashboardCacheTTL 渠道概览聚合结果在 Redis 中的缓存时间
+	ChannelDashboardCacheTTL = 30 * time.Second
+	// channelDashboardLastSuccessLookback 最近成功请求时间只在该范围内的消费日志中查找
+	channelDashboardLastSuccessLookback = 24 * time.Hour
+)
+
+// ChannelDashboardItem 管理后台渠道概览中单个渠道的实时状态
+type ChannelDashboardItem struct {
+	Id            int     `json:"id"`
+	Name          string  `json:"name"`
+	Type          int     `json:"type"`
+	Status        int     `json:"status"`
+	KeyCount      int     `json:"key_count"`
+	InFlightTasks int64   `json:"in_flight_tasks"`
+	Requests      int64   `json:"requests"`
+	ErrorRate     float64 `json:"error_rate"`
+	EstimatedQPM  float64 `json:"estimated_qpm"`
+	LastSuccessAt int64   `json:"last_success_at"`
+}
+
+// ChannelDashboard 渠道概览，Requests 与 ErrorRate 统计 [WindowStart, GeneratedAt] 内的请求
+type ChannelDashboard struct {
+	GeneratedAt int64                   `json:"generated_at"`
+	WindowStart int64                   `json:"window_start"`
+	Channels    []*ChannelDashboardItem `json:"channels"`
+}
+
+// channelDashboardAggregates 需要缓存的聚合查询结果
+type channelDashboardAggregates struct {
+	GeneratedAt   int64                             `json:"generated_at"`
+	WindowStart   int64                             `json:"window_start"`
+	InFlightTasks map[int]int64                     `json:"in_flight_tasks"`
+	RequestStats  map[int]*model.ChannelRequestStat `json:"request_stats"`
+	LastSuccessAt map[int]int64                     `json:"last_success_at"`
+}
+
+// GetChannelDashboard 返回所有启用或被自动禁用渠道的实时状态。渠道状态每次实时读取，
+// 任务数、错误率与最近成功时间等聚合结果在启用 Redis 时缓存 ChannelDashboardCacheTTL
+func GetChannelDashboard() (*ChannelDashboard, error) {
+	channels, err := model.GetChannelDashboardChannels()
+	if err != nil {
+		return nil, err
+	}
+	aggregates, err := getChannelDashboardAggregates()
+	if err != nil {
+		return nil, err
+	}
+
+	// 错误率与 QPM 基于上一个完整健康窗口起至今的请求，时长在 1 到 2 个窗口之间
+	minutes := float64(aggregates.GeneratedAt-aggregates.WindowStart) / 60
+	dashboard := &ChannelDashboard{
+		GeneratedAt: aggregates.GeneratedAt,
+		WindowStart: aggregates.WindowStart,
+		Channels:    make([]*ChannelDashboardItem, 0, len(channels)),
+	}
+	for _, channel := range channels {
+		if channel.Status == common.ChannelStatusManuallyDisabled {
+			continue
+		}
+		item := &ChannelDashboardItem{
+			Id:            channel.Id,
+			Name:          channel.Name,
+			Type:          channel.Type,
+			Status:        channel.Status,
+			KeyCount:      channel.GetKeyCount(),
+			InFlightTasks: aggregates.InFlightTasks[channel.Id],
+			LastSuccessAt: aggregates.LastSuccessAt[channel.Id],
+		}
+		if stat := aggregates.RequestStats[channel.Id]; stat != nil && stat.Total > 0 {
+			item.Requests = stat.Total
+			item.ErrorRate = float64(stat.Errors) / float64(stat.Total)
+			if minutes > 0 {
+				item.EstimatedQPM = float64(stat.Total) / minutes
+			}
+		}
+		dashboard.Channels = append(dashboard.Channels, item)
+	}
+	return dashboard, nil
+}
+
+func getChannelDashboardAggregates() (*channelDashboardAggregates, error) {
+	if common.RedisEnabled {
+		val, err := common.RedisGet(channelDashboardCacheKey)
+		if err == nil {
+			var aggregates channelDashboardAggregates
+			if err := common.UnmarshalJsonStr(val, &aggregates); err == nil {
+				return &aggregates, nil
+			}
+		} else if err != redis.Nil {
+			common.SysLog("failed to get channel dashboard cache: " + err.Error())
+		}
+	}
+
+	aggregates, err := queryChannelDashboardAggregates(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if common.RedisEnabled {
+		if data, err := common.Marshal(aggregates); err == nil {
+			if err := common.RedisSet(channelDashboardCacheKey, string(data), ChannelDashboardCacheTTL); err != nil {
+				common.SysLog("failed to set channel dashboard cache: " + err.Error())
+			}
+		}
+	}
+	return aggregates, nil
+}
+
+// queryChannelDashboardAggregates 并发执行三个聚合查询，每个查询都是按渠道分组的单条 SQL
+func queryChannelDashboardAggregates(now time.Time) (*channelDashboardAggregates, error) {
+	window := int64(ChannelHealthWindow / time.Second)
+	aggregates := &channelDashboardAggregates{
+		GeneratedAt: now.Unix(),
+		WindowStart: now.Unix() - now.Unix()%window - window,
+	}
+	var g errgroup.Group
+	g.Go(func() (err error) {
+		aggregates.InFlightTasks, err = model.GetInFlightTaskCountByChannel()
+		return err
+	})
+	g.Go(func() (err error) {
+		aggregates.RequestStats, err = model.GetChannelRequestStatsSince(aggregates.WindowStart)
+		return err
+	})
+	g.Go(func() (err error) {
+		aggregates.LastSuccessAt, err = model.GetLastConsumeTimeByChannel(now.Add(-channelDashboardLastSuccessLookback).Unix())
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return aggregates, nil
+}
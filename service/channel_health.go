@@ -0,0 +1,260 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/notify"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	ChannelHealthWindow          = 5 * time.Minute
+	channelHealthFlushInterval   = 30 * time.Second
+	channelHealthRetention       = 7 * 24 * time.Hour
+	channelHealthCleanupInterval = time.Hour
+)
+
+type channelHealthKey struct {
+	channelId   int
+	errorCode   string
+	windowStart int64
+}
+
+// channelHealthWindow 单个渠道当前窗口在本节点内的统计，用于判断是否需要告警
+type channelHealthWindow struct {
+	windowStart int64
+	total       int64
+	errors      int64
+	errorCodes  map[string]int64
+	alerted     bool
+}
+
+// ChannelHealthMonitor 按 5 分钟窗口聚合各渠道的请求结果，定期写入 ChannelHealthEvent，
+// 并在错误率超过阈值时告警、按配置自动禁用渠道。告警基于本节点的统计判断。
+type ChannelHealthMonitor struct {
+	Window time.Duration
+	// SenderFunc 返回当前使用的告警发送器，为空表示不发送告警
+	SenderFunc func() notify.AlertSender
+
+	mu      sync.Mutex
+	pending map[channelHealthKey]int64
+	windows map[int]*channelHealthWindow
+	now     func() time.Time
+}
+
+var DefaultChannelHealthMonitor = NewChannelHealthMonitor(ChannelHealthWindow)
+
+func NewChannelHealthMonitor(window time.Duration) *ChannelHealthMonitor {
+	return &ChannelHealthMonitor{
+		Window:     window,
+		SenderFunc: defaultChannelAlertSender,
+		pending:    make(map[channelHealthKey]int64),
+		windows:    make(map[int]*channelHealthWindow),
+		now:        time.Now,
+	}
+}
+
+// defaultChannelAlertSender 根据监控设置构建邮件与 webhook 告警发送器
+func defaultChannelAlertSender() notify.AlertSender {
+	setting := operation_setting.GetMonitorSetting()
+	var senders notify.MultiAlertSender
+	if setting.ChannelHealthAlertEmail != "" {
+		senders = append(senders, &notify.EmailAlertSender{Receiver: setting.ChannelHealthAlertEmail})
+	}
+	if setting.ChannelHealthAlertWebhookUrl != "" {
+		senders = append(senders, &notify.WebhookAlertSender{
+			URL:    setting.ChannelHealthAlertWebhookUrl,
+			Secret: setting.ChannelHealthAlertWebhookSecret,
+			Client: GetHttpClient(),
+		})
+	}
+	if len(senders) == 0 {
+		return nil
+	}
+	return senders
+}
+
+// RecordSuccess 记录一次成功请求
+func (m *ChannelHealthMonitor) RecordSuccess(channelId int) {
+	m.record(channelId, model.ChannelHealthSuccessCode)
+}
+
+// RecordError 记录一次渠道错误
+func (m *ChannelHealthMonitor) RecordError(channelId int, err *types.NewAPIError) {
+	code := string(err.GetErrorCode())
+	if code == "" {
+		code = fmt.Sprintf("status_%d", err.StatusCode)
+	}
+	m.record(channelId, code)
+}
+
+func (m *ChannelHealthMonitor) windowStart(t time.Time) int64 {
+	return t.Truncate(m.Window).Unix()
+}
+
+func (m *ChannelHealthMonitor) record(channelId int, code string) {
+	if channelId == 0 {
+		return
+	}
+	windowStart := m.windowStart(m.now())
+
+	m.mu.Lock()
+	m.pending[channelHealthKey{channelId: channelId, errorCode: code, windowStart: windowStart}]++
+	w, ok := m.windows[channelId]
+	if !ok || w.windowStart != windowStart {
+		w = &channelHealthWindow{windowStart: windowStart, errorCodes: make(map[string]int64)}
+		m.windows[channelId] = w
+	}
+	w.total++
+	if code != model.ChannelHealthSuccessCode {
+		w.errors++
+		w.errorCodes[code]++
+	}
+	alert := m.checkThreshold(channelId, w)
+	m.mu.Unlock()
+
+	if alert != nil {
+		gopool.Go(func() {
+			m.handleAlert(alert)
+		})
+	}
+}
+
+// checkThreshold 判断窗口是否超过告警阈值，每个窗口最多告警一次，调用方需持有锁
+func (m *ChannelHealthMonitor) checkThreshold(channelId int, w *channelHealthWindow) *notify.ChannelAlert {
+	setting := operation_setting.GetMonitorSetting()
+	if !setting.ChannelHealthAlertEnabled || w.alerted || setting.ChannelHealthErrorRateThreshold <= 0 {
+		return nil
+	}
+	if w.total < int64(setting.ChannelHealthMinRequests) {
+		return nil
+	}
+	rate := float64(w.errors) / float64(w.total)
+	if rate < setting.ChannelHealthErrorRateThreshold {
+		return nil
+	}
+	w.alerted = true
+	codes := make(map[string]int64, len(w.errorCodes))
+	for k, v := range w.errorCodes {
+		codes[k] = v
+	}
+	return &notify.ChannelAlert{
+		ChannelId:   channelId,
+		WindowStart: w.windowStart,
+		WindowEnd:   w.windowStart + int64(m.Window/time.Second),
+		Total:       w.total,
+		Errors:      w.errors,
+		ErrorRate:   rate,
+		ErrorCodes:  codes,
+	}
+}
+
+func (m *ChannelHealthMonitor) handleAlert(alert *notify.ChannelAlert) {
+	channel, err := model.CacheGetChannel(alert.ChannelId)
+	if err == nil && channel != nil {
+		alert.ChannelName = channel.Name
+		// 多 key 渠道无法确定具体 key，只告警不禁用
+		if operation_setting.GetMonitorSetting().ChannelHealthAutoDisable && !channel.ChannelInfo.IsMultiKey &&
+			channel.Status == common.ChannelStatusEnabled {
+			reason := fmt.Sprintf("错误率 %.1f%% 超过阈值", alert.ErrorRate*100)
+			alert.Disabled = model.UpdateChannelStatus(alert.ChannelId, "", common.ChannelStatusAutoDisabled, reason)
+		}
+	}
+	common.SysLog(fmt.Sprintf("channel health alert: channel #%d error rate %.2f (%d/%d), disabled: %t",
+		alert.ChannelId, alert.ErrorRate, alert.Errors, alert.Total, alert.Disabled))
+
+	if m.SenderFunc == nil {
+		return
+	}
+	sender := m.SenderFunc()
+	if sender == nil {
+		return
+	}
+	if err := sender.SendAlert(alert); err != nil {
+		common.SysError(fmt.Sprintf("failed to send channel health alert for channel #%d: %s", alert.ChannelId, err.Error()))
+	}
+}
+
+// Flush 将尚未写入的计数写入数据库，并清理已过期窗口的内存统计
+func (m *ChannelHealthMonitor) Flush() {
+	current := m.windowStart(m.now())
+
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[channelHealthKey]int64)
+	for channelId, w := range m.windows {
+		if w.windowStart < current {
+			delete(m.windows, channelId)
+		}
+	}
+	m.mu.Unlock()
+
+	for key, delta := range pending {
+		if err := model.IncreaseChannelHealthEventCount(key.channelId, key.errorCode, key.windowStart, delta); err != nil {
+			common.SysError(fmt.Sprintf("failed to save channel health event for channel #%d: %s", key.channelId, err.Error()))
+		}
+	}
+}
+
+// Run 周期性地写入聚合结果并清理过期数据，应在独立的 goroutine 中运行
+func (m *ChannelHealthMonitor) Run() {
+	flushTicker := time.NewTicker(channelHealthFlushInterval)
+	cleanupTicker := time.NewTicker(channelHealthCleanupInterval)
+	defer flushTicker.Stop()
+	defer cleanupTicker.Stop()
+	for {
+		select {
+		case <-flushTicker.C:
+			m.Flush()
+		case <-cleanupTicker.C:
+			if !common.IsMasterNode {
+				continue
+			}
+			if err := model.DeleteChannelHealthEventsBefore(m.now().Add(-channelHealthRetention).Unix()); err != nil {
+				common.SysError("failed to clean up channel health events: " + err.Error())
+			}
+		}
+	}
+}
+
+// ChannelHealthWindowStat 单个窗口的渠道健康统计
+type ChannelHealthWindowStat struct {
+	WindowStart int64            `json:"window_start"`
+	Total       int64            `json:"total"`
+	Errors      int64            `json:"errors"`
+	ErrorRate   float64          `json:"error_rate"`
+	ErrorCodes  map[string]int64 `json:"error_codes"`
+}
+
+// GetChannelHealthStats 将渠道自 since 起的聚合记录按窗口合并
+func GetChannelHealthStats(channelId int, since int64) ([]*ChannelHealthWindowStat, error) {
+	events, err := model.GetChannelHealthEvents(channelId, since)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]*ChannelHealthWindowStat, 0)
+	var last *ChannelHealthWindowStat
+	for _, event := range events {
+		if last == nil || last.WindowStart != event.WindowStart {
+			last = &ChannelHealthWindowStat{WindowStart: event.WindowStart, ErrorCodes: map[string]int64{}}
+			stats = append(stats, last)
+		}
+		last.Total += event.Count
+		if event.ErrorCode != model.ChannelHealthSuccessCode {
+			last.Errors += event.Count
+			last.ErrorCodes[event.ErrorCode] += event.Count
+		}
+	}
+	for _, stat := range stats {
+		if stat.Total > 0 {
+			stat.ErrorRate = float64(stat.Errors) / float64(stat.Total)
+		}
+	}
+	return stats, nil
+}
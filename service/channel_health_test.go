@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/notify"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+type recordingAlertSender struct {
+	mu     sync.Mutex
+	alerts []*notify.ChannelAlert
+	done   chan struct{}
+}
+
+func (s *recordingAlertSender) SendAlert(alert *notify.ChannelAlert) error {
+	s.mu.Lock()
+	s.alerts = append(s.alerts, alert)
+	s.mu.Unlock()
+	s.done <- struct{}{}
+	return nil
+}
+
+func setupChannelHealthTest(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.Channel{}, &model.Ability{}, &model.ChannelHealthEvent{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originSQLite, originCache := model.DB, common.UsingSQLite, common.MemoryCacheEnabled
+	model.DB, common.UsingSQLite, common.MemoryCacheEnabled = db, true, false
+
+	setting := operation_setting.GetMonitorSetting()
+	originSetting := *setting
+	setting.ChannelHealthAlertEnabled = true
+	setting.ChannelHealthErrorRateThreshold = 0.5
+	setting.ChannelHealthMinRequests = 4
+	setting.ChannelHealthAutoDisable = true
+
+	t.Cleanup(func() {
+		model.DB, common.UsingSQLite, common.MemoryCacheEnabled = originDB, originSQLite, originCache
+		*setting = originSetting
+	})
+}
+
+func TestChannelHealthMonitorAlertsAndDisables(t *testing.T) {
+	setupChannelHealthTest(t)
+	channel := &model.Channel{Name: "flaky", Key: "sk-test", Status: common.ChannelStatusEnabled}
+	if err := model.DB.Create(channel).Error; err != nil {
+		t.Fatalf("create channel failed: %v", err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	sender := &recordingAlertSender{done: make(chan struct{}, 1)}
+	m := NewChannelHealthMonitor(ChannelHealthWindow)
+	m.now = func() time.Time { return now }
+	m.SenderFunc = func() notify.AlertSender { return sender }
+
+	upstreamErr := types.NewOpenAIError(errors.New("bad gateway"), types.ErrorCodeBadResponseStatusCode, http.StatusBadGateway)
+	m.RecordSuccess(channel.Id)
+	m.RecordError(channel.Id, upstreamErr)
+	m.RecordError(channel.Id, upstreamErr)
+	m.RecordError(channel.Id, upstreamErr)
+	// 同一窗口内只告警一次
+	m.RecordError(channel.Id, upstreamErr)
+
+	select {
+	case <-sender.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected alert to be sent")
+	}
+	if len(sender.alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(sender.alerts))
+	}
+	alert := sender.alerts[0]
+	if alert.ChannelName != "flaky" || alert.Total != 4 || alert.Errors != 3 || !alert.Disabled {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+	updated, err := model.GetChannelById(channel.Id, false)
+	if err != nil {
+		t.Fatalf("get channel failed: %v", err)
+	}
+	if updated.Status != common.ChannelStatusAutoDisabled {
+		t.Fatalf("expected channel auto disabled, got status %d", updated.Status)
+	}
+
+	m.Flush()
+	stats, err := GetChannelHealthStats(channel.Id, 0)
+	if err != nil {
+		t.Fatalf("get stats failed: %v", err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 window, got %d", len(stats))
+	}
+	if stats[0].Total != 5 || stats[0].Errors != 4 || stats[0].ErrorCodes[string(types.ErrorCodeBadResponseStatusCode)] != 4 {
+		t.Fatalf("unexpected stats: %+v", stats[0])
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	ChannelProbeTimeout = 30 * time.Second
+	// 空闲超过该时长的渠道会在定时任务中被探活，且每个空闲周期最多探活一次
+	channelProbeIdleDuration = 24 * time.Hour
+	channelProbeInterval     = time.Hour
+)
+
+var ErrChannelProbeUnsupported = errors.New("channel probe is not supported for this channel type")
+
+// ChannelProbeRunner 发送探活请求，由 controller 注册为渠道测试逻辑，避免 service 依赖 relay
+var ChannelProbeRunner func(channel *model.Channel) error
+
+// ChannelHealthProbe 向渠道发送一次合成请求，最多等待 30 秒，并记录探活结果。
+// 渠道类型不支持探活时返回 ErrChannelProbeUnsupported，且不更新探活状态
+func ChannelHealthProbe(channel *model.Channel) error {
+	if ChannelProbeRunner == nil {
+		return ErrChannelProbeUnsupported
+	}
+	done := make(chan error, 1)
+	gopool.Go(func() {
+		done <- ChannelProbeRunner(channel)
+	})
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(ChannelProbeTimeout):
+		err = fmt.Errorf("channel probe timed out after %s", ChannelProbeTimeout)
+	}
+	if errors.Is(err, ErrChannelProbeUnsupported) {
+		return err
+	}
+	if err != nil {
+		channel.UpdateProbeStatus(model.ChannelProbeStatusFail)
+		return err
+	}
+	channel.UpdateProbeStatus(model.ChannelProbeStatusOK)
+	return nil
+}
+
+// ProbeIdleChannels 探活所有空闲超过 24 小时的已启用渠道
+func ProbeIdleChannels() {
+	now := time.Now()
+	since := now.Add(-channelProbeIdleDuration).Unix()
+	channels, err := model.GetIdleChannelsForProbe(since, since)
+	if err != nil {
+		common.SysLog("failed to get idle channels for probe: " + err.Error())
+		return
+	}
+	for _, channel := range channels {
+		err := ChannelHealthProbe(channel)
+		if errors.Is(err, ErrChannelProbeUnsupported) {
+			continue
+		}
+		if err != nil {
+			common.SysLog(fmt.Sprintf("channel probe failed: channel_id=%d, name=%s, error=%v", channel.Id, channel.Name, err))
+		}
+	}
+}
+
+var idleChannelProbeOnce sync.Once
+
+// AutomaticallyProbeIdleChannels 每小时探活一次空闲渠道，仅在主节点运行
+func AutomaticallyProbeIdleChannels() {
+	if !common.IsMasterNode {
+		return
+	}
+	idleChannelProbeOnce.Do(func() {
+		for {
+			time.Sleep(channelProbeInterval)
+			ProbeIdleChannels()
+		}
+	})
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrCircuitBreakerOpen = errors.New("channel circuit breaker is open")
+
+type CircuitBreakerState int
+
+const (
+	CircuitBreakerClosed CircuitBreakerState = iota
+	CircuitBreakerHalfOpen
+	CircuitBreakerOpen
+)
+
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case CircuitBreakerHalfOpen:
+		return "half_open"
+	case CircuitBreakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker 按渠道统计上游请求的失败率，失败率超过阈值时熔断（Open），
+// 冷却期结束后进入 HalfOpen 并只放行一个探测请求：探测成功则恢复（Closed），失败则重新熔断。
+// 失败率按与 GroupRateLimiter 相同的滑动窗口近似计算；启用 Redis 时状态在节点间共享，否则使用进程内状态。
+type CircuitBreaker struct {
+	Window time.Duration
+
+	mu     sync.Mutex
+	states map[int]*circuitBreakerState
+	now    func() time.Time
+}
+
+type circuitBreakerState struct {
+	total    windowCounter
+	failures windowCounter
+	openedAt int64
+	probing  bool
+	// probeSeq 标识当前探测请求，避免已结束的探测归还后续探测的名额
+	probeSeq uint64
+}
+
+var releaseProbeScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func noopRelease() {}
+
+var DefaultCircuitBreaker = NewCircuitBreaker(time.Minute)
+
+func NewCircuitBreaker(window time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Window: window,
+		states: make(map[int]*circuitBreakerState),
+		now:    time.Now,
+	}
+}
+
+func (b *CircuitBreaker) cooldown() time.Duration {
+	return time.Duration(operation_setting.GetMonitorSetting().ChannelCircuitBreakerCooldownSeconds) * time.Second
+}
+
+// stateAt 根据熔断时间判断当前状态
+func (b *CircuitBreaker) stateAt(openedAt int64, now time.Time) CircuitBreakerState {
+	if openedAt == 0 {
+		return CircuitBreakerClosed
+	}
+	if now.Before(time.UnixMilli(openedAt).Add(b.cooldown())) {
+		return CircuitBreakerOpen
+	}
+	return CircuitBreakerHalfOpen
+}
+
+// Allow 判断是否可以向渠道发送请求，HalfOpen 状态下只有获得探测名额的请求会被放行。
+// 放行时返回的 release 必须在请求结束时调用（通常 defer）：请求未调用 Record 就结束时
+// （如被取消、并发受限或出错提前返回），release 归还探测名额；已调用 Record 时为空操作
+func (b *CircuitBreaker) Allow(ctx context.Context, channelId int) (release func(), err error) {
+	if channelId == 0 || !operation_setting.GetMonitorSetting().ChannelCircuitBreakerEnabled {
+		return noopRelease, nil
+	}
+	var allowed bool
+	if common.RedisEnabled && common.RDB != nil {
+		allowed, release, err = b.allowRedis(ctx, channelId)
+		if err != nil {
+			// Redis 不可用时不阻断请求
+			common.SysError(fmt.Sprintf("circuit breaker check failed for channel #%d: %s", channelId, err.Error()))
+			return noopRelease, nil
+		}
+	} else {
+		allowed, release = b.allowMemory(channelId)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w: channel #%d", ErrCircuitBreakerOpen, channelId)
+	}
+	return release, nil
+}
+
+// Record 记录一次请求结果，连接失败或上游 5xx 视为失败
+func (b *CircuitBreaker) Record(ctx context.Context, channelId int, success bool) {
+	if channelId == 0 || !operation_setting.GetMonitorSetting().ChannelCircuitBreakerEnabled {
+		return
+	}
+	if common.RedisEnabled && common.RDB != nil {
+		if err := b.recordRedis(ctx, channelId, success); err != nil {
+			common.SysError(fmt.Sprintf("failed to record circuit breaker result for channel #%d: %s", channelId, err.Error()))
+		}
+		return
+	}
+	b.recordMemory(channelId, success)
+}
+
+// State 返回渠道当前的熔断状态
+func (b *CircuitBreaker) State(ctx context.Context, channelId int) CircuitBreakerState {
+	var openedAt int64
+	if common.RedisEnabled && common.RDB != nil {
+		openedAt, _ = common.RDB.Get(ctx, b.key(channelId, "opened_at")).Int64()
+	} else {
+		b.mu.Lock()
+		if s, ok := b.states[channelId]; ok {
+			openedAt = s.openedAt
+		}
+		b.mu.Unlock()
+	}
+	return b.stateAt(openedAt, b.now())
+}
+
+// shouldOpen 判断滑动窗口内的失败率是否达到熔断条件
+func (b *CircuitBreaker) shouldOpen(total, failures float64) bool {
+	setting := operation_setting.GetMonitorSetting()
+	if setting.ChannelCircuitBreakerErrorRate <= 0 || total < float64(setting.ChannelCircuitBreakerMinRequests) || total == 0 {
+		return false
+	}
+	return failures/total >= setting.ChannelCircuitBreakerErrorRate
+}
+
+// windowStart 返回当前窗口起点（毫秒）以及已经过的比例
+func (b *CircuitBreaker) windowStart(now time.Time) (int64, float64) {
+	window := b.Window.Milliseconds()
+	nowMs := now.UnixMilli()
+	start := nowMs - nowMs%window
+	return start, float64(nowMs-start) / float64(window)
+}
+
+func (b *CircuitBreaker) allowMemory(channelId int) (bool, func()) {
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.states[channelId]
+	if !ok {
+		return true, noopRelease
+	}
+	switch b.stateAt(s.openedAt, now) {
+	case CircuitBreakerOpen:
+		return false, nil
+	case CircuitBreakerHalfOpen:
+		if s.probing {
+			return false, nil
+		}
+		s.probing = true
+		s.probeSeq++
+		seq := s.probeSeq
+		return true, func() { b.releaseMemoryProbe(channelId, seq) }
+	}
+	return true, noopRelease
+}
+
+func (b *CircuitBreaker) releaseMemoryProbe(channelId int, seq uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.states[channelId]; ok && s.probing && s.probeSeq == seq {
+		s.probing = false
+	}
+}
+
+func (b *CircuitBreaker) recordMemory(channelId int, success bool) {
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.states[channelId]
+	if !ok {
+		s = &circuitBreakerState{}
+		b.states[channelId] = s
+	}
+	switch b.stateAt(s.openedAt, now) {
+	case CircuitBreakerOpen:
+		// 熔断前已发出的请求，结果不影响状态
+		return
+	case CircuitBreakerHalfOpen:
+		if !s.probing {
+			return
+		}
+		s.probing = false
+		if success {
+			delete(b.states, channelId)
+		} else {
+			s.openedAt = now.UnixMilli()
+		}
+		return
+	}
+
+	start, elapsed := b.windowStart(now)
+	if s.total.windowStart != start {
+		adjacent := start-s.total.windowStart == b.Window.Milliseconds()
+		s.total.roll(start, adjacent)
+		s.failures.roll(start, adjacent)
+	}
+	s.total.current++
+	if !success {
+		s.failures.current++
+	}
+	total := float64(s.total.previous)*(1-elapsed) + float64(s.total.current)
+	failures := float64(s.failures.previous)*(1-elapsed) + float64(s.failures.current)
+	if !success && b.shouldOpen(total, failures) {
+		*s = circuitBreakerState{openedAt: now.UnixMilli()}
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker opened, failure rate %.2f", channelId, failures/total))
+	}
+}
+
+func (c *windowCounter) roll(windowStart int64, adjacent bool) {
+	if adjacent {
+		c.previous = c.current
+	} else {
+		c.previous = 0
+	}
+	c.current = 0
+	c.windowStart = windowStart
+}
+
+func (b *CircuitBreaker) key(channelId int, name string) string {
+	return fmt.Sprintf("circuitBreaker:%d:%s", channelId, name)
+}
+
+func (b *CircuitBreaker) allowRedis(ctx context.Context, channelId int) (bool, func(), error) {
+	openedAt, err := common.RDB.Get(ctx, b.key(channelId, "opened_at")).Int64()
+	if err == redis.Nil {
+		return true, noopRelease, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	switch b.stateAt(openedAt, b.now()) {
+	case CircuitBreakerOpen:
+		return false, nil, nil
+	case CircuitBreakerHalfOpen:
+		// 探测名额在冷却期后过期，作为节点异常退出时的兜底
+		probeKey := b.key(channelId, "probe")
+		token := common.GetUUID()
+		acquired, err := common.RDB.SetNX(ctx, probeKey, token, b.cooldown()).Result()
+		if err != nil || !acquired {
+			return false, nil, err
+		}
+		return true, func() {
+			// 请求的 ctx 可能已取消，使用独立的 ctx 归还
+			if err := releaseProbeScript.Run(context.Background(), common.RDB, []string{probeKey}, token).Err(); err != nil && err != redis.Nil {
+				common.SysError(fmt.Sprintf("failed to release circuit breaker probe for channel #%d: %s", channelId, err.Error()))
+			}
+		}, nil
+	}
+	return true, noopRelease, nil
+}
+
+func (b *CircuitBreaker) recordRedis(ctx context.Context, channelId int, success bool) error {
+	now := b.now()
+	openedKey := b.key(channelId, "opened_at")
+	probeKey := b.key(channelId, "probe")
+	openedAt, err := common.RDB.Get(ctx, openedKey).Int64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	switch b.stateAt(openedAt, now) {
+	case CircuitBreakerOpen:
+		return nil
+	case CircuitBreakerHalfOpen:
+		deleted, err := common.RDB.Del(ctx, probeKey).Result()
+		if err != nil || deleted == 0 {
+			// 不是探测请求的结果
+			return err
+		}
+		if success {
+			return common.RDB.Del(ctx, openedKey).Err()
+		}
+		return common.RDB.Set(ctx, openedKey, now.UnixMilli(), 0).Err()
+	}
+
+	start, elapsed := b.windowStart(now)
+	window := b.Window.Milliseconds()
+	totalKey := fmt.Sprintf("%s:%d", b.key(channelId, "total"), start)
+	failureKey := fmt.Sprintf("%s:%d", b.key(channelId, "failure"), start)
+	prevTotalKey := fmt.Sprintf("%s:%d", b.key(channelId, "total"), start-window)
+	prevFailureKey := fmt.Sprintf("%s:%d", b.key(channelId, "failure"), start-window)
+	// 当前窗口的计数需要保留到下一个窗口结束，供其作为上一窗口参与加权
+	expireAt := time.UnixMilli(start + 2*window)
+	failureDelta := int64(0)
+	if !success {
+		failureDelta = 1
+	}
+
+	pipe := common.RDB.Pipeline()
+	total := pipe.IncrBy(ctx, totalKey, 1)
+	pipe.ExpireAt(ctx, totalKey, expireAt)
+	failures := pipe.IncrBy(ctx, failureKey, failureDelta)
+	pipe.ExpireAt(ctx, failureKey, expireAt)
+	prevTotal := pipe.Get(ctx, prevTotalKey)
+	prevFailures := pipe.Get(ctx, prevFailureKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return err
+	}
+	if success {
+		return nil
+	}
+	previousTotal, _ := prevTotal.Int64()
+	previousFailures, _ := prevFailures.Int64()
+	weightedTotal := float64(previousTotal)*(1-elapsed) + float64(total.Val())
+	weightedFailures := float64(previousFailures)*(1-elapsed) + float64(failures.Val())
+	if !b.shouldOpen(weightedTotal, weightedFailures) {
+		return nil
+	}
+	opened, err := common.RDB.SetNX(ctx, openedKey, now.UnixMilli(), 0).Result()
+	if err != nil {
+		return err
+	}
+	if opened {
+		// 恢复后重新开始统计
+		common.RDB.Del(ctx, totalKey, failureKey, prevTotalKey, prevFailureKey)
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker opened, failure rate %.2f", channelId, weightedFailures/weightedTotal))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	prevRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	setting := operation_setting.GetMonitorSetting()
+	prevSetting := *setting
+	setting.ChannelCircuitBreakerEnabled = true
+	setting.ChannelCircuitBreakerErrorRate = 0.5
+	setting.ChannelCircuitBreakerMinRequests = 4
+	setting.ChannelCircuitBreakerCooldownSeconds = 30
+	t.Cleanup(func() {
+		common.RedisEnabled = prevRedis
+		*setting = prevSetting
+	})
+
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	b := NewCircuitBreaker(time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// 请求数不足最小请求数时不熔断
+	b.Record(ctx, 1, false)
+	b.Record(ctx, 1, false)
+	b.Record(ctx, 1, true)
+	if _, err := b.Allow(ctx, 1); err != nil {
+		t.Fatalf("expected closed breaker, got %v", err)
+	}
+	b.Record(ctx, 1, false)
+	if state := b.State(ctx, 1); state != CircuitBreakerOpen {
+		t.Fatalf("state = %s, want open", state)
+	}
+	if _, err := b.Allow(ctx, 1); !errors.Is(err, ErrCircuitBreakerOpen) {
+		t.Fatalf("expected ErrCircuitBreakerOpen, got %v", err)
+	}
+	if _, err := b.Allow(ctx, 2); err != nil {
+		t.Fatalf("other channels should not be affected, got %v", err)
+	}
+
+	// 冷却期后只放行一个探测请求，探测失败重新熔断
+	now = now.Add(31 * time.Second)
+	if state := b.State(ctx, 1); state != CircuitBreakerHalfOpen {
+		t.Fatalf("state = %s, want half_open", state)
+	}
+	if _, err := b.Allow(ctx, 1); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	if _, err := b.Allow(ctx, 1); err == nil {
+		t.Fatal("expected only one probe in half open state")
+	}
+	b.Record(ctx, 1, false)
+	if state := b.State(ctx, 1); state != CircuitBreakerOpen {
+		t.Fatalf("state = %s, want open after failed probe", state)
+	}
+
+	// 探测成功后恢复
+	now = now.Add(31 * time.Second)
+	if _, err := b.Allow(ctx, 1); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	b.Record(ctx, 1, true)
+	if state := b.State(ctx, 1); state != CircuitBreakerClosed {
+		t.Fatalf("state = %s, want closed after successful probe", state)
+	}
+	if _, err := b.Allow(ctx, 1); err != nil {
+		t.Fatalf("expected closed breaker, got %v", err)
+	}
+}
+
+func TestCircuitBreakerReleaseProbe(t *testing.T) {
+	prevRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	setting := operation_setting.GetMonitorSetting()
+	prevSetting := *setting
+	setting.ChannelCircuitBreakerEnabled = true
+	setting.ChannelCircuitBreakerErrorRate = 0.5
+	setting.ChannelCircuitBreakerMinRequests = 1
+	setting.ChannelCircuitBreakerCooldownSeconds = 30
+	t.Cleanup(func() {
+		common.RedisEnabled = prevRedis
+		*setting = prevSetting
+	})
+
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	b := NewCircuitBreaker(time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	b.Record(ctx, 1, false)
+	now = now.Add(31 * time.Second)
+
+	// 探测请求未记录结果就结束时归还探测名额
+	release, err := b.Allow(ctx, 1)
+	if err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	release()
+	staleRelease, err := b.Allow(ctx, 1)
+	if err != nil {
+		t.Fatalf("expected probe to be allowed after release, got %v", err)
+	}
+
+	// 已记录结果的探测归还时不影响后续探测
+	b.Record(ctx, 1, false)
+	now = now.Add(31 * time.Second)
+	release, err = b.Allow(ctx, 1)
+	if err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	staleRelease()
+	if _, err := b.Allow(ctx, 1); err == nil {
+		t.Fatal("stale release must not free the current probe")
+	}
+	release()
+	if _, err := b.Allow(ctx, 1); err != nil {
+		t.Fatalf("expected probe to be allowed after release, got %v", err)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -84,7 +83,7 @@ func ClaudeErrorWrapperLocal(err error, code string, statusCode int) *dto.Claude
 func RelayErrorHandler(ctx context.Context, resp *http.Response, showBodyWhenFail bool) (newApiErr *types.NewAPIError) {
 	newApiErr = types.InitOpenAIError(types.ErrorCodeBadResponseStatusCode, resp.StatusCode)
 
-	responseBody, err := io.ReadAll(resp.Body)
+	responseBody, err := ReadCompressedBody(resp)
 	if err != nil {
 		return
 	}
@@ -175,6 +174,28 @@ func RelayErrorHandler(ctx context.Context, resp *http.Response, showBodyWhenFai
 	return
 }
 
+// quotaExhaustedCodes 上游明确表示额度或账单耗尽的错误码。Google 等上游的 429 "quota exceeded"
+// 属于限流，由 429 冷却逻辑处理，不在此列
+var quotaExhaustedCodes = []string{
+	"insufficient_quota",
+	"billing_hard_limit_reached",
+	"billing_not_active",
+}
+
+// IsQuotaExhaustedError 判断上游错误是否表示 key 的额度已耗尽：402，或错误码（任务接口为错误信息中的错误码）
+// 为 insufficient_quota 等账单类错误码
+func IsQuotaExhaustedError(statusCode int, code string, message string) bool {
+	if statusCode == http.StatusPaymentRequired {
+		return true
+	}
+	for _, exhausted := range quotaExhaustedCodes {
+		if code == exhausted || strings.Contains(message, exhausted) {
+			return true
+		}
+	}
+	return false
+}
+
 func ResetStatusCode(newApiErr *types.NewAPIError, statusCodeMappingStr string) {
 	if statusCodeMappingStr == "" || statusCodeMappingStr == "{}" {
 		return
@@ -200,7 +221,13 @@ func TaskErrorWrapperLocal(err error, code string, statusCode int) *dto.TaskErro
 	return openaiErr
 }
 
+// TaskErrorWrapper 包装任务错误并附带错误目录中的编号与是否可重试，statusCode 为 0 时使用目录中的 HTTP 状态码。
+// 请求体超出大小限制的错误统一为 413 request_body_too_large
 func TaskErrorWrapper(err error, code string, statusCode int) *dto.TaskError {
+	if common.IsRequestBodyTooLargeError(err) {
+		code = "request_body_too_large"
+		statusCode = http.StatusRequestEntityTooLarge
+	}
 	text := err.Error()
 	lowerText := strings.ToLower(text)
 	if strings.Contains(lowerText, "post") || strings.Contains(lowerText, "dial") || strings.Contains(lowerText, "http") {
@@ -215,6 +242,6 @@ func TaskErrorWrapper(err error, code string, statusCode int) *dto.TaskError {
 		StatusCode: statusCode,
 		Error:      err,
 	}
-
+	ApplyErrorCatalogue(taskError)
 	return taskError
 }
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// errorCatalogue 任务接口返回的全部错误码，按数字编号分段：
+// 1xxx 请求参数，2xxx 额度与权限，3xxx 渠道，4xxx 上游响应，5xxx 任务，9xxx 内部错误
+var errorCatalogue = []dto.ErrorCatalogue{
+	errorEntry(1001, "invalid_request", http.StatusBadRequest, false, "Invalid request: {message}"),
+	errorEntry(1006, "read_request_body_failed", http.StatusBadRequest, false, "Failed to read request body: {message}"),
+	errorEntry(1007, "request_body_too_large", http.StatusRequestEntityTooLarge, false, "Request body too large: {message}"),
+	errorEntry(1008, "unmarshal_task_request_failed", http.StatusBadRequest, false, "Invalid task request: {message}"),
+	errorEntry(1009, "get_task_request_failed", http.StatusBadRequest, false, "Invalid task request: {message}"),
+	errorEntry(1010, "invalid_api_platform", http.StatusBadRequest, false, "Unsupported task platform: {message}"),
+	errorEntry(1011, "invalid_relay_mode", http.StatusBadRequest, false, "Unsupported request path"),
+	errorEntry(1012, "model_mapping_failed", http.StatusBadRequest, false, "Model mapping failed: {message}"),
+	errorEntry(1013, "content_policy_violation", http.StatusBadRequest, false, "Prompt violates content policy: {message}"),
+	errorEntry(1014, "invalid_channel_id", http.StatusBadRequest, false, "Invalid channel: {message}"),
+
+	errorEntry(2001, "quota_not_enough", http.StatusForbidden, false, "Insufficient quota: {message}"),
+	errorEntry(2002, "model_spending_cap_exceeded", http.StatusForbidden, false, "Model spending cap exceeded: {message}"),
+	errorEntry(2003, "reserve_user_quota_failed", http.StatusInternalServerError, true, "Failed to reserve user quota"),
+	errorEntry(2004, "reserve_token_quota_failed", http.StatusInternalServerError, true, "Failed to reserve token quota"),
+	errorEntry(2005, "check_model_spending_cap_failed", http.StatusInternalServerError, true, "Failed to check model spending cap"),
+	errorEntry(2006, "ip_not_allowed", http.StatusForbidden, false, "Client IP is not allowed to use this token"),
+
+	errorEntry(3001, "get_channel_failed", http.StatusInternalServerError, true, "No available channel: {message}"),
+	errorEntry(3002, "channel_not_found", http.StatusBadRequest, false, "Channel not found: {message}"),
+	errorEntry(3003, "channel_no_available_key", 0, true, "No available key in channel: {message}"),
+	errorEntry(3004, "channel_concurrency_limit", http.StatusTooManyRequests, true, "Channel concurrency limit reached, please retry later"),
+	errorEntry(3006, "channel:circuit_breaker_open", http.StatusServiceUnavailable, true, "Channel is temporarily unavailable, please retry later"),
+	errorEntry(3007, "shadow_channel_disabled", http.StatusServiceUnavailable, false, "Shadow channel is disabled"),
+	errorEntry(3008, "task_channel_disable", http.StatusBadRequest, false, "The channel of the origin task is disabled"),
+	errorEntry(3010, "midjourney_no_available_account", http.StatusServiceUnavailable, true, "No available Midjourney account, please retry later"),
+
+	errorEntry(4001, "do_request_failed", http.StatusInternalServerError, true, "Request to upstream failed: {message}"),
+	errorEntry(4002, "read_response_body_failed", http.StatusInternalServerError, true, "Failed to read upstream response: {message}"),
+	errorEntry(4003, "unmarshal_response_body_failed", http.StatusInternalServerError, false, "Failed to parse upstream response: {message}"),
+	errorEntry(4004, "unmarshal_response_failed", http.StatusInternalServerError, false, "Failed to parse upstream response: {message}"),
+	errorEntry(4005, "invalid_response", http.StatusInternalServerError, false, "Invalid upstream response: {message}"),
+	errorEntry(4006, "invalid_upstream_response", http.StatusBadGateway, false, "Invalid upstream response: {message}"),
+	errorEntry(4007, "copy_response_body_failed", http.StatusInternalServerError, true, "Failed to write response: {message}"),
+	errorEntry(4008, "fail_to_fetch_task", 0, true, "Failed to fetch task from upstream: {message}"),
+	errorEntry(4009, "ali_api_error", 0, false, "Upstream error: {message}"),
+	errorEntry(4010, "luma_error", http.StatusBadRequest, false, "Upstream error: {message}"),
+	errorEntry(4011, "runway_error", http.StatusBadRequest, false, "Upstream error: {message}"),
+	errorEntry(4012, "udio_error", http.StatusBadRequest, false, "Upstream error: {message}"),
+	errorEntry(4013, "midjourney_error", http.StatusInternalServerError, false, "Upstream error: {message}"),
+	errorEntry(4014, "midjourney_request_error", http.StatusBadRequest, false, "Upstream rejected the request: {message}"),
+
+	errorEntry(5001, "task_not_exist", http.StatusNotFound, false, "Task not found"),
+	errorEntry(5002, "task_failed", http.StatusBadRequest, false, "Task failed: {message}"),
+	errorEntry(5003, "dependency_task_failed", http.StatusBadRequest, false, "Dependency task failed: {message}"),
+	errorEntry(5004, "task_not_failed", http.StatusBadRequest, false, "Only failed tasks can be replayed"),
+	errorEntry(5005, "task_not_replayable", http.StatusBadRequest, false, "The original request of this task was not saved"),
+	errorEntry(5006, "task_replay_limit_exceeded", http.StatusTooManyRequests, false, "Task replay limit exceeded: {message}"),
+	errorEntry(5007, "not_implemented", http.StatusNotImplemented, false, "Not implemented: {message}"),
+	errorEntry(5008, "get_task_failed", http.StatusInternalServerError, true, "Failed to get task"),
+	errorEntry(5009, "get_tasks_failed", http.StatusInternalServerError, true, "Failed to get tasks"),
+	errorEntry(5010, "insert_task_failed", http.StatusInternalServerError, true, "Failed to save task"),
+	errorEntry(5011, "update_task_failed", http.StatusInternalServerError, true, "Failed to update task"),
+	errorEntry(5012, "insert_task_dependency_failed", http.StatusInternalServerError, true, "Failed to save task dependency"),
+	errorEntry(5013, "get_dependency_task_failed", http.StatusInternalServerError, true, "Failed to get dependency task"),
+	errorEntry(5014, "get_origin_task_failed", http.StatusInternalServerError, true, "Failed to get origin task"),
+	errorEntry(5015, "get_previous_task_failed", http.StatusInternalServerError, true, "Failed to get previous task"),
+	errorEntry(5016, "parse_previous_task_failed", http.StatusInternalServerError, false, "Failed to parse previous task"),
+	errorEntry(5017, "reserve_task_replay_failed", http.StatusInternalServerError, true, "Failed to reserve task replay"),
+	errorEntry(5018, "replay_task_failed", http.StatusInternalServerError, true, "Failed to replay task: {message}"),
+	errorEntry(5019, "task_already_finished", http.StatusConflict, false, "Task has already finished"),
+	errorEntry(5020, "task_submission_in_progress", http.StatusConflict, true, "An identical task submission is still in progress, please retry later"),
+
+	errorEntry(9001, "gen_relay_info_failed", http.StatusInternalServerError, false, "Internal error: {message}"),
+	errorEntry(9002, "build_request_failed", http.StatusInternalServerError, false, "Failed to build upstream request: {message}"),
+	errorEntry(9003, "convert_to_ali_request_failed", http.StatusInternalServerError, false, "Failed to build upstream request: {message}"),
+	errorEntry(9004, "convert_to_openai_video_failed", http.StatusInternalServerError, false, "Failed to convert task result: {message}"),
+	errorEntry(9005, "marshal_response_failed", http.StatusInternalServerError, false, "Failed to build response: {message}"),
+}
+
+var errorCatalogueByCode = func() map[string]dto.ErrorCatalogue {
+	m := make(map[string]dto.ErrorCatalogue, len(errorCatalogue))
+	for _, entry := range errorCatalogue {
+		m[entry.Code] = entry
+	}
+	return m
+}()
+
+// ErrorCatalogue 返回完整的错误目录，供 SDK 开发者查询
+func ErrorCatalogue() []dto.ErrorCatalogue {
+	return slices.Clone(errorCatalogue)
+}
+
+// LookupErrorCatalogue 按字符串错误码查找目录项，上游透传的错误码不在目录中
+func LookupErrorCatalogue(code string) (dto.ErrorCatalogue, bool) {
+	entry, ok := errorCatalogueByCode[code]
+	return entry, ok
+}
+
+// ApplyErrorCatalogue 为目录中的错误码填充数字编号与是否可重试，未设置状态码时使用目录中的 HTTP 状态码
+func ApplyErrorCatalogue(taskErr *dto.TaskError) {
+	if taskErr == nil {
+		return
+	}
+	entry, ok := LookupErrorCatalogue(taskErr.Code)
+	if !ok {
+		return
+	}
+	taskErr.NumericCode = entry.NumericCode
+	taskErr.Retryable = entry.Retryable
+	if taskErr.StatusCode == 0 {
+		taskErr.StatusCode = entry.HttpStatus
+	}
+}
+
+func errorEntry(numericCode int, code string, httpStatus int, retryable bool, message string) dto.ErrorCatalogue {
+	return dto.ErrorCatalogue{
+		NumericCode: numericCode,
+		Code:        code,
+		HttpStatus:  httpStatus,
+		Retryable:   retryable,
+		Message:     message,
+	}
+}
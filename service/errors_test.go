@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestErrorCatalogueUnique(t *testing.T) {
+	codes := make(map[string]bool)
+	numericCodes := make(map[int]bool)
+	for _, entry := range ErrorCatalogue() {
+		if codes[entry.Code] || numericCodes[entry.NumericCode] {
+			t.Fatalf("duplicate catalogue entry: %+v", entry)
+		}
+		codes[entry.Code] = true
+		numericCodes[entry.NumericCode] = true
+		if entry.Message == "" {
+			t.Errorf("%s has no message", entry.Code)
+		}
+	}
+}
+
+func TestTaskErrorWrapperUsesCatalogue(t *testing.T) {
+	cases := []struct {
+		err           error
+		code          string
+		statusCode    int
+		wantCode      string
+		wantStatus    int
+		wantNumeric   int
+		wantRetryable bool
+	}{
+		// 未指定状态码时使用目录中的状态码
+		{errors.New("task not found"), "task_not_exist", 0, "task_not_exist", http.StatusNotFound, 5001, false},
+		// 调用方指定的状态码优先
+		{errors.New("bad prompt"), "invalid_request", http.StatusUnprocessableEntity, "invalid_request", http.StatusUnprocessableEntity, 1001, false},
+		{fmt.Errorf("read body: %w", common.ErrRequestBodyTooLarge), "invalid_request", http.StatusBadRequest, "request_body_too_large", http.StatusRequestEntityTooLarge, 1007, false},
+		{errors.New("upstream failed"), "fail_to_fetch_task", http.StatusBadGateway, "fail_to_fetch_task", http.StatusBadGateway, 4008, true},
+		// 上游透传的错误码不在目录中
+		{errors.New("upstream failed"), "InvalidParameter", http.StatusBadRequest, "InvalidParameter", http.StatusBadRequest, 0, false},
+	}
+	for _, tc := range cases {
+		taskErr := TaskErrorWrapperLocal(tc.err, tc.code, tc.statusCode)
+		if taskErr.Code != tc.wantCode || taskErr.StatusCode != tc.wantStatus || !taskErr.LocalError {
+			t.Errorf("%s: got code=%s status=%d", tc.code, taskErr.Code, taskErr.StatusCode)
+		}
+		if taskErr.NumericCode != tc.wantNumeric || taskErr.Retryable != tc.wantRetryable {
+			t.Errorf("%s: got numeric_code=%d retryable=%v", tc.code, taskErr.NumericCode, taskErr.Retryable)
+		}
+	}
+}
+
+func TestIsQuotaExhaustedError(t *testing.T) {
+	cases := []struct {
+		status  int
+		code    string
+		message string
+		want    bool
+	}{
+		{http.StatusPaymentRequired, "", "payment required", true},
+		{http.StatusTooManyRequests, "insufficient_quota", "You exceeded your current quota", true},
+		{http.StatusBadRequest, "", `{"error":{"code":"billing_hard_limit_reached"}}`, true},
+		{http.StatusTooManyRequests, "", "Quota exceeded for quota metric 'Generate requests' and limit 'per minute'", false},
+		{http.StatusTooManyRequests, "RESOURCE_EXHAUSTED", "You exceeded your current quota, please check your plan", false},
+		{http.StatusTooManyRequests, "rate_limit_exceeded", "Rate limit reached", false},
+	}
+	for _, tc := range cases {
+		if got := IsQuotaExhaustedError(tc.status, tc.code, tc.message); got != tc.want {
+			t.Errorf("IsQuotaExhaustedError(%d, %q, %q) = %v, want %v", tc.status, tc.code, tc.message, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"sync"
+)
+
+// Event is a single occurrence published on a Bus.
+type Event interface {
+	Type() string
+	Payload() any
+}
+
+// Subscriber receives every event published on the bus it is attached to.
+// Handle is called synchronously from Publish, so implementations must not block.
+type Subscriber interface {
+	Handle(event Event)
+}
+
+// Bus fans published events out to its subscribers in publish order.
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]Subscriber
+}
+
+// DefaultBus is the process-wide bus used by the relay and task pollers.
+var DefaultBus = NewBus()
+
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]Subscriber),
+	}
+}
+
+// Subscribe attaches sub to the bus and returns a function that detaches it.
+func (b *Bus) Subscribe(sub Subscriber) (unsubscribe func()) {
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *Bus) Publish(event Event) {
+	if event == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subscribers {
+		sub.Handle(event)
+	}
+}
+
+// Publish publishes event on DefaultBus.
+func Publish(event Event) {
+	DefaultBus.Publish(event)
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBusDeliversEventsInOrder(t *testing.T) {
+	bus := NewBus()
+	mem := NewMemorySubscriber()
+	unsubscribe := bus.Subscribe(mem)
+
+	bus.Publish(&TaskSubmittedEvent{TaskID: "task-1", Quota: 100})
+	bus.Publish(&QuotaDeductedEvent{UserId: 1, Quota: 100})
+	bus.Publish(&TaskStatusChangedEvent{TaskID: "task-1", FromStatus: "SUBMITTED", ToStatus: "QUEUED"})
+	bus.Publish(&TaskStatusChangedEvent{TaskID: "task-1", FromStatus: "QUEUED", ToStatus: "IN_PROGRESS"})
+	bus.Publish(&TaskCancelledEvent{TaskID: "task-1", RefundQuota: 100})
+
+	want := []string{
+		TypeTaskSubmitted,
+		TypeQuotaDeducted,
+		TypeTaskStatusChanged,
+		TypeTaskStatusChanged,
+		TypeTaskCancelled,
+	}
+	got := mem.Events()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d", len(want), len(got))
+	}
+	for i, event := range got {
+		if event.Type() != want[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, want[i], event.Type())
+		}
+	}
+	if to := got[3].Payload().(*TaskStatusChangedEvent).ToStatus; to != "IN_PROGRESS" {
+		t.Fatalf("expected last status change to IN_PROGRESS, got %s", to)
+	}
+
+	unsubscribe()
+	bus.Publish(&TaskSubmittedEvent{TaskID: "task-2"})
+	if len(mem.Events()) != len(want) {
+		t.Fatal("expected no events after unsubscribe")
+	}
+}
+
+func TestLogSubscriber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	sub, err := NewLogSubscriber(path)
+	if err != nil {
+		t.Fatalf("NewLogSubscriber returned error: %v", err)
+	}
+	sub.Handle(&TaskSubmittedEvent{TaskID: "task-1"})
+	sub.Handle(&TaskCancelledEvent{TaskID: "task-1"})
+	if err := sub.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], `"type":"task.submitted"`) || !strings.Contains(lines[1], `"type":"task.cancelled"`) {
+		t.Fatalf("unexpected log contents: %s", string(data))
+	}
+}
@@ -0,0 +1,62 @@
+package events
+
+const (
+	TypeTaskSubmitted     = "task.submitted"
+	TypeTaskStatusChanged = "task.status_changed"
+	TypeTaskCancelled     = "task.cancelled"
+	TypeQuotaDeducted     = "quota.deducted"
+)
+
+type TaskSubmittedEvent struct {
+	TaskID    string `json:"task_id"`
+	Platform  string `json:"platform"`
+	Action    string `json:"action"`
+	UserId    int    `json:"user_id"`
+	ChannelId int    `json:"channel_id"`
+	ModelName string `json:"model_name"`
+	Quota     int    `json:"quota"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (e *TaskSubmittedEvent) Type() string { return TypeTaskSubmitted }
+func (e *TaskSubmittedEvent) Payload() any { return e }
+
+type TaskStatusChangedEvent struct {
+	TaskID     string `json:"task_id"`
+	UserId     int    `json:"user_id"`
+	ChannelId  int    `json:"channel_id"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+	Progress   string `json:"progress"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+func (e *TaskStatusChangedEvent) Type() string { return TypeTaskStatusChanged }
+func (e *TaskStatusChangedEvent) Payload() any { return e }
+
+// TaskCancelledEvent is published when the system gives up on a task before
+// upstream reports a terminal status, e.g. after VideoTaskTimeoutMinutes.
+type TaskCancelledEvent struct {
+	TaskID      string `json:"task_id"`
+	UserId      int    `json:"user_id"`
+	ChannelId   int    `json:"channel_id"`
+	Reason      string `json:"reason"`
+	RefundQuota int    `json:"refund_quota"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+func (e *TaskCancelledEvent) Type() string { return TypeTaskCancelled }
+func (e *TaskCancelledEvent) Payload() any { return e }
+
+type QuotaDeductedEvent struct {
+	UserId           int    `json:"user_id"`
+	TokenId          int    `json:"token_id"`
+	ChannelId        int    `json:"channel_id"`
+	ModelName        string `json:"model_name"`
+	Quota            int    `json:"quota"`
+	PreConsumedQuota int    `json:"pre_consumed_quota"`
+	Timestamp        int64  `json:"timestamp"`
+}
+
+func (e *QuotaDeductedEvent) Type() string { return TypeQuotaDeducted }
+func (e *QuotaDeductedEvent) Payload() any { return e }
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// SetupDefaultBus attaches a LogSubscriber to DefaultBus when a log
+// directory is configured.
+func SetupDefaultBus() {
+	if common.LogDir == nil || *common.LogDir == "" {
+		return
+	}
+	path := filepath.Join(*common.LogDir, "events.log")
+	sub, err := NewLogSubscriber(path)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to open event log %s: %v", path, err))
+		return
+	}
+	DefaultBus.Subscribe(sub)
+	common.SysLog("event log enabled: " + path)
+}
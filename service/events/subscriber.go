@@ -0,0 +1,99 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// envelope is the wire format shared by LogSubscriber and the admin event stream.
+type envelope struct {
+	Type      string `json:"type"`
+	Timestamp int64  `json:"timestamp"`
+	Payload   any    `json:"payload"`
+}
+
+// Marshal encodes event as a JSON envelope.
+func Marshal(event Event) ([]byte, error) {
+	return common.Marshal(envelope{
+		Type:      event.Type(),
+		Timestamp: time.Now().UnixMilli(),
+		Payload:   event.Payload(),
+	})
+}
+
+// LogSubscriber appends one JSON object per event to a log file.
+type LogSubscriber struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewLogSubscriber(path string) (*LogSubscriber, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &LogSubscriber{file: file}, nil
+}
+
+func (s *LogSubscriber) Handle(event Event) {
+	data, err := Marshal(event)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to marshal event %s: %v", event.Type(), err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		common.SysError(fmt.Sprintf("failed to write event %s: %v", event.Type(), err))
+	}
+}
+
+func (s *LogSubscriber) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// MemorySubscriber records events in memory, mainly for tests.
+type MemorySubscriber struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemorySubscriber() *MemorySubscriber {
+	return &MemorySubscriber{}
+}
+
+func (s *MemorySubscriber) Handle(event Event) {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+}
+
+// Events returns a copy of the events received so far.
+func (s *MemorySubscriber) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+// ChanSubscriber forwards events to a buffered channel. Events are dropped
+// when the buffer is full so a slow consumer never blocks Publish.
+type ChanSubscriber struct {
+	C chan Event
+}
+
+func NewChanSubscriber(buffer int) *ChanSubscriber {
+	return &ChanSubscriber{C: make(chan Event, buffer)}
+}
+
+func (s *ChanSubscriber) Handle(event Event) {
+	select {
+	case s.C <- event:
+	default:
+	}
+}
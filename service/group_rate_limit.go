@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	groupRateLimitKindRequest = "rpm"
+	groupRateLimitKindToken   = "tpm"
+)
+
+// GroupRateLimiter 按 (分组, 模型) 统计 RPM/TPM 的滑动窗口限流器。
+// 滑动窗口由当前窗口与上一窗口的固定计数加权近似得到；启用 Redis 时通过
+// INCRBY + EXPIREAT 计数，否则使用进程内计数。
+type GroupRateLimiter struct {
+	Window time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*windowCounter
+	now      func() time.Time
+}
+
+type windowCounter struct {
+	windowStart int64
+	current     int64
+	previous    int64
+}
+
+var DefaultGroupRateLimiter = NewGroupRateLimiter(time.Minute)
+
+func NewGroupRateLimiter(window time.Duration) *GroupRateLimiter {
+	return &GroupRateLimiter{
+		Window:   window,
+		counters: make(map[string]*windowCounter),
+		now:      time.Now,
+	}
+}
+
+// Allow 校验 RPM 并计入一次请求，同时校验当前 TPM 用量是否已达上限。
+// 被拒绝时返回建议的重试等待时间。
+func (l *GroupRateLimiter) Allow(ctx context.Context, group, modelName string, limit ratio_setting.GroupModelRateLimit) (bool, time.Duration, error) {
+	if limit.TPM > 0 {
+		used, retryAfter, err := l.add(ctx, l.key(groupRateLimitKindToken, group, modelName), 0)
+		if err != nil {
+			return false, 0, err
+		}
+		if used >= float64(limit.TPM) {
+			return false, retryAfter, nil
+		}
+	}
+	if limit.RPM > 0 {
+		key := l.key(groupRateLimitKindRequest, group, modelName)
+		used, retryAfter, err := l.add(ctx, key, 1)
+		if err != nil {
+			return false, 0, err
+		}
+		if used > float64(limit.RPM) {
+			// 被拒绝的请求不占用额度
+			_, _, _ = l.add(ctx, key, -1)
+			return false, retryAfter, nil
+		}
+	}
+	return true, 0, nil
+}
+
+// RecordTokens 在请求完成后计入消耗的 token 数
+func (l *GroupRateLimiter) RecordTokens(ctx context.Context, group, modelName string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	_, _, err := l.add(ctx, l.key(groupRateLimitKindToken, group, modelName), int64(tokens))
+	return err
+}
+
+func (l *GroupRateLimiter) key(kind, group, modelName string) string {
+	return fmt.Sprintf("groupRateLimit:%s:%s:%s", kind, group, modelName)
+}
+
+// add 在当前窗口计入 delta，返回加权后的滑动窗口用量以及当前窗口剩余时间
+func (l *GroupRateLimiter) add(ctx context.Context, key string, delta int64) (float64, time.Duration, error) {
+	now := l.now()
+	window := l.Window.Milliseconds()
+	nowMs := now.UnixMilli()
+	windowStart := nowMs - nowMs%window
+	elapsed := float64(nowMs-windowStart) / float64(window)
+	retryAfter := time.Duration(windowStart+window-nowMs) * time.Millisecond
+
+	var current, previous int64
+	if common.RedisEnabled && common.RDB != nil {
+		var err error
+		current, previous, err = l.addRedis(ctx, key, windowStart, delta)
+		if err != nil {
+			return 0, 0, err
+		}
+	} else {
+		current, previous = l.addMemory(key, windowStart, delta)
+	}
+	return float64(previous)*(1-elapsed) + float64(current), retryAfter, nil
+}
+
+func (l *GroupRateLimiter) addRedis(ctx context.Context, key string, windowStart int64, delta int64) (int64, int64, error) {
+	window := l.Window.Milliseconds()
+	currentKey := fmt.Sprintf("%s:%d", key, windowStart)
+	previousKey := fmt.Sprintf("%s:%d", key, windowStart-window)
+	// 当前窗口的计数需要保留到下一个窗口结束，供其作为上一窗口参与加权
+	expireAt := time.UnixMilli(windowStart + 2*window)
+
+	pipe := common.RDB.Pipeline()
+	incr := pipe.IncrBy(ctx, currentKey, delta)
+	pipe.ExpireAt(ctx, currentKey, expireAt)
+	prev := pipe.Get(ctx, previousKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	previous, err := prev.Int64()
+	if err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	return incr.Val(), previous, nil
+}
+
+func (l *GroupRateLimiter) addMemory(key string, windowStart int64, delta int64) (int64, int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	counter, ok := l.counters[key]
+	if !ok {
+		counter = &windowCounter{windowStart: windowStart}
+		l.counters[key] = counter
+	}
+	if counter.windowStart != windowStart {
+		if windowStart-counter.windowStart == l.Window.Milliseconds() {
+			counter.previous = counter.current
+		} else {
+			counter.previous = 0
+		}
+		counter.current = 0
+		counter.windowStart = windowStart
+	}
+	counter.current += delta
+	return counter.current, counter.previous
+}
+
+// RetryAfterSeconds 将等待时间转换为 Retry-After 头使用的秒数，至少为 1
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+func newTestGroupRateLimiter(now *time.Time) *GroupRateLimiter {
+	l := NewGroupRateLimiter(time.Minute)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestGroupRateLimiterRPM(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	l := newTestGroupRateLimiter(&now)
+	ctx := context.Background()
+	limit := ratio_setting.GroupModelRateLimit{RPM: 2}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, err := l.Allow(ctx, "default", "gpt-4o", limit); err != nil || !ok {
+			t.Fatalf("request %d: expected allowed, got ok=%v err=%v", i, ok, err)
+		}
+	}
+	ok, retryAfter, err := l.Allow(ctx, "default", "gpt-4o", limit)
+	if err != nil || ok {
+		t.Fatalf("expected third request rejected, got ok=%v err=%v", ok, err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("unexpected retry after %v", retryAfter)
+	}
+
+	// 其他模型不受影响
+	if ok, _, _ := l.Allow(ctx, "default", "gpt-4o-mini", limit); !ok {
+		t.Fatal("expected a different model to be allowed")
+	}
+
+	// 下一窗口开始时上一窗口仍按权重计入
+	now = now.Add(time.Minute)
+	if ok, _, _ := l.Allow(ctx, "default", "gpt-4o", limit); ok {
+		t.Fatal("expected sliding window to still reject at window start")
+	}
+	now = now.Add(45 * time.Second)
+	if ok, _, _ := l.Allow(ctx, "default", "gpt-4o", limit); !ok {
+		t.Fatal("expected request allowed once previous window decays")
+	}
+}
+
+func TestGroupRateLimiterTPM(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0).Truncate(time.Minute)
+	l := newTestGroupRateLimiter(&now)
+	ctx := context.Background()
+	limit := ratio_setting.GroupModelRateLimit{TPM: 1000}
+
+	if ok, _, _ := l.Allow(ctx, "vip", "claude", limit); !ok {
+		t.Fatal("expected first request allowed")
+	}
+	if err := l.RecordTokens(ctx, "vip", "claude", 1000); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _, _ := l.Allow(ctx, "vip", "claude", limit); ok {
+		t.Fatal("expected request rejected after token limit reached")
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _, _ := l.Allow(ctx, "vip", "claude", limit); !ok {
+		t.Fatal("expected request allowed after window expired")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	if got := RetryAfterSeconds(100 * time.Millisecond); got != 1 {
+		t.Fatalf("expected 1, got %d", got)
+	}
+	if got := RetryAfterSeconds(2500 * time.Millisecond); got != 3 {
+		t.Fatalf("expected 3, got %d", got)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -12,11 +13,14 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/setting/system_setting"
 
+	"golang.org/x/net/http2"
 	"golang.org/x/net/proxy"
 )
 
 var (
 	httpClient      *http.Client
+	http2Client     *http.Client
+	http2ClientOnce sync.Once
 	proxyClientLock sync.Mutex
 	proxyClients    = make(map[string]*http.Client)
 )
@@ -59,6 +63,41 @@ func GetHttpClient() *http.Client {
 	return httpClient
 }
 
+// GetHTTP2Client 返回优先使用 HTTP/2 的客户端，连接在多个请求间多路复用并定期 ping 检测失效连接。
+// https 上游通过 TLS ALPN 协商为 h2，http 上游回退到 HTTP/1.1
+func GetHTTP2Client() *http.Client {
+	http2ClientOnce.Do(func() {
+		http2Client = NewHTTP2Client(nil)
+	})
+	return http2Client
+}
+
+// NewHTTP2Client 使用给定的 TLS 配置创建 HTTP/2 客户端，tlsConfig 为空时使用系统默认配置。
+// 代理与空闲连接配置与默认客户端一致
+func NewHTTP2Client(tlsConfig *tls.Config) *http.Client {
+	transport := &http.Transport{
+		MaxIdleConns:        common.RelayMaxIdleConns,
+		MaxIdleConnsPerHost: common.RelayMaxIdleConnsPerHost,
+		ForceAttemptHTTP2:   true,
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+	}
+	if h2Transport, err := http2.ConfigureTransports(transport); err != nil {
+		common.SysError("configure http2 transport failed: " + err.Error())
+	} else {
+		h2Transport.ReadIdleTimeout = 30 * time.Second
+		h2Transport.PingTimeout = 15 * time.Second
+	}
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: checkRedirect,
+	}
+	if common.RelayTimeout != 0 {
+		client.Timeout = time.Duration(common.RelayTimeout) * time.Second
+	}
+	return client
+}
+
 // GetHttpClientWithProxy returns the default client or a proxy-enabled one when proxyURL is provided.
 func GetHttpClientWithProxy(proxyURL string) (*http.Client, error) {
 	if proxyURL == "" {
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewHTTP2ClientNegotiatesH2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || r.TLS.NegotiatedProtocol != "h2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+	client := NewHTTP2Client(tlsConfig)
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+			t.Fatalf("status = %d, proto = %s, want 200 over HTTP/2", resp.StatusCode, resp.Proto)
+		}
+	}
+
+	// 未信任服务端证书时 TLS 握手失败
+	if _, err := NewHTTP2Client(nil).Get(server.URL); err == nil {
+		t.Fatal("expected TLS verification error without the server certificate")
+	}
+}
+
+func TestNewHTTP2ClientFallsBackForPlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	defer server.Close()
+
+	client := NewHTTP2Client(nil)
+	if _, ok := client.Transport.(*http.Transport); !ok {
+		t.Fatalf("transport = %T, want *http.Transport", client.Transport)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("plain http request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 1 {
+		t.Fatalf("status = %d, proto = %s, want 200 over HTTP/1.1", resp.StatusCode, resp.Proto)
+	}
+}
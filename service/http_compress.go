@@ -0,0 +1,72 @@
+package service
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// UpstreamAcceptEncoding 请求上游时声明支持的压缩格式
+const UpstreamAcceptEncoding = "gzip, br"
+
+type decompressReadCloser struct {
+	io.Reader
+	closeFn func() error
+}
+
+func (rc *decompressReadCloser) Close() error {
+	return rc.closeFn()
+}
+
+// DecompressResponseBody 按 Content-Encoding 将响应体替换为解压后的流，并移除压缩相关的响应头，
+// 之后的读取（包括流式读取）拿到的都是原始数据。未压缩或已由 http.Transport 解压的响应不做处理
+func DecompressResponseBody(resp *http.Response) error {
+	if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	origBody := resp.Body
+	switch encoding {
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(origBody)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				// 空响应体
+				break
+			}
+			return fmt.Errorf("create gzip reader failed: %w", err)
+		}
+		resp.Body = &decompressReadCloser{
+			Reader: gzipReader,
+			closeFn: func() error {
+				_ = gzipReader.Close()
+				return origBody.Close()
+			},
+		}
+	case "br":
+		resp.Body = &decompressReadCloser{
+			Reader:  brotli.NewReader(origBody),
+			closeFn: origBody.Close,
+		}
+	default:
+		return nil
+	}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return nil
+}
+
+// ReadCompressedBody 读取完整的上游响应体，按 Content-Encoding 透明解压 gzip 与 brotli
+func ReadCompressedBody(resp *http.Response) ([]byte, error) {
+	if err := DecompressResponseBody(resp); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestReadCompressedBody(t *testing.T) {
+	payload := []byte(`{"status":"succeeded","video":"` + string(bytes.Repeat([]byte("A"), 4096)) + `"}`)
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	_, _ = gw.Write(payload)
+	_ = gw.Close()
+
+	var brotlied bytes.Buffer
+	bw := brotli.NewWriter(&brotlied)
+	_, _ = bw.Write(payload)
+	_ = bw.Close()
+
+	cases := map[string][]byte{
+		"":     payload,
+		"gzip": gzipped.Bytes(),
+		"br":   brotlied.Bytes(),
+	}
+	for encoding, body := range cases {
+		resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(body)), ContentLength: int64(len(body))}
+		if encoding != "" {
+			resp.Header.Set("Content-Encoding", encoding)
+		}
+		got, err := ReadCompressedBody(resp)
+		if err != nil {
+			t.Fatalf("%q: ReadCompressedBody: %v", encoding, err)
+		}
+		if !bytes.Equal(got, payload) {
+			t.Errorf("%q: decoded body mismatch, got %d bytes", encoding, len(got))
+		}
+		if resp.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%q: Content-Encoding header not removed", encoding)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Content-Encoding": {"gzip"}}, Body: io.NopCloser(bytes.NewReader(nil))}
+	if got, err := ReadCompressedBody(resp); err != nil || len(got) != 0 {
+		t.Errorf("empty gzip body: %q, err %v", got, err)
+	}
+	resp = &http.Response{Header: http.Header{"Content-Encoding": {"gzip"}}, Body: io.NopCloser(bytes.NewReader([]byte("not gzip")))}
+	if _, err := ReadCompressedBody(resp); err == nil {
+		t.Error("expected error for invalid gzip body")
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed i18n/task_errors.yaml
+var defaultTaskErrorTemplates []byte
+
+// SupportedLanguages 错误信息支持的语言标签
+var SupportedLanguages = []string{"zh-CN", "en-US", "ja-JP"}
+
+var languageMatcher = language.NewMatcher(func() []language.Tag {
+	tags := make([]language.Tag, 0, len(SupportedLanguages))
+	for _, lang := range SupportedLanguages {
+		tags = append(tags, language.MustParse(lang))
+	}
+	return tags
+}())
+
+// I18n 按错误码和语言标签查找错误信息模板，模板中的 {message} 会被替换为原始错误信息
+type I18n struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]string
+}
+
+var DefaultI18n = NewI18n()
+
+func NewI18n() *I18n {
+	i := &I18n{templates: make(map[string]map[string]string)}
+	if err := i.Load(defaultTaskErrorTemplates); err != nil {
+		common.SysError("failed to load default task error templates: " + err.Error())
+	}
+	return i
+}
+
+// Load 合并 YAML 格式的模板（错误码 -> 语言标签 -> 模板），已有的模板会被覆盖
+func (i *I18n) Load(data []byte) error {
+	var templates map[string]map[string]string
+	if err := yaml.Unmarshal(data, &templates); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for code, langs := range templates {
+		if i.templates[code] == nil {
+			i.templates[code] = make(map[string]string, len(langs))
+		}
+		for lang, tmpl := range langs {
+			i.templates[code][lang] = tmpl
+		}
+	}
+	return nil
+}
+
+// LoadFile 从文件加载模板，用于覆盖内置模板
+func (i *I18n) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := i.Load(data); err != nil {
+		return fmt.Errorf("parse %s failed: %w", path, err)
+	}
+	return nil
+}
+
+// Message 返回本地化后的错误信息，lang 为空或没有对应模板时返回原始信息
+func (i *I18n) Message(code string, lang string, message string) string {
+	if lang == "" {
+		return message
+	}
+	i.mu.RLock()
+	tmpl, ok := i.templates[code][lang]
+	i.mu.RUnlock()
+	if !ok {
+		return message
+	}
+	return strings.ReplaceAll(tmpl, "{message}", message)
+}
+
+// MatchLanguage 根据 Accept-Language 请求头匹配支持的语言，未携带或无法匹配时返回空字符串
+func MatchLanguage(acceptLanguage string) string {
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return ""
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	_, index, confidence := languageMatcher.Match(tags...)
+	if confidence == language.No {
+		return ""
+	}
+	return SupportedLanguages[index]
+}
+
+// LocalizeTaskError 按错误码将 TaskError 的信息替换为对应语言的模板，只应在返回给客户端前调用一次
+func LocalizeTaskError(taskErr *dto.TaskError, lang string) {
+	if taskErr == nil {
+		return
+	}
+	taskErr.Message = DefaultI18n.Message(taskErr.Code, lang, taskErr.Message)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestMatchLanguage(t *testing.T) {
+	cases := map[string]string{
+		"":                          "",
+		"en-US,en;q=0.9":            "en-US",
+		"en":                        "en-US",
+		"ja":                        "ja-JP",
+		"zh-TW,zh;q=0.9":            "zh-CN",
+		"fr-FR,ja;q=0.8,en;q=0.5":   "ja-JP",
+		"de-DE":                     "",
+		"not a language tag at all": "",
+	}
+	for header, want := range cases {
+		if got := MatchLanguage(header); got != want {
+			t.Errorf("MatchLanguage(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestLocalizeTaskError(t *testing.T) {
+	taskErr := TaskErrorWrapperLocal(errors.New("prompt is required"), "invalid_request", http.StatusBadRequest)
+	LocalizeTaskError(taskErr, "")
+	if taskErr.Message != "prompt is required" {
+		t.Fatalf("message without language = %q, want unchanged", taskErr.Message)
+	}
+	LocalizeTaskError(taskErr, "en-US")
+	if taskErr.Message != "Invalid request: prompt is required" {
+		t.Fatalf("localized message = %q", taskErr.Message)
+	}
+
+	unknown := TaskErrorWrapperLocal(errors.New("boom"), "no_such_code", http.StatusBadRequest)
+	LocalizeTaskError(unknown, "ja-JP")
+	if unknown.Message != "boom" {
+		t.Fatalf("message for unknown code = %q, want unchanged", unknown.Message)
+	}
+
+	i := NewI18n()
+	if err := i.Load([]byte("invalid_request:\n  en-US: \"Bad request ({message})\"\n")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := i.Message("invalid_request", "en-US", "x"); got != "Bad request (x)" {
+		t.Errorf("overridden template = %q", got)
+	}
+	if got := i.Message("invalid_request", "ja-JP", "x"); got != "無効なリクエストです: x" {
+		t.Errorf("built-in template should be kept, got %q", got)
+	}
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+var modelSpendingCapResetOnce sync.Once
+
+// AutomaticallyResetModelSpendingCaps 每天零点清零已进入新周期的模型消费上限，仅在主节点运行。
+// 周、月周期的起点都是某天零点，因此按天检查即可覆盖
+func AutomaticallyResetModelSpendingCaps() {
+	if !common.IsMasterNode {
+		return
+	}
+	modelSpendingCapResetOnce.Do(func() {
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+			time.Sleep(next.Sub(now))
+			count, err := model.ResetExpiredModelSpendingCaps(time.Now())
+			if err != nil {
+				common.SysLog("failed to reset model spending caps: " + err.Error())
+				continue
+			}
+			if count > 0 {
+				common.SysLog(fmt.Sprintf("reset %d model spending caps", count))
+			}
+		}
+	})
+}
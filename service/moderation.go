@@ -0,0 +1,97 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+type moderationRequest struct {
+	Model string `json:"model,omitempty"`
+	Input string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// ModerationResult 内容审核结果
+type ModerationResult struct {
+	Violated bool
+	// Categories 触发违规的类别
+	Categories []string
+	MaxScore   float64
+}
+
+// ModeratePrompt 调用 OpenAI 兼容的审核接口检查 prompt。
+// threshold > 0 时任一类别分数不低于阈值即视为违规，否则以接口返回的 flagged 为准
+func ModeratePrompt(ctx context.Context, prompt string, threshold float64) (*ModerationResult, error) {
+	setting := ratio_setting.GetGroupModerationSetting()
+	body, err := common.Marshal(moderationRequest{Model: setting.Model, Input: prompt})
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(setting.TimeoutSeconds) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+setting.ApiKey)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer CloseResponseBodyGracefully(resp)
+	respBody, err := ReadCompressedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("moderation endpoint returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	var moderationResp moderationResponse
+	if err := common.Unmarshal(respBody, &moderationResp); err != nil {
+		return nil, fmt.Errorf("decode moderation response failed: %w", err)
+	}
+
+	result := &ModerationResult{}
+	for _, r := range moderationResp.Results {
+		for category, score := range r.CategoryScores {
+			result.MaxScore = max(result.MaxScore, score)
+			if threshold > 0 && score >= threshold {
+				result.Categories = append(result.Categories, category)
+			}
+		}
+		if threshold <= 0 && r.Flagged {
+			for category, flagged := range r.Categories {
+				if flagged {
+					result.Categories = append(result.Categories, category)
+				}
+			}
+			result.Violated = true
+		}
+	}
+	if threshold > 0 {
+		result.Violated = len(result.Categories) > 0
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
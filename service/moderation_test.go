@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+func TestModeratePrompt(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"sexual":false},"category_scores":{"violence":0.6,"sexual":0.2}}]}`))
+	}))
+	defer server.Close()
+
+	setting := ratio_setting.GetGroupModerationSetting()
+	prevSetting := *setting
+	setting.Endpoint = server.URL
+	setting.ApiKey = "sk-test"
+	t.Cleanup(func() { *setting = prevSetting })
+	InitHttpClient()
+
+	ctx := context.Background()
+	result, err := ModeratePrompt(ctx, "prompt", 0)
+	if err != nil {
+		t.Fatalf("ModeratePrompt() error = %v", err)
+	}
+	if gotAuth != "Bearer sk-test" {
+		t.Fatalf("Authorization = %q", gotAuth)
+	}
+	if !result.Violated || !reflect.DeepEqual(result.Categories, []string{"violence"}) || result.MaxScore != 0.6 {
+		t.Fatalf("flagged result = %+v", result)
+	}
+
+	// 阈值优先于 flagged
+	result, err = ModeratePrompt(ctx, "prompt", 0.8)
+	if err != nil {
+		t.Fatalf("ModeratePrompt() error = %v", err)
+	}
+	if result.Violated {
+		t.Fatalf("expected score below threshold to pass, got %+v", result)
+	}
+	result, err = ModeratePrompt(ctx, "prompt", 0.1)
+	if err != nil {
+		t.Fatalf("ModeratePrompt() error = %v", err)
+	}
+	if !result.Violated || !reflect.DeepEqual(result.Categories, []string{"sexual", "violence"}) {
+		t.Fatalf("threshold result = %+v", result)
+	}
+}
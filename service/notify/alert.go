@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+// ChannelAlert describes a channel whose error rate exceeded the threshold
+// within one aggregation window.
+type ChannelAlert struct {
+	ChannelId   int              `json:"channel_id"`
+	ChannelName string           `json:"channel_name"`
+	WindowStart int64            `json:"window_start"`
+	WindowEnd   int64            `json:"window_end"`
+	Total       int64            `json:"total"`
+	Errors      int64            `json:"errors"`
+	ErrorRate   float64          `json:"error_rate"`
+	ErrorCodes  map[string]int64 `json:"error_codes"`
+	Disabled    bool             `json:"disabled"`
+}
+
+func (a *ChannelAlert) Subject() string {
+	return fmt.Sprintf("通道「%s」（#%d）错误率过高：%.1f%%", a.ChannelName, a.ChannelId, a.ErrorRate*100)
+}
+
+func (a *ChannelAlert) Content() string {
+	codes := make([]string, 0, len(a.ErrorCodes))
+	for code := range a.ErrorCodes {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return a.ErrorCodes[codes[i]] > a.ErrorCodes[codes[j]] })
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("通道「%s」（#%d）在 %s 至 %s 期间共 %d 次请求，失败 %d 次，错误率 %.1f%%。<br/>",
+		a.ChannelName, a.ChannelId,
+		time.Unix(a.WindowStart, 0).Format(time.DateTime), time.Unix(a.WindowEnd, 0).Format(time.DateTime),
+		a.Total, a.Errors, a.ErrorRate*100))
+	for _, code := range codes {
+		sb.WriteString(fmt.Sprintf("%s: %d<br/>", code, a.ErrorCodes[code]))
+	}
+	if a.Disabled {
+		sb.WriteString("该通道已被自动禁用。")
+	}
+	return sb.String()
+}
+
+// AlertSender delivers channel health alerts.
+type AlertSender interface {
+	SendAlert(alert *ChannelAlert) error
+}
+
+// EmailAlertSender sends alerts through the configured SMTP server.
+type EmailAlertSender struct {
+	Receiver string
+}
+
+func (s *EmailAlertSender) SendAlert(alert *ChannelAlert) error {
+	return common.SendEmail(alert.Subject(), s.Receiver, alert.Content())
+}
+
+// WebhookAlertSender posts alerts as JSON, signed with X-Webhook-Signature
+// when a secret is configured.
+type WebhookAlertSender struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+type webhookAlertPayload struct {
+	Type      string        `json:"type"`
+	Title     string        `json:"title"`
+	Alert     *ChannelAlert `json:"alert"`
+	Timestamp int64         `json:"timestamp"`
+}
+
+func (s *WebhookAlertSender) SendAlert(alert *ChannelAlert) error {
+	fetchSetting := system_setting.GetFetchSetting()
+	if err := common.ValidateURLWithFetchSetting(s.URL, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+		return fmt.Errorf("request reject: %v", err)
+	}
+
+	body, err := json.Marshal(webhookAlertPayload{
+		Type:      "channel_health_alert",
+		Title:     alert.Subject(),
+		Alert:     alert,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert payload: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Secret != "" {
+		h := hmac.New(sha256.New, []byte(s.Secret))
+		h.Write(body)
+		req.Header.Set("X-Webhook-Signature", hex.EncodeToString(h.Sum(nil)))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert request failed with status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MultiAlertSender fans an alert out to several senders and returns the
+// first error encountered after trying all of them.
+type MultiAlertSender []AlertSender
+
+func (m MultiAlertSender) SendAlert(alert *ChannelAlert) error {
+	var firstErr error
+	for _, sender := range m {
+		if err := sender.SendAlert(alert); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service/events"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/setting/system_setting"
 	"github.com/QuantumNous/new-api/types"
@@ -219,6 +220,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 	}
 	other := GenerateWssOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	common.SetContextKey(ctx, constant.ContextKeyUsageTotalTokens, usage.InputTokens+usage.OutputTokens)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     usage.InputTokens,
@@ -301,6 +303,9 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	totalTokens := promptTokens + completionTokens
 
 	var logContent string
+	if common.GetContextKeyBool(ctx, constant.ContextKeyStreamQuotaExceeded) {
+		logContent += "流式输出过程中额度不足，已中断"
+	}
 	// record all the consume log even if quota is 0
 	if totalTokens == 0 {
 		// in this case, must be some error happened
@@ -343,6 +348,7 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		cacheCreationTokens5m, cacheCreationRatio5m,
 		cacheCreationTokens1h, cacheCreationRatio1h,
 		modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	common.SetContextKey(ctx, constant.ContextKeyUsageTotalTokens, promptTokens+completionTokens)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     promptTokens,
@@ -468,6 +474,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 	}
 	other := GenerateAudioOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
 		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice, relayInfo.PriceData.GroupRatioInfo.GroupSpecialRatio)
+	common.SetContextKey(ctx, constant.ContextKeyUsageTotalTokens, usage.PromptTokens+usage.CompletionTokens)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     usage.PromptTokens,
@@ -530,13 +537,66 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		}
 	}
 
+	afterQuotaConsumed(relayInfo, quota, preConsumedQuota, sendEmail)
+	return nil
+}
+
+// confirmReservationAttempts 确认预留的最大尝试次数，上游已接受请求，确认失败的预留会在过期后被退款
+const confirmReservationAttempts = 3
+
+// ConfirmQuotaReservation 确认预留的用户与令牌额度，效果等同于 PostConsumeQuota 扣费。
+// 额度在预留时已扣除，因此某个预留确认失败时仍会确认其余预留并执行扣费后的统计
+func ConfirmQuotaReservation(relayInfo *relaycommon.RelayInfo, quota int, reservationIds ...int64) error {
+	var errs []error
+	for _, id := range reservationIds {
+		if err := confirmReservation(id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	afterQuotaConsumed(relayInfo, quota, 0, true)
+	return errors.Join(errs...)
+}
+
+// confirmReservation 确认单个预留，数据库错误时重试，预留已不是待确认状态时直接返回
+func confirmReservation(reservationId int64) error {
+	var err error
+	for attempt := 1; attempt <= confirmReservationAttempts; attempt++ {
+		err = model.ConfirmReservation(reservationId)
+		if err == nil || errors.Is(err, model.ErrReservationNotPending) {
+			return err
+		}
+		if attempt < confirmReservationAttempts {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+	}
+	return err
+}
+
+// ReleaseQuotaReservation 释放预留的额度
+func ReleaseQuotaReservation(reservationIds ...int64) {
+	for _, id := range reservationIds {
+		if err := model.ReleaseReservation(id); err != nil {
+			common.SysLog(fmt.Sprintf("failed to release quota reservation %d: %s", id, err.Error()))
+		}
+	}
+}
+
+func afterQuotaConsumed(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) {
 	if sendEmail {
 		if (quota + preConsumedQuota) != 0 {
 			checkAndSendQuotaNotify(relayInfo, quota, preConsumedQuota)
 		}
 	}
 
-	return nil
+	events.Publish(&events.QuotaDeductedEvent{
+		UserId:           relayInfo.UserId,
+		TokenId:          relayInfo.TokenId,
+		ChannelId:        relayInfo.ChannelId,
+		ModelName:        relayInfo.OriginModelName,
+		Quota:            quota,
+		PreConsumedQuota: preConsumedQuota,
+		Timestamp:        time.Now().Unix(),
+	})
 }
 
 func checkAndSendQuotaNotify(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int) {
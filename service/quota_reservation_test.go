@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service/events"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConfirmQuotaReservationConfirmsAllIds(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.QuotaReservation{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() { model.DB, common.UsingSQLite = originDB, originSQLite })
+
+	released := &model.QuotaReservation{UserId: 1, Amount: 10, Status: model.QuotaReservationStatusReleased}
+	pending := &model.QuotaReservation{UserId: 1, TokenId: 2, Amount: 10, Status: model.QuotaReservationStatusPending}
+	for _, r := range []*model.QuotaReservation{released, pending} {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatalf("create reservation failed: %v", err)
+		}
+	}
+	mem := events.NewMemorySubscriber()
+	unsubscribe := events.DefaultBus.Subscribe(mem)
+	defer unsubscribe()
+
+	info := &relaycommon.RelayInfo{UserId: 1, TokenId: 2, ChannelMeta: &relaycommon.ChannelMeta{}}
+	err = ConfirmQuotaReservation(info, 0, released.Id, pending.Id)
+	if !errors.Is(err, model.ErrReservationNotPending) {
+		t.Fatalf("expected not pending error, got %v", err)
+	}
+	var stored model.QuotaReservation
+	db.First(&stored, pending.Id)
+	if stored.Status != model.QuotaReservationStatusConfirmed {
+		t.Fatalf("token reservation status = %s, want confirmed", stored.Status)
+	}
+	deducted := false
+	for _, event := range mem.Events() {
+		if e, ok := event.Payload().(*events.QuotaDeductedEvent); ok && e.UserId == 1 {
+			deducted = true
+		}
+	}
+	if !deducted {
+		t.Fatal("expected post-consume accounting to run")
+	}
+}
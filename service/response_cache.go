@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/go-redis/redis/v8"
+)
+
+const responseCacheKeyPrefix = "response_cache:"
+
+// CachedResponse 缓存的上游响应
+type CachedResponse struct {
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// ResponseCache 按模型配置的有效期（ratio_setting.ResponseCacheSetting）缓存非流式响应，
+// 仅在启用 Redis 时生效。缓存按用户隔离，避免不同用户之间共享生成结果
+type ResponseCache struct {
+	// HitLogInterval 命中日志按用户与模型汇总后写入的间隔
+	HitLogInterval time.Duration
+
+	hitsMu  sync.Mutex
+	hits    map[responseCacheHit]int
+	logOnce sync.Once
+}
+
+type responseCacheHit struct {
+	userId    int
+	modelName string
+}
+
+var DefaultResponseCache = &ResponseCache{HitLogInterval: time.Minute}
+
+// Key 根据用户、请求路径、模型与请求体计算 SHA-256 缓存键。JSON 请求体按字段名排序并去除空白后参与计算，
+// 字段顺序或格式不同的相同请求得到相同的键
+func (rc *ResponseCache) Key(userId int, path string, modelName string, body []byte) string {
+	body = canonicalJSON(body)
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d\n%s\n%s\n", userId, path, modelName)
+	h.Write(body)
+	return responseCacheKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalJSON 将 JSON 按字段名排序并去除空白，非 JSON 内容原样返回
+func canonicalJSON(body []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return body
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+// Get 返回命中的缓存响应，未启用 Redis 或未命中时返回 false
+func (rc *ResponseCache) Get(key string) (*CachedResponse, bool) {
+	if !common.RedisEnabled {
+		return nil, false
+	}
+	val, err := common.RedisGet(key)
+	if err != nil {
+		if err != redis.Nil {
+			common.SysLog("failed to get response cache: " + err.Error())
+		}
+		return nil, false
+	}
+	var resp CachedResponse
+	if err := common.UnmarshalJsonStr(val, &resp); err != nil || len(resp.Body) == 0 {
+		return nil, false
+	}
+	return &resp, true
+}
+
+// Set 写入缓存，空响应不缓存
+func (rc *ResponseCache) Set(key string, resp *CachedResponse, ttl time.Duration) {
+	if !common.RedisEnabled || resp == nil || len(resp.Body) == 0 || ttl <= 0 {
+		return
+	}
+	data, err := common.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if err := common.RedisSet(key, string(data), ttl); err != nil {
+		common.SysLog("failed to set response cache: " + err.Error())
+	}
+}
+
+// RecordHit 记录一次缓存命中。命中日志按用户与模型汇总，每 HitLogInterval 写入一次，避免每次命中都写一行日志
+func (rc *ResponseCache) RecordHit(userId int, modelName string) {
+	rc.hitsMu.Lock()
+	if rc.hits == nil {
+		rc.hits = make(map[responseCacheHit]int)
+	}
+	rc.hits[responseCacheHit{userId: userId, modelName: modelName}]++
+	rc.hitsMu.Unlock()
+	rc.logOnce.Do(func() {
+		gopool.Go(func() {
+			for {
+				time.Sleep(rc.HitLogInterval)
+				rc.FlushHitLogs()
+			}
+		})
+	})
+}
+
+// FlushHitLogs 将汇总的命中次数写入用户日志
+func (rc *ResponseCache) FlushHitLogs() {
+	rc.hitsMu.Lock()
+	hits := rc.hits
+	rc.hits = nil
+	rc.hitsMu.Unlock()
+	for hit, count := range hits {
+		model.RecordLog(hit.userId, model.LogTypeSystem, fmt.Sprintf("%d 次请求命中响应缓存，模型 %s，未扣费", count, hit.modelName))
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	cache := &ResponseCache{}
+	body := []byte(`{"model":"flux-dev","prompt":"a cat","seed":42}`)
+	key := cache.Key(1, "/v1/images/generations", "flux-dev", body)
+
+	if got := cache.Key(1, "/v1/images/generations", "flux-dev", []byte("{\n  \"model\": \"flux-dev\", \"prompt\": \"a cat\", \"seed\": 42\n}")); got != key {
+		t.Fatalf("whitespace should not change the key: %s != %s", got, key)
+	}
+	if got := cache.Key(1, "/v1/images/generations", "flux-dev", []byte(`{"seed":42,"prompt":"a cat","model":"flux-dev"}`)); got != key {
+		t.Fatalf("field order should not change the key: %s != %s", got, key)
+	}
+	for name, got := range map[string]string{
+		"user":  cache.Key(2, "/v1/images/generations", "flux-dev", body),
+		"path":  cache.Key(1, "/v1/images/edits", "flux-dev", body),
+		"model": cache.Key(1, "/v1/images/generations", "flux-pro", body),
+		"seed":  cache.Key(1, "/v1/images/generations", "flux-dev", []byte(`{"model":"flux-dev","prompt":"a cat","seed":7}`)),
+		"large": cache.Key(1, "/v1/images/generations", "flux-dev", []byte(`{"model":"flux-dev","prompt":"a cat","seed":9007199254740993}`)),
+	} {
+		if got == key {
+			t.Errorf("%s change should produce a different key", name)
+		}
+	}
+	if cache.Key(1, "/v1/images/generations", "flux-dev", []byte(`{"seed":9007199254740993}`)) == cache.Key(1, "/v1/images/generations", "flux-dev", []byte(`{"seed":9007199254740992}`)) {
+		t.Error("large integers should keep their precision")
+	}
+}
+
+func TestResponseCacheHitLogsAreBatched(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}, &model.Log{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originLogDB, originRedis := model.DB, model.LOG_DB, common.RedisEnabled
+	model.DB, model.LOG_DB, common.RedisEnabled = db, db, false
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB, common.RedisEnabled = originDB, originLogDB, originRedis
+	})
+
+	// 间隔足够长，由测试手动写入
+	cache := &ResponseCache{HitLogInterval: 24 * time.Hour}
+	for i := 0; i < 3; i++ {
+		cache.RecordHit(1, "flux-dev")
+	}
+	cache.RecordHit(2, "flux-dev")
+	var count int64
+	db.Model(&model.Log{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("hits should not be logged individually, got %d logs", count)
+	}
+
+	cache.FlushHitLogs()
+	var logs []model.Log
+	db.Order("user_id").Find(&logs)
+	if len(logs) != 2 || logs[0].UserId != 1 || logs[0].Content != "3 次请求命中响应缓存，模型 flux-dev，未扣费" {
+		t.Fatalf("unexpected logs: %+v", logs)
+	}
+	cache.FlushHitLogs()
+	db.Model(&model.Log{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("flushing again should not write logs, got %d", count)
+	}
+}
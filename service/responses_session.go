@@ -0,0 +1,132 @@
+package service
+
+import (
+	"container/list"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// Responses API 的 response id 只在创建它的上游账号内有效，
+// 记录 response id 所属渠道，使携带 previous_response_id 的后续请求回到同一渠道
+const (
+	// 与 OpenAI 对已存储 response 的保留期一致
+	responsesSessionTTL = 30 * 24 * time.Hour
+	// 内存模式下最多保留的记录数，超出时淘汰最久未使用的记录
+	responsesSessionMaxSize = 10000
+)
+
+type responsesSession struct {
+	responseId string
+	userId     int
+	channelId  int
+	expiresAt  time.Time
+}
+
+// responsesSessionCache 内存模式下的会话记录，按最近使用顺序保存，容量有上限，读取时检查过期
+type responsesSessionCache struct {
+	mu      sync.Mutex
+	maxSize int
+	order   *list.List
+	items   map[string]*list.Element
+}
+
+func newResponsesSessionCache(maxSize int) *responsesSessionCache {
+	return &responsesSessionCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+	}
+}
+
+func (c *responsesSessionCache) set(session responsesSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[session.responseId]; ok {
+		elem.Value = session
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[session.responseId] = c.order.PushFront(session)
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(responsesSession).responseId)
+	}
+}
+
+func (c *responsesSessionCache) get(responseId string, now time.Time) (responsesSession, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[responseId]
+	if !ok {
+		return responsesSession{}, false
+	}
+	session := elem.Value.(responsesSession)
+	if now.After(session.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, responseId)
+		return responsesSession{}, false
+	}
+	c.order.MoveToFront(elem)
+	return session, true
+}
+
+var responsesSessions = newResponsesSessionCache(responsesSessionMaxSize)
+
+func responsesSessionKey(responseId string) string {
+	return "responses_session:" + responseId
+}
+
+// RecordResponsesSession 记录 response id 由哪个用户在哪个渠道上创建
+func RecordResponsesSession(responseId string, userId int, channelId int) {
+	if responseId == "" || channelId == 0 {
+		return
+	}
+	if common.RedisEnabled {
+		value := fmt.Sprintf("%d:%d", userId, channelId)
+		if err := common.RedisSet(responsesSessionKey(responseId), value, responsesSessionTTL); err != nil {
+			common.SysLog("failed to record responses session: " + err.Error())
+		}
+		return
+	}
+
+	responsesSessions.set(responsesSession{
+		responseId: responseId,
+		userId:     userId,
+		channelId:  channelId,
+		expiresAt:  time.Now().Add(responsesSessionTTL),
+	})
+}
+
+// GetResponsesSessionChannel 返回创建该 response id 的渠道，仅对同一用户生效
+func GetResponsesSessionChannel(responseId string, userId int) (int, bool) {
+	if responseId == "" {
+		return 0, false
+	}
+	if common.RedisEnabled {
+		value, err := common.RedisGet(responsesSessionKey(responseId))
+		if err != nil {
+			return 0, false
+		}
+		owner, channel, found := strings.Cut(value, ":")
+		if !found || owner != strconv.Itoa(userId) {
+			return 0, false
+		}
+		channelId, err := strconv.Atoi(channel)
+		if err != nil {
+			return 0, false
+		}
+		return channelId, true
+	}
+
+	session, ok := responsesSessions.get(responseId, time.Now())
+	if !ok || session.userId != userId {
+		return 0, false
+	}
+	return session.channelId, true
+}
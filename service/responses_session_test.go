@@ -0,0 +1,54 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestResponsesSession(t *testing.T) {
+	prevRedis := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = prevRedis })
+
+	RecordResponsesSession("resp_1", 7, 42)
+
+	channelId, ok := GetResponsesSessionChannel("resp_1", 7)
+	if !ok || channelId != 42 {
+		t.Fatalf("GetResponsesSessionChannel = %d, %v, want 42, true", channelId, ok)
+	}
+	if _, ok := GetResponsesSessionChannel("resp_1", 8); ok {
+		t.Error("session should not resolve for another user")
+	}
+	if _, ok := GetResponsesSessionChannel("resp_missing", 7); ok {
+		t.Error("unknown response id should not resolve")
+	}
+
+	responsesSessions.set(responsesSession{responseId: "resp_1", userId: 7, channelId: 42, expiresAt: time.Now().Add(-time.Second)})
+	if _, ok := GetResponsesSessionChannel("resp_1", 7); ok {
+		t.Error("expired session should not resolve")
+	}
+}
+
+func TestResponsesSessionCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponsesSessionCache(2)
+	now := time.Now()
+	expiresAt := now.Add(time.Hour)
+	cache.set(responsesSession{responseId: "a", channelId: 1, expiresAt: expiresAt})
+	cache.set(responsesSession{responseId: "b", channelId: 2, expiresAt: expiresAt})
+	// 读取 a 后 b 成为最久未使用的记录
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatal("a should be cached")
+	}
+	cache.set(responsesSession{responseId: "c", channelId: 3, expiresAt: expiresAt})
+	if _, ok := cache.get("b", now); ok {
+		t.Error("b should be evicted")
+	}
+	if _, ok := cache.get("a", now); !ok {
+		t.Error("a should be kept")
+	}
+	if cache.order.Len() != 2 || len(cache.items) != 2 {
+		t.Errorf("cache size = %d/%d, want 2", cache.order.Len(), len(cache.items))
+	}
+}
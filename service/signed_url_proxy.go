@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// S3 签名链接的最长有效期
+	maxSignedURLTTL = 7 * 24 * time.Hour
+)
+
+// SignedURLProxy 将任务产出的上游视频转存到 S3 兼容的对象存储，并返回带有效期的签名链接，
+// 避免上游链接过期后无法访问。对象以 path-style（endpoint/bucket/key）寻址
+type SignedURLProxy struct {
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyId     string
+	SecretAccessKey string
+	TTL             time.Duration
+
+	signer   *v4.Signer
+	inflight sync.Map
+}
+
+var (
+	signedURLProxy     *SignedURLProxy
+	signedURLProxyOnce sync.Once
+)
+
+// GetSignedURLProxy 返回按环境变量配置的转存代理，未配置对象存储时返回 nil
+func GetSignedURLProxy() *SignedURLProxy {
+	signedURLProxyOnce.Do(func() {
+		if constant.TaskStorageEndpoint == "" || constant.TaskStorageBucket == "" {
+			return
+		}
+		signedURLProxy = NewSignedURLProxy(constant.TaskStorageEndpoint, constant.TaskStorageBucket, constant.TaskStorageRegion,
+			constant.TaskStorageAccessKeyId, constant.TaskStorageSecretAccessKey, time.Duration(constant.TaskStorageURLTTLSeconds)*time.Second)
+	})
+	return signedURLProxy
+}
+
+// SetSignedURLProxy 替换按环境变量初始化的转存代理，传入 nil 时关闭转存
+func SetSignedURLProxy(p *SignedURLProxy) {
+	signedURLProxyOnce.Do(func() {})
+	signedURLProxy = p
+}
+
+func NewSignedURLProxy(endpoint, bucket, region, accessKeyId, secretAccessKey string, ttl time.Duration) *SignedURLProxy {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if ttl <= 0 || ttl > maxSignedURLTTL {
+		ttl = maxSignedURLTTL
+	}
+	return &SignedURLProxy{
+		Endpoint:        strings.TrimSuffix(endpoint, "/"),
+		Bucket:          bucket,
+		Region:          region,
+		AccessKeyId:     accessKeyId,
+		SecretAccessKey: secretAccessKey,
+		TTL:             ttl,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 的 canonical URI 不做二次转义
+			o.DisableURIPathEscaping = true
+		}),
+	}
+}
+
+func (p *SignedURLProxy) objectURL(key string) string {
+	return p.Endpoint + "/" + p.Bucket + "/" + strings.TrimPrefix(key, "/")
+}
+
+func (p *SignedURLProxy) credentials() aws.Credentials {
+	return aws.Credentials{AccessKeyID: p.AccessKeyId, SecretAccessKey: p.SecretAccessKey}
+}
+
+// Upload 以 PUT 上传对象，size 为 -1 时先读入内存以确定 Content-Length
+func (p *SignedURLProxy) Upload(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("read object body failed: %w", err)
+		}
+		body = bytes.NewReader(data)
+		size = int64(len(data))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.objectURL(key), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	if err := p.signer.SignHTTP(ctx, p.credentials(), req, s3UnsignedPayload, "s3", p.Region, time.Now()); err != nil {
+		return fmt.Errorf("sign upload request failed: %w", err)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("upload object failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload object failed: status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// SignURL 生成对象的 GET 签名链接，有效期为 TTL
+func (p *SignedURLProxy) SignURL(ctx context.Context, key string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.objectURL(key), nil)
+	if err != nil {
+		return "", err
+	}
+	query := req.URL.Query()
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(p.TTL/time.Second), 10))
+	req.URL.RawQuery = query.Encode()
+	signedURL, _, err := p.signer.PresignHTTP(ctx, p.credentials(), req, s3UnsignedPayload, "s3", p.Region, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("presign object url failed: %w", err)
+	}
+	return signedURL, nil
+}
+
+// Mirror 下载 sourceURL 并上传为 key
+func (p *SignedURLProxy) Mirror(ctx context.Context, sourceURL string, key string) error {
+	resp, err := DoDownloadRequest(sourceURL, "mirror task output")
+	if err != nil {
+		return fmt.Errorf("download source failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download source failed: status %d", resp.StatusCode)
+	}
+	return p.Upload(ctx, key, resp.Body, resp.ContentLength, resp.Header.Get("Content-Type"))
+}
+
+// StoreTaskOutput 将上游直接返回内容（而非链接）的任务产出上传到对象存储，返回 object key 与对象地址
+func (p *SignedURLProxy) StoreTaskOutput(ctx context.Context, task *model.Task, contentType string, data []byte) (string, string, error) {
+	key := taskObjectKey(task, videoExtension(contentType))
+	if err := p.Upload(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", "", err
+	}
+	return key, p.objectURL(key), nil
+}
+
+// TaskStorageURL 返回任务产出转存后的签名链接，尚未转存时返回空字符串，调用方继续使用 fail_reason 中的上游链接。
+// 转存由任务轮询在任务成功后通过 MirrorTaskOutput 触发，读取接口不会产生转存
+func (p *SignedURLProxy) TaskStorageURL(task *model.Task) string {
+	if p == nil || task == nil || task.PrivateData.StorageKey == "" {
+		return ""
+	}
+	signedURL, err := p.SignURL(context.Background(), task.PrivateData.StorageKey)
+	if err != nil {
+		common.SysError(fmt.Sprintf("task %s sign storage url failed: %s", task.TaskID, err.Error()))
+		return ""
+	}
+	return signedURL
+}
+
+// TaskThumbnailURL 返回任务缩略图的访问链接，转存在当前对象存储中的缩略图返回签名链接
+func (p *SignedURLProxy) TaskThumbnailURL(task *model.Task) string {
+	if task == nil || task.Properties.ThumbnailURL == "" {
+		return ""
+	}
+	if p == nil {
+		return task.Properties.ThumbnailURL
+	}
+	key, ok := strings.CutPrefix(task.Properties.ThumbnailURL, p.objectURL(""))
+	if !ok {
+		return task.Properties.ThumbnailURL
+	}
+	signedURL, err := p.SignURL(context.Background(), key)
+	if err != nil {
+		common.SysError(fmt.Sprintf("task %s sign thumbnail url failed: %s", task.TaskID, err.Error()))
+		return ""
+	}
+	return signedURL
+}
+
+// MirrorTaskOutput 在后台将成功任务的上游产出链接转存到对象存储，完成后只写入 private_data 中的 storage_key
+func (p *SignedURLProxy) MirrorTaskOutput(task *model.Task) {
+	if p == nil || task == nil || task.Status != model.TaskStatusSuccess ||
+		task.PrivateData.StorageKey != "" || !isHTTPURL(task.FailReason) {
+		return
+	}
+	if _, loaded := p.inflight.LoadOrStore(task.ID, struct{}{}); loaded {
+		return
+	}
+	taskId, sourceURL, key := task.TaskID, task.FailReason, taskStorageKey(task)
+	// 转存期间任务的其他字段可能被更新，只保留 ID 用于写回 storage_key
+	target := &model.Task{ID: task.ID}
+	gopool.Go(func() {
+		defer p.inflight.Delete(target.ID)
+		if err := p.Mirror(context.Background(), sourceURL, key); err != nil {
+			common.SysError(fmt.Sprintf("task %s mirror output failed: %s", taskId, err.Error()))
+			return
+		}
+		if err := target.UpdateStorageKey(key); err != nil {
+			common.SysError(fmt.Sprintf("task %s save storage key failed: %s", taskId, err.Error()))
+		}
+	})
+}
+
+// taskStorageKey 按用户与任务 ID 生成 object key，保留上游链接中的文件扩展名
+func taskStorageKey(task *model.Task) string {
+	ext := ".mp4"
+	if u, err := url.Parse(task.FailReason); err == nil {
+		if e := path.Ext(u.Path); e != "" && len(e) <= 5 {
+			ext = e
+		}
+	}
+	return taskObjectKey(task, ext)
+}
+
+func taskObjectKey(task *model.Task, ext string) string {
+	return fmt.Sprintf("tasks/%d/%s%s", task.UserId, url.PathEscape(task.TaskID), ext)
+}
+
+func videoExtension(contentType string) string {
+	switch contentType {
+	case "video/webm":
+		return ".webm"
+	case "video/quicktime":
+		return ".mov"
+	default:
+		return ".mp4"
+	}
+}
+
+func isHTTPURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
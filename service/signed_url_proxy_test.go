@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSignedURLProxyUploadAndSign(t *testing.T) {
+	InitHttpClient()
+	var gotPath, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotAuth, gotBody = r.URL.Path, r.Header.Get("Authorization"), string(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	proxy := NewSignedURLProxy(server.URL+"/", "videos", "", "AKID", "SECRET", 0)
+	if proxy.TTL != maxSignedURLTTL || proxy.Region != "us-east-1" {
+		t.Fatalf("defaults = %s %s", proxy.TTL, proxy.Region)
+	}
+	if err := proxy.Upload(context.Background(), "tasks/1/a.mp4", strings.NewReader("video"), -1, "video/mp4"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if gotPath != "/videos/tasks/1/a.mp4" || gotBody != "video" || !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/") {
+		t.Fatalf("upload request path=%s body=%s auth=%s", gotPath, gotBody, gotAuth)
+	}
+
+	proxy.TTL = time.Hour
+	signed, err := proxy.SignURL(context.Background(), "tasks/1/a.mp4")
+	if err != nil {
+		t.Fatalf("SignURL: %v", err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse signed url: %v", err)
+	}
+	query := u.Query()
+	if u.Path != "/videos/tasks/1/a.mp4" || query.Get("X-Amz-Expires") != "3600" || query.Get("X-Amz-Signature") == "" {
+		t.Fatalf("unexpected signed url: %s", signed)
+	}
+}
+
+func TestTaskStorageKey(t *testing.T) {
+	task := &model.Task{UserId: 7, TaskID: "task_1", FailReason: "https://cdn.example.com/out/video.webm?sig=1"}
+	if key := taskStorageKey(task); key != "tasks/7/task_1.webm" {
+		t.Fatalf("key = %s", key)
+	}
+	task.FailReason = "https://cdn.example.com/out/video"
+	if key := taskStorageKey(task); key != "tasks/7/task_1.mp4" {
+		t.Fatalf("key = %s", key)
+	}
+	var proxy *SignedURLProxy
+	if proxy.TaskStorageURL(task) != "" {
+		t.Fatal("nil proxy should return empty storage url")
+	}
+}
+
+func TestMirrorTaskOutput(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.Task{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originSQLite := model.DB, common.UsingSQLite
+	model.DB, common.UsingSQLite = db, true
+	t.Cleanup(func() { model.DB, common.UsingSQLite = originDB, originSQLite })
+	InitHttpClient()
+	fetchSetting := system_setting.GetFetchSetting()
+	originSSRF := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = originSSRF })
+
+	uploaded := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_, _ = w.Write([]byte("video"))
+		case http.MethodPut:
+			uploaded <- r.URL.Path
+		}
+	}))
+	defer server.Close()
+	proxy := NewSignedURLProxy(server.URL, "videos", "", "AKID", "SECRET", time.Hour)
+
+	task := &model.Task{TaskID: "task_1", UserId: 7, Status: model.TaskStatusSuccess, FailReason: server.URL + "/out/video.mp4"}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatalf("create task failed: %v", err)
+	}
+	// 读取接口不触发转存
+	if proxy.TaskStorageURL(task) != "" {
+		t.Fatal("storage url should be empty before mirroring")
+	}
+	select {
+	case path := <-uploaded:
+		t.Fatalf("TaskStorageURL uploaded %s", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 转存期间写入的其他 private_data 字段不被覆盖
+	db.Model(&model.Task{}).Where("id = ?", task.ID).Update("private_data", model.TaskPrivateData{CallbackSecret: "secret"})
+	proxy.MirrorTaskOutput(task)
+	select {
+	case path := <-uploaded:
+		if path != "/videos/tasks/7/task_1.mp4" {
+			t.Fatalf("uploaded path = %s", path)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("task output not mirrored")
+	}
+	var stored model.Task
+	for i := 0; i < 100; i++ {
+		db.First(&stored, task.ID)
+		if stored.PrivateData.StorageKey != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stored.PrivateData.StorageKey != "tasks/7/task_1.mp4" || stored.PrivateData.CallbackSecret != "secret" {
+		t.Fatalf("private data = %+v", stored.PrivateData)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	streamQuotaKeyPrefix = "stream_quota:"
+	// 进行中请求的输出 token 计数在 Redis 中的过期时间，正常情况下请求结束时即删除
+	streamQuotaCounterTTL = time.Hour
+)
+
+var ErrStreamQuotaExceeded = errors.New("quota exceeded during streaming")
+
+// StreamQuotaMonitor 流式文本请求的分段扣费：以预扣费额度作为初始预留，
+// 输出每累计 STREAM_QUOTA_CHUNK_TOKENS 个 token 检查一次，已输出的费用超出预留时追加扣费，
+// 追加的额度计入 FinalPreConsumedQuota，流结束后由 postConsumeQuota 按实际用量多退少补
+type StreamQuotaMonitor struct {
+	c    *gin.Context
+	info *relaycommon.RelayInfo
+	key  string
+
+	promptQuota   float64
+	quotaPerToken float64
+	chunkTokens   int64
+	nextCheck     int64
+	localTokens   int64
+	syncedTokens  int64
+}
+
+// NewStreamQuotaMonitor 为流式请求创建分段扣费监控，按次计费、未开启分段扣费或非流式请求时返回 nil
+func NewStreamQuotaMonitor(c *gin.Context, info *relaycommon.RelayInfo) *StreamQuotaMonitor {
+	if !info.IsStream || info.PriceData.UsePrice || constant.StreamQuotaChunkTokens <= 0 {
+		return nil
+	}
+	ratio := info.PriceData.ModelRatio * info.PriceData.GroupRatioInfo.GroupRatio
+	quotaPerToken := ratio * info.PriceData.CompletionRatio
+	if quotaPerToken <= 0 {
+		return nil
+	}
+	chunkTokens := int64(constant.StreamQuotaChunkTokens)
+	return &StreamQuotaMonitor{
+		c:             c,
+		info:          info,
+		key:           streamQuotaKeyPrefix + c.GetString(common.RequestIdKey),
+		promptQuota:   float64(info.GetEstimatePromptTokens()) * ratio,
+		quotaPerToken: quotaPerToken,
+		chunkTokens:   chunkTokens,
+		nextCheck:     chunkTokens,
+	}
+}
+
+// OnStreamChunk 累计一个上游数据块的输出 token，达到检查点时按需追加扣费，额度不足时返回 ErrStreamQuotaExceeded
+func (m *StreamQuotaMonitor) OnStreamChunk(data string) error {
+	tokens := countStreamChunkTokens(m.info.UpstreamModelName, data)
+	if tokens == 0 {
+		return nil
+	}
+	total := m.addTokens(int64(tokens))
+	if total < m.nextCheck {
+		return nil
+	}
+	m.nextCheck = total + m.chunkTokens
+	m.syncTokens(total)
+
+	owed := int(math.Ceil(m.promptQuota + float64(total)*m.quotaPerToken))
+	if owed <= m.info.FinalPreConsumedQuota {
+		return nil
+	}
+	// 多预留一个分段的额度，减少扣费次数
+	quota := owed - m.info.FinalPreConsumedQuota + int(math.Ceil(float64(m.chunkTokens)*m.quotaPerToken))
+	if err := m.consume(quota); err != nil {
+		return err
+	}
+	m.info.FinalPreConsumedQuota += quota
+	logger.LogInfo(m.c, fmt.Sprintf("用户 %d 流式输出 %d tokens, 追加预扣费 %s", m.info.UserId, total, logger.FormatQuota(quota)))
+	return nil
+}
+
+// consume 扣除令牌额度后以条件更新原子扣除用户额度，用户额度扣除失败时退回已扣的令牌额度
+func (m *StreamQuotaMonitor) consume(quota int) error {
+	if err := PreConsumeTokenQuota(m.info, quota); err != nil {
+		return fmt.Errorf("%w: %s", ErrStreamQuotaExceeded, err.Error())
+	}
+	ok, err := model.DecreaseUserQuotaIfEnough(m.info.UserId, quota)
+	if err == nil && !ok {
+		err = fmt.Errorf("%w: user remain quota is less than %s", ErrStreamQuotaExceeded, logger.FormatQuota(quota))
+	}
+	if err != nil {
+		if !m.info.IsPlayground {
+			if rollbackErr := model.IncreaseTokenQuota(m.info.TokenId, m.info.TokenKey, quota); rollbackErr != nil {
+				logger.LogError(m.c, "failed to roll back stream token quota: "+rollbackErr.Error())
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// addTokens 在进程内累加本次请求已输出的 token 数
+func (m *StreamQuotaMonitor) addTokens(tokens int64) int64 {
+	m.localTokens += tokens
+	return m.localTokens
+}
+
+// syncTokens 在检查点将新增的输出 token 数同步到 Redis，便于观察进行中的流式请求，失败时仅记录日志
+func (m *StreamQuotaMonitor) syncTokens(total int64) {
+	if !common.RedisEnabled || total <= m.syncedTokens {
+		return
+	}
+	ctx := context.Background()
+	pipe := common.RDB.TxPipeline()
+	pipe.IncrBy(ctx, m.key, total-m.syncedTokens)
+	pipe.Expire(ctx, m.key, streamQuotaCounterTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		common.SysLog("failed to incr stream quota counter: " + err.Error())
+		return
+	}
+	m.syncedTokens = total
+}
+
+// Finish 清理本次请求的输出 token 计数
+func (m *StreamQuotaMonitor) Finish() {
+	if common.RedisEnabled {
+		if err := common.RedisDel(m.key); err != nil {
+			common.SysLog("failed to delete stream quota counter: " + err.Error())
+		}
+	}
+}
+
+// streamQuotaChunk 兼容 OpenAI Chat/Completions、Responses、Claude 与 Gemini 流式数据块中的输出文本字段
+type streamQuotaChunk struct {
+	Choices []struct {
+		Text  string `json:"text"`
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			Reasoning        string `json:"reasoning"`
+			ToolCalls        []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	// Responses 的 delta 为字符串，Claude 的 delta 为对象
+	Delta any `json:"delta"`
+}
+
+// countStreamChunkTokens 估算一个流式数据块中输出文本的 token 数，无法解析时返回 0，以流结束后的结算为准
+func countStreamChunkTokens(modelName string, data string) int {
+	var chunk streamQuotaChunk
+	if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+		return 0
+	}
+	var text strings.Builder
+	for _, choice := range chunk.Choices {
+		text.WriteString(choice.Text)
+		text.WriteString(choice.Delta.Content)
+		text.WriteString(choice.Delta.ReasoningContent)
+		text.WriteString(choice.Delta.Reasoning)
+		for _, tool := range choice.Delta.ToolCalls {
+			text.WriteString(tool.Function.Name)
+			text.WriteString(tool.Function.Arguments)
+		}
+	}
+	for _, candidate := range chunk.Candidates {
+		for _, part := range candidate.Content.Parts {
+			text.WriteString(part.Text)
+		}
+	}
+	switch delta := chunk.Delta.(type) {
+	case string:
+		text.WriteString(delta)
+	case map[string]any:
+		for _, field := range []string{"text", "thinking", "partial_json"} {
+			if s, ok := delta[field].(string); ok {
+				text.WriteString(s)
+			}
+		}
+	}
+	return EstimateTokenByModel(modelName, text.String())
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCountStreamChunkTokens(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"openai chat", `{"choices":[{"delta":{"content":"hello world"}}]}`, "hello world"},
+		{"openai completions", `{"choices":[{"text":"hello world"}]}`, "hello world"},
+		{"responses", `{"type":"response.output_text.delta","delta":"hello world"}`, "hello world"},
+		{"claude", `{"type":"content_block_delta","delta":{"type":"text_delta","text":"hello world"}}`, "hello world"},
+		{"gemini", `{"candidates":[{"content":{"parts":[{"text":"hello world"}]}}]}`, "hello world"},
+		{"usage only", `{"choices":[],"usage":{"prompt_tokens":10}}`, ""},
+		{"invalid json", `not json`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want := EstimateTokenByModel("gpt-4o", tc.want)
+			if got := countStreamChunkTokens("gpt-4o", tc.data); got != want {
+				t.Fatalf("countStreamChunkTokens = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+// setupStreamQuotaTest 使用 playground 请求，只扣除用户额度
+func setupStreamQuotaTest(t *testing.T, userQuota int) *relaycommon.RelayInfo {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originRedis, originChunk := model.DB, common.RedisEnabled, constant.StreamQuotaChunkTokens
+	model.DB, common.RedisEnabled, constant.StreamQuotaChunkTokens = db, false, 10
+	t.Cleanup(func() {
+		model.DB, common.RedisEnabled, constant.StreamQuotaChunkTokens = originDB, originRedis, originChunk
+	})
+
+	if err := db.Create(&model.User{Id: 1, Username: "stream", Quota: userQuota}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	return &relaycommon.RelayInfo{
+		UserId:       1,
+		IsStream:     true,
+		IsPlayground: true,
+		PriceData: types.PriceData{
+			ModelRatio:      1,
+			CompletionRatio: 1,
+			GroupRatioInfo:  types.GroupRatioInfo{GroupRatio: 1},
+		},
+		ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "gpt-4o"},
+	}
+}
+
+func newStreamQuotaTestMonitor(t *testing.T, info *relaycommon.RelayInfo) *StreamQuotaMonitor {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	monitor := NewStreamQuotaMonitor(c, info)
+	if monitor == nil {
+		t.Fatal("expected stream quota monitor")
+	}
+	return monitor
+}
+
+func streamQuotaTestChunk(words int) string {
+	return `{"choices":[{"delta":{"content":"` + strings.Repeat("hello ", words) + `"}}]}`
+}
+
+func TestStreamQuotaMonitorDeductsInChunks(t *testing.T) {
+	info := setupStreamQuotaTest(t, 1000)
+	monitor := newStreamQuotaTestMonitor(t, info)
+
+	for i := 0; i < 5; i++ {
+		if err := monitor.OnStreamChunk(streamQuotaTestChunk(5)); err != nil {
+			t.Fatalf("OnStreamChunk returned error: %v", err)
+		}
+	}
+	if info.FinalPreConsumedQuota == 0 {
+		t.Fatal("expected quota to be deducted during streaming")
+	}
+	userQuota, err := model.GetUserQuota(1, true)
+	if err != nil {
+		t.Fatalf("get user quota failed: %v", err)
+	}
+	if userQuota != 1000-info.FinalPreConsumedQuota {
+		t.Fatalf("user quota = %d, want %d", userQuota, 1000-info.FinalPreConsumedQuota)
+	}
+}
+
+func TestStreamQuotaMonitorStopsWhenQuotaExhausted(t *testing.T) {
+	info := setupStreamQuotaTest(t, 15)
+	monitor := newStreamQuotaTestMonitor(t, info)
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = monitor.OnStreamChunk(streamQuotaTestChunk(5))
+	}
+	if !errors.Is(err, ErrStreamQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrStreamQuotaExceeded", err)
+	}
+}
+
+func TestNewStreamQuotaMonitorSkipsPerCallPricing(t *testing.T) {
+	info := setupStreamQuotaTest(t, 1000)
+	info.PriceData.UsePrice = true
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if monitor := NewStreamQuotaMonitor(c, info); monitor != nil {
+		t.Fatal("expected no monitor for per-call pricing")
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	taskAnalyticsCacheKeyPrefix = "task_analytics:"
+	// TaskAnalyticsCacheTTL 任务统计结果在 Redis 中的缓存时间
+	TaskAnalyticsCacheTTL = 5 * time.Minute
+)
+
+// GetTaskAnalytics 返回指定时间范围的任务统计，启用 Redis 时结果缓存 TaskAnalyticsCacheTTL
+func GetTaskAnalytics(startTimestamp int64, endTimestamp int64) (*model.TaskAnalytics, error) {
+	key := fmt.Sprintf("%s%d:%d", taskAnalyticsCacheKeyPrefix, startTimestamp, endTimestamp)
+	if common.RedisEnabled {
+		val, err := common.RedisGet(key)
+		if err == nil {
+			var analytics model.TaskAnalytics
+			if err := common.UnmarshalJsonStr(val, &analytics); err == nil {
+				return &analytics, nil
+			}
+		} else if err != redis.Nil {
+			common.SysLog("failed to get task analytics cache: " + err.Error())
+		}
+	}
+
+	analytics, err := model.GetTaskAnalytics(startTimestamp, endTimestamp)
+	if err != nil {
+		return nil, err
+	}
+	if common.RedisEnabled {
+		if data, err := common.Marshal(analytics); err == nil {
+			if err := common.RedisSet(key, string(data), TaskAnalyticsCacheTTL); err != nil {
+				common.SysLog("failed to set task analytics cache: " + err.Error())
+			}
+		}
+	}
+	return analytics, nil
+}
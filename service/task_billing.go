@@ -0,0 +1,77 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+// TaskPriceData 任务按次计费的计算结果
+type TaskPriceData struct {
+	ModelPrice        float64
+	GroupRatio        float64
+	UserGroupRatio    float64
+	HasUserGroupRatio bool
+	// OtherRatio 为秒数、分辨率等附加倍率的乘积，未使用附加倍率时为 1
+	OtherRatio float64
+	// Ratio 为模型价格乘以分组倍率及附加倍率后的最终倍率
+	Ratio float64
+	Quota int
+}
+
+// EffectiveGroupRatio 返回实际生效的分组倍率，分组间倍率优先
+func (p TaskPriceData) EffectiveGroupRatio() float64 {
+	if p.HasUserGroupRatio {
+		return p.UserGroupRatio
+	}
+	return p.GroupRatio
+}
+
+// GetTaskModelPrice 获取任务模型的按次价格，未配置时使用默认价格
+func GetTaskModelPrice(modelName string) float64 {
+	modelPrice, success := ratio_setting.GetModelPrice(modelName, true)
+	if !success {
+		defaultPrice, ok := ratio_setting.GetDefaultModelPriceMap()[modelName]
+		if !ok {
+			modelPrice = 0.1
+		} else {
+			modelPrice = defaultPrice
+		}
+	}
+	return modelPrice
+}
+
+// ComputeTaskPrice 根据模型价格、分组倍率及 info.PriceData.OtherRatios 计算任务额度，不依赖请求上下文
+func ComputeTaskPrice(info *relaycommon.RelayInfo, modelName string) TaskPriceData {
+	price := TaskPriceData{
+		ModelPrice: GetTaskModelPrice(modelName),
+		GroupRatio: ratio_setting.GetGroupRatio(info.UsingGroup),
+		OtherRatio: 1,
+	}
+	// 用户自定义模型按注册价格计费（不低于上游模型的按次价格），秒数等附加倍率照常生效
+	if info.UserModelPrice > 0 {
+		price.ModelPrice = info.UserModelPrice
+		if modelPrice, ok := ratio_setting.GetUserModelPrice(info.UserModelUpstream, info.UserModelPrice); ok {
+			price.ModelPrice = modelPrice
+		}
+	}
+	price.UserGroupRatio, price.HasUserGroupRatio = ratio_setting.GetGroupGroupRatio(info.UserGroup, info.UsingGroup)
+	price.Ratio = price.ModelPrice * price.EffectiveGroupRatio()
+	// FIXME: 临时修补，支持任务仅按次计费
+	if !common.StringsContains(constant.TaskPricePatches, modelName) {
+		for _, ra := range info.PriceData.OtherRatios {
+			if 1.0 != ra {
+				price.Ratio *= ra
+				price.OtherRatio *= ra
+			}
+		}
+	}
+	price.Quota = int(price.Ratio * common.QuotaPerUnit)
+	return price
+}
+
+// EstimateTaskQuota 估算任务提交时需要预扣的额度
+func EstimateTaskQuota(info *relaycommon.RelayInfo, modelName string) int {
+	return ComputeTaskPrice(info, modelName).Quota
+}
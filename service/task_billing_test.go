@@ -0,0 +1,42 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func TestComputeTaskPrice(t *testing.T) {
+	info := &relaycommon.RelayInfo{UsingGroup: "default", UserGroup: "default"}
+	base := ComputeTaskPrice(info, "unknown-video-model")
+	if base.ModelPrice != 0.1 || base.GroupRatio != 1 {
+		t.Fatalf("unexpected base price: %+v", base)
+	}
+	if base.Quota != int(0.1*common.QuotaPerUnit) {
+		t.Fatalf("unexpected base quota: %d", base.Quota)
+	}
+
+	info.PriceData.OtherRatios = map[string]float64{"seconds": 8, "size": 1}
+	withRatios := ComputeTaskPrice(info, "unknown-video-model")
+	if withRatios.Quota != int(0.1*8*common.QuotaPerUnit) {
+		t.Fatalf("expected other ratios to be applied, got quota %d", withRatios.Quota)
+	}
+	if EstimateTaskQuota(info, "unknown-video-model") != withRatios.Quota {
+		t.Fatal("EstimateTaskQuota should match ComputeTaskPrice")
+	}
+}
+
+func TestComputeTaskPriceUserModel(t *testing.T) {
+	info := &relaycommon.RelayInfo{
+		UsingGroup:        "default",
+		UserGroup:         "default",
+		UserModelUpstream: "owner/fine-tune:abc",
+		UserModelPrice:    0.5,
+	}
+	info.PriceData.OtherRatios = map[string]float64{"seconds": 4}
+	price := ComputeTaskPrice(info, "my-alias")
+	if price.ModelPrice != 0.5 || price.Quota != int(0.5*4*common.QuotaPerUnit) {
+		t.Fatalf("expected registered price with per-second ratio, got %+v", price)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+)
+
+// TaskDependencyJob 定期检查待提交依赖任务的上游任务，上游成功后写入其产出链接并提交下游任务，上游失败时下游一并失败
+type TaskDependencyJob struct {
+	Interval time.Duration
+	// Submit 以依赖任务保存的令牌和请求重新走一遍任务提交流程，返回下游任务 ID
+	Submit func(ctx context.Context, dep *model.TaskDependency) (string, error)
+
+	once sync.Once
+}
+
+var DefaultTaskDependencyJob = &TaskDependencyJob{Interval: 15 * time.Second}
+
+// ApplyTaskDependencyOutput 移除请求体中的 depends_on_task_id，并将上游任务的产出链接写入 field
+func ApplyTaskDependencyOutput(body []byte, field string, outputURL string) ([]byte, error) {
+	var req map[string]any
+	if err := common.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	delete(req, "depends_on_task_id")
+	req[field] = outputURL
+	return common.Marshal(req)
+}
+
+// Run 在主节点上周期性处理待提交的依赖任务
+func (j *TaskDependencyJob) Run() {
+	if !common.IsMasterNode || j.Submit == nil {
+		return
+	}
+	j.once.Do(func() {
+		for {
+			time.Sleep(j.Interval)
+			if count := j.RunOnce(context.Background()); count > 0 {
+				common.SysLog(fmt.Sprintf("resolved %d task dependencies", count))
+			}
+		}
+	})
+}
+
+// RunOnce 处理一批待提交的依赖任务，返回提交或判定失败的数量
+func (j *TaskDependencyJob) RunOnce(ctx context.Context) int {
+	deps, err := model.GetPendingTaskDependencies(constant.TaskQueryLimit)
+	if err != nil {
+		common.SysError("failed to query pending task dependencies: " + err.Error())
+		return 0
+	}
+	count := 0
+	for _, dep := range deps {
+		resolved, err := j.resolve(ctx, dep)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to resolve task dependency #%d: %s", dep.Id, err.Error()))
+			continue
+		}
+		if resolved {
+			count++
+		}
+	}
+	return count
+}
+
+func (j *TaskDependencyJob) resolve(ctx context.Context, dep *model.TaskDependency) (bool, error) {
+	upstream, exist, err := model.GetByTaskId(dep.UserId, dep.DependsOnTaskId)
+	if err != nil {
+		return false, err
+	}
+	if !exist {
+		return dep.Finish(model.TaskDependencyStatusFailed, "", fmt.Sprintf("dependency task %s not exist", dep.DependsOnTaskId))
+	}
+	switch upstream.Status {
+	case model.TaskStatusFailure:
+		return dep.Finish(model.TaskDependencyStatusFailed, "", fmt.Sprintf("dependency task %s failed: %s", dep.DependsOnTaskId, upstream.FailReason))
+	case model.TaskStatusSuccess:
+	default:
+		return false, nil
+	}
+
+	field := dep.InputField
+	if field == "" {
+		// 记录该字段之前保存的依赖任务沿用原来的默认字段
+		field = "input_reference"
+	}
+	body, err := ApplyTaskDependencyOutput([]byte(dep.RequestBody), field, upstream.FailReason)
+	if err != nil {
+		return dep.Finish(model.TaskDependencyStatusFailed, "", "invalid request body: "+err.Error())
+	}
+	// 先抢占再提交，避免多次提交；提交失败时更新为失败
+	claimed, err := dep.Finish(model.TaskDependencyStatusSubmitted, "", "")
+	if err != nil || !claimed {
+		return false, err
+	}
+	dep.RequestBody = string(body)
+	taskId, err := j.Submit(ctx, dep)
+	failReason := ""
+	if err != nil {
+		failReason = err.Error()
+		logger.LogWarn(ctx, fmt.Sprintf("task dependency #%d submit failed: %s", dep.Id, failReason))
+		model.RecordLog(dep.UserId, model.LogTypeSystem, fmt.Sprintf("依赖任务 %s 完成后自动提交失败：%s", dep.DependsOnTaskId, failReason))
+	}
+	return true, dep.SetSubmitResult(taskId, failReason)
+}
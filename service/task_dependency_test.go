@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestApplyTaskDependencyOutput(t *testing.T) {
+	body, err := ApplyTaskDependencyOutput([]byte(`{"model":"veo","prompt":"next","depends_on_task_id":"task_a"}`), "input_reference", "https://cdn.example.com/a.mp4")
+	if err != nil {
+		t.Fatalf("ApplyTaskDependencyOutput: %v", err)
+	}
+	if string(body) != `{"input_reference":"https://cdn.example.com/a.mp4","model":"veo","prompt":"next"}` {
+		t.Fatalf("body = %s", body)
+	}
+	if _, err := ApplyTaskDependencyOutput([]byte(`not json`), "image", "x"); err == nil {
+		t.Fatal("expected error for invalid body")
+	}
+}
+
+func TestTaskDependencyJobUsesRecordedInputField(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.Task{}, &model.TaskDependency{}, &model.Log{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originLogDB, originRedis, originLimit := model.DB, model.LOG_DB, common.RedisEnabled, constant.TaskQueryLimit
+	model.DB, model.LOG_DB, common.RedisEnabled, constant.TaskQueryLimit = db, db, false, 100
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB, common.RedisEnabled, constant.TaskQueryLimit = originDB, originLogDB, originRedis, originLimit
+	})
+
+	upstream := &model.Task{TaskID: "task_a", UserId: 1, Status: model.TaskStatusSuccess, FailReason: "https://cdn.example.com/a.mp4"}
+	if err := db.Create(upstream).Error; err != nil {
+		t.Fatalf("create task failed: %v", err)
+	}
+	dep := &model.TaskDependency{UserId: 1, DependsOnTaskId: "task_a", RequestBody: `{"model":"kling-v1","depends_on_task_id":"task_a"}`, InputField: "image"}
+	if err := dep.Insert(); err != nil {
+		t.Fatalf("insert dependency failed: %v", err)
+	}
+
+	var submitted string
+	job := &TaskDependencyJob{Submit: func(ctx context.Context, dep *model.TaskDependency) (string, error) {
+		submitted = dep.RequestBody
+		return "task_b", nil
+	}}
+	if count := job.RunOnce(context.Background()); count != 1 {
+		t.Fatalf("RunOnce = %d, want 1", count)
+	}
+	if submitted != `{"image":"https://cdn.example.com/a.mp4","model":"kling-v1"}` {
+		t.Fatalf("submitted body = %s", submitted)
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service/events"
+)
+
+// TaskExpiryJob 定期将长时间未完成的异步任务标记为失败并退款，
+// 兜底轮询失败或上游一直不更新状态导致任务卡在 SUBMITTED / QUEUED 的情况
+type TaskExpiryJob struct {
+	Interval time.Duration
+	// OnExpired 任务被标记为失败后调用，用于发送任务回调与进度回调
+	OnExpired func(ctx context.Context, task *model.Task)
+
+	once sync.Once
+	now  func() time.Time
+}
+
+var DefaultTaskExpiryJob = &TaskExpiryJob{Interval: 10 * time.Minute, now: time.Now}
+
+// TaskTimeoutMinutes 返回平台的任务超时时间（分钟），未单独配置时使用 VideoTaskTimeoutMinutes，0 表示不超时
+func TaskTimeoutMinutes(platform constant.TaskPlatform) int {
+	if minutes, ok := constant.TaskPlatformTimeoutMinutes[string(platform)]; ok {
+		return minutes
+	}
+	return constant.VideoTaskTimeoutMinutes
+}
+
+// minTaskTimeoutMinutes 返回所有平台中最短的超时时间，作为查询的截止时间
+func minTaskTimeoutMinutes() int {
+	minimum := constant.VideoTaskTimeoutMinutes
+	for _, minutes := range constant.TaskPlatformTimeoutMinutes {
+		if minutes > 0 && (minimum <= 0 || minutes < minimum) {
+			minimum = minutes
+		}
+	}
+	return minimum
+}
+
+// Run 在主节点上周期性执行过期检查
+func (j *TaskExpiryJob) Run() {
+	if !common.IsMasterNode {
+		return
+	}
+	j.once.Do(func() {
+		for {
+			time.Sleep(j.Interval)
+			if count := j.RunOnce(context.Background()); count > 0 {
+				common.SysLog(fmt.Sprintf("expired %d stale tasks", count))
+			}
+			j.pruneTaskRequestBodies()
+			j.releaseStaleReservations()
+		}
+	})
+}
+
+// RunOnce 执行一次过期检查，返回被标记为失败的任务数
+func (j *TaskExpiryJob) RunOnce(ctx context.Context) int {
+	minimum := minTaskTimeoutMinutes()
+	if minimum <= 0 {
+		return 0
+	}
+	now := j.now().Unix()
+	tasks, err := model.GetStaleUnfinishedTasks(now-int64(minimum*60), constant.TaskQueryLimit)
+	if err != nil {
+		common.SysError("failed to query stale tasks: " + err.Error())
+		return 0
+	}
+	count := 0
+	for _, task := range tasks {
+		reason, expired := taskExpiryReason(task, now)
+		if !expired {
+			continue
+		}
+		if err := j.expireTask(ctx, task, reason, now); err != nil {
+			common.SysError(fmt.Sprintf("failed to expire task %s: %s", task.TaskID, err.Error()))
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// pruneTaskRequestBodies 清除成功任务及超过 TASK_REPLAY_RETENTION_DAYS 的失败任务保存的重放请求体
+func (j *TaskExpiryJob) pruneTaskRequestBodies() {
+	failedBefore := j.now().Add(-time.Duration(constant.TaskReplayRetentionDays) * 24 * time.Hour).Unix()
+	count, err := model.PruneTaskRequestBodies(failedBefore)
+	if err != nil {
+		common.SysError("failed to prune task request bodies: " + err.Error())
+		return
+	}
+	if count > 0 {
+		common.SysLog(fmt.Sprintf("pruned request bodies of %d tasks", count))
+	}
+}
+
+// releaseStaleReservations 释放超过 QUOTA_RESERVATION_TIMEOUT_MINUTES 仍未确认的额度预留
+func (j *TaskExpiryJob) releaseStaleReservations() {
+	if constant.QuotaReservationTimeoutMinutes <= 0 {
+		return
+	}
+	before := j.now().Add(-time.Duration(constant.QuotaReservationTimeoutMinutes) * time.Minute).Unix()
+	count, err := model.ReleaseStaleReservations(before, constant.TaskQueryLimit)
+	if err != nil {
+		common.SysError("failed to release stale quota reservations: " + err.Error())
+		return
+	}
+	if count > 0 {
+		common.SysLog(fmt.Sprintf("released %d stale quota reservations", count))
+	}
+}
+
+// taskExpiryReason 判断任务是否已超时：用户指定了 execution_expires_after 时以其为准，否则按平台超时时间
+func taskExpiryReason(task *model.Task, now int64) (string, bool) {
+	if task.Properties.ExecutionExpiresAfterSec > 0 {
+		if !task.IsExpired(now) {
+			return "", false
+		}
+		return fmt.Sprintf("task expired after %d seconds", task.Properties.ExecutionExpiresAfterSec), true
+	}
+	minutes := TaskTimeoutMinutes(task.Platform)
+	if minutes <= 0 || now-task.SubmitTime <= int64(minutes*60) {
+		return "", false
+	}
+	return fmt.Sprintf("task timed out after %d minutes", minutes), true
+}
+
+func (j *TaskExpiryJob) expireTask(ctx context.Context, task *model.Task, reason string, now int64) error {
+	quota := task.Quota
+	updated, err := task.FailIfUnfinished(reason, now)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		// 已被轮询更新为终态
+		return nil
+	}
+	logger.LogWarn(ctx, fmt.Sprintf("Task %s stuck in %s, marking as failure: %s", task.TaskID, task.Platform, reason))
+	NotifyTaskUpdated(task.TaskID)
+	events.Publish(&events.TaskCancelledEvent{
+		TaskID:      task.TaskID,
+		UserId:      task.UserId,
+		ChannelId:   task.ChannelId,
+		Reason:      reason,
+		RefundQuota: quota,
+		Timestamp:   now,
+	})
+	if quota != 0 {
+		if err := RefundTaskQuota(ctx, task, quota); err != nil {
+			logger.LogWarn(ctx, "Failed to increase user quota: "+err.Error())
+		}
+		model.RecordLog(task.UserId, model.LogTypeSystem, fmt.Sprintf("Async task expired %s, refund %s", task.TaskID, logger.LogQuota(quota)))
+	}
+	if j.OnExpired != nil {
+		j.OnExpired(ctx, task)
+	}
+	return nil
+}
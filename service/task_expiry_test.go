@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestTaskExpiryReason(t *testing.T) {
+	prevTimeout, prevOverrides := constant.VideoTaskTimeoutMinutes, constant.TaskPlatformTimeoutMinutes
+	constant.VideoTaskTimeoutMinutes = 180
+	constant.TaskPlatformTimeoutMinutes = map[string]int{"suno": 30, "kling": 0}
+	t.Cleanup(func() {
+		constant.VideoTaskTimeoutMinutes, constant.TaskPlatformTimeoutMinutes = prevTimeout, prevOverrides
+	})
+
+	if got := minTaskTimeoutMinutes(); got != 30 {
+		t.Fatalf("minTaskTimeoutMinutes() = %d, want 30", got)
+	}
+
+	now := int64(100_000)
+	cases := []struct {
+		name string
+		task *model.Task
+		want bool
+	}{
+		{"global timeout not reached", &model.Task{Platform: "sora", SubmitTime: now - 60*60}, false},
+		{"global timeout reached", &model.Task{Platform: "sora", SubmitTime: now - 181*60}, true},
+		{"platform override reached", &model.Task{Platform: "suno", SubmitTime: now - 31*60}, true},
+		{"platform override disabled", &model.Task{Platform: "kling", SubmitTime: now - 1000*60}, false},
+		{"user expiry not reached", &model.Task{Platform: "sora", SubmitTime: now - 181*60, Properties: model.Properties{ExecutionExpiresAfterSec: 24 * 3600}}, false},
+		{"user expiry reached", &model.Task{Platform: "sora", SubmitTime: now - 120, Properties: model.Properties{ExecutionExpiresAfterSec: 60}}, true},
+	}
+	for _, tc := range cases {
+		if _, got := taskExpiryReason(tc.task, now); got != tc.want {
+			t.Errorf("%s: expired = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
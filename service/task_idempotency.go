@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	taskIdempotencyKeyPrefix = "task_idempotency:"
+	TaskIdempotencyKeyHeader = "X-Idempotency-Key"
+	// taskIdempotencyPending 首次提交尚未完成时占位的值
+	taskIdempotencyPending = "pending"
+)
+
+// TaskIdempotency 记录同一用户在短时间内提交的相同任务请求，重复提交时直接返回首次提交的响应，
+// 避免重复创建任务与重复扣费。仅在启用 Redis 时生效
+type TaskIdempotency struct {
+	TTL time.Duration
+}
+
+var DefaultTaskIdempotency = NewTaskIdempotency(60 * time.Second)
+
+func NewTaskIdempotency(ttl time.Duration) *TaskIdempotency {
+	return &TaskIdempotency{TTL: ttl}
+}
+
+// TaskIdempotencyRecord 首次提交成功后写出的响应
+type TaskIdempotencyRecord struct {
+	TaskID      string `json:"task_id"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+// Key 计算幂等键：调用方通过 X-Idempotency-Key 显式指定时使用该值，否则使用规范化后的请求体。
+// JSON 请求体会重新序列化以消除字段顺序与空白差异，非 JSON 请求体按原始字节计算
+func (t *TaskIdempotency) Key(userId int, path string, explicitKey string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(userId) + "\n" + path + "\n"))
+	if explicitKey = strings.TrimSpace(explicitKey); explicitKey != "" {
+		h.Write([]byte("explicit\n" + explicitKey))
+	} else {
+		h.Write(normalizeTaskRequestBody(body))
+	}
+	return taskIdempotencyKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+func normalizeTaskRequestBody(body []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return body
+	}
+	// map 按 key 排序序列化
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// Get 返回幂等键对应的首次响应，未启用 Redis 或未命中时返回 false
+func (t *TaskIdempotency) Get(key string) (*TaskIdempotencyRecord, bool) {
+	if !common.RedisEnabled || key == "" {
+		return nil, false
+	}
+	val, err := common.RedisGet(key)
+	if err != nil {
+		if err != redis.Nil {
+			common.SysLog("failed to get task idempotency record: " + err.Error())
+		}
+		return nil, false
+	}
+	if val == taskIdempotencyPending {
+		return nil, false
+	}
+	var record TaskIdempotencyRecord
+	if err := common.UnmarshalJsonStr(val, &record); err != nil || record.TaskID == "" {
+		return nil, false
+	}
+	return &record, true
+}
+
+// Acquire 在提交上游前以 SETNX 写入占位标记，保证相同请求只有一个能提交。
+// 返回 true 表示当前请求获得了提交权；返回 false 时若首次提交已完成则同时返回其响应，
+// 否则表示首次提交仍在进行中。未启用 Redis 或 Redis 出错时始终放行
+func (t *TaskIdempotency) Acquire(key string) (*TaskIdempotencyRecord, bool) {
+	if !common.RedisEnabled || key == "" {
+		return nil, true
+	}
+	ok, err := common.RDB.SetNX(context.Background(), key, taskIdempotencyPending, t.TTL).Result()
+	if err != nil {
+		common.SysLog("failed to acquire task idempotency key: " + err.Error())
+		return nil, true
+	}
+	if ok {
+		return nil, true
+	}
+	record, _ := t.Get(key)
+	return record, false
+}
+
+// Release 删除提交失败时的占位标记，使客户端可以重试
+func (t *TaskIdempotency) Release(key string) {
+	if !common.RedisEnabled || key == "" {
+		return
+	}
+	if err := common.RedisDel(key); err != nil {
+		common.SysLog("failed to release task idempotency key: " + err.Error())
+	}
+}
+
+// Set 记录首次提交的响应，覆盖 Acquire 写入的占位标记
+func (t *TaskIdempotency) Set(key string, record *TaskIdempotencyRecord) {
+	if !common.RedisEnabled || key == "" || record == nil || record.TaskID == "" {
+		return
+	}
+	data, err := common.Marshal(record)
+	if err != nil {
+		return
+	}
+	if err := common.RedisSet(key, string(data), t.TTL); err != nil {
+		common.SysLog("failed to set task idempotency record: " + err.Error())
+	}
+}
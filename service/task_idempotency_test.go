@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskIdempotencyKey(t *testing.T) {
+	idem := NewTaskIdempotency(time.Minute)
+	a := idem.Key(1, "/v1/videos", "", []byte(`{"model":"sora-2","prompt":"a cat","seconds":8}`))
+	b := idem.Key(1, "/v1/videos", "", []byte(`{ "seconds": 8, "prompt": "a cat", "model": "sora-2" }`))
+	if a != b {
+		t.Fatal("equivalent json bodies should share the same key")
+	}
+	if a == idem.Key(2, "/v1/videos", "", []byte(`{"model":"sora-2","prompt":"a cat","seconds":8}`)) {
+		t.Fatal("different users should not share a key")
+	}
+	if a == idem.Key(1, "/v1/videos", "", []byte(`{"model":"sora-2","prompt":"a dog","seconds":8}`)) {
+		t.Fatal("different prompts should not share a key")
+	}
+
+	explicit := idem.Key(1, "/v1/videos", "req-1", []byte(`{"prompt":"a cat"}`))
+	if explicit != idem.Key(1, "/v1/videos", " req-1 ", []byte(`{"prompt":"a dog"}`)) {
+		t.Fatal("explicit key should take precedence over the request body")
+	}
+	if explicit == a {
+		t.Fatal("explicit key should differ from body key")
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import "time"
+
+// PollStage 任务运行时长小于 Until 时按 Interval 轮询
+type PollStage struct {
+	Until    time.Duration
+	Interval time.Duration
+}
+
+// AdaptivePoller 按任务已运行时长逐级拉长轮询间隔：短任务保持低延迟，长时间运行的视频任务减少对数据库和上游的查询
+type AdaptivePoller struct {
+	Stages        []PollStage
+	FinalInterval time.Duration
+}
+
+// DefaultAdaptivePoller 前 30 秒每 5 秒轮询一次，之后 5 分钟内每 15 秒一次，再之后每 60 秒一次
+var DefaultAdaptivePoller = AdaptivePoller{
+	Stages: []PollStage{
+		{Until: 30 * time.Second, Interval: 5 * time.Second},
+		{Until: 30*time.Second + 5*time.Minute, Interval: 15 * time.Second},
+	},
+	FinalInterval: 60 * time.Second,
+}
+
+// Interval 返回任务已运行 elapsed 时的轮询间隔
+func (p AdaptivePoller) Interval(elapsed time.Duration) time.Duration {
+	for _, stage := range p.Stages {
+		if elapsed < stage.Until {
+			return stage.Interval
+		}
+	}
+	return p.FinalInterval
+}
+
+// MinInterval 返回最短的轮询间隔，调度器按此频率检查到期任务
+func (p AdaptivePoller) MinInterval() time.Duration {
+	interval := p.FinalInterval
+	for _, stage := range p.Stages {
+		if stage.Interval < interval {
+			interval = stage.Interval
+		}
+	}
+	return interval
+}
+
+// NextPollAt 根据任务提交时间计算下一次轮询的时间戳（秒）
+func (p AdaptivePoller) NextPollAt(submitTime int64, now int64) int64 {
+	elapsed := time.Duration(now-submitTime) * time.Second
+	return now + int64(p.Interval(elapsed)/time.Second)
+}
@@ -0,0 +1,21 @@
+package service
+
+import "sync"
+
+// taskUpdateSignals task_id -> chan struct{}。任务更新时关闭并移除对应通道以唤醒所有等待方，
+// 等待方被唤醒后需重新调用 TaskUpdateSignal 订阅下一次更新
+var taskUpdateSignals sync.Map
+
+// TaskUpdateSignal 返回在任务下一次更新时被关闭的通道。
+// 应先订阅再读取任务状态，避免错过两者之间发生的更新
+func TaskUpdateSignal(taskId string) <-chan struct{} {
+	ch, _ := taskUpdateSignals.LoadOrStore(taskId, make(chan struct{}))
+	return ch.(chan struct{})
+}
+
+// NotifyTaskUpdated 唤醒所有等待该任务更新的订阅方
+func NotifyTaskUpdated(taskId string) {
+	if ch, ok := taskUpdateSignals.LoadAndDelete(taskId); ok {
+		close(ch.(chan struct{}))
+	}
+}
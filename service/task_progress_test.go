@@ -0,0 +1,37 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskUpdateSignal(t *testing.T) {
+	first := TaskUpdateSignal("task-1")
+	second := TaskUpdateSignal("task-1")
+	other := TaskUpdateSignal("task-2")
+
+	NotifyTaskUpdated("task-1")
+	for _, ch := range []<-chan struct{}{first, second} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("subscriber was not woken up")
+		}
+	}
+	select {
+	case <-other:
+		t.Fatal("unrelated task must not be signalled")
+	default:
+	}
+
+	// 唤醒后重新订阅得到的是新的通道
+	next := TaskUpdateSignal("task-1")
+	select {
+	case <-next:
+		t.Fatal("new subscription must wait for the next update")
+	default:
+	}
+	NotifyTaskUpdated("task-1")
+	<-next
+	NotifyTaskUpdated("task-missing")
+}
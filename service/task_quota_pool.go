@@ -0,0 +1,93 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+// TaskQuotaPool 批量提交时一次性预留的额度。批次内的任务提交时从中取用，不再单独预留，
+// 批次结束后由 Settle 确认已用部分并退回剩余额度
+type TaskQuotaPool struct {
+	mu        sync.Mutex
+	total     int
+	remaining int
+
+	userId             int
+	tokenId            int
+	tokenKey           string
+	userReservationId  int64
+	tokenReservationId int64
+}
+
+// ReserveTaskQuotaPool 预留用户与令牌额度作为批次额度池，tokenId 为 0 时只预留用户额度。
+// 额度不足时返回 model.ErrQuotaNotEnough
+func ReserveTaskQuotaPool(userId, tokenId int, tokenKey string, total int) (*TaskQuotaPool, error) {
+	pool := &TaskQuotaPool{
+		total:     total,
+		remaining: total,
+		userId:    userId,
+		tokenId:   tokenId,
+		tokenKey:  tokenKey,
+	}
+	var err error
+	if pool.userReservationId, err = model.ReserveUserQuota(userId, total); err != nil {
+		return nil, err
+	}
+	if tokenId > 0 {
+		if pool.tokenReservationId, err = model.ReserveTokenQuota(tokenId, total); err != nil {
+			ReleaseQuotaReservation(pool.userReservationId)
+			return nil, err
+		}
+	}
+	return pool, nil
+}
+
+// Take 从额度池中取用 quota，剩余额度不足时不做修改并返回 false
+func (p *TaskQuotaPool) Take(quota int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if quota > p.remaining {
+		return false
+	}
+	p.remaining -= quota
+	return true
+}
+
+// Return 归还提交失败的任务取用的额度
+func (p *TaskQuotaPool) Return(quota int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.remaining += quota
+}
+
+// Settle 结算额度池：全部未使用时释放预留，否则确认预留并退回剩余额度
+func (p *TaskQuotaPool) Settle() {
+	p.mu.Lock()
+	remaining := p.remaining
+	p.remaining = 0
+	p.mu.Unlock()
+
+	if remaining >= p.total {
+		ReleaseQuotaReservation(p.userReservationId, p.tokenReservationId)
+		return
+	}
+	for _, id := range []int64{p.userReservationId, p.tokenReservationId} {
+		if err := model.ConfirmReservation(id); err != nil {
+			common.SysLog(fmt.Sprintf("failed to confirm quota reservation %d: %s", id, err.Error()))
+		}
+	}
+	if remaining <= 0 {
+		return
+	}
+	if err := model.IncreaseUserQuota(p.userId, remaining, false); err != nil {
+		common.SysLog(fmt.Sprintf("failed to refund batch quota of user %d: %s", p.userId, err.Error()))
+	}
+	if p.tokenReservationId != 0 {
+		if err := model.IncreaseTokenQuota(p.tokenId, p.tokenKey, remaining); err != nil {
+			common.SysLog(fmt.Sprintf("failed to refund batch quota of token %d: %s", p.tokenId, err.Error()))
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+)
+
+// RefundTaskQuota 退还任务的部分或全部额度：用户额度、提交令牌额度，
+// 以及提交时累加的模型周期消费上限。返回用户额度退还的错误，其余失败仅记录日志
+func RefundTaskQuota(ctx context.Context, task *model.Task, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	if err := model.IncreaseUserQuota(task.UserId, quota, false); err != nil {
+		return err
+	}
+	if task.PrivateData.TokenId > 0 && task.PrivateData.TokenKey != "" {
+		if err := model.IncreaseTokenQuota(task.PrivateData.TokenId, task.PrivateData.TokenKey, quota); err != nil {
+			logger.LogWarn(ctx, "Failed to increase token quota: "+err.Error())
+		}
+	}
+	if task.PrivateData.SpendingCapModel != "" {
+		if err := model.ReleaseModelSpendingCap(task.UserId, task.PrivateData.SpendingCapModel, quota); err != nil {
+			logger.LogWarn(ctx, "Failed to release model spending cap: "+err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestRefundTaskQuotaReleasesSpendingCap(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}, &model.ModelSpendingCap{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originRedis := model.DB, common.RedisEnabled
+	model.DB, common.RedisEnabled = db, false
+	t.Cleanup(func() {
+		model.DB, common.RedisEnabled = originDB, originRedis
+	})
+
+	if err := db.Create(&model.User{Id: 1, Username: "refund", Quota: 0}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	if _, err := model.SetModelSpendingCap(1, "veo", model.ModelSpendingCapPeriodDay, 100); err != nil {
+		t.Fatalf("set cap failed: %v", err)
+	}
+	if err := model.CheckModelSpendingCap(1, "veo", 80); err != nil {
+		t.Fatalf("check cap failed: %v", err)
+	}
+
+	task := &model.Task{UserId: 1, Quota: 80}
+	task.PrivateData.SpendingCapModel = "veo"
+	if err := RefundTaskQuota(context.Background(), task, 30); err != nil {
+		t.Fatalf("refund failed: %v", err)
+	}
+
+	var user model.User
+	db.First(&user, 1)
+	if user.Quota != 30 {
+		t.Errorf("user quota = %d, want 30", user.Quota)
+	}
+	var spendingCap model.ModelSpendingCap
+	db.Where("user_id = ? AND model_name = ?", 1, "veo").First(&spendingCap)
+	if spendingCap.UsedQuota != 50 {
+		t.Errorf("cap used quota = %d, want 50", spendingCap.UsedQuota)
+	}
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// RetryPolicy 控制任务轮询失败后的重试次数与退避间隔
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	Jitter     time.Duration
+}
+
+var DefaultTaskRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	BaseDelay:  30 * time.Second,
+	Jitter:     10 * time.Second,
+}
+
+// GetTaskRetryPolicy 返回渠道设置中的重试策略，未配置的字段使用默认值
+func GetTaskRetryPolicy(setting dto.ChannelSettings) RetryPolicy {
+	policy := DefaultTaskRetryPolicy
+	if setting.TaskRetryPolicy == nil {
+		return policy
+	}
+	if setting.TaskRetryPolicy.MaxRetries > 0 {
+		policy.MaxRetries = setting.TaskRetryPolicy.MaxRetries
+	}
+	if setting.TaskRetryPolicy.BaseDelaySeconds > 0 {
+		policy.BaseDelay = time.Duration(setting.TaskRetryPolicy.BaseDelaySeconds) * time.Second
+	}
+	if setting.TaskRetryPolicy.JitterSeconds > 0 {
+		policy.Jitter = time.Duration(setting.TaskRetryPolicy.JitterSeconds) * time.Second
+	}
+	return policy
+}
+
+// Exhausted 判断重试次数是否已超过上限
+func (p RetryPolicy) Exhausted(retryCount int) bool {
+	return retryCount > p.MaxRetries
+}
+
+// NextDelay 返回第 retryCount 次重试前的等待时间：BaseDelay * 2^(retryCount-1) 加随机抖动
+func (p RetryPolicy) NextDelay(retryCount int) time.Duration {
+	if retryCount < 1 {
+		retryCount = 1
+	}
+	// 避免位移溢出
+	if retryCount > 16 {
+		retryCount = 16
+	}
+	delay := p.BaseDelay * time.Duration(1<<(retryCount-1))
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(p.Jitter)))
+	}
+	return delay
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestTaskRetryPolicy(t *testing.T) {
+	policy := GetTaskRetryPolicy(dto.ChannelSettings{
+		TaskRetryPolicy: &dto.TaskRetryPolicy{MaxRetries: 3, BaseDelaySeconds: 10},
+	})
+	if policy.MaxRetries != 3 || policy.BaseDelay != 10*time.Second || policy.Jitter != DefaultTaskRetryPolicy.Jitter {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+	policy.Jitter = 0
+	for retry, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 40 * time.Second} {
+		if got := policy.NextDelay(retry); got != want {
+			t.Fatalf("retry %d: expected delay %s, got %s", retry, want, got)
+		}
+	}
+	if policy.Exhausted(3) || !policy.Exhausted(4) {
+		t.Fatal("expected policy to be exhausted only after MaxRetries is exceeded")
+	}
+	if GetTaskRetryPolicy(dto.ChannelSettings{}) != DefaultTaskRetryPolicy {
+		t.Fatal("expected default policy when channel has no override")
+	}
+}
+
+func TestAdaptivePoller(t *testing.T) {
+	p := DefaultAdaptivePoller
+	cases := map[time.Duration]time.Duration{
+		0:                              5 * time.Second,
+		29 * time.Second:               5 * time.Second,
+		30 * time.Second:               15 * time.Second,
+		5 * time.Minute:                15 * time.Second,
+		5*time.Minute + 30*time.Second: 60 * time.Second,
+		2 * time.Hour:                  60 * time.Second,
+	}
+	for elapsed, want := range cases {
+		if got := p.Interval(elapsed); got != want {
+			t.Errorf("Interval(%s) = %s, want %s", elapsed, got, want)
+		}
+	}
+	if p.MinInterval() != 5*time.Second {
+		t.Fatalf("MinInterval = %s", p.MinInterval())
+	}
+	if got := p.NextPollAt(1_000, 1_400); got != 1_460 {
+		t.Fatalf("NextPollAt = %d, want 1460", got)
+	}
+}
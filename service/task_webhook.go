@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const TaskWebhookSignatureHeader = "X-New-API-Signature"
+
+// TaskWebhookSecretHeader 提交带回调地址的任务时，响应头中返回该任务回调签名使用的密钥
+const TaskWebhookSecretHeader = "X-New-API-Callback-Secret"
+
+// WebhookDispatcher 在任务进入终态时向用户配置的 callback_url 推送任务结果
+type WebhookDispatcher struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+var DefaultWebhookDispatcher = &WebhookDispatcher{
+	MaxAttempts: 5,
+	BaseDelay:   2 * time.Second,
+}
+
+// Dispatch 异步投递任务回调，失败时按指数退避重试，最终失败记录到用户日志
+func (d *WebhookDispatcher) Dispatch(task *model.Task, payload []byte) {
+	if task == nil || task.CallbackURL == "" {
+		return
+	}
+	d.deliver(task.UserId, "任务 "+task.TaskID, task.CallbackURL, task.PrivateData.CallbackSecret, payload)
+}
+
+func (d *WebhookDispatcher) deliver(userId int, subject string, callbackURL string, secret string, payload []byte) {
+	gopool.Go(func() {
+		var err error
+		for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+			if err = d.send(callbackURL, secret, payload); err == nil {
+				return
+			}
+			common.SysLog(fmt.Sprintf("%s webhook attempt %d/%d failed: %s", subject, attempt, d.MaxAttempts, err.Error()))
+			if attempt < d.MaxAttempts {
+				time.Sleep(d.BaseDelay * time.Duration(1<<(attempt-1)))
+			}
+		}
+		model.RecordLog(userId, model.LogTypeSystem,
+			fmt.Sprintf("%s 回调 %s 失败（已重试 %d 次）：%s", subject, callbackURL, d.MaxAttempts, err.Error()))
+	})
+}
+
+func (d *WebhookDispatcher) send(callbackURL, secret string, payload []byte) error {
+	return postTaskWebhook(context.Background(), callbackURL, secret, payload)
+}
+
+// webhookHttpClient 复用全局客户端的连接与超时设置但不跟随重定向，3xx 响应按投递失败处理，
+// 避免带签名的负载被转发到未经 SSRF 校验的地址
+func webhookHttpClient() *http.Client {
+	client := *GetHttpClient()
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &client
+}
+
+func postTaskWebhook(ctx context.Context, callbackURL, secret string, payload []byte) error {
+	fetchSetting := system_setting.GetFetchSetting()
+	if err := common.ValidateURLWithFetchSetting(callbackURL, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
+		return fmt.Errorf("request reject: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(TaskWebhookSignatureHeader, generateSignature(secret, payload))
+	}
+	resp, err := webhookHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// ProgressWebhookDispatcher 在任务状态或进度变化时向 progress_callback_url 推送任务详情，
+// 只投递一次不重试，同一任务在 MinInterval 内最多推送一次，避免压垮响应较慢的回调地址。
+// 进入终态的推送不受限流影响
+type ProgressWebhookDispatcher struct {
+	Timeout     time.Duration
+	MinInterval time.Duration
+
+	mu        sync.Mutex
+	lastSent  map[string]time.Time
+	lastPrune time.Time
+}
+
+var DefaultProgressWebhookDispatcher = &ProgressWebhookDispatcher{
+	Timeout:     5 * time.Second,
+	MinInterval: 10 * time.Second,
+}
+
+// Dispatch 异步投递进度回调。非终态的推送距离上次不足 MinInterval 时直接丢弃；
+// 终态的推送总是投递，并清除该任务的限流记录
+func (d *ProgressWebhookDispatcher) Dispatch(task *model.Task, payload []byte) {
+	if task == nil || task.ProgressCallbackURL == "" {
+		return
+	}
+	if task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure {
+		d.Forget(task.TaskID)
+	} else if !d.allow(task.TaskID, time.Now()) {
+		return
+	}
+	callbackURL, secret := task.ProgressCallbackURL, task.PrivateData.CallbackSecret
+	subject := "任务 " + task.TaskID
+	gopool.Go(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), d.Timeout)
+		defer cancel()
+		if err := postTaskWebhook(ctx, callbackURL, secret, payload); err != nil {
+			common.SysLog(fmt.Sprintf("%s progress webhook failed: %s", subject, err.Error()))
+		}
+	})
+}
+
+// Forget 清除任务的限流记录
+func (d *ProgressWebhookDispatcher) Forget(taskID string) {
+	d.mu.Lock()
+	delete(d.lastSent, taskID)
+	d.mu.Unlock()
+}
+
+// allow 判断任务是否可以推送并记录推送时间。超过 MinInterval 的记录不再起作用，
+// 每隔 MinInterval 清理一次，避免未在本节点进入终态的任务残留
+func (d *ProgressWebhookDispatcher) allow(taskID string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.lastPrune) >= d.MinInterval {
+		for id, last := range d.lastSent {
+			if now.Sub(last) >= d.MinInterval {
+				delete(d.lastSent, id)
+			}
+		}
+		d.lastPrune = now
+	}
+	if last, ok := d.lastSent[taskID]; ok && now.Sub(last) < d.MinInterval {
+		return false
+	}
+	if d.lastSent == nil {
+		d.lastSent = make(map[string]time.Time)
+	}
+	d.lastSent[taskID] = now
+	return true
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+)
+
+func TestProgressWebhookRateLimit(t *testing.T) {
+	d := &ProgressWebhookDispatcher{Timeout: time.Second, MinInterval: 10 * time.Second}
+	start := time.Unix(1_000_000, 0)
+	if !d.allow("task-1", start) {
+		t.Fatal("first callback should be allowed")
+	}
+	if d.allow("task-1", start.Add(9*time.Second)) {
+		t.Fatal("callback within MinInterval should be dropped")
+	}
+	if !d.allow("task-2", start.Add(time.Second)) {
+		t.Fatal("rate limit must be per task")
+	}
+	if !d.allow("task-1", start.Add(10*time.Second)) {
+		t.Fatal("callback after MinInterval should be allowed")
+	}
+	d.Forget("task-1")
+	if !d.allow("task-1", start.Add(11*time.Second)) {
+		t.Fatal("callback after Forget should be allowed")
+	}
+}
+
+func TestProgressWebhookPrunesStaleEntries(t *testing.T) {
+	d := &ProgressWebhookDispatcher{Timeout: time.Second, MinInterval: 10 * time.Second}
+	start := time.Unix(1_000_000, 0)
+	d.allow("task-1", start)
+	d.allow("task-2", start.Add(5*time.Second))
+	d.allow("task-3", start.Add(11*time.Second))
+	if _, ok := d.lastSent["task-1"]; ok {
+		t.Fatal("entry older than MinInterval should be pruned")
+	}
+	if _, ok := d.lastSent["task-2"]; !ok {
+		t.Fatal("recent entry should be kept")
+	}
+}
+
+func TestProgressWebhookDeliversTerminalStatus(t *testing.T) {
+	InitHttpClient()
+	received := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer server.Close()
+	fetchSetting := system_setting.GetFetchSetting()
+	originSSRF := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = originSSRF })
+
+	d := &ProgressWebhookDispatcher{Timeout: time.Second, MinInterval: time.Hour}
+	task := &model.Task{TaskID: "task-1", ProgressCallbackURL: server.URL, Status: model.TaskStatusInProgress}
+	d.Dispatch(task, []byte("progress"))
+	if got := waitWebhook(t, received); got != "progress" {
+		t.Fatalf("first payload = %q", got)
+	}
+	// 限流窗口内的进度推送被丢弃，但终态推送总是投递
+	d.Dispatch(task, []byte("dropped"))
+	task.Status = model.TaskStatusSuccess
+	d.Dispatch(task, []byte("done"))
+	if got := waitWebhook(t, received); got != "done" {
+		t.Fatalf("terminal payload = %q, want done", got)
+	}
+	if _, ok := d.lastSent["task-1"]; ok {
+		t.Fatal("terminal dispatch should forget the task")
+	}
+}
+
+func waitWebhook(t *testing.T, received chan string) string {
+	t.Helper()
+	select {
+	case body := <-received:
+		return body
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not delivered")
+		return ""
+	}
+}
+
+func TestPostTaskWebhookDoesNotFollowRedirects(t *testing.T) {
+	InitHttpClient()
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+	}))
+	defer internal.Close()
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer redirector.Close()
+	fetchSetting := system_setting.GetFetchSetting()
+	originSSRF := fetchSetting.EnableSSRFProtection
+	fetchSetting.EnableSSRFProtection = false
+	t.Cleanup(func() { fetchSetting.EnableSSRFProtection = originSSRF })
+
+	err := postTaskWebhook(context.Background(), redirector.URL, "secret", []byte(`{"task_id":"task-1"}`))
+	if err == nil {
+		t.Fatal("expected a redirect response to count as a failed delivery")
+	}
+	if internalHits.Load() != 0 {
+		t.Fatal("webhook payload must not be forwarded to the redirect target")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+)
+
+const WebhookEventTokenInvalidated = "token.invalidated"
+
+// TokenWebhookEvent 令牌相关事件推送到用户设置中 webhook_url 的负载
+type TokenWebhookEvent struct {
+	Type      string `json:"type"`
+	UserId    int    `json:"user_id"`
+	TokenId   int    `json:"token_id"`
+	TokenName string `json:"token_name"`
+	Operator  string `json:"operator"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// DispatchTokenInvalidated 令牌被作废后通知用户配置的 webhook，未配置 webhook 时不做任何事。
+// operator 为 self 或 admin，标识作废的发起方
+func DispatchTokenInvalidated(token *model.Token, operator string) {
+	if token == nil {
+		return
+	}
+	userSetting, err := model.GetUserSetting(token.UserId, false)
+	if err != nil || userSetting.WebhookUrl == "" {
+		return
+	}
+	payload, err := common.Marshal(TokenWebhookEvent{
+		Type:      WebhookEventTokenInvalidated,
+		UserId:    token.UserId,
+		TokenId:   token.Id,
+		TokenName: token.Name,
+		Operator:  operator,
+		Timestamp: common.GetTimestamp(),
+	})
+	if err != nil {
+		common.SysError(fmt.Sprintf("marshal token %d webhook payload failed: %s", token.Id, err.Error()))
+		return
+	}
+	DefaultWebhookDispatcher.deliver(token.UserId, fmt.Sprintf("令牌 %s", token.Name), userSetting.WebhookUrl, userSetting.WebhookSecret, payload)
+}
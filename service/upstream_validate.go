@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	upstreamValidatePeekBytes = 128
+	upstreamValidateLogBytes  = 200
+)
+
+// ValidateUpstreamResponse 检查上游响应是否符合预期格式，用于发现渠道配置错误时
+// 返回的 HTML 错误页或重定向页面。校验后响应体会被重置，可继续读取。
+// 非 2xx 响应不做校验，交由调用方按上游错误处理。
+func ValidateUpstreamResponse(resp *http.Response, expectedContentType string) error {
+	if resp == nil || resp.Body == nil {
+		return errors.New("empty upstream response")
+	}
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil
+	}
+	body, err := ReadCompressedBody(resp)
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("read upstream response failed: %w", err)
+	}
+
+	validateErr := validateUpstreamBody(resp.Header.Get("Content-Type"), body, expectedContentType)
+	if validateErr != nil {
+		preview := body
+		if len(preview) > upstreamValidateLogBytes {
+			preview = preview[:upstreamValidateLogBytes]
+		}
+		common.SysLog(fmt.Sprintf("invalid upstream response (status %d, content-type %q): %s, body: %s",
+			resp.StatusCode, resp.Header.Get("Content-Type"), validateErr.Error(), string(preview)))
+	}
+	return validateErr
+}
+
+func validateUpstreamBody(contentType string, body []byte, expectedContentType string) error {
+	if expectedContentType != "" && !strings.Contains(strings.ToLower(contentType), strings.ToLower(expectedContentType)) {
+		return fmt.Errorf("unexpected content type %q, expected %q", contentType, expectedContentType)
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	head := strings.ToLower(string(trimmed[:min(len(trimmed), upstreamValidatePeekBytes)]))
+	if strings.HasPrefix(head, "<!doctype") || strings.HasPrefix(head, "<html") {
+		return errors.New("upstream returned an HTML page")
+	}
+
+	if strings.Contains(strings.ToLower(expectedContentType), "json") {
+		if err := quickValidateJSON(trimmed[:min(len(trimmed), upstreamValidatePeekBytes)], len(trimmed) > upstreamValidatePeekBytes); err != nil {
+			return fmt.Errorf("upstream returned invalid JSON: %w", err)
+		}
+	}
+	return nil
+}
+
+// quickValidateJSON 只解析响应开头的若干字节；当内容被截断时，读到末尾视为合法
+func quickValidateJSON(data []byte, truncated bool) error {
+	if len(data) == 0 {
+		return errors.New("empty body")
+	}
+	if !truncated {
+		if !json.Valid(data) {
+			return errors.New("malformed body")
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
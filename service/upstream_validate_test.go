@@ -0,0 +1,47 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newValidateResponse(status int, contentType string, body string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+	if contentType != "" {
+		resp.Header.Set("Content-Type", contentType)
+	}
+	return resp
+}
+
+func TestValidateUpstreamResponse(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  int
+		ctype   string
+		body    string
+		wantErr bool
+	}{
+		{"valid json", http.StatusOK, "application/json", `{"id":"task-1"}`, false},
+		{"long json truncated", http.StatusOK, "application/json; charset=utf-8", `{"data":"` + strings.Repeat("a", 500) + `"}`, false},
+		{"html page", http.StatusOK, "application/json", "<!DOCTYPE html><html></html>", true},
+		{"wrong content type", http.StatusOK, "text/html", `{"id":"task-1"}`, true},
+		{"malformed json", http.StatusOK, "application/json", `{"id":`, true},
+		{"empty body", http.StatusOK, "application/json", "", true},
+		{"upstream error html", http.StatusBadGateway, "text/html", "<html>bad gateway</html>", false},
+		{"upstream error json", http.StatusBadRequest, "application/json", `{"error":"invalid prompt"}`, false},
+	}
+	for _, tc := range cases {
+		resp := newValidateResponse(tc.status, tc.ctype, tc.body)
+		err := ValidateUpstreamResponse(resp, "application/json")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tc.name, err, tc.wantErr)
+		}
+		// 校验后响应体需保持可读，供后续错误处理或解析使用
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != tc.body {
+			t.Errorf("%s: body not restored, got %q", tc.name, string(body))
+		}
+	}
+}
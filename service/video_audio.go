@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	VideoAudioContentType = "audio/aac"
+	videoAudioTimeout     = 5 * time.Minute
+)
+
+// ErrVideoHasNoAudio 视频产出中不包含音频流
+var ErrVideoHasNoAudio = errors.New("the video does not contain an audio track")
+
+// VideoAudioExtractor 从视频中提取音轨，便于替换为云端转码服务
+type VideoAudioExtractor interface {
+	Extract(ctx context.Context, videoURL string) ([]byte, error)
+}
+
+// FfmpegAudioExtractor 调用 ffmpeg 直接读取视频链接，将音频流转码为 AAC（ADTS）输出到标准输出
+type FfmpegAudioExtractor struct {
+	Path string
+}
+
+var DefaultVideoAudioExtractor VideoAudioExtractor = &FfmpegAudioExtractor{}
+
+func (e *FfmpegAudioExtractor) Extract(ctx context.Context, videoURL string) ([]byte, error) {
+	if _, err := url.ParseRequestURI(videoURL); err != nil || !isHTTPURL(videoURL) {
+		return nil, fmt.Errorf("invalid video url")
+	}
+	path := e.Path
+	if path == "" {
+		path = constant.FfmpegPath
+	}
+	ctx, cancel := context.WithTimeout(ctx, videoAudioTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path,
+		"-hide_banner", "-loglevel", "error",
+		"-protocol_whitelist", "http,https,tcp,tls",
+		"-i", videoURL,
+		"-vn", "-map", "0:a:0", "-c:a", "aac", "-b:a", "192k",
+		"-f", "adts", "pipe:1")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if isNoAudioStreamError(stderr.String()) {
+			return nil, ErrVideoHasNoAudio
+		}
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, ErrVideoHasNoAudio
+	}
+	return stdout.Bytes(), nil
+}
+
+// isNoAudioStreamError 根据 ffmpeg 输出判断失败原因是否为视频不含音频流
+func isNoAudioStreamError(stderr string) bool {
+	return strings.Contains(stderr, "matches no streams") ||
+		strings.Contains(stderr, "does not contain any stream")
+}
+
+// TaskAudioStorageKey 按用户与任务 ID 生成音轨的 object key
+func TaskAudioStorageKey(task *model.Task) string {
+	return fmt.Sprintf("tasks/%d/%s.aac", task.UserId, url.PathEscape(task.TaskID))
+}
+
+// ChargeVideoAudioExtraction 按固定额度扣除音轨提取费用并记录消费日志，同一任务只扣费一次
+func ChargeVideoAudioExtraction(c *gin.Context, task *model.Task, quota int) error {
+	if quota <= 0 {
+		return nil
+	}
+	charged, err := task.ChargeAudioExtraction(quota)
+	if err != nil || !charged {
+		return err
+	}
+	tokenId := c.GetInt("token_id")
+	if tokenId > 0 {
+		if err := model.DecreaseTokenQuota(tokenId, c.GetString("token_key"), quota); err != nil {
+			logger.LogWarn(c, fmt.Sprintf("task %s decrease token quota for audio extraction failed: %s", task.TaskID, err.Error()))
+		}
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(task.UserId, quota)
+	model.RecordConsumeLog(c, task.UserId, model.RecordConsumeLogParams{
+		ChannelId: task.ChannelId,
+		ModelName: task.Properties.OriginModelName,
+		TokenName: c.GetString("token_name"),
+		Quota:     quota,
+		Content:   fmt.Sprintf("视频任务 %s 音轨提取", task.TaskID),
+		TokenId:   tokenId,
+		Group:     task.Group,
+		Other: map[string]interface{}{
+			"task_id":          task.TaskID,
+			"audio_extraction": true,
+		},
+	})
+	return nil
+}
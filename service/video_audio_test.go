@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeFakeFfmpeg(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg requires a posix shell")
+	}
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatalf("write fake ffmpeg failed: %v", err)
+	}
+	return path
+}
+
+func TestFfmpegAudioExtractor(t *testing.T) {
+	extractor := &FfmpegAudioExtractor{Path: writeFakeFfmpeg(t, "printf 'audio-bytes'\n")}
+	audio, err := extractor.Extract(context.Background(), "https://cdn.example.com/video.mp4")
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+	if string(audio) != "audio-bytes" {
+		t.Fatalf("audio = %q", audio)
+	}
+
+	if _, err := extractor.Extract(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Fatal("expected non-http url to be rejected")
+	}
+
+	noAudio := &FfmpegAudioExtractor{Path: writeFakeFfmpeg(t, "echo 'Stream map 0:a:0 matches no streams.' >&2\nexit 1\n")}
+	if _, err := noAudio.Extract(context.Background(), "https://cdn.example.com/video.mp4"); !errors.Is(err, ErrVideoHasNoAudio) {
+		t.Fatalf("expected ErrVideoHasNoAudio, got %v", err)
+	}
+
+	broken := &FfmpegAudioExtractor{Path: writeFakeFfmpeg(t, "echo 'Server returned 403 Forbidden' >&2\nexit 1\n")}
+	if _, err := broken.Extract(context.Background(), "https://cdn.example.com/video.mp4"); err == nil || errors.Is(err, ErrVideoHasNoAudio) {
+		t.Fatalf("expected generic ffmpeg error, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const videoQualityScoringPrompt = `You are a video quality reviewer. Watch the video and rate it against the generation prompt below.
+Score each dimension with an integer from 1 (worst) to 10 (best):
+- motion: motion coherence, temporal consistency, absence of flicker or deformation
+- adherence: how faithfully the video follows the prompt
+- visual: overall visual quality, sharpness and aesthetics
+Reply with a JSON object only, for example {"motion":7,"adherence":8,"visual":6}.
+
+Prompt: %s`
+
+// VideoQualityScorerBackend 对视频产出评分的后端，便于替换为不同的视觉模型或服务
+type VideoQualityScorerBackend interface {
+	Score(ctx context.Context, videoURL string, prompt string) (*dto.VideoQualityScores, error)
+}
+
+// VideoQualityScorer 在视频任务成功后异步评分，结果写入 task.Properties.quality_scores 并按分组配置扣除评分费用
+type VideoQualityScorer struct {
+	Backend VideoQualityScorerBackend
+}
+
+var DefaultVideoQualityScorer = &VideoQualityScorer{
+	Backend: &VisionModelQualityBackend{},
+}
+
+// ScoreTask 对已成功且产出为 http(s) 链接的任务发起评分，任务所在分组未开启评分或已有评分时跳过
+func (s *VideoQualityScorer) ScoreTask(ctx context.Context, task *model.Task) {
+	if s == nil || s.Backend == nil || task == nil || task.Status != model.TaskStatusSuccess {
+		return
+	}
+	if task.Properties.QualityScores != nil || !isHTTPURL(task.FailReason) {
+		return
+	}
+	cfg, ok := ratio_setting.GetGroupQualityScoringConfig(task.Group)
+	if !ok {
+		return
+	}
+	scored := *task
+	gopool.Go(func() {
+		// 评分前先预留费用，余额不足时不评分；评分失败时退回
+		reservationIds, err := reserveVideoQualityScoring(&scored, cfg.Quota)
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("task %s quality scoring skipped: %s", scored.TaskID, err.Error()))
+			return
+		}
+		scores, err := s.Backend.Score(context.Background(), scored.FailReason, scored.Properties.Input)
+		if err != nil {
+			ReleaseQuotaReservation(reservationIds...)
+			logger.LogWarn(ctx, fmt.Sprintf("task %s quality scoring failed: %s", scored.TaskID, err.Error()))
+			return
+		}
+		if err := scored.UpdateQualityScores(scores); err != nil {
+			ReleaseQuotaReservation(reservationIds...)
+			logger.LogError(ctx, fmt.Sprintf("task %s save quality scores failed: %s", scored.TaskID, err.Error()))
+			return
+		}
+		if cfg.Quota > 0 {
+			chargeVideoQualityScoring(ctx, &scored, cfg.Quota, reservationIds)
+		}
+	})
+}
+
+// reserveVideoQualityScoring 原子地检查并预留用户与令牌的评分费用，任一额度不足时返回错误且不扣费
+func reserveVideoQualityScoring(task *model.Task, quota int) ([]int64, error) {
+	if quota <= 0 {
+		return nil, nil
+	}
+	userReservationId, err := model.ReserveUserQuota(task.UserId, quota)
+	if err != nil {
+		return nil, err
+	}
+	if task.PrivateData.TokenId <= 0 {
+		return []int64{userReservationId}, nil
+	}
+	tokenReservationId, err := model.ReserveTokenQuota(task.PrivateData.TokenId, quota)
+	if err != nil {
+		ReleaseQuotaReservation(userReservationId)
+		return nil, err
+	}
+	return []int64{userReservationId, tokenReservationId}, nil
+}
+
+func chargeVideoQualityScoring(ctx context.Context, task *model.Task, quota int, reservationIds []int64) {
+	for _, id := range reservationIds {
+		if err := model.ConfirmReservation(id); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task %s confirm quality scoring charge failed: %s", task.TaskID, err.Error()))
+			return
+		}
+	}
+	model.UpdateUserUsedQuotaAndRequestCount(task.UserId, quota)
+	model.RecordConsumeLog(nil, task.UserId, model.RecordConsumeLogParams{
+		ChannelId: task.ChannelId,
+		ModelName: ratio_setting.GetGroupQualityScoringSetting().Model,
+		TokenName: task.PrivateData.TokenName,
+		Quota:     quota,
+		Content:   fmt.Sprintf("视频任务 %s 质量评分", task.TaskID),
+		TokenId:   task.PrivateData.TokenId,
+		Group:     task.Group,
+		Other: map[string]interface{}{
+			"task_id":         task.TaskID,
+			"quality_scoring": true,
+		},
+	})
+}
+
+type qualityScoringMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type qualityScoringRequest struct {
+	Model          string                  `json:"model"`
+	Messages       []qualityScoringMessage `json:"messages"`
+	ResponseFormat map[string]string       `json:"response_format,omitempty"`
+}
+
+type qualityScoringResponse struct {
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+// VisionModelQualityBackend 调用 OpenAI 兼容的 chat completions 接口（如 Gemini Flash）以 video_url 输入评分
+type VisionModelQualityBackend struct{}
+
+func (b *VisionModelQualityBackend) Score(ctx context.Context, videoURL string, prompt string) (*dto.VideoQualityScores, error) {
+	setting := ratio_setting.GetGroupQualityScoringSetting()
+	body, err := common.Marshal(qualityScoringRequest{
+		Model: setting.Model,
+		Messages: []qualityScoringMessage{{
+			Role: "user",
+			Content: []dto.MediaContent{
+				{Type: dto.ContentTypeText, Text: fmt.Sprintf(videoQualityScoringPrompt, prompt)},
+				{Type: dto.ContentTypeVideoUrl, VideoUrl: &dto.MessageVideoUrl{Url: videoURL}},
+			},
+		}},
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(setting.TimeoutSeconds) * time.Second
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.ApiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+setting.ApiKey)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("quality scoring request failed: %w", err)
+	}
+	defer CloseResponseBodyGracefully(resp)
+	respBody, err := ReadCompressedBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("quality scoring endpoint returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	var scoringResp qualityScoringResponse
+	if err := common.Unmarshal(respBody, &scoringResp); err != nil {
+		return nil, fmt.Errorf("decode quality scoring response failed: %w", err)
+	}
+	if len(scoringResp.Choices) == 0 {
+		return nil, fmt.Errorf("quality scoring response has no choices")
+	}
+	return parseVideoQualityScores(scoringResp.Choices[0].Message.Content)
+}
+
+// parseVideoQualityScores 解析模型回复中的 JSON 评分，兼容包裹在 markdown 代码块中的回复，分数截断到 1-10
+func parseVideoQualityScores(content string) (*dto.VideoQualityScores, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("quality scoring reply is not json: %s", content)
+	}
+	var scores dto.VideoQualityScores
+	if err := common.UnmarshalJsonStr(content[start:end+1], &scores); err != nil {
+		return nil, fmt.Errorf("decode quality scores failed: %w", err)
+	}
+	scores.Motion = clampQualityScore(scores.Motion)
+	scores.Adherence = clampQualityScore(scores.Adherence)
+	scores.Visual = clampQualityScore(scores.Visual)
+	return &scores, nil
+}
+
+func clampQualityScore(score int) int {
+	return min(max(score, 1), 10)
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestVisionModelQualityBackend(t *testing.T) {
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"` + "```json\\n" + `{\"motion\":7,\"adherence\":12,\"visual\":0}\n` + "```" + `"}}]}`))
+	}))
+	defer server.Close()
+
+	setting := ratio_setting.GetGroupQualityScoringSetting()
+	prevSetting := *setting
+	setting.Endpoint = server.URL
+	t.Cleanup(func() { *setting = prevSetting })
+	InitHttpClient()
+
+	scores, err := (&VisionModelQualityBackend{}).Score(context.Background(), "https://cdn.example.com/v.mp4", "a cat")
+	if err != nil {
+		t.Fatalf("Score() error = %v", err)
+	}
+	if *scores != (dto.VideoQualityScores{Motion: 7, Adherence: 10, Visual: 1}) {
+		t.Fatalf("scores = %+v", scores)
+	}
+	if !strings.Contains(gotBody, `"video_url":{"url":"https://cdn.example.com/v.mp4"}`) || !strings.Contains(gotBody, "Prompt: a cat") {
+		t.Fatalf("unexpected request body: %s", gotBody)
+	}
+}
+
+func TestParseVideoQualityScoresRejectsNonJSON(t *testing.T) {
+	if _, err := parseVideoQualityScores("looks great"); err == nil {
+		t.Fatal("expected error for non-json reply")
+	}
+}
+
+type stubQualityBackend struct {
+	calls chan struct{}
+	err   error
+}
+
+func (b *stubQualityBackend) Score(ctx context.Context, videoURL string, prompt string) (*dto.VideoQualityScores, error) {
+	b.calls <- struct{}{}
+	if b.err != nil {
+		return nil, b.err
+	}
+	return &dto.VideoQualityScores{Motion: 8, Adherence: 8, Visual: 8}, nil
+}
+
+func TestScoreTaskChargesOnlyWithEnoughQuota(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&model.User{}, &model.Token{}, &model.Task{}, &model.QuotaReservation{}, &model.Log{}, &model.BillingSnapshot{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	originDB, originLogDB, originSQLite, originRedis := model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled
+	model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled = db, db, true, false
+	t.Cleanup(func() {
+		model.DB, model.LOG_DB, common.UsingSQLite, common.RedisEnabled = originDB, originLogDB, originSQLite, originRedis
+	})
+	setting := ratio_setting.GetGroupQualityScoringSetting()
+	prevSetting := *setting
+	setting.Endpoint = "http://scorer.invalid"
+	setting.Groups = map[string]ratio_setting.GroupQualityScoringConfig{"vip": {Enabled: true, Quota: 10}}
+	t.Cleanup(func() { *setting = prevSetting })
+
+	userQuota := func() int {
+		var q int
+		db.Model(&model.User{}).Where("id = ?", 1).Select("quota").Find(&q)
+		return q
+	}
+	waitQuota := func(want int) {
+		t.Helper()
+		for i := 0; i < 100 && userQuota() != want; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if got := userQuota(); got != want {
+			t.Fatalf("user quota = %d, want %d", got, want)
+		}
+	}
+	if err := db.Create(&model.User{Id: 1, Username: "scoring", Quota: 5}).Error; err != nil {
+		t.Fatalf("create user failed: %v", err)
+	}
+	task := &model.Task{TaskID: "task_1", UserId: 1, Group: "vip", Status: model.TaskStatusSuccess, FailReason: "https://cdn.example.com/v.mp4"}
+	if err := db.Create(task).Error; err != nil {
+		t.Fatalf("create task failed: %v", err)
+	}
+
+	// 余额不足时不评分也不扣费
+	backend := &stubQualityBackend{calls: make(chan struct{}, 1)}
+	scorer := &VideoQualityScorer{Backend: backend}
+	scorer.ScoreTask(context.Background(), task)
+	select {
+	case <-backend.calls:
+		t.Fatal("scoring should be skipped when quota is not enough")
+	case <-time.After(100 * time.Millisecond):
+	}
+	waitQuota(5)
+
+	// 评分失败时退回预留的费用
+	db.Model(&model.User{}).Where("id = ?", 1).Update("quota", 100)
+	backend.err = errors.New("scorer unavailable")
+	scorer.ScoreTask(context.Background(), task)
+	<-backend.calls
+	waitQuota(100)
+
+	backend.err = nil
+	scorer.ScoreTask(context.Background(), task)
+	<-backend.calls
+	waitQuota(90)
+}
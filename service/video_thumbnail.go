@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	VideoThumbnailPositionStart  = "start"
+	VideoThumbnailPositionMiddle = "middle"
+	// 截取、上传缩略图的总超时
+	videoThumbnailTimeout = 30 * time.Second
+	// 缩略图服务返回图片的大小上限
+	maxVideoThumbnailBytes = 10 << 20
+)
+
+// VideoThumbnailExtractor 在视频任务成功后异步调用缩略图服务截取一帧（开头或中间），
+// 上传到对象存储并写入 task.Properties.thumbnail_url。
+// 缩略图服务接收 POST {"video_url": "...", "position": "start|middle"}，以图片内容作为响应体
+type VideoThumbnailExtractor struct {
+	// Endpoint 为空时使用 VIDEO_THUMBNAIL_SERVICE_URL
+	Endpoint string
+	// Position 为空时使用 VIDEO_THUMBNAIL_POSITION
+	Position string
+	Storage  func() *SignedURLProxy
+
+	inflight sync.Map
+}
+
+var DefaultVideoThumbnailExtractor = &VideoThumbnailExtractor{Storage: GetSignedURLProxy}
+
+type videoThumbnailRequest struct {
+	VideoURL string `json:"video_url"`
+	Position string `json:"position"`
+}
+
+func (e *VideoThumbnailExtractor) endpoint() string {
+	if e.Endpoint != "" {
+		return e.Endpoint
+	}
+	return constant.VideoThumbnailServiceURL
+}
+
+func (e *VideoThumbnailExtractor) position() string {
+	position := e.Position
+	if position == "" {
+		position = constant.VideoThumbnailPosition
+	}
+	if position != VideoThumbnailPositionMiddle {
+		return VideoThumbnailPositionStart
+	}
+	return position
+}
+
+// ExtractTask 对已成功且产出为 http(s) 链接的任务在后台生成缩略图，未配置缩略图服务或对象存储、已有缩略图时跳过
+func (e *VideoThumbnailExtractor) ExtractTask(ctx context.Context, task *model.Task) {
+	if e == nil || task == nil || task.Status != model.TaskStatusSuccess {
+		return
+	}
+	if task.Properties.ThumbnailURL != "" || !isHTTPURL(task.FailReason) || e.endpoint() == "" {
+		return
+	}
+	var storage *SignedURLProxy
+	if e.Storage != nil {
+		storage = e.Storage()
+	}
+	if storage == nil {
+		return
+	}
+	if _, loaded := e.inflight.LoadOrStore(task.ID, struct{}{}); loaded {
+		return
+	}
+	extracted := *task
+	gopool.Go(func() {
+		defer e.inflight.Delete(extracted.ID)
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), videoThumbnailTimeout)
+		defer cancel()
+		thumbnailURL, err := e.extract(timeoutCtx, storage, &extracted)
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("task %s thumbnail extraction failed: %s", extracted.TaskID, err.Error()))
+			return
+		}
+		if err := extracted.UpdateThumbnailURL(thumbnailURL); err != nil {
+			logger.LogError(ctx, fmt.Sprintf("task %s save thumbnail url failed: %s", extracted.TaskID, err.Error()))
+		}
+	})
+}
+
+// extract 调用缩略图服务并上传结果，返回对象存储中的缩略图地址
+func (e *VideoThumbnailExtractor) extract(ctx context.Context, storage *SignedURLProxy, task *model.Task) (string, error) {
+	body, err := common.Marshal(videoThumbnailRequest{VideoURL: task.FailReason, Position: e.position()})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint(), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request thumbnail service failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("thumbnail service returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	image, err := io.ReadAll(io.LimitReader(resp.Body, maxVideoThumbnailBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("read thumbnail failed: %w", err)
+	}
+	if len(image) == 0 || len(image) > maxVideoThumbnailBytes {
+		return "", fmt.Errorf("invalid thumbnail size %d", len(image))
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(image)
+	}
+
+	key := taskThumbnailKey(task, contentType)
+	if err := storage.Upload(ctx, key, bytes.NewReader(image), int64(len(image)), contentType); err != nil {
+		return "", err
+	}
+	return storage.objectURL(key), nil
+}
+
+// taskThumbnailKey 按用户与任务 ID 生成缩略图的 object key
+func taskThumbnailKey(task *model.Task, contentType string) string {
+	ext := ".jpg"
+	switch {
+	case strings.HasPrefix(contentType, "image/png"):
+		ext = ".png"
+	case strings.HasPrefix(contentType, "image/webp"):
+		ext = ".webp"
+	}
+	return fmt.Sprintf("tasks/%d/%s_thumbnail%s", task.UserId, url.PathEscape(task.TaskID), ext)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+)
+
+func TestVideoThumbnailExtractorExtract(t *testing.T) {
+	InitHttpClient()
+	var gotRequest string
+	thumbnailServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotRequest = string(body)
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG\r\n\x1a\nframe"))
+	}))
+	defer thumbnailServer.Close()
+	var uploadPath, uploadType string
+	storageServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploadPath, uploadType = r.URL.Path, r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storageServer.Close()
+
+	storage := NewSignedURLProxy(storageServer.URL, "videos", "", "AKID", "SECRET", 0)
+	extractor := &VideoThumbnailExtractor{Endpoint: thumbnailServer.URL, Position: VideoThumbnailPositionMiddle}
+	task := &model.Task{UserId: 7, TaskID: "task_1", Status: model.TaskStatusSuccess, FailReason: "https://cdn.example.com/v.mp4"}
+
+	thumbnailURL, err := extractor.extract(context.Background(), storage, task)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !strings.Contains(gotRequest, `"video_url":"https://cdn.example.com/v.mp4"`) || !strings.Contains(gotRequest, `"position":"middle"`) {
+		t.Fatalf("unexpected thumbnail request: %s", gotRequest)
+	}
+	if uploadPath != "/videos/tasks/7/task_1_thumbnail.png" || uploadType != "image/png" {
+		t.Fatalf("unexpected upload path=%s type=%s", uploadPath, uploadType)
+	}
+	if thumbnailURL != storageServer.URL+"/videos/tasks/7/task_1_thumbnail.png" {
+		t.Fatalf("thumbnail url = %s", thumbnailURL)
+	}
+
+	task.Properties.ThumbnailURL = thumbnailURL
+	if signed := storage.TaskThumbnailURL(task); !strings.Contains(signed, "X-Amz-Signature=") {
+		t.Fatalf("expected signed thumbnail url, got %s", signed)
+	}
+	var nilProxy *SignedURLProxy
+	if nilProxy.TaskThumbnailURL(task) != thumbnailURL {
+		t.Fatal("nil proxy should return the stored thumbnail url")
+	}
+}
+
+func TestVideoThumbnailExtractorServiceError(t *testing.T) {
+	InitHttpClient()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	storage := NewSignedURLProxy(server.URL, "videos", "", "AKID", "SECRET", 0)
+	task := &model.Task{UserId: 7, TaskID: "task_1", FailReason: "https://cdn.example.com/v.mp4"}
+	if _, err := (&VideoThumbnailExtractor{Endpoint: server.URL}).extract(context.Background(), storage, task); err == nil {
+		t.Fatal("expected error when the thumbnail service fails")
+	}
+}
@@ -0,0 +1,35 @@
+package model_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// SunoSettings 定义Suno音乐生成的配置
+type SunoSettings struct {
+	// BillByClip 为 true 时按生成的歌曲片段数计费，suno_music 的模型价格视为单个片段的价格
+	BillByClip bool `json:"bill_by_clip"`
+	// ClipsPerGeneration 每次生成产出的片段数
+	ClipsPerGeneration int `json:"clips_per_generation"`
+}
+
+// 默认配置
+var defaultSunoSettings = SunoSettings{
+	BillByClip:         false,
+	ClipsPerGeneration: 2,
+}
+
+// 全局实例
+var sunoSettings = defaultSunoSettings
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("suno", &sunoSettings)
+}
+
+// GetSunoSettings 获取Suno配置
+func GetSunoSettings() *SunoSettings {
+	if sunoSettings.ClipsPerGeneration <= 0 {
+		sunoSettings.ClipsPerGeneration = defaultSunoSettings.ClipsPerGeneration
+	}
+	return &sunoSettings
+}
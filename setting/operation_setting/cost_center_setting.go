@@ -0,0 +1,36 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type CostCenterSetting struct {
+	// 允许通过 X-Cost-Center 请求头指定的成本中心，为空时不启用成本中心标记
+	AllowedCostCenters []string `json:"allowed_cost_centers"`
+}
+
+var costCenterSetting = CostCenterSetting{
+	AllowedCostCenters: []string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("cost_center_setting", &costCenterSetting)
+}
+
+func GetCostCenterSetting() *CostCenterSetting {
+	return &costCenterSetting
+}
+
+// IsCostCenterEnabled 是否配置了成本中心白名单
+func IsCostCenterEnabled() bool {
+	return len(costCenterSetting.AllowedCostCenters) > 0
+}
+
+// IsCostCenterAllowed 判断成本中心是否在白名单中
+func IsCostCenterAllowed(costCenter string) bool {
+	for _, allowed := range costCenterSetting.AllowedCostCenters {
+		if allowed == costCenter {
+			return true
+		}
+	}
+	return false
+}
@@ -10,12 +10,37 @@ import (
 type MonitorSetting struct {
 	AutoTestChannelEnabled bool    `json:"auto_test_channel_enabled"`
 	AutoTestChannelMinutes float64 `json:"auto_test_channel_minutes"`
+
+	// 渠道健康告警：单个窗口内错误率超过阈值且请求数不少于最小请求数时告警
+	ChannelHealthAlertEnabled       bool    `json:"channel_health_alert_enabled"`
+	ChannelHealthErrorRateThreshold float64 `json:"channel_health_error_rate_threshold"`
+	ChannelHealthMinRequests        int     `json:"channel_health_min_requests"`
+	ChannelHealthAutoDisable        bool    `json:"channel_health_auto_disable"`
+	ChannelHealthAlertEmail         string  `json:"channel_health_alert_email"`
+	ChannelHealthAlertWebhookUrl    string  `json:"channel_health_alert_webhook_url"`
+	ChannelHealthAlertWebhookSecret string  `json:"channel_health_alert_webhook_secret"`
+
+	// 渠道熔断：滑动窗口内失败率超过阈值且请求数不少于最小请求数时熔断，冷却期后放行一个探测请求
+	ChannelCircuitBreakerEnabled         bool    `json:"channel_circuit_breaker_enabled"`
+	ChannelCircuitBreakerErrorRate       float64 `json:"channel_circuit_breaker_error_rate"`
+	ChannelCircuitBreakerMinRequests     int     `json:"channel_circuit_breaker_min_requests"`
+	ChannelCircuitBreakerCooldownSeconds int     `json:"channel_circuit_breaker_cooldown_seconds"`
 }
 
 // 默认配置
 var monitorSetting = MonitorSetting{
 	AutoTestChannelEnabled: false,
 	AutoTestChannelMinutes: 10,
+
+	ChannelHealthAlertEnabled:       false,
+	ChannelHealthErrorRateThreshold: 0.5,
+	ChannelHealthMinRequests:        20,
+	ChannelHealthAutoDisable:        false,
+
+	ChannelCircuitBreakerEnabled:         false,
+	ChannelCircuitBreakerErrorRate:       0.5,
+	ChannelCircuitBreakerMinRequests:     10,
+	ChannelCircuitBreakerCooldownSeconds: 30,
 }
 
 func init() {
@@ -0,0 +1,53 @@
+package operation_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type TaskSetting struct {
+	// 每个渠道允许同时提交中的任务数，key 为渠道 ID，未配置或 <= 0 表示不限制
+	MaxConcurrentTasksPerChannel map[int]int `json:"max_concurrent_tasks_per_channel"`
+	// 依赖任务提交时，上游任务产出链接写入的请求字段，为空时由下游任务的适配器决定
+	DependencyInputField string `json:"dependency_input_field"`
+	// 支持提取音轨的模型名前缀
+	AudioExtractionModels []string `json:"audio_extraction_models"`
+	// 每次提取音轨扣除的额度，已提取过的音轨再次获取不重复扣费
+	AudioExtractionQuota int `json:"audio_extraction_quota"`
+}
+
+var taskSetting = TaskSetting{
+	MaxConcurrentTasksPerChannel: map[int]int{},
+	AudioExtractionModels:        []string{"doubao-seedance-1-5-pro"},
+	AudioExtractionQuota:         1000,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("task_setting", &taskSetting)
+}
+
+func GetTaskSetting() *TaskSetting {
+	return &taskSetting
+}
+
+// GetMaxConcurrentTasks 获取渠道的任务并发上限，0 表示不限制
+func GetMaxConcurrentTasks(channelId int) int {
+	return taskSetting.MaxConcurrentTasksPerChannel[channelId]
+}
+
+// GetDependencyInputField 获取管理员配置的依赖任务产出链接写入字段，未配置时返回空字符串
+func GetDependencyInputField() string {
+	return taskSetting.DependencyInputField
+}
+
+// IsAudioExtractionModel 判断模型产出的视频是否支持提取音轨
+func IsAudioExtractionModel(modelName string) bool {
+	for _, prefix := range taskSetting.AudioExtractionModels {
+		if prefix != "" && strings.HasPrefix(modelName, prefix) {
+			return true
+		}
+	}
+	return false
+}
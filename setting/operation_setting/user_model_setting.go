@@ -0,0 +1,44 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type UserModelSetting struct {
+	// 用户注册的自定义模型是否需要管理员审核后才能使用
+	RequireApproval bool `json:"require_approval"`
+	// 自定义模型单次调用的最低价格（美元），防止用户设置过低价格套利
+	MinPrice float64 `json:"min_price"`
+	// 允许绑定自定义模型的渠道类型
+	AllowedChannelTypes []int `json:"allowed_channel_types"`
+}
+
+var userModelSetting = UserModelSetting{
+	RequireApproval: true,
+	MinPrice:        0.01,
+	AllowedChannelTypes: []int{
+		constant.ChannelTypeReplicate,
+		constant.ChannelTypeReplicate2,
+		constant.ChannelTypeFal,
+	},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("user_model_setting", &userModelSetting)
+}
+
+func GetUserModelSetting() *UserModelSetting {
+	return &userModelSetting
+}
+
+// IsUserModelChannelTypeAllowed 判断渠道类型是否允许绑定自定义模型
+func IsUserModelChannelTypeAllowed(channelType int) bool {
+	for _, t := range userModelSetting.AllowedChannelTypes {
+		if t == channelType {
+			return true
+		}
+	}
+	return false
+}
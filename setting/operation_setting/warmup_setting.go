@@ -0,0 +1,42 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type WarmupSetting struct {
+	// 是否启用定时预热
+	Enabled bool `json:"enabled"`
+	// 定时预热的 cron 表达式（分 时 日 月 周），默认每天 08:30
+	Cron string `json:"cron"`
+	// 参与定时预热的渠道
+	ChannelIds []int `json:"channel_ids"`
+	// 提交预热任务所用的令牌，预热任务与普通任务一样计费，取消后退回额度
+	TokenId int `json:"token_id"`
+	// 每个模型的预热提示词，未配置时使用 DefaultPrompt
+	TestPrompts   map[string]string `json:"test_prompts"`
+	DefaultPrompt string            `json:"default_prompt"`
+}
+
+var warmupSetting = WarmupSetting{
+	Enabled:       false,
+	Cron:          "30 8 * * *",
+	ChannelIds:    []int{},
+	TestPrompts:   map[string]string{},
+	DefaultPrompt: "a cat",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("warmup_setting", &warmupSetting)
+}
+
+func GetWarmupSetting() *WarmupSetting {
+	return &warmupSetting
+}
+
+// GetWarmupPrompt 获取模型的预热提示词
+func GetWarmupPrompt(modelName string) string {
+	if prompt, ok := warmupSetting.TestPrompts[modelName]; ok && prompt != "" {
+		return prompt
+	}
+	return warmupSetting.DefaultPrompt
+}
@@ -6,3 +6,6 @@ var StripePriceId = ""
 var StripeUnitPrice = 8.0
 var StripeMinTopUp = 1
 var StripePromotionCodesEnabled = false
+
+// StripeQuotaPackages 额度套餐，JSON 数组：[{"id":"basic","name":"500K","quota":500000,"price":1,"currency":"usd"}]
+var StripeQuotaPackages = "[]"
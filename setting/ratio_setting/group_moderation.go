@@ -0,0 +1,49 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// GroupModerationConfig 单个分组的任务提交前内容审核配置
+type GroupModerationConfig struct {
+	Enabled bool `json:"enabled"`
+	// DryRun 只记录审核结果，不拦截请求
+	DryRun bool `json:"dry_run"`
+	// Threshold 任一类别分数不低于阈值即视为违规，<= 0 时以审核接口返回的 flagged 为准
+	Threshold float64 `json:"threshold"`
+}
+
+// GroupModerationSetting 异步任务提交前对 prompt 进行内容审核，审核接口需兼容 OpenAI /v1/moderations
+type GroupModerationSetting struct {
+	// Endpoint 审核接口完整地址，如 https://api.openai.com/v1/moderations 或本地部署的兼容服务
+	Endpoint       string `json:"endpoint"`
+	ApiKey         string `json:"api_key"`
+	Model          string `json:"model"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	// Groups 分组 -> 审核配置，未配置的分组不审核
+	Groups map[string]GroupModerationConfig `json:"groups"`
+}
+
+var groupModerationSetting = GroupModerationSetting{
+	Endpoint:       "https://api.openai.com/v1/moderations",
+	Model:          "omni-moderation-latest",
+	TimeoutSeconds: 10,
+	Groups:         map[string]GroupModerationConfig{},
+}
+
+func init() {
+	config.GlobalConfig.Register("group_moderation_setting", &groupModerationSetting)
+}
+
+func GetGroupModerationSetting() *GroupModerationSetting {
+	return &groupModerationSetting
+}
+
+// GetGroupModerationConfig 返回分组启用的审核配置
+func GetGroupModerationConfig(group string) (GroupModerationConfig, bool) {
+	cfg, ok := groupModerationSetting.Groups[group]
+	if !ok || !cfg.Enabled || groupModerationSetting.Endpoint == "" {
+		return GroupModerationConfig{}, false
+	}
+	return cfg, true
+}
@@ -0,0 +1,45 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// GroupQualityScoringConfig 单个分组的视频质量评分配置
+type GroupQualityScoringConfig struct {
+	Enabled bool `json:"enabled"`
+	// Quota 每次评分成功后额外扣除的额度
+	Quota int `json:"quota"`
+}
+
+// GroupQualityScoringSetting 视频任务成功后调用视觉模型对产出评分，接口需兼容 OpenAI /v1/chat/completions 且支持 video_url 输入
+type GroupQualityScoringSetting struct {
+	Endpoint       string `json:"endpoint"`
+	ApiKey         string `json:"api_key"`
+	Model          string `json:"model"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	// Groups 分组 -> 评分配置，未配置的分组不评分
+	Groups map[string]GroupQualityScoringConfig `json:"groups"`
+}
+
+var groupQualityScoringSetting = GroupQualityScoringSetting{
+	Model:          "gemini-2.0-flash",
+	TimeoutSeconds: 60,
+	Groups:         map[string]GroupQualityScoringConfig{},
+}
+
+func init() {
+	config.GlobalConfig.Register("group_quality_scoring_setting", &groupQualityScoringSetting)
+}
+
+func GetGroupQualityScoringSetting() *GroupQualityScoringSetting {
+	return &groupQualityScoringSetting
+}
+
+// GetGroupQualityScoringConfig 返回分组启用的评分配置
+func GetGroupQualityScoringConfig(group string) (GroupQualityScoringConfig, bool) {
+	cfg, ok := groupQualityScoringSetting.Groups[group]
+	if !ok || !cfg.Enabled || groupQualityScoringSetting.Endpoint == "" {
+		return GroupQualityScoringConfig{}, false
+	}
+	return cfg, true
+}
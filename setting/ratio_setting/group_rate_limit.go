@@ -0,0 +1,48 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// GroupModelRateLimit 分组下单个模型的限流配置，0 表示不限制
+type GroupModelRateLimit struct {
+	RPM int `json:"rpm"`
+	TPM int `json:"tpm"`
+}
+
+// GroupRateLimitSetting 按分组配置的 RPM/TPM 限流，计数按 (分组, 模型) 维度独立统计
+type GroupRateLimitSetting struct {
+	Enabled bool `json:"enabled"`
+	// Limits 分组 -> 限流配置，对该分组下的每个模型分别生效
+	Limits map[string]GroupModelRateLimit `json:"limits"`
+	// ModelLimits 分组 -> 模型 -> 限流配置，优先于 Limits
+	ModelLimits map[string]map[string]GroupModelRateLimit `json:"model_limits"`
+}
+
+var groupRateLimitSetting = GroupRateLimitSetting{
+	Enabled:     false,
+	Limits:      map[string]GroupModelRateLimit{},
+	ModelLimits: map[string]map[string]GroupModelRateLimit{},
+}
+
+func init() {
+	config.GlobalConfig.Register("group_rate_limit_setting", &groupRateLimitSetting)
+}
+
+func GetGroupRateLimitSetting() *GroupRateLimitSetting {
+	return &groupRateLimitSetting
+}
+
+// GetGroupModelRateLimit 返回分组下指定模型的限流配置
+func GetGroupModelRateLimit(group, modelName string) (GroupModelRateLimit, bool) {
+	if !groupRateLimitSetting.Enabled {
+		return GroupModelRateLimit{}, false
+	}
+	if models, ok := groupRateLimitSetting.ModelLimits[group]; ok {
+		if limit, ok := models[modelName]; ok {
+			return limit, true
+		}
+	}
+	limit, ok := groupRateLimitSetting.Limits[group]
+	return limit, ok
+}
@@ -0,0 +1,43 @@
+package ratio_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	TaskPriorityNormal   = 0
+	TaskPriorityHigh     = 1
+	TaskPriorityCritical = 2
+)
+
+// GroupTaskPrioritySetting 按用户分组配置异步任务的轮询优先级，未配置的分组为普通优先级
+type GroupTaskPrioritySetting struct {
+	// Priorities 分组 -> 优先级（0 普通，1 高，2 紧急）
+	Priorities map[string]int `json:"priorities"`
+}
+
+var groupTaskPrioritySetting = GroupTaskPrioritySetting{
+	Priorities: map[string]int{},
+}
+
+func init() {
+	config.GlobalConfig.Register("group_task_priority_setting", &groupTaskPrioritySetting)
+}
+
+func GetGroupTaskPrioritySetting() *GroupTaskPrioritySetting {
+	return &groupTaskPrioritySetting
+}
+
+// IsValidTaskPriority 判断优先级是否在允许范围内
+func IsValidTaskPriority(priority int) bool {
+	return priority >= TaskPriorityNormal && priority <= TaskPriorityCritical
+}
+
+// GetGroupTaskPriority 返回分组的任务优先级，超出范围的配置按普通优先级处理
+func GetGroupTaskPriority(group string) int {
+	priority, ok := groupTaskPrioritySetting.Priorities[group]
+	if !ok || !IsValidTaskPriority(priority) {
+		return TaskPriorityNormal
+	}
+	return priority
+}
@@ -0,0 +1,65 @@
+package ratio_setting
+
+import (
+	"time"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ModelResponseCacheConfig 单个模型的响应缓存配置
+type ModelResponseCacheConfig struct {
+	CacheEnabled bool `json:"cache_enabled"`
+	// CacheTTL 缓存有效期（秒）
+	CacheTTL int `json:"cache_ttl"`
+}
+
+// ResponseCacheSetting 对输出确定的模型（如固定 seed 的图片生成）缓存非流式响应，命中时不请求上游也不扣费，需要启用 Redis
+type ResponseCacheSetting struct {
+	// Models 模型 -> 缓存配置，未配置的模型不缓存
+	Models map[string]ModelResponseCacheConfig `json:"models"`
+	// MaxBodyBytes 超过该大小的响应不缓存
+	MaxBodyBytes int `json:"max_body_bytes"`
+	// ImageGenerationTTL 未在 Models 中配置的模型，图片生成请求的缓存有效期（秒），0 表示不缓存
+	ImageGenerationTTL int `json:"image_generation_ttl"`
+}
+
+var responseCacheSetting = ResponseCacheSetting{
+	Models:             map[string]ModelResponseCacheConfig{},
+	MaxBodyBytes:       1 << 20,
+	ImageGenerationTTL: 600,
+}
+
+func init() {
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
+
+// GetModelResponseCacheTTL 返回模型的响应缓存有效期，未启用缓存时返回 false
+func GetModelResponseCacheTTL(model string) (time.Duration, bool) {
+	cfg, ok := responseCacheSetting.Models[model]
+	if !ok {
+		cfg, ok = responseCacheSetting.Models[FormatMatchingModelName(model)]
+	}
+	if !ok || !cfg.CacheEnabled || cfg.CacheTTL <= 0 {
+		return 0, false
+	}
+	return time.Duration(cfg.CacheTTL) * time.Second, true
+}
+
+// GetImageGenerationResponseCacheTTL 返回图片生成请求的缓存有效期，模型在 Models 中有配置时以模型配置为准
+func GetImageGenerationResponseCacheTTL(model string) (time.Duration, bool) {
+	_, configured := responseCacheSetting.Models[model]
+	if !configured {
+		_, configured = responseCacheSetting.Models[FormatMatchingModelName(model)]
+	}
+	if configured {
+		return GetModelResponseCacheTTL(model)
+	}
+	if responseCacheSetting.ImageGenerationTTL <= 0 {
+		return 0, false
+	}
+	return time.Duration(responseCacheSetting.ImageGenerationTTL) * time.Second, true
+}
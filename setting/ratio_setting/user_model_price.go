@@ -0,0 +1,21 @@
+package ratio_setting
+
+// GetUserModelPrice 返回用户自定义模型的按次价格，取注册价格与运营方为上游模型配置的按次价格中的较大者。
+// 上游模型只配置了按量倍率时返回 false，调用方应按上游模型的倍率计费
+func GetUserModelPrice(upstreamModel string, priceOverride float64) (float64, bool) {
+	price, ok := GetModelPrice(upstreamModel, false)
+	if !ok {
+		if hasModelRatio(upstreamModel) {
+			return -1, false
+		}
+		return priceOverride, true
+	}
+	return max(price, priceOverride), true
+}
+
+func hasModelRatio(name string) bool {
+	modelRatioMapMutex.RLock()
+	defer modelRatioMapMutex.RUnlock()
+	_, ok := modelRatioMap[FormatMatchingModelName(name)]
+	return ok
+}
@@ -0,0 +1,21 @@
+package system_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+type UpstreamSetting struct {
+	// 校验上游响应的 Content-Type 与内容，拦截 HTML 错误页等异常响应
+	StrictResponseValidation bool `json:"strict_response_validation"`
+}
+
+var defaultUpstreamSetting = UpstreamSetting{
+	StrictResponseValidation: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("upstream_setting", &defaultUpstreamSetting)
+}
+
+func GetUpstreamSetting() *UpstreamSetting {
+	return &defaultUpstreamSetting
+}
@@ -58,6 +58,9 @@ const (
 	ErrorCodeChannelAwsClientError        ErrorCode = "channel:aws_client_error"
 	ErrorCodeChannelInvalidKey            ErrorCode = "channel:invalid_key"
 	ErrorCodeChannelResponseTimeExceeded  ErrorCode = "channel:response_time_exceeded"
+	ErrorCodeChannelConcurrencyLimit      ErrorCode = "channel_concurrency_limit"
+	ErrorCodeChannelCircuitBreakerOpen    ErrorCode = "channel:circuit_breaker_open"
+	ErrorCodeChannelKeysRateLimited       ErrorCode = "channel:keys_rate_limited"
 
 	// client request error
 	ErrorCodeReadRequestBodyFailed ErrorCode = "read_request_body_failed"
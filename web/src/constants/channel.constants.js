@@ -194,6 +194,16 @@ export const CHANNEL_OPTIONS = [
     color: 'purple',
     label: 'Replicate2 (img2img)',
   },
+  {
+    value: 103,
+    color: 'violet',
+    label: 'Fal',
+  },
+  {
+    value: 104,
+    color: 'indigo',
+    label: 'Stability AI (SD3)',
+  },
 ];
 
 export const MODEL_TABLE_PAGE_SIZE = 10;